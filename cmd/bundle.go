@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+)
+
+const (
+	// exportManifestSigFile holds the hex HMAC-SHA256 of manifest.json when the bundle is signed
+	exportManifestSigFile = "manifest.sig"
+	// bundleEntriesDir holds the files of bundled entries as entries/<id>/<file>
+	bundleEntriesDir = "entries"
+)
+
+// errBundleTampered is returned when a bundle does not match its manifest or signature
+var errBundleTampered = errors.New("bundle failed verification")
+
+// bundleFile is a file of a bundle with its checksum
+type bundleFile struct {
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// sha256File returns the hex-encoded SHA-256 of a file
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	return sha256Reader(f)
+}
+
+func sha256Reader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readBundleKey reads the key bundles are signed with. Surrounding whitespace is ignored so the key
+// file can end with a newline.
+func readBundleKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// signManifest returns the signature of the manifest file data
+func signManifest(data, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// splitBundleEntryFile splits an entries/<id>/<file> path of a bundle. ok is false for other paths.
+func splitBundleEntryFile(file string) (id, name string, ok bool) {
+	parts := strings.Split(file, "/")
+	if len(parts) != 3 || parts[0] != bundleEntriesDir || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// currentProject returns the root and config of the project containing the current directory
+func currentProject() (string, *config.ProjectConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	projectRoot, err := project.FindProjectRoot(cwd)
+	if err != nil {
+		return "", nil, err
+	}
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	return projectRoot, projectCfg, nil
+}
+
+// openBundle opens an export directory or zip archive. The returned function releases the archive.
+func openBundle(bundlePath string) (fs.FS, func() error, error) {
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	if info.IsDir() {
+		return os.DirFS(bundlePath), func() error { return nil }, nil
+	}
+	zr, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	return zr, zr.Close, nil
+}
+
+// verifyBundle checks every file of a bundle against the checksums of its manifest and, with key,
+// the manifest against its signature. It refuses bundles with files the manifest does not list.
+// signed reports whether the bundle carries a signature, checked or not.
+func verifyBundle(fsys fs.FS, key []byte) (manifest *exportManifest, signed bool, err error) {
+	data, err := fs.ReadFile(fsys, exportManifestFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("not an export bundle: %w", err)
+	}
+	sig, err := fs.ReadFile(fsys, exportManifestSigFile)
+	switch {
+	case err == nil:
+		signed = true
+		if key != nil && !hmac.Equal([]byte(strings.TrimSpace(string(sig))), []byte(signManifest(data, key))) {
+			return nil, true, fmt.Errorf("%w: manifest signature does not match the key", errBundleTampered)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		if key != nil {
+			return nil, false, fmt.Errorf("%w: bundle is not signed", errBundleTampered)
+		}
+	default:
+		return nil, false, fmt.Errorf("failed to read %s: %w", exportManifestSigFile, err)
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, signed, fmt.Errorf("%w: invalid manifest: %w", errBundleTampered, err)
+	}
+	files := slices.Clone(manifest.EntryFiles)
+	for _, row := range manifest.Files {
+		files = append(files, bundleFile{File: row.File, SHA256: row.SHA256})
+	}
+
+	listed := map[string]bool{exportManifestFile: true, exportManifestSigFile: true}
+	for _, file := range files {
+		if file.SHA256 == "" {
+			return nil, signed, fmt.Errorf("manifest has no checksum for %s; re-export the bundle with this banago", file.File)
+		}
+		if !fs.ValidPath(file.File) || listed[file.File] {
+			return nil, signed, fmt.Errorf("%w: invalid file %q in manifest", errBundleTampered, file.File)
+		}
+		listed[file.File] = true
+		f, err := fsys.Open(file.File)
+		if err != nil {
+			return nil, signed, fmt.Errorf("%w: %s is missing", errBundleTampered, file.File)
+		}
+		sum, err := sha256Reader(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, signed, fmt.Errorf("failed to read %s: %w", file.File, err)
+		}
+		if sum != file.SHA256 {
+			return nil, signed, fmt.Errorf("%w: %s does not match its checksum", errBundleTampered, file.File)
+		}
+	}
+
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !listed[p] {
+			return fmt.Errorf("%w: %s is not in the manifest", errBundleTampered, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, signed, err
+	}
+	return manifest, signed, nil
+}
+
+// bundleEntryIDs returns the IDs of the entries in a verified manifest, in manifest order
+func bundleEntryIDs(manifest *exportManifest) ([]string, error) {
+	var ids []string
+	for _, file := range manifest.EntryFiles {
+		id, _, ok := splitBundleEntryFile(file.File)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid entry file %q in manifest", errBundleTampered, file.File)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// extractBundleEntries writes the entry files of a bundle into dir laid out as a history directory
+func extractBundleEntries(fsys fs.FS, manifest *exportManifest, dir string) error {
+	for _, file := range manifest.EntryFiles {
+		id, name, _ := splitBundleEntryFile(file.File)
+		data, err := fs.ReadFile(fsys, file.File)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.File, err)
+		}
+		entryDir := history.GetEntryDirByID(dir, id)
+		if err := os.MkdirAll(entryDir, 0o755); err != nil {
+			return fmt.Errorf("failed to extract bundle: %w", err)
+		}
+		if err := os.WriteFile(history.GetEntryFilePath(entryDir, name), data, 0o644); err != nil {
+			return fmt.Errorf("failed to extract bundle: %w", err)
+		}
+	}
+	return nil
+}
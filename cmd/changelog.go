@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var changelogOpts struct {
+	since      string
+	until      string
+	subproject string
+	prompts    int
+}
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Summarize recent activity per subproject in Markdown",
+	Long: `Summarize the activity of a period per subproject as Markdown, for status
+reports: new entries (failed and regenerated ones), edits, tokens and estimated
+cost, and the prompts of the newest entries.
+
+--since and --until accept a date (2026-01-31), an RFC3339 timestamp or a
+relative age (7d, 12h); --until includes the whole day it names. Edits count
+when they were made, also on older entries. Deleted entries leave no trace in
+history and are not reported.
+
+Examples:
+  banago changelog
+  banago changelog --since 2026-01-01 --until 2026-01-31 > january.md
+  banago changelog --since 30d --subproject fox --prompts 0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		now := time.Now()
+		since, _, err := parseTimeFlag(changelogOpts.since, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		var until time.Time
+		if changelogOpts.until != "" {
+			t, precision, err := parseTimeFlag(changelogOpts.until, now)
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			until = t.Add(precision)
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		projectRoot, err := project.FindProjectRoot(cwd)
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return errors.New("banago project not found. Run 'banago init' first")
+			}
+			return err
+		}
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+
+		names := []string{changelogOpts.subproject}
+		if changelogOpts.subproject == "" {
+			infos, err := project.ListSubprojectInfos(projectRoot)
+			if err != nil {
+				return fmt.Errorf("failed to list subprojects: %w", err)
+			}
+			names = names[:0]
+			for _, info := range infos {
+				names = append(names, info.Name)
+			}
+		}
+
+		var sections []changelogSection
+		for _, name := range names {
+			subprojectDir := project.GetSubprojectDir(projectRoot, name)
+			if !config.SubprojectConfigExists(subprojectDir) {
+				return fmt.Errorf("subproject %s not found", name)
+			}
+			subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+			if err != nil {
+				return fmt.Errorf("failed to load subproject config of %s: %w", name, err)
+			}
+			historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+			activity, err := history.ActivityBetween(historyDir, since, until, projectCfg.Model)
+			if err != nil {
+				return fmt.Errorf("failed to load history of %s: %w", name, err)
+			}
+			sections = append(sections, changelogSection{name: name, historyDir: historyDir, activity: activity})
+		}
+
+		end := "now"
+		if !until.IsZero() {
+			end = until.Add(-time.Nanosecond).Format(time.DateOnly)
+		}
+		printChangelog(cmd.OutOrStdout(), projectCfg.Name, since.Format(time.DateOnly)+" to "+end, sections, changelogOpts.prompts)
+		return nil
+	},
+}
+
+// changelogSection is the activity of one subproject
+type changelogSection struct {
+	name       string
+	historyDir string
+	activity   *history.Activity
+}
+
+// printChangelog writes the Markdown report. Subprojects without activity are listed on one line
+// at the end; prompts is the number of newest entry prompts shown per subproject.
+func printChangelog(w io.Writer, projectName, period string, sections []changelogSection, prompts int) {
+	_, _ = fmt.Fprintf(w, "# %s changelog\n\n", projectName)
+	_, _ = fmt.Fprintf(w, "Period: %s\n", period)
+
+	var total history.Activity
+	var idle []string
+	for _, s := range sections {
+		a := s.activity
+		if a.Generations == 0 && a.Edits == 0 {
+			idle = append(idle, s.name)
+			continue
+		}
+		total.Spend.Add(a.Spend)
+		total.Failed += a.Failed
+		total.Regenerated += a.Regenerated
+
+		_, _ = fmt.Fprintf(w, "\n## %s\n\n", s.name)
+		printChangelogActivity(w, a)
+
+		if prompts > 0 && len(a.Entries) > 0 {
+			_, _ = fmt.Fprintln(w, "\nLatest prompts:")
+			for i := len(a.Entries) - 1; i >= 0 && i >= len(a.Entries)-prompts; i-- {
+				prompt, err := history.LoadPrompt(a.Entries[i].GetEntryDir(s.historyDir))
+				if err != nil || strings.TrimSpace(prompt) == "" {
+					continue
+				}
+				_, _ = fmt.Fprintf(w, "- %s\n", promptSnippet(prompt))
+			}
+		}
+	}
+
+	_, _ = fmt.Fprint(w, "\n## Total\n\n")
+	if total.Generations == 0 && total.Edits == 0 {
+		_, _ = fmt.Fprintln(w, "No activity in this period.")
+	} else {
+		printChangelogActivity(w, &total)
+	}
+	if len(idle) > 0 && len(idle) < len(sections) {
+		_, _ = fmt.Fprintf(w, "\nNo activity: %s\n", strings.Join(idle, ", "))
+	}
+}
+
+// printChangelogActivity writes the counts of an activity as a Markdown list
+func printChangelogActivity(w io.Writer, a *history.Activity) {
+	var details []string
+	if a.Failed > 0 {
+		details = append(details, fmt.Sprintf("%d failed", a.Failed))
+	}
+	if a.Regenerated > 0 {
+		details = append(details, fmt.Sprintf("%d regenerated", a.Regenerated))
+	}
+	entries := fmt.Sprint(a.Generations)
+	if len(details) > 0 {
+		entries += " (" + strings.Join(details, ", ") + ")"
+	}
+	_, _ = fmt.Fprintf(w, "- New entries: %s\n", entries)
+	_, _ = fmt.Fprintf(w, "- Edits: %d\n", a.Edits)
+	_, _ = fmt.Fprintf(w, "- Tokens: %d, estimated cost %s\n", a.TokenUsage.Total, formatUSD(a.USD))
+	if a.Unpriced > 0 {
+		_, _ = fmt.Fprintf(w, "- %d runs used a model without pricing and are not included in the cost\n", a.Unpriced)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().StringVar(&changelogOpts.since, "since", "7d", "Start of the period: date, timestamp or age (e.g. 7d)")
+	changelogCmd.Flags().StringVar(&changelogOpts.until, "until", "", "End of the period, inclusive (default: now)")
+	changelogCmd.Flags().StringVar(&changelogOpts.subproject, "subproject", "", "Only report this subproject")
+	changelogCmd.Flags().IntVar(&changelogOpts.prompts, "prompts", 5, "Prompts of the newest entries to list per subproject (0 for none)")
+}
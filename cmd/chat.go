@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// chatHandler handles the chat command with injectable dependencies.
+type chatHandler struct {
+	generator generation.Generator
+	stdin     io.Reader // instructions, one per line (default: os.Stdin)
+}
+
+// chatSession tracks the image the next instruction applies to
+type chatSession struct {
+	entryID string // current generate entry (empty before the first generation)
+	editID  string // latest edit in the chain (empty to edit the generated image)
+}
+
+const chatHelp = `Each line is an instruction for the current subproject:
+  <text>         generate on the first turn, then edit the latest image
+  /new [text]    start over: the next instruction generates a new entry
+  /status        show the entry and edit the next instruction applies to
+  /help          show this help
+  /quit          leave (also Ctrl-D)`
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Iterate on an image interactively",
+	Long: `Open an interactive loop in the current subproject.
+
+The first instruction generates a new entry (using input_images as with
+generate). Every following instruction edits the latest result, so edits
+chain on each other. Each step is written to history as usual.
+
+` + chatHelp,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+
+		handler := &chatHandler{generator: client, stdin: cmd.InOrStdin()}
+		return handler.run(cmd.Context(), cwd, cmd.OutOrStdout())
+	},
+}
+
+// run executes the chat loop until /quit or end of input.
+// Failed turns are reported and the loop continues.
+func (h *chatHandler) run(ctx context.Context, workDir string, w io.Writer) error {
+	in := h.stdin
+	if in == nil {
+		in = os.Stdin
+	}
+
+	// Fail before the first prompt when not run inside a subproject
+	if _, err := subprojectHistoryDir(workDir); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(w, "banago chat. Type /help for commands.")
+	var session chatSession
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		_, _ = fmt.Fprint(w, "> ")
+		line, readErr := readLineErr(in)
+		line = strings.TrimSpace(line)
+
+		if line != "" {
+			quit, err := h.turn(ctx, &session, line, workDir, in, w)
+			if quit {
+				return nil
+			}
+			if err != nil {
+				_, _ = fmt.Fprintf(w, "Error: %v\n", err)
+			}
+			_, _ = fmt.Fprintln(w, "")
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				_, _ = fmt.Fprintln(w, "")
+				return nil
+			}
+			return fmt.Errorf("failed to read input: %w", readErr)
+		}
+	}
+}
+
+// turn handles a single line. It reports whether the session should end.
+func (h *chatHandler) turn(ctx context.Context, session *chatSession, line, workDir string, in io.Reader, w io.Writer) (bool, error) {
+	if strings.HasPrefix(line, "/") {
+		cmd, rest, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "/quit", "/exit":
+			return true, nil
+		case "/help":
+			_, _ = fmt.Fprintln(w, chatHelp)
+			return false, nil
+		case "/status":
+			switch {
+			case session.entryID == "":
+				_, _ = fmt.Fprintln(w, "Next instruction generates a new entry")
+			case session.editID == "":
+				_, _ = fmt.Fprintf(w, "Editing entry %s\n", session.entryID)
+			default:
+				_, _ = fmt.Fprintf(w, "Editing entry %s, edit %s\n", session.entryID, session.editID)
+			}
+			return false, nil
+		case "/new":
+			*session = chatSession{}
+			line = strings.TrimSpace(rest)
+			if line == "" {
+				_, _ = fmt.Fprintln(w, "Next instruction generates a new entry")
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("unknown command %s. Type /help for commands", cmd)
+		}
+	}
+
+	if session.entryID == "" {
+		gen := &generateHandler{generator: h.generator, stdin: in}
+		results, err := gen.runEntries(ctx, generateOptions{prompt: line}, workDir, w)
+		if err != nil {
+			return false, err
+		}
+		session.entryID = results[0].EntryID
+		return false, nil
+	}
+
+	edit := &editHandler{generator: h.generator, stdin: in}
+	editID, err := edit.runEdit(ctx, editOptions{
+		id:     session.entryID,
+		editID: session.editID,
+		prompt: line,
+	}, workDir, w)
+	if err != nil {
+		return false, err
+	}
+	session.editID = editID
+	return false, nil
+}
+
+// subprojectHistoryDir resolves the history directory of the subproject containing workDir
+func subprojectHistoryDir(workDir string) (string, error) {
+	projectRoot, err := project.FindProjectRoot(workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return "", errors.New("banago project not found. Run 'banago init' first")
+		}
+		return "", err
+	}
+	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrNotInSubproject) {
+			return "", errors.New("not in a subproject. Navigate to a subproject directory")
+		}
+		return "", err
+	}
+	subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to load subproject config: %w", err)
+	}
+	return project.ResolveHistoryDir(subprojectDir, subprojectCfg), nil
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatHandler_Run(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	input := strings.Join([]string{
+		"a castle",
+		"make it night",
+		"/bogus",
+		"add stars",
+		"/new a forest",
+		"/quit",
+		"never read",
+	}, "\n")
+
+	mock := newSuccessMock(pngData)
+	handler := &chatHandler{generator: mock, stdin: strings.NewReader(input)}
+	var buf bytes.Buffer
+	require.NoError(t, handler.run(context.Background(), subprojectDir, &buf))
+
+	require.Equal(t, 4, mock.callCount())
+	assert.Contains(t, buf.String(), "unknown command /bogus")
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// The first entry carries a chain of two edits
+	edits, err := history.ListEditEntries(entries[0].GetEntryDir(historyDir))
+	require.NoError(t, err)
+	require.Len(t, edits, 2)
+	assert.Equal(t, "generate", edits[0].Source.Type)
+	assert.Equal(t, "edit", edits[1].Source.Type)
+	assert.Equal(t, edits[0].ID, edits[1].Source.EditID)
+
+	prompt, err := history.LoadPrompt(entries[1].GetEntryDir(historyDir))
+	require.NoError(t, err)
+	assert.Equal(t, "a forest", prompt)
+}
+
+func TestChatHandler_Run_EOF(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	mock := newSuccessMock(nil)
+	handler := &chatHandler{generator: mock, stdin: strings.NewReader("/status")}
+	var buf bytes.Buffer
+	require.NoError(t, handler.run(context.Background(), subprojectDir, &buf))
+	assert.Contains(t, buf.String(), "Next instruction generates a new entry")
+	assert.Equal(t, 0, mock.callCount())
+}
+
+// interleavingGenerator creates an unrelated entry during the first API call, as another
+// process writing to the subproject would
+type interleavingGenerator struct {
+	*mockGenerator
+	interleave func()
+}
+
+func (g *interleavingGenerator) Generate(ctx context.Context, params gemini.Params) *gemini.Result {
+	if g.interleave != nil {
+		g.interleave()
+		g.interleave = nil
+	}
+	return g.mockGenerator.Generate(ctx, params)
+}
+
+func TestChatHandler_Run_ConcurrentEntry(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// The other entry is newer than the one the chat generates
+	var other *history.Entry
+	generator := &interleavingGenerator{mockGenerator: newSuccessMock(pngData), interleave: func() {
+		other = createHistoryEntryForCLI(t, historyDir, "someone else")
+	}}
+	handler := &chatHandler{generator: generator, stdin: strings.NewReader("a castle\nmake it night\n/quit")}
+	require.NoError(t, handler.run(context.Background(), subprojectDir, &bytes.Buffer{}))
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, other.ID, entries[1].ID)
+
+	// The edit continues the chat's own entry
+	edits, err := history.ListEditEntries(entries[0].GetEntryDir(historyDir))
+	require.NoError(t, err)
+	assert.Len(t, edits, 1)
+	assert.Zero(t, history.CountEditEntries(other.GetEntryDir(historyDir)))
+}
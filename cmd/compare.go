@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var compareOpts struct {
+	latest   bool
+	previous bool
+	html     string
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [<id-a> <id-b>]",
+	Short: "Compare two history entries",
+	Long: `Compare the prompts, generation parameters and token usage of two history entries.
+
+The prompt diff is word based: removed words are shown as [-word-] and
+added words as {+word+}. Use --html to also write a self-contained HTML page
+with the output images of both entries side by side.
+
+Examples:
+  banago compare <uuid-a> <uuid-b>
+  banago compare --latest --previous
+  banago compare --latest --previous --html compare.html`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if compareOpts.latest {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyDir, err := currentHistoryDir()
+		if err != nil {
+			return err
+		}
+
+		var a, b *history.Entry
+		if compareOpts.latest {
+			entries, err := history.ListEntries(historyDir)
+			if err != nil {
+				return fmt.Errorf("failed to load history: %w", err)
+			}
+			if len(entries) < 2 {
+				return errors.New("at least two history entries are required to compare")
+			}
+			a, b = entries[len(entries)-2], entries[len(entries)-1]
+		} else {
+			if a, err = history.GetEntryByID(historyDir, args[0]); err != nil {
+				return fmt.Errorf("failed to get history entry: %w", err)
+			}
+			if b, err = history.GetEntryByID(historyDir, args[1]); err != nil {
+				return fmt.Errorf("failed to get history entry: %w", err)
+			}
+		}
+
+		comparison, err := newEntryComparison(historyDir, a, b)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		comparison.print(w)
+
+		if compareOpts.html != "" {
+			if err := comparison.writeHTML(compareOpts.html); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(w, "")
+			_, _ = fmt.Fprintf(w, "Wrote %s\n", compareOpts.html)
+		}
+
+		return nil
+	},
+}
+
+// entryComparison holds two entries and their prompts for comparison
+type entryComparison struct {
+	historyDir string
+	a, b       *history.Entry
+	promptA    string
+	promptB    string
+}
+
+func newEntryComparison(historyDir string, a, b *history.Entry) (*entryComparison, error) {
+	promptA, err := history.LoadPrompt(a.GetEntryDir(historyDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt of %s: %w", a.ID, err)
+	}
+	promptB, err := history.LoadPrompt(b.GetEntryDir(historyDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt of %s: %w", b.ID, err)
+	}
+	return &entryComparison{historyDir: historyDir, a: a, b: b, promptA: promptA, promptB: promptB}, nil
+}
+
+// compareField is a single row of the parameter comparison
+type compareField struct {
+	Name string
+	A, B string
+}
+
+// Changed reports whether the field differs between the entries
+func (f compareField) Changed() bool {
+	return f.A != f.B
+}
+
+func (c *entryComparison) generationFields() []compareField {
+	ga, gb := c.a.Generation, c.b.Generation
+	return []compareField{
+		{"created_at", c.a.CreatedAt, c.b.CreatedAt},
+		{"aspect_ratio", ga.AspectRatio, gb.AspectRatio},
+		{"image_size", ga.ImageSize, gb.ImageSize},
+		{"input_images", strings.Join(ga.InputImages, ", "), strings.Join(gb.InputImages, ", ")},
+		{"outputs", fmt.Sprint(len(c.a.Result.OutputImages)), fmt.Sprint(len(c.b.Result.OutputImages))},
+	}
+}
+
+func (c *entryComparison) tokenFields() []compareField {
+	ta, tb := c.a.Result.TokenUsage, c.b.Result.TokenUsage
+	return []compareField{
+		{"prompt", fmt.Sprint(ta.Prompt), fmt.Sprint(tb.Prompt)},
+		{"candidates", fmt.Sprint(ta.Candidates), fmt.Sprint(tb.Candidates)},
+		{"total", fmt.Sprint(ta.Total), fmt.Sprint(tb.Total)},
+		{"cached", fmt.Sprint(ta.Cached), fmt.Sprint(tb.Cached)},
+		{"thoughts", fmt.Sprint(ta.Thoughts), fmt.Sprint(tb.Thoughts)},
+	}
+}
+
+func (c *entryComparison) print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "A: %s (%s)\n", c.a.ID, c.a.CreatedAt)
+	_, _ = fmt.Fprintf(w, "B: %s (%s)\n", c.b.ID, c.b.CreatedAt)
+	_, _ = fmt.Fprintln(w, "")
+
+	_, _ = fmt.Fprintln(w, "Prompt:")
+	if c.promptA == c.promptB {
+		_, _ = fmt.Fprintln(w, "  (identical)")
+	} else {
+		_, _ = fmt.Fprintf(w, "  %s\n", wordDiff(c.promptA, c.promptB))
+	}
+	_, _ = fmt.Fprintln(w, "")
+
+	_, _ = fmt.Fprintln(w, "Generation:")
+	printFields(w, c.generationFields())
+	_, _ = fmt.Fprintln(w, "")
+
+	_, _ = fmt.Fprintln(w, "Token usage:")
+	printFields(w, c.tokenFields())
+}
+
+func printFields(w io.Writer, fields []compareField) {
+	for _, f := range fields {
+		if f.Changed() {
+			_, _ = fmt.Fprintf(w, "  %s: %s -> %s\n", f.Name, cmpValue(f.A), cmpValue(f.B))
+		} else {
+			_, _ = fmt.Fprintf(w, "  %s: %s\n", f.Name, cmpValue(f.A))
+		}
+	}
+}
+
+// cmpValue renders empty values visibly
+func cmpValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+// wordDiff returns a word-level diff of two texts using [-removed-] and {+added+} markers
+func wordDiff(a, b string) string {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of wa[i:] and wb[j:]
+	lcs := make([][]int, len(wa)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(wb)+1)
+	}
+	for i := len(wa) - 1; i >= 0; i-- {
+		for j := len(wb) - 1; j >= 0; j-- {
+			if wa[i] == wb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out, removed, added []string
+	flush := func() {
+		if len(removed) > 0 {
+			out = append(out, "[-"+strings.Join(removed, " ")+"-]")
+			removed = nil
+		}
+		if len(added) > 0 {
+			out = append(out, "{+"+strings.Join(added, " ")+"+}")
+			added = nil
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(wa) || j < len(wb) {
+		switch {
+		case i < len(wa) && j < len(wb) && wa[i] == wb[j]:
+			flush()
+			out = append(out, wa[i])
+			i++
+			j++
+		case j < len(wb) && (i == len(wa) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, wb[j])
+			j++
+		default:
+			removed = append(removed, wa[i])
+			i++
+		}
+	}
+	flush()
+
+	return strings.Join(out, " ")
+}
+
+var compareHTMLTemplate = template.Must(template.New("compare").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>banago compare</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.columns { display: flex; gap: 2em; }
+.column { flex: 1; min-width: 0; }
+img { max-width: 100%; display: block; margin-bottom: 1em; }
+pre { white-space: pre-wrap; background: #f5f5f5; padding: 1em; }
+td, th { padding: 0.2em 1em; text-align: left; }
+tr.changed { background: #fff3c4; }
+</style>
+</head>
+<body>
+<h1>Compare</h1>
+<table>
+<tr><th></th><th>A</th><th>B</th></tr>
+<tr><td>id</td><td>{{.A.ID}}</td><td>{{.B.ID}}</td></tr>
+{{range .Fields}}<tr{{if .Changed}} class="changed"{{end}}><td>{{.Name}}</td><td>{{.A}}</td><td>{{.B}}</td></tr>
+{{end}}</table>
+<div class="columns">
+<div class="column">
+<h2>A</h2>
+<pre>{{.PromptA}}</pre>
+{{range .ImagesA}}<img src="{{.URL}}" alt="{{.Alt}}">
+{{end}}</div>
+<div class="column">
+<h2>B</h2>
+<pre>{{.PromptB}}</pre>
+{{range .ImagesB}}<img src="{{.URL}}" alt="{{.Alt}}">
+{{end}}</div>
+</div>
+</body>
+</html>
+`))
+
+// writeHTML writes a self-contained comparison page with output images embedded as data URLs
+func (c *entryComparison) writeHTML(path string) error {
+	imagesA, err := embedOutputs(c.historyDir, c.a, c.promptA)
+	if err != nil {
+		return err
+	}
+	imagesB, err := embedOutputs(c.historyDir, c.b, c.promptB)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data := struct {
+		A, B             *history.Entry
+		Fields           []compareField
+		PromptA, PromptB string
+		ImagesA, ImagesB []embeddedImage
+	}{
+		A:       c.a,
+		B:       c.b,
+		Fields:  append(c.generationFields(), c.tokenFields()...),
+		PromptA: c.promptA,
+		PromptB: c.promptB,
+		ImagesA: imagesA,
+		ImagesB: imagesB,
+	}
+	if err := compareHTMLTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to write HTML file: %w", err)
+	}
+	return nil
+}
+
+// embeddedImage is an output image inlined into an HTML page
+type embeddedImage struct {
+	URL template.URL
+	Alt string
+}
+
+// embedOutputs returns the output images of an entry as data URLs, with alt text derived from its prompt
+func embedOutputs(historyDir string, entry *history.Entry, prompt string) ([]embeddedImage, error) {
+	var images []embeddedImage
+	for i, img := range entry.Result.OutputImages {
+		data, err := os.ReadFile(entry.OutputPath(historyDir, img))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output image: %w", err)
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(img))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		alt := altText(prompt)
+		if len(entry.Result.OutputImages) > 1 {
+			alt = fmt.Sprintf("%s (output %d of %d)", alt, i+1, len(entry.Result.OutputImages))
+		}
+		images = append(images, embeddedImage{
+			URL: template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)),
+			Alt: alt,
+		})
+	}
+	return images, nil
+}
+
+// altTextMaxLen keeps alt text short enough for screen readers
+const altTextMaxLen = 125
+
+// altText derives image alt text from a generation prompt, cut at a word boundary
+func altText(prompt string) string {
+	text := strings.Join(strings.Fields(prompt), " ")
+	if text == "" {
+		return "Generated image"
+	}
+	if len(text) <= altTextMaxLen {
+		return text
+	}
+	cut := strings.LastIndex(text[:altTextMaxLen], " ")
+	if cut <= 0 {
+		cut = altTextMaxLen
+	}
+	return text[:cut] + "..."
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().BoolVar(&compareOpts.latest, "latest", false, "Use the latest history entry as B")
+	compareCmd.Flags().BoolVar(&compareOpts.previous, "previous", false, "Use the entry before the latest as A (with --latest)")
+	compareCmd.Flags().StringVar(&compareOpts.html, "html", "", "Write an HTML comparison page to this path")
+
+	compareCmd.MarkFlagsRequiredTogether("latest", "previous")
+}
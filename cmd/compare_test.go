@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "a cat on a mat", "a cat on a mat", "a cat on a mat"},
+		{"replaced word", "a cat sitting on a mat", "a cat standing on a mat", "a cat [-sitting-] {+standing+} on a mat"},
+		{"added words", "a cat", "a fluffy cat at night", "a {+fluffy+} cat {+at night+}"},
+		{"removed words", "a big red ball", "a ball", "a [-big red-] ball"},
+		{"empty a", "", "new prompt", "{+new prompt+}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, wordDiff(tt.a, tt.b))
+		})
+	}
+}
+
+func TestAltText(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("word ", 40)
+
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"short prompt", "a cat on a mat", "a cat on a mat"},
+		{"whitespace collapsed", "a cat\n\n  on a mat ", "a cat on a mat"},
+		{"empty prompt", "  ", "Generated image"},
+		{"long prompt cut at word", long, strings.TrimSpace(strings.Repeat("word ", 25)) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, altText(tt.prompt))
+		})
+	}
+}
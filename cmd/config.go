@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and change project and subproject configuration",
+}
+
+var configProjectFlag bool
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a configuration value",
+	Long: `Print the value of a banago.yaml or config.yaml key, one line per list item.
+Nested keys are dotted (e.g. mirror.mode). Inside a subproject, keys are read from
+its config.yaml unless only banago.yaml defines them; --project always reads banago.yaml.
+Unset optional values print nothing.
+
+Examples:
+  banago config get model
+  banago config get input_images
+  banago config get archive.inputs`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		return runConfigGet(cmd.OutOrStdout(), cwd, args[0], configProjectFlag)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> [values...]",
+	Short: "Change a configuration value",
+	Long: `Set a banago.yaml or config.yaml key without hand-editing YAML.
+Lists take any number of values (none clears the list); other keys take exactly one.
+The key is chosen like 'config get'. The rest of the file, comments included, is kept,
+and nothing is written if the new value is invalid. version and created_at cannot be set.
+
+Examples:
+  banago config set input_images img1.png img2.png
+  banago config set aspect_ratio 16:9
+  banago config set --project model gemini-2.5-flash-image`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		return runConfigSet(cmd.OutOrStdout(), cwd, args[0], args[1:], configProjectFlag)
+	},
+}
+
+// configTarget returns the project root and, when key belongs to config.yaml of the subproject
+// containing workDir, that subproject's directory (empty for banago.yaml)
+func configTarget(workDir, key string, projectOnly bool) (string, string, error) {
+	projectRoot, err := project.FindProjectRoot(workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return "", "", errors.New("banago project not found. Run 'banago init' first")
+		}
+		return "", "", err
+	}
+	if projectOnly {
+		return projectRoot, "", nil
+	}
+	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
+	if err != nil {
+		if !errors.Is(err, project.ErrNotInSubproject) {
+			return "", "", err
+		}
+		if !config.HasProjectField(key) && config.HasSubprojectField(key) {
+			return "", "", fmt.Errorf("%s is a subproject setting. Navigate to a subproject directory", key)
+		}
+		return projectRoot, "", nil
+	}
+	if config.HasProjectField(key) && !config.HasSubprojectField(key) {
+		return projectRoot, "", nil
+	}
+	return projectRoot, project.GetSubprojectDir(projectRoot, subprojectName), nil
+}
+
+// runConfigGet prints the value of key
+func runConfigGet(w io.Writer, workDir, key string, projectOnly bool) error {
+	projectRoot, subprojectDir, err := configTarget(workDir, key, projectOnly)
+	if err != nil {
+		return err
+	}
+	var values []string
+	if subprojectDir != "" {
+		values, err = config.GetSubprojectField(subprojectDir, key)
+	} else {
+		values, err = config.GetProjectField(projectRoot, key)
+	}
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		_, _ = fmt.Fprintln(w, v)
+	}
+	return nil
+}
+
+// runConfigSet sets key to values in the file chosen by configTarget
+func runConfigSet(w io.Writer, workDir, key string, values []string, projectOnly bool) error {
+	projectRoot, subprojectDir, err := configTarget(workDir, key, projectOnly)
+	if err != nil {
+		return err
+	}
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+	if err := requireWritable(projectCfg); err != nil {
+		return err
+	}
+
+	file := "banago.yaml"
+	if subprojectDir != "" {
+		relPath, _ := filepath.Rel(projectRoot, filepath.Join(subprojectDir, "config.yaml"))
+		file = filepath.ToSlash(relPath)
+		err = config.SetSubprojectField(subprojectDir, key, values)
+	} else {
+		err = config.SetProjectField(projectRoot, key, values)
+	}
+	if err != nil {
+		if errors.Is(err, config.ErrManagedField) && key == "version" {
+			return fmt.Errorf("%w. Run 'banago migrate' to upgrade the config version", err)
+		}
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Set %s in %s\n", key, file)
+	return nil
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check banago.yaml and every config.yaml",
+	Long: `Check banago.yaml and the config.yaml of every subproject and list all errors:
+unknown keys (with the closest known key), malformed versions, and invalid
+aspect_ratio, image_size, output_format, token_budget, archive and mirror values.
+
+Every command applies the same checks when it loads a configuration.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		projectRoot, err := project.FindProjectRoot(cwd)
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return errors.New("banago project not found. Run 'banago init' first")
+			}
+			return err
+		}
+
+		invalid, err := validateConfigs(cmd.OutOrStdout(), projectRoot)
+		if err != nil {
+			return err
+		}
+		if invalid > 0 {
+			// Each error is already listed under its file
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d invalid config files", invalid)
+		}
+		return nil
+	},
+}
+
+// validateConfigs loads every configuration file of the project, prints the errors of each
+// and returns the number of invalid files
+func validateConfigs(w io.Writer, projectRoot string) (int, error) {
+	invalid := 0
+	report := func(name string, err error) {
+		if err == nil {
+			_, _ = fmt.Fprintf(w, "%s: ok\n", name)
+			return
+		}
+		invalid++
+		_, _ = fmt.Fprintf(w, "%s:\n", name)
+		for _, e := range configErrors(err) {
+			_, _ = fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+
+	_, err := config.LoadProjectConfig(projectRoot)
+	report("banago.yaml", err)
+
+	names, err := project.ListSubprojectNames(projectRoot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list subprojects: %w", err)
+	}
+	for _, name := range names {
+		subprojectDir := project.GetSubprojectDir(projectRoot, name)
+		relPath, _ := filepath.Rel(projectRoot, filepath.Join(subprojectDir, "config.yaml"))
+		_, err := config.LoadSubprojectConfig(subprojectDir)
+		report(filepath.ToSlash(relPath), err)
+	}
+	return invalid, nil
+}
+
+// configErrors splits a load error into one message per problem, without the "failed to parse" wrapping
+func configErrors(err error) []string {
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []string{err.Error()}
+	}
+	var msgs []string
+	for _, e := range joined.Unwrap() {
+		msgs = append(msgs, e.Error())
+	}
+	return msgs
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configGetCmd.Flags().BoolVar(&configProjectFlag, "project", false, "Read banago.yaml even inside a subproject")
+	configSetCmd.Flags().BoolVar(&configProjectFlag, "project", false, "Write banago.yaml even inside a subproject")
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+)
+
+// promptSnippetLen is the number of prompt characters shown in confirmations and summaries
+const promptSnippetLen = 60
+
+// promptSnippet returns prompt on one line, cut to promptSnippetLen characters
+func promptSnippet(prompt string) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(prompt); len(runes) > promptSnippetLen {
+		prompt = string(runes[:promptSnippetLen]) + "..."
+	}
+	return prompt
+}
+
+// confirmLatest shows which entry --latest resolved to and asks for confirmation.
+// It guards against acting on the wrong subproject when several terminals are open.
+func confirmLatest(in io.Reader, w io.Writer, subprojectName, historyDir string, entry *history.Entry) error {
+	prompt, _ := history.LoadPrompt(entry.GetEntryDir(historyDir))
+	prompt = promptSnippet(prompt)
+
+	_, _ = fmt.Fprintf(w, "Latest entry in subproject '%s':\n", subprojectName)
+	_, _ = fmt.Fprintf(w, "  ID:     %s\n", entry.ID)
+	_, _ = fmt.Fprintf(w, "  Date:   %s\n", history.FormatLocal(entry.CreatedAt, time.Now()))
+	_, _ = fmt.Fprintf(w, "  Prompt: %s\n", prompt)
+
+	if !askYesNo(in, w, "Continue?") {
+		return errors.New("aborted. Use --yes to skip confirmation")
+	}
+	return nil
+}
+
+// askYesNo prints a y/N question and reports whether the answer was yes
+func askYesNo(in io.Reader, w io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(w, "%s [y/N]: ", question)
+	if in == nil {
+		in = os.Stdin
+	}
+	switch strings.ToLower(strings.TrimSpace(readLine(in))) {
+	case "y", "yes":
+		_, _ = fmt.Fprintln(w, "")
+		return true
+	default:
+		return false
+	}
+}
+
+// readLine reads a single line byte by byte so that consecutive questions
+// can share the same reader without losing buffered input
+func readLine(in io.Reader) string {
+	line, _ := readLineErr(in)
+	return line
+}
+
+// readLineErr is readLine that also reports the read error (io.EOF when input ends before a newline)
+func readLineErr(in io.Reader) (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return sb.String(), nil
+			}
+			sb.WriteByte(buf[0])
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+	}
+}
+
+// generationPreview summarizes a generation request before any API call
+type generationPreview struct {
+	model      string
+	prompt     string
+	imagePaths []string
+	aspect     string
+	size       string
+	count      int
+}
+
+// confirmGeneration shows the resolved request and its estimated cost and asks for confirmation.
+// It is used when banago.yaml has confirm_before_generate: true.
+func confirmGeneration(in io.Reader, w io.Writer, p generationPreview) error {
+	var inputBytes int64
+	for _, path := range p.imagePaths {
+		if info, err := os.Stat(path); err == nil {
+			inputBytes += info.Size()
+		}
+	}
+	count := max(p.count, 1)
+	est := gemini.EstimateGeneration(p.model, len([]rune(p.prompt)), len(p.imagePaths), p.size)
+
+	_, _ = fmt.Fprintln(w, "About to call the Gemini API:")
+	_, _ = fmt.Fprintf(w, "  Model:   %s\n", p.model)
+	_, _ = fmt.Fprintf(w, "  Prompt:  %d characters\n", len([]rune(p.prompt)))
+	_, _ = fmt.Fprintf(w, "  Inputs:  %d images (%s)\n", len(p.imagePaths), formatBytes(inputBytes))
+	_, _ = fmt.Fprintf(w, "  Target:  aspect %s, size %s\n", cmpValue(p.aspect), cmpValue(p.size))
+	if count > 1 {
+		_, _ = fmt.Fprintf(w, "  Count:   %d generations\n", count)
+	}
+	if est.Known {
+		_, _ = fmt.Fprintf(w, "  Estimated cost: ~$%.3f (%d input + %d output tokens per generation)\n",
+			est.USD*float64(count), est.InputTokens, est.OutputTokens)
+	} else {
+		_, _ = fmt.Fprintf(w, "  Estimated cost: unknown for this model (~%d input + %d output tokens per generation)\n",
+			est.InputTokens, est.OutputTokens)
+	}
+
+	if !askYesNo(in, w, "Continue?") {
+		return errors.New("aborted. Use --yes to skip confirmation")
+	}
+	return nil
+}
+
+// formatBytes formats a byte count for display
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
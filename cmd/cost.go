@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var costOpts struct {
+	subproject string
+}
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Report estimated spend per subproject and month",
+	Long: `Report the estimated spend of generations and edits in the project,
+per subproject and per month (UTC).
+
+Costs are recorded in meta.yaml from the token usage and the model's pricing
+at generation time. Older entries without a recorded cost are estimated from
+their token usage with the project model. Runs of models without pricing
+are counted but not priced.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		projectRoot, err := project.FindProjectRoot(cwd)
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return errors.New("banago project not found. Run 'banago init' first")
+			}
+			return err
+		}
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+
+		names := []string{costOpts.subproject}
+		if costOpts.subproject == "" {
+			infos, err := project.ListSubprojectInfos(projectRoot)
+			if err != nil {
+				return fmt.Errorf("failed to list subprojects: %w", err)
+			}
+			names = names[:0]
+			for _, info := range infos {
+				names = append(names, info.Name)
+			}
+		}
+
+		rows, err := costRows(projectRoot, projectCfg.Model, names)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if jsonOutput() {
+			return writeJSON(w, newCostJSON(rows))
+		}
+		printCostReport(w, rows)
+		return nil
+	},
+}
+
+// costRow is the spend of one subproject in one month
+type costRow struct {
+	month      string
+	subproject string
+	spend      history.Spend
+}
+
+// costRows collects the monthly spend of the named subprojects, ordered by month then subproject
+func costRows(projectRoot, model string, names []string) ([]costRow, error) {
+	var rows []costRow
+	for _, name := range names {
+		subprojectDir := project.GetSubprojectDir(projectRoot, name)
+		if !config.SubprojectConfigExists(subprojectDir) {
+			return nil, fmt.Errorf("subproject %s not found", name)
+		}
+		subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load subproject config of %s: %w", name, err)
+		}
+		months, err := history.SpendByMonth(project.ResolveHistoryDir(subprojectDir, subprojectCfg), model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history of %s: %w", name, err)
+		}
+		for month, spend := range months {
+			rows = append(rows, costRow{month: month, subproject: name, spend: *spend})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].month != rows[j].month {
+			return rows[i].month < rows[j].month
+		}
+		return rows[i].subproject < rows[j].subproject
+	})
+	return rows, nil
+}
+
+func printCostReport(w io.Writer, rows []costRow) {
+	if len(rows) == 0 {
+		_, _ = fmt.Fprintln(w, "No history yet")
+		return
+	}
+
+	nameWidth := len("Subproject")
+	for _, r := range rows {
+		nameWidth = max(nameWidth, len(r.subproject))
+	}
+
+	var total history.Spend
+	_, _ = fmt.Fprintf(w, "%-7s  %-*s  %11s  %5s  %10s  %9s\n", "Month", nameWidth, "Subproject", "Generations", "Edits", "Tokens", "Cost")
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(w, "%-7s  %-*s  %11d  %5d  %10d  %9s\n",
+			r.month, nameWidth, r.subproject, r.spend.Generations, r.spend.Edits, r.spend.TokenUsage.Total, formatUSD(r.spend.USD))
+		total.Add(r.spend)
+	}
+	_, _ = fmt.Fprintf(w, "%-7s  %-*s  %11d  %5d  %10d  %9s\n",
+		"Total", nameWidth, "", total.Generations, total.Edits, total.TokenUsage.Total, formatUSD(total.USD))
+	if total.Unpriced > 0 {
+		_, _ = fmt.Fprintf(w, "\n%d runs used a model without pricing and are not included in the cost\n", total.Unpriced)
+	}
+}
+
+// totalSpend returns the spend of all generations and edits in historyDir
+func totalSpend(historyDir, model string) (history.Spend, error) {
+	var total history.Spend
+	months, err := history.SpendByMonth(historyDir, model)
+	if err != nil {
+		return total, err
+	}
+	for _, s := range months {
+		total.Add(*s)
+	}
+	return total, nil
+}
+
+// formatUSD formats an estimated cost in dollars
+func formatUSD(usd float64) string {
+	return fmt.Sprintf("~$%.2f", usd)
+}
+
+// costJSON is the JSON output of cost
+type costJSON struct {
+	Months []costMonthJSON `json:"months"`
+	Total  spendJSON       `json:"total"`
+}
+
+type costMonthJSON struct {
+	Month      string `json:"month"`
+	Subproject string `json:"subproject"`
+	spendJSON
+}
+
+// spendJSON is an estimated spend
+type spendJSON struct {
+	Generations int     `json:"generations"`
+	Edits       int     `json:"edits"`
+	Tokens      int     `json:"tokens"`
+	USD         float64 `json:"estimated_cost_usd"`
+	Unpriced    int     `json:"unpriced_runs,omitempty"`
+}
+
+func newSpendJSON(s history.Spend) spendJSON {
+	return spendJSON{Generations: s.Generations, Edits: s.Edits, Tokens: s.TokenUsage.Total, USD: s.USD, Unpriced: s.Unpriced}
+}
+
+func newCostJSON(rows []costRow) costJSON {
+	out := costJSON{Months: []costMonthJSON{}}
+	var total history.Spend
+	for _, r := range rows {
+		out.Months = append(out.Months, costMonthJSON{Month: r.month, Subproject: r.subproject, spendJSON: newSpendJSON(r.spend)})
+		total.Add(r.spend)
+	}
+	out.Total = newSpendJSON(total)
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+
+	costCmd.Flags().StringVar(&costOpts.subproject, "subproject", "", "Only report this subproject")
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment and project for problems",
+	Long: `Check the environment and the whole project and print a fix for each problem:
+  - GEMINI_API_KEY (or --api-key) is set
+  - banago.yaml and every config.yaml load and use the current version
+  - input_images, context_file and character_file point to existing files
+  - history directories contain only loadable entries
+  - free disk space is above min_free_disk_mb
+  - the temp workspace is below tmp_max_mb
+
+Exits with an error when a problem is found. Nothing is modified.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		problems, err := runDoctor(cmd.OutOrStdout(), cwd)
+		if err != nil {
+			return err
+		}
+		if problems == 0 {
+			return nil
+		}
+		// The report already explains each problem; usage text would bury it
+		cmd.SilenceUsage = true
+		if problems == 1 {
+			return errors.New("1 problem found")
+		}
+		return fmt.Errorf("%d problems found", problems)
+	},
+}
+
+// doctorReport prints check results and counts the problems
+type doctorReport struct {
+	w        io.Writer
+	problems int
+}
+
+func (r *doctorReport) ok(format string, args ...any) {
+	_, _ = fmt.Fprintf(r.w, "  ok  "+format+"\n", args...)
+}
+
+func (r *doctorReport) problem(fix, format string, args ...any) {
+	r.problems++
+	_, _ = fmt.Fprintf(r.w, "  !!  "+format+"\n", args...)
+	_, _ = fmt.Fprintf(r.w, "      Fix: %s\n", fix)
+}
+
+// runDoctor checks the environment and the project containing workDir and returns the number of problems
+func runDoctor(w io.Writer, workDir string) (int, error) {
+	r := &doctorReport{w: w}
+
+	_, _ = fmt.Fprintln(w, "Environment:")
+	if err := requireAPIKey(); err != nil {
+		r.problem("export GEMINI_API_KEY=<key> or pass --api-key", "API key is not set")
+	} else {
+		r.ok("API key is set")
+	}
+
+	projectRoot, err := project.FindProjectRoot(workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return 0, errors.New("banago project not found. Run 'banago init' first")
+		}
+		return 0, err
+	}
+
+	_, _ = fmt.Fprintln(w, "")
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		// Keep checking the subprojects with the defaults
+		_, _ = fmt.Fprintf(w, "Project %s:\n", filepath.Base(projectRoot))
+		r.problem("fix banago.yaml; 'banago config validate' lists every error", "%s", strings.Join(configErrors(err), "; "))
+		projectCfg = &config.ProjectConfig{}
+	} else {
+		_, _ = fmt.Fprintf(w, "Project %s:\n", projectCfg.Name)
+		doctorProject(r, projectCfg)
+	}
+
+	names, err := project.ListSubprojectNames(projectRoot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list subprojects: %w", err)
+	}
+	var historyBytes int64
+	for _, name := range names {
+		_, _ = fmt.Fprintln(w, "")
+		_, _ = fmt.Fprintf(w, "Subproject %s:\n", name)
+		historyBytes += doctorSubproject(r, projectRoot, name)
+	}
+
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Disk:")
+	_, _ = fmt.Fprintf(w, "      History uses %s\n", formatBytes(historyBytes))
+	if live, stale, err := project.WorkspaceUsage(project.ResolveWorkspaceDir(projectRoot, projectCfg)); err == nil && live+stale > 0 {
+		line := "Temp workspace uses " + formatBytes(live)
+		if stale > 0 {
+			// Removed by the next run that opens the workspace
+			line += fmt.Sprintf(" (plus %s left by ended runs)", formatBytes(stale))
+		}
+		if maxBytes := projectCfg.TmpMaxBytes(); maxBytes > 0 && live >= maxBytes {
+			r.problem("wait for the running banago processes or raise tmp_max_mb in banago.yaml",
+				"%s, at tmp_max_mb (%d)", line, maxBytes>>20)
+		} else {
+			_, _ = fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	if free, ok := generation.FreeDiskSpace(projectRoot); ok {
+		if minFree := projectCfg.MinFreeDisk(); free < minFree {
+			r.problem("free up disk space or remove old entries with 'banago prune'",
+				"%s free, below min_free_disk_mb (%d)", formatBytes(free), minFree>>20)
+		} else {
+			r.ok("%s free", formatBytes(free))
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, "")
+	if r.problems == 0 {
+		_, _ = fmt.Fprintln(w, "No problems found")
+	}
+	return r.problems, nil
+}
+
+// doctorProject checks banago.yaml
+func doctorProject(r *doctorReport, projectCfg *config.ProjectConfig) {
+	if version, err := config.ParseVersion(projectCfg.Version); err != nil {
+		r.problem("set version in banago.yaml, then run 'banago migrate'", "banago.yaml has an invalid version %q", projectCfg.Version)
+	} else if version < 2 {
+		r.problem("run 'banago migrate'", "banago.yaml is version %s", projectCfg.Version)
+	} else {
+		r.ok("banago.yaml version %s", projectCfg.Version)
+	}
+	if projectCfg.Model == "" {
+		r.problem(fmt.Sprintf("set model in banago.yaml (e.g. %s)", config.DefaultModel), "no model is configured")
+	}
+}
+
+// doctorSubproject checks one subproject and returns the disk space used by its history
+func doctorSubproject(r *doctorReport, projectRoot, name string) int64 {
+	subprojectDir := project.GetSubprojectDir(projectRoot, name)
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		r.problem(fmt.Sprintf("fix subprojects/%s/config.yaml; 'banago config validate' lists every error", name), "%s", strings.Join(configErrors(err), "; "))
+		return 0
+	}
+
+	if version, err := config.ParseVersion(subprojectCfg.Version); err != nil || version < 2 {
+		r.problem("run 'banago migrate'", "config.yaml is version %q", subprojectCfg.Version)
+	}
+
+	if check, err := project.CheckInputs(subprojectDir, subprojectCfg); err != nil {
+		r.problem("check the permissions of inputs/", "failed to read inputs/: %v", err)
+	} else {
+		for _, img := range check.Missing {
+			r.problem(fmt.Sprintf("add inputs/%s or remove it from input_images", img), "input image %s not found", img)
+		}
+		if len(check.Missing) == 0 && len(subprojectCfg.InputImages) > 0 {
+			r.ok("%d input images", len(subprojectCfg.InputImages))
+		}
+	}
+	if subprojectCfg.ContextFile != "" {
+		if _, err := os.Stat(filepath.Join(subprojectDir, subprojectCfg.ContextFile)); err != nil {
+			r.problem(fmt.Sprintf("create %s or clear context_file in config.yaml", subprojectCfg.ContextFile),
+				"context_file %s not found", subprojectCfg.ContextFile)
+		}
+	}
+	if subprojectCfg.CharacterFile != "" {
+		characterPath := project.GetCharacterPath(projectRoot, subprojectCfg.CharacterFile)
+		if _, err := os.Stat(characterPath); err != nil {
+			relPath, _ := filepath.Rel(projectRoot, characterPath)
+			r.problem(fmt.Sprintf("create %s or clear character_file in config.yaml", relPath),
+				"character_file %s not found", subprojectCfg.CharacterFile)
+		}
+		if _, err := config.LoadCharacterPreset(characterPath); err != nil {
+			relPath, _ := filepath.Rel(projectRoot, config.CharacterPresetPath(characterPath))
+			r.problem(fmt.Sprintf("fix %s", relPath), "%v", err)
+		}
+	}
+
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+	entries, err := history.ListEntries(historyDir)
+	if err != nil {
+		r.problem("check the permissions of the history directory", "%v", err)
+		return 0
+	}
+	orphans, err := history.FindOrphans(historyDir)
+	if err != nil {
+		r.problem("check the permissions of the history directory", "%v", err)
+	}
+	for _, o := range orphans {
+		fix := fmt.Sprintf("move %s out of the history directory or delete it", o.Name)
+		if o.Collectable {
+			fix = "run 'banago gc' to delete what interrupted runs left behind"
+		}
+		r.problem(fix, "history/%s is not a valid entry: %s", o.Name, o.Reason)
+	}
+	size, err := history.DirSize(historyDir)
+	if err != nil {
+		r.problem("check the permissions of the history directory", "failed to measure history: %v", err)
+	}
+	if len(orphans) == 0 {
+		r.ok("history: %d entries, %s", len(entries), formatBytes(size))
+	}
+	return size
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/generation"
+)
+
+// dryRunRequest is one API call a --dry-run resolved
+type dryRunRequest struct {
+	sourceID   string // the entry regenerated or edited, empty for generate
+	generation int    // number of the generation in a batch whose generations send different requests, else 0
+	count      int    // generations sending this request
+	result     *generation.DryRunResult
+}
+
+// printDryRun prints the requests a run would send and their estimated cost
+func printDryRun(w io.Writer, requests []dryRunRequest) {
+	var totalUSD float64
+	known := true
+	for i, req := range requests {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w, "")
+		}
+		params := req.result.Params
+		count := max(req.count, 1)
+		switch {
+		case req.sourceID != "":
+			_, _ = fmt.Fprintf(w, "Request for %s:\n", req.sourceID)
+		case req.generation > 0:
+			_, _ = fmt.Fprintf(w, "Request for generation %d of %d:\n", req.generation, len(requests))
+		default:
+			_, _ = fmt.Fprintln(w, "Request:")
+		}
+		_, _ = fmt.Fprintf(w, "  Model:       %s\n", params.Model)
+		_, _ = fmt.Fprintf(w, "  Aspect:      %s\n", cmpValue(params.AspectRatio))
+		_, _ = fmt.Fprintf(w, "  Size:        %s\n", cmpValue(params.ImageSize))
+		if params.Seed != nil {
+			_, _ = fmt.Fprintf(w, "  Seed:        %d\n", *params.Seed)
+		} else {
+			_, _ = fmt.Fprintln(w, "  Seed:        random")
+		}
+		_, _ = fmt.Fprintf(w, "  Candidates:  %d\n", max(params.Candidates, 1))
+		if count > 1 {
+			_, _ = fmt.Fprintf(w, "  Count:       %d generations\n", count)
+		}
+		_, _ = fmt.Fprintf(w, "  Inputs:      %d images\n", len(params.ImagePaths))
+		for _, path := range params.ImagePaths {
+			size := "missing"
+			if info, err := os.Stat(path); err == nil {
+				size = formatBytes(info.Size())
+			}
+			_, _ = fmt.Fprintf(w, "    %s (%s)\n", path, size)
+		}
+		for _, name := range req.result.Omitted {
+			_, _ = fmt.Fprintf(w, "    %s (omitted by token_budget)\n", name)
+		}
+		_, _ = fmt.Fprintln(w, "  Prompt:")
+		for line := range strings.SplitSeq(params.Prompt, "\n") {
+			_, _ = fmt.Fprintf(w, "    %s\n", line)
+		}
+
+		est := req.result.Estimate
+		if est.Known {
+			_, _ = fmt.Fprintf(w, "  Estimate:    ~$%.3f (%d input + %d output tokens per generation)\n",
+				est.USD*float64(count), est.InputTokens, est.OutputTokens)
+			totalUSD += est.USD * float64(count)
+		} else {
+			_, _ = fmt.Fprintf(w, "  Estimate:    unknown cost for this model (~%d input + %d output tokens per generation)\n",
+				est.InputTokens, est.OutputTokens)
+			known = false
+		}
+	}
+	if len(requests) > 1 && known {
+		_, _ = fmt.Fprintf(w, "\nEstimated total: ~$%.3f\n", totalUSD)
+	}
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Dry run: the API was not called and history was not changed")
+}
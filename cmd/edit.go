@@ -8,7 +8,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/gemini"
@@ -19,22 +21,39 @@ import (
 )
 
 type editOptions struct {
-	id         string
-	latest     bool
-	editID     string
-	editLatest bool
-	prompt     string
-	promptFile string
-	aspect     string
-	size       string
+	id           string
+	latest       bool
+	editID       string
+	editLatest   bool
+	prompt       string
+	promptFile   string
+	aspect       string
+	size         string
+	format       string         // converts outputs to png, jpeg or webp (overrides output_format in config.yaml)
+	model        string         // overrides banago.yaml's model for this edit
+	mask         string         // mask image, relative to the working directory
+	index        int            // 1-based output of the source entry or edit to start from (0 = the first)
+	upscale      bool           // record the edit as an upscale (banago upscale)
+	keepFailures bool           // keep edits of failed API calls (also keep_failures in banago.yaml)
+	dryRun       bool           // print the resolved request and exit without calling the API
+	timeout      *time.Duration // --timeout; nil uses banago.yaml's timeout
+	yes          bool
+	json         bool
+	quiet        bool
+	events       bool // stream progress events as NDJSON instead of text
+	wait         bool // queue behind other processes writing to the subproject instead of failing
 }
 
 // editHandler handles the edit command with dependency injection support.
 type editHandler struct {
 	generator generation.Generator
+	stdin     io.Reader // answers the --latest confirmation (default: os.Stdin)
 }
 
-var editOpts editOptions
+var (
+	editOpts    editOptions
+	editTimeout time.Duration
+)
 
 var editCmd = &cobra.Command{
 	Use:   "edit",
@@ -51,22 +70,31 @@ Examples:
   banago edit --id <uuid> --edit-id <edit-uuid> -p "Additional adjustments"`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		if err := requireAPIKey(); err != nil {
-			return err
-		}
-
 		cwd, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		// Create Gemini client and inject into handler
-		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
-		if err != nil {
-			return fmt.Errorf("failed to create Gemini client: %w", err)
+		// Create Gemini client and inject into handler; a dry run needs neither key nor client
+		var generator generation.Generator
+		if !editOpts.dryRun {
+			if err := requireAPIKey(); err != nil {
+				return err
+			}
+			client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+			if err != nil {
+				return fmt.Errorf("failed to create Gemini client: %w", err)
+			}
+			generator = client
 		}
 
-		handler := &editHandler{generator: client}
+		if cmd.Flags().Changed("timeout") {
+			editOpts.timeout = &editTimeout
+		}
+
+		handler := &editHandler{generator: generator, stdin: cmd.InOrStdin()}
+		editOpts.json = jsonOutput()
+		editOpts.events = eventsOutput()
 		return handler.run(cmd.Context(), editOpts, cwd, cmd.OutOrStdout())
 	},
 }
@@ -74,56 +102,85 @@ Examples:
 // run executes the edit command logic.
 // This method is independent of cobra.Command for testability.
 func (h *editHandler) run(ctx context.Context, opts editOptions, workDir string, w io.Writer) error {
+	_, err := h.runEdit(ctx, opts, workDir, w)
+	return err
+}
+
+// runEdit is run returning the ID of the edit it created ("" for --dry-run)
+func (h *editHandler) runEdit(ctx context.Context, opts editOptions, workDir string, w io.Writer) (string, error) {
+	// In JSON mode progress text is discarded and a single JSON document is written at the end.
+	// --quiet likewise writes only the resulting IDs, and --events only the progress events.
+	jsonW := w
+	if opts.json || opts.quiet || opts.events {
+		w = io.Discard
+	}
+
 	promptText, err := resolveEditPrompt(opts.prompt, opts.promptFile)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	projectRoot, err := project.FindProjectRoot(workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrProjectNotFound) {
-			return errors.New("banago project not found. Run 'banago init' first")
+			return "", errors.New("banago project not found. Run 'banago init' first")
 		}
-		return err
+		return "", err
 	}
 
 	// Load project config
 	projectCfg, err := config.LoadProjectConfig(projectRoot)
 	if err != nil {
-		return fmt.Errorf("failed to load project config: %w", err)
+		return "", fmt.Errorf("failed to load project config: %w", err)
 	}
-	model := projectCfg.Model
+	if !opts.dryRun {
+		if err := requireWritable(projectCfg); err != nil {
+			return "", err
+		}
+	}
+	model, modelOverride := resolveModel(opts.model, projectCfg)
 
 	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrNotInSubproject) {
-			return errors.New("not in a subproject. Navigate to a subproject directory")
+			return "", errors.New("not in a subproject. Navigate to a subproject directory")
 		}
-		return err
+		return "", err
 	}
 
 	subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
 	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
 	if err != nil {
-		return fmt.Errorf("failed to load subproject config: %w", err)
+		return "", fmt.Errorf("failed to load subproject config: %w", err)
 	}
-	historyDir := history.GetHistoryDir(subprojectDir)
+	if err := project.CheckWorkDir(subprojectDir, subprojectCfg, workDir); err != nil {
+		return "", err
+	}
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
 
 	// Load generate entry
 	var genEntry *history.Entry
 	if opts.latest {
 		genEntry, err = history.GetLatestEntry(historyDir)
 		if err != nil {
-			return fmt.Errorf("failed to get latest history: %w", err)
+			return "", fmt.Errorf("failed to get latest history: %w", err)
+		}
+		if !opts.yes && !opts.dryRun {
+			if opts.json || opts.quiet || opts.events {
+				return "", errConfirmRequired
+			}
+			if err := confirmLatest(h.stdin, w, subprojectName, historyDir, genEntry); err != nil {
+				return "", err
+			}
 		}
 	} else {
 		genEntry, err = history.GetEntryByID(historyDir, opts.id)
 		if err != nil {
-			return fmt.Errorf("failed to get history entry: %w", err)
+			return "", fmt.Errorf("failed to get history entry: %w", err)
 		}
 	}
 
-	entryDir := filepath.Join(historyDir, genEntry.ID)
+	entryDir := genEntry.GetEntryDir(historyDir)
 
 	// Determine source image path and source info
 	var sourceImagePath string
@@ -137,37 +194,43 @@ func (h *editHandler) run(ctx context.Context, opts editOptions, workDir string,
 		if opts.editLatest {
 			editEntry, err = history.GetLatestEditEntry(entryDir)
 			if err != nil {
-				return fmt.Errorf("failed to get latest edit: %w", err)
+				return "", fmt.Errorf("failed to get latest edit: %w", err)
 			}
 		} else {
 			editEntry, err = history.GetEditEntryByID(entryDir, opts.editID)
 			if err != nil {
-				return fmt.Errorf("failed to get edit entry: %w", err)
+				return "", fmt.Errorf("failed to get edit entry: %w", err)
 			}
 		}
 
 		if len(editEntry.Result.OutputImages) == 0 {
-			return errors.New("no output images in edit entry")
+			return "", errors.New("no output images in edit entry")
 		}
 
-		sourceOutput = editEntry.Result.OutputImages[0]
+		sourceOutput, err = pickOutput(editEntry.Result.OutputImages, opts.index)
+		if err != nil {
+			return "", err
+		}
 		sourceImagePath = history.GetEditOutputPath(entryDir, editEntry.ID, sourceOutput)
 		sourceType = "edit"
 		sourceEditID = editEntry.ID
 	} else {
 		// Edit from generate output
 		if len(genEntry.Result.OutputImages) == 0 {
-			return errors.New("no output images in history entry")
+			return "", errors.New("no output images in history entry")
 		}
 
-		sourceOutput = genEntry.Result.OutputImages[0]
-		sourceImagePath = filepath.Join(entryDir, sourceOutput)
+		sourceOutput, err = pickOutput(genEntry.Result.OutputImages, opts.index)
+		if err != nil {
+			return "", err
+		}
+		sourceImagePath = genEntry.OutputPath(historyDir, sourceOutput)
 		sourceType = "generate"
 	}
 
 	// Verify source image exists
 	if _, err := os.Stat(sourceImagePath); err != nil {
-		return fmt.Errorf("source image not found: %s", sourceImagePath)
+		return "", fmt.Errorf("source image not found: %s", sourceImagePath)
 	}
 
 	_, _ = fmt.Fprintf(w, "Editing from %s: %s\n", sourceType, sourceOutput)
@@ -182,22 +245,108 @@ func (h *editHandler) run(ctx context.Context, opts editOptions, workDir string,
 	aspect := cmp.Or(opts.aspect, editAspect, genEntry.Generation.AspectRatio, subprojectCfg.AspectRatio)
 	size := cmp.Or(opts.size, editSize, genEntry.Generation.ImageSize, subprojectCfg.ImageSize)
 
+	format := cmp.Or(opts.format, subprojectCfg.OutputFormat)
+	if err := config.ValidateOutputFormat(format); err != nil {
+		return "", err
+	}
+
+	maskPath := opts.mask
+	if maskPath != "" && !filepath.IsAbs(maskPath) {
+		maskPath = filepath.Join(workDir, maskPath)
+	}
+
 	// Build edit spec
+	mirror, err := resolveMirrorPolicy(subprojectDir, subprojectCfg)
+	if err != nil {
+		return "", err
+	}
+
 	spec := generation.EditSpec{
 		Model:           model,
+		ModelOverride:   modelOverride,
 		Prompt:          promptText,
 		AspectRatio:     aspect,
 		ImageSize:       size,
+		OutputFormat:    format,
 		SourceImagePath: sourceImagePath,
+		MaskPath:        maskPath,
 		EntryID:         genEntry.ID,
 		SourceType:      sourceType,
 		SourceEditID:    sourceEditID,
 		SourceOutput:    sourceOutput,
+		Upscale:         opts.upscale,
+		Mirror:          mirror,
+		MinFreeDisk:     projectCfg.MinFreeDisk(),
+		KeepFailures:    opts.keepFailures || projectCfg.KeepFailures,
+	}
+
+	if opts.dryRun {
+		result, err := generation.NewService(h.generator).DryRunEdit(spec, historyDir, w)
+		if err != nil {
+			return "", err
+		}
+		requests := []dryRunRequest{{sourceID: genEntry.ID, count: 1, result: result}}
+		if opts.json {
+			return "", writeJSON(jsonW, newDryRunJSON(requests))
+		}
+		printDryRun(jsonW, requests)
+		return "", nil
 	}
 
+	if projectCfg.ConfirmBeforeGenerate && !opts.yes {
+		if opts.json || opts.quiet || opts.events {
+			return "", errConfirmRequired
+		}
+		if err := confirmGeneration(h.stdin, w, generationPreview{
+			model:      model,
+			prompt:     promptText,
+			imagePaths: slices.DeleteFunc([]string{sourceImagePath, maskPath}, func(p string) bool { return p == "" }),
+			aspect:     aspect,
+			size:       size,
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	ctx, cancel := runContext(ctx, opts.timeout, projectCfg)
+	defer cancel()
+	unlock, err := lockSubproject(ctx, subprojectDir, opts.wait, w)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
 	// Run edit with injected generator
-	_, err = generation.NewService(h.generator).Edit(ctx, spec, historyDir, w)
-	return err
+	service := generation.NewService(h.generator)
+	if opts.events {
+		service.WithEvents(ndjsonEvents(jsonW))
+	}
+	result, err := service.Edit(ctx, spec, historyDir, w)
+	if err != nil {
+		return "", err
+	}
+	if opts.json {
+		edit, err := history.GetEditEntryByID(entryDir, result.EditID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load edit entry: %w", err)
+		}
+		return result.EditID, writeJSON(jsonW, newEditJSON(entryDir, edit))
+	}
+	if opts.quiet && !opts.events {
+		_, _ = fmt.Fprintln(jsonW, result.EditID)
+	}
+	return result.EditID, nil
+}
+
+// pickOutput returns the index-th (1-based) of outputs, or the first for 0
+func pickOutput(outputs []string, index int) (string, error) {
+	if index == 0 {
+		return outputs[0], nil
+	}
+	if index < 1 || index > len(outputs) {
+		return "", fmt.Errorf("invalid output index %d: the source has %d outputs", index, len(outputs))
+	}
+	return outputs[index-1], nil
 }
 
 func resolveEditPrompt(prompt, promptFile string) (string, error) {
@@ -235,6 +384,15 @@ func init() {
 	editCmd.Flags().StringVarP(&editOpts.promptFile, "prompt-file", "F", "", "Path to edit prompt file")
 	editCmd.Flags().StringVar(&editOpts.aspect, "aspect", "", "Output image aspect ratio (overrides history/config)")
 	editCmd.Flags().StringVar(&editOpts.size, "size", "", "Output image size (overrides history/config)")
+	editCmd.Flags().StringVar(&editOpts.format, "format", "", "Convert outputs to png, jpeg or webp when saving (overrides output_format)")
+	editCmd.Flags().StringVar(&editOpts.model, "model", "", "Model for this edit instead of banago.yaml's (recorded in history)")
+	editCmd.Flags().StringVar(&editOpts.mask, "mask", "", "Mask image: only white areas are edited, black areas are preserved")
+	editCmd.Flags().BoolVar(&editOpts.dryRun, "dry-run", false, "Print the request that would be sent and its estimated cost without calling the API")
+	editCmd.Flags().BoolVarP(&editOpts.yes, "yes", "y", false, "Skip confirmation for --latest and confirm_before_generate")
+	editCmd.Flags().BoolVarP(&editOpts.quiet, "quiet", "q", false, "Print only the new edit ID")
+	editCmd.Flags().BoolVar(&editOpts.keepFailures, "keep-failures", false, "Keep the edit with the error when the API call fails")
+	editCmd.Flags().BoolVar(&editOpts.wait, "wait", false, "Wait for other banago processes writing to the subproject instead of failing")
+	editCmd.Flags().DurationVar(&editTimeout, "timeout", 0, "Stop the edit after this long, e.g. 5m (overrides timeout in banago.yaml; 0 = no limit)")
 
 	editCmd.MarkFlagsOneRequired("id", "latest")
 	editCmd.MarkFlagsMutuallyExclusive("id", "latest")
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/blck-snwmn/banago/internal/config"
@@ -58,6 +59,7 @@ func TestEditHandler_Run_Success(t *testing.T) {
 	var buf bytes.Buffer
 	err = handler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "edit prompt",
 	}, subprojectDir, &buf)
 
@@ -208,6 +210,7 @@ func TestEditHandler_Run_APIError(t *testing.T) {
 	var buf bytes.Buffer
 	err = handler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "edit prompt",
 	}, subprojectDir, &buf)
 	require.Error(t, err)
@@ -246,6 +249,7 @@ func TestEditHandler_Run_NoHistory(t *testing.T) {
 	var buf bytes.Buffer
 	err = handler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "edit prompt",
 	}, subprojectDir, &buf)
 
@@ -279,6 +283,7 @@ func TestEditHandler_Run_EmptyPrompt(t *testing.T) {
 	var buf bytes.Buffer
 	err = handler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "",
 	}, subprojectDir, &buf)
 
@@ -331,6 +336,7 @@ func TestScenario_GenerateAndEdit(t *testing.T) {
 	var editBuf bytes.Buffer
 	err = editHandler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "edit prompt",
 	}, subprojectDir, &editBuf)
 	require.NoError(t, err)
@@ -414,6 +420,7 @@ func TestScenario_EditChain(t *testing.T) {
 	var edit1Buf bytes.Buffer
 	err = edit1Handler.run(context.Background(), editOptions{
 		latest: true,
+		yes:    true,
 		prompt: "first edit",
 	}, subprojectDir, &edit1Buf)
 	require.NoError(t, err)
@@ -431,6 +438,7 @@ func TestScenario_EditChain(t *testing.T) {
 	var edit2Buf bytes.Buffer
 	err = edit2Handler.run(context.Background(), editOptions{
 		latest:     true,
+		yes:        true,
 		editLatest: true,
 		prompt:     "second edit",
 	}, subprojectDir, &edit2Buf)
@@ -473,3 +481,136 @@ func TestScenario_EditChain(t *testing.T) {
 		assert.Len(t, outputFiles, 1)
 	}
 }
+
+func TestEditHandler_Run_Mask(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(subprojectDir, "sky-mask.png"), pngData, 0o644))
+
+	genHandler := &generateHandler{generator: newSuccessMock(pngData)}
+	require.NoError(t, genHandler.run(context.Background(), generateOptions{prompt: "original prompt"}, subprojectDir, &bytes.Buffer{}))
+
+	t.Run("mask is sent and recorded", func(t *testing.T) {
+		editMock := newSuccessMock(pngData)
+		handler := &editHandler{generator: editMock}
+		require.NoError(t, handler.run(context.Background(), editOptions{
+			latest: true,
+			yes:    true,
+			prompt: "make the sky purple",
+			mask:   "sky-mask.png",
+		}, subprojectDir, &bytes.Buffer{}))
+
+		lastCall := editMock.lastCall()
+		require.Len(t, lastCall.ImagePaths, 2)
+		assert.Equal(t, filepath.Join(subprojectDir, "sky-mask.png"), lastCall.ImagePaths[1])
+		assert.True(t, strings.HasPrefix(lastCall.Prompt, "make the sky purple"))
+		assert.Contains(t, lastCall.Prompt, "mask")
+
+		genEntry, err := history.GetLatestEntry(historyDir)
+		require.NoError(t, err)
+		entryDir := genEntry.GetEntryDir(historyDir)
+		edit, err := history.GetLatestEditEntry(entryDir)
+		require.NoError(t, err)
+		assert.Equal(t, "mask.png", edit.Generation.MaskFile)
+		assert.FileExists(t, filepath.Join(edit.GetEditEntryDir(entryDir), "mask.png"))
+
+		prompt, err := history.LoadEditPrompt(edit.GetEditEntryDir(entryDir))
+		require.NoError(t, err)
+		assert.Equal(t, "make the sky purple", prompt, "the saved prompt excludes the mask instruction")
+	})
+
+	t.Run("missing mask fails before the API call", func(t *testing.T) {
+		editMock := newSuccessMock(pngData)
+		handler := &editHandler{generator: editMock}
+		err := handler.run(context.Background(), editOptions{
+			latest: true,
+			yes:    true,
+			prompt: "make the sky purple",
+			mask:   "missing.png",
+		}, subprojectDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mask")
+		assert.Equal(t, 0, editMock.callCount())
+	})
+}
+
+func TestEditHandler_Run_Upscale(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	genHandler := &generateHandler{generator: newMultiImageMock(pngData, 2)}
+	require.NoError(t, genHandler.run(context.Background(), generateOptions{prompt: "original prompt", aspect: "16:9"}, subprojectDir, &bytes.Buffer{}))
+	genEntry, err := history.GetLatestEntry(historyDir)
+	require.NoError(t, err)
+	require.Len(t, genEntry.Result.OutputImages, 2)
+	entryDir := genEntry.GetEntryDir(historyDir)
+
+	t.Run("upscales the selected output", func(t *testing.T) {
+		editMock := newSuccessMock(pngData)
+		handler := &editHandler{generator: editMock}
+		require.NoError(t, handler.run(context.Background(), editOptions{
+			latest:  true,
+			yes:     true,
+			prompt:  upscaleEditPrompt("keep the grain"),
+			size:    upscaleDefaultSize,
+			index:   2,
+			upscale: true,
+		}, subprojectDir, &bytes.Buffer{}))
+
+		lastCall := editMock.lastCall()
+		require.Len(t, lastCall.ImagePaths, 1)
+		assert.Equal(t, history.GetEntryFilePath(entryDir, genEntry.Result.OutputImages[1]), lastCall.ImagePaths[0])
+		assert.Equal(t, "4K", lastCall.ImageSize)
+		assert.Equal(t, "16:9", lastCall.AspectRatio)
+		assert.True(t, strings.HasPrefix(lastCall.Prompt, upscalePrompt))
+		assert.True(t, strings.HasSuffix(lastCall.Prompt, "keep the grain"))
+
+		edit, err := history.GetLatestEditEntry(entryDir)
+		require.NoError(t, err)
+		assert.True(t, edit.Generation.Upscale)
+		assert.Equal(t, genEntry.Result.OutputImages[1], edit.Source.Output)
+	})
+
+	t.Run("out of range index fails before the API call", func(t *testing.T) {
+		editMock := newSuccessMock(pngData)
+		handler := &editHandler{generator: editMock}
+		err := handler.run(context.Background(), editOptions{
+			latest:  true,
+			yes:     true,
+			prompt:  upscalePrompt,
+			index:   3,
+			upscale: true,
+		}, subprojectDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid output index 3")
+		assert.Equal(t, 0, editMock.callCount())
+	})
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var entryCmd = &cobra.Command{
+	Use:   "entry",
+	Short: "Curate history entries",
+	Long:  "Split and merge history entries of the current subproject.",
+}
+
+var entrySplitOpts struct {
+	id     string
+	latest bool
+	yes    bool
+}
+
+var entrySplitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split a multi-output entry into one entry per output",
+	Long: `Split a history entry with multiple outputs into one entry per output.
+
+The prompt and input images are copied into each new entry, and each new
+entry records the original entry ID as lineage. The original entry is removed.
+Entries with edits cannot be split.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+
+		id := entrySplitOpts.id
+		if entrySplitOpts.latest {
+			entry, err := history.GetLatestEntry(historyDir)
+			if err != nil {
+				return fmt.Errorf("failed to get latest history: %w", err)
+			}
+			if !entrySplitOpts.yes {
+				if err := confirmLatest(cmd.InOrStdin(), cmd.OutOrStdout(), hist.subprojectName, historyDir, entry); err != nil {
+					return err
+				}
+			}
+			id = entry.ID
+		}
+
+		created, err := history.SplitEntry(historyDir, id, hist.layout)
+		if err != nil {
+			return fmt.Errorf("failed to split entry: %w", err)
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Split %s into %d entries:\n", id, len(created))
+		for _, e := range created {
+			_, _ = fmt.Fprintf(w, "  %s (%s)\n", e.ID, e.Result.OutputImages[0])
+		}
+
+		return nil
+	},
+}
+
+var entryMergeCmd = &cobra.Command{
+	Use:   "merge <id> <id>...",
+	Short: "Merge related entries into one entry",
+	Long: `Merge history entries that share the same prompt and input images into one entry.
+
+Outputs of all entries are collected into the new entry, which records the
+original entry IDs as lineage. The original entries are removed.
+Entries with edits or failed entries cannot be merged.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+
+		merged, err := history.MergeEntries(historyDir, args, hist.layout)
+		if err != nil {
+			return fmt.Errorf("failed to merge entries: %w", err)
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Merged %d entries into %s\n", len(args), merged.ID)
+		_, _ = fmt.Fprintf(w, "  Output: %d images\n", len(merged.Result.OutputImages))
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(entryCmd)
+	entryCmd.AddCommand(entrySplitCmd)
+	entryCmd.AddCommand(entryMergeCmd)
+
+	entrySplitCmd.Flags().StringVar(&entrySplitOpts.id, "id", "", "History entry ID to split")
+	entrySplitCmd.Flags().BoolVar(&entrySplitOpts.latest, "latest", false, "Use the latest history entry")
+	entrySplitCmd.Flags().BoolVarP(&entrySplitOpts.yes, "yes", "y", false, "Skip confirmation for --latest")
+
+	entrySplitCmd.MarkFlagsOneRequired("id", "latest")
+	entrySplitCmd.MarkFlagsMutuallyExclusive("id", "latest")
+}
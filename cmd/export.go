@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var exportOpts struct {
+	ids        []string
+	allSuccess bool
+	dir        string
+	zip        string
+	entries    bool   // also bundle the entry files so 'banago import' can recreate the entries
+	signKey    string // key file to sign the manifest with
+	strip      bool   // strip embedded metadata from the exported images
+}
+
+// exportManifestFile is written next to the exported images
+const exportManifestFile = "manifest.json"
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Bundle selected output images for sharing",
+	Long: `Copy output images of selected history entries into a flat directory
+or a zip archive, together with a manifest.json that maps each file back
+to its entry ID and prompt. The history itself is not modified.
+
+The manifest records the SHA-256 of every file. With --entries the bundle
+also holds each entry's metadata, prompt and inputs, so 'banago import' can
+recreate the entries elsewhere; --sign-key signs the manifest with a key
+shared with the recipient.
+
+--strip-metadata removes all metadata embedded in the exported copies (EXIF,
+XMP, PNG text chunks, including the banago entry ID and prompt hash) and
+leaves the prompts out of the manifest, for public release. Every stripped
+copy is checked to be clean; images that cannot be checked fail the export.
+The history keeps its metadata.
+
+Examples:
+  banago export --ids <uuid-a>,<uuid-b> --dir ./delivery
+  banago export --all-success --zip delivery.zip
+  banago export --ids <uuid> --strip-metadata --dir ./public
+  banago export --all-success --entries --sign-key studio.key --zip handoff.zip`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		subprojectName, historyDir, err := resolveCurrentHistoryDir(false)
+		if err != nil {
+			return err
+		}
+
+		var entries []*history.Entry
+		if exportOpts.allSuccess {
+			all, err := history.ListEntries(historyDir)
+			if err != nil {
+				return fmt.Errorf("failed to load history: %w", err)
+			}
+			for _, e := range all {
+				if e.Result.Success && len(e.Result.OutputImages) > 0 {
+					entries = append(entries, e)
+				}
+			}
+		} else {
+			for _, id := range exportOpts.ids {
+				e, err := history.GetEntryByID(historyDir, id)
+				if err != nil {
+					return fmt.Errorf("failed to get history entry %s: %w", id, err)
+				}
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == 0 {
+			return errors.New("no entries to export")
+		}
+
+		var key []byte
+		if exportOpts.signKey != "" {
+			if key, err = readBundleKey(exportOpts.signKey); err != nil {
+				return err
+			}
+		}
+		manifest, err := newExportManifest(subprojectName, historyDir, entries)
+		if err != nil {
+			return err
+		}
+		if exportOpts.strip {
+			if err := manifest.stripMetadata(historyDir); err != nil {
+				return err
+			}
+		}
+		if exportOpts.entries {
+			_, projectCfg, err := currentProject()
+			if err != nil {
+				return err
+			}
+			manifest.Project = projectCfg.Name
+			if err := manifest.addEntryFiles(historyDir, entries); err != nil {
+				return err
+			}
+		}
+
+		dest := exportOpts.dir
+		if exportOpts.zip != "" {
+			dest = exportOpts.zip
+			err = exportZip(exportOpts.zip, historyDir, manifest, key)
+		} else {
+			err = exportDir(exportOpts.dir, historyDir, manifest, key)
+		}
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Exported %d images from %d entries to %s\n", len(manifest.Files), len(entries), dest)
+		if manifest.MetadataStripped {
+			_, _ = fmt.Fprintln(w, "  Metadata stripped and verified clean")
+		}
+		if key != nil {
+			_, _ = fmt.Fprintf(w, "  Signed: %s\n", exportManifestSigFile)
+		}
+		return nil
+	},
+}
+
+// exportManifest describes the contents of an export
+type exportManifest struct {
+	Project    string              `json:"project,omitempty"` // set with --entries, recorded as the import source
+	Subproject string              `json:"subproject"`
+	ExportedAt string              `json:"exported_at"`
+	Files      []exportManifestRow `json:"files"`
+	EntryFiles []bundleFile        `json:"entry_files,omitempty"` // files of the entries with --entries
+
+	MetadataStripped bool `json:"metadata_stripped,omitempty"` // images without embedded metadata, rows without prompts
+}
+
+// exportManifestRow maps an exported file back to its history entry
+type exportManifestRow struct {
+	File      string `json:"file"`
+	EntryID   string `json:"entry_id"`
+	Output    string `json:"output"`
+	CreatedAt string `json:"created_at"`
+	Prompt    string `json:"prompt,omitempty"` // left out with --strip-metadata
+	SHA256    string `json:"sha256,omitempty"` // missing in bundles of older banago versions
+}
+
+// newExportManifest lists the output images of entries under flat, unique filenames.
+// Output filenames are kept as is unless two entries share one, in which case the entry ID is prepended.
+func newExportManifest(subprojectName, historyDir string, entries []*history.Entry) (*exportManifest, error) {
+	manifest := &exportManifest{
+		Subproject: subprojectName,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:      []exportManifestRow{},
+	}
+
+	used := map[string]bool{exportManifestFile: true, exportManifestSigFile: true}
+	for _, e := range entries {
+		prompt, err := history.LoadPrompt(e.GetEntryDir(historyDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt of %s: %w", e.ID, err)
+		}
+		for _, img := range e.Result.OutputImages {
+			name := img
+			if used[name] {
+				name = e.ID + "-" + img
+			}
+			used[name] = true
+			sum, err := sha256File(e.OutputPath(historyDir, img))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read output image: %w", err)
+			}
+			manifest.Files = append(manifest.Files, exportManifestRow{
+				File:      name,
+				EntryID:   e.ID,
+				Output:    img,
+				CreatedAt: e.CreatedAt,
+				Prompt:    prompt,
+				SHA256:    sum,
+			})
+		}
+	}
+	return manifest, nil
+}
+
+func (m *exportManifest) sourcePath(historyDir string, row exportManifestRow) string {
+	return history.ResolveEntryFile(history.GetEntryDirByID(historyDir, row.EntryID), row.Output)
+}
+
+// outputData returns the exported content of an image, without metadata if the manifest says so
+func (m *exportManifest) outputData(historyDir string, row exportManifestRow) ([]byte, error) {
+	data, err := os.ReadFile(m.sourcePath(historyDir, row))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output image: %w", err)
+	}
+	if !m.MetadataStripped {
+		return data, nil
+	}
+	stripped, err := gemini.StripMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s of %s: %w", row.Output, row.EntryID, err)
+	}
+	return stripped, nil
+}
+
+// stripMetadata marks the images for export without metadata, recording the sums of the stripped
+// copies and dropping the prompts
+func (m *exportManifest) stripMetadata(historyDir string) error {
+	m.MetadataStripped = true
+	for i, row := range m.Files {
+		data, err := m.outputData(historyDir, row)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		m.Files[i].SHA256 = hex.EncodeToString(sum[:])
+		m.Files[i].Prompt = ""
+	}
+	return nil
+}
+
+// addEntryFiles lists the metadata, prompt, inputs and outputs of entries under entries/<id>/.
+// Bundles are flat whatever the layout of the entries; edits are not bundled, like with import-entry.
+func (m *exportManifest) addEntryFiles(historyDir string, entries []*history.Entry) error {
+	for _, e := range entries {
+		entryDir := e.GetEntryDir(historyDir)
+		names, err := history.ListEntryFiles(entryDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			sum, err := sha256File(history.ResolveEntryFile(entryDir, name))
+			if err != nil {
+				return fmt.Errorf("failed to read %s of %s: %w", name, e.ID, err)
+			}
+			m.EntryFiles = append(m.EntryFiles, bundleFile{File: path.Join(bundleEntriesDir, e.ID, name), SHA256: sum})
+		}
+		// Inputs kept in the object store are bundled like copies in the entry directory
+		for _, name := range e.Generation.InputImages {
+			if _, ok := e.Generation.InputObjects[name]; !ok || slices.Contains(names, name) {
+				continue
+			}
+			src := history.ResolveEntryFile(entryDir, name)
+			if src == history.GetEntryFilePath(entryDir, name) {
+				continue
+			}
+			sum, err := sha256File(src)
+			if err != nil {
+				return fmt.Errorf("failed to read %s of %s: %w", name, e.ID, err)
+			}
+			m.EntryFiles = append(m.EntryFiles, bundleFile{File: path.Join(bundleEntriesDir, e.ID, name), SHA256: sum})
+		}
+	}
+	return nil
+}
+
+// entrySourcePath returns where an entry file of the bundle is read from
+func entrySourcePath(historyDir string, file bundleFile) string {
+	id, name, _ := splitBundleEntryFile(file.File)
+	return history.ResolveEntryFile(history.GetEntryDirByID(historyDir, id), name)
+}
+
+// encode returns the manifest file and, with key, its signature
+func (m *exportManifest) encode(key []byte) ([]byte, []byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	data = append(data, '\n')
+	if key == nil {
+		return data, nil, nil
+	}
+	return data, []byte(signManifest(data, key) + "\n"), nil
+}
+
+// exportDir copies the files of a manifest into dir, refusing to overwrite existing files
+func exportDir(dir, historyDir string, manifest *exportManifest, key []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	for _, row := range manifest.Files {
+		data, err := manifest.outputData(historyDir, row)
+		if err != nil {
+			return err
+		}
+		if err := writeNewFile(filepath.Join(dir, row.File), data); err != nil {
+			return err
+		}
+	}
+	for _, file := range manifest.EntryFiles {
+		dst := filepath.Join(dir, filepath.FromSlash(file.File))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return fmt.Errorf("failed to create export directory: %w", err)
+		}
+		if err := copyToNewFile(entrySourcePath(historyDir, file), dst); err != nil {
+			return err
+		}
+	}
+
+	data, sig, err := manifest.encode(key)
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(filepath.Join(dir, exportManifestFile), data); err != nil {
+		return err
+	}
+	if sig != nil {
+		return writeNewFile(filepath.Join(dir, exportManifestSigFile), sig)
+	}
+	return nil
+}
+
+// exportZip writes the files of a manifest and the manifest itself into a new zip archive
+func exportZip(path, historyDir string, manifest *exportManifest, key []byte) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create zip archive: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write zip archive: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	for _, row := range manifest.Files {
+		data, err := manifest.outputData(historyDir, row)
+		if err != nil {
+			return err
+		}
+		if err := addZipData(zw, row.File, data); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range manifest.EntryFiles {
+		if err := addZipFile(zw, file.File, entrySourcePath(historyDir, file)); err != nil {
+			return err
+		}
+	}
+
+	data, sig, err := manifest.encode(key)
+	if err != nil {
+		return err
+	}
+	if err := addZipData(zw, exportManifestFile, data); err != nil {
+		return err
+	}
+	if sig != nil {
+		if err := addZipData(zw, exportManifestSigFile, sig); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	return nil
+}
+
+func addZipFile(zw *zip.Writer, name, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read output image: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	return nil
+}
+
+func addZipData(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	return nil
+}
+
+// copyToNewFile copies srcPath to dstPath, failing if dstPath already exists
+func copyToNewFile(srcPath, dstPath string) error {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read output image: %w", err)
+	}
+	return writeNewFile(dstPath, data)
+}
+
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringSliceVar(&exportOpts.ids, "ids", nil, "Comma-separated history entry IDs to export")
+	exportCmd.Flags().BoolVar(&exportOpts.allSuccess, "all-success", false, "Export every successful entry")
+	exportCmd.Flags().StringVar(&exportOpts.dir, "dir", "", "Export into this directory")
+	exportCmd.Flags().StringVar(&exportOpts.zip, "zip", "", "Export into this zip archive")
+	exportCmd.Flags().BoolVar(&exportOpts.entries, "entries", false, "Also bundle the entries (metadata, prompt, inputs) for 'banago import'")
+	exportCmd.Flags().StringVar(&exportOpts.signKey, "sign-key", "", "Sign the manifest with the key in this file")
+	exportCmd.Flags().BoolVar(&exportOpts.strip, "strip-metadata", false, "Strip embedded metadata and prompts for public release")
+
+	exportCmd.MarkFlagsOneRequired("ids", "all-success")
+	exportCmd.MarkFlagsMutuallyExclusive("ids", "all-success")
+	exportCmd.MarkFlagsOneRequired("dir", "zip")
+	exportCmd.MarkFlagsMutuallyExclusive("dir", "zip")
+	// Entry bundles carry the prompts and metadata 'banago import' needs
+	exportCmd.MarkFlagsMutuallyExclusive("strip-metadata", "entries")
+}
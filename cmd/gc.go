@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var gcOpts struct {
+	dryRun bool
+	yes    bool
+	wait   bool
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete files left behind by interrupted runs",
+	Long: `Find and delete files of the whole project that nothing references,
+left behind when a run crashed or was killed:
+  - entry directories without meta.yaml
+  - edit directories without edit-meta.yaml
+  - entries and edits whose generation never finished writing them
+  - temporary files of interrupted metadata writes
+  - input snapshots in .banago/objects that no entry references
+
+Running generations and commands that change history hold the subproject
+lock, so gc fails (or waits with --wait) while one runs. Unfinished entries,
+edits and temporary files changed within the last hour are kept as well, in
+case a banago without the lock is still writing them. Directories in history that are not named like
+entries are kept; 'banago doctor' reports them. The object store is only
+collected when the history of every subproject could be read.
+
+Examples:
+  banago gc --dry-run
+  banago gc --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		projectRoot, err := project.FindProjectRoot(cwd)
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return errors.New("banago project not found. Run 'banago init' first")
+			}
+			return err
+		}
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+		if !gcOpts.dryRun {
+			if err := requireWritable(projectCfg); err != nil {
+				return err
+			}
+		}
+
+		infos, err := project.ListSubprojectInfos(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to list subprojects: %w", err)
+		}
+		w := cmd.OutOrStdout()
+
+		// Running generations write entries under the subproject lock; hold all of them while collecting
+		if !gcOpts.dryRun {
+			for _, info := range infos {
+				unlock, err := lockSubproject(cmd.Context(), project.GetSubprojectDir(projectRoot, info.Name), gcOpts.wait, w)
+				if err != nil {
+					return fmt.Errorf("subproject %s: %w", info.Name, err)
+				}
+				defer unlock()
+			}
+		}
+
+		var garbage []history.Garbage
+		store := history.FindObjectStore(projectRoot)
+		refs := map[string]bool{}
+		var skipStore string
+		seen := map[string]bool{}
+		for _, info := range infos {
+			subprojectDir := project.GetSubprojectDir(projectRoot, info.Name)
+			subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+			if err != nil {
+				skipStore = fmt.Sprintf("subprojects/%s/config.yaml cannot be loaded", info.Name)
+				continue
+			}
+			historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+			if seen[historyDir] {
+				continue
+			}
+			seen[historyDir] = true
+			found, err := history.FindGarbage(historyDir)
+			if err != nil {
+				return fmt.Errorf("subproject %s: %w", info.Name, err)
+			}
+			garbage = append(garbage, found...)
+			if err := store.AddReferences(historyDir, refs); err != nil {
+				return fmt.Errorf("subproject %s: %w", info.Name, err)
+			}
+		}
+		if skipStore != "" {
+			_, _ = fmt.Fprintf(w, "Skipping %s: %s\n\n", history.ObjectsDir, skipStore)
+		} else {
+			found, err := store.FindGarbage(refs)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", history.ObjectsDir, err)
+			}
+			garbage = append(garbage, found...)
+		}
+
+		if len(garbage) == 0 {
+			_, _ = fmt.Fprintln(w, "No garbage found")
+			return nil
+		}
+
+		var total int64
+		for _, g := range garbage {
+			path := g.Path
+			if rel, err := filepath.Rel(projectRoot, path); err == nil && filepath.IsLocal(rel) {
+				path = rel
+			}
+			_, _ = fmt.Fprintf(w, "  %s  %s  (%s)\n", path, formatBytes(g.Size), g.Reason)
+			total += g.Size
+		}
+		_, _ = fmt.Fprintln(w, "")
+
+		if gcOpts.dryRun {
+			_, _ = fmt.Fprintf(w, "Would delete %d items and reclaim %s\n", len(garbage), formatBytes(total))
+			return nil
+		}
+		if !gcOpts.yes {
+			if !askYesNo(cmd.InOrStdin(), w, fmt.Sprintf("Delete %d items (%s)?", len(garbage), formatBytes(total))) {
+				return errors.New("aborted. Use --yes to skip confirmation")
+			}
+		}
+
+		if err := history.RemoveGarbage(garbage); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Deleted %d items and reclaimed %s\n", len(garbage), formatBytes(total))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVar(&gcOpts.dryRun, "dry-run", false, "Show what would be deleted and the space reclaimed")
+	gcCmd.Flags().BoolVarP(&gcOpts.yes, "yes", "y", false, "Skip the confirmation")
+	gcCmd.Flags().BoolVar(&gcOpts.wait, "wait", false, "Wait for running generations to finish instead of failing")
+}
@@ -7,27 +7,53 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/generation"
-	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/spf13/cobra"
 )
 
 type generateOptions struct {
-	prompt     string
-	promptFile string
-	aspect     string
-	size       string
+	prompt         string
+	promptFile     string
+	promptName     string // prompt of the subproject's prompt library (prompts/<name>.txt)
+	aspect         string
+	size           string
+	format         string // converts outputs to png, jpeg or webp (overrides output_format in config.yaml)
+	model          string // overrides banago.yaml's model for this run
+	count          int
+	candidates     int            // variants returned by each API call, saved in the same entry
+	seed           *int32         // --seed; nil picks a random seed, recorded in the entry
+	timeout        *time.Duration // --timeout; nil uses banago.yaml's timeout
+	inputs         []string       // ad-hoc input images, relative to the working directory
+	inputsGlob     string         // glob selecting input images inside inputs/
+	noInputImages  bool
+	tokenBreakdown bool
+	template       bool // render the prompt as a template for each generation (also prompt_template in config.yaml)
+	withContext    bool // prepend context and character files to the prompt (also include_context in config.yaml)
+	keepFailures   bool // keep entries of failed API calls (also keep_failures in banago.yaml)
+	dryRun         bool // print the resolved request and exit without calling the API
+	yes            bool
+	json           bool
+	quiet          bool
+	events         bool // stream progress events as NDJSON instead of text
+	wait           bool // queue behind other processes writing to the subproject instead of failing
 }
 
+// The real client supports --token-breakdown.
+var _ generation.TokenCounter = (*gemini.Client)(nil)
+
 // generateHandler handles the generate command with dependency injection support.
 type generateHandler struct {
 	generator generation.Generator
+	stdin     io.Reader // answers the confirm_before_generate prompt (default: os.Stdin)
 }
 
 // resolvePrompt returns the prompt text from either inline prompt or file.
@@ -65,12 +91,215 @@ func collectImagePaths(subprojectDir string, subprojectCfg *config.SubprojectCon
 	return imagePaths
 }
 
+// warnInputsMismatch prints a one-line warning when input_images and the images in inputs/ disagree.
+// The full list is shown by 'banago status'.
+func warnInputsMismatch(w io.Writer, subprojectDir string, subprojectCfg *config.SubprojectConfig) {
+	check, err := project.CheckInputs(subprojectDir, subprojectCfg)
+	if err != nil || check.OK() {
+		return
+	}
+	var parts []string
+	if len(check.Missing) > 0 {
+		parts = append(parts, "missing "+summarizeNames(check.Missing))
+	}
+	if len(check.Unreferenced) > 0 {
+		parts = append(parts, "not in input_images: "+summarizeNames(check.Unreferenced))
+	}
+	_, _ = fmt.Fprintf(w, "Warning: input_images and inputs/ differ (%s). Run 'banago status' for details\n", strings.Join(parts, "; "))
+}
+
+// summarizeNames joins up to three names and counts the rest
+func summarizeNames(names []string) string {
+	const shown = 3
+	if len(names) <= shown {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:shown], ", "), len(names)-shown)
+}
+
+// overrideImagePaths resolves --input paths (relative to workDir) and --inputs-glob matches (inside inputs/)
+// used instead of input_images for a single run. Inputs are snapshotted by filename, so names must be unique.
+func overrideImagePaths(workDir, subprojectDir string, inputs []string, glob string) ([]string, error) {
+	var paths []string
+	for _, in := range inputs {
+		if !filepath.IsAbs(in) {
+			in = filepath.Join(workDir, in)
+		}
+		paths = append(paths, in)
+	}
+
+	if glob != "" {
+		matches, err := filepath.Glob(filepath.Join(project.GetInputsDir(subprojectDir), glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --inputs-glob: %w", err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("--inputs-glob %q matched no files in inputs/", glob)
+		}
+		paths = append(paths, matches...)
+	}
+
+	seen := map[string]string{}
+	var unique []string
+	for _, p := range paths {
+		name := filepath.Base(p)
+		if other, ok := seen[name]; ok {
+			if other != p {
+				return nil, fmt.Errorf("input images %s and %s have the same filename", other, p)
+			}
+			continue
+		}
+		seen[name] = p
+		unique = append(unique, p)
+	}
+	return unique, nil
+}
+
+// lockSubproject takes the subproject's write lock. With wait it queues behind the process holding it.
+func lockSubproject(ctx context.Context, subprojectDir string, wait bool, w io.Writer) (func(), error) {
+	unlock, err := project.LockSubproject(ctx, subprojectDir, false)
+	if errors.Is(err, project.ErrSubprojectLocked) && wait {
+		_, _ = fmt.Fprintln(w, "Waiting for another banago process to finish writing to this subproject...")
+		unlock, err = project.LockSubproject(ctx, subprojectDir, true)
+	}
+	if errors.Is(err, project.ErrSubprojectLocked) {
+		return nil, fmt.Errorf("%w. Retry later or pass --wait to queue", err)
+	}
+	return unlock, err
+}
+
+// errInterrupted is the cause of runs stopped with Ctrl+C
+var errInterrupted = errors.New("interrupted")
+
+// runContext bounds ctx by the run's time limit, --timeout over banago.yaml's timeout (0 = none), and
+// cancels it on the first Ctrl+C or SIGTERM, so the API call is aborted and its entry cleaned up.
+// Signals are handled as usual again once ctx ends, so a second Ctrl+C kills banago.
+func runContext(ctx context.Context, flagTimeout *time.Duration, projectCfg *config.ProjectConfig) (context.Context, context.CancelFunc) {
+	timeout := projectCfg.RunTimeout()
+	if flagTimeout != nil {
+		timeout = *flagTimeout
+	}
+	cancelTimeout := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel(errInterrupted)
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, func() {
+		cancel(context.Canceled)
+		cancelTimeout()
+	}
+}
+
+// resolveModel returns the model for a run, --model over banago.yaml, and whether the flag chose it
+func resolveModel(flagModel string, projectCfg *config.ProjectConfig) (string, bool) {
+	if flagModel != "" {
+		return flagModel, true
+	}
+	return projectCfg.Model, false
+}
+
+// applyCharacterPreset loads the preset of the subproject's character and fills the aspect ratio and
+// image size config.yaml leaves unset, so flags win over config.yaml and config.yaml over the preset.
+// Callers append the preset's prompt notes themselves (WithNotes); they end up in prompt.txt, which
+// is why regenerate only applies the params, and only to entries without a config snapshot.
+// It returns nil when the subproject has no character or the character no preset.
+func applyCharacterPreset(projectRoot string, subprojectCfg *config.SubprojectConfig) (*config.CharacterPreset, error) {
+	if subprojectCfg.CharacterFile == "" {
+		return nil, nil
+	}
+	preset, err := config.LoadCharacterPreset(project.GetCharacterPath(projectRoot, subprojectCfg.CharacterFile))
+	if err != nil || preset == nil {
+		return nil, err
+	}
+	preset.ApplyTo(subprojectCfg)
+	return preset, nil
+}
+
 // resolveGenerationParams determines aspect ratio and size from flags and config.
 func resolveGenerationParams(flagAspect, flagSize string, subprojectCfg *config.SubprojectConfig) (aspect, size string) {
 	return cmp.Or(flagAspect, subprojectCfg.AspectRatio), cmp.Or(flagSize, subprojectCfg.ImageSize)
 }
 
-var genOpts generateOptions
+// resolveArchivePolicy builds the archive policy from the subproject config.
+// Context and character files are archived only if they exist; with includeContext they always
+// are, since they become part of the prompt.
+func resolveArchivePolicy(projectRoot, subprojectDir string, subprojectCfg *config.SubprojectConfig, includeContext bool) (generation.ArchivePolicy, error) {
+	archive := subprojectCfg.Archive
+	if err := archive.Validate(); err != nil {
+		return generation.ArchivePolicy{}, err
+	}
+
+	policy := generation.ArchivePolicy{
+		HashInputsOnly: archive.InputsMode() == config.ArchiveInputsHash,
+		RawResponse:    archive.RawResponse,
+	}
+	if archive.Context || includeContext {
+		projectContextPath := project.GetProjectContextPath(projectRoot)
+		if _, err := os.Stat(projectContextPath); err == nil {
+			policy.ProjectContextPath = projectContextPath
+		}
+	}
+	if (archive.Context || includeContext) && subprojectCfg.ContextFile != "" {
+		contextPath := filepath.Join(subprojectDir, subprojectCfg.ContextFile)
+		if _, err := os.Stat(contextPath); err == nil {
+			policy.ContextPath = contextPath
+		}
+	}
+	if (archive.Character || includeContext) && subprojectCfg.CharacterFile != "" {
+		characterPath := project.GetCharacterPath(projectRoot, subprojectCfg.CharacterFile)
+		if _, err := os.Stat(characterPath); err == nil {
+			policy.CharacterPath = characterPath
+		}
+	}
+	return policy, nil
+}
+
+// resolveMirrorPolicy builds the output mirror policy from the subproject config.
+func resolveMirrorPolicy(subprojectDir string, subprojectCfg *config.SubprojectConfig) (generation.MirrorPolicy, error) {
+	if err := subprojectCfg.Mirror.Validate(); err != nil {
+		return generation.MirrorPolicy{}, err
+	}
+	return generation.MirrorPolicy{
+		Dir:     project.ResolveMirrorDir(subprojectDir, subprojectCfg),
+		Symlink: subprojectCfg.Mirror.Mode == config.MirrorSymlink,
+	}, nil
+}
+
+// resolveEvaluationPolicy builds the evaluator policy from the subproject config.
+// Evaluators run from the subproject directory.
+func resolveEvaluationPolicy(subprojectDir string, subprojectCfg *config.SubprojectConfig) (generation.EvaluationPolicy, error) {
+	if err := subprojectCfg.Evaluation.Validate(); err != nil {
+		return generation.EvaluationPolicy{}, err
+	}
+	return generation.EvaluationPolicy{
+		Commands:   subprojectCfg.Evaluators,
+		Dir:        subprojectDir,
+		MarkFailed: subprojectCfg.Evaluation.OnFail != "",
+		Retries:    subprojectCfg.Evaluation.Retries(),
+	}, nil
+}
+
+// openWorkspace opens a run directory in the project's scratch space (see project.OpenWorkspace)
+func openWorkspace(projectRoot string, projectCfg *config.ProjectConfig) (*project.Workspace, error) {
+	return project.OpenWorkspace(project.ResolveWorkspaceDir(projectRoot, projectCfg), projectCfg.TmpMaxBytes())
+}
+
+var (
+	genOpts    generateOptions
+	genSeed    int32
+	genTimeout time.Duration
+)
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
@@ -82,21 +311,34 @@ Must be run inside a subproject directory:
   - Results are saved to history/`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		if err := requireAPIKey(); err != nil {
-			return err
-		}
 		cwd, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		// Create Gemini client and inject into handler
-		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
-		if err != nil {
-			return fmt.Errorf("failed to create Gemini client: %w", err)
+		// Create Gemini client and inject into handler; a dry run needs neither key nor client
+		var generator generation.Generator
+		if !genOpts.dryRun {
+			if err := requireAPIKey(); err != nil {
+				return err
+			}
+			client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+			if err != nil {
+				return fmt.Errorf("failed to create Gemini client: %w", err)
+			}
+			generator = client
 		}
 
-		handler := &generateHandler{generator: client}
+		if cmd.Flags().Changed("seed") {
+			genOpts.seed = &genSeed
+		}
+		if cmd.Flags().Changed("timeout") {
+			genOpts.timeout = &genTimeout
+		}
+
+		handler := &generateHandler{generator: generator, stdin: cmd.InOrStdin()}
+		genOpts.json = jsonOutput()
+		genOpts.events = eventsOutput()
 		return handler.run(cmd.Context(), genOpts, cwd, cmd.OutOrStdout())
 	},
 }
@@ -104,51 +346,218 @@ Must be run inside a subproject directory:
 // run executes the generate command logic.
 // This method is independent of cobra.Command for testability.
 func (h *generateHandler) run(ctx context.Context, opts generateOptions, workDir string, w io.Writer) error {
-	// Get prompt
-	promptText, err := resolvePrompt(opts.prompt, opts.promptFile)
+	_, err := h.runEntries(ctx, opts, workDir, w)
+	return err
+}
+
+// runEntries is run returning the results of the entries it created (none for --dry-run)
+func (h *generateHandler) runEntries(ctx context.Context, opts generateOptions, workDir string, w io.Writer) ([]*generation.Result, error) {
+	// In JSON mode progress text is discarded and a single JSON document is written at the end.
+	// --quiet likewise writes only the resulting IDs, and --events only the progress events.
+	jsonW := w
+	if opts.json || opts.quiet || opts.events {
+		w = io.Discard
+	}
+
+	spec, target, err := resolveGenerateSpec(opts, workDir, w)
+	if err != nil {
+		return nil, err
+	}
+	projectCfg, historyDir := target.projectCfg, target.historyDir
+
+	count := cmp.Or(opts.count, 1)
+	if opts.dryRun {
+		results, err := generation.NewService(h.generator).DryRunBatch(ctx, spec, count, historyDir, w)
+		if err != nil {
+			return nil, err
+		}
+		requests := []dryRunRequest{{count: count, result: results[0]}}
+		if len(results) > 1 {
+			requests = nil
+			for i, result := range results {
+				requests = append(requests, dryRunRequest{generation: i + 1, result: result})
+			}
+		}
+		if opts.json {
+			return nil, writeJSON(jsonW, newDryRunJSON(requests))
+		}
+		printDryRun(jsonW, requests)
+		return nil, nil
+	}
+
+	if projectCfg.ConfirmBeforeGenerate && !opts.yes {
+		if opts.json || opts.quiet || opts.events {
+			return nil, errConfirmRequired
+		}
+		if err := confirmGeneration(h.stdin, w, generationPreview{
+			model:      spec.Model,
+			prompt:     spec.Prompt,
+			imagePaths: spec.ImagePaths,
+			aspect:     spec.AspectRatio,
+			size:       spec.ImageSize,
+			count:      count,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := runContext(ctx, opts.timeout, projectCfg)
+	defer cancel()
+	unlock, err := lockSubproject(ctx, target.subprojectDir, opts.wait, w)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer unlock()
+
+	if len(spec.Evaluation.Commands) > 0 {
+		workspace, err := openWorkspace(target.projectRoot, projectCfg)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = workspace.Close() }()
+		spec.Evaluation.TmpDir = workspace.Dir
+	}
+
+	// Run generation with injected generator
+	service := generation.NewService(h.generator)
+	if opts.events {
+		service.WithEvents(ndjsonEvents(jsonW))
+	}
+	results, err := service.RunBatch(ctx, spec, count, historyDir, w)
+	if err != nil {
+		return nil, err
+	}
+	if opts.json {
+		return results, writeJSON(jsonW, newGenerationJSON(historyDir, "", results))
+	}
+	if opts.quiet && !opts.events {
+		for _, r := range results {
+			_, _ = fmt.Fprintln(jsonW, r.EntryID)
+		}
+	}
+	return results, nil
+}
+
+// generateTarget is the project and subproject a resolved generate run writes to
+type generateTarget struct {
+	projectRoot   string
+	projectCfg    *config.ProjectConfig
+	subprojectDir string
+	historyDir    string
+}
+
+// resolveGenerateSpec resolves the options of generate into the spec of one generation: the prompt,
+// character preset, inputs, aspect and size, and the policies of the subproject.
+func resolveGenerateSpec(opts generateOptions, workDir string, w io.Writer) (generation.Spec, generateTarget, error) {
+	// Get prompt; a library prompt is read once the subproject is known
+	var promptText string
+	var err error
+	if opts.promptName == "" {
+		promptText, err = resolvePrompt(opts.prompt, opts.promptFile)
+		if err != nil {
+			return generation.Spec{}, generateTarget{}, err
+		}
 	}
 
 	projectRoot, err := project.FindProjectRoot(workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrProjectNotFound) {
-			return errors.New("banago project not found. Run 'banago init' first")
+			return generation.Spec{}, generateTarget{}, errors.New("banago project not found. Run 'banago init' first")
 		}
-		return err
+		return generation.Spec{}, generateTarget{}, err
 	}
 
 	// Load project config
 	projectCfg, err := config.LoadProjectConfig(projectRoot)
 	if err != nil {
-		return fmt.Errorf("failed to load project config: %w", err)
+		return generation.Spec{}, generateTarget{}, fmt.Errorf("failed to load project config: %w", err)
+	}
+	if !opts.dryRun {
+		if err := requireWritable(projectCfg); err != nil {
+			return generation.Spec{}, generateTarget{}, err
+		}
 	}
-	model := projectCfg.Model
+	model, modelOverride := resolveModel(opts.model, projectCfg)
 
 	// Must be in a subproject
 	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrNotInSubproject) {
-			return errors.New("not in a subproject. Navigate to a subproject directory")
+			return generation.Spec{}, generateTarget{}, errors.New("not in a subproject. Navigate to a subproject directory")
 		}
-		return err
+		return generation.Spec{}, generateTarget{}, err
 	}
 
 	subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
 	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
 	if err != nil {
-		return fmt.Errorf("failed to load subproject config: %w", err)
+		return generation.Spec{}, generateTarget{}, fmt.Errorf("failed to load subproject config: %w", err)
+	}
+	if err := project.CheckWorkDir(subprojectDir, subprojectCfg, workDir); err != nil {
+		return generation.Spec{}, generateTarget{}, err
+	}
+	if opts.promptName != "" {
+		promptText, err = project.ReadPrompt(subprojectDir, opts.promptName)
+		if err != nil {
+			return generation.Spec{}, generateTarget{}, err
+		}
+	}
+	preset, err := applyCharacterPreset(projectRoot, subprojectCfg)
+	if err != nil {
+		return generation.Spec{}, generateTarget{}, err
+	}
+	if preset != nil {
+		promptText = preset.WithNotes(promptText)
 	}
 
 	// Collect image paths
-	imagePaths := collectImagePaths(subprojectDir, subprojectCfg)
-	if len(imagePaths) == 0 {
-		return errors.New("no images specified. Set input_images in subproject config.yaml")
+	var imagePaths, inputNames []string
+	switch {
+	case opts.noInputImages:
+	case len(opts.inputs) > 0 || opts.inputsGlob != "":
+		imagePaths, err = overrideImagePaths(workDir, subprojectDir, opts.inputs, opts.inputsGlob)
+		if err != nil {
+			return generation.Spec{}, generateTarget{}, err
+		}
+		for _, p := range imagePaths {
+			inputNames = append(inputNames, filepath.Base(p))
+		}
+	default:
+		imagePaths = collectImagePaths(subprojectDir, subprojectCfg)
+		inputNames = subprojectCfg.InputImages
+		warnInputsMismatch(w, subprojectDir, subprojectCfg)
+	}
+	textOnly := opts.noInputImages || subprojectCfg.AllowTextOnly
+	if len(imagePaths) == 0 && !textOnly {
+		return generation.Spec{}, generateTarget{}, errors.New("no images specified. Set input_images in subproject config.yaml or pass --no-input-images")
 	}
 
 	// Determine aspect ratio and size
 	aspect, size := resolveGenerationParams(opts.aspect, opts.size, subprojectCfg)
 
+	includeContext := opts.withContext || subprojectCfg.IncludeContext
+	archive, err := resolveArchivePolicy(projectRoot, subprojectDir, subprojectCfg, includeContext)
+	if err != nil {
+		return generation.Spec{}, generateTarget{}, err
+	}
+	mirror, err := resolveMirrorPolicy(subprojectDir, subprojectCfg)
+	if err != nil {
+		return generation.Spec{}, generateTarget{}, err
+	}
+	evaluation, err := resolveEvaluationPolicy(subprojectDir, subprojectCfg)
+	if err != nil {
+		return generation.Spec{}, generateTarget{}, err
+	}
+	if archive.HashInputsOnly && len(opts.inputs) > 0 {
+		// Hashed entries resolve their inputs from inputs/ on regenerate
+		inputsDir := project.GetInputsDir(subprojectDir)
+		for _, p := range imagePaths {
+			if filepath.Dir(p) != inputsDir {
+				return generation.Spec{}, generateTarget{}, fmt.Errorf("--input %s is outside inputs/, which archive inputs: hash cannot snapshot", p)
+			}
+		}
+	}
+
 	// Build generation spec
 	spec := generation.Spec{
 		Model:           model,
@@ -156,13 +565,37 @@ func (h *generateHandler) run(ctx context.Context, opts generateOptions, workDir
 		ImagePaths:      imagePaths,
 		AspectRatio:     aspect,
 		ImageSize:       size,
-		InputImageNames: subprojectCfg.InputImages,
-	}
+		Seed:            opts.seed,
+		Candidates:      opts.candidates,
+		OutputFormat:    cmp.Or(opts.format, subprojectCfg.OutputFormat),
+		Capabilities:    cachedCapabilities(projectRoot, model),
+		InputImageNames: inputNames,
+		ModelOverride:   modelOverride,
+		Archive:         archive,
+		TokenBreakdown:  opts.tokenBreakdown,
+		TokenBudget:     subprojectCfg.TokenBudget,
+		TextOnly:        textOnly,
 
-	// Run generation with injected generator
-	historyDir := history.GetHistoryDir(subprojectDir)
-	_, err = generation.NewService(h.generator).Run(ctx, spec, historyDir, w)
-	return err
+		AssembleAnimation: subprojectCfg.AssembleAnimation,
+		Mirror:            mirror,
+		Evaluation:        evaluation,
+		MinFreeDisk:       projectCfg.MinFreeDisk(),
+		KeepFailures:      opts.keepFailures || projectCfg.KeepFailures,
+		IncludeContext:    includeContext,
+		Layout:            projectCfg.HistoryLayout,
+	}
+	if opts.template || subprojectCfg.PromptTemplate {
+		// Parse errors surface now; each generation renders its own prompt
+		if spec.PromptTemplate, err = generation.ParsePromptTemplate(promptText); err != nil {
+			return generation.Spec{}, generateTarget{}, err
+		}
+	}
+	return spec, generateTarget{
+		projectRoot:   projectRoot,
+		projectCfg:    projectCfg,
+		subprojectDir: subprojectDir,
+		historyDir:    project.ResolveHistoryDir(subprojectDir, subprojectCfg),
+	}, nil
 }
 
 func init() {
@@ -170,9 +603,29 @@ func init() {
 
 	generateCmd.Flags().StringVarP(&genOpts.prompt, "prompt", "p", "", "Prompt for generation")
 	generateCmd.Flags().StringVarP(&genOpts.promptFile, "prompt-file", "F", "", "Path to text file containing prompt")
+	generateCmd.Flags().StringVar(&genOpts.promptName, "prompt-name", "", "Name of a prompt in the subproject's prompts/ library")
 	generateCmd.Flags().StringVar(&genOpts.aspect, "aspect", "", "Output image aspect ratio (e.g., 1:1, 16:9)")
 	generateCmd.Flags().StringVar(&genOpts.size, "size", "", "Output image size (1K / 2K / 4K)")
+	generateCmd.Flags().StringVar(&genOpts.format, "format", "", "Convert outputs to png, jpeg or webp when saving (overrides output_format)")
+	generateCmd.Flags().StringVar(&genOpts.model, "model", "", "Model for this run instead of banago.yaml's (recorded in history)")
+	generateCmd.Flags().BoolVar(&genOpts.dryRun, "dry-run", false, "Print the request that would be sent and its estimated cost without calling the API")
+	generateCmd.Flags().BoolVarP(&genOpts.yes, "yes", "y", false, "Skip the confirm_before_generate prompt")
+	generateCmd.Flags().BoolVarP(&genOpts.quiet, "quiet", "q", false, "Print only the new entry IDs, one per line")
+	generateCmd.Flags().BoolVar(&genOpts.wait, "wait", false, "Wait for other banago processes writing to the subproject instead of failing")
+	generateCmd.Flags().DurationVar(&genTimeout, "timeout", 0, "Stop the run after this long, e.g. 5m (overrides timeout in banago.yaml; 0 = no limit)")
+	generateCmd.Flags().StringArrayVar(&genOpts.inputs, "input", nil, "Input image for this run instead of input_images (repeatable)")
+	generateCmd.Flags().StringVar(&genOpts.inputsGlob, "inputs-glob", "", "Glob selecting input images in inputs/ for this run (e.g. 'pose-*.png')")
+	generateCmd.Flags().BoolVar(&genOpts.noInputImages, "no-input-images", false, "Generate from the prompt alone, ignoring input_images")
+	generateCmd.Flags().BoolVar(&genOpts.keepFailures, "keep-failures", false, "Keep the history entry with the error when the API call fails")
+	generateCmd.Flags().BoolVar(&genOpts.template, "template", false, "Render the prompt as a template for each generation ({{.Counter}}, {{.Date}}, choice, cycle)")
+	generateCmd.Flags().BoolVar(&genOpts.withContext, "with-context", false, "Prepend context.md and the character file to the prompt (archived in the entry)")
+	generateCmd.Flags().BoolVar(&genOpts.tokenBreakdown, "token-breakdown", false, "Count and record prompt tokens of the text and each input image (extra CountTokens calls)")
+	generateCmd.Flags().Int32Var(&genSeed, "seed", 0, "Seed for reproducible outputs (default random; recorded in history)")
+	generateCmd.Flags().IntVar(&genOpts.candidates, "candidates", 1, fmt.Sprintf("Variants returned by one API call, saved in the same entry (1-%d)", generation.MaxCandidates))
+	generateCmd.Flags().IntVar(&genOpts.count, "count", 1, "Number of independent generations (one history entry each)")
 
-	generateCmd.MarkFlagsOneRequired("prompt", "prompt-file")
-	generateCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file")
+	generateCmd.MarkFlagsMutuallyExclusive("no-input-images", "input")
+	generateCmd.MarkFlagsMutuallyExclusive("no-input-images", "inputs-glob")
+	generateCmd.MarkFlagsOneRequired("prompt", "prompt-file", "prompt-name")
+	generateCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file", "prompt-name")
 }
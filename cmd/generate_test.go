@@ -3,17 +3,27 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
 	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
 	"google.golang.org/genai"
 )
 
@@ -486,6 +496,50 @@ func TestGenerateHandler_Run_APIError(t *testing.T) {
 	assert.Empty(t, entries, "expected no history entries after error")
 }
 
+func TestGenerateHandler_Run_Timeout(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// banago.yaml's timeout applies unless --timeout overrides it
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	projectCfg.Timeout = "1h"
+	require.NoError(t, projectCfg.Save(projectRoot))
+
+	handler := &generateHandler{generator: &mockGenerator{hang: true}}
+	timeout := 50 * time.Millisecond
+	err = handler.run(context.Background(), generateOptions{
+		prompt:       "test prompt",
+		keepFailures: true,
+		timeout:      &timeout,
+	}, subprojectDir, &bytes.Buffer{})
+	require.ErrorIs(t, err, gemini.ErrCanceled)
+	assert.Contains(t, err.Error(), "timed out after 50ms")
+	assert.Equal(t, "canceled", errorCode(err))
+
+	// The canceled run leaves nothing behind, even with --keep-failures
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	projectCfg.Timeout = "50ms"
+	require.NoError(t, projectCfg.Save(projectRoot))
+	err = handler.run(context.Background(), generateOptions{prompt: "test prompt"}, subprojectDir, &bytes.Buffer{})
+	require.ErrorIs(t, err, gemini.ErrCanceled)
+}
+
 func TestGenerateHandler_Run_MultipleImages(t *testing.T) {
 	t.Parallel()
 
@@ -692,9 +746,7 @@ func TestGenerateHandler_Run_NotInSubproject(t *testing.T) {
 	assert.Contains(t, err.Error(), "not in a subproject")
 }
 
-// Scenario tests
-
-func TestScenario_MultipleGenerations(t *testing.T) {
+func TestGenerateHandler_Run_Count(t *testing.T) {
 	t.Parallel()
 
 	// Setup project
@@ -703,13 +755,11 @@ func TestScenario_MultipleGenerations(t *testing.T) {
 	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
 	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
 
-	// Configure input images
 	cfg, err := config.LoadSubprojectConfig(subprojectDir)
 	require.NoError(t, err)
 	cfg.InputImages = []string{"test.png"}
 	require.NoError(t, cfg.Save(subprojectDir))
 
-	// Create input image file
 	pngData, err := os.ReadFile("testdata/sample.png")
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
@@ -717,28 +767,981 @@ func TestScenario_MultipleGenerations(t *testing.T) {
 	mock := newSuccessMock(pngData)
 	handler := &generateHandler{generator: mock}
 
-	// Generate multiple times
-	for i := 0; i < 3; i++ {
-		var buf bytes.Buffer
-		err := handler.run(context.Background(), generateOptions{
-			prompt: "test prompt",
-		}, subprojectDir, &buf)
-		require.NoError(t, err)
-	}
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+		count:  2,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
 
-	// Verify all entries are unique
+	assert.Len(t, mock.calls, 2)
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Contains(t, buf.String(), "Aggregate token usage:")
+}
+
+func TestGenerateHandler_Run_Template(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	// Each generation of a batch renders its own counter
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt:        `variation #{{.Counter}}, {{cycle "ink" "watercolor"}}`,
+		template:      true,
+		count:         3,
+		noInputImages: true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 3)
+	assert.Equal(t, "variation #1, ink", mock.calls[0].Prompt)
+	assert.Equal(t, "variation #2, watercolor", mock.calls[1].Prompt)
+	assert.Equal(t, "variation #3, ink", mock.calls[2].Prompt)
+
+	// History keeps the rendered prompt, so regenerate repeats it exactly
 	historyDir := filepath.Join(subprojectDir, "history")
 	entries, err := history.ListEntries(historyDir)
 	require.NoError(t, err)
-	assert.Len(t, entries, 3)
+	require.Len(t, entries, 3)
+	prompt, err := history.LoadPrompt(entries[1].GetEntryDir(historyDir))
+	require.NoError(t, err)
+	assert.Equal(t, "variation #2, watercolor", prompt)
 
-	// Verify all entries have different IDs
-	ids := make(map[string]bool)
-	for _, entry := range entries {
-		assert.False(t, ids[entry.ID], "duplicate entry ID found")
-		ids[entry.ID] = true
+	// Without --template the text is sent as is
+	err = handler.run(context.Background(), generateOptions{prompt: "{{.Counter}}", noInputImages: true}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "{{.Counter}}", mock.calls[3].Prompt)
+
+	// Syntax errors fail before any API call
+	err = handler.run(context.Background(), generateOptions{prompt: "{{.Counter", template: true, noInputImages: true}, subprojectDir, &buf)
+	require.ErrorContains(t, err, "invalid prompt template")
+	assert.Len(t, mock.calls, 4)
+}
+
+func TestGenerateHandler_Run_CharacterPreset(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	characterPath := project.GetCharacterPath(projectRoot, "fox.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(characterPath), 0o755))
+	require.NoError(t, os.WriteFile(characterPath, []byte("A red fox.\n"), 0o644))
+	require.NoError(t, os.WriteFile(config.CharacterPresetPath(characterPath),
+		[]byte("aspect_ratio: \"3:4\"\nimage_size: 2K\nprompt_notes: Blue scarf, soft watercolor.\n"), 0o644))
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.CharacterFile = "fox.md"
+	cfg.ImageSize = "1K"
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	err = handler.run(context.Background(), generateOptions{prompt: "The fox jumps", noInputImages: true}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 1)
+	assert.Equal(t, "The fox jumps\n\nBlue scarf, soft watercolor.", mock.calls[0].Prompt)
+	assert.Equal(t, "3:4", mock.calls[0].AspectRatio, "the preset fills what config.yaml leaves unset")
+	assert.Equal(t, "1K", mock.calls[0].ImageSize, "config.yaml wins over the preset")
+
+	// Flags win over the preset, and regenerate does not append the notes twice
+	err = handler.run(context.Background(), generateOptions{prompt: "The fox jumps", aspect: "16:9", noInputImages: true}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 2)
+	assert.Equal(t, "16:9", mock.calls[1].AspectRatio)
+
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	err = (&regenerateHandler{generator: mock}).run(context.Background(), regenerateOptions{id: entries[0].ID}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 3)
+	assert.Equal(t, mock.calls[0].Prompt, mock.calls[2].Prompt)
+	assert.Equal(t, "3:4", mock.calls[2].AspectRatio)
+}
+
+func TestGenerateHandler_Run_WithContext(t *testing.T) {
+	t.Parallel()
+
+	// Setup project with project context, subproject context and a character file
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	require.NoError(t, os.WriteFile(project.GetProjectContextPath(projectRoot), []byte("Picture book series.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(subprojectDir, "context.md"), []byte("Autumn forest.\n"), 0o644))
+	characterPath := project.GetCharacterPath(projectRoot, "fox.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(characterPath), 0o755))
+	require.NoError(t, os.WriteFile(characterPath, []byte("A red fox with a blue scarf.\n"), 0o644))
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.CharacterFile = "fox.md"
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt:        "The fox jumps",
+		withContext:   true,
+		noInputImages: true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 1)
+	assert.Equal(t, "Picture book series.\n\nAutumn forest.\n\nA red fox with a blue scarf.\n\nThe fox jumps", mock.calls[0].Prompt)
+
+	// The entry keeps the prompt as given and archives the files even though archive is off
+	historyDir := filepath.Join(subprojectDir, "history")
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entryDir := entries[0].GetEntryDir(historyDir)
+	prompt, err := history.LoadPrompt(entryDir)
+	require.NoError(t, err)
+	assert.Equal(t, "The fox jumps", prompt)
+	assert.Equal(t, history.ContextFile, entries[0].Generation.ContextFile)
+	assert.Equal(t, history.CharacterFile, entries[0].Generation.CharacterFile)
+	require.NotNil(t, entries[0].Generation.Config)
+	assert.True(t, entries[0].Generation.Config.IncludeContext)
+
+	// Regenerate sends the archived context, not the edited files
+	require.NoError(t, os.WriteFile(filepath.Join(subprojectDir, "context.md"), []byte("Winter night.\n"), 0o644))
+	regenHandler := &regenerateHandler{generator: mock}
+	err = regenHandler.run(context.Background(), regenerateOptions{id: entries[0].ID}, subprojectDir, &buf)
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 2)
+	assert.Equal(t, mock.calls[0].Prompt, mock.calls[1].Prompt)
+
+	// Without the option only the prompt is sent
+	err = handler.run(context.Background(), generateOptions{prompt: "The fox jumps", noInputImages: true}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "The fox jumps", mock.calls[2].Prompt)
+}
+
+func TestGenerateHandler_Run_PromptName(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	require.NoError(t, project.SavePrompt(subprojectDir, "sunset", "The castle at sunset", false))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{promptName: "sunset", noInputImages: true}, subprojectDir, &buf)
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 1)
+	assert.Equal(t, "The castle at sunset", mock.calls[0].Prompt)
+
+	err = handler.run(context.Background(), generateOptions{promptName: "missing", noInputImages: true}, subprojectDir, &buf)
+	require.ErrorIs(t, err, project.ErrPromptNotFound)
+	assert.Len(t, mock.calls, 1)
+}
+
+func TestGenerateHandler_Run_JSON(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+		count:  2,
+		json:   true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	// Only the JSON document is written
+	var out generationJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out.Entries, 2)
+	for _, e := range out.Entries {
+		assert.NotEmpty(t, e.ID)
+		assert.True(t, e.Success)
+		require.NotEmpty(t, e.Outputs)
+		assert.True(t, filepath.IsAbs(e.Outputs[0]))
+		assert.FileExists(t, e.Outputs[0])
 	}
+	assert.Equal(t, out.Entries[0].TokenUsage.Total*2, out.TokenUsage.Total)
+}
 
-	// Verify mock was called 3 times
-	assert.Equal(t, 3, mock.callCount())
+func TestGenerateHandler_Run_Quiet(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+		count:  2,
+		quiet:  true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	// Only the entry IDs are written, one per line
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	assert.ElementsMatch(t, ids, strings.Fields(buf.String()))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
+
+func TestGenerateHandler_Run_WarnsUnreferencedInputs(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "new-pose.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	// Generation proceeds with the configured inputs
+	assert.Contains(t, buf.String(), "Warning: input_images and inputs/ differ (not in input_images: new-pose.png)")
+	require.Len(t, mock.calls, 1)
+	assert.Len(t, mock.calls[0].ImagePaths, 1)
+}
+
+func TestGenerateHandler_Run_Events(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+		count:  2,
+		quiet:  true, // events take precedence
+		events: true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	// Every line is an event; the text output is discarded
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e generation.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e), "line: %s", line)
+		types = append(types, e.Type)
+	}
+	one := []string{generation.EventValidation, generation.EventRequestSent, generation.EventImageSaved, generation.EventEntryCreated}
+	assert.Equal(t, append(one, one...), types)
+}
+
+func TestGenerateHandler_Run_ConfirmBeforeGenerate(t *testing.T) {
+	t.Parallel()
+
+	// Setup project with confirmation enabled
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	projectCfg.ConfirmBeforeGenerate = true
+	require.NoError(t, projectCfg.Save(projectRoot))
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	tests := []struct {
+		name      string
+		stdin     string
+		yes       bool
+		wantCalls int
+		wantErr   bool
+		wantShown bool
+	}{
+		{"declined", "n\n", false, 0, true, true},
+		{"accepted", "y\n", false, 1, false, true},
+		{"skipped with yes", "", true, 1, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newSuccessMock(pngData)
+			handler := &generateHandler{generator: mock, stdin: strings.NewReader(tt.stdin)}
+
+			var buf bytes.Buffer
+			err := handler.run(context.Background(), generateOptions{
+				prompt: "test prompt",
+				size:   "2K",
+				yes:    tt.yes,
+			}, subprojectDir, &buf)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "aborted")
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Len(t, mock.calls, tt.wantCalls)
+
+			output := buf.String()
+			if tt.wantShown {
+				assert.Contains(t, output, "Prompt:  11 characters")
+				assert.Contains(t, output, "Inputs:  1 images")
+				assert.Contains(t, output, "size 2K")
+				assert.Contains(t, output, "Estimated cost: ~$0.136")
+			} else {
+				assert.NotContains(t, output, "Estimated cost")
+			}
+		})
+	}
+}
+
+func TestGenerateHandler_Run_ReadOnlyProject(t *testing.T) {
+	t.Parallel()
+
+	// Setup read-only project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	projectCfg.ReadOnly = true
+	require.NoError(t, projectCfg.Save(projectRoot))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+	}, subprojectDir, &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readonly: true")
+
+	// Verify API was not called and nothing was written
+	assert.Empty(t, mock.calls)
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGenerateHandler_Run_DryRun(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	cfg.AspectRatio = "16:9"
+	require.NoError(t, cfg.Save(subprojectDir))
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// A dry run neither needs a client nor writes, so read-only projects and confirmations pass
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	projectCfg.ReadOnly = true
+	projectCfg.ConfirmBeforeGenerate = true
+	require.NoError(t, projectCfg.Save(projectRoot))
+
+	handler := &generateHandler{}
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "a cat\non a roof",
+		size:   "2K",
+		count:  3,
+		dryRun: true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "Model:       "+projectCfg.Model)
+	assert.Contains(t, output, "Aspect:      16:9")
+	assert.Contains(t, output, "Size:        2K")
+	assert.Contains(t, output, "Seed:        random")
+	assert.Contains(t, output, "Count:       3 generations")
+	assert.Contains(t, output, filepath.Join(project.GetInputsDir(subprojectDir), "test.png"))
+	assert.Contains(t, output, "    a cat\n    on a roof\n")
+	assert.Contains(t, output, "Estimate:")
+	assert.Contains(t, output, "the API was not called")
+
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// JSON carries the same request
+	var seed int32 = 7
+	buf.Reset()
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "a cat",
+		seed:   &seed,
+		dryRun: true,
+		json:   true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	var out dryRunJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.True(t, out.DryRun)
+	require.Len(t, out.Requests, 1)
+	req := out.Requests[0]
+	assert.Equal(t, "a cat", req.Prompt)
+	assert.Equal(t, "16:9", req.AspectRatio)
+	require.NotNil(t, req.Seed)
+	assert.Equal(t, seed, *req.Seed)
+	assert.Equal(t, 1, req.Count)
+	assert.Len(t, req.InputImages, 1)
+	assert.Positive(t, req.EstimatedOutputTokens)
+}
+
+func TestGenerateHandler_Run_DryRunTemplateCount(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	// Each generation renders the template for the entry it would create
+	handler := &generateHandler{}
+	var buf bytes.Buffer
+	err := handler.run(context.Background(), generateOptions{
+		prompt:        "pose {{.Counter}}",
+		template:      true,
+		noInputImages: true,
+		count:         3,
+		dryRun:        true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+	for i := 1; i <= 3; i++ {
+		assert.Contains(t, output, fmt.Sprintf("Request for generation %d of 3:", i))
+		assert.Contains(t, output, fmt.Sprintf("    pose %d\n", i))
+	}
+	assert.NotContains(t, output, "Count:")
+
+	// Seeds advance per generation like in the real batch
+	var seed int32 = 7
+	buf.Reset()
+	err = handler.run(context.Background(), generateOptions{
+		prompt:        "pose {{.Counter}}",
+		template:      true,
+		noInputImages: true,
+		seed:          &seed,
+		count:         2,
+		dryRun:        true,
+		json:          true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	var out dryRunJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out.Requests, 2)
+	for i, req := range out.Requests {
+		assert.Equal(t, i+1, req.Generation)
+		assert.Equal(t, fmt.Sprintf("pose %d", i+1), req.Prompt)
+		assert.Equal(t, 1, req.Count)
+		require.NotNil(t, req.Seed)
+		assert.Equal(t, seed+int32(i), *req.Seed)
+	}
+
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGenerateHandler_Run_SubprojectLocked(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// Another process is writing to the subproject
+	unlock, err := project.LockSubproject(context.Background(), subprojectDir, false)
+	require.NoError(t, err)
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{prompt: "test prompt"}, subprojectDir, &buf)
+	require.ErrorIs(t, err, project.ErrSubprojectLocked)
+	assert.Contains(t, err.Error(), "--wait")
+	assert.Empty(t, mock.calls)
+
+	// --wait queues until the lock is released
+	done := make(chan error, 1)
+	var waitBuf bytes.Buffer
+	go func() {
+		done <- handler.run(context.Background(), generateOptions{prompt: "test prompt", wait: true}, subprojectDir, &waitBuf)
+	}()
+	time.Sleep(300 * time.Millisecond)
+	unlock()
+	require.NoError(t, <-done)
+	assert.Contains(t, waitBuf.String(), "Waiting for another banago process")
+
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestGenerateHandler_Run_InsideHistoryDir(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "old prompt")
+
+	mock := newSuccessMock(nil)
+	handler := &generateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err := handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+	}, entry.GetEntryDir(historyDir), &buf)
+	require.ErrorIs(t, err, project.ErrInsideArchiveDir)
+	assert.Contains(t, err.Error(), subprojectDir)
+	assert.Empty(t, mock.calls)
+}
+
+func TestGenerateHandler_Run_CustomHistoryDir(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	projectCfg.HistoryLayout = history.LayoutNested
+	require.NoError(t, projectCfg.Save(projectRoot))
+
+	// Point history_dir to external storage
+	externalDir := filepath.Join(t.TempDir(), "nas", "history")
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	cfg.HistoryDir = externalDir
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	// Create input image file
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	handler := &generateHandler{generator: newSuccessMock(pngData)}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), generateOptions{
+		prompt: "test prompt",
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	// Verify entry was written to the configured directory only, in the project's layout
+	entries, err := history.ListEntries(externalDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, history.LayoutNested, entries[0].Layout())
+	assert.FileExists(t, entries[0].OutputPath(externalDir, entries[0].Result.OutputImages[0]))
+
+	defaultEntries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, defaultEntries)
+}
+
+// Scenario tests
+
+func TestScenario_MultipleGenerations(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	// Configure input images
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	// Create input image file
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &generateHandler{generator: mock}
+
+	// Generate multiple times
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		err := handler.run(context.Background(), generateOptions{
+			prompt: "test prompt",
+		}, subprojectDir, &buf)
+		require.NoError(t, err)
+	}
+
+	// Verify all entries are unique
+	historyDir := filepath.Join(subprojectDir, "history")
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// Verify all entries have different IDs
+	ids := make(map[string]bool)
+	for _, entry := range entries {
+		assert.False(t, ids[entry.ID], "duplicate entry ID found")
+		ids[entry.ID] = true
+	}
+
+	// Verify mock was called 3 times
+	assert.Equal(t, 3, mock.callCount())
+}
+
+func TestGenerateHandler_Run_TextOnly(t *testing.T) {
+	t.Parallel()
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	setup := func(t *testing.T) string {
+		t.Helper()
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		return project.GetSubprojectDir(projectRoot, "test-sub")
+	}
+
+	t.Run("requires inputs by default", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		err := handler.run(context.Background(), generateOptions{prompt: "a castle"}, subprojectDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--no-input-images")
+		assert.Empty(t, mock.calls)
+	})
+
+	t.Run("flag ignores configured inputs", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+
+		cfg, err := config.LoadSubprojectConfig(subprojectDir)
+		require.NoError(t, err)
+		cfg.InputImages = []string{"test.png"}
+		require.NoError(t, cfg.Save(subprojectDir))
+		require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		require.NoError(t, handler.run(context.Background(), generateOptions{
+			prompt:        "a castle",
+			noInputImages: true,
+		}, subprojectDir, &bytes.Buffer{}))
+		assert.Empty(t, mock.lastCall().ImagePaths)
+
+		entry, err := history.GetLatestEntry(filepath.Join(subprojectDir, "history"))
+		require.NoError(t, err)
+		assert.True(t, entry.Generation.TextOnly)
+		assert.Empty(t, entry.Generation.InputImages)
+
+		// A text-only entry can be regenerated
+		regenMock := newSuccessMock(pngData)
+		regenHandler := &regenerateHandler{generator: regenMock}
+		require.NoError(t, regenHandler.run(context.Background(), regenerateOptions{id: entry.ID}, subprojectDir, &bytes.Buffer{}))
+		assert.Equal(t, "a castle", regenMock.lastCall().Prompt)
+		assert.Empty(t, regenMock.lastCall().ImagePaths)
+	})
+
+	t.Run("config allows text-only", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+
+		cfg, err := config.LoadSubprojectConfig(subprojectDir)
+		require.NoError(t, err)
+		cfg.AllowTextOnly = true
+		require.NoError(t, cfg.Save(subprojectDir))
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		require.NoError(t, handler.run(context.Background(), generateOptions{prompt: "a castle"}, subprojectDir, &bytes.Buffer{}))
+		assert.Len(t, mock.calls, 1)
+	})
+}
+
+func TestGenerateHandler_Run_InputOverride(t *testing.T) {
+	t.Parallel()
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	setup := func(t *testing.T) string {
+		t.Helper()
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+		cfg, err := config.LoadSubprojectConfig(subprojectDir)
+		require.NoError(t, err)
+		cfg.InputImages = []string{"base.png"}
+		require.NoError(t, cfg.Save(subprojectDir))
+		for _, name := range []string{"base.png", "pose-a.png", "pose-b.png"} {
+			require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), name), pngData, 0o644))
+		}
+		return subprojectDir
+	}
+
+	t.Run("glob selects a subset of inputs", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		require.NoError(t, handler.run(context.Background(), generateOptions{
+			prompt:     "a castle",
+			inputsGlob: "pose-*.png",
+		}, subprojectDir, &bytes.Buffer{}))
+		assert.Len(t, mock.lastCall().ImagePaths, 2)
+
+		entry, err := history.GetLatestEntry(filepath.Join(subprojectDir, "history"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pose-a.png", "pose-b.png"}, entry.Generation.InputImages)
+		assert.FileExists(t, history.ResolveEntryFile(entry.GetEntryDir(filepath.Join(subprojectDir, "history")), "pose-a.png"))
+	})
+
+	t.Run("ad-hoc file is snapshotted", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+		adHoc := filepath.Join(t.TempDir(), "sketch.png")
+		require.NoError(t, os.WriteFile(adHoc, pngData, 0o644))
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		require.NoError(t, handler.run(context.Background(), generateOptions{
+			prompt: "a castle",
+			inputs: []string{adHoc, "inputs/base.png"},
+		}, subprojectDir, &bytes.Buffer{}))
+
+		entry, err := history.GetLatestEntry(filepath.Join(subprojectDir, "history"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sketch.png", "base.png"}, entry.Generation.InputImages)
+		assert.FileExists(t, history.ResolveEntryFile(entry.GetEntryDir(filepath.Join(subprojectDir, "history")), "sketch.png"))
+	})
+
+	t.Run("duplicate filenames are rejected", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+		adHoc := filepath.Join(t.TempDir(), "base.png")
+		require.NoError(t, os.WriteFile(adHoc, pngData, 0o644))
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		err := handler.run(context.Background(), generateOptions{
+			prompt: "a castle",
+			inputs: []string{adHoc, "inputs/base.png"},
+		}, subprojectDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "same filename")
+		assert.Empty(t, mock.calls)
+	})
+
+	t.Run("glob without matches fails", func(t *testing.T) {
+		t.Parallel()
+		subprojectDir := setup(t)
+
+		mock := newSuccessMock(pngData)
+		handler := &generateHandler{generator: mock}
+		err := handler.run(context.Background(), generateOptions{
+			prompt:     "a castle",
+			inputsGlob: "missing-*.png",
+		}, subprojectDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "matched no files")
+	})
+}
+
+func TestGenerateHandler_Run_OutputFormat(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	// A gradient with transparency exercises every channel of the conversion
+	src := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	for y := range 21 {
+		for x := range 37 {
+			src.Set(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 12), B: uint8(x * y), A: uint8(255 - x)})
+		}
+	}
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, src))
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.OutputFormat = "jpeg"
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	latestOutput := func(t *testing.T) string {
+		t.Helper()
+		entry, err := history.GetLatestEntry(historyDir)
+		require.NoError(t, err)
+		require.Len(t, entry.Result.OutputImages, 1)
+		return history.GetEntryFilePath(entry.GetEntryDir(historyDir), entry.Result.OutputImages[0])
+	}
+
+	handler := &generateHandler{generator: newSuccessMock(pngData.Bytes())}
+	require.NoError(t, handler.run(context.Background(), generateOptions{prompt: "a gradient", noInputImages: true}, subprojectDir, &bytes.Buffer{}))
+	output := latestOutput(t)
+	assert.Equal(t, ".jpg", filepath.Ext(output), "output_format of config.yaml applies")
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	_, err = jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// --format wins over config.yaml; WebP is lossless, so every pixel survives
+	require.NoError(t, handler.run(context.Background(), generateOptions{prompt: "a gradient", format: "webp", noInputImages: true}, subprojectDir, &bytes.Buffer{}))
+	output = latestOutput(t)
+	assert.Equal(t, ".webp", filepath.Ext(output))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	got, err := webp.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, src.Bounds(), got.Bounds())
+	for y := range 21 {
+		for x := range 37 {
+			require.Equal(t, src.NRGBAAt(x, y), color.NRGBAModel.Convert(got.At(x, y)), "pixel %d,%d", x, y)
+		}
+	}
+
+	err = handler.run(context.Background(), generateOptions{prompt: "a gradient", format: "tiff", noInputImages: true}, subprojectDir, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output format")
 }
@@ -3,23 +3,58 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/spf13/cobra"
 )
 
 var historyOpts struct {
-	limit int
+	entryQuery
+	limit  int
+	paths  bool
+	byDay  bool
+	thumbs bool
+}
+
+// entryQuery holds the flags that filter history entries, shared by history and the bulk tag and rate commands
+type entryQuery struct {
+	search     string
+	since      string
+	until      string
+	failedOnly bool
+	tag        string
+	minRating  int
 }
 
 var historyCmd = &cobra.Command{
 	Use:   "history",
 	Short: "Show generation history",
-	Long:  "Display the generation history of the current subproject.",
-	Args:  cobra.NoArgs,
+	Long: `Display the generation history of the current subproject.
+
+--since and --until accept a date (2026-01-31), an RFC3339 timestamp or a
+relative age such as 7d or 12h. --until includes the whole given day.
+Dates are shown in local time, formatted for the locale of LC_TIME or LANG;
+--by-day groups the entries under their local calendar day.
+--thumbs draws the first output of each entry in terminals that show images
+(iTerm2, WezTerm, kitty, Ghostty); BANAGO_THUMBS=iterm|kitty|off overrides the detection.
+
+Examples:
+  banago history --search knight
+  banago history --by-day --limit 50
+  banago history --thumbs --limit 20
+  banago history --since 7d --failed-only
+  banago history --tag hero --min-rating 4
+  banago history --since 2026-01-01 --until 2026-01-31`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -42,16 +77,44 @@ var historyCmd = &cobra.Command{
 			return err
 		}
 
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+
 		subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
-		historyDir := history.GetHistoryDir(subprojectDir)
+		subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+		if err != nil {
+			return fmt.Errorf("failed to load subproject config: %w", err)
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
 
-		entries, err := history.ListEntries(historyDir)
+		now := time.Now()
+		predicates, err := historyOpts.predicates(now)
+		if err != nil {
+			return err
+		}
+
+		entries, err := history.SearchEntries(historyDir, predicates...)
 		if err != nil {
 			return fmt.Errorf("failed to load history: %w", err)
 		}
 
 		w := cmd.OutOrStdout()
 
+		if jsonOutput() {
+			return printHistoryJSON(w, historyDir, entries, historyOpts.limit)
+		}
+
+		if historyOpts.paths {
+			return printOutputPaths(w, historyDir, entries, historyOpts.limit)
+		}
+
+		if len(entries) == 0 && len(predicates) > 0 {
+			_, _ = fmt.Fprintln(w, "No matching history entries")
+			return nil
+		}
+
 		if len(entries) == 0 {
 			_, _ = fmt.Fprintln(w, "No history found")
 			_, _ = fmt.Fprintln(w, "")
@@ -60,39 +123,72 @@ var historyCmd = &cobra.Command{
 			return nil
 		}
 
-		_, _ = fmt.Fprintf(w, "History (%d entries):\n", len(entries))
+		if len(predicates) > 0 {
+			_, _ = fmt.Fprintf(w, "History (%d matching entries):\n", len(entries))
+		} else {
+			_, _ = fmt.Fprintf(w, "History (%d entries):\n", len(entries))
+		}
 		_, _ = fmt.Fprintln(w, "")
 
+		thumbs := thumbsNone
+		if historyOpts.thumbs {
+			if isTerminal(w) || os.Getenv("BANAGO_THUMBS") != "" {
+				thumbs = detectThumbsProtocol(os.Getenv)
+			}
+			if thumbs == thumbsNone {
+				_, _ = fmt.Fprintf(w, "%s\n\n", thumbsHint)
+			}
+		}
+
 		// Show entries in reverse order (newest first)
 		start := 0
 		if historyOpts.limit > 0 && historyOpts.limit < len(entries) {
 			start = len(entries) - historyOpts.limit
 		}
 
+		day := ""
 		for i := len(entries) - 1; i >= start; i-- {
 			entry := entries[i]
+			if historyOpts.byDay {
+				if d := history.LocalDay(entry.CreatedAt); d != day {
+					day = d
+					_, _ = fmt.Fprintf(w, "%s\n\n", formatDayHeading(day))
+				}
+			}
 			status := "✓"
 			if !entry.Result.Success {
 				status = "✗"
 			}
 			_, _ = fmt.Fprintf(w, "  %s %s\n", status, entry.ID)
-			_, _ = fmt.Fprintf(w, "      Date: %s\n", entry.CreatedAt)
+			if thumbs != thumbsNone && entry.Result.Success && len(entry.Result.OutputImages) > 0 {
+				// An output that cannot be decoded (e.g. WebP) just has no thumbnail
+				path := entry.OutputPath(historyDir, entry.Result.OutputImages[0])
+				_ = writeThumbnail(w, thumbs, path, "      ")
+			}
+			_, _ = fmt.Fprintf(w, "      Date: %s\n", history.FormatLocal(entry.CreatedAt, now))
 			if entry.Result.Success && len(entry.Result.OutputImages) > 0 {
 				_, _ = fmt.Fprintf(w, "      Output: %d images\n", len(entry.Result.OutputImages))
 			}
+			if len(entry.Tags) > 0 {
+				_, _ = fmt.Fprintf(w, "      Tags: %s\n", strings.Join(entry.Tags, ", "))
+			}
+			if entry.Rating > 0 {
+				_, _ = fmt.Fprintf(w, "      Rating: %d/%d\n", entry.Rating, history.MaxRating)
+			}
 
 			// List edits
-			entryDir := filepath.Join(historyDir, entry.ID)
+			entryDir := entry.GetEntryDir(historyDir)
 			edits, _ := history.ListEditEntries(entryDir)
 			if len(edits) > 0 {
-				_, _ = fmt.Fprintf(w, "      Edits:\n")
+				stats := history.ComputeEditStats(entryDir)
+				_, _ = fmt.Fprintf(w, "      Edits: %s\n", formatEditStats(stats, projectCfg.Model))
 				for _, edit := range edits {
 					editStatus := "✓"
 					if !edit.Result.Success {
 						editStatus = "✗"
 					}
 					_, _ = fmt.Fprintf(w, "        %s %s\n", editStatus, edit.ID)
-					_, _ = fmt.Fprintf(w, "            Date: %s\n", edit.CreatedAt)
+					_, _ = fmt.Fprintf(w, "            Date: %s\n", history.FormatLocal(edit.CreatedAt, now))
 				}
 			}
 
@@ -106,8 +202,142 @@ var historyCmd = &cobra.Command{
 	},
 }
 
+// formatDayHeading returns the heading of a local calendar day (YYYY-MM-DD) in --by-day output
+func formatDayHeading(day string) string {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return "Unknown date"
+	}
+	return t.Format("2006-01-02 (Monday)")
+}
+
+// addEntryQueryFlags registers the filter flags of q on cmd; verb completes their help ("show", "select")
+func addEntryQueryFlags(cmd *cobra.Command, q *entryQuery, verb string) {
+	cmd.Flags().StringVar(&q.search, "search", "", fmt.Sprintf("Only %s entries whose prompt contains this text (case-insensitive)", verb))
+	cmd.Flags().StringVar(&q.since, "since", "", fmt.Sprintf("Only %s entries created at or after this date, timestamp or age (e.g. 7d)", verb))
+	cmd.Flags().StringVar(&q.until, "until", "", fmt.Sprintf("Only %s entries created up to this date, timestamp or age", verb))
+	cmd.Flags().BoolVar(&q.failedOnly, "failed-only", false, fmt.Sprintf("Only %s failed generations", verb))
+	cmd.Flags().StringVar(&q.tag, "tag", "", fmt.Sprintf("Only %s entries with this tag", verb))
+	cmd.Flags().IntVar(&q.minRating, "min-rating", 0, fmt.Sprintf("Only %s entries rated at least this (1-%d)", verb, history.MaxRating))
+}
+
+// predicates builds the entry filters from the query flags
+func (q entryQuery) predicates(now time.Time) ([]history.Predicate, error) {
+	var predicates []history.Predicate
+	if q.search != "" {
+		predicates = append(predicates, history.PromptContains(q.search))
+	}
+	if q.since != "" {
+		since, _, err := parseTimeFlag(q.since, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+		predicates = append(predicates, history.CreatedSince(since))
+	}
+	if q.until != "" {
+		until, precision, err := parseTimeFlag(q.until, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+		predicates = append(predicates, history.CreatedBefore(until.Add(precision)))
+	}
+	if q.failedOnly {
+		predicates = append(predicates, history.Failed())
+	}
+	if q.tag != "" {
+		predicates = append(predicates, history.HasTag(q.tag))
+	}
+	if q.minRating < 0 || q.minRating > history.MaxRating {
+		return nil, fmt.Errorf("invalid --min-rating %d: must be 1 to %d", q.minRating, history.MaxRating)
+	}
+	if q.minRating > 0 {
+		predicates = append(predicates, history.RatedAtLeast(q.minRating))
+	}
+	return predicates, nil
+}
+
+// parseTimeFlag parses a date, an RFC3339 timestamp or a relative age (7d, 12h) into a point in time.
+// precision is the span the value covers (a day for dates), used to make upper bounds inclusive.
+func parseTimeFlag(value string, now time.Time) (t time.Time, precision time.Duration, err error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, time.Second, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, 24 * time.Hour, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), time.Second, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), time.Second, nil
+	}
+	return time.Time{}, 0, fmt.Errorf("%q is not a date (YYYY-MM-DD), RFC3339 timestamp or age like 7d", value)
+}
+
+// formatEditStats summarizes edit totals as "N (T tokens, ~$C, last DATE)"
+func formatEditStats(stats history.EditStats, model string) string {
+	parts := []string{fmt.Sprintf("%d tokens", stats.TokenUsage.Total)}
+	if cost, ok := gemini.EstimateCost(model, stats.TokenUsage); ok {
+		parts = append(parts, fmt.Sprintf("~$%.2f", cost))
+	}
+	if stats.LastEditAt != "" {
+		parts = append(parts, "last "+stats.LastEditAt)
+	}
+	return fmt.Sprintf("%d (%s)", stats.Count, strings.Join(parts, ", "))
+}
+
+// printOutputPaths prints absolute paths of output images one per line (newest entry first).
+// The output is intended for piping into other tools, so no headers are printed.
+func printOutputPaths(w io.Writer, historyDir string, entries []*history.Entry, limit int) error {
+	absHistoryDir, err := filepath.Abs(historyDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve history directory: %w", err)
+	}
+
+	start := 0
+	if limit > 0 && limit < len(entries) {
+		start = len(entries) - limit
+	}
+
+	for i := len(entries) - 1; i >= start; i-- {
+		entry := entries[i]
+		if !entry.Result.Success {
+			continue
+		}
+		for _, img := range entry.Result.OutputImages {
+			_, _ = fmt.Fprintln(w, entry.OutputPath(absHistoryDir, img))
+		}
+	}
+	return nil
+}
+
+// printHistoryJSON prints entries (newest first) with their edits as a JSON array
+func printHistoryJSON(w io.Writer, historyDir string, entries []*history.Entry, limit int) error {
+	start := 0
+	if limit > 0 && limit < len(entries) {
+		start = len(entries) - limit
+	}
+
+	out := []entryJSON{}
+	for i := len(entries) - 1; i >= start; i-- {
+		e := newEntryJSON(historyDir, entries[i])
+		edits, _ := history.ListEditEntries(e.Dir)
+		for _, edit := range edits {
+			e.Edits = append(e.Edits, newEditJSON(e.Dir, edit))
+		}
+		out = append(out, e)
+	}
+	return writeJSON(w, out)
+}
+
 func init() {
 	rootCmd.AddCommand(historyCmd)
 
 	historyCmd.Flags().IntVar(&historyOpts.limit, "limit", 10, "Number of history entries to show")
+	historyCmd.Flags().BoolVar(&historyOpts.paths, "paths", false, "Print absolute paths of output images, one per line")
+	addEntryQueryFlags(historyCmd, &historyOpts.entryQuery, "show")
+	historyCmd.Flags().BoolVar(&historyOpts.byDay, "by-day", false, "Group entries under their local calendar day")
+	historyCmd.Flags().BoolVar(&historyOpts.thumbs, "thumbs", false, "Draw a thumbnail of each entry in terminals that show images")
 }
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var importEntryOpts struct {
+	id         string
+	subproject string
+}
+
+var importEntryCmd = &cobra.Command{
+	Use:   "import-entry <project-path>",
+	Short: "Import a history entry from another project",
+	Long: `Copy a history entry from another banago project into the current subproject.
+
+The prompt, input images and outputs are copied into a new entry, which
+records the source project, subproject and entry ID as lineage. Edits of the
+source entry are not copied. The source project is never modified.
+
+Examples:
+  banago import-entry ../shared-project --id <uuid>
+  banago import-entry ../shared-project --id <uuid> --subproject forest`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+
+		srcRoot, err := project.FindProjectRoot(args[0])
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return fmt.Errorf("banago project not found at %s", args[0])
+			}
+			return err
+		}
+		srcCfg, err := config.LoadProjectConfig(srcRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load source project config: %w", err)
+		}
+
+		subprojectName, srcHistoryDir, err := findEntrySubproject(srcRoot, importEntryOpts.subproject, importEntryOpts.id)
+		if err != nil {
+			return err
+		}
+
+		entry, err := history.ImportEntry(srcHistoryDir, importEntryOpts.id, historyDir, hist.layout, history.ImportSource{
+			Project:    srcCfg.Name,
+			Subproject: subprojectName,
+			EntryID:    importEntryOpts.id,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import entry: %w", err)
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Imported %s/%s/%s as %s\n", srcCfg.Name, subprojectName, importEntryOpts.id, entry.ID)
+		_, _ = fmt.Fprintf(w, "  Output: %d images\n", len(entry.Result.OutputImages))
+
+		return nil
+	},
+}
+
+// findEntrySubproject returns the subproject name and history directory holding the entry.
+// When subprojectName is empty, all subprojects of the project are searched.
+func findEntrySubproject(projectRoot, subprojectName, id string) (string, string, error) {
+	var names []string
+	if subprojectName != "" {
+		names = []string{subprojectName}
+	} else {
+		infos, err := project.ListSubprojectInfos(projectRoot)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to list subprojects: %w", err)
+		}
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+	}
+
+	for _, name := range names {
+		subprojectDir := project.GetSubprojectDir(projectRoot, name)
+		subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load subproject config: %w", err)
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+		_, err = history.GetEntryByID(historyDir, id)
+		if err == nil {
+			return name, historyDir, nil
+		}
+		if !errors.Is(err, history.ErrEntryNotFound) {
+			return "", "", fmt.Errorf("failed to get history entry: %w", err)
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: %s in %s", history.ErrEntryNotFound, id, projectRoot)
+}
+
+var importOpts struct {
+	verifyKey string
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import the entries of an export bundle",
+	Long: `Recreate the entries of a bundle written by 'banago export --entries' in the
+current subproject. The bundle can be the zip archive or the directory.
+
+Every file is checked against the SHA-256 recorded in the manifest before
+anything is imported; bundles with changed, missing or extra files are
+refused. With --verify-key the manifest must also carry a signature made with
+that key (export --sign-key). New entries record the bundle's project,
+subproject and entry ID under lineage, like import-entry.
+
+Examples:
+  banago import handoff.zip
+  banago import handoff.zip --verify-key studio.key`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+		var key []byte
+		if importOpts.verifyKey != "" {
+			if key, err = readBundleKey(importOpts.verifyKey); err != nil {
+				return err
+			}
+		}
+
+		fsys, closeBundle, err := openBundle(args[0])
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closeBundle() }()
+		manifest, signed, err := verifyBundle(fsys, key)
+		if err != nil {
+			return err
+		}
+		ids, err := bundleEntryIDs(manifest)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return errors.New("bundle has no entries to import; export it with --entries")
+		}
+
+		projectRoot, projectCfg, err := currentProject()
+		if err != nil {
+			return err
+		}
+		workspace, err := openWorkspace(projectRoot, projectCfg)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = workspace.Close() }()
+		if err := extractBundleEntries(fsys, manifest, workspace.Dir); err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		switch {
+		case key != nil:
+			_, _ = fmt.Fprintln(w, "Verified checksums and signature")
+		case signed:
+			_, _ = fmt.Fprintln(w, "Verified checksums; signature not checked (pass --verify-key)")
+		default:
+			_, _ = fmt.Fprintln(w, "Verified checksums; bundle is not signed")
+		}
+		for _, id := range ids {
+			entry, err := history.ImportEntry(workspace.Dir, id, historyDir, hist.layout, history.ImportSource{
+				Project:    manifest.Project,
+				Subproject: manifest.Subproject,
+				EntryID:    id,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import entry %s: %w", id, err)
+			}
+			_, _ = fmt.Fprintf(w, "  Imported %s as %s (%d images)\n", id, entry.ID, len(entry.Result.OutputImages))
+		}
+		_, _ = fmt.Fprintf(w, "Imported %d entries from %s/%s\n", len(ids), manifest.Project, manifest.Subproject)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importOpts.verifyKey, "verify-key", "", "Require a manifest signature made with the key in this file")
+
+	rootCmd.AddCommand(importEntryCmd)
+
+	importEntryCmd.Flags().StringVar(&importEntryOpts.id, "id", "", "History entry ID in the source project")
+	importEntryCmd.Flags().StringVar(&importEntryOpts.subproject, "subproject", "", "Source subproject (default: search all)")
+
+	_ = importEntryCmd.MarkFlagRequired("id")
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/spf13/cobra"
 )
@@ -34,6 +35,15 @@ The following files and directories will be created:
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
+		// Overwriting an existing read-only project is a modification too
+		var existingCfg *config.ProjectConfig
+		if config.ProjectConfigExists(cwd) {
+			existingCfg, _ = config.LoadProjectConfig(cwd)
+		}
+		if err := requireWritable(existingCfg); err != nil {
+			return err
+		}
+
 		name := initOpts.name
 		if name == "" {
 			name = filepath.Base(cwd)
@@ -56,10 +66,10 @@ The following files and directories will be created:
 		_, _ = fmt.Fprintln(w, "  AGENTS.md")
 		_, _ = fmt.Fprintln(w, "  characters/")
 		_, _ = fmt.Fprintln(w, "  subprojects/")
-		_, _ = fmt.Fprintln(w, "")
-		_, _ = fmt.Fprintln(w, "Next steps:")
-		_, _ = fmt.Fprintln(w, "  1. Create character definition files in characters/")
-		_, _ = fmt.Fprintln(w, "  2. Run 'banago subproject create <name>' to create a subproject")
+		printNextSteps(w, existingCfg,
+			"Create character definition files in characters/",
+			"Run 'banago subproject create <name>' to create a subproject",
+		)
 
 		return nil
 	},
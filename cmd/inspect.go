@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <image>",
+	Short: "Show the banago metadata embedded in an image",
+	Long: `Show the entry ID, edit ID, model, prompt hash and creation time banago
+embedded in an output image: PNG tEXt chunks, JPEG and WebP EXIF.
+
+The image can be anywhere, e.g. a copy taken out of history. Inside a banago
+project the entry is also looked up in the history of every subproject, and
+the prompt hash is checked against its saved prompt.
+
+Examples:
+  banago inspect ~/Downloads/fox.png
+  banago inspect final.jpg --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read image: %w", err)
+		}
+		prov, err := gemini.ReadProvenance(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+
+		report := inspectReport{image: args[0], provenance: prov}
+		if cwd, err := os.Getwd(); err == nil && prov.EntryID != "" {
+			if projectRoot, err := project.FindProjectRoot(cwd); err == nil {
+				report.locate(projectRoot)
+			}
+		}
+
+		w := cmd.OutOrStdout()
+		if jsonOutput() {
+			return writeJSON(w, report.json())
+		}
+		report.print(w)
+		return nil
+	},
+}
+
+// inspectReport is the embedded provenance of an image and where its entry is in the current project
+type inspectReport struct {
+	image       string
+	provenance  *gemini.Provenance
+	subproject  string // subproject holding the entry, empty if not found
+	dir         string // entry or edit directory
+	promptMatch *bool  // whether the saved prompt still has the embedded hash, nil if unknown
+}
+
+// locate looks up the entry of the provenance in the subprojects of projectRoot
+func (r *inspectReport) locate(projectRoot string) {
+	name, historyDir, err := findEntrySubproject(projectRoot, "", r.provenance.EntryID)
+	if err != nil {
+		return
+	}
+	dir := history.GetEntryDirByID(historyDir, r.provenance.EntryID)
+	load := history.LoadPrompt
+	if r.provenance.EditID != "" {
+		if _, err := history.GetEditEntryByID(dir, r.provenance.EditID); err != nil {
+			return
+		}
+		dir = history.GetEditDirByID(dir, r.provenance.EditID)
+		load = history.LoadEditPrompt
+	}
+	r.subproject = name
+	r.dir = dir
+	if prompt, err := load(dir); err == nil && r.provenance.PromptHash != "" {
+		match := gemini.PromptHash(prompt) == r.provenance.PromptHash
+		r.promptMatch = &match
+	}
+}
+
+func (r *inspectReport) print(w io.Writer) {
+	p := r.provenance
+	_, _ = fmt.Fprintf(w, "Image:   %s\n", r.image)
+	if p.EntryID != "" {
+		_, _ = fmt.Fprintf(w, "Entry:   %s\n", p.EntryID)
+	}
+	if p.EditID != "" {
+		_, _ = fmt.Fprintf(w, "Edit:    %s\n", p.EditID)
+	}
+	if p.Model != "" {
+		_, _ = fmt.Fprintf(w, "Model:   %s\n", p.Model)
+	}
+	if p.PromptHash != "" {
+		_, _ = fmt.Fprintf(w, "Prompt:  sha256 %s\n", p.PromptHash)
+	}
+	if p.CreatedAt != "" {
+		_, _ = fmt.Fprintf(w, "Created: %s\n", history.FormatLocal(p.CreatedAt, time.Now()))
+	}
+
+	if r.subproject == "" {
+		if p.EntryID != "" {
+			_, _ = fmt.Fprintln(w, "\nThe entry is not in the history of this project.")
+		}
+		return
+	}
+	dir := r.dir
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, dir); err == nil {
+			dir = rel
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\nHistory: %s (subproject %s)\n", dir, r.subproject)
+	if r.promptMatch != nil && !*r.promptMatch {
+		_, _ = fmt.Fprintln(w, "Warning: the saved prompt no longer matches the embedded hash")
+	}
+}
+
+// inspectJSON is the JSON output of inspect
+type inspectJSON struct {
+	Image       string `json:"image"`
+	EntryID     string `json:"entry_id,omitempty"`
+	EditID      string `json:"edit_id,omitempty"`
+	Model       string `json:"model,omitempty"`
+	PromptHash  string `json:"prompt_sha256,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	Subproject  string `json:"subproject,omitempty"`
+	Dir         string `json:"dir,omitempty"`
+	PromptMatch *bool  `json:"prompt_match,omitempty"`
+}
+
+func (r *inspectReport) json() inspectJSON {
+	p := r.provenance
+	return inspectJSON{
+		Image:       r.image,
+		EntryID:     p.EntryID,
+		EditID:      p.EditID,
+		Model:       p.Model,
+		PromptHash:  p.PromptHash,
+		CreatedAt:   p.CreatedAt,
+		Subproject:  r.subproject,
+		Dir:         r.dir,
+		PromptMatch: r.promptMatch,
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+}
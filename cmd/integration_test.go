@@ -1,12 +1,26 @@
 package cmd
 
 import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/stretchr/testify/assert"
@@ -189,6 +203,41 @@ func TestIntegration_SubprojectCreate(t *testing.T) {
 		assert.Contains(t, string(output), "already exists")
 	})
 
+	t.Run("hints can be turned off", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+
+		cmd := exec.Command(testBinPath, "subproject", "create", "with-hints")
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "output: %s", output)
+		assert.Contains(t, string(output), "Next steps:")
+
+		cmd = exec.Command(testBinPath, "--no-hints", "subproject", "create", "no-hints-flag")
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err = cmd.CombinedOutput()
+		require.NoError(t, err, "output: %s", output)
+		assert.Contains(t, string(output), "Created subproject 'no-hints-flag'")
+		assert.NotContains(t, string(output), "Next steps:")
+
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		require.NoError(t, err)
+		hints := false
+		projectCfg.Hints = &hints
+		require.NoError(t, projectCfg.Save(projectRoot))
+
+		cmd = exec.Command(testBinPath, "subproject", "create", "no-hints-config")
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err = cmd.CombinedOutput()
+		require.NoError(t, err, "output: %s", output)
+		assert.NotContains(t, string(output), "Next steps:")
+	})
+
 	t.Run("fails outside project", func(t *testing.T) {
 		t.Parallel()
 
@@ -280,6 +329,140 @@ func TestIntegration_History(t *testing.T) {
 		assert.Contains(t, string(output), entry2.ID[:8])
 	})
 
+	t.Run("groups entries by local day", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+		entry := createHistoryEntryForCLI(t, historyDir, "first prompt")
+
+		cmd := exec.Command(testBinPath, "history", "--by-day")
+		cmd.Dir = subprojectDir
+		cmd.Env = append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "TZ=UTC", "LC_ALL=C")
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("history failed: %v\noutput: %s", err, output)
+		}
+		created, err := time.Parse(time.RFC3339, entry.CreatedAt)
+		require.NoError(t, err)
+		assert.Contains(t, string(output), created.Format("2006-01-02 (Monday)"))
+		assert.Contains(t, string(output), "Date: "+created.Format("2006-01-02 15:04")+" (just now)")
+	})
+
+	t.Run("draws thumbnails", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		createHistoryEntryForCLI(t, filepath.Join(subprojectDir, "history"), "first prompt")
+
+		run := func(env ...string) string {
+			cmd := exec.Command(testBinPath, "history", "--thumbs")
+			cmd.Dir = subprojectDir
+			cmd.Env = append(filterEnv(filterEnv(os.Environ(), "GEMINI_API_KEY"), "BANAGO_THUMBS"), env...)
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+			return string(output)
+		}
+
+		// Output to a pipe gets the hint instead of escape sequences
+		output := run()
+		assert.Contains(t, output, thumbsHint)
+		assert.NotContains(t, output, "\x1b")
+
+		output = run("BANAGO_THUMBS=kitty")
+		assert.Contains(t, output, "      \x1b_Ga=T,f=100,")
+		assert.NotContains(t, output, thumbsHint)
+	})
+
+	t.Run("shows edit statistics", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+
+		entry := createHistoryEntryForCLI(t, historyDir, "test prompt")
+		edit := history.NewEditEntry()
+		edit.CreatedAt = "2026-01-02T00:00:00Z"
+		edit.Result.Success = true
+		edit.Result.TokenUsage.Total = 1500
+		require.NoError(t, edit.Save(entry.GetEntryDir(historyDir)))
+
+		cmd := exec.Command(testBinPath, "history")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("history failed: %v\noutput: %s", err, output)
+		}
+
+		assert.Contains(t, string(output), "Edits: 1 (1500 tokens, ~$")
+		assert.Contains(t, string(output), "last 2026-01-02T00:00:00Z")
+	})
+
+	t.Run("filters entries", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+
+		old := createHistoryEntryForCLI(t, historyDir, "a knight in the rain")
+		old.CreatedAt = "2025-06-01T00:00:00Z"
+		require.NoError(t, old.Save(historyDir))
+		knight := createHistoryEntryForCLI(t, historyDir, "the Knight at dawn")
+		failed := createHistoryEntryForCLI(t, historyDir, "a dragon")
+		failed.Result.Success = false
+		failed.Result.ErrorMessage = "quota exceeded"
+		require.NoError(t, failed.Save(historyDir))
+
+		run := func(args ...string) string {
+			t.Helper()
+			cmd := exec.Command(testBinPath, append([]string{"history"}, args...)...)
+			cmd.Dir = subprojectDir
+			cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("history failed: %v\noutput: %s", err, output)
+			}
+			return string(output)
+		}
+
+		output := run("--search", "knight", "--since", "7d")
+		assert.Contains(t, output, "History (1 matching entries)")
+		assert.Contains(t, output, knight.ID)
+		assert.NotContains(t, output, old.ID)
+
+		output = run("--until", "2025-06-01")
+		assert.Contains(t, output, old.ID)
+		assert.NotContains(t, output, knight.ID)
+
+		output = run("--failed-only")
+		assert.Contains(t, output, failed.ID)
+		assert.NotContains(t, output, knight.ID)
+
+		assert.Contains(t, run("--search", "spaceship"), "No matching history entries")
+
+		cmd := exec.Command(testBinPath, "history", "--since", "yesterday")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		errOutput, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(errOutput), "invalid --since")
+	})
+
 	t.Run("empty history", func(t *testing.T) {
 		t.Parallel()
 
@@ -300,6 +483,37 @@ func TestIntegration_History(t *testing.T) {
 		assert.Contains(t, string(output), "No history")
 	})
 
+	t.Run("paths flag prints output paths", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+
+		entry1 := createHistoryEntryForCLI(t, historyDir, "first prompt")
+		entry2 := createHistoryEntryForCLI(t, historyDir, "second prompt")
+
+		cmd := exec.Command(testBinPath, "history", "--paths")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("history --paths failed: %v\noutput: %s", err, output)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		require.Len(t, lines, 2)
+
+		// Newest entry first, one absolute path per line
+		assert.True(t, filepath.IsAbs(lines[0]))
+		assert.True(t, strings.HasSuffix(lines[0], filepath.Join(entry2.ID, "output-test-1.png")))
+		assert.True(t, strings.HasSuffix(lines[1], filepath.Join(entry1.ID, "output-test-1.png")))
+		assert.FileExists(t, lines[0])
+	})
+
 	t.Run("fails outside subproject", func(t *testing.T) {
 		t.Parallel()
 
@@ -363,6 +577,631 @@ func TestIntegration_Status(t *testing.T) {
 	})
 }
 
+func TestIntegration_OutputsRm(t *testing.T) {
+	t.Parallel()
+
+	t.Run("removes matching outputs", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+
+		// Add a second output to the entry
+		entry := createHistoryEntryForCLI(t, historyDir, "test prompt")
+		entryDir := entry.GetEntryDir(historyDir)
+		require.NoError(t, os.WriteFile(filepath.Join(entryDir, "output-test-2.png"), []byte("data"), 0o644))
+		entry.Result.OutputImages = append(entry.Result.OutputImages, "output-test-2.png")
+		require.NoError(t, entry.Save(historyDir))
+
+		cmd := exec.Command(testBinPath, "outputs", "rm", "--id", entry.ID, "output-*-2.png")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("outputs rm failed: %v\noutput: %s", err, output)
+		}
+
+		assert.Contains(t, string(output), "output-test-2.png")
+		assert.NoFileExists(t, filepath.Join(entryDir, "output-test-2.png"))
+		assert.FileExists(t, filepath.Join(entryDir, "output-test-1.png"))
+
+		loaded, err := history.GetEntryByID(historyDir, entry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"output-test-1.png"}, loaded.Result.OutputImages)
+	})
+
+	t.Run("fails when removing all outputs", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		createHistoryEntryForCLI(t, filepath.Join(subprojectDir, "history"), "test prompt")
+
+		cmd := exec.Command(testBinPath, "outputs", "rm", "--latest", "--yes", "output-*")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Error("outputs rm should fail when all outputs match")
+		}
+
+		assert.Contains(t, string(output), "all outputs")
+	})
+}
+
+func TestIntegration_PromptRestore(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	entry := createHistoryEntryForCLI(t, filepath.Join(subprojectDir, "history"), "the wording that worked")
+
+	promptPath := filepath.Join(subprojectDir, "prompt.txt")
+	require.NoError(t, os.WriteFile(promptPath, []byte("a newer draft"), 0o644))
+
+	run := func() (string, error) {
+		cmd := exec.Command(testBinPath, "prompt", "restore", "--id", entry.ID)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run()
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "prompt.txt.bak")
+
+	restored, err := os.ReadFile(promptPath)
+	require.NoError(t, err)
+	assert.Equal(t, "the wording that worked", string(restored))
+	backup, err := os.ReadFile(promptPath + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, "a newer draft", string(backup))
+
+	// Restoring again leaves the backup alone since the prompt already matches
+	output, err = run()
+	require.NoError(t, err, output)
+	assert.NotContains(t, output, "Backed up")
+	backup, err = os.ReadFile(promptPath + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, "a newer draft", string(backup))
+}
+
+func TestIntegration_TagAndRate(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	knight := createHistoryEntryForCLI(t, historyDir, "a knight")
+	dragon := createHistoryEntryForCLI(t, historyDir, "a dragon")
+	castle := createHistoryEntryForCLI(t, historyDir, "a castle")
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, args...)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	load := func(id string) *history.Entry {
+		t.Helper()
+		e, err := history.GetEntryByID(historyDir, id)
+		require.NoError(t, err)
+		return e
+	}
+
+	output, err := run("tag", "add", "--all", "--since", "7d", "draft")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "Tagged 3 of 3 entries")
+
+	output, err = run("tag", "add", "--ids", knight.ID+","+castle.ID, "hero")
+	require.NoError(t, err, output)
+	output, err = run("tag", "rm", "--all", "--search", "dragon", "draft")
+	require.NoError(t, err, output)
+	assert.Equal(t, []string{"draft", "hero"}, load(knight.ID).Tags)
+	assert.Empty(t, load(dragon.ID).Tags)
+
+	output, err = run("rate", "--all", "--tag", "hero", "4")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "Rated 2 of 2 entries 4/5")
+	output, err = run("rate", "--ids", castle.ID, "2")
+	require.NoError(t, err, output)
+	assert.Equal(t, 4, load(knight.ID).Rating)
+	assert.Equal(t, 2, load(castle.ID).Rating)
+
+	output, err = run("history", "--min-rating", "3")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, knight.ID)
+	assert.NotContains(t, output, castle.ID)
+	assert.Contains(t, output, "Tags: draft, hero")
+
+	output, err = run("tag", "list")
+	require.NoError(t, err, output)
+	assert.Equal(t, "draft\t2\nhero\t2\n", output)
+
+	// Filters only apply to --all, and ratings are bounded
+	_, err = run("rate", "--ids", castle.ID, "--tag", "hero", "3")
+	require.Error(t, err)
+	_, err = run("rate", "--all", "6")
+	require.Error(t, err)
+}
+
+func TestIntegration_PromptLibrary(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	entry := createHistoryEntryForCLI(t, filepath.Join(subprojectDir, "history"), "the castle at sunset")
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, append([]string{"prompt"}, args...)...)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("list")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "No prompts")
+
+	output, err = run("save", "sunset", "--id", entry.ID)
+	require.NoError(t, err, output)
+	output, err = run("save", "dawn", "-p", "the castle at dawn")
+	require.NoError(t, err, output)
+
+	output, err = run("save", "dawn", "-p", "again")
+	require.Error(t, err)
+	assert.Contains(t, output, "--force")
+
+	output, err = run("list")
+	require.NoError(t, err, output)
+	assert.Equal(t, "dawn\tthe castle at dawn\nsunset\tthe castle at sunset\n", output)
+
+	output, err = run("use", "sunset")
+	require.NoError(t, err, output)
+	working, err := os.ReadFile(filepath.Join(subprojectDir, "prompt.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "the castle at sunset\n", string(working))
+
+	output, err = run("use", "missing")
+	require.Error(t, err)
+	assert.Contains(t, output, "prompt not found")
+}
+
+func TestIntegration_OpenDirPrint(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "a cat")
+
+	cmd := exec.Command(testBinPath, "open-dir", "--id", entry.ID, "--print")
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err := cmd.Output()
+	require.NoError(t, err, string(output))
+
+	want, err := filepath.EvalSymlinks(entry.GetEntryDir(historyDir))
+	require.NoError(t, err)
+	got, err := filepath.EvalSymlinks(strings.TrimSpace(string(output)))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestIntegration_Cost(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	historyDir := filepath.Join(project.GetSubprojectDir(projectRoot, "test-sub"), "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "a cat")
+	entry.Result.CostUSD = 0.134
+	require.NoError(t, entry.Save(historyDir))
+
+	cmd := exec.Command(testBinPath, "cost")
+	cmd.Dir = projectRoot
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "test-sub")
+	assert.Contains(t, string(output), "Total")
+	assert.Contains(t, string(output), "~$0.13")
+}
+
+func TestIntegration_Changelog(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "active", ""))
+	require.NoError(t, project.CreateSubproject(projectRoot, "idle", ""))
+	historyDir := filepath.Join(project.GetSubprojectDir(projectRoot, "active"), "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "a cat on a sofa")
+	entry.Result.CostUSD = 0.134
+	require.NoError(t, entry.Save(historyDir))
+	old := createHistoryEntryForCLI(t, historyDir, "an old dog")
+	old.CreatedAt = "2020-01-01T00:00:00Z"
+	require.NoError(t, old.Save(historyDir))
+
+	cmd := exec.Command(testBinPath, "changelog", "--since", "7d")
+	cmd.Dir = projectRoot
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	out := string(output)
+	assert.Contains(t, out, "# test-project changelog")
+	assert.Contains(t, out, "## active")
+	assert.Contains(t, out, "- New entries: 1\n")
+	assert.Contains(t, out, "~$0.13")
+	assert.Contains(t, out, "- a cat on a sofa")
+	assert.NotContains(t, out, "an old dog")
+	assert.Contains(t, out, "No activity: idle")
+
+	cmd = exec.Command(testBinPath, "changelog", "--since", "yesterday")
+	cmd.Dir = projectRoot
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err = cmd.CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "invalid --since")
+}
+
+func TestIntegration_Doctor(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	createHistoryEntryForCLI(t, historyDir, "a cat")
+
+	run := func(env []string) (string, error) {
+		cmd := exec.Command(testBinPath, "doctor")
+		cmd.Dir = projectRoot
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	env := append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "GEMINI_API_KEY=test-key")
+
+	output, err := run(env)
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "history: 1 entries")
+	assert.Contains(t, output, "No problems found")
+
+	// Break the subproject: a missing input image, a dangling character file and a stray directory
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	subprojectCfg.InputImages = []string{"missing.png"}
+	subprojectCfg.CharacterFile = "nobody.md"
+	require.NoError(t, subprojectCfg.Save(subprojectDir))
+	require.NoError(t, os.MkdirAll(filepath.Join(historyDir, "stray"), 0o755))
+
+	output, err = run(filterEnv(os.Environ(), "GEMINI_API_KEY"))
+	require.Error(t, err)
+	assert.Contains(t, output, "API key is not set")
+	assert.Contains(t, output, "input image missing.png not found")
+	assert.Contains(t, output, "character_file nobody.md not found")
+	assert.Contains(t, output, "history/stray is not a valid entry")
+	assert.Contains(t, output, "4 problems found")
+	assert.NotContains(t, output, "Usage:")
+}
+
+func TestIntegration_ConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	run := func() (string, error) {
+		cmd := exec.Command(testBinPath, "config", "validate")
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run()
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "subprojects/test-sub/config.yaml: ok")
+
+	configPath := filepath.Join(subprojectDir, "config.yaml")
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, append(data, []byte("apect_ratio: \"16:9\"\n")...), 0o644))
+
+	output, err = run()
+	require.Error(t, err)
+	assert.Contains(t, output, `unknown key "apect_ratio" (did you mean "aspect_ratio"?)`)
+	assert.Contains(t, output, "1 invalid config files")
+}
+
+func TestIntegration_ConfigSetGet(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, append([]string{"config"}, args...)...)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("set", "input_images", "a.png", "b.png")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "Set input_images in subprojects/test-sub/config.yaml")
+	output, err = run("get", "input_images")
+	require.NoError(t, err, output)
+	assert.Equal(t, "a.png\nb.png\n", output)
+
+	// Keys only banago.yaml defines go there, even inside a subproject
+	output, err = run("set", "confirm_before_generate", "true")
+	require.NoError(t, err, output)
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+	assert.True(t, projectCfg.ConfirmBeforeGenerate)
+
+	output, err = run("set", "aspect_ratio", "wide")
+	require.Error(t, err)
+	assert.Contains(t, output, `invalid aspect ratio "wide"`)
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	assert.Empty(t, subprojectCfg.AspectRatio)
+	assert.Equal(t, []string{"a.png", "b.png"}, subprojectCfg.InputImages)
+
+	output, err = run("--read-only", "set", "aspect_ratio", "1:1")
+	require.Error(t, err)
+	assert.Contains(t, output, "--read-only is set")
+}
+
+// TestIntegration_Selftest tests that selftest passes without an API key and cleans up after itself.
+func TestIntegration_Selftest(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	cmd := exec.Command(testBinPath, "selftest")
+	cmd.Dir = tmpDir
+	cmd.Env = append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "TMPDIR="+tmpDir, "TMP="+tmpDir, "TEMP="+tmpDir)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	for _, step := range []string{"init", "generate", "edit", "regenerate", "history", "serve"} {
+		assert.Contains(t, string(output), "ok  "+step)
+	}
+	assert.Contains(t, string(output), "Self-test passed")
+
+	left, err := filepath.Glob(filepath.Join(tmpDir, "banago-selftest-*"))
+	require.NoError(t, err)
+	assert.Empty(t, left)
+}
+
+func TestIntegration_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	t.Run("flag refuses mutating commands", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		historyDir := filepath.Join(subprojectDir, "history")
+
+		entry := createHistoryEntryForCLI(t, historyDir, "test prompt")
+		entryDir := entry.GetEntryDir(historyDir)
+		require.NoError(t, os.WriteFile(filepath.Join(entryDir, "output-test-2.png"), []byte("data"), 0o644))
+		entry.Result.OutputImages = append(entry.Result.OutputImages, "output-test-2.png")
+		require.NoError(t, entry.Save(historyDir))
+
+		cmd := exec.Command(testBinPath, "--read-only", "outputs", "rm", "--id", entry.ID, "output-*-2.png")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Error("outputs rm should fail with --read-only")
+		}
+
+		assert.Contains(t, string(output), "--read-only is set")
+		assert.FileExists(t, filepath.Join(entryDir, "output-test-2.png"))
+	})
+
+	t.Run("config refuses mutating commands but allows browsing", func(t *testing.T) {
+		t.Parallel()
+
+		projectRoot := t.TempDir()
+		require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+		require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+		subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+		createHistoryEntryForCLI(t, filepath.Join(subprojectDir, "history"), "test prompt")
+
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		require.NoError(t, err)
+		projectCfg.ReadOnly = true
+		require.NoError(t, projectCfg.Save(projectRoot))
+
+		cmd := exec.Command(testBinPath, "subproject", "create", "another")
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Error("subproject create should fail in a read-only project")
+		}
+		assert.Contains(t, string(output), "readonly: true")
+		assert.NoDirExists(t, project.GetSubprojectDir(projectRoot, "another"))
+
+		cmd = exec.Command(testBinPath, "history")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("history should work in a read-only project: %v\noutput: %s", err, output)
+		}
+
+		cmd = exec.Command(testBinPath, "status")
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("status failed: %v\noutput: %s", err, output)
+		}
+		assert.Contains(t, string(output), "Mode: read-only")
+	})
+}
+
+func TestIntegration_EntrySplitMerge(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	// Add a second output to the entry
+	entry := createHistoryEntryForCLI(t, historyDir, "test prompt")
+	require.NoError(t, os.WriteFile(filepath.Join(entry.GetEntryDir(historyDir), "output-test-2.png"), []byte("data"), 0o644))
+	entry.Result.OutputImages = append(entry.Result.OutputImages, "output-test-2.png")
+	require.NoError(t, entry.Save(historyDir))
+
+	cmd := exec.Command(testBinPath, "entry", "split", "--id", entry.ID)
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("entry split failed: %v\noutput: %s", err, output)
+	}
+	assert.Contains(t, string(output), "into 2 entries")
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	cmd = exec.Command(testBinPath, "entry", "merge", entries[0].ID, entries[1].ID)
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("entry merge failed: %v\noutput: %s", err, output)
+	}
+	assert.Contains(t, string(output), "Merged 2 entries")
+
+	entries, err = history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Len(t, entries[0].Result.OutputImages, 2)
+}
+
+func TestIntegration_ImportEntry(t *testing.T) {
+	t.Parallel()
+
+	// Source project is read-only; importing must not modify it
+	srcRoot := t.TempDir()
+	require.NoError(t, project.InitProject(srcRoot, "shared-project", false))
+	require.NoError(t, project.CreateSubproject(srcRoot, "forest", ""))
+	srcHistoryDir := filepath.Join(project.GetSubprojectDir(srcRoot, "forest"), "history")
+	source := createHistoryEntryForCLI(t, srcHistoryDir, "shared prompt")
+
+	srcCfg, err := config.LoadProjectConfig(srcRoot)
+	require.NoError(t, err)
+	srcCfg.ReadOnly = true
+	require.NoError(t, srcCfg.Save(srcRoot))
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cmd := exec.Command(testBinPath, "import-entry", srcRoot, "--id", source.ID)
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("import-entry failed: %v\noutput: %s", err, output)
+	}
+	assert.Contains(t, string(output), "shared-project/forest/"+source.ID)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].Lineage)
+	assert.Equal(t, source.ID, entries[0].Lineage.ImportedFrom.EntryID)
+	assert.FileExists(t, filepath.Join(entries[0].GetEntryDir(historyDir), "output-test-1.png"))
+
+	srcEntries, err := history.ListEntries(srcHistoryDir)
+	require.NoError(t, err)
+	assert.Len(t, srcEntries, 1)
+}
+
+func TestIntegration_Compare(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	first := createHistoryEntryForCLI(t, historyDir, "a cat sitting on a mat")
+	second := createHistoryEntryForCLI(t, historyDir, "a cat standing on a mat")
+	second.Generation.AspectRatio = "16:9"
+	require.NoError(t, second.Save(historyDir))
+
+	htmlPath := filepath.Join(t.TempDir(), "compare.html")
+	cmd := exec.Command(testBinPath, "compare", "--latest", "--previous", "--html", htmlPath)
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("compare failed: %v\noutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	assert.Contains(t, outputStr, "A: "+first.ID)
+	assert.Contains(t, outputStr, "B: "+second.ID)
+	assert.Contains(t, outputStr, "[-sitting-] {+standing+}")
+	assert.Contains(t, outputStr, "aspect_ratio: (none) -> 16:9")
+
+	html, err := os.ReadFile(htmlPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "data:image/png;base64,")
+	assert.Contains(t, string(html), `alt="a cat standing on a mat"`)
+}
+
 func TestIntegration_ServeHelp(t *testing.T) {
 	t.Parallel()
 
@@ -423,3 +1262,692 @@ func TestIntegration_RegenerateRequiresAPIKey(t *testing.T) {
 		t.Errorf("expected error message about API key, got: %s", output)
 	}
 }
+
+func TestIntegration_OutputJSON(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", "json test"))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "test prompt")
+
+	run := func(t *testing.T, dir string, args ...string) ([]byte, error) {
+		t.Helper()
+		cmd := exec.Command(testBinPath, append([]string{"--output", "json"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		return cmd.Output()
+	}
+
+	t.Run("history", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := run(t, subprojectDir, "history")
+		require.NoError(t, err)
+
+		var entries []entryJSON
+		require.NoError(t, json.Unmarshal(output, &entries))
+		require.Len(t, entries, 1)
+		assert.Equal(t, entry.ID, entries[0].ID)
+		require.Len(t, entries[0].Outputs, len(entry.Result.OutputImages))
+		assert.True(t, filepath.IsAbs(entries[0].Outputs[0]))
+	})
+
+	t.Run("status", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := run(t, subprojectDir, "status")
+		require.NoError(t, err)
+
+		var status statusJSON
+		require.NoError(t, json.Unmarshal(output, &status))
+		assert.Equal(t, "test-project", status.Project)
+		assert.Equal(t, "test-sub", status.Subproject)
+		require.NotNil(t, status.History)
+		assert.Equal(t, 1, status.History.Count)
+		assert.Equal(t, entry.ID, status.History.LatestID)
+	})
+
+	t.Run("subproject list", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := run(t, projectRoot, "subproject", "list")
+		require.NoError(t, err)
+
+		var subprojects []subprojectJSON
+		require.NoError(t, json.Unmarshal(output, &subprojects))
+		assert.Equal(t, []subprojectJSON{{Name: "test-sub", Description: "json test"}}, subprojects)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := run(t, subprojectDir, "compare", "missing-a", "missing-b")
+		require.Error(t, err)
+
+		var jsonErr jsonError
+		require.NoError(t, json.Unmarshal(output, &jsonErr))
+		assert.Contains(t, jsonErr.Error, "failed to get history entry")
+		assert.Equal(t, "entry_not_found", jsonErr.Code)
+	})
+}
+
+func TestIntegration_Export(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	first := createHistoryEntryForCLI(t, historyDir, "first prompt")
+	second := createHistoryEntryForCLI(t, historyDir, "second prompt")
+	failed := createHistoryEntryForCLI(t, historyDir, "failed prompt")
+	failed.Result.Success = false
+	require.NoError(t, failed.Save(historyDir))
+
+	run := func(t *testing.T, args ...string) {
+		t.Helper()
+		cmd := exec.Command(testBinPath, append([]string{"export"}, args...)...)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("export failed: %v\noutput: %s", err, output)
+		}
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+
+		exportDir := filepath.Join(t.TempDir(), "delivery")
+		run(t, "--ids", first.ID+","+second.ID, "--dir", exportDir)
+
+		data, err := os.ReadFile(filepath.Join(exportDir, "manifest.json"))
+		require.NoError(t, err)
+		var manifest exportManifest
+		require.NoError(t, json.Unmarshal(data, &manifest))
+
+		require.Len(t, manifest.Files, 2)
+		assert.Equal(t, "test-sub", manifest.Subproject)
+		assert.Equal(t, first.ID, manifest.Files[0].EntryID)
+		assert.Equal(t, "first prompt", manifest.Files[0].Prompt)
+		assert.Equal(t, second.ID, manifest.Files[1].EntryID)
+		// Colliding output filenames are prefixed with the entry ID
+		assert.Equal(t, "output-test-1.png", manifest.Files[0].File)
+		assert.Equal(t, second.ID+"-output-test-1.png", manifest.Files[1].File)
+		for _, row := range manifest.Files {
+			assert.FileExists(t, filepath.Join(exportDir, row.File))
+		}
+
+		// Existing files are never overwritten
+		cmd := exec.Command(testBinPath, "export", "--ids", first.ID, "--dir", exportDir)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(output), "already exists")
+	})
+
+	t.Run("zip with all successful entries", func(t *testing.T) {
+		t.Parallel()
+
+		zipPath := filepath.Join(t.TempDir(), "delivery.zip")
+		run(t, "--all-success", "--zip", zipPath)
+
+		zr, err := zip.OpenReader(zipPath)
+		require.NoError(t, err)
+		defer func() { _ = zr.Close() }()
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.Len(t, names, 3)
+		assert.Contains(t, names, "manifest.json")
+	})
+}
+
+func TestIntegration_ExportStripMetadata(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	entry := createHistoryEntryForCLI(t, historyDir, "secret prompt")
+
+	// Give the output a provenance text chunk right after IHDR
+	outputPath := filepath.Join(entry.GetEntryDir(historyDir), "output-test-1.png")
+	original, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	text := []byte("banago:entry_id\x00" + entry.ID)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(append(chunk, "tEXt"...), text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	tagged := slices.Concat(original[:33], chunk, original[33:])
+	require.NoError(t, os.WriteFile(outputPath, tagged, 0o644))
+	_, err = gemini.ReadProvenance(tagged)
+	require.NoError(t, err)
+
+	exportDir := filepath.Join(t.TempDir(), "public")
+	cmd := exec.Command(testBinPath, "export", "--ids", entry.ID, "--strip-metadata", "--dir", exportDir)
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "Metadata stripped and verified clean")
+
+	data, err := os.ReadFile(filepath.Join(exportDir, "manifest.json"))
+	require.NoError(t, err)
+	var manifest exportManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.True(t, manifest.MetadataStripped)
+	require.Len(t, manifest.Files, 1)
+	assert.Empty(t, manifest.Files[0].Prompt)
+	assert.NotContains(t, string(data), "secret prompt")
+
+	exported, err := os.ReadFile(filepath.Join(exportDir, manifest.Files[0].File))
+	require.NoError(t, err)
+	assert.Equal(t, original, exported)
+	sum, err := sha256File(filepath.Join(exportDir, manifest.Files[0].File))
+	require.NoError(t, err)
+	assert.Equal(t, sum, manifest.Files[0].SHA256)
+	_, err = gemini.ReadProvenance(exported)
+	assert.ErrorIs(t, err, gemini.ErrNoProvenance)
+
+	// The history keeps its metadata
+	kept, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Equal(t, tagged, kept)
+
+	// Entry bundles keep prompts for import
+	cmd = exec.Command(testBinPath, "export", "--ids", entry.ID, "--strip-metadata", "--entries", "--dir", filepath.Join(t.TempDir(), "bundle"))
+	cmd.Dir = subprojectDir
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err = cmd.CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "strip-metadata")
+}
+
+func TestIntegration_ImportBundle(t *testing.T) {
+	t.Parallel()
+
+	srcRoot := t.TempDir()
+	require.NoError(t, project.InitProject(srcRoot, "studio", false))
+	require.NoError(t, project.CreateSubproject(srcRoot, "forest", ""))
+	srcDir := project.GetSubprojectDir(srcRoot, "forest")
+	source := createHistoryEntryForCLI(t, filepath.Join(srcDir, "history"), "a fox in the snow")
+
+	keyPath := filepath.Join(t.TempDir(), "studio.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("shared secret\n"), 0o600))
+	otherKey := filepath.Join(t.TempDir(), "other.key")
+	require.NoError(t, os.WriteFile(otherKey, []byte("another secret"), 0o600))
+
+	run := func(t *testing.T, dir string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(testBinPath, args...)
+		cmd.Dir = dir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	newSubproject := func(t *testing.T) (string, string) {
+		t.Helper()
+		root := t.TempDir()
+		require.NoError(t, project.InitProject(root, "client", false))
+		require.NoError(t, project.CreateSubproject(root, "inbox", ""))
+		dir := project.GetSubprojectDir(root, "inbox")
+		return dir, filepath.Join(dir, "history")
+	}
+
+	t.Run("signed zip", func(t *testing.T) {
+		t.Parallel()
+
+		zipPath := filepath.Join(t.TempDir(), "handoff.zip")
+		output, err := run(t, srcDir, "export", "--ids", source.ID, "--entries", "--sign-key", keyPath, "--zip", zipPath)
+		require.NoError(t, err, output)
+		assert.Contains(t, output, "Signed: manifest.sig")
+
+		dir, historyDir := newSubproject(t)
+		output, err = run(t, dir, "import", zipPath, "--verify-key", otherKey)
+		require.Error(t, err)
+		assert.Contains(t, output, "manifest signature does not match the key")
+
+		output, err = run(t, dir, "import", zipPath, "--verify-key", keyPath)
+		require.NoError(t, err, output)
+		assert.Contains(t, output, "Verified checksums and signature")
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.NotNil(t, entries[0].Lineage)
+		assert.Equal(t, history.ImportSource{Project: "studio", Subproject: "forest", EntryID: source.ID}, *entries[0].Lineage.ImportedFrom)
+		assert.FileExists(t, filepath.Join(entries[0].GetEntryDir(historyDir), "output-test-1.png"))
+		prompt, err := history.LoadPrompt(entries[0].GetEntryDir(historyDir))
+		require.NoError(t, err)
+		assert.Equal(t, "a fox in the snow", prompt)
+	})
+
+	t.Run("refuses tampered directories", func(t *testing.T) {
+		t.Parallel()
+
+		export := func(t *testing.T, args ...string) string {
+			t.Helper()
+			exportDir := filepath.Join(t.TempDir(), "handoff")
+			output, err := run(t, srcDir, append([]string{"export", "--ids", source.ID, "--entries", "--dir", exportDir}, args...)...)
+			require.NoError(t, err, output)
+			return exportDir
+		}
+		dir, historyDir := newSubproject(t)
+
+		unsigned := export(t)
+		output, err := run(t, dir, "import", unsigned, "--verify-key", keyPath)
+		require.Error(t, err)
+		assert.Contains(t, output, "bundle is not signed")
+
+		changed := export(t)
+		promptPath := filepath.Join(changed, "entries", source.ID, "prompt.txt")
+		require.NoError(t, os.WriteFile(promptPath, []byte("a wolf in the snow\n"), 0o644))
+		output, err = run(t, dir, "--output", "json", "import", changed)
+		require.Error(t, err)
+		assert.Contains(t, output, `"code": "bundle_tampered"`)
+		assert.Contains(t, output, "prompt.txt does not match its checksum")
+
+		extra := export(t)
+		require.NoError(t, os.WriteFile(filepath.Join(extra, "entries", source.ID, "notes.txt"), []byte("x"), 0o644))
+		output, err = run(t, dir, "import", extra)
+		require.Error(t, err)
+		assert.Contains(t, output, "notes.txt is not in the manifest")
+
+		outputsOnly := filepath.Join(t.TempDir(), "outputs")
+		output, err = run(t, srcDir, "export", "--ids", source.ID, "--dir", outputsOnly)
+		require.NoError(t, err, output)
+		output, err = run(t, dir, "import", outputsOnly)
+		require.Error(t, err)
+		assert.Contains(t, output, "export it with --entries")
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "refused bundles import nothing")
+
+		output, err = run(t, dir, "import", export(t))
+		require.NoError(t, err, output)
+		assert.Contains(t, output, "bundle is not signed")
+	})
+}
+
+func TestIntegration_Template(t *testing.T) {
+	t.Parallel()
+
+	srcRoot := t.TempDir()
+	require.NoError(t, project.InitProject(srcRoot, "src-project", false))
+	require.NoError(t, project.CreateSubproject(srcRoot, "watercolor", "Soft watercolor style"))
+	srcDir := project.GetSubprojectDir(srcRoot, "watercolor")
+	cfg, err := config.LoadSubprojectConfig(srcDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"reference.png"}
+	require.NoError(t, cfg.Save(srcDir))
+	entry := createHistoryEntryForCLI(t, filepath.Join(srcDir, "history"), "a lighthouse at dusk")
+
+	templatePath := filepath.Join(t.TempDir(), "watercolor.yaml")
+	cmd := exec.Command(testBinPath, "template", "pack", "watercolor", "--prompts", entry.ID, "--out", templatePath)
+	cmd.Dir = srcRoot
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("template pack failed: %v\noutput: %s", err, output)
+	}
+	assert.Contains(t, string(output), "1 prompt templates")
+
+	dstRoot := t.TempDir()
+	require.NoError(t, project.InitProject(dstRoot, "dst-project", false))
+
+	cmd = exec.Command(testBinPath, "template", "unpack", templatePath, "--name", "my-watercolor")
+	cmd.Dir = dstRoot
+	cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("template unpack failed: %v\noutput: %s", err, output)
+	}
+	assert.Contains(t, string(output), "Created subproject 'my-watercolor'")
+	assert.Contains(t, string(output), "reference.png")
+
+	dstDir := project.GetSubprojectDir(dstRoot, "my-watercolor")
+	dstCfg, err := config.LoadSubprojectConfig(dstDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Soft watercolor style", dstCfg.Description)
+	assert.Equal(t, []string{"reference.png"}, dstCfg.InputImages)
+
+	prompt, err := os.ReadFile(filepath.Join(dstDir, "prompts", "prompt-1.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a lighthouse at dusk", string(prompt))
+}
+
+func TestIntegration_Prune(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	kept := createHistoryEntryForCLI(t, historyDir, "good prompt")
+	failed := createHistoryEntryForCLI(t, historyDir, "failed prompt")
+	failed.Result.Success = false
+	require.NoError(t, failed.Save(historyDir))
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, append([]string{"prune"}, args...)...)
+		cmd.Dir = subprojectDir
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("--failed", "--dry-run")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, failed.ID)
+	assert.NotContains(t, output, kept.ID)
+	assert.Contains(t, output, "Would delete 1 entries and reclaim")
+	assert.DirExists(t, failed.GetEntryDir(historyDir))
+
+	output, err = run("--failed")
+	require.Error(t, err)
+	assert.Contains(t, output, "aborted")
+	assert.DirExists(t, failed.GetEntryDir(historyDir))
+
+	output, err = run("--failed", "--yes")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "Deleted 1 entries")
+	assert.NoDirExists(t, failed.GetEntryDir(historyDir))
+	assert.DirExists(t, kept.GetEntryDir(historyDir))
+
+	_, err = run("--dry-run")
+	require.Error(t, err)
+}
+
+func TestIntegration_GC(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	// A complete entry whose input snapshot is kept
+	kept := createHistoryEntryForCLI(t, historyDir, "good prompt")
+	n, err := kept.StoreInputImages(historyDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	store := history.FindObjectStore(projectRoot)
+	keptSnapshot := store.Path(kept.Generation.InputObjects["test.png"], "test.png")
+	require.FileExists(t, keptSnapshot)
+
+	// Leftovers of interrupted runs
+	unreferenced := filepath.Join(t.TempDir(), "old.png")
+	require.NoError(t, os.WriteFile(unreferenced, []byte("old input"), 0o644))
+	sum, err := store.Put(unreferenced)
+	require.NoError(t, err)
+	noMeta := history.NewEntry().GetEntryDir(historyDir)
+	require.NoError(t, os.MkdirAll(noMeta, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(noMeta, "output-1.png"), []byte("partial"), 0o644))
+	pending := history.NewEntry()
+	require.NoError(t, pending.SavePending(historyDir))
+	editDir := history.GetEditDirByID(kept.GetEntryDir(historyDir), history.NewEntry().ID)
+	require.NoError(t, os.MkdirAll(editDir, 0o755))
+	tempFile := filepath.Join(kept.GetEntryDir(historyDir), ".meta.yaml.123.tmp")
+	require.NoError(t, os.WriteFile(tempFile, []byte("partial"), 0o644))
+	old := time.Now().Add(-2 * time.Hour)
+	for _, path := range []string{noMeta, pending.GetEntryDir(historyDir), editDir, tempFile} {
+		require.NoError(t, filepath.WalkDir(path, func(p string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(p, old, old)
+		}))
+	}
+	// A generation still running without the lock, e.g. of an older banago, is left alone
+	running := history.NewEntry()
+	require.NoError(t, running.SavePending(historyDir))
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, append([]string{"gc"}, args...)...)
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("--dry-run")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "entry directory without meta.yaml")
+	assert.Contains(t, output, "entry never finalized")
+	assert.Contains(t, output, "edit directory without edit-meta.yaml")
+	assert.Contains(t, output, "temporary file of an interrupted write")
+	assert.Contains(t, output, "snapshot no entry references")
+	assert.Contains(t, output, "Would delete 5 items")
+	assert.NotContains(t, output, keptSnapshot)
+	assert.DirExists(t, noMeta)
+
+	output, err = run("--yes")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "Deleted 5 items")
+	for _, path := range []string{noMeta, pending.GetEntryDir(historyDir), editDir, tempFile} {
+		assert.NoFileExists(t, path)
+		assert.NoDirExists(t, path)
+	}
+	assert.NoDirExists(t, filepath.Dir(store.Path(sum, "old.png")))
+	assert.FileExists(t, keptSnapshot)
+	assert.DirExists(t, running.GetEntryDir(historyDir))
+	_, err = history.GetEntryByID(historyDir, kept.ID)
+	require.NoError(t, err)
+
+	output, err = run("--dry-run")
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "No garbage found")
+}
+
+func TestIntegration_ModelInfo(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(testBinPath, append([]string{"model", "info"}, args...)...)
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	// Without an API key the bundled table of the project's model is shown
+	output, err := run()
+	require.NoError(t, err, output)
+	assert.Contains(t, output, config.DefaultModel)
+	assert.Contains(t, output, "21:9")
+	assert.Contains(t, output, "1K, 2K, 4K")
+	assert.Contains(t, output, "up to 14")
+	assert.Contains(t, output, "table bundled")
+
+	_, err = run("gemini-unknown-image")
+	require.Error(t, err)
+
+	// A fresh probe in the cache is reused
+	cache := map[string]gemini.Capabilities{"gemini-next-image": {
+		Model:        "gemini-next-image",
+		AspectRatios: []string{"1:1", "16:9"},
+		Source:       gemini.CapabilitiesAPI,
+		FetchedAt:    time.Now().UTC().Format(time.RFC3339),
+	}}
+	data, err := json.Marshal(cache)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(projectRoot, ".banago"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectRoot, ".banago", "models.json"), data, 0o644))
+
+	output, err = run("gemini-next-image", "--output", "json")
+	require.NoError(t, err, output)
+	var caps gemini.Capabilities
+	require.NoError(t, json.Unmarshal([]byte(output), &caps), output)
+	assert.Equal(t, []string{"1:1", "16:9"}, caps.AspectRatios)
+	assert.Equal(t, gemini.CapabilitiesAPI, caps.Source)
+}
+
+func TestIntegration_Logging(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+
+	run := func(args ...string) (string, string, error) {
+		cmd := exec.Command(testBinPath, args...)
+		cmd.Dir = projectRoot
+		cmd.Env = filterEnv(os.Environ(), "GEMINI_API_KEY")
+		var stdout, stderr strings.Builder
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	// Nothing is logged by default
+	plain, stderr, err := run("model", "info", "--offline")
+	require.NoError(t, err, stderr)
+	assert.Empty(t, stderr)
+
+	// --log-file appends JSON lines and leaves the output alone
+	logFile := filepath.Join(t.TempDir(), "banago.log")
+	for range 2 {
+		output, stderr, err := run("model", "info", "--offline", "--log-json", "--log-file", logFile)
+		require.NoError(t, err, stderr)
+		assert.Equal(t, plain, output)
+		assert.Empty(t, stderr)
+	}
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		assert.Equal(t, "command finished", record["msg"])
+		assert.Equal(t, "banago model info", record["command"])
+	}
+
+	// --verbose logs as text to stderr
+	output, stderr, err := run("model", "info", "--offline", "--verbose")
+	require.NoError(t, err, stderr)
+	assert.Equal(t, plain, output)
+	assert.Contains(t, stderr, "command finished")
+}
+
+func TestIntegration_Tracing(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var bodies []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.URL.Path+" "+string(data))
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+
+	cmd := exec.Command(testBinPath, "model", "info", "--offline")
+	cmd.Dir = projectRoot
+	cmd.Env = append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "OTEL_EXPORTER_OTLP_ENDPOINT="+collector.URL, "OTEL_SERVICE_NAME=banago-test")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	// The span of the command is exported before it exits
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, bodies, 1)
+	assert.True(t, strings.HasPrefix(bodies[0], "/v1/traces "), bodies[0])
+	assert.Contains(t, bodies[0], `"name":"banago model info"`)
+	assert.Contains(t, bodies[0], `"banago-test"`)
+
+	// An unsupported protocol is an error rather than silently not tracing
+	cmd = exec.Command(testBinPath, "model", "info", "--offline")
+	cmd.Dir = projectRoot
+	cmd.Env = append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "OTEL_EXPORTER_OTLP_ENDPOINT="+collector.URL, "OTEL_EXPORTER_OTLP_PROTOCOL=grpc")
+	output, err = cmd.CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "http/json")
+}
+
+func TestIntegration_GenerateInterrupted(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Interrupt cannot be sent to a process on Windows")
+	}
+
+	// An API that never answers
+	requested := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	defer api.Close()
+	defer close(stop)
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cmd := exec.Command(testBinPath, "generate", "-p", "a cat", "--no-input-images", "--keep-failures", "--yes")
+	cmd.Dir = subprojectDir
+	cmd.Env = append(filterEnv(os.Environ(), "GEMINI_API_KEY"), "GEMINI_API_KEY=test-key", "GOOGLE_GEMINI_BASE_URL="+api.URL)
+	var output strings.Builder
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	require.NoError(t, cmd.Start())
+
+	select {
+	case <-requested:
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatalf("the API was never called:\n%s", output.String())
+	}
+	require.NoError(t, cmd.Process.Signal(os.Interrupt))
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatalf("banago did not stop after Ctrl+C:\n%s", output.String())
+	}
+	require.Error(t, err)
+	assert.Contains(t, output.String(), "interrupted")
+
+	// The entry of the aborted call is cleaned up
+	historyDir := filepath.Join(subprojectDir, "history")
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	dirs, _ := os.ReadDir(historyDir)
+	for _, d := range dirs {
+		assert.False(t, d.IsDir(), "leftover directory %s", d.Name())
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// closeLog closes the --log-file once the command finished (no-op without one)
+var closeLog = func() {}
+
+// setupLogging installs the default slog logger selected by --verbose, --log-json and --log-file.
+// Without any of them nothing is logged, so the output of commands stays unchanged.
+func setupLogging() error {
+	if !cfg.verbose && !cfg.logJSON && cfg.logFile == "" {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return nil
+	}
+
+	var out io.Writer = os.Stderr
+	if cfg.logFile != "" {
+		f, err := os.OpenFile(cfg.logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		out = f
+		closeLog = func() { _ = f.Close() }
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if cfg.verbose {
+		opts.Level = slog.LevelDebug
+	}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.logJSON {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
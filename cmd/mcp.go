@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/mcp"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// mcpVersion is reported to MCP clients as the server version
+const mcpVersion = "0.1.0"
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve banago as MCP tools over stdio",
+	Long: `Run a Model Context Protocol server on stdin/stdout.
+
+Exposes generate, edit, regenerate, history and status as tools returning
+the same JSON as --output json, so agents can drive banago without parsing
+text output. Run it from the project or a subproject directory; every tool
+also accepts a "subproject" argument.
+
+Example client configuration:
+  {"command": "banago", "args": ["mcp"], "cwd": "/path/to/project"}`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+
+		server := mcp.NewServer("banago", mcpVersion, mcpTools(client, cwd)...)
+		return server.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+// mcpTools returns the tools backed by the command handlers.
+// Handlers run in JSON mode and never read stdin, which carries the protocol.
+func mcpTools(generator generation.Generator, cwd string) []mcp.Tool {
+	noStdin := strings.NewReader("")
+	subprojectProp := map[string]any{"type": "string", "description": "Subproject name (default: the server's current subproject)"}
+	yesProp := map[string]any{"type": "boolean", "description": "Confirm the request when the project sets confirm_before_generate"}
+
+	return []mcp.Tool{
+		{
+			Name:        "generate",
+			Description: "Generate images from a prompt and the subproject's input_images. Each generation becomes a history entry.",
+			InputSchema: mcpSchema([]string{"prompt"}, map[string]any{
+				"prompt":     map[string]any{"type": "string"},
+				"aspect":     map[string]any{"type": "string", "description": "Aspect ratio such as 1:1 or 16:9"},
+				"size":       map[string]any{"type": "string", "enum": []string{"1K", "2K", "4K"}},
+				"count":      map[string]any{"type": "integer", "minimum": 1},
+				"subproject": subprojectProp,
+				"yes":        yesProp,
+			}),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args struct {
+					Prompt     string `json:"prompt"`
+					Aspect     string `json:"aspect"`
+					Size       string `json:"size"`
+					Count      int    `json:"count"`
+					Subproject string `json:"subproject"`
+					Yes        bool   `json:"yes"`
+				}
+				workDir, err := mcpArgs(raw, &args, cwd, func() string { return args.Subproject })
+				if err != nil {
+					return "", err
+				}
+				var buf bytes.Buffer
+				h := &generateHandler{generator: generator, stdin: noStdin}
+				err = h.run(ctx, generateOptions{
+					prompt: args.Prompt, aspect: args.Aspect, size: args.Size, count: args.Count, yes: args.Yes, json: true,
+				}, workDir, &buf)
+				return buf.String(), err
+			},
+		},
+		{
+			Name:        "edit",
+			Description: "Edit the first output of a history entry (or of one of its edits) with a prompt.",
+			InputSchema: mcpSchema([]string{"id", "prompt"}, map[string]any{
+				"id":         map[string]any{"type": "string", "description": "History entry ID"},
+				"edit_id":    map[string]any{"type": "string", "description": "Edit to continue from (chained edit)"},
+				"prompt":     map[string]any{"type": "string"},
+				"mask":       map[string]any{"type": "string", "description": "Mask image path: white areas are edited"},
+				"aspect":     map[string]any{"type": "string"},
+				"size":       map[string]any{"type": "string", "enum": []string{"1K", "2K", "4K"}},
+				"subproject": subprojectProp,
+				"yes":        yesProp,
+			}),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args struct {
+					ID         string `json:"id"`
+					EditID     string `json:"edit_id"`
+					Prompt     string `json:"prompt"`
+					Mask       string `json:"mask"`
+					Aspect     string `json:"aspect"`
+					Size       string `json:"size"`
+					Subproject string `json:"subproject"`
+					Yes        bool   `json:"yes"`
+				}
+				workDir, err := mcpArgs(raw, &args, cwd, func() string { return args.Subproject })
+				if err != nil {
+					return "", err
+				}
+				var buf bytes.Buffer
+				h := &editHandler{generator: generator, stdin: noStdin}
+				err = h.run(ctx, editOptions{
+					id: args.ID, editID: args.EditID, prompt: args.Prompt, mask: args.Mask,
+					aspect: args.Aspect, size: args.Size, yes: args.Yes, json: true,
+				}, workDir, &buf)
+				return buf.String(), err
+			},
+		},
+		{
+			Name:        "regenerate",
+			Description: "Regenerate a history entry with its prompt and input images, creating a new entry.",
+			InputSchema: mcpSchema([]string{"id"}, map[string]any{
+				"id":         map[string]any{"type": "string", "description": "History entry ID"},
+				"aspect":     map[string]any{"type": "string"},
+				"size":       map[string]any{"type": "string", "enum": []string{"1K", "2K", "4K"}},
+				"subproject": subprojectProp,
+				"yes":        yesProp,
+			}),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args struct {
+					ID         string `json:"id"`
+					Aspect     string `json:"aspect"`
+					Size       string `json:"size"`
+					Subproject string `json:"subproject"`
+					Yes        bool   `json:"yes"`
+				}
+				workDir, err := mcpArgs(raw, &args, cwd, func() string { return args.Subproject })
+				if err != nil {
+					return "", err
+				}
+				var buf bytes.Buffer
+				h := &regenerateHandler{generator: generator, stdin: noStdin}
+				err = h.run(ctx, regenerateOptions{
+					id: args.ID, aspect: args.Aspect, size: args.Size, yes: args.Yes, json: true,
+				}, workDir, &buf)
+				return buf.String(), err
+			},
+		},
+		{
+			Name:        "history",
+			Description: "List history entries of a subproject, newest first, with their outputs and edits.",
+			InputSchema: mcpSchema(nil, map[string]any{
+				"limit":       map[string]any{"type": "integer", "minimum": 0, "description": "Maximum entries (0 = all)"},
+				"search":      map[string]any{"type": "string", "description": "Only entries whose prompt contains this text"},
+				"failed_only": map[string]any{"type": "boolean"},
+				"subproject":  subprojectProp,
+			}),
+			Call: func(_ context.Context, raw json.RawMessage) (string, error) {
+				var args struct {
+					Limit      int    `json:"limit"`
+					Search     string `json:"search"`
+					FailedOnly bool   `json:"failed_only"`
+					Subproject string `json:"subproject"`
+				}
+				workDir, err := mcpArgs(raw, &args, cwd, func() string { return args.Subproject })
+				if err != nil {
+					return "", err
+				}
+				historyDir, err := subprojectHistoryDir(workDir)
+				if err != nil {
+					return "", err
+				}
+				var predicates []history.Predicate
+				if args.Search != "" {
+					predicates = append(predicates, history.PromptContains(args.Search))
+				}
+				if args.FailedOnly {
+					predicates = append(predicates, history.Failed())
+				}
+				entries, err := history.SearchEntries(historyDir, predicates...)
+				if err != nil {
+					return "", fmt.Errorf("failed to load history: %w", err)
+				}
+				var buf bytes.Buffer
+				err = printHistoryJSON(&buf, historyDir, entries, args.Limit)
+				return buf.String(), err
+			},
+		},
+		{
+			Name:        "status",
+			Description: "Show the project and subproject configuration, input images and history summary.",
+			InputSchema: mcpSchema(nil, map[string]any{
+				"subproject": subprojectProp,
+			}),
+			Call: func(_ context.Context, raw json.RawMessage) (string, error) {
+				var args struct {
+					Subproject string `json:"subproject"`
+				}
+				workDir, err := mcpArgs(raw, &args, cwd, func() string { return args.Subproject })
+				if err != nil {
+					return "", err
+				}
+				return mcpStatus(workDir)
+			},
+		},
+	}
+}
+
+// mcpArgs decodes tool arguments into dst and returns the working directory for the call:
+// the named subproject's directory, or cwd when no subproject is given.
+func mcpArgs(raw json.RawMessage, dst any, cwd string, subproject func() string) (string, error) {
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	name := subproject()
+	if name == "" {
+		return cwd, nil
+	}
+	projectRoot, err := project.FindProjectRoot(cwd)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return "", errors.New("banago project not found. Start the MCP server inside a project")
+		}
+		return "", err
+	}
+	subprojectDir := project.GetSubprojectDir(projectRoot, name)
+	if _, err := os.Stat(subprojectDir); err != nil {
+		return "", fmt.Errorf("subproject %s not found", name)
+	}
+	return subprojectDir, nil
+}
+
+// mcpStatus returns the status JSON of the subproject containing workDir
+func mcpStatus(workDir string) (string, error) {
+	projectRoot, err := project.FindProjectRoot(workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return "", errors.New("banago project not found. Run 'banago init' first")
+		}
+		return "", err
+	}
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to load project config: %w", err)
+	}
+
+	var status statusJSON
+	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
+	switch {
+	case errors.Is(err, project.ErrNotInSubproject):
+		status = statusJSON{Project: projectCfg.Name, Model: projectCfg.Model, ReadOnly: requireWritable(projectCfg) != nil}
+	case err != nil:
+		return "", err
+	default:
+		subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
+		subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to load subproject config: %w", err)
+		}
+		status = newSubprojectStatusJSON(projectRoot, projectCfg, subprojectDir, subprojectCfg)
+	}
+
+	var buf bytes.Buffer
+	err = writeJSON(&buf, status)
+	return buf.String(), err
+}
+
+// mcpSchema builds the JSON Schema of a tool's arguments object
+func mcpSchema(required []string, properties map[string]any) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
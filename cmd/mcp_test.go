@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/mcp"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPTools(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// The server runs from the project root; tools select the subproject by name
+	mock := newSuccessMock(pngData)
+	tools := map[string]mcp.Tool{}
+	for _, tool := range mcpTools(mock, projectRoot) {
+		tools[tool.Name] = tool
+	}
+	call := func(name string, args map[string]any) (string, error) {
+		t.Helper()
+		raw, err := json.Marshal(args)
+		require.NoError(t, err)
+		return tools[name].Call(context.Background(), raw)
+	}
+
+	out, err := call("generate", map[string]any{"prompt": "a castle", "subproject": "test-sub"})
+	require.NoError(t, err)
+	var gen generationJSON
+	require.NoError(t, json.Unmarshal([]byte(out), &gen))
+	require.Len(t, gen.Entries, 1)
+	entryID := gen.Entries[0].ID
+
+	out, err = call("edit", map[string]any{"id": entryID, "prompt": "make it night", "subproject": "test-sub"})
+	require.NoError(t, err)
+	assert.Contains(t, out, `"entry_id": "`+entryID+`"`)
+
+	out, err = call("history", map[string]any{"subproject": "test-sub"})
+	require.NoError(t, err)
+	var entries []entryJSON
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, entryID, entries[0].ID)
+	assert.Len(t, entries[0].Edits, 1)
+
+	out, err = call("status", map[string]any{"subproject": "test-sub"})
+	require.NoError(t, err)
+	assert.Contains(t, out, `"subproject": "test-sub"`)
+
+	_, err = call("regenerate", map[string]any{"id": "missing", "subproject": "test-sub"})
+	require.Error(t, err)
+
+	_, err = call("history", map[string]any{"subproject": "nope"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subproject nope not found")
+}
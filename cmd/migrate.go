@@ -1,11 +1,12 @@
 package cmd
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/history"
@@ -22,6 +23,12 @@ This command:
   - Copies input images from inputs/ to each history entry directory
   - Removes context.md and character.md from history entries
   - Updates the project version to 2
+  - Moves the files of history entries into the history_layout of banago.yaml
+    (flat, or nested with outputs/ and inputs/ per entry)
+  - Moves input images copied into history entries into the shared object
+    store (.banago/objects), keeping one snapshot per content
+
+The first three steps only run for version 1 projects.
 
 The migration is idempotent - running it multiple times is safe.`,
 	Args: cobra.NoArgs,
@@ -44,18 +51,36 @@ The migration is idempotent - running it multiple times is safe.`,
 		if err != nil {
 			return fmt.Errorf("failed to load project config: %w", err)
 		}
+		if err := requireWritable(projectCfg); err != nil {
+			return err
+		}
+
+		// Hold every subproject lock so no generation writes history while it is rewritten
+		infos, err := project.ListSubprojectInfos(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to list subprojects: %w", err)
+		}
+		for _, info := range infos {
+			unlock, err := lockSubproject(cmd.Context(), project.GetSubprojectDir(projectRoot, info.Name), true, cmd.ErrOrStderr())
+			if err != nil {
+				return fmt.Errorf("subproject %s: %w", info.Name, err)
+			}
+			defer unlock()
+		}
 
 		// Check version
-		version, err := parseVersion(projectCfg.Version)
+		version, err := config.ParseVersion(projectCfg.Version)
 		if err != nil {
 			return fmt.Errorf("failed to parse project version: %w", err)
 		}
+		w := cmd.OutOrStdout()
 		if version >= 2 {
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Already migrated (version >= 2)")
+			_, _ = fmt.Fprintln(w, "Already migrated (version >= 2)")
+			convertHistoryLayouts(w, projectRoot, projectCfg.HistoryLayout)
+			storeInputSnapshots(w, projectRoot)
 			return nil
 		}
 
-		w := cmd.OutOrStdout()
 		_, _ = fmt.Fprintln(w, "Starting migration...")
 		_, _ = fmt.Fprintln(w, "")
 
@@ -82,7 +107,7 @@ The migration is idempotent - running it multiple times is safe.`,
 				continue
 			}
 
-			subVersion, err := parseVersion(subprojectCfg.Version)
+			subVersion, err := config.ParseVersion(subprojectCfg.Version)
 			if err != nil {
 				failedPaths = append(failedPaths, fmt.Sprintf("%s: invalid version %q", subprojectDir, subprojectCfg.Version))
 				allSubprojectsSuccess = false
@@ -95,7 +120,7 @@ The migration is idempotent - running it multiple times is safe.`,
 				continue
 			}
 
-			historyDir := history.GetHistoryDir(subprojectDir)
+			historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
 			inputsDir := project.GetInputsDir(subprojectDir)
 
 			entries, err := history.ListEntries(historyDir)
@@ -113,7 +138,7 @@ The migration is idempotent - running it multiple times is safe.`,
 				// Copy input images from inputs/ to history entry
 				for _, imgName := range entry.Generation.InputImages {
 					srcPath := filepath.Join(inputsDir, imgName)
-					dstPath := filepath.Join(entryDir, imgName)
+					dstPath := history.GetEntryFilePath(entryDir, imgName)
 
 					// Skip if already exists
 					if _, err := os.Stat(dstPath); err == nil {
@@ -135,7 +160,7 @@ The migration is idempotent - running it multiple times is safe.`,
 				}
 
 				// Remove context.md if exists
-				contextPath := filepath.Join(entryDir, "context.md")
+				contextPath := history.GetEntryFilePath(entryDir, "context.md")
 				if _, err := os.Stat(contextPath); err == nil {
 					if err := os.Remove(contextPath); err != nil {
 						failedPaths = append(failedPaths, fmt.Sprintf("%s: %v", contextPath, err))
@@ -144,7 +169,7 @@ The migration is idempotent - running it multiple times is safe.`,
 				}
 
 				// Remove character.md if exists
-				characterPath := filepath.Join(entryDir, "character.md")
+				characterPath := history.GetEntryFilePath(entryDir, "character.md")
 				if _, err := os.Stat(characterPath); err == nil {
 					if err := os.Remove(characterPath); err != nil {
 						failedPaths = append(failedPaths, fmt.Sprintf("%s: %v", characterPath, err))
@@ -182,37 +207,88 @@ The migration is idempotent - running it multiple times is safe.`,
 		}
 		_, _ = fmt.Fprintln(w, "")
 
-		if len(failedPaths) > 0 {
-			_, _ = fmt.Fprintln(w, "")
-			_, _ = fmt.Fprintln(w, "Failed paths:")
-			for _, p := range failedPaths {
-				_, _ = fmt.Fprintf(w, "  - %s\n", p)
-			}
-		}
+		printFailedPaths(w, failedPaths)
 
+		convertHistoryLayouts(w, projectRoot, projectCfg.HistoryLayout)
+		storeInputSnapshots(w, projectRoot)
 		return nil
 	},
 }
 
-func init() {
-	rootCmd.AddCommand(migrateCmd)
+// storeInputSnapshots moves the input images copied into history entries into the object store
+func storeInputSnapshots(w io.Writer, projectRoot string) {
+	var moved, entries int
+	failedPaths := migrateEntries(projectRoot, func(historyDir string, entry *history.Entry) error {
+		n, err := entry.StoreInputImages(historyDir)
+		if n > 0 {
+			moved += n
+			entries++
+		}
+		return err
+	})
+
+	_, _ = fmt.Fprintf(w, "Input snapshots: %d images of %d entries moved to %s\n", moved, entries, history.ObjectsDir)
+	printFailedPaths(w, failedPaths)
+}
+
+// convertHistoryLayouts moves the files of history entries into layout, the history_layout of the
+// project, including history directories outside it
+func convertHistoryLayouts(w io.Writer, projectRoot, layout string) {
+	layout = cmp.Or(layout, history.LayoutFlat)
+	var converted int
+	failedPaths := migrateEntries(projectRoot, func(historyDir string, entry *history.Entry) error {
+		changed, err := entry.ConvertLayout(historyDir, layout)
+		if changed {
+			converted++
+		}
+		return err
+	})
+
+	_, _ = fmt.Fprintf(w, "History layout: %d entries converted to %s\n", converted, layout)
+	printFailedPaths(w, failedPaths)
 }
 
-// parseVersion parses version string and returns major version number
-func parseVersion(version string) (int, error) {
-	// Handle versions like "1.0", "2", "2.0"
-	if version == "" {
-		return 0, errors.New("empty version")
+// migrateEntries calls fn for every history entry of every subproject and returns the paths it failed on
+func migrateEntries(projectRoot string, fn func(historyDir string, entry *history.Entry) error) []string {
+	infos, err := project.ListSubprojectInfos(projectRoot)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: failed to list subprojects: %v", projectRoot, err)}
 	}
 
-	// Extract major version (before first dot)
-	major := version
-	for i, c := range version {
-		if c == '.' {
-			major = version[:i]
-			break
+	var failedPaths []string
+	for _, info := range infos {
+		subprojectDir := project.GetSubprojectDir(projectRoot, info.Name)
+		subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+		if err != nil {
+			failedPaths = append(failedPaths, fmt.Sprintf("%s/config.yaml: %v", subprojectDir, err))
+			continue
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+		list, err := history.ListEntries(historyDir)
+		if err != nil {
+			failedPaths = append(failedPaths, fmt.Sprintf("%s: %v", historyDir, err))
+			continue
 		}
+		for _, entry := range list {
+			if err := fn(historyDir, entry); err != nil {
+				failedPaths = append(failedPaths, fmt.Sprintf("%s: %v", entry.GetEntryDir(historyDir), err))
+			}
+		}
+	}
+	return failedPaths
+}
+
+func printFailedPaths(w io.Writer, failedPaths []string) {
+	if len(failedPaths) == 0 {
+		return
 	}
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Failed paths:")
+	for _, p := range failedPaths {
+		_, _ = fmt.Fprintf(w, "  - %s\n", p)
+	}
+}
 
-	return strconv.Atoi(major)
+func init() {
+	rootCmd.AddCommand(migrateCmd)
 }
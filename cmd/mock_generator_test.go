@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/blck-snwmn/banago/internal/gemini"
@@ -21,18 +22,24 @@ type mockGenerator struct {
 	responseMIME   string            // MIME type for response images (default: image/png)
 	tokenUsage     gemini.TokenUsage // Token usage to return
 	err            error             // Error to return (if set, overrides success response)
+	hang           bool              // Block until the context ends and fail like gemini.Client
 
 	// Recording fields
 	calls []gemini.Params // Records all Generate calls
 }
 
 // Generate implements generation.Generator.
-func (m *mockGenerator) Generate(_ context.Context, params gemini.Params) *gemini.Result {
+func (m *mockGenerator) Generate(ctx context.Context, params gemini.Params) *gemini.Result {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.calls = append(m.calls, params)
 
+	if m.hang {
+		<-ctx.Done()
+		return &gemini.Result{Error: fmt.Errorf("%w: %w", gemini.ErrCanceled, context.Cause(ctx))}
+	}
+
 	if m.err != nil {
 		return &gemini.Result{Error: m.err}
 	}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// modelCacheFile holds the probed capabilities of models, relative to the project root
+const modelCacheFile = ".banago/models.json"
+
+// modelCacheTTL is how long model info reuses a probe before asking the API again
+const modelCacheTTL = 24 * time.Hour
+
+var modelCmd = &cobra.Command{
+	Use:   "model",
+	Short: "Show what image models support",
+	Long:  "Show the capabilities of image models.",
+}
+
+var modelInfoOpts struct {
+	refresh bool
+	offline bool
+}
+
+var modelInfoCmd = &cobra.Command{
+	Use:   "info [model]",
+	Short: "Show the aspect ratios, sizes, input limit and pricing of a model",
+	Long: `Show the aspect ratios, image sizes, maximum input images, token limits
+and pricing of a model (default: the model of banago.yaml).
+
+The API is asked whether the model exists and for its token limits; what it
+does not report comes from the table bundled with banago. Inside a project
+the answer is cached in .banago/models.json for a day, and generate and
+regenerate check aspect ratios, sizes and input counts against it before
+calling the API. Without an API key, or with --offline, the bundled table
+is shown.
+
+Examples:
+  banago model info
+  banago model info gemini-2.5-flash-image --offline
+  banago model info --refresh --output json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Outside a project the default model is shown and nothing is cached
+		var projectRoot string
+		var projectCfg *config.ProjectConfig
+		model := config.DefaultModel
+		if cwd, err := os.Getwd(); err == nil {
+			if root, err := project.FindProjectRoot(cwd); err == nil {
+				if projectCfg, err = config.LoadProjectConfig(root); err != nil {
+					return fmt.Errorf("failed to load project config: %w", err)
+				}
+				projectRoot = root
+				model = cmp.Or(projectCfg.Model, model)
+			}
+		}
+		if len(args) == 1 {
+			model = args[0]
+		}
+
+		caps, err := modelInfo(cmd, projectRoot, projectCfg, model)
+		if err != nil {
+			return err
+		}
+		w := cmd.OutOrStdout()
+		if jsonOutput() {
+			return writeJSON(w, caps)
+		}
+		printCapabilities(w, caps)
+		return nil
+	},
+}
+
+// modelInfo returns the capabilities of model: a fresh cached probe, a new probe, or the bundled table
+func modelInfo(cmd *cobra.Command, projectRoot string, projectCfg *config.ProjectConfig, model string) (*gemini.Capabilities, error) {
+	if !modelInfoOpts.offline && !modelInfoOpts.refresh {
+		if cached := cachedCapabilities(projectRoot, model); cached != nil {
+			if fetched, err := time.Parse(time.RFC3339, cached.FetchedAt); err == nil && time.Since(fetched) < modelCacheTTL {
+				return cached, nil
+			}
+		}
+	}
+
+	if modelInfoOpts.offline || requireAPIKey() != nil {
+		caps, ok := gemini.BundledCapabilities(model)
+		if !ok {
+			return nil, fmt.Errorf("model %s is not in the bundled table; set GEMINI_API_KEY to ask the API", model)
+		}
+		return &caps, nil
+	}
+
+	client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+	if err != nil {
+		return nil, err
+	}
+	caps, err := client.ProbeCapabilities(cmd.Context(), model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up model %s: %w", model, err)
+	}
+	if projectRoot != "" && requireWritable(projectCfg) == nil {
+		if err := gemini.SaveCapabilityCache(filepath.Join(projectRoot, modelCacheFile), caps); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+		}
+	}
+	return caps, nil
+}
+
+// cachedCapabilities returns the probed capabilities of model cached in projectRoot, or nil if there are none
+func cachedCapabilities(projectRoot, model string) *gemini.Capabilities {
+	if projectRoot == "" {
+		return nil
+	}
+	return gemini.LoadCapabilityCache(filepath.Join(projectRoot, modelCacheFile))[model]
+}
+
+func printCapabilities(w io.Writer, caps *gemini.Capabilities) {
+	orUnknown := func(values []string) string {
+		if len(values) == 0 {
+			return "unknown"
+		}
+		return strings.Join(values, ", ")
+	}
+
+	name := caps.Model
+	if caps.DisplayName != "" {
+		name += " (" + caps.DisplayName + ")"
+	}
+	_, _ = fmt.Fprintf(w, "Model:         %s\n", name)
+	_, _ = fmt.Fprintf(w, "Aspect ratios: %s\n", orUnknown(caps.AspectRatios))
+	_, _ = fmt.Fprintf(w, "Image sizes:   %s\n", orUnknown(caps.ImageSizes))
+	if caps.MaxInputImages > 0 {
+		_, _ = fmt.Fprintf(w, "Input images:  up to %d\n", caps.MaxInputImages)
+	} else {
+		_, _ = fmt.Fprintln(w, "Input images:  unknown")
+	}
+	if caps.InputTokenLimit > 0 || caps.OutputTokenLimit > 0 {
+		_, _ = fmt.Fprintf(w, "Token limits:  %d input, %d output\n", caps.InputTokenLimit, caps.OutputTokenLimit)
+	}
+	if caps.Pricing != nil {
+		_, _ = fmt.Fprintf(w, "Pricing:       $%.2f input, $%.2f output per million tokens\n", caps.Pricing.InputPerMillion, caps.Pricing.OutputPerMillion)
+	} else {
+		_, _ = fmt.Fprintln(w, "Pricing:       unknown")
+	}
+	if caps.Source == gemini.CapabilitiesAPI {
+		_, _ = fmt.Fprintf(w, "Source:        API, %s\n", history.FormatLocal(caps.FetchedAt, time.Now()))
+	} else {
+		_, _ = fmt.Fprintln(w, "Source:        table bundled with this banago version")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(modelCmd)
+	modelCmd.AddCommand(modelInfoCmd)
+
+	modelInfoCmd.Flags().BoolVar(&modelInfoOpts.refresh, "refresh", false, "Ask the API again instead of using the cache")
+	modelInfoCmd.Flags().BoolVar(&modelInfoOpts.offline, "offline", false, "Show the bundled table without calling the API")
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/platform"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var editPromptOpts struct {
+	id string
+}
+
+var editPromptCmd = &cobra.Command{
+	Use:   "edit-prompt",
+	Short: "Edit the working prompt starting from a history entry",
+	Long: `Copy the prompt of a history entry into the subproject's prompt.txt, like
+'banago prompt restore', and open it in $VISUAL or $EDITOR (default: vi,
+notepad on Windows). The previous prompt.txt is kept as prompt.txt.bak.
+
+Examples:
+  banago edit-prompt --id <uuid>
+  EDITOR="code --wait" banago edit-prompt --id <uuid>`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		subprojectDir, subprojectCfg, err := resolveCurrentSubproject(true)
+		if err != nil {
+			return err
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+
+		entry, err := history.GetEntryByID(historyDir, editPromptOpts.id)
+		if err != nil {
+			return fmt.Errorf("failed to get history entry: %w", err)
+		}
+		prompt, err := history.LoadPrompt(entry.GetEntryDir(historyDir))
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		backedUp, err := restoreWorkingPrompt(subprojectDir, prompt)
+		if err != nil {
+			return err
+		}
+		if backedUp != "" {
+			_, _ = fmt.Fprintf(w, "Backed up current prompt to %s\n", backedUp)
+		}
+		if err := platform.Edit(filepath.Join(subprojectDir, workingPromptFile)); err != nil {
+			return err
+		}
+
+		var projectCfg *config.ProjectConfig
+		if projectRoot, err := project.FindProjectRoot(subprojectDir); err == nil {
+			projectCfg, _ = config.LoadProjectConfig(projectRoot)
+		}
+		printNextSteps(w, projectCfg, "banago generate -F "+workingPromptFile)
+		return nil
+	},
+}
+
+var openDirOpts struct {
+	id    string
+	print bool
+}
+
+var openDirCmd = &cobra.Command{
+	Use:   "open-dir",
+	Short: "Open a history entry directory in the file manager",
+	Long: `Open the directory of a history entry in the system file manager
+(open on macOS, explorer on Windows, xdg-open elsewhere).
+
+With --print the path is printed instead, for terminals without a desktop or
+for use in scripts.
+
+Examples:
+  banago open-dir --id <uuid>
+  cd "$(banago open-dir --id <uuid> --print)"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		subprojectDir, subprojectCfg, err := resolveCurrentSubproject(false)
+		if err != nil {
+			return err
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+
+		entry, err := history.GetEntryByID(historyDir, openDirOpts.id)
+		if err != nil {
+			return fmt.Errorf("failed to get history entry: %w", err)
+		}
+		entryDir, err := filepath.Abs(entry.GetEntryDir(historyDir))
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if openDirOpts.print {
+			_, _ = fmt.Fprintln(w, entryDir)
+			return nil
+		}
+		if err := platform.Open(entryDir); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Opened %s\n", entryDir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editPromptCmd)
+	rootCmd.AddCommand(openDirCmd)
+
+	editPromptCmd.Flags().StringVar(&editPromptOpts.id, "id", "", "History entry ID whose prompt to start from")
+	_ = editPromptCmd.MarkFlagRequired("id")
+
+	openDirCmd.Flags().StringVar(&openDirOpts.id, "id", "", "History entry ID whose directory to open")
+	openDirCmd.Flags().BoolVar(&openDirOpts.print, "print", false, "Print the directory instead of opening it")
+	_ = openDirCmd.MarkFlagRequired("id")
+}
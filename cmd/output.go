@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+)
+
+// Output formats for the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// eventsNDJSON is the format of the global --events flag: newline-delimited JSON
+const eventsNDJSON = "ndjson"
+
+// errConfirmRequired is returned when a confirmation would be needed in JSON, events or --quiet mode
+var errConfirmRequired = errors.New("confirmation required. Pass --yes when using --output json, --events or --quiet")
+
+// validateOutputFormat checks the value of the --output flag
+func validateOutputFormat() error {
+	switch cfg.output {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q: must be %s or %s", cfg.output, outputText, outputJSON)
+	}
+}
+
+// validateEventsFormat checks the value of the --events flag.
+// Events replace the text output, so they cannot be combined with --output json.
+func validateEventsFormat() error {
+	switch cfg.events {
+	case "":
+		return nil
+	case eventsNDJSON:
+		if jsonOutput() {
+			return errors.New("--events cannot be combined with --output json")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid events format %q: must be %s", cfg.events, eventsNDJSON)
+	}
+}
+
+// eventsOutput reports whether commands should stream progress events instead of human text
+func eventsOutput() bool {
+	return cfg.events == eventsNDJSON
+}
+
+// ndjsonEvents returns an event sink writing one JSON object per line to w
+func ndjsonEvents(w io.Writer) generation.EventSink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e generation.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(e)
+	}
+}
+
+// jsonOutput reports whether commands should emit JSON instead of human text
+func jsonOutput() bool {
+	return cfg.output == outputJSON
+}
+
+// printNextSteps prints numbered next-step hints after a command's summary.
+// Nothing is printed with --no-hints or hints: false in banago.yaml (projectCfg may be nil).
+func printNextSteps(w io.Writer, projectCfg *config.ProjectConfig, steps ...string) {
+	if cfg.noHints || (projectCfg != nil && !projectCfg.HintsEnabled()) {
+		return
+	}
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Next steps:")
+	for i, step := range steps {
+		_, _ = fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+	}
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// jsonError is the JSON document printed when a command fails in JSON mode
+type jsonError struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"` // see errorCodes; empty for failures without a code
+}
+
+// errorCodes are the machine-readable codes of the failures scripts can react to, checked in order
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{history.ErrEntryNotFound, "entry_not_found"},
+	{history.ErrNotFinalized, "entry_pending"},
+	{history.ErrIncompatibleSchema, "incompatible_schema"},
+	{config.ErrConfigNotFound, "config_not_found"},
+	{config.ErrInvalidConfig, "invalid_config"},
+	{gemini.ErrSafetyBlocked, "safety_blocked"},
+	{gemini.ErrQuota, "quota_exceeded"},
+	{gemini.ErrNoImage, "no_image"},
+	{gemini.ErrCanceled, "canceled"},
+	{gemini.ErrNoProvenance, "no_metadata"},
+	{generation.ErrInvalidSpec, "invalid_spec"},
+	{generation.ErrInsufficientDisk, "insufficient_disk"},
+	{project.ErrSubprojectLocked, "subproject_locked"},
+	{project.ErrWorkspaceFull, "workspace_full"},
+	{project.ErrPromptNotFound, "prompt_not_found"},
+	{errConfirmRequired, "confirmation_required"},
+	{errBundleTampered, "bundle_tampered"},
+}
+
+// errorCode returns the code of the first known failure in err's chain, or ""
+func errorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// entryJSON describes a history entry in JSON output
+type entryJSON struct {
+	ID          string            `json:"id"`
+	SourceID    string            `json:"source_id,omitempty"`
+	CreatedAt   string            `json:"created_at,omitempty"`
+	Success     bool              `json:"success"`
+	Dir         string            `json:"dir"`
+	Outputs     []string          `json:"outputs"`
+	TokenUsage  gemini.TokenUsage `json:"token_usage"`
+	Error       string            `json:"error,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Rating      int               `json:"rating,omitempty"`
+	Evaluations []evaluationJSON  `json:"evaluations,omitempty"`
+	Edits       []editJSON        `json:"edits,omitempty"`
+}
+
+// evaluationJSON describes the verdict of an evaluator in JSON output
+type evaluationJSON struct {
+	Evaluator string `json:"evaluator"`
+	Passed    bool   `json:"passed"`
+	Notes     string `json:"notes,omitempty"`
+}
+
+// editJSON describes an edit entry in JSON output
+type editJSON struct {
+	ID         string            `json:"id"`
+	EntryID    string            `json:"entry_id"`
+	CreatedAt  string            `json:"created_at,omitempty"`
+	Success    bool              `json:"success"`
+	Dir        string            `json:"dir"`
+	Outputs    []string          `json:"outputs"`
+	TokenUsage gemini.TokenUsage `json:"token_usage"`
+}
+
+// absDir returns an absolute form of dir, falling back to dir itself
+func absDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// newEntryJSON converts a history entry, with output paths made absolute
+func newEntryJSON(historyDir string, e *history.Entry) entryJSON {
+	historyDir = absDir(historyDir)
+	entryDir := e.GetEntryDir(historyDir)
+	out := entryJSON{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Success:    e.Result.Success,
+		Dir:        entryDir,
+		Outputs:    []string{},
+		TokenUsage: e.Result.TokenUsage,
+		Error:      e.Result.ErrorMessage,
+		Tags:       e.Tags,
+		Rating:     e.Rating,
+	}
+	for _, img := range e.Result.OutputImages {
+		out.Outputs = append(out.Outputs, e.OutputPath(historyDir, img))
+	}
+	for _, ev := range e.Result.Evaluations {
+		out.Evaluations = append(out.Evaluations, evaluationJSON{Evaluator: ev.Evaluator, Passed: ev.Passed, Notes: ev.Notes})
+	}
+	return out
+}
+
+// newEditJSON converts an edit entry, with output paths made absolute
+func newEditJSON(entryDir string, e *history.EditEntry) editJSON {
+	entryDir = absDir(entryDir)
+	out := editJSON{
+		ID:         e.ID,
+		EntryID:    filepath.Base(entryDir),
+		CreatedAt:  e.CreatedAt,
+		Success:    e.Result.Success,
+		Dir:        e.GetEditEntryDir(entryDir),
+		Outputs:    []string{},
+		TokenUsage: e.Result.TokenUsage,
+	}
+	for _, img := range e.Result.OutputImages {
+		out.Outputs = append(out.Outputs, history.GetEditOutputPath(entryDir, e.ID, img))
+	}
+	return out
+}
+
+// generationJSON is the JSON output of generate and regenerate
+type generationJSON struct {
+	SourceID   string            `json:"source_id,omitempty"`
+	Entries    []entryJSON       `json:"entries"`
+	TokenUsage gemini.TokenUsage `json:"token_usage"`
+}
+
+// newGenerationJSON converts generation results into JSON output
+func newGenerationJSON(historyDir, sourceID string, results []*generation.Result) generationJSON {
+	out := generationJSON{SourceID: sourceID, Entries: []entryJSON{}}
+	for _, r := range results {
+		out.Entries = append(out.Entries, newEntryJSON(historyDir, &history.Entry{
+			ID: r.EntryID,
+			Result: history.Result{
+				Success:      true,
+				OutputImages: r.OutputImages,
+				TokenUsage:   r.TokenUsage,
+			},
+		}))
+		out.TokenUsage.Add(r.TokenUsage)
+	}
+	return out
+}
+
+// dryRunJSON is the JSON output of --dry-run
+type dryRunJSON struct {
+	DryRun   bool                `json:"dry_run"`
+	Requests []dryRunRequestJSON `json:"requests"`
+}
+
+// dryRunRequestJSON describes one API call a --dry-run resolved
+type dryRunRequestJSON struct {
+	SourceID              string   `json:"source_id,omitempty"`
+	Generation            int      `json:"generation,omitempty"` // set when each generation of a batch sends its own request
+	Model                 string   `json:"model"`
+	Prompt                string   `json:"prompt"`
+	InputImages           []string `json:"input_images"`
+	OmittedInputs         []string `json:"omitted_inputs,omitempty"`
+	AspectRatio           string   `json:"aspect_ratio,omitempty"`
+	ImageSize             string   `json:"image_size,omitempty"`
+	Seed                  *int32   `json:"seed,omitempty"` // omitted when the run would pick a random seed
+	Candidates            int      `json:"candidates"`
+	Count                 int      `json:"count"`
+	EstimatedInputTokens  int      `json:"estimated_input_tokens"`
+	EstimatedOutputTokens int      `json:"estimated_output_tokens"`
+	EstimatedUSD          *float64 `json:"estimated_usd,omitempty"` // all generations, omitted for models without pricing
+}
+
+// newDryRunJSON converts the requests of a --dry-run to their JSON document
+func newDryRunJSON(requests []dryRunRequest) dryRunJSON {
+	out := dryRunJSON{DryRun: true, Requests: []dryRunRequestJSON{}}
+	for _, req := range requests {
+		params, est := req.result.Params, req.result.Estimate
+		r := dryRunRequestJSON{
+			SourceID:              req.sourceID,
+			Generation:            req.generation,
+			Model:                 params.Model,
+			Prompt:                params.Prompt,
+			InputImages:           append([]string{}, params.ImagePaths...),
+			OmittedInputs:         req.result.Omitted,
+			AspectRatio:           params.AspectRatio,
+			ImageSize:             params.ImageSize,
+			Seed:                  params.Seed,
+			Candidates:            max(params.Candidates, 1),
+			Count:                 max(req.count, 1),
+			EstimatedInputTokens:  est.InputTokens,
+			EstimatedOutputTokens: est.OutputTokens,
+		}
+		if est.Known {
+			usd := est.USD * float64(r.Count)
+			r.EstimatedUSD = &usd
+		}
+		out.Requests = append(out.Requests, r)
+	}
+	return out
+}
+
+// previewJSON is the JSON output of preview
+type previewJSON struct {
+	Prompt        string   `json:"prompt"`
+	InputImages   []string `json:"input_images"`
+	OmittedInputs []string `json:"omitted_inputs,omitempty"`
+	HTMLPath      string   `json:"html_path,omitempty"`
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var outputsCmd = &cobra.Command{
+	Use:   "outputs",
+	Short: "Manage output images of history entries",
+	Long:  "Manage output images stored in history entries.",
+}
+
+var outputsRmOpts struct {
+	id     string
+	latest bool
+	yes    bool
+}
+
+var outputsRmCmd = &cobra.Command{
+	Use:   "rm <pattern>...",
+	Short: "Remove output images from a history entry",
+	Long: `Remove output images matching glob patterns from a history entry.
+
+Patterns are matched against output filenames, and meta.yaml is updated
+to drop the removed outputs. Outputs used as the source of an edit
+cannot be removed, and at least one output must remain.
+
+Examples:
+  banago outputs rm --id <uuid> 'output-*-3.png'
+  banago outputs rm --latest 'output-*-2.png' 'output-*-4.png'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+
+		var entry *history.Entry
+		if outputsRmOpts.latest {
+			entry, err = history.GetLatestEntry(historyDir)
+			if err != nil {
+				return fmt.Errorf("failed to get latest history: %w", err)
+			}
+			if !outputsRmOpts.yes {
+				if err := confirmLatest(cmd.InOrStdin(), cmd.OutOrStdout(), hist.subprojectName, historyDir, entry); err != nil {
+					return err
+				}
+			}
+		} else {
+			entry, err = history.GetEntryByID(historyDir, outputsRmOpts.id)
+			if err != nil {
+				return fmt.Errorf("failed to get history entry: %w", err)
+			}
+		}
+
+		removed, err := entry.RemoveOutputs(historyDir, args)
+		if err != nil {
+			return fmt.Errorf("failed to remove outputs: %w", err)
+		}
+
+		w := cmd.OutOrStdout()
+		if len(removed) == 0 {
+			_, _ = fmt.Fprintln(w, "No outputs matched")
+			return nil
+		}
+
+		_, _ = fmt.Fprintf(w, "Removed from %s:\n", entry.ID)
+		for _, img := range removed {
+			_, _ = fmt.Fprintf(w, "  %s\n", img)
+		}
+
+		return nil
+	},
+}
+
+var outputsNormalizeOpts struct {
+	dryRun bool
+}
+
+var outputsNormalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "Rename outputs to the output-<run>-<n> naming scheme",
+	Long: `Rename outputs of every entry and edit in the current subproject whose
+filenames do not follow the naming scheme banago writes, output-<run UUID>-<n>.<ext>
+(for example output_1.png placed by hand or by other tools), so that tools
+globbing output-* find every output.
+
+meta.yaml, edit-meta.yaml and edits using a renamed output as their source are
+updated. Mirror folders are not touched.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var historyDir string
+		var err error
+		unlock := func() {}
+		if outputsNormalizeOpts.dryRun {
+			historyDir, err = currentHistoryDir()
+		} else {
+			var hist *writableHistory
+			if hist, err = openWritableHistory(cmd.Context(), cmd.ErrOrStderr()); err == nil {
+				historyDir, unlock = hist.dir, hist.unlock
+			}
+		}
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		renames, err := history.NormalizeOutputNames(historyDir, outputsNormalizeOpts.dryRun)
+		w := cmd.OutOrStdout()
+		for _, r := range renames {
+			owner := r.EntryID
+			if r.EditID != "" {
+				owner += "/edits/" + r.EditID
+			}
+			_, _ = fmt.Fprintf(w, "%s: %s -> %s\n", owner, r.From, r.To)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rename outputs: %w", err)
+		}
+
+		switch {
+		case len(renames) == 0:
+			_, _ = fmt.Fprintln(w, "All outputs already follow the naming scheme")
+		case outputsNormalizeOpts.dryRun:
+			_, _ = fmt.Fprintf(w, "Would rename %d outputs\n", len(renames))
+		default:
+			_, _ = fmt.Fprintf(w, "Renamed %d outputs\n", len(renames))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outputsCmd)
+	outputsCmd.AddCommand(outputsRmCmd)
+	outputsCmd.AddCommand(outputsNormalizeCmd)
+
+	outputsRmCmd.Flags().StringVar(&outputsRmOpts.id, "id", "", "History entry ID")
+	outputsRmCmd.Flags().BoolVar(&outputsRmOpts.latest, "latest", false, "Use the latest history entry")
+	outputsRmCmd.Flags().BoolVarP(&outputsRmOpts.yes, "yes", "y", false, "Skip confirmation for --latest")
+
+	outputsRmCmd.MarkFlagsOneRequired("id", "latest")
+	outputsRmCmd.MarkFlagsMutuallyExclusive("id", "latest")
+
+	outputsNormalizeCmd.Flags().BoolVar(&outputsNormalizeOpts.dryRun, "dry-run", false, "Show the renames without changing anything")
+}
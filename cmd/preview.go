@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+type previewOptions struct {
+	generate generateOptions // prompt and input flags, resolved exactly as generate does
+	html     bool            // write an HTML page with the input images instead of printing the prompt
+	open     bool            // open the HTML page in the default browser
+	json     bool
+}
+
+// previewHandler handles the preview command with dependency injection support.
+type previewHandler struct {
+	stderr io.Writer               // receives warnings so stdout carries only the prompt (default: io.Discard)
+	open   func(path string) error // opens the HTML page (default: platform.Open)
+}
+
+var previewOpts previewOptions
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Show the prompt a generation would send",
+	Long: `Show the final prompt generate would send, after the prompt template,
+--with-context and the character preset are applied, without calling the API.
+
+The prompt is printed to stdout as is, so it can be piped or diffed. With
+--html a self-contained page with the prompt, the generation parameters and
+the input images inlined is written to the temp directory instead, and its
+path printed; --open also opens it in the browser.
+
+Examples:
+  banago preview -p "The fox jumps" --with-context
+  banago preview --prompt-name hero | less
+  banago preview -F prompt.txt --html --open`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		handler := &previewHandler{stderr: cmd.ErrOrStderr(), open: platform.Open}
+		previewOpts.json = jsonOutput()
+		return handler.run(cmd.Context(), previewOpts, cwd, cmd.OutOrStdout())
+	},
+}
+
+// run executes the preview command logic.
+// This method is independent of cobra.Command for testability.
+func (h *previewHandler) run(ctx context.Context, opts previewOptions, workDir string, w io.Writer) error {
+	stderr := h.stderr
+	if stderr == nil || opts.json {
+		stderr = io.Discard
+	}
+
+	genOpts := opts.generate
+	genOpts.dryRun = true
+	spec, target, err := resolveGenerateSpec(genOpts, workDir, stderr)
+	if err != nil {
+		return err
+	}
+	result, err := generation.NewService(nil).DryRun(ctx, spec, target.historyDir, stderr)
+	if err != nil {
+		return err
+	}
+
+	var htmlPath string
+	if opts.html || opts.open {
+		if htmlPath, err = writePreviewHTML(result); err != nil {
+			return err
+		}
+		if opts.open {
+			open := h.open
+			if open == nil {
+				open = platform.Open
+			}
+			if err := open(htmlPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.json {
+		return writeJSON(w, previewJSON{
+			Prompt:        result.Params.Prompt,
+			InputImages:   append([]string{}, result.Params.ImagePaths...),
+			OmittedInputs: result.Omitted,
+			HTMLPath:      htmlPath,
+		})
+	}
+	if htmlPath != "" {
+		_, _ = fmt.Fprintln(w, htmlPath)
+		return nil
+	}
+	_, _ = fmt.Fprintln(w, result.Params.Prompt)
+	return nil
+}
+
+var previewHTMLTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>banago preview</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+img { max-width: 32em; max-height: 32em; display: block; margin-bottom: 0.5em; }
+figure { margin: 0 0 1.5em 0; }
+pre { white-space: pre-wrap; background: #f5f5f5; padding: 1em; }
+td, th { padding: 0.2em 1em; text-align: left; }
+.omitted { color: #a00; }
+</style>
+</head>
+<body>
+<h1>Preview</h1>
+<table>
+<tr><td>model</td><td>{{.Params.Model}}</td></tr>
+<tr><td>aspect_ratio</td><td>{{or .Params.AspectRatio "(none)"}}</td></tr>
+<tr><td>image_size</td><td>{{or .Params.ImageSize "(none)"}}</td></tr>
+<tr><td>candidates</td><td>{{.Candidates}}</td></tr>
+</table>
+<h2>Prompt</h2>
+<pre>{{.Params.Prompt}}</pre>
+<h2>Input images ({{len .Images}})</h2>
+{{range .Images}}<figure><img src="{{.URL}}" alt="{{.Alt}}"><figcaption>{{.Alt}}</figcaption></figure>
+{{end}}{{range .Omitted}}<p class="omitted">{{.}} (omitted by token_budget)</p>
+{{end}}</body>
+</html>
+`))
+
+// writePreviewHTML writes a self-contained page of the request, with the input images embedded as
+// data URLs, to a new file in the temp directory and returns its path
+func writePreviewHTML(result *generation.DryRunResult) (string, error) {
+	var images []embeddedImage
+	for _, path := range result.Params.ImagePaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input image: %w", err)
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(path))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		images = append(images, embeddedImage{
+			URL: template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)),
+			Alt: filepath.Base(path),
+		})
+	}
+
+	f, err := os.CreateTemp("", "banago-preview-*.html")
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data := struct {
+		Params     gemini.Params
+		Candidates int
+		Images     []embeddedImage
+		Omitted    []string
+	}{
+		Params:     result.Params,
+		Candidates: max(result.Params.Candidates, 1),
+		Images:     images,
+		Omitted:    result.Omitted,
+	}
+	if err := previewHTMLTemplate.Execute(f, data); err != nil {
+		return "", fmt.Errorf("failed to write HTML file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+
+	previewCmd.Flags().StringVarP(&previewOpts.generate.prompt, "prompt", "p", "", "Prompt for generation")
+	previewCmd.Flags().StringVarP(&previewOpts.generate.promptFile, "prompt-file", "F", "", "Path to text file containing prompt")
+	previewCmd.Flags().StringVar(&previewOpts.generate.promptName, "prompt-name", "", "Name of a prompt in the subproject's prompts/ library")
+	previewCmd.Flags().StringVar(&previewOpts.generate.aspect, "aspect", "", "Output image aspect ratio (e.g., 1:1, 16:9)")
+	previewCmd.Flags().StringVar(&previewOpts.generate.size, "size", "", "Output image size (1K / 2K / 4K)")
+	previewCmd.Flags().StringVar(&previewOpts.generate.model, "model", "", "Model instead of banago.yaml's")
+	previewCmd.Flags().StringArrayVar(&previewOpts.generate.inputs, "input", nil, "Input image instead of input_images (repeatable)")
+	previewCmd.Flags().StringVar(&previewOpts.generate.inputsGlob, "inputs-glob", "", "Glob selecting input images in inputs/ (e.g. 'pose-*.png')")
+	previewCmd.Flags().BoolVar(&previewOpts.generate.noInputImages, "no-input-images", false, "Preview a generation from the prompt alone, ignoring input_images")
+	previewCmd.Flags().BoolVar(&previewOpts.generate.template, "template", false, "Render the prompt as a template, as the next generation would")
+	previewCmd.Flags().BoolVar(&previewOpts.generate.withContext, "with-context", false, "Prepend context.md and the character file to the prompt")
+	previewCmd.Flags().BoolVar(&previewOpts.html, "html", false, "Write an HTML page with the prompt and inlined input images to the temp directory")
+	previewCmd.Flags().BoolVar(&previewOpts.open, "open", false, "Open the HTML page in the default browser (implies --html)")
+
+	previewCmd.MarkFlagsMutuallyExclusive("no-input-images", "input")
+	previewCmd.MarkFlagsMutuallyExclusive("no-input-images", "inputs-glob")
+	previewCmd.MarkFlagsOneRequired("prompt", "prompt-file", "prompt-name")
+	previewCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file", "prompt-name")
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewHandler_Run(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	require.NoError(t, os.WriteFile(project.GetProjectContextPath(projectRoot), []byte("Picture book series.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(subprojectDir, "context.md"), []byte("Autumn forest.\n"), 0o644))
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// stdout carries exactly the prompt that would be sent
+	var stderr bytes.Buffer
+	handler := &previewHandler{stderr: &stderr}
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), previewOptions{generate: generateOptions{
+		prompt:      "The fox jumps",
+		withContext: true,
+	}}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Picture book series.\n\nAutumn forest.\n\nThe fox jumps\n", buf.String())
+
+	// Templates render as the next generation would
+	buf.Reset()
+	err = handler.run(context.Background(), previewOptions{generate: generateOptions{
+		prompt:   "Take {{.Counter}}",
+		template: true,
+	}}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Take 1\n", buf.String())
+
+	// --html writes a page with the inputs inlined and prints its path
+	var opened string
+	handler.open = func(path string) error {
+		opened = path
+		return nil
+	}
+	buf.Reset()
+	err = handler.run(context.Background(), previewOptions{
+		generate: generateOptions{prompt: "The fox <jumps>"},
+		open:     true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	htmlPath := strings.TrimSpace(buf.String())
+	t.Cleanup(func() { _ = os.Remove(htmlPath) })
+	assert.Equal(t, htmlPath, opened)
+	page, err := os.ReadFile(htmlPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "The fox &lt;jumps&gt;")
+	assert.Contains(t, string(page), `src="data:image/png;base64,`)
+	assert.Contains(t, string(page), "test.png")
+
+	buf.Reset()
+	err = handler.run(context.Background(), previewOptions{
+		generate: generateOptions{prompt: "The fox jumps"},
+		json:     true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	var out previewJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "The fox jumps", out.Prompt)
+	assert.Equal(t, []string{filepath.Join(project.GetInputsDir(subprojectDir), "test.png")}, out.InputImages)
+	assert.Empty(t, out.HTMLPath)
+
+	// Nothing reaches history
+	entries, err := history.ListEntries(filepath.Join(subprojectDir, "history"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
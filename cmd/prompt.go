@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// workingPromptFile is the prompt file kept in the subproject directory and passed to --prompt-file
+const workingPromptFile = "prompt.txt"
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Manage the working prompt and prompt library of a subproject",
+	Long: `Manage the working prompt file (prompt.txt) in the current subproject directory
+and the subproject's prompt library: reusable prompts kept as prompts/<name>.txt.
+
+  banago prompt save sunset -p "the castle at sunset, warm light"
+  banago generate --prompt-name sunset`,
+}
+
+var promptListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the prompts in the prompt library",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		subprojectDir, _, err := resolveCurrentSubproject(false)
+		if err != nil {
+			return err
+		}
+		names, err := project.ListPrompts(subprojectDir)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if len(names) == 0 {
+			_, _ = fmt.Fprintln(w, "No prompts. Add one with 'banago prompt save <name>'")
+			return nil
+		}
+		for _, name := range names {
+			text, err := project.ReadPrompt(subprojectDir, name)
+			if err != nil {
+				_, _ = fmt.Fprintf(w, "%s\t(%v)\n", name, err)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", name, promptPreview(text))
+		}
+		return nil
+	},
+}
+
+var promptSaveOpts struct {
+	prompt     string
+	promptFile string
+	id         string
+	force      bool
+}
+
+var promptSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a prompt to the prompt library",
+	Long: `Save a prompt as prompts/<name>.txt in the current subproject.
+
+The prompt comes from --prompt, --prompt-file, the prompt of a history entry (--id)
+or, by default, the working prompt.txt. An existing prompt is only replaced with --force.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		subprojectDir, subprojectCfg, err := resolveCurrentSubproject(true)
+		if err != nil {
+			return err
+		}
+
+		var text string
+		switch {
+		case promptSaveOpts.id != "":
+			historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+			entry, err := history.GetEntryByID(historyDir, promptSaveOpts.id)
+			if err != nil {
+				return fmt.Errorf("failed to get history entry: %w", err)
+			}
+			if text, err = history.LoadPrompt(entry.GetEntryDir(historyDir)); err != nil {
+				return err
+			}
+		case promptSaveOpts.prompt != "" || promptSaveOpts.promptFile != "":
+			if text, err = resolvePrompt(promptSaveOpts.prompt, promptSaveOpts.promptFile); err != nil {
+				return err
+			}
+		default:
+			if text, err = resolvePrompt("", filepath.Join(subprojectDir, workingPromptFile)); err != nil {
+				return err
+			}
+		}
+
+		name := args[0]
+		if err := project.SavePrompt(subprojectDir, name, text, promptSaveOpts.force); err != nil {
+			if errors.Is(err, project.ErrPromptExists) {
+				return fmt.Errorf("%w. Pass --force to replace it", err)
+			}
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Saved prompt %s to prompts/%s.txt\n", name, name)
+		return nil
+	},
+}
+
+var promptUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Copy a prompt of the library to the working prompt",
+	Long: `Copy prompts/<name>.txt to the subproject's prompt.txt to edit it before generating.
+
+The current prompt.txt is kept as prompt.txt.bak, as with 'banago prompt restore'.
+To generate from a library prompt as is, use 'banago generate --prompt-name <name>'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		subprojectDir, _, err := resolveCurrentSubproject(true)
+		if err != nil {
+			return err
+		}
+		text, err := project.ReadPrompt(subprojectDir, args[0])
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		backedUp, err := restoreWorkingPrompt(subprojectDir, text+"\n")
+		if err != nil {
+			return err
+		}
+		if backedUp != "" {
+			_, _ = fmt.Fprintf(w, "Backed up current prompt to %s\n", backedUp)
+		}
+		_, _ = fmt.Fprintf(w, "Copied prompt %s to %s\n", args[0], workingPromptFile)
+		return nil
+	},
+}
+
+// promptPreviewLen is the length at which prompt list cuts off the first line of a prompt
+const promptPreviewLen = 60
+
+// promptPreview returns the first line of a prompt, shortened to promptPreviewLen characters
+func promptPreview(text string) string {
+	line, _, more := strings.Cut(text, "\n")
+	if runes := []rune(line); len(runes) > promptPreviewLen {
+		return string(runes[:promptPreviewLen]) + "..."
+	}
+	if more {
+		return line + " ..."
+	}
+	return line
+}
+
+var promptRestoreOpts struct {
+	id string
+}
+
+var promptRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the working prompt from a history entry",
+	Long: `Copy the prompt of a history entry back into the subproject's prompt.txt.
+
+The current prompt.txt is kept as prompt.txt.bak before it is overwritten,
+so the next generate can reuse the wording of an earlier entry:
+
+  banago prompt restore --id <uuid>
+  banago generate -F prompt.txt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		subprojectDir, subprojectCfg, err := resolveCurrentSubproject(true)
+		if err != nil {
+			return err
+		}
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+
+		entry, err := history.GetEntryByID(historyDir, promptRestoreOpts.id)
+		if err != nil {
+			return fmt.Errorf("failed to get history entry: %w", err)
+		}
+		prompt, err := history.LoadPrompt(entry.GetEntryDir(historyDir))
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		backedUp, err := restoreWorkingPrompt(subprojectDir, prompt)
+		if err != nil {
+			return err
+		}
+		if backedUp != "" {
+			_, _ = fmt.Fprintf(w, "Backed up current prompt to %s\n", backedUp)
+		}
+		_, _ = fmt.Fprintf(w, "Restored prompt of %s to %s\n", entry.ID, workingPromptFile)
+		return nil
+	},
+}
+
+// restoreWorkingPrompt writes prompt to the subproject's working prompt file.
+// An existing file with different content is first renamed to <file>.bak, whose name is returned.
+func restoreWorkingPrompt(subprojectDir, prompt string) (string, error) {
+	path := filepath.Join(subprojectDir, workingPromptFile)
+
+	var backup string
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return "", fmt.Errorf("failed to read %s: %w", workingPromptFile, err)
+	case string(current) != prompt:
+		backup = workingPromptFile + ".bak"
+		if err := os.Rename(path, filepath.Join(subprojectDir, backup)); err != nil {
+			return "", fmt.Errorf("failed to back up %s: %w", workingPromptFile, err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(prompt), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", workingPromptFile, err)
+	}
+	return backup, nil
+}
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.AddCommand(promptRestoreCmd)
+	promptCmd.AddCommand(promptListCmd)
+	promptCmd.AddCommand(promptSaveCmd)
+	promptCmd.AddCommand(promptUseCmd)
+
+	promptRestoreCmd.Flags().StringVar(&promptRestoreOpts.id, "id", "", "History entry ID whose prompt to restore")
+	_ = promptRestoreCmd.MarkFlagRequired("id")
+
+	promptSaveCmd.Flags().StringVarP(&promptSaveOpts.prompt, "prompt", "p", "", "Prompt text to save")
+	promptSaveCmd.Flags().StringVarP(&promptSaveOpts.promptFile, "prompt-file", "F", "", "File whose prompt to save")
+	promptSaveCmd.Flags().StringVar(&promptSaveOpts.id, "id", "", "History entry ID whose prompt to save")
+	promptSaveCmd.Flags().BoolVar(&promptSaveOpts.force, "force", false, "Replace an existing prompt with the same name")
+	promptSaveCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file", "id")
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var pruneOpts struct {
+	olderThan string
+	failed    bool
+	keep      int
+	dryRun    bool
+	yes       bool
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old or failed history entries",
+	Long: `Delete history entries of the current subproject, including their edits.
+
+Entries are selected when they match every given criterion. --older-than
+accepts an age such as 30d or a date (2026-01-31). --keep protects the
+newest N entries regardless of the other criteria.
+
+Examples:
+  banago prune --failed --dry-run
+  banago prune --older-than 30d
+  banago prune --keep 20 --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var historyDir string
+		var err error
+		unlock := func() {}
+		if pruneOpts.dryRun {
+			historyDir, err = currentHistoryDir()
+		} else {
+			var hist *writableHistory
+			if hist, err = openWritableHistory(cmd.Context(), cmd.ErrOrStderr()); err == nil {
+				historyDir, unlock = hist.dir, hist.unlock
+			}
+		}
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		var criteria history.PruneCriteria
+		if pruneOpts.olderThan != "" {
+			before, _, err := parseTimeFlag(pruneOpts.olderThan, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+			criteria.Predicates = append(criteria.Predicates, history.CreatedBefore(before))
+		}
+		if pruneOpts.failed {
+			criteria.Predicates = append(criteria.Predicates, history.Failed())
+		}
+		if pruneOpts.keep < 0 {
+			return errors.New("--keep must not be negative")
+		}
+		criteria.KeepNewest = pruneOpts.keep
+
+		candidates, err := history.SelectPrune(historyDir, criteria)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if len(candidates) == 0 {
+			_, _ = fmt.Fprintln(w, "No entries to prune")
+			return nil
+		}
+
+		var total int64
+		for _, c := range candidates {
+			status := "✓"
+			if !c.Entry.Result.Success {
+				status = "✗"
+			}
+			_, _ = fmt.Fprintf(w, "  %s %s  %s  %s\n", status, c.Entry.ID, c.Entry.CreatedAt, formatBytes(c.Size))
+			total += c.Size
+		}
+		_, _ = fmt.Fprintln(w, "")
+
+		if pruneOpts.dryRun {
+			_, _ = fmt.Fprintf(w, "Would delete %d entries and reclaim %s\n", len(candidates), formatBytes(total))
+			return nil
+		}
+
+		if !pruneOpts.yes {
+			if !askYesNo(cmd.InOrStdin(), w, fmt.Sprintf("Delete %d entries (%s)?", len(candidates), formatBytes(total))) {
+				return errors.New("aborted. Use --yes to skip confirmation")
+			}
+		}
+
+		if err := history.Prune(historyDir, candidates); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Deleted %d entries and reclaimed %s\n", len(candidates), formatBytes(total))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().StringVar(&pruneOpts.olderThan, "older-than", "", "Select entries created before this age (e.g. 30d) or date")
+	pruneCmd.Flags().BoolVar(&pruneOpts.failed, "failed", false, "Select failed generations")
+	pruneCmd.Flags().IntVar(&pruneOpts.keep, "keep", 0, "Never delete the newest N entries; alone, prunes everything older")
+	pruneCmd.Flags().BoolVar(&pruneOpts.dryRun, "dry-run", false, "Show what would be deleted and the space reclaimed")
+	pruneCmd.Flags().BoolVarP(&pruneOpts.yes, "yes", "y", false, "Skip the confirmation")
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// quickOptions holds options for the quick command.
+type quickOptions struct {
+	prompt     string
+	promptFile string
+	images     []string
+	outDir     string
+	model      string
+	aspect     string
+	size       string
+	format     string // converts outputs to png, jpeg or webp
+	adopt      string // directory of earlier quick runs to import into the current subproject
+}
+
+// quickHandler handles the quick command with injectable dependencies.
+type quickHandler struct {
+	generator generation.Generator
+}
+
+var quickOpts quickOptions
+
+var quickCmd = &cobra.Command{
+	Use:   "quick",
+	Short: "Generate images without a project",
+	Long: `Generate images for a quick experiment, without banago.yaml or subprojects.
+
+Outputs are written to --out together with a quick-<id>.yaml manifest
+(prompt, inputs, parameters). No history is kept. To keep results, run
+--adopt <dir> inside a subproject later: every run in the directory that
+was not adopted yet becomes a history entry.
+
+Examples:
+  banago quick -p "a red fox, watercolor" -i ref.png -o out/
+  banago quick -p "..." --aspect 16:9 --size 2K
+  cd subprojects/fox && banago quick --adopt ../../out`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		w := cmd.OutOrStdout()
+		if quickOpts.adopt != "" {
+			hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			defer hist.unlock()
+			return adoptQuickRuns(quickOpts.adopt, cwd, hist, w)
+		}
+
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+
+		handler := &quickHandler{generator: client}
+		return handler.run(cmd.Context(), quickOpts, cwd, w)
+	},
+}
+
+// run generates once and writes the outputs and the quick run manifest to opts.outDir.
+// This method is independent of cobra.Command for testability.
+func (h *quickHandler) run(ctx context.Context, opts quickOptions, workDir string, w io.Writer) error {
+	promptText, err := resolvePrompt(opts.prompt, opts.promptFile)
+	if err != nil {
+		return err
+	}
+
+	var imagePaths []string
+	for _, img := range opts.images {
+		if !filepath.IsAbs(img) {
+			img = filepath.Join(workDir, img)
+		}
+		imagePaths = append(imagePaths, img)
+	}
+
+	outDir := cmp.Or(opts.outDir, ".")
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(workDir, outDir)
+	}
+
+	params := gemini.Params{
+		Model:       cmp.Or(opts.model, config.DefaultModel),
+		Prompt:      promptText,
+		ImagePaths:  imagePaths,
+		AspectRatio: opts.aspect,
+		ImageSize:   opts.size,
+	}
+	// Inputs are optional for quick experiments
+	if err := generation.ValidateSpec(generation.Spec{
+		ImagePaths:   imagePaths,
+		AspectRatio:  opts.aspect,
+		ImageSize:    opts.size,
+		OutputFormat: opts.format,
+		TextOnly:     true,
+	}); err != nil {
+		return err
+	}
+
+	result := h.generator.Generate(ctx, params)
+	if result.Error != nil {
+		return fmt.Errorf("failed to generate image: %w", result.Error)
+	}
+	run := history.NewQuickRun()
+	// Quick runs have no history entry yet: only the model, prompt and time are embedded
+	prov := &gemini.Provenance{Model: params.Model, PromptHash: gemini.PromptHash(promptText), CreatedAt: run.CreatedAt}
+	saved, err := gemini.SaveImagesAs(result.Response, outDir, opts.format, prov)
+	if err != nil {
+		return err
+	}
+
+	run.Model = params.Model
+	run.Prompt = promptText
+	run.InputImages = imagePaths
+	run.AspectRatio = opts.aspect
+	run.ImageSize = opts.size
+	run.TokenUsage = result.TokenUsage
+	for _, p := range saved {
+		run.Outputs = append(run.Outputs, filepath.Base(p))
+	}
+	if err := run.Save(outDir); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "Quick run: %s\n", run.ID)
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Generated files:")
+	for _, p := range saved {
+		_, _ = fmt.Fprintf(w, "  %s\n", p)
+	}
+	gemini.PrintOutput(w, result.Response, params.Model)
+	return nil
+}
+
+// adoptQuickRuns imports the not yet adopted quick runs in dir (relative to workDir) into hist
+func adoptQuickRuns(dir, workDir string, hist *writableHistory, w io.Writer) error {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workDir, dir)
+	}
+	runs, err := history.ListQuickRuns(dir)
+	if err != nil {
+		return err
+	}
+
+	adopted := 0
+	for _, run := range runs {
+		if run.AdoptedAs != "" {
+			continue
+		}
+		entry, err := history.AdoptQuickRun(dir, run, hist.dir, hist.layout)
+		if err != nil {
+			return fmt.Errorf("failed to adopt quick run %s: %w", run.ID, err)
+		}
+		_, _ = fmt.Fprintf(w, "Adopted %s as %s (%d outputs)\n", run.ID, entry.ID, len(entry.Result.OutputImages))
+		adopted++
+	}
+	if adopted == 0 {
+		_, _ = fmt.Fprintf(w, "No quick runs to adopt in %s\n", dir)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(quickCmd)
+
+	quickCmd.Flags().StringVarP(&quickOpts.prompt, "prompt", "p", "", "Prompt for generation")
+	quickCmd.Flags().StringVarP(&quickOpts.promptFile, "prompt-file", "F", "", "Path to text file containing prompt")
+	quickCmd.Flags().StringArrayVarP(&quickOpts.images, "image", "i", nil, "Input image (repeatable)")
+	quickCmd.Flags().StringVarP(&quickOpts.outDir, "out", "o", ".", "Output directory")
+	quickCmd.Flags().StringVar(&quickOpts.model, "model", config.DefaultModel, "Model to use")
+	quickCmd.Flags().StringVar(&quickOpts.aspect, "aspect", "", "Output image aspect ratio (e.g., 1:1, 16:9)")
+	quickCmd.Flags().StringVar(&quickOpts.size, "size", "", "Output image size (1K / 2K / 4K)")
+	quickCmd.Flags().StringVar(&quickOpts.format, "format", "", "Convert outputs to png, jpeg or webp when saving")
+	quickCmd.Flags().StringVar(&quickOpts.adopt, "adopt", "", "Import earlier quick runs in this directory into the current subproject")
+
+	quickCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file")
+	quickCmd.MarkFlagsMutuallyExclusive("adopt", "prompt")
+	quickCmd.MarkFlagsMutuallyExclusive("adopt", "prompt-file")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickHandler_RunAndAdopt(t *testing.T) {
+	t.Parallel()
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	// Generate outside any project
+	scratch := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(scratch, "ref.png"), pngData, 0o644))
+
+	mock := newSuccessMock(pngData)
+	handler := &quickHandler{generator: mock}
+	var buf bytes.Buffer
+	require.NoError(t, handler.run(context.Background(), quickOptions{
+		prompt: "a red fox",
+		images: []string{"ref.png"},
+		outDir: "out",
+	}, scratch, &buf))
+	assert.Contains(t, buf.String(), "Quick run:")
+	assert.Equal(t, []string{filepath.Join(scratch, "ref.png")}, mock.lastCall().ImagePaths)
+
+	outDir := filepath.Join(scratch, "out")
+	runs, err := history.ListQuickRuns(outDir)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Len(t, runs[0].Outputs, 1)
+	assert.FileExists(t, filepath.Join(outDir, runs[0].Outputs[0]))
+	assert.Equal(t, "a red fox", runs[0].Prompt)
+
+	// Adopt into a subproject
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	hist := &writableHistory{subprojectName: "test-sub", dir: historyDir, unlock: func() {}}
+
+	buf.Reset()
+	require.NoError(t, adoptQuickRuns(outDir, subprojectDir, hist, &buf))
+	assert.Contains(t, buf.String(), "Adopted "+runs[0].ID)
+
+	entry, err := history.GetLatestEntry(historyDir)
+	require.NoError(t, err)
+	assert.Equal(t, runs[0].Outputs, entry.Result.OutputImages)
+	assert.Equal(t, []string{"ref.png"}, entry.Generation.InputImages)
+	assert.Equal(t, config.DefaultModel, entry.Generation.Model)
+	require.NotNil(t, entry.Lineage)
+	assert.Equal(t, runs[0].ID, entry.Lineage.AdoptedFrom)
+	prompt, err := history.LoadPrompt(entry.GetEntryDir(historyDir))
+	require.NoError(t, err)
+	assert.Equal(t, "a red fox", prompt)
+
+	// Adopted runs are not imported twice
+	buf.Reset()
+	require.NoError(t, adoptQuickRuns(outDir, subprojectDir, hist, &buf))
+	assert.Contains(t, buf.String(), "No quick runs to adopt")
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var rateSel entrySelection
+
+var rateCmd = &cobra.Command{
+	Use:   "rate <rating>",
+	Short: "Rate history entries",
+	Long: fmt.Sprintf(`Rate history entries of the current subproject from 1 to %d; 0 clears the rating.
+
+Entries are selected like with 'banago tag': --ids, or --all with the filters
+of 'banago history'. 'banago history --min-rating' lists the best ones.
+
+Examples:
+  banago rate --ids <uuid>,<uuid>,<uuid> 2
+  banago rate --all --tag final 5
+  banago rate --all --failed-only 0`, history.MaxRating),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rating, err := strconv.Atoi(args[0])
+		if err != nil || rating < 0 || rating > history.MaxRating {
+			return fmt.Errorf("invalid rating %q: must be 0 to %d", args[0], history.MaxRating)
+		}
+		hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		defer hist.unlock()
+		historyDir := hist.dir
+		entries, err := rateSel.entries(historyDir)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		changed, err := updateEntries(w, historyDir, entries, "Rated", func(e *history.Entry) bool {
+			ok, _ := e.SetRating(rating)
+			return ok
+		})
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Rated %d of %d entries %d/%d\n", changed, len(entries), rating, history.MaxRating)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rateCmd)
+	rateSel.register(rateCmd)
+}
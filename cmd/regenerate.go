@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/gemini"
@@ -18,18 +18,40 @@ import (
 )
 
 type regenerateOptions struct {
-	id     string
-	latest bool
-	aspect string
-	size   string
+	id             string
+	latest         bool
+	aspect         string
+	size           string
+	format         string         // converts outputs to png, jpeg or webp (overrides output_format in config.yaml)
+	model          string         // overrides the recorded and configured model for this run
+	seed           *int32         // --seed; nil reuses the seed the entry recorded
+	timeout        *time.Duration // --timeout; nil uses banago.yaml's timeout
+	newSeed        bool           // pick a random seed instead of reusing the recorded one
+	candidates     int            // overrides the recorded number of variants per call (0 = recorded)
+	ids            []string
+	allFailed      bool // regenerate every failed entry not yet regenerated successfully
+	concurrency    int
+	tokenBreakdown bool
+	keepFailures   bool // keep entries of failed API calls (also keep_failures in banago.yaml)
+	dryRun         bool // print the resolved requests and exit without calling the API
+	yes            bool
+	json           bool
+	quiet          bool
+	events         bool // stream progress events as NDJSON instead of text
+	wait           bool // queue behind other processes writing to the subproject instead of failing
 }
 
 // regenerateHandler handles the regenerate command with dependency injection support.
 type regenerateHandler struct {
 	generator generation.Generator
+	stdin     io.Reader // answers the --latest confirmation (default: os.Stdin)
 }
 
-var regenOpts regenerateOptions
+var (
+	regenOpts    regenerateOptions
+	regenSeed    int32
+	regenTimeout time.Duration
+)
 
 var regenerateCmd = &cobra.Command{
 	Use:   "regenerate",
@@ -41,25 +63,44 @@ to generate new images. Results are saved as a new history entry.
 
 Examples:
   banago regenerate --latest           # Use the latest history entry
-  banago regenerate --id <uuid>        # Use a specific history entry`,
+  banago regenerate --id <uuid>        # Use a specific history entry
+  banago regenerate --ids <a>,<b>,<c>  # Regenerate several entries in parallel
+  banago regenerate --all-failed       # Re-run every failed entry, one at a time`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		if err := requireAPIKey(); err != nil {
-			return err
-		}
-
 		cwd, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		// Create Gemini client and inject into handler
-		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
-		if err != nil {
-			return fmt.Errorf("failed to create Gemini client: %w", err)
+		// Create Gemini client and inject into handler; a dry run needs neither key nor client
+		var generator generation.Generator
+		if !regenOpts.dryRun {
+			if err := requireAPIKey(); err != nil {
+				return err
+			}
+			client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+			if err != nil {
+				return fmt.Errorf("failed to create Gemini client: %w", err)
+			}
+			generator = client
+		}
+
+		// --all-failed usually follows an outage: go one at a time unless asked otherwise
+		if regenOpts.allFailed && !cmd.Flags().Changed("concurrency") {
+			regenOpts.concurrency = 1
 		}
 
-		handler := &regenerateHandler{generator: client}
+		if cmd.Flags().Changed("seed") {
+			regenOpts.seed = &regenSeed
+		}
+		if cmd.Flags().Changed("timeout") {
+			regenOpts.timeout = &regenTimeout
+		}
+
+		handler := &regenerateHandler{generator: generator, stdin: cmd.InOrStdin()}
+		regenOpts.json = jsonOutput()
+		regenOpts.events = eventsOutput()
 		return handler.run(cmd.Context(), regenOpts, cwd, cmd.OutOrStdout())
 	},
 }
@@ -67,6 +108,13 @@ Examples:
 // run executes the regenerate command logic.
 // This method is independent of cobra.Command for testability.
 func (h *regenerateHandler) run(ctx context.Context, opts regenerateOptions, workDir string, w io.Writer) error {
+	// In JSON mode progress text is discarded and a single JSON document is written at the end.
+	// --quiet likewise writes only the resulting IDs, and --events only the progress events.
+	jsonW := w
+	if opts.json || opts.quiet || opts.events {
+		w = io.Discard
+	}
+
 	projectRoot, err := project.FindProjectRoot(workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrProjectNotFound) {
@@ -80,8 +128,11 @@ func (h *regenerateHandler) run(ctx context.Context, opts regenerateOptions, wor
 	if err != nil {
 		return fmt.Errorf("failed to load project config: %w", err)
 	}
-	model := projectCfg.Model
-
+	if !opts.dryRun {
+		if err := requireWritable(projectCfg); err != nil {
+			return err
+		}
+	}
 	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
 	if err != nil {
 		if errors.Is(err, project.ErrNotInSubproject) {
@@ -95,58 +146,316 @@ func (h *regenerateHandler) run(ctx context.Context, opts regenerateOptions, wor
 	if err != nil {
 		return fmt.Errorf("failed to load subproject config: %w", err)
 	}
+	if err := project.CheckWorkDir(subprojectDir, subprojectCfg, workDir); err != nil {
+		return err
+	}
+	// Entries without a config snapshot fall back to the config, preset included; prompt.txt
+	// already holds the preset's notes
+	if _, err := applyCharacterPreset(projectRoot, subprojectCfg); err != nil {
+		return err
+	}
 
-	historyDir := history.GetHistoryDir(subprojectDir)
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
 
-	// Load history entry
-	var sourceEntry *history.Entry
-	if opts.latest {
-		sourceEntry, err = history.GetLatestEntry(historyDir)
+	// Load history entries
+	var sourceEntries []*history.Entry
+	switch {
+	case opts.latest:
+		sourceEntry, err := history.GetLatestEntry(historyDir)
 		if err != nil {
 			return fmt.Errorf("failed to get latest history: %w", err)
 		}
-	} else {
-		sourceEntry, err = history.GetEntryByID(historyDir, opts.id)
+		if !opts.yes && !opts.dryRun {
+			if opts.json || opts.quiet || opts.events {
+				return errConfirmRequired
+			}
+			if err := confirmLatest(h.stdin, w, subprojectName, historyDir, sourceEntry); err != nil {
+				return err
+			}
+		}
+		sourceEntries = append(sourceEntries, sourceEntry)
+	case len(opts.ids) > 0:
+		for _, id := range opts.ids {
+			sourceEntry, err := history.GetEntryByID(historyDir, id)
+			if err != nil {
+				return fmt.Errorf("failed to get history entry %s: %w", id, err)
+			}
+			sourceEntries = append(sourceEntries, sourceEntry)
+		}
+	case opts.allFailed:
+		entries, err := history.ListEntries(historyDir)
+		if err != nil {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+		sourceEntries = unresolvedFailures(entries)
+		if len(sourceEntries) == 0 {
+			if opts.json {
+				return writeJSON(jsonW, generationJSON{Entries: []entryJSON{}})
+			}
+			_, _ = fmt.Fprintln(w, "No failed entries to regenerate")
+			return nil
+		}
+		_, _ = fmt.Fprintf(w, "Regenerating %d failed entries\n", len(sourceEntries))
+	default:
+		sourceEntry, err := history.GetEntryByID(historyDir, opts.id)
 		if err != nil {
 			return fmt.Errorf("failed to get history entry: %w", err)
 		}
+		sourceEntries = append(sourceEntries, sourceEntry)
 	}
 
-	// Load prompt from history
-	sourceEntryDir := filepath.Join(historyDir, sourceEntry.ID)
-	promptText, err := history.LoadPrompt(sourceEntryDir)
+	archive, err := resolveArchivePolicy(projectRoot, subprojectDir, subprojectCfg, subprojectCfg.IncludeContext)
 	if err != nil {
-		return fmt.Errorf("failed to load prompt from history: %w", err)
+		return err
+	}
+	mirror, err := resolveMirrorPolicy(subprojectDir, subprojectCfg)
+	if err != nil {
+		return err
+	}
+	evaluation, err := resolveEvaluationPolicy(subprojectDir, subprojectCfg)
+	if err != nil {
+		return err
 	}
 
-	_, _ = fmt.Fprintf(w, "Regenerating from history: %s\n", sourceEntry.ID)
+	// Build one generation spec per source entry
+	var specs []generation.Spec
+	for _, sourceEntry := range sourceEntries {
+		spec, err := regenerateSpec(sourceEntry, historyDir, subprojectDir, subprojectCfg, opts)
+		if err != nil && opts.allFailed {
+			// A failure that left no usable prompt or inputs must not block the others
+			_, _ = fmt.Fprintf(w, "Skipping %s: %v\n", sourceEntry.ID, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceEntry.ID, err)
+		}
+		spec.Model, spec.ModelOverride = regenerateModel(sourceEntry, opts.model, projectCfg)
+		spec.Capabilities = cachedCapabilities(projectRoot, spec.Model)
+		spec.Archive = archive
+		if spec.IncludeContext && sourceEntry.Generation.Config != nil {
+			spec.Archive = entryContextArchive(archive, sourceEntry, historyDir)
+		}
+		spec.Mirror = mirror
+		spec.Evaluation = evaluation
+		spec.MinFreeDisk = projectCfg.MinFreeDisk()
+		spec.KeepFailures = opts.keepFailures || projectCfg.KeepFailures
+		spec.Layout = projectCfg.HistoryLayout
+
+		if projectCfg.ConfirmBeforeGenerate && !opts.yes && !opts.dryRun {
+			if opts.json || opts.quiet || opts.events {
+				return errConfirmRequired
+			}
+			if len(sourceEntries) > 1 {
+				_, _ = fmt.Fprintf(w, "Regenerating from history: %s\n", sourceEntry.ID)
+			}
+			if err := confirmGeneration(h.stdin, w, generationPreview{
+				model:      spec.Model,
+				prompt:     spec.Prompt,
+				imagePaths: spec.ImagePaths,
+				aspect:     spec.AspectRatio,
+				size:       spec.ImageSize,
+			}); err != nil {
+				return err
+			}
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return errors.New("none of the failed entries can be regenerated")
+	}
+	if opts.dryRun {
+		service := generation.NewService(h.generator)
+		var requests []dryRunRequest
+		for _, spec := range specs {
+			result, err := service.DryRun(ctx, spec, historyDir, w)
+			if err != nil {
+				return fmt.Errorf("%s: %w", spec.SourceEntryID, err)
+			}
+			requests = append(requests, dryRunRequest{sourceID: spec.SourceEntryID, count: 1, result: result})
+		}
+		if opts.json {
+			return writeJSON(jsonW, newDryRunJSON(requests))
+		}
+		printDryRun(jsonW, requests)
+		return nil
+	}
+
+	ctx, cancel := runContext(ctx, opts.timeout, projectCfg)
+	defer cancel()
+	unlock, err := lockSubproject(ctx, subprojectDir, opts.wait, w)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if len(evaluation.Commands) > 0 {
+		workspace, err := openWorkspace(projectRoot, projectCfg)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = workspace.Close() }()
+		for i := range specs {
+			specs[i].Evaluation.TmpDir = workspace.Dir
+		}
+	}
+
+	service := generation.NewService(h.generator)
+	if opts.events {
+		service.WithEvents(ndjsonEvents(jsonW))
+	}
+
+	if len(opts.ids) > 0 || opts.allFailed {
+		// Run the selection with bounded concurrency
+		results, err := service.RunParallel(ctx, specs, cmp.Or(opts.concurrency, 1), historyDir, w)
+		if opts.json {
+			out := generationJSON{Entries: []entryJSON{}}
+			for i, r := range results {
+				if r == nil {
+					continue
+				}
+				e := newGenerationJSON(historyDir, "", []*generation.Result{r}).Entries[0]
+				e.SourceID = specs[i].SourceEntryID
+				out.Entries = append(out.Entries, e)
+				out.TokenUsage.Add(r.TokenUsage)
+			}
+			if err != nil {
+				return err
+			}
+			return writeJSON(jsonW, out)
+		}
+		if opts.quiet && !opts.events {
+			for _, r := range results {
+				if r != nil {
+					_, _ = fmt.Fprintln(jsonW, r.EntryID)
+				}
+			}
+		}
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "Regenerating from history: %s\n", specs[0].SourceEntryID)
 	_, _ = fmt.Fprintln(w, "")
 
+	// Run generation with injected generator
+	result, err := service.Run(ctx, specs[0], historyDir, w)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		return writeJSON(jsonW, newGenerationJSON(historyDir, specs[0].SourceEntryID, []*generation.Result{result}))
+	}
+	if opts.quiet && !opts.events {
+		_, _ = fmt.Fprintln(jsonW, result.EntryID)
+	}
+	return nil
+}
+
+// unresolvedFailures returns the failed entries that were not regenerated yet, so re-running
+// --all-failed neither repeats recovered failures nor retries one whose retry failed again
+// (with keep_failures the newer failed entry is retried instead)
+func unresolvedFailures(entries []*history.Entry) []*history.Entry {
+	recovered := map[string]bool{}
+	for _, e := range entries {
+		if e.Lineage != nil && e.Lineage.RegeneratedFrom != "" {
+			recovered[e.Lineage.RegeneratedFrom] = true
+		}
+	}
+	var failed []*history.Entry
+	for _, e := range entries {
+		if !e.Result.Success && !recovered[e.ID] {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+// regenerateModel returns the model to regenerate sourceEntry with: --model, else the model the entry
+// recorded, else (entries without one) banago.yaml's. It reports whether the model is an override.
+func regenerateModel(sourceEntry *history.Entry, flagModel string, projectCfg *config.ProjectConfig) (string, bool) {
+	if flagModel == "" && sourceEntry.Generation.Model != "" {
+		return sourceEntry.Generation.Model, sourceEntry.Generation.ModelOverride
+	}
+	return resolveModel(flagModel, projectCfg)
+}
+
+// regenerateSpec builds the generation spec that re-runs a history entry.
+// Model and archive policy are left to the caller since they are shared by all entries.
+func regenerateSpec(sourceEntry *history.Entry, historyDir, subprojectDir string, subprojectCfg *config.SubprojectConfig, opts regenerateOptions) (generation.Spec, error) {
+	if sourceEntry.Generation.Media == history.MediaVideo {
+		return generation.Spec{}, fmt.Errorf("entry %s is a video; regenerate supports image entries only", sourceEntry.ID)
+	}
+
+	// Load prompt from history
+	sourceEntryDir := sourceEntry.GetEntryDir(historyDir)
+	promptText, err := history.LoadPrompt(sourceEntryDir)
+	if err != nil {
+		return generation.Spec{}, fmt.Errorf("failed to load prompt from history: %w", err)
+	}
+
 	// Get input images from history entry directory
 	imagePaths := history.GetInputImagePaths(sourceEntryDir, sourceEntry.Generation.InputImages)
 
-	if len(imagePaths) == 0 {
-		return errors.New("no input images found in history entry. Run 'banago migrate' first")
+	// Entries archived with hash-only inputs reference the current inputs/ directory
+	if len(imagePaths) == 0 && len(sourceEntry.Generation.InputHashes) > 0 {
+		imagePaths, err = sourceEntry.ResolveHashedInputs(project.GetInputsDir(subprojectDir))
+		if err != nil {
+			return generation.Spec{}, err
+		}
 	}
 
-	// Resolve aspect ratio and image size: flag > history > config
-	aspect := cmp.Or(opts.aspect, sourceEntry.Generation.AspectRatio, subprojectCfg.AspectRatio)
-	size := cmp.Or(opts.size, sourceEntry.Generation.ImageSize, subprojectCfg.ImageSize)
+	if len(imagePaths) == 0 && !sourceEntry.Generation.TextOnly {
+		return generation.Spec{}, errors.New("no input images found in history entry. Run 'banago migrate' first")
+	}
 
-	// Build generation spec
 	spec := generation.Spec{
-		Model:           model,
 		Prompt:          promptText,
 		ImagePaths:      imagePaths,
-		AspectRatio:     aspect,
-		ImageSize:       size,
 		InputImageNames: sourceEntry.Generation.InputImages,
 		SourceEntryID:   sourceEntry.ID,
+		TokenBreakdown:  opts.tokenBreakdown,
+		TextOnly:        sourceEntry.Generation.TextOnly,
+		Seed:            sourceEntry.Generation.Seed,
+		Candidates:      cmp.Or(opts.candidates, sourceEntry.Generation.Candidates),
+		OutputFormat:    cmp.Or(opts.format, subprojectCfg.OutputFormat),
+	}
+	// Reuse the recorded seed unless --seed replaces it; --new-seed, like entries that recorded none,
+	// leaves it nil so a random seed is picked
+	if opts.seed != nil {
+		spec.Seed = opts.seed
+	} else if opts.newSeed {
+		spec.Seed = nil
 	}
 
-	// Run generation with injected generator
-	_, err = generation.NewService(h.generator).Run(ctx, spec, historyDir, w)
-	return err
+	// Entries with a config snapshot recorded exactly what was sent: flag > history.
+	// Older entries fall back to the current config for values they did not record.
+	if snapshot := sourceEntry.Generation.Config; snapshot != nil {
+		spec.AspectRatio = cmp.Or(opts.aspect, sourceEntry.Generation.AspectRatio)
+		spec.ImageSize = cmp.Or(opts.size, sourceEntry.Generation.ImageSize)
+		spec.TokenBudget = snapshot.TokenBudget
+		spec.AssembleAnimation = snapshot.AssembleAnimation
+		spec.IncludeContext = snapshot.IncludeContext
+	} else {
+		spec.AspectRatio = cmp.Or(opts.aspect, sourceEntry.Generation.AspectRatio, subprojectCfg.AspectRatio)
+		spec.ImageSize = cmp.Or(opts.size, sourceEntry.Generation.ImageSize, subprojectCfg.ImageSize)
+		spec.TokenBudget = subprojectCfg.TokenBudget
+		spec.AssembleAnimation = subprojectCfg.AssembleAnimation
+		spec.IncludeContext = subprojectCfg.IncludeContext
+	}
+	return spec, nil
+}
+
+// entryContextArchive points the context and character files of archive at the copies archived in
+// sourceEntry, so an entry that included them in its prompt is regenerated with the same text
+func entryContextArchive(archive generation.ArchivePolicy, sourceEntry *history.Entry, historyDir string) generation.ArchivePolicy {
+	entryDir := sourceEntry.GetEntryDir(historyDir)
+	archive.ProjectContextPath, archive.ContextPath, archive.CharacterPath = "", "", ""
+	if name := sourceEntry.Generation.ContextFile; name != "" {
+		archive.ContextPath = history.GetEntryFilePath(entryDir, name)
+	}
+	if name := sourceEntry.Generation.CharacterFile; name != "" {
+		archive.CharacterPath = history.GetEntryFilePath(entryDir, name)
+	}
+	return archive
 }
 
 func init() {
@@ -156,7 +465,23 @@ func init() {
 	regenerateCmd.Flags().BoolVar(&regenOpts.latest, "latest", false, "Use the latest history entry")
 	regenerateCmd.Flags().StringVar(&regenOpts.aspect, "aspect", "", "Output image aspect ratio (overrides history/config)")
 	regenerateCmd.Flags().StringVar(&regenOpts.size, "size", "", "Output image size (overrides history/config)")
+	regenerateCmd.Flags().StringVar(&regenOpts.format, "format", "", "Convert outputs to png, jpeg or webp when saving (overrides output_format)")
+	regenerateCmd.Flags().StringVar(&regenOpts.model, "model", "", "Model for this run (overrides history/config; recorded in history)")
+	regenerateCmd.Flags().Int32Var(&regenSeed, "seed", 0, "Seed for this run instead of the one recorded in history")
+	regenerateCmd.Flags().BoolVar(&regenOpts.newSeed, "new-seed", false, "Use a random seed instead of the one recorded in history")
+	regenerateCmd.Flags().IntVar(&regenOpts.candidates, "candidates", 0, "Variants returned by the API call (default: as recorded in history)")
+	regenerateCmd.Flags().StringSliceVar(&regenOpts.ids, "ids", nil, "Comma-separated history entry IDs to regenerate")
+	regenerateCmd.Flags().BoolVar(&regenOpts.allFailed, "all-failed", false, "Regenerate every failed entry that has not been regenerated successfully")
+	regenerateCmd.Flags().IntVar(&regenOpts.concurrency, "concurrency", 3, "Maximum number of parallel generations with --ids (default 1 with --all-failed)")
+	regenerateCmd.Flags().BoolVar(&regenOpts.keepFailures, "keep-failures", false, "Keep the history entry with the error when the API call fails")
+	regenerateCmd.Flags().BoolVar(&regenOpts.tokenBreakdown, "token-breakdown", false, "Count and record prompt tokens of the text and each input image (extra CountTokens calls)")
+	regenerateCmd.Flags().BoolVar(&regenOpts.dryRun, "dry-run", false, "Print the requests that would be sent and their estimated cost without calling the API")
+	regenerateCmd.Flags().BoolVarP(&regenOpts.yes, "yes", "y", false, "Skip confirmation for --latest and confirm_before_generate")
+	regenerateCmd.Flags().BoolVarP(&regenOpts.quiet, "quiet", "q", false, "Print only the new entry IDs, one per line")
+	regenerateCmd.Flags().BoolVar(&regenOpts.wait, "wait", false, "Wait for other banago processes writing to the subproject instead of failing")
+	regenerateCmd.Flags().DurationVar(&regenTimeout, "timeout", 0, "Stop the run after this long, e.g. 5m (overrides timeout in banago.yaml; 0 = no limit)")
 
-	regenerateCmd.MarkFlagsOneRequired("id", "latest")
-	regenerateCmd.MarkFlagsMutuallyExclusive("id", "latest")
+	regenerateCmd.MarkFlagsOneRequired("id", "latest", "ids", "all-failed")
+	regenerateCmd.MarkFlagsMutuallyExclusive("id", "latest", "ids", "all-failed")
+	regenerateCmd.MarkFlagsMutuallyExclusive("seed", "new-seed")
 }
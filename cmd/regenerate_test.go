@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/blck-snwmn/banago/internal/config"
@@ -59,6 +60,7 @@ func TestScenario_Regenerate_Latest(t *testing.T) {
 	var regenBuf bytes.Buffer
 	err = regenHandler.run(context.Background(), regenerateOptions{
 		latest: true,
+		yes:    true,
 	}, subprojectDir, &regenBuf)
 	require.NoError(t, err)
 
@@ -219,6 +221,7 @@ func TestScenario_Regenerate_APIError(t *testing.T) {
 	var regenBuf bytes.Buffer
 	err = regenHandler.run(context.Background(), regenerateOptions{
 		latest: true,
+		yes:    true,
 	}, subprojectDir, &regenBuf)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to generate image")
@@ -256,6 +259,7 @@ func TestScenario_Regenerate_NoHistory(t *testing.T) {
 	var buf bytes.Buffer
 	err = handler.run(context.Background(), regenerateOptions{
 		latest: true,
+		yes:    true,
 	}, subprojectDir, &buf)
 
 	require.Error(t, err)
@@ -339,6 +343,7 @@ func TestScenario_Regenerate_WithOverrides(t *testing.T) {
 	var regenBuf bytes.Buffer
 	err = regenHandler.run(context.Background(), regenerateOptions{
 		latest: true,
+		yes:    true,
 		aspect: "16:9",
 		size:   "4K",
 	}, subprojectDir, &regenBuf)
@@ -419,6 +424,7 @@ func TestScenario_Regenerate_Multiple(t *testing.T) {
 	var regenBuf bytes.Buffer
 	err = regenHandler.run(context.Background(), regenerateOptions{
 		latest: true,
+		yes:    true,
 	}, subprojectDir, &regenBuf)
 	require.NoError(t, err)
 
@@ -452,3 +458,544 @@ func TestScenario_Regenerate_Multiple(t *testing.T) {
 		assert.Len(t, outputFiles, 1)
 	}
 }
+
+// TestScenario_Regenerate_HashedInputs tests regeneration from an entry archived with hash-only inputs.
+func TestScenario_Regenerate_HashedInputs(t *testing.T) {
+	t.Parallel()
+
+	// Setup project with hash-only input archiving
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	cfg.Archive.Inputs = config.ArchiveInputsHash
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	// Step 1: Generate initial entry
+	genHandler := &generateHandler{generator: newSuccessMock(pngData)}
+	var genBuf bytes.Buffer
+	require.NoError(t, genHandler.run(context.Background(), generateOptions{
+		prompt: "original prompt",
+	}, subprojectDir, &genBuf))
+
+	// Step 2: Regenerate resolves inputs from inputs/
+	regenMock := newSuccessMock(pngData)
+	regenHandler := &regenerateHandler{generator: regenMock}
+	var regenBuf bytes.Buffer
+	require.NoError(t, regenHandler.run(context.Background(), regenerateOptions{
+		latest: true,
+		yes:    true,
+	}, subprojectDir, &regenBuf))
+	assert.Equal(t, []string{inputPath}, regenMock.lastCall().ImagePaths)
+
+	// Step 3: Regenerate fails once the input image changes
+	require.NoError(t, os.WriteFile(inputPath, []byte("changed"), 0o644))
+	err = regenHandler.run(context.Background(), regenerateOptions{
+		latest: true,
+		yes:    true,
+	}, subprojectDir, &regenBuf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has changed")
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+// TestScenario_Regenerate_LatestConfirmation tests the confirmation shown for --latest without --yes.
+func TestScenario_Regenerate_LatestConfirmation(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	genHandler := &generateHandler{generator: newSuccessMock(pngData)}
+	var genBuf bytes.Buffer
+	require.NoError(t, genHandler.run(context.Background(), generateOptions{
+		prompt: "a knight standing in the rain",
+	}, subprojectDir, &genBuf))
+
+	t.Run("declined", func(t *testing.T) {
+		mock := newSuccessMock(pngData)
+		handler := &regenerateHandler{generator: mock, stdin: strings.NewReader("n\n")}
+
+		var buf bytes.Buffer
+		err := handler.run(context.Background(), regenerateOptions{latest: true}, subprojectDir, &buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "aborted")
+		assert.Contains(t, buf.String(), "Latest entry in subproject 'test-sub'")
+		assert.Contains(t, buf.String(), "a knight standing in the rain")
+		assert.Empty(t, mock.calls)
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		mock := newSuccessMock(pngData)
+		handler := &regenerateHandler{generator: mock, stdin: strings.NewReader("y\n")}
+
+		var buf bytes.Buffer
+		require.NoError(t, handler.run(context.Background(), regenerateOptions{latest: true}, subprojectDir, &buf))
+		assert.Len(t, mock.calls, 1)
+	})
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestScenario_Regenerate_IDs(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	// Step 1: Generate three entries with distinct prompts
+	prompts := []string{"first prompt", "second prompt", "third prompt"}
+	var ids []string
+	for _, prompt := range prompts {
+		genHandler := &generateHandler{generator: newSuccessMock(pngData)}
+		var genBuf bytes.Buffer
+		require.NoError(t, genHandler.run(context.Background(), generateOptions{prompt: prompt}, subprojectDir, &genBuf))
+
+		latest, err := history.GetLatestEntry(historyDir)
+		require.NoError(t, err)
+		ids = append(ids, latest.ID)
+	}
+
+	// Step 2: Regenerate the selection in parallel
+	mock := newSuccessMock(pngData)
+	handler := &regenerateHandler{generator: mock}
+
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), regenerateOptions{
+		ids:         ids,
+		concurrency: 2,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	require.Len(t, mock.calls, 3)
+	var called []string
+	for _, c := range mock.calls {
+		called = append(called, c.Prompt)
+	}
+	assert.ElementsMatch(t, prompts, called)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 6)
+
+	// Output is printed in selection order
+	output := buf.String()
+	assert.Contains(t, output, "=== Generation 1/3 (from "+ids[0]+") ===")
+	assert.Contains(t, output, "=== Generation 3/3 (from "+ids[2]+") ===")
+	assert.Less(t, strings.Index(output, ids[0]), strings.Index(output, ids[2]))
+	assert.Contains(t, output, "Batch: 3/3 generations succeeded, 3 images")
+}
+
+// TestScenario_Regenerate_VideoEntry tests that video entries are rejected.
+func TestScenario_Regenerate_VideoEntry(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	entry := history.NewEntry()
+	entry.Generation.Media = history.MediaVideo
+	entry.Generation.PromptFile = history.PromptFile
+	entry.Result.Success = true
+	entry.Result.OutputImages = []string{"output-1.mp4"}
+	require.NoError(t, entry.Save(historyDir))
+	require.NoError(t, entry.SavePrompt(historyDir, "a slow pan"))
+
+	mock := newSuccessMock(nil)
+	handler := &regenerateHandler{generator: mock}
+	err := handler.run(context.Background(), regenerateOptions{id: entry.ID}, subprojectDir, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a video")
+	assert.Empty(t, mock.calls)
+}
+
+// TestScenario_Regenerate_ModelOverride tests that a --model override is recorded and reused by regenerate.
+func TestScenario_Regenerate_ModelOverride(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	require.NoError(t, err)
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	genMock := newSuccessMock(pngData)
+	err = (&generateHandler{generator: genMock}).run(context.Background(), generateOptions{
+		prompt: "a fox",
+		model:  "new-model",
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, genMock.calls, 1)
+	assert.Equal(t, "new-model", genMock.calls[0].Model)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "new-model", entries[0].Generation.Model)
+	assert.True(t, entries[0].Generation.ModelOverride)
+
+	// Without --model, regenerate reproduces the override
+	regenMock := newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id: entries[0].ID,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Equal(t, "new-model", regenMock.calls[0].Model)
+
+	// Entries generated with banago.yaml's model keep that model after banago.yaml changes
+	plain := history.NewEntry()
+	plain.Generation.Model = "old-model"
+	plain.Generation.PromptFile = history.PromptFile
+	plain.Generation.InputImages = []string{"test.png"}
+	plain.Result.Success = true
+	require.NoError(t, plain.Save(historyDir))
+	require.NoError(t, plain.SavePrompt(historyDir, "a fox"))
+	require.NoError(t, plain.SaveInputImages(historyDir, []string{filepath.Join(project.GetInputsDir(subprojectDir), "test.png")}))
+
+	regenMock = newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id: plain.ID,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Equal(t, "old-model", regenMock.calls[0].Model)
+
+	// Entries that recorded no model follow banago.yaml
+	plain.Generation.Model = ""
+	require.NoError(t, plain.Save(historyDir))
+	regenMock = newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id: plain.ID,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Equal(t, projectCfg.Model, regenMock.calls[0].Model)
+}
+
+// TestScenario_Regenerate_Seed tests that regenerate reuses the recorded seed unless
+// --seed or --new-seed replaces it.
+func TestScenario_Regenerate_Seed(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	seed := int32(1234)
+	genMock := newSuccessMock(pngData)
+	err = (&generateHandler{generator: genMock}).run(context.Background(), generateOptions{
+		prompt: "a fox",
+		seed:   &seed,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, genMock.calls, 1)
+	assert.Equal(t, &seed, genMock.calls[0].Seed)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, &seed, entries[0].Generation.Seed)
+
+	regenerate := func(opts regenerateOptions) *int32 {
+		t.Helper()
+		mock := newSuccessMock(pngData)
+		opts.id = entries[0].ID
+		require.NoError(t, (&regenerateHandler{generator: mock}).run(context.Background(), opts, subprojectDir, &bytes.Buffer{}))
+		require.Len(t, mock.calls, 1)
+		require.NotNil(t, mock.calls[0].Seed)
+		return mock.calls[0].Seed
+	}
+
+	assert.Equal(t, seed, *regenerate(regenerateOptions{}), "the recorded seed is reused")
+	override := int32(7)
+	assert.Equal(t, override, *regenerate(regenerateOptions{seed: &override}))
+	assert.NotEqual(t, seed, *regenerate(regenerateOptions{newSeed: true}), "--new-seed picks a random seed")
+}
+
+// TestScenario_Regenerate_Candidates tests that regenerate asks for as many candidates as the
+// entry recorded unless --candidates overrides it.
+func TestScenario_Regenerate_Candidates(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	genMock := newMultiImageMock(pngData, 4)
+	err = (&generateHandler{generator: genMock}).run(context.Background(), generateOptions{
+		prompt:        "a fox",
+		noInputImages: true,
+		candidates:    4,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, genMock.calls, 1)
+	assert.Equal(t, 4, genMock.calls[0].Candidates)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 4, entries[0].Generation.Candidates)
+	assert.Len(t, entries[0].Result.OutputImages, 4)
+
+	for _, tc := range []struct {
+		flag int
+		want int
+	}{{0, 4}, {2, 2}} {
+		mock := newMultiImageMock(pngData, tc.want)
+		err = (&regenerateHandler{generator: mock}).run(context.Background(), regenerateOptions{
+			id:         entries[0].ID,
+			candidates: tc.flag,
+		}, subprojectDir, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Len(t, mock.calls, 1)
+		assert.Equal(t, tc.want, mock.calls[0].Candidates, "--candidates %d", tc.flag)
+	}
+}
+
+// TestScenario_Regenerate_ConfigSnapshot tests that regenerate reuses the recorded settings
+// of entries with a config snapshot and falls back to the current config for older entries.
+func TestScenario_Regenerate_ConfigSnapshot(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	// Generated with the API default aspect ratio and size
+	genMock := newSuccessMock(pngData)
+	err = (&generateHandler{generator: genMock}).run(context.Background(), generateOptions{
+		prompt: "a fox",
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].Generation.Config)
+
+	// The config changes afterwards
+	cfg.AspectRatio = "16:9"
+	cfg.ImageSize = "2K"
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	regenMock := newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id: entries[0].ID,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Empty(t, regenMock.calls[0].AspectRatio)
+	assert.Empty(t, regenMock.calls[0].ImageSize)
+
+	// Flags still override the recorded values
+	regenMock = newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id:     entries[0].ID,
+		aspect: "1:1",
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Equal(t, "1:1", regenMock.calls[0].AspectRatio)
+
+	// Entries without a snapshot take what they did not record from the config
+	entries[0].Generation.Config = nil
+	require.NoError(t, entries[0].Save(historyDir))
+	regenMock = newSuccessMock(pngData)
+	err = (&regenerateHandler{generator: regenMock}).run(context.Background(), regenerateOptions{
+		id: entries[0].ID,
+	}, subprojectDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, regenMock.calls, 1)
+	assert.Equal(t, "16:9", regenMock.calls[0].AspectRatio)
+	assert.Equal(t, "2K", regenMock.calls[0].ImageSize)
+}
+
+// TestScenario_Regenerate_AllFailed tests that --all-failed re-runs failed entries not yet recovered
+// and links the new entries to them.
+func TestScenario_Regenerate_AllFailed(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	saveEntry := func(prompt string, success bool, regeneratedFrom string) *history.Entry {
+		entry := history.NewEntry()
+		entry.Generation.PromptFile = history.PromptFile
+		entry.Generation.InputImages = []string{"test.png"}
+		entry.Result.Success = success
+		if !success {
+			entry.Result.ErrorMessage = "503 Service Unavailable"
+		}
+		if regeneratedFrom != "" {
+			entry.Lineage = &history.Lineage{RegeneratedFrom: regeneratedFrom}
+		}
+		require.NoError(t, entry.Save(historyDir))
+		require.NoError(t, entry.SavePrompt(historyDir, prompt))
+		require.NoError(t, entry.SaveInputImages(historyDir, []string{inputPath}))
+		return entry
+	}
+	saveEntry("fine", true, "")
+	recovered := saveEntry("recovered", false, "")
+	saveEntry("recovered", true, recovered.ID)
+	failed := saveEntry("still failing", false, "")
+
+	mock := newSuccessMock(pngData)
+	var buf bytes.Buffer
+	err = (&regenerateHandler{generator: mock}).run(context.Background(), regenerateOptions{
+		allFailed:   true,
+		concurrency: 1,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Regenerating 1 failed entries")
+	require.Len(t, mock.calls, 1)
+	assert.Equal(t, "still failing", mock.calls[0].Prompt)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	newest := entries[len(entries)-1]
+	assert.True(t, newest.Result.Success)
+	require.NotNil(t, newest.Lineage)
+	assert.Equal(t, failed.ID, newest.Lineage.RegeneratedFrom)
+
+	// Every failure is recovered now
+	mock = newSuccessMock(pngData)
+	buf.Reset()
+	err = (&regenerateHandler{generator: mock}).run(context.Background(), regenerateOptions{
+		allFailed: true,
+	}, subprojectDir, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No failed entries to regenerate")
+	assert.Empty(t, mock.calls)
+}
+
+func TestScenario_Regenerate_DryRun(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	require.NoError(t, err)
+	cfg.InputImages = []string{"test.png"}
+	require.NoError(t, cfg.Save(subprojectDir))
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(project.GetInputsDir(subprojectDir), "test.png"), pngData, 0o644))
+
+	var ids []string
+	for _, prompt := range []string{"first prompt", "second prompt"} {
+		genHandler := &generateHandler{generator: newSuccessMock(pngData)}
+		require.NoError(t, genHandler.run(context.Background(), generateOptions{prompt: prompt}, subprojectDir, &bytes.Buffer{}))
+		latest, err := history.GetLatestEntry(historyDir)
+		require.NoError(t, err)
+		ids = append(ids, latest.ID)
+	}
+
+	// Each selected entry prints the request it would send; nothing is called or written
+	handler := &regenerateHandler{}
+	var buf bytes.Buffer
+	err = handler.run(context.Background(), regenerateOptions{ids: ids, dryRun: true}, subprojectDir, &buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "Request for "+ids[0]+":")
+	assert.Contains(t, output, "Request for "+ids[1]+":")
+	assert.Less(t, strings.Index(output, "first prompt"), strings.Index(output, "second prompt"))
+	assert.Contains(t, output, "the API was not called")
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
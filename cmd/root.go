@@ -2,15 +2,31 @@ package cmd
 
 import (
 	"cmp"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/spf13/cobra"
 )
 
 var cfg = struct {
-	apiKey string
+	apiKey   string
+	readOnly bool
+	output   string
+	events   string
+	noHints  bool
+	verbose  bool
+	logJSON  bool
+	logFile  string
 }{}
 
 // rootCmd represents the base command when called without any subcommands
@@ -18,19 +34,59 @@ var rootCmd = &cobra.Command{
 	Use:   "banago",
 	Short: "Image generation CLI powered by Gemini",
 	Long:  "CLI tool to generate images using Gemini 3 Pro Image Preview with prompts and reference images",
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		if err := validateEventsFormat(); err != nil {
+			return err
+		}
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if err := setupTracing(cmd); err != nil {
+			return err
+		}
+		if jsonOutput() {
+			// Errors are reported as JSON by Execute
+			cmd.Root().SilenceErrors = true
+			cmd.Root().SilenceUsage = true
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// Until setupLogging runs with the parsed flags, nothing is logged
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
 	if err != nil {
+		slog.Error("command failed", "command", cmd.CommandPath(), "duration", time.Since(start), "error", err)
+	} else {
+		slog.Info("command finished", "command", cmd.CommandPath(), "duration", time.Since(start))
+	}
+	finishTrace(err)
+	closeLog()
+	if err != nil {
+		if jsonOutput() {
+			_ = writeJSON(os.Stdout, jsonError{Error: err.Error(), Code: errorCode(err)})
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfg.apiKey, "api-key", "", "Gemini API key (defaults to GEMINI_API_KEY env var)")
+	rootCmd.PersistentFlags().StringVar(&cfg.output, "output", outputText, "Output format: text or json")
+	rootCmd.PersistentFlags().StringVar(&cfg.events, "events", "", "Stream progress events of generate, regenerate and edit to stdout: ndjson")
+	rootCmd.PersistentFlags().BoolVar(&cfg.readOnly, "read-only", false, "Refuse to run commands that modify the project")
+	rootCmd.PersistentFlags().BoolVar(&cfg.noHints, "no-hints", false, "Don't print \"Next steps\" hints")
+	rootCmd.PersistentFlags().BoolVar(&cfg.verbose, "verbose", false, "Log debug details (API latency, retries, durations) to stderr")
+	rootCmd.PersistentFlags().BoolVar(&cfg.logJSON, "log-json", false, "Write logs as JSON lines")
+	rootCmd.PersistentFlags().StringVar(&cfg.logFile, "log-file", "", "Append logs to this file instead of stderr")
 }
 
 // requireAPIKey checks if the API key is set and returns an error if not.
@@ -42,3 +98,116 @@ func requireAPIKey() error {
 	}
 	return nil
 }
+
+// requireWritable returns an error if the project must not be modified,
+// either because of the --read-only flag or readonly: true in banago.yaml.
+// Should be called by every command that writes to the project.
+func requireWritable(projectCfg *config.ProjectConfig) error {
+	if cfg.readOnly {
+		return errors.New("refusing to modify the project: --read-only is set")
+	}
+	if projectCfg != nil && projectCfg.ReadOnly {
+		return errors.New("refusing to modify the project: banago.yaml has readonly: true")
+	}
+	return nil
+}
+
+// currentHistoryDir resolves the history directory of the subproject containing the current directory.
+func currentHistoryDir() (string, error) {
+	_, historyDir, err := resolveCurrentHistoryDir(false)
+	return historyDir, err
+}
+
+// writableHistory is the history of the current subproject opened for writing by openWritableHistory
+type writableHistory struct {
+	subprojectName string // for confirmations
+	dir            string
+	layout         string // history_layout of banago.yaml, for entries the command creates
+	unlock         func() // releases the subproject lock; call once done writing
+}
+
+// openWritableHistory resolves the history directory like currentHistoryDir but fails if the project
+// is read-only, and takes the subproject lock so history is not changed under a running generation
+// or gc; it waits for the process holding it.
+func openWritableHistory(ctx context.Context, w io.Writer) (*writableHistory, error) {
+	projectCfg, subprojectDir, subprojectCfg, err := findCurrentSubproject(true)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockSubproject(ctx, subprojectDir, true, w)
+	if err != nil {
+		return nil, err
+	}
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+	// Listings only read history/index.json; writers holding the lock keep it current
+	history.RefreshIndex(historyDir)
+	return &writableHistory{
+		subprojectName: filepath.Base(subprojectDir),
+		dir:            historyDir,
+		layout:         projectCfg.HistoryLayout,
+		unlock:         unlock,
+	}, nil
+}
+
+func resolveCurrentHistoryDir(writable bool) (string, string, error) {
+	subprojectDir, subprojectCfg, err := resolveCurrentSubproject(writable)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Base(subprojectDir), project.ResolveHistoryDir(subprojectDir, subprojectCfg), nil
+}
+
+// resolveCurrentSubproject returns the directory and config of the subproject containing the current directory.
+// With writable set it fails if the project is read-only.
+func resolveCurrentSubproject(writable bool) (string, *config.SubprojectConfig, error) {
+	_, subprojectDir, subprojectCfg, err := findCurrentSubproject(writable)
+	return subprojectDir, subprojectCfg, err
+}
+
+// findCurrentSubproject is resolveCurrentSubproject that also returns the project config, which is
+// only loaded (and otherwise nil) with writable set
+func findCurrentSubproject(writable bool) (*config.ProjectConfig, string, *config.SubprojectConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	projectRoot, err := project.FindProjectRoot(cwd)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return nil, "", nil, errors.New("banago project not found. Run 'banago init' first")
+		}
+		return nil, "", nil, err
+	}
+
+	var projectCfg *config.ProjectConfig
+	if writable {
+		if projectCfg, err = config.LoadProjectConfig(projectRoot); err != nil {
+			return nil, "", nil, fmt.Errorf("failed to load project config: %w", err)
+		}
+		if err := requireWritable(projectCfg); err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	subprojectName, err := project.FindCurrentSubproject(projectRoot, cwd)
+	if err != nil {
+		if errors.Is(err, project.ErrNotInSubproject) {
+			return nil, "", nil, errors.New("not in a subproject. Navigate to a subproject directory")
+		}
+		return nil, "", nil, err
+	}
+
+	subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to load subproject config: %w", err)
+	}
+	if writable {
+		if err := project.CheckWorkDir(subprojectDir, subprojectCfg, cwd); err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return projectCfg, subprojectDir, subprojectCfg, nil
+}
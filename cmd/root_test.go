@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -19,6 +20,9 @@ func TestMain(m *testing.M) {
 	defer func() { _ = os.RemoveAll(tmpDir) }()
 
 	testBinPath = filepath.Join(tmpDir, "banago")
+	if runtime.GOOS == "windows" {
+		testBinPath += ".exe"
+	}
 	buildCmd := exec.Command("go", "build", "-o", testBinPath, "github.com/blck-snwmn/banago")
 	if output, err := buildCmd.CombinedOutput(); err != nil {
 		panic("failed to build binary: " + err.Error() + "\n" + string(output))
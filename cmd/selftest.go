@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/blck-snwmn/banago/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// selftestSubproject is the subproject the self-test creates in its temporary project
+const selftestSubproject = "selftest"
+
+var selftestOpts struct {
+	keep bool
+}
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Verify the installation end to end without calling the API",
+	Long: `Create a temporary project and run the main workflows against it with a mock
+image provider: generate, edit, regenerate, history and requests to the serve
+web UI. Each step is reported as ok or failed. No API key is needed and
+nothing outside the temporary directory is touched.
+
+Exits with an error when a step fails.
+
+Examples:
+  banago selftest
+  banago selftest --keep`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dir, err := os.MkdirTemp("", "banago-selftest-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		if !selftestOpts.keep {
+			defer func() { _ = os.RemoveAll(dir) }()
+		}
+
+		w := cmd.OutOrStdout()
+		failed := runSelftest(cmd.Context(), dir, w)
+		if selftestOpts.keep {
+			_, _ = fmt.Fprintf(w, "Project kept at %s\n", dir)
+		}
+		if failed {
+			// The report already names the failing step
+			cmd.SilenceUsage = true
+			return errors.New("self-test failed")
+		}
+		_, _ = fmt.Fprintln(w, "Self-test passed")
+		return nil
+	},
+}
+
+// selftestState is what earlier self-test steps hand to later ones
+type selftestState struct {
+	projectRoot   string
+	subprojectDir string
+	historyDir    string
+	entryID       string
+}
+
+// selftestStep is one check of the self-test
+type selftestStep struct {
+	name string
+	run  func(ctx context.Context, st *selftestState) error
+}
+
+// runSelftest runs the self-test steps in a new project below dir and reports whether one failed.
+// Steps after a failure are skipped because each builds on the previous ones.
+func runSelftest(ctx context.Context, dir string, w io.Writer) bool {
+	_, _ = fmt.Fprintf(w, "Running self-test in %s\n", dir)
+	st := &selftestState{projectRoot: dir}
+	failed := false
+	for _, step := range selftestSteps(gemini.NewMockClient()) {
+		if failed {
+			_, _ = fmt.Fprintf(w, "  --  %s (skipped)\n", step.name)
+			continue
+		}
+		if err := step.run(ctx, st); err != nil {
+			_, _ = fmt.Fprintf(w, "  !!  %s: %v\n", step.name, err)
+			failed = true
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "  ok  %s\n", step.name)
+	}
+	return failed
+}
+
+// selftestSteps returns the self-test steps, generating with client
+func selftestSteps(client *gemini.MockClient) []selftestStep {
+	return []selftestStep{
+		{"init", selftestInit},
+		{"generate", func(ctx context.Context, st *selftestState) error {
+			handler := &generateHandler{generator: client}
+			if err := handler.run(ctx, generateOptions{prompt: "a red fox in the snow", yes: true}, st.subprojectDir, io.Discard); err != nil {
+				return err
+			}
+			entry, err := selftestLatestEntry(st.historyDir, 1)
+			if err != nil {
+				return err
+			}
+			st.entryID = entry.ID
+			return nil
+		}},
+		{"edit", func(ctx context.Context, st *selftestState) error {
+			handler := &editHandler{generator: client}
+			if err := handler.run(ctx, editOptions{id: st.entryID, prompt: "make it night", yes: true}, st.subprojectDir, io.Discard); err != nil {
+				return err
+			}
+			edits, err := history.ListEditEntries(history.GetEntryDirByID(st.historyDir, st.entryID))
+			if err != nil {
+				return err
+			}
+			if len(edits) != 1 || !edits[0].Result.Success {
+				return fmt.Errorf("expected 1 successful edit, found %d", len(edits))
+			}
+			return selftestOutputsExist(edits[0].GetEditEntryDir(history.GetEntryDirByID(st.historyDir, st.entryID)), edits[0].Result.OutputImages)
+		}},
+		{"regenerate", func(ctx context.Context, st *selftestState) error {
+			handler := &regenerateHandler{generator: client}
+			if err := handler.run(ctx, regenerateOptions{id: st.entryID, yes: true}, st.subprojectDir, io.Discard); err != nil {
+				return err
+			}
+			entry, err := selftestLatestEntry(st.historyDir, 2)
+			if err != nil {
+				return err
+			}
+			if entry.Lineage == nil || entry.Lineage.RegeneratedFrom != st.entryID {
+				return errors.New("regenerated entry does not record its source")
+			}
+			return nil
+		}},
+		{"history", func(_ context.Context, st *selftestState) error {
+			entries, err := history.SearchEntries(st.historyDir)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := printHistoryJSON(&buf, st.historyDir, entries, 0); err != nil {
+				return err
+			}
+			var out []entryJSON
+			if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+				return fmt.Errorf("invalid history JSON: %w", err)
+			}
+			if len(out) != 2 {
+				return fmt.Errorf("expected 2 entries in history, found %d", len(out))
+			}
+			return nil
+		}},
+		{"serve", selftestServe},
+	}
+}
+
+// selftestInit creates the project and a subproject with one input image
+func selftestInit(_ context.Context, st *selftestState) error {
+	if err := project.InitProject(st.projectRoot, "banago-selftest", false); err != nil {
+		return err
+	}
+	if err := project.CreateSubproject(st.projectRoot, selftestSubproject, "self-test"); err != nil {
+		return err
+	}
+	st.subprojectDir = project.GetSubprojectDir(st.projectRoot, selftestSubproject)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(project.GetInputsDir(st.subprojectDir), "input.png"), buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write input image: %w", err)
+	}
+	cfg, err := config.LoadSubprojectConfig(st.subprojectDir)
+	if err != nil {
+		return err
+	}
+	cfg.InputImages = []string{"input.png"}
+	if err := cfg.Save(st.subprojectDir); err != nil {
+		return err
+	}
+	st.historyDir = project.ResolveHistoryDir(st.subprojectDir, cfg)
+	return nil
+}
+
+// selftestServe starts the web UI on a free port and requests its pages and an output image
+func selftestServe(ctx context.Context, st *selftestState) error {
+	entry, err := history.GetEntryByID(st.historyDir, st.entryID)
+	if err != nil {
+		return err
+	}
+
+	srv := server.New(st.projectRoot, 0)
+	addr, err := srv.Listen()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	base := "http://" + net.JoinHostPort("127.0.0.1", fmt.Sprint(addr.(*net.TCPAddr).Port))
+	paths := []string{
+		"/",
+		"/subprojects/" + selftestSubproject,
+		"/entry/" + selftestSubproject + "/" + entry.ID,
+		"/images/" + selftestSubproject + "/" + entry.ID + "/" + entry.Result.OutputImages[0],
+	}
+	for _, path := range paths {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("GET %s: %w", path, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: %s", path, resp.Status)
+		}
+	}
+	return nil
+}
+
+// selftestLatestEntry checks that historyDir holds want entries and returns the newest,
+// which must have succeeded with its outputs on disk
+func selftestLatestEntry(historyDir string, want int) (*history.Entry, error) {
+	entries, err := history.ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != want {
+		return nil, fmt.Errorf("expected %d history entries, found %d", want, len(entries))
+	}
+	entry := entries[len(entries)-1]
+	if !entry.Result.Success {
+		return nil, fmt.Errorf("entry %s failed: %s", entry.ID, entry.Result.ErrorMessage)
+	}
+	return entry, selftestOutputsExist(entry.OutputDir(historyDir), entry.Result.OutputImages)
+}
+
+// selftestOutputsExist checks that at least one output was recorded and every one exists in dir
+func selftestOutputsExist(dir string, outputs []string) error {
+	if len(outputs) == 0 {
+		return errors.New("no outputs recorded")
+	}
+	for _, name := range outputs {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("output missing: %w", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+	selftestCmd.Flags().BoolVar(&selftestOpts.keep, "keep", false, "Keep the temporary project for inspection")
+}
@@ -1,24 +1,50 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/blck-snwmn/banago/internal/server"
 	"github.com/spf13/cobra"
 )
 
 var serveOpts struct {
-	port int
+	port          int
+	authToken     string
+	pageSize      int
+	allowGenerate bool
 }
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start a web server to browse generated images",
-	Long:  "Launch a local web server to view generation history and images in a browser.",
-	Args:  cobra.NoArgs,
+	Long: `Launch a local web server to view generation history and images in a browser.
+
+With --allow-generate, which needs an API key (--api-key or GEMINI_API_KEY) and
+a writable project, subproject pages also get a form to generate images.
+Generation runs in the background with the same settings as 'banago generate'
+in that subproject, and its progress is streamed to the page. Submissions from
+other sites are refused, and at most a few generations run at once.
+
+Set --auth-token (or BANAGO_SERVE_TOKEN) before exposing the server beyond
+localhost: every request then needs the token, as a bearer token or as the
+password of the browser's login prompt (any user name).
+
+Subproject pages show the newest --page-size entries and link to older pages
+(?page=2, ...). Use --page-size 0 to list every entry on one page.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -34,16 +60,71 @@ var serveCmd = &cobra.Command{
 		}
 
 		w := cmd.OutOrStdout()
-		_, _ = fmt.Fprintf(w, "Starting server at http://localhost:%d\n", serveOpts.port)
+		srv := server.New(projectRoot, serveOpts.port)
+		srv.SetPageSize(serveOpts.pageSize)
+
+		// Browsing needs no API key; generating from the browser spends credit, so it is opt-in
+		if serveOpts.allowGenerate {
+			projectCfg, err := config.LoadProjectConfig(projectRoot)
+			if err != nil {
+				return fmt.Errorf("failed to load project config: %w", err)
+			}
+			if err := requireWritable(projectCfg); err != nil {
+				return fmt.Errorf("--allow-generate: %w", err)
+			}
+			if err := requireAPIKey(); err != nil {
+				return fmt.Errorf("--allow-generate: %w", err)
+			}
+			client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+			if err != nil {
+				return fmt.Errorf("failed to create Gemini client: %w", err)
+			}
+			srv.EnableGeneration(serveGenerateFunc(client))
+			_, _ = fmt.Fprintln(w, "Generation from the browser is enabled")
+		}
+
+		if token := cmp.Or(strings.TrimSpace(serveOpts.authToken), strings.TrimSpace(os.Getenv("BANAGO_SERVE_TOKEN"))); token != "" {
+			srv.RequireToken(token)
+			_, _ = fmt.Fprintln(w, "Authentication required: use the token as a bearer token or as the login password")
+		}
+
+		addr, err := srv.Listen()
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Starting server at http://localhost:%d\n", addr.(*net.TCPAddr).Port)
 		_, _ = fmt.Fprintln(w, "Press Ctrl+C to stop")
 
-		srv := server.New(projectRoot, serveOpts.port)
-		return srv.Start()
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := srv.Serve(ctx); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, "Server stopped")
+		return nil
 	},
 }
 
+// serveGenerateFunc runs browser-submitted generations through the generate handler.
+// Submitting the form is the confirmation, so confirm_before_generate is not asked again.
+func serveGenerateFunc(generator generation.Generator) server.GenerateFunc {
+	return func(ctx context.Context, subprojectDir string, req server.GenerateRequest, w io.Writer) error {
+		h := &generateHandler{generator: generator, stdin: strings.NewReader("")}
+		return h.run(ctx, generateOptions{
+			prompt: req.Prompt,
+			aspect: req.Aspect,
+			size:   req.Size,
+			yes:    true,
+			wait:   true,
+		}, subprojectDir, w)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 
-	serveCmd.Flags().IntVar(&serveOpts.port, "port", 8080, "Port to listen on")
+	serveCmd.Flags().IntVar(&serveOpts.port, "port", 8080, "Port to listen on (0 picks a free port)")
+	serveCmd.Flags().StringVar(&serveOpts.authToken, "auth-token", "", "Require this token on every request (default: BANAGO_SERVE_TOKEN)")
+	serveCmd.Flags().BoolVar(&serveOpts.allowGenerate, "allow-generate", false, "Let subproject pages submit generations (needs an API key; spends API credit)")
+	serveCmd.Flags().IntVar(&serveOpts.pageSize, "page-size", server.DefaultPageSize, "Entries per subproject page (0 shows all)")
 }
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var statsOpts struct {
+	since      string
+	until      string
+	subproject string
+	failures   bool
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report API calls and failure rates per subproject or day",
+	Long: `Report the API calls of generations and edits in a period and how many failed,
+per subproject. --failures breaks the failures down by day and category
+instead, to tell a prompt problem from a quota or API problem:
+
+  safety   blocked by the safety filter (prompts or input images)
+  quota    rate limit or quota used up
+  network  the request or its answer got lost
+  server   the API failed (HTTP 5xx)
+  empty    the response held no image
+  other    any other error
+
+Failures are read from history/failures.jsonl, which generate, regenerate
+and edit append to whether or not keep_failures keeps the entry. Days are UTC.
+--since and --until take the values of 'banago changelog'.
+
+Examples:
+  banago stats
+  banago stats --failures --since 14d
+  banago stats --failures --subproject fox --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		now := time.Now()
+		since, _, err := parseTimeFlag(statsOpts.since, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		var until time.Time
+		if statsOpts.until != "" {
+			t, precision, err := parseTimeFlag(statsOpts.until, now)
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			until = t.Add(precision)
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		projectRoot, err := project.FindProjectRoot(cwd)
+		if err != nil {
+			if errors.Is(err, project.ErrProjectNotFound) {
+				return errors.New("banago project not found. Run 'banago init' first")
+			}
+			return err
+		}
+
+		names := []string{statsOpts.subproject}
+		if statsOpts.subproject == "" {
+			infos, err := project.ListSubprojectInfos(projectRoot)
+			if err != nil {
+				return fmt.Errorf("failed to list subprojects: %w", err)
+			}
+			names = names[:0]
+			for _, info := range infos {
+				names = append(names, info.Name)
+			}
+		}
+
+		report := statsReport{days: map[string]*history.FailureStats{}}
+		for _, name := range names {
+			subprojectDir := project.GetSubprojectDir(projectRoot, name)
+			if !config.SubprojectConfigExists(subprojectDir) {
+				return fmt.Errorf("subproject %s not found", name)
+			}
+			subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+			if err != nil {
+				return fmt.Errorf("failed to load subproject config of %s: %w", name, err)
+			}
+			days, err := history.FailuresByDay(project.ResolveHistoryDir(subprojectDir, subprojectCfg), since, until)
+			if err != nil {
+				return fmt.Errorf("failed to load history of %s: %w", name, err)
+			}
+			var sub history.FailureStats
+			for day, s := range days {
+				sub.Add(*s)
+				if report.days[day] == nil {
+					report.days[day] = &history.FailureStats{}
+				}
+				report.days[day].Add(*s)
+			}
+			report.subprojects = append(report.subprojects, statsRow{label: name, stats: sub})
+			report.total.Add(sub)
+		}
+
+		w := cmd.OutOrStdout()
+		if jsonOutput() {
+			return writeJSON(w, newStatsJSON(report))
+		}
+		if statsOpts.failures {
+			printFailureReport(w, report)
+		} else {
+			printStatsReport(w, report)
+		}
+		return nil
+	},
+}
+
+// statsReport holds the API calls of the selected subprojects, per subproject and per day
+type statsReport struct {
+	subprojects []statsRow
+	days        map[string]*history.FailureStats // keyed by YYYY-MM-DD (UTC)
+	total       history.FailureStats
+}
+
+// statsRow is a labeled line of a stats table
+type statsRow struct {
+	label string
+	stats history.FailureStats
+}
+
+// dayRows returns the days of the report in order
+func (r statsReport) dayRows() []statsRow {
+	var rows []statsRow
+	for day, s := range r.days {
+		rows = append(rows, statsRow{label: day, stats: *s})
+	}
+	slices.SortFunc(rows, func(a, b statsRow) int { return strings.Compare(a.label, b.label) })
+	return rows
+}
+
+// failureRate formats the share of failed calls
+func failureRate(s history.FailureStats) string {
+	if s.Runs == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(s.Failed())*100/float64(s.Runs))
+}
+
+func printStatsReport(w io.Writer, report statsReport) {
+	if report.total.Runs == 0 {
+		_, _ = fmt.Fprintln(w, "No API calls in this period")
+		return
+	}
+	nameWidth := len("Subproject")
+	for _, r := range report.subprojects {
+		nameWidth = max(nameWidth, len(r.label))
+	}
+	_, _ = fmt.Fprintf(w, "%-*s  %5s  %6s  %5s\n", nameWidth, "Subproject", "Calls", "Failed", "Rate")
+	for _, r := range append(report.subprojects, statsRow{label: "Total", stats: report.total}) {
+		_, _ = fmt.Fprintf(w, "%-*s  %5d  %6d  %5s\n", nameWidth, r.label, r.stats.Runs, r.stats.Failed(), failureRate(r.stats))
+	}
+	if report.total.Failed() > 0 {
+		_, _ = fmt.Fprintln(w, "\nRun 'banago stats --failures' for failures by day and category")
+	}
+}
+
+func printFailureReport(w io.Writer, report statsReport) {
+	if report.total.Failed() == 0 {
+		_, _ = fmt.Fprintf(w, "No failures in this period (%d API calls)\n", report.total.Runs)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%-10s  %5s  %6s  %5s", "Day", "Calls", "Failed", "Rate")
+	for _, category := range gemini.FailureCategories {
+		_, _ = fmt.Fprintf(w, "  %7s", category)
+	}
+	_, _ = fmt.Fprintln(w)
+	for _, r := range append(report.dayRows(), statsRow{label: "Total", stats: report.total}) {
+		_, _ = fmt.Fprintf(w, "%-10s  %5d  %6d  %5s", r.label, r.stats.Runs, r.stats.Failed(), failureRate(r.stats))
+		for _, category := range gemini.FailureCategories {
+			_, _ = fmt.Fprintf(w, "  %7d", r.stats.Categories[category])
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+// statsJSON is the JSON output of stats
+type statsJSON struct {
+	Subprojects []statsRowJSON `json:"subprojects"`
+	Days        []statsRowJSON `json:"days"`
+	Total       statsRowJSON   `json:"total"`
+}
+
+type statsRowJSON struct {
+	Subproject string         `json:"subproject,omitempty"`
+	Day        string         `json:"day,omitempty"`
+	Calls      int            `json:"calls"`
+	Failed     int            `json:"failed"`
+	Categories map[string]int `json:"categories"`
+}
+
+func newStatsRowJSON(s history.FailureStats) statsRowJSON {
+	categories := map[string]int{}
+	for category, n := range s.Categories {
+		if n > 0 {
+			categories[category] = n
+		}
+	}
+	return statsRowJSON{Calls: s.Runs, Failed: s.Failed(), Categories: categories}
+}
+
+func newStatsJSON(report statsReport) statsJSON {
+	out := statsJSON{Subprojects: []statsRowJSON{}, Days: []statsRowJSON{}, Total: newStatsRowJSON(report.total)}
+	for _, r := range report.subprojects {
+		row := newStatsRowJSON(r.stats)
+		row.Subproject = r.label
+		out.Subprojects = append(out.Subprojects, row)
+	}
+	for _, r := range report.dayRows() {
+		row := newStatsRowJSON(r.stats)
+		row.Day = r.label
+		out.Days = append(out.Days, row)
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsOpts.since, "since", "30d", "Start of the period: date, timestamp or age (e.g. 7d)")
+	statsCmd.Flags().StringVar(&statsOpts.until, "until", "", "End of the period, inclusive (default: now)")
+	statsCmd.Flags().StringVar(&statsOpts.subproject, "subproject", "", "Only report this subproject")
+	statsCmd.Flags().BoolVar(&statsOpts.failures, "failures", false, "Break failures down by day and category")
+}
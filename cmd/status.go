@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/history"
@@ -12,10 +13,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	uuidShortLen  = 8  // Length for shortened UUID display (e.g., "01234567...")
-	datePrefixLen = 10 // Length for date prefix from RFC3339 (YYYY-MM-DD)
-)
+const uuidShortLen = 8 // Length for shortened UUID display (e.g., "01234567...")
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
@@ -48,9 +46,20 @@ var statusCmd = &cobra.Command{
 		subprojectName, err := project.FindCurrentSubproject(projectRoot, cwd)
 		if err != nil {
 			if errors.Is(err, project.ErrNotInSubproject) {
+				if jsonOutput() {
+					return writeJSON(w, statusJSON{
+						Project:  projectCfg.Name,
+						Model:    projectCfg.Model,
+						ReadOnly: requireWritable(projectCfg) != nil,
+					})
+				}
+
 				// Show project-level status
 				_, _ = fmt.Fprintf(w, "Project: %s\n", projectCfg.Name)
 				_, _ = fmt.Fprintf(w, "Model: %s\n", projectCfg.Model)
+				if requireWritable(projectCfg) != nil {
+					_, _ = fmt.Fprintln(w, "Mode: read-only")
+				}
 				_, _ = fmt.Fprintln(w, "")
 				_, _ = fmt.Fprintln(w, "Not in a subproject.")
 				_, _ = fmt.Fprintln(w, "Navigate to a subproject or create one:")
@@ -68,14 +77,26 @@ var statusCmd = &cobra.Command{
 			return fmt.Errorf("failed to load subproject config: %w", err)
 		}
 
+		if jsonOutput() {
+			return writeJSON(w, newSubprojectStatusJSON(projectRoot, projectCfg, subprojectDir, subprojectCfg))
+		}
+
 		_, _ = fmt.Fprintf(w, "Project: %s\n", projectCfg.Name)
 		_, _ = fmt.Fprintf(w, "Subproject: %s\n", subprojectCfg.Name)
+		if requireWritable(projectCfg) != nil {
+			_, _ = fmt.Fprintln(w, "Mode: read-only")
+		}
 		if subprojectCfg.Description != "" {
 			_, _ = fmt.Fprintf(w, "Description: %s\n", subprojectCfg.Description)
 		}
 		_, _ = fmt.Fprintln(w, "")
 
-		// Context file
+		// Context files, project-level first
+		projectContextPath := project.GetProjectContextPath(projectRoot)
+		if _, err := os.Stat(projectContextPath); err == nil {
+			relPath, _ := filepath.Rel(cwd, projectContextPath)
+			_, _ = fmt.Fprintf(w, "Project context: %s\n", relPath)
+		}
 		contextPath := filepath.Join(subprojectDir, subprojectCfg.ContextFile)
 		if _, err := os.Stat(contextPath); err == nil {
 			relPath, _ := filepath.Rel(cwd, contextPath)
@@ -110,10 +131,28 @@ var statusCmd = &cobra.Command{
 				}
 			}
 		}
+		if check, err := project.CheckInputs(subprojectDir, subprojectCfg); err == nil && len(check.Unreferenced) > 0 {
+			_, _ = fmt.Fprintln(w, "Not in input_images:")
+			inputsDir := project.GetInputsDir(subprojectDir)
+			for _, img := range check.Unreferenced {
+				relPath, _ := filepath.Rel(cwd, filepath.Join(inputsDir, img))
+				_, _ = fmt.Fprintf(w, "  %s\n", relPath)
+			}
+		}
+		_, _ = fmt.Fprintln(w, "")
+
+		// Archive policy
+		archive := subprojectCfg.Archive
+		_, _ = fmt.Fprintf(w, "Archive: inputs=%s context=%s character=%s raw_response=%s\n",
+			archive.InputsMode(), onOff(archive.Context), onOff(archive.Character), onOff(archive.RawResponse))
+		if mirrorDir := project.ResolveMirrorDir(subprojectDir, subprojectCfg); mirrorDir != "" {
+			relPath, _ := filepath.Rel(cwd, mirrorDir)
+			_, _ = fmt.Fprintf(w, "Mirror: %s to %s/YYYY-MM-DD/\n", subprojectCfg.Mirror.Mode, relPath)
+		}
 		_, _ = fmt.Fprintln(w, "")
 
 		// History summary
-		historyDir := history.GetHistoryDir(subprojectDir)
+		historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
 		entries, err := history.ListEntries(historyDir)
 		if err != nil {
 			_, _ = fmt.Fprintln(w, "History: (load error)")
@@ -123,13 +162,134 @@ var statusCmd = &cobra.Command{
 			_, _ = fmt.Fprintf(w, "History: %d entries\n", len(entries))
 			// Show latest entry
 			latest := entries[len(entries)-1]
-			_, _ = fmt.Fprintf(w, "  Latest: %s (%s)\n", latest.ID[:uuidShortLen]+"...", latest.CreatedAt[:datePrefixLen])
+			_, _ = fmt.Fprintf(w, "  Latest: %s (%s)\n", latest.ID[:uuidShortLen]+"...", history.FormatLocal(latest.CreatedAt, time.Now()))
+			if spend, err := totalSpend(historyDir, projectCfg.Model); err == nil {
+				_, _ = fmt.Fprintf(w, "  Estimated spend: %s (%d generations, %d edits). Run 'banago cost' for details\n",
+					formatUSD(spend.USD), spend.Generations, spend.Edits)
+			}
 		}
 
 		return nil
 	},
 }
 
+// statusJSON is the JSON output of status
+type statusJSON struct {
+	Project            string             `json:"project"`
+	Model              string             `json:"model"`
+	ReadOnly           bool               `json:"read_only"`
+	Subproject         string             `json:"subproject,omitempty"`
+	Description        string             `json:"description,omitempty"`
+	ProjectContext     string             `json:"project_context,omitempty"` // shared context.md at the project root
+	Context            string             `json:"context,omitempty"`
+	Character          *statusFileJSON    `json:"character,omitempty"`
+	InputImages        []statusFileJSON   `json:"input_images,omitempty"`
+	UnreferencedInputs []string           `json:"unreferenced_inputs,omitempty"` // images in inputs/ not listed in input_images
+	Archive            *statusArchiveJSON `json:"archive,omitempty"`
+	Mirror             *statusMirrorJSON  `json:"mirror,omitempty"`
+	History            *statusHistoryJSON `json:"history,omitempty"`
+}
+
+// statusFileJSON is a configured file and whether it exists
+type statusFileJSON struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// statusArchiveJSON is the effective archive policy of a subproject
+type statusArchiveJSON struct {
+	Inputs      string `json:"inputs"`
+	Context     bool   `json:"context"`
+	Character   bool   `json:"character"`
+	RawResponse bool   `json:"raw_response"`
+}
+
+// statusMirrorJSON is the output mirror of a subproject
+type statusMirrorJSON struct {
+	Mode string `json:"mode"`
+	Dir  string `json:"dir"`
+}
+
+// statusHistoryJSON summarizes the history of a subproject
+type statusHistoryJSON struct {
+	Count            int     `json:"count"`
+	LatestID         string  `json:"latest_id,omitempty"`
+	LatestCreatedAt  string  `json:"latest_created_at,omitempty"`
+	Edits            int     `json:"edits"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"` // generations and edits
+}
+
+func newStatusFileJSON(path string) statusFileJSON {
+	_, err := os.Stat(path)
+	return statusFileJSON{Path: absDir(path), Exists: err == nil}
+}
+
+func newSubprojectStatusJSON(projectRoot string, projectCfg *config.ProjectConfig, subprojectDir string, subprojectCfg *config.SubprojectConfig) statusJSON {
+	archive := subprojectCfg.Archive
+	out := statusJSON{
+		Project:     projectCfg.Name,
+		Model:       projectCfg.Model,
+		ReadOnly:    requireWritable(projectCfg) != nil,
+		Subproject:  subprojectCfg.Name,
+		Description: subprojectCfg.Description,
+		InputImages: []statusFileJSON{},
+		Archive: &statusArchiveJSON{
+			Inputs:      archive.InputsMode(),
+			Context:     archive.Context,
+			Character:   archive.Character,
+			RawResponse: archive.RawResponse,
+		},
+	}
+
+	if mirrorDir := project.ResolveMirrorDir(subprojectDir, subprojectCfg); mirrorDir != "" {
+		out.Mirror = &statusMirrorJSON{Mode: subprojectCfg.Mirror.Mode, Dir: absDir(mirrorDir)}
+	}
+	projectContextPath := project.GetProjectContextPath(projectRoot)
+	if _, err := os.Stat(projectContextPath); err == nil {
+		out.ProjectContext = absDir(projectContextPath)
+	}
+	contextPath := filepath.Join(subprojectDir, subprojectCfg.ContextFile)
+	if _, err := os.Stat(contextPath); err == nil {
+		out.Context = absDir(contextPath)
+	}
+	if subprojectCfg.CharacterFile != "" {
+		character := newStatusFileJSON(project.GetCharacterPath(projectRoot, subprojectCfg.CharacterFile))
+		out.Character = &character
+	}
+	inputsDir := project.GetInputsDir(subprojectDir)
+	for _, img := range subprojectCfg.InputImages {
+		out.InputImages = append(out.InputImages, newStatusFileJSON(filepath.Join(inputsDir, img)))
+	}
+	if check, err := project.CheckInputs(subprojectDir, subprojectCfg); err == nil {
+		for _, img := range check.Unreferenced {
+			out.UnreferencedInputs = append(out.UnreferencedInputs, absDir(filepath.Join(inputsDir, img)))
+		}
+	}
+
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+	if entries, err := history.ListEntries(historyDir); err == nil {
+		out.History = &statusHistoryJSON{Count: len(entries)}
+		if len(entries) > 0 {
+			latest := entries[len(entries)-1]
+			out.History.LatestID = latest.ID
+			out.History.LatestCreatedAt = latest.CreatedAt
+		}
+		if spend, err := totalSpend(historyDir, projectCfg.Model); err == nil {
+			out.History.Edits = spend.Edits
+			out.History.EstimatedCostUSD = spend.USD
+		}
+	}
+	return out
+}
+
+// onOff formats a boolean setting for display
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 }
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +24,8 @@ var subprojectCmd = &cobra.Command{
 
 var subprojectCreateOpts struct {
 	description string
+	brief       string // free-form brief file to draft context.md, prompt.txt and config fields from
+	model       string // text model for --brief (default: text_model in banago.yaml)
 }
 
 var subprojectCreateCmd = &cobra.Command{
@@ -28,7 +37,15 @@ The following files and directories will be created:
   - subprojects/<name>/config.yaml (subproject config)
   - subprojects/<name>/context.md (additional info file)
   - subprojects/<name>/inputs/ (input images directory)
-  - subprojects/<name>/history/ (generation history directory)`,
+  - subprojects/<name>/history/ (generation history directory)
+
+With --brief, a text model drafts context.md, a starting prompt.txt and the
+aspect_ratio / image_size of config.yaml from a free-form brief. Only what the
+brief states is used; review the drafted files before generating.
+
+Examples:
+  banago subproject create beach
+  banago subproject create beach --brief brief.md`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
@@ -46,22 +63,99 @@ The following files and directories will be created:
 			return err
 		}
 
-		if err := project.CreateSubproject(projectRoot, name, subprojectCreateOpts.description); err != nil {
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+		if err := requireWritable(projectCfg); err != nil {
+			return err
+		}
+
+		// Draft before creating anything so that a failed API call leaves no half-made subproject
+		var draft *generation.SubprojectDraft
+		if subprojectCreateOpts.brief != "" {
+			if draft, err = draftFromBrief(cmd.Context(), projectCfg, subprojectCreateOpts.brief, subprojectCreateOpts.model); err != nil {
+				return err
+			}
+		}
+
+		description := subprojectCreateOpts.description
+		if description == "" && draft != nil {
+			description = draft.Description
+		}
+		if err := project.CreateSubproject(projectRoot, name, description); err != nil {
 			return fmt.Errorf("failed to create subproject: %w", err)
 		}
 
 		w := cmd.OutOrStdout()
 		_, _ = fmt.Fprintf(w, "Created subproject '%s'\n", name)
-		_, _ = fmt.Fprintln(w, "")
-		_, _ = fmt.Fprintln(w, "Next steps:")
-		_, _ = fmt.Fprintf(w, "  1. Configure character reference in subprojects/%s/config.yaml\n", name)
-		_, _ = fmt.Fprintf(w, "  2. Add context info to subprojects/%s/context.md\n", name)
-		_, _ = fmt.Fprintf(w, "  3. Place reference images in subprojects/%s/inputs/\n", name)
+		if draft != nil {
+			subprojectDir := project.GetSubprojectDir(projectRoot, name)
+			if err := applySubprojectDraft(subprojectDir, draft); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(w, "Drafted %s, %s and config.yaml from %s\n", config.DefaultContextFile, workingPromptFile, subprojectCreateOpts.brief)
+			for _, dropped := range draft.Dropped {
+				_, _ = fmt.Fprintf(w, "  Ignored invalid suggestion %s\n", dropped)
+			}
+			printNextSteps(w, projectCfg,
+				fmt.Sprintf("Review the drafted subprojects/%s/%s and %s", name, config.DefaultContextFile, workingPromptFile),
+				fmt.Sprintf("Configure character reference in subprojects/%s/config.yaml", name),
+				fmt.Sprintf("Place reference images in subprojects/%s/inputs/", name),
+			)
+			return nil
+		}
+		printNextSteps(w, projectCfg,
+			fmt.Sprintf("Configure character reference in subprojects/%s/config.yaml", name),
+			fmt.Sprintf("Add context info to subprojects/%s/context.md", name),
+			fmt.Sprintf("Place reference images in subprojects/%s/inputs/", name),
+		)
 
 		return nil
 	},
 }
 
+// draftFromBrief reads the brief file and has the text model draft the subproject from it
+func draftFromBrief(ctx context.Context, projectCfg *config.ProjectConfig, briefPath, model string) (*generation.SubprojectDraft, error) {
+	if err := requireAPIKey(); err != nil {
+		return nil, err
+	}
+	brief, err := os.ReadFile(briefPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read brief: %w", err)
+	}
+	client, err := gemini.NewClient(ctx, cfg.apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return generation.DraftSubproject(ctx, client, cmp.Or(model, projectCfg.TextModel, gemini.DefaultTextModel), string(brief))
+}
+
+// applySubprojectDraft writes a draft into a newly created subproject: context.md, prompt.txt and
+// the suggested config fields
+func applySubprojectDraft(subprojectDir string, draft *generation.SubprojectDraft) error {
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return fmt.Errorf("failed to load subproject config: %w", err)
+	}
+	subprojectCfg.AspectRatio = draft.AspectRatio
+	subprojectCfg.ImageSize = draft.ImageSize
+	if err := subprojectCfg.Save(subprojectDir); err != nil {
+		return fmt.Errorf("failed to save subproject config: %w", err)
+	}
+
+	files := []struct{ name, text string }{
+		{config.DefaultContextFile, draft.Context},
+		{workingPromptFile, draft.Prompt},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(subprojectDir, f.name), []byte(strings.TrimSpace(f.text)+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
 var subprojectListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List subprojects",
@@ -86,6 +180,14 @@ var subprojectListCmd = &cobra.Command{
 		}
 
 		w := cmd.OutOrStdout()
+		if jsonOutput() {
+			out := []subprojectJSON{}
+			for _, info := range infos {
+				out = append(out, subprojectJSON{Name: info.Name, Description: info.Description})
+			}
+			return writeJSON(w, out)
+		}
+
 		if len(infos) == 0 {
 			_, _ = fmt.Fprintln(w, "No subprojects found")
 			_, _ = fmt.Fprintln(w, "")
@@ -107,10 +209,18 @@ var subprojectListCmd = &cobra.Command{
 	},
 }
 
+// subprojectJSON describes a subproject in JSON output of subproject list
+type subprojectJSON struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
 func init() {
 	rootCmd.AddCommand(subprojectCmd)
 	subprojectCmd.AddCommand(subprojectCreateCmd)
 	subprojectCmd.AddCommand(subprojectListCmd)
 
 	subprojectCreateCmd.Flags().StringVar(&subprojectCreateOpts.description, "description", "", "Subproject description")
+	subprojectCreateCmd.Flags().StringVar(&subprojectCreateOpts.brief, "brief", "", "Brief file to draft context.md, prompt.txt and config fields from with a text model")
+	subprojectCreateCmd.Flags().StringVar(&subprojectCreateOpts.model, "model", "", "Text model for --brief instead of banago.yaml's text_model")
 }
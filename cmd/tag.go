@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// entrySelection selects the history entries a bulk command works on: the entries given by --ids,
+// or with --all every entry matching the query flags
+type entrySelection struct {
+	entryQuery
+	ids []string
+	all bool
+}
+
+// register adds the selection flags to cmd
+func (s *entrySelection) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&s.ids, "ids", nil, "Comma-separated history entry IDs (repeatable)")
+	cmd.Flags().BoolVar(&s.all, "all", false, "Select every entry matching the filter flags (all entries without filters)")
+	addEntryQueryFlags(cmd, &s.entryQuery, "select")
+	cmd.MarkFlagsOneRequired("ids", "all")
+	cmd.MarkFlagsMutuallyExclusive("ids", "all")
+}
+
+// entries loads the selected entries of historyDir
+func (s *entrySelection) entries(historyDir string) ([]*history.Entry, error) {
+	predicates, err := s.predicates(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !s.all {
+		if len(predicates) > 0 {
+			return nil, errors.New("filter flags select entries only with --all")
+		}
+		var entries []*history.Entry
+		for _, id := range s.ids {
+			e, err := history.GetEntryByID(historyDir, strings.TrimSpace(id))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get history entry: %w", err)
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	}
+
+	entries, err := history.SearchEntries(historyDir, predicates...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	return entries, nil
+}
+
+// updateEntries applies update to every entry and saves those it changed. It prints each changed
+// entry with label and returns how many changed.
+func updateEntries(w io.Writer, historyDir string, entries []*history.Entry, label string, update func(e *history.Entry) bool) (int, error) {
+	changed := 0
+	for _, e := range entries {
+		if !update(e) {
+			continue
+		}
+		if err := e.Save(historyDir); err != nil {
+			return changed, fmt.Errorf("failed to update entry %s: %w", e.ID, err)
+		}
+		changed++
+		_, _ = fmt.Fprintf(w, "  %s %s\n", label, e.ID)
+	}
+	return changed, nil
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag history entries",
+	Long: `Add or remove tags on history entries of the current subproject, one entry or
+many at once, to curate the results of a batch run.
+
+Entries are selected with --ids, or with --all and the filters of
+'banago history' (--search, --since, --until, --failed-only, --tag, --min-rating).
+
+Examples:
+  banago tag add --all --since 7d draft
+  banago tag add --ids <uuid>,<uuid> hero final
+  banago tag rm --all --tag draft draft
+  banago history --tag hero`,
+}
+
+var tagAddSel, tagRmSel entrySelection
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <tag>...",
+	Short: "Add tags to the selected entries",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, &tagAddSel, args, "Tagged", (*history.Entry).AddTags)
+	},
+}
+
+var tagRmCmd = &cobra.Command{
+	Use:   "rm <tag>...",
+	Short: "Remove tags from the selected entries",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, &tagRmSel, args, "Untagged", (*history.Entry).RemoveTags)
+	},
+}
+
+// runTagUpdate applies a tag change to the selected entries of the current subproject
+func runTagUpdate(cmd *cobra.Command, sel *entrySelection, tags []string, label string, update func(*history.Entry, ...string) bool) error {
+	w := cmd.OutOrStdout()
+	for _, tag := range tags {
+		if err := history.ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	hist, err := openWritableHistory(cmd.Context(), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	defer hist.unlock()
+	historyDir := hist.dir
+	entries, err := sel.entries(historyDir)
+	if err != nil {
+		return err
+	}
+
+	changed, err := updateEntries(w, historyDir, entries, label, func(e *history.Entry) bool {
+		return update(e, tags...)
+	})
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "%s %d of %d entries (%s)\n", label, changed, len(entries), strings.Join(tags, ", "))
+	return nil
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tags in use with their entry counts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		historyDir, err := currentHistoryDir()
+		if err != nil {
+			return err
+		}
+		entries, err := history.ListEntries(historyDir)
+		if err != nil {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+
+		counts := map[string]int{}
+		for _, e := range entries {
+			for _, tag := range e.Tags {
+				counts[tag]++
+			}
+		}
+		w := cmd.OutOrStdout()
+		if len(counts) == 0 {
+			_, _ = fmt.Fprintln(w, "No tags")
+			return nil
+		}
+		tags := make([]string, 0, len(counts))
+		for tag := range counts {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			_, _ = fmt.Fprintf(w, "%s\t%d\n", tag, counts[tag])
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	tagCmd.AddCommand(tagListCmd)
+
+	tagAddSel.register(tagAddCmd)
+	tagRmSel.register(tagRmCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// templateFileSuffix is appended to the subproject name for the default template file name
+const templateFileSuffix = ".banago-template.yaml"
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Share subproject setups as portable templates",
+	Long: `Pack a subproject into a portable template file and unpack templates into new subprojects.
+
+A template contains the description, context, generation parameters,
+archive policy, names of the expected reference images and optional prompt
+templates. Reference images, the character file and history are never
+included, so templates can be shared without leaking private material.`,
+}
+
+var templatePackOpts struct {
+	out     string
+	prompts []string
+}
+
+var templatePackCmd = &cobra.Command{
+	Use:   "pack <subproject>",
+	Short: "Pack a subproject into a template file",
+	Long: `Pack a subproject into a template file.
+
+Examples:
+  banago template pack watercolor
+  banago template pack watercolor --prompts <uuid-a>,<uuid-b> --out watercolor.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		projectRoot, err := findProjectRoot()
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := project.PackSubproject(projectRoot, name, templatePackOpts.prompts)
+		if err != nil {
+			return fmt.Errorf("failed to pack subproject: %w", err)
+		}
+
+		out := cmp.Or(templatePackOpts.out, name+templateFileSuffix)
+		if err := tmpl.Save(out); err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Packed subproject '%s' into %s\n", name, out)
+		if len(tmpl.Prompts) > 0 {
+			_, _ = fmt.Fprintf(w, "  %d prompt templates\n", len(tmpl.Prompts))
+		}
+		return nil
+	},
+}
+
+var templateUnpackOpts struct {
+	name string
+}
+
+var templateUnpackCmd = &cobra.Command{
+	Use:   "unpack <file>",
+	Short: "Create a subproject from a template file",
+	Long: `Create a new subproject from a template file.
+
+The subproject is named after the template unless --name is given.
+Prompt templates are written to subprojects/<name>/prompts/.
+
+Examples:
+  banago template unpack watercolor.banago-template.yaml
+  banago template unpack watercolor.banago-template.yaml --name my-watercolor`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectRoot, err := findProjectRoot()
+		if err != nil {
+			return err
+		}
+
+		projectCfg, err := config.LoadProjectConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load project config: %w", err)
+		}
+		if err := requireWritable(projectCfg); err != nil {
+			return err
+		}
+
+		tmpl, err := project.LoadTemplate(args[0])
+		if err != nil {
+			return err
+		}
+
+		name := cmp.Or(templateUnpackOpts.name, tmpl.Name)
+		if name == "" {
+			return errors.New("template has no name. Pass --name")
+		}
+		if err := project.UnpackTemplate(projectRoot, name, tmpl); err != nil {
+			return fmt.Errorf("failed to unpack template: %w", err)
+		}
+
+		w := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(w, "Created subproject '%s' from %s\n", name, args[0])
+		if len(tmpl.Prompts) > 0 {
+			_, _ = fmt.Fprintf(w, "  %d prompt templates in subprojects/%s/%s/\n", len(tmpl.Prompts), name, project.TemplatePromptsDir)
+		}
+		if len(tmpl.InputImages) > 0 {
+			_, _ = fmt.Fprintln(w, "")
+			_, _ = fmt.Fprintf(w, "Place these reference images in subprojects/%s/inputs/:\n", name)
+			for _, img := range tmpl.InputImages {
+				_, _ = fmt.Fprintf(w, "  %s\n", img)
+			}
+		}
+		return nil
+	},
+}
+
+// findProjectRoot locates the project containing the current directory
+func findProjectRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	projectRoot, err := project.FindProjectRoot(cwd)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return "", errors.New("banago project not found. Run 'banago init' first")
+		}
+		return "", err
+	}
+	return projectRoot, nil
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templatePackCmd)
+	templateCmd.AddCommand(templateUnpackCmd)
+
+	templatePackCmd.Flags().StringVar(&templatePackOpts.out, "out", "", "Template file to write (default: <subproject>"+templateFileSuffix+")")
+	templatePackCmd.Flags().StringSliceVar(&templatePackOpts.prompts, "prompts", nil, "Comma-separated history entry IDs whose prompts are included as prompt templates")
+
+	templateUnpackCmd.Flags().StringVar(&templateUnpackOpts.name, "name", "", "Name of the new subproject (default: template name)")
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoder for GIF outputs
+	_ "image/jpeg" // register decoder for JPEG outputs
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+// Terminal image protocols for history --thumbs
+const (
+	thumbsNone  = ""
+	thumbsITerm = "iterm" // iTerm2 inline images (OSC 1337), also understood by WezTerm
+	thumbsKitty = "kitty" // kitty graphics protocol, also understood by Ghostty
+)
+
+const (
+	// thumbPixels is the longest side of a thumbnail before it is sent, which keeps the escape sequences small
+	thumbPixels = 160
+	// thumbRows and thumbCols are the terminal cells a thumbnail is drawn into
+	thumbRows = 4
+	thumbCols = 8
+	// kittyChunkSize is the largest base64 payload of one kitty graphics escape sequence
+	kittyChunkSize = 4096
+)
+
+// detectThumbsProtocol returns the image protocol of the terminal from the environment. BANAGO_THUMBS
+// (iterm, kitty or off) overrides the detection, e.g. inside tmux, which hides the terminal.
+func detectThumbsProtocol(getenv func(string) string) string {
+	switch strings.ToLower(getenv("BANAGO_THUMBS")) {
+	case thumbsITerm:
+		return thumbsITerm
+	case thumbsKitty:
+		return thumbsKitty
+	case "off":
+		return thumbsNone
+	}
+	if getenv("TMUX") != "" {
+		return thumbsNone
+	}
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "" || getenv("TERM") == "xterm-kitty" || getenv("TERM_PROGRAM") == "ghostty":
+		return thumbsKitty
+	case getenv("TERM_PROGRAM") == "iTerm.app" || getenv("TERM_PROGRAM") == "WezTerm" || getenv("LC_TERMINAL") == "iTerm2":
+		return thumbsITerm
+	}
+	return thumbsNone
+}
+
+// thumbsHint is printed instead of thumbnails when the terminal cannot show images
+const thumbsHint = "Thumbnails need a terminal that shows images (iTerm2, WezTerm, kitty, Ghostty); set BANAGO_THUMBS=iterm or kitty to force one"
+
+// isTerminal reports whether w is a terminal rather than a file or pipe, so escape sequences are only
+// written where they are rendered
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// writeThumbnail draws the image at path as a thumbnail of thumbRows lines with protocol, indented by
+// indent. It writes nothing and returns an error if the image cannot be read.
+func writeThumbnail(w io.Writer, protocol, path, indent string) error {
+	data, err := thumbnailPNG(path)
+	if err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(data)
+
+	var b strings.Builder
+	b.WriteString(indent)
+	switch protocol {
+	case thumbsITerm:
+		_, _ = fmt.Fprintf(&b, "\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a", len(data), thumbCols, thumbRows, payload)
+	case thumbsKitty:
+		for i := 0; i < len(payload); i += kittyChunkSize {
+			chunk := payload[i:min(i+kittyChunkSize, len(payload))]
+			more := 0
+			if i+kittyChunkSize < len(payload) {
+				more = 1
+			}
+			if i == 0 {
+				_, _ = fmt.Fprintf(&b, "\x1b_Ga=T,f=100,c=%d,r=%d,m=%d;%s\x1b\\", thumbCols, thumbRows, more, chunk)
+			} else {
+				_, _ = fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown thumbnail protocol %q", protocol)
+	}
+	// Both protocols leave the cursor after the image on its last row
+	b.WriteString("\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// thumbnailPNG decodes the image at path and returns it scaled down to thumbPixels as PNG
+func thumbnailPNG(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	bounds := src.Bounds()
+	scale := float64(thumbPixels) / float64(max(bounds.Dx(), bounds.Dy()))
+	if scale > 1 {
+		scale = 1
+	}
+	width, height := max(int(float64(bounds.Dx())*scale), 1), max(int(float64(bounds.Dy())*scale), 1)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			// Nearest neighbor is enough to recognize an image at this size
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
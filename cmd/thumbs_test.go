@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectThumbsProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"plain terminal", map[string]string{"TERM": "xterm-256color"}, thumbsNone},
+		{"iTerm2", map[string]string{"TERM_PROGRAM": "iTerm.app"}, thumbsITerm},
+		{"WezTerm", map[string]string{"TERM_PROGRAM": "WezTerm"}, thumbsITerm},
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, thumbsKitty},
+		{"tmux hides the terminal", map[string]string{"TERM_PROGRAM": "iTerm.app", "TMUX": "/tmp/tmux"}, thumbsNone},
+		{"forced in tmux", map[string]string{"TMUX": "/tmp/tmux", "BANAGO_THUMBS": "kitty"}, thumbsKitty},
+		{"turned off", map[string]string{"TERM_PROGRAM": "iTerm.app", "BANAGO_THUMBS": "off"}, thumbsNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, detectThumbsProtocol(func(key string) string { return tt.env[key] }))
+		})
+	}
+}
+
+func TestWriteThumbnail(t *testing.T) {
+	t.Parallel()
+
+	// A noisy image large enough that its thumbnail needs several kitty chunks
+	rng := rand.New(rand.NewPCG(1, 2))
+	src := image.NewNRGBA(image.Rect(0, 0, 640, 320))
+	for y := range 320 {
+		for x := range 640 {
+			src.Set(x, y, color.NRGBA{R: uint8(rng.IntN(256)), G: uint8(rng.IntN(256)), B: uint8(rng.IntN(256)), A: 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "output.png")
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, src))
+	require.NoError(t, os.WriteFile(path, encoded.Bytes(), 0o644))
+
+	data, err := thumbnailPNG(path)
+	require.NoError(t, err)
+	thumb, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(thumbPixels, thumbPixels/2), thumb.Bounds().Size())
+
+	var iterm bytes.Buffer
+	require.NoError(t, writeThumbnail(&iterm, thumbsITerm, path, "  "))
+	assert.True(t, strings.HasPrefix(iterm.String(), "  \x1b]1337;File=inline=1;"))
+	assert.True(t, strings.HasSuffix(iterm.String(), "\a\n"))
+
+	var kitty bytes.Buffer
+	require.NoError(t, writeThumbnail(&kitty, thumbsKitty, path, ""))
+	chunks := strings.Split(strings.TrimSuffix(kitty.String(), "\n"), "\x1b\\")
+	require.Greater(t, len(chunks), 2)
+	assert.True(t, strings.HasPrefix(chunks[0], "\x1b_Ga=T,f=100,"))
+	assert.Contains(t, chunks[len(chunks)-2], "m=0;")
+
+	err = writeThumbnail(&kitty, thumbsKitty, filepath.Join(t.TempDir(), "missing.png"), "")
+	require.Error(t, err)
+}
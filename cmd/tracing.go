@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/tracing"
+	"github.com/spf13/cobra"
+)
+
+// traceExportTimeout bounds how long a finished command waits for the collector
+const traceExportTimeout = 5 * time.Second
+
+// finishTrace ends the span of the command and exports the remaining spans (no-op without tracing)
+var finishTrace = func(error) {}
+
+// setupTracing starts tracing when the OTEL_* environment selects a collector, and gives the
+// command a root span named after it
+func setupTracing(cmd *cobra.Command) error {
+	shutdown, err := tracing.Setup()
+	if err != nil {
+		return err
+	}
+	ctx, span := tracing.Start(cmd.Context(), cmd.CommandPath())
+	cmd.SetContext(ctx)
+	finishTrace = func(err error) {
+		span.End(err)
+		ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Warn("trace export failed", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/spf13/cobra"
+)
+
+// upscalePrompt asks the model to re-render the source image unchanged at the requested size
+const upscalePrompt = "Upscale this image to a higher resolution. Keep the composition, subjects, colors and style exactly the same; only add fine detail and sharpen edges. Do not add, remove or change anything."
+
+// upscaleDefaultSize is the image size an upscale is rendered at without --size
+const upscaleDefaultSize = "4K"
+
+var upscaleOpts editOptions
+
+var upscaleCmd = &cobra.Command{
+	Use:   "upscale",
+	Short: "Re-generate an output at a higher image size",
+	Long: `Re-generate an output image at a higher image size (4K by default) with a
+prompt that keeps it unchanged.
+
+The upscale is saved as an edit of the history entry (edits/), marked as an
+upscale, so it is listed with the entry's edits and can be edited further.
+The aspect ratio of the source is kept. --prompt adds instructions to the
+upscale prompt.
+
+Examples:
+  banago upscale --latest
+  banago upscale --id <uuid> --index 2
+  banago upscale --id <uuid> --edit-latest --size 2K
+  banago upscale --latest -p "Keep the film grain"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+
+		handler := &editHandler{generator: client, stdin: cmd.InOrStdin()}
+		opts := upscaleOpts
+		opts.prompt = upscaleEditPrompt(opts.prompt)
+		opts.upscale = true
+		opts.json = jsonOutput()
+		opts.events = eventsOutput()
+		return handler.run(cmd.Context(), opts, cwd, cmd.OutOrStdout())
+	},
+}
+
+// upscaleEditPrompt returns the upscale prompt followed by the user's extra instructions
+func upscaleEditPrompt(extra string) string {
+	extra = strings.TrimSpace(extra)
+	if extra == "" {
+		return upscalePrompt
+	}
+	return upscalePrompt + "\n\n" + extra
+}
+
+func init() {
+	rootCmd.AddCommand(upscaleCmd)
+
+	upscaleCmd.Flags().StringVar(&upscaleOpts.id, "id", "", "History entry ID to upscale")
+	upscaleCmd.Flags().BoolVar(&upscaleOpts.latest, "latest", false, "Use the latest history entry")
+	upscaleCmd.Flags().StringVar(&upscaleOpts.editID, "edit-id", "", "Edit entry ID to upscale instead of the generation")
+	upscaleCmd.Flags().BoolVar(&upscaleOpts.editLatest, "edit-latest", false, "Upscale the latest edit entry")
+	upscaleCmd.Flags().IntVar(&upscaleOpts.index, "index", 1, "Output to upscale (1-based, for entries with several outputs)")
+	upscaleCmd.Flags().StringVar(&upscaleOpts.size, "size", upscaleDefaultSize, "Image size to upscale to")
+	upscaleCmd.Flags().StringVar(&upscaleOpts.format, "format", "", "Convert outputs to png, jpeg or webp when saving (overrides output_format)")
+	upscaleCmd.Flags().StringVarP(&upscaleOpts.prompt, "prompt", "p", "", "Extra instructions appended to the upscale prompt")
+	upscaleCmd.Flags().StringVar(&upscaleOpts.model, "model", "", "Model for this upscale instead of banago.yaml's (recorded in history)")
+	upscaleCmd.Flags().BoolVarP(&upscaleOpts.yes, "yes", "y", false, "Skip confirmation for --latest and confirm_before_generate")
+	upscaleCmd.Flags().BoolVarP(&upscaleOpts.quiet, "quiet", "q", false, "Print only the new edit ID")
+	upscaleCmd.Flags().BoolVar(&upscaleOpts.keepFailures, "keep-failures", false, "Keep the edit with the error when the API call fails")
+	upscaleCmd.Flags().BoolVar(&upscaleOpts.wait, "wait", false, "Wait for other banago processes writing to the subproject instead of failing")
+
+	upscaleCmd.MarkFlagsOneRequired("id", "latest")
+	upscaleCmd.MarkFlagsMutuallyExclusive("id", "latest")
+	upscaleCmd.MarkFlagsMutuallyExclusive("edit-id", "edit-latest")
+}
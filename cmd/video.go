@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/generation"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// videoGenerateOptions holds options for the video generate command.
+type videoGenerateOptions struct {
+	prompt       string
+	promptFile   string
+	image        string
+	aspect       string
+	resolution   string
+	duration     int
+	keepFailures bool           // keep entries of failed API calls (also keep_failures in banago.yaml)
+	wait         bool           // queue behind other processes writing to the subproject instead of failing
+	timeout      *time.Duration // --timeout; nil uses banago.yaml's timeout
+	json         bool
+}
+
+// videoHandler handles the video generate command with injectable dependencies.
+type videoHandler struct {
+	generator generation.VideoGenerator
+}
+
+var _ generation.VideoGenerator = (*gemini.Client)(nil)
+
+var (
+	videoOpts    videoGenerateOptions
+	videoTimeout time.Duration
+)
+
+var videoCmd = &cobra.Command{
+	Use:   "video",
+	Short: "Generate videos",
+	Long:  "Generate videos with Veo models. Results are saved to history like images.",
+}
+
+var videoGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a video",
+	Long: `Generate a video using a Veo model (video_model in banago.yaml).
+
+Must be run inside a subproject directory. The video (mp4) is saved to a
+new history entry marked with media: video. Pass --image to animate a
+first frame; input_images from config.yaml are not used.
+
+Examples:
+  banago video generate --prompt "a slow dolly shot of the castle"
+  banago video generate -F shot.txt --image inputs/castle.png --duration 8`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		client, err := gemini.NewClient(cmd.Context(), cfg.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+
+		if cmd.Flags().Changed("timeout") {
+			videoOpts.timeout = &videoTimeout
+		}
+
+		handler := &videoHandler{generator: client}
+		videoOpts.json = jsonOutput()
+		return handler.run(cmd.Context(), videoOpts, cwd, cmd.OutOrStdout())
+	},
+}
+
+// run executes the video generate command logic.
+// This method is independent of cobra.Command for testability.
+func (h *videoHandler) run(ctx context.Context, opts videoGenerateOptions, workDir string, w io.Writer) error {
+	jsonW := w
+	if opts.json {
+		w = io.Discard
+	}
+
+	promptText, err := resolvePrompt(opts.prompt, opts.promptFile)
+	if err != nil {
+		return err
+	}
+
+	projectRoot, err := project.FindProjectRoot(workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			return errors.New("banago project not found. Run 'banago init' first")
+		}
+		return err
+	}
+	projectCfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+	if err := requireWritable(projectCfg); err != nil {
+		return err
+	}
+
+	subprojectName, err := project.FindCurrentSubproject(projectRoot, workDir)
+	if err != nil {
+		if errors.Is(err, project.ErrNotInSubproject) {
+			return errors.New("not in a subproject. Navigate to a subproject directory")
+		}
+		return err
+	}
+	subprojectDir := project.GetSubprojectDir(projectRoot, subprojectName)
+	subprojectCfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return fmt.Errorf("failed to load subproject config: %w", err)
+	}
+	if err := project.CheckWorkDir(subprojectDir, subprojectCfg, workDir); err != nil {
+		return err
+	}
+
+	imagePath := opts.image
+	if imagePath != "" && !filepath.IsAbs(imagePath) {
+		imagePath = filepath.Join(workDir, imagePath)
+	}
+
+	ctx, cancel := runContext(ctx, opts.timeout, projectCfg)
+	defer cancel()
+	unlock, err := lockSubproject(ctx, subprojectDir, opts.wait, w)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectCfg)
+	result, err := generation.NewVideoService(h.generator).Run(ctx, generation.VideoSpec{
+		Model:           cmp.Or(projectCfg.VideoModel, gemini.DefaultVideoModel),
+		Prompt:          promptText,
+		ImagePath:       imagePath,
+		AspectRatio:     cmp.Or(opts.aspect, subprojectCfg.AspectRatio),
+		Resolution:      opts.resolution,
+		DurationSeconds: opts.duration,
+		Layout:          projectCfg.HistoryLayout,
+		KeepFailures:    opts.keepFailures || projectCfg.KeepFailures,
+	}, historyDir, w)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		return writeJSON(jsonW, newGenerationJSON(historyDir, "", []*generation.Result{result}))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(videoCmd)
+	videoCmd.AddCommand(videoGenerateCmd)
+
+	videoGenerateCmd.Flags().StringVarP(&videoOpts.prompt, "prompt", "p", "", "Prompt for generation")
+	videoGenerateCmd.Flags().StringVarP(&videoOpts.promptFile, "prompt-file", "F", "", "Path to text file containing prompt")
+	videoGenerateCmd.Flags().StringVar(&videoOpts.image, "image", "", "First frame image to animate")
+	videoGenerateCmd.Flags().StringVar(&videoOpts.aspect, "aspect", "", "Video aspect ratio (e.g., 16:9, 9:16)")
+	videoGenerateCmd.Flags().StringVar(&videoOpts.resolution, "resolution", "", "Video resolution (e.g., 720p, 1080p)")
+	videoGenerateCmd.Flags().IntVar(&videoOpts.duration, "duration", 0, "Video length in seconds (model default when 0)")
+	videoGenerateCmd.Flags().BoolVar(&videoOpts.keepFailures, "keep-failures", false, "Keep the history entry with the error when the API call fails")
+	videoGenerateCmd.Flags().BoolVar(&videoOpts.wait, "wait", false, "Wait for other banago processes writing to the subproject instead of failing")
+	videoGenerateCmd.Flags().DurationVar(&videoTimeout, "timeout", 0, "Stop the run after this long, e.g. 5m (overrides timeout in banago.yaml; 0 = no limit)")
+
+	videoGenerateCmd.MarkFlagsOneRequired("prompt", "prompt-file")
+	videoGenerateCmd.MarkFlagsMutuallyExclusive("prompt", "prompt-file")
+}
@@ -6,6 +6,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/spf13/cobra v1.10.2
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/image v0.34.0
 	google.golang.org/genai v1.62.0
 	gopkg.in/yaml.v3 v3.0.1
 )
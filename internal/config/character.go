@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// characterPresetExt replaces the extension of a character file to name its preset file
+const characterPresetExt = ".preset.yaml"
+
+// CharacterPreset holds the generation defaults a character declares (characters/<name>.preset.yaml),
+// so every subproject referencing the character gets the same look without repeating them
+type CharacterPreset struct {
+	AspectRatio string `yaml:"aspect_ratio,omitempty"` // used when config.yaml sets none
+	ImageSize   string `yaml:"image_size,omitempty"`   // used when config.yaml sets none
+	PromptNotes string `yaml:"prompt_notes,omitempty"` // style notes appended to every prompt
+}
+
+// Validate checks the values of a character preset
+func (p *CharacterPreset) Validate() error {
+	var errs []error
+	if err := ValidateAspectRatio(p.AspectRatio); err != nil {
+		errs = append(errs, fmt.Errorf("aspect_ratio: %w", err))
+	}
+	if err := ValidateImageSize(p.ImageSize); err != nil {
+		errs = append(errs, fmt.Errorf("image_size: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// CharacterPresetPath returns the preset file of the character file at characterPath:
+// characters/hero.md has characters/hero.preset.yaml
+func CharacterPresetPath(characterPath string) string {
+	return strings.TrimSuffix(characterPath, filepath.Ext(characterPath)) + characterPresetExt
+}
+
+// LoadCharacterPreset loads the preset of the character file at characterPath.
+// Characters without a preset file have none: it returns nil and no error.
+func LoadCharacterPreset(characterPath string) (*CharacterPreset, error) {
+	path := CharacterPresetPath(characterPath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read character preset: %w", err)
+	}
+
+	var preset CharacterPreset
+	if err := decodeStrict(data, &preset); err != nil {
+		return nil, fmt.Errorf("failed to parse character preset %s: %w", filepath.Base(path), err)
+	}
+	return &preset, nil
+}
+
+// ApplyTo fills the aspect ratio and image size that cfg leaves empty with the preset's
+func (p *CharacterPreset) ApplyTo(cfg *SubprojectConfig) {
+	if cfg.AspectRatio == "" {
+		cfg.AspectRatio = p.AspectRatio
+	}
+	if cfg.ImageSize == "" {
+		cfg.ImageSize = p.ImageSize
+	}
+}
+
+// WithNotes returns prompt followed by the preset's prompt notes, separated by a blank line.
+// A prompt that already ends with the notes (e.g. restored from an entry) is returned as is.
+func (p *CharacterPreset) WithNotes(prompt string) string {
+	notes := strings.TrimSpace(p.PromptNotes)
+	if notes == "" || strings.HasSuffix(prompt, notes) {
+		return prompt
+	}
+	return prompt + "\n\n" + notes
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewProjectConfig(t *testing.T) {
@@ -17,8 +21,8 @@ func TestNewProjectConfig(t *testing.T) {
 	if cfg.Version != configVersion {
 		t.Errorf("Version = %q, want %q", cfg.Version, configVersion)
 	}
-	if cfg.Model != defaultModel {
-		t.Errorf("Model = %q, want %q", cfg.Model, defaultModel)
+	if cfg.Model != DefaultModel {
+		t.Errorf("Model = %q, want %q", cfg.Model, DefaultModel)
 	}
 	if cfg.CreatedAt == "" {
 		t.Error("CreatedAt should not be empty")
@@ -58,8 +62,8 @@ func TestLoadProjectConfig_NotFound(t *testing.T) {
 
 	tmpDir := t.TempDir()
 	_, err := LoadProjectConfig(tmpDir)
-	if err == nil {
-		t.Error("LoadProjectConfig() expected error for missing file")
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("LoadProjectConfig() error = %v, want ErrConfigNotFound", err)
 	}
 }
 
@@ -173,8 +177,52 @@ func TestLoadSubprojectConfig_NotFound(t *testing.T) {
 
 	tmpDir := t.TempDir()
 	_, err := LoadSubprojectConfig(tmpDir)
-	if err == nil {
-		t.Error("LoadSubprojectConfig() expected error for missing file")
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("LoadSubprojectConfig() error = %v, want ErrConfigNotFound", err)
+	}
+}
+
+func TestLoadCharacterPreset(t *testing.T) {
+	t.Parallel()
+
+	characterPath := filepath.Join(t.TempDir(), "fox.md")
+	if got := CharacterPresetPath(characterPath); filepath.Base(got) != "fox.preset.yaml" {
+		t.Errorf("CharacterPresetPath() = %s, want fox.preset.yaml", got)
+	}
+
+	preset, err := LoadCharacterPreset(characterPath)
+	if err != nil || preset != nil {
+		t.Errorf("LoadCharacterPreset() without a preset = %v, %v, want nil, nil", preset, err)
+	}
+
+	data := "aspect_ratio: \"3:4\"\nimage_size: 2K\nprompt_notes: |\n  Blue scarf, soft watercolor.\n"
+	if err := os.WriteFile(CharacterPresetPath(characterPath), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	preset, err = LoadCharacterPreset(characterPath)
+	if err != nil {
+		t.Fatalf("LoadCharacterPreset() error = %v", err)
+	}
+
+	cfg := &SubprojectConfig{ImageSize: "4K"}
+	preset.ApplyTo(cfg)
+	if cfg.AspectRatio != "3:4" || cfg.ImageSize != "4K" {
+		t.Errorf("ApplyTo() = %s, %s, want 3:4 from the preset and 4K from config.yaml", cfg.AspectRatio, cfg.ImageSize)
+	}
+	want := "A fox\n\nBlue scarf, soft watercolor."
+	if got := preset.WithNotes("A fox"); got != want {
+		t.Errorf("WithNotes() = %q, want %q", got, want)
+	}
+	if got := preset.WithNotes(want); got != want {
+		t.Errorf("WithNotes() of a prompt with the notes = %q, want it unchanged", got)
+	}
+
+	if err := os.WriteFile(CharacterPresetPath(characterPath), []byte("aspect: 3:4\nimage_size: 3K\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadCharacterPreset(characterPath)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("LoadCharacterPreset() error = %v, want ErrInvalidConfig", err)
 	}
 }
 
@@ -188,7 +236,257 @@ func TestLoadProjectConfig_InvalidYAML(t *testing.T) {
 	}
 
 	_, err := LoadProjectConfig(tmpDir)
-	if err == nil {
-		t.Error("LoadProjectConfig() expected error for invalid YAML")
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("LoadProjectConfig() error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestLoadSubprojectConfig_Strict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr []string // substrings of the error; none means the config is valid
+	}{
+		{
+			name: "valid",
+			yaml: "version: \"2\"\nname: fox\naspect_ratio: \"16:9\"\nimage_size: 2K\noutput_format: webp\narchive:\n  raw_response: true\n",
+		},
+		{
+			name: "missing version is accepted",
+			yaml: "name: fox\n",
+		},
+		{
+			name:    "unknown keys with suggestions",
+			yaml:    "name: fox\napect_ratio: \"16:9\"\narchive:\n  raw_respons: true\nfavorite_color: blue\n",
+			wantErr: []string{`line 2: unknown key "apect_ratio" (did you mean "aspect_ratio"?)`, `"archive.raw_respons" (did you mean "archive.raw_response"?)`, `line 5: unknown key "favorite_color"`},
+		},
+		{
+			name:    "invalid values",
+			yaml:    "name: fox\naspect_ratio: wide\nimage_size: 8K\noutput_format: tiff\ntoken_budget: -1\nmirror:\n  mode: hardlink\nevaluation:\n  on_fail: delete\n",
+			wantErr: []string{"aspect_ratio", "image_size", "output_format", "token_budget", "mirror.mode", "evaluation.on_fail"},
+		},
+		{
+			name:    "malformed version",
+			yaml:    "version: two\nname: fox\n",
+			wantErr: []string{`invalid version "two"`},
+		},
+		{
+			name:    "newer version",
+			yaml:    "version: \"9\"\nname: fox\n",
+			wantErr: []string{"newer than this banago supports"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadSubprojectConfig(dir)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("LoadSubprojectConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("LoadSubprojectConfig() expected error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadProjectConfig_UnknownKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	data := "version: \"2\"\nname: test\nmodel: m\nconfirm_before_generat: true\n"
+	if err := os.WriteFile(filepath.Join(dir, "banago.yaml"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadProjectConfig(dir)
+	if err == nil || !strings.Contains(err.Error(), `did you mean "confirm_before_generate"?`) {
+		t.Errorf("LoadProjectConfig() error = %v, want unknown key with suggestion", err)
+	}
+}
+
+func TestArchiveConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		inputs  string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", ArchiveInputsCopy, false},
+		{"copy", "copy", ArchiveInputsCopy, false},
+		{"hash", "hash", ArchiveInputsHash, false},
+		{"invalid", "link", "link", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := ArchiveConfig{Inputs: tt.inputs}
+			if got := a.InputsMode(); got != tt.want {
+				t.Errorf("InputsMode() = %q, want %q", got, tt.want)
+			}
+			if err := a.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMirrorConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		mode        string
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{"disabled", "", false, false},
+		{"copy", MirrorCopy, true, false},
+		{"symlink", MirrorSymlink, true, false},
+		{"invalid", "hardlink", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := MirrorConfig{Mode: tt.mode}
+			if got := m.Enabled(); got != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.wantEnabled)
+			}
+			if err := m.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProjectConfig_MinFreeDisk(t *testing.T) {
+	t.Parallel()
+
+	zero, custom := 0, 50
+	tests := []struct {
+		name string
+		mb   *int
+		want int64
+	}{
+		{"default", nil, DefaultMinFreeDiskMB << 20},
+		{"disabled", &zero, 0},
+		{"custom", &custom, 50 << 20},
+	}
+	for _, tt := range tests {
+		cfg := &ProjectConfig{MinFreeDiskMB: tt.mb}
+		if got := cfg.MinFreeDisk(); got != tt.want {
+			t.Errorf("%s: MinFreeDisk() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProjectConfig_RunTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		timeout string
+		want    time.Duration
+		valid   bool
+	}{
+		{"", 0, true},
+		{"90s", 90 * time.Second, true},
+		{"10m", 10 * time.Minute, true},
+		{"0", 0, true},
+		{"10", 0, false},
+		{"-1m", 0, false},
+	}
+	for _, tt := range tests {
+		cfg := &ProjectConfig{Timeout: tt.timeout}
+		if got := cfg.RunTimeout(); got != tt.want {
+			t.Errorf("%q: RunTimeout() = %v, want %v", tt.timeout, got, tt.want)
+		}
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%q: Validate() error = %v, want valid %v", tt.timeout, err, tt.valid)
+		}
+	}
+}
+
+func TestSetSubprojectField(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	data := "version: \"2\"\nname: test\ncontext_file: context.md # shared notes\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetSubprojectField(dir, "mirror.mode", []string{"copy"}); err != nil {
+		t.Fatalf("SetSubprojectField() error = %v", err)
+	}
+	if err := SetSubprojectField(dir, "input_images", []string{"a.png", "yes"}); err != nil {
+		t.Fatalf("SetSubprojectField() error = %v", err)
+	}
+	if err := SetSubprojectField(dir, "token_budget", []string{"1000"}); err != nil {
+		t.Fatalf("SetSubprojectField() error = %v", err)
+	}
+	cfg, err := LoadSubprojectConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadSubprojectConfig() error = %v", err)
+	}
+	if cfg.Mirror.Mode != MirrorCopy || cfg.TokenBudget != 1000 || !slices.Equal(cfg.InputImages, []string{"a.png", "yes"}) {
+		t.Errorf("config = %+v, want mirror copy, token budget 1000, inputs [a.png yes]", cfg)
+	}
+	written, _ := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if !strings.Contains(string(written), "# shared notes") {
+		t.Errorf("comment was lost:\n%s", written)
+	}
+
+	got, err := GetSubprojectField(dir, "input_images")
+	if err != nil || !slices.Equal(got, []string{"a.png", "yes"}) {
+		t.Errorf("GetSubprojectField() = %v, %v", got, err)
+	}
+
+	// Clearing a list removes the key
+	if err := SetSubprojectField(dir, "input_images", nil); err != nil {
+		t.Fatalf("SetSubprojectField() error = %v", err)
+	}
+	written, _ = os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if strings.Contains(string(written), "input_images") {
+		t.Errorf("input_images should be removed:\n%s", written)
+	}
+
+	failures := []struct {
+		key    string
+		values []string
+		want   string
+	}{
+		{"image_size", []string{"8K"}, `invalid image size "8K"`},
+		{"token_budget", []string{"many"}, "must be a number"},
+		{"aspect_ratio", []string{"1:1", "2:3"}, "exactly one value"},
+		{"apect_ratio", []string{"1:1"}, `did you mean "aspect_ratio"?`},
+		{"archive", []string{"hash"}, "archive.inputs"},
+		{"version", []string{"9"}, "managed by banago"},
+	}
+	for _, tt := range failures {
+		err := SetSubprojectField(dir, tt.key, tt.values)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetSubprojectField(%q, %v) error = %v, want %q", tt.key, tt.values, err, tt.want)
+		}
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, "config.yaml")); string(after) != string(written) {
+		t.Errorf("rejected values must not change the file:\n%s", after)
 	}
 }
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrManagedField is returned when setting a field that banago maintains itself
+var ErrManagedField = errors.New("field is managed by banago")
+
+// managedFields cannot be set from the CLI: version changes only through 'banago migrate'
+var managedFields = map[string]bool{"version": true, "created_at": true}
+
+// HasProjectField reports whether key (dotted for nested keys) is a banago.yaml field
+func HasProjectField(key string) bool {
+	_, err := lookupField(reflect.TypeFor[ProjectConfig](), key)
+	return err == nil
+}
+
+// HasSubprojectField reports whether key (dotted for nested keys, e.g. mirror.mode) is a config.yaml field
+func HasSubprojectField(key string) bool {
+	_, err := lookupField(reflect.TypeFor[SubprojectConfig](), key)
+	return err == nil
+}
+
+// GetProjectField returns the value of key in the banago.yaml of dir: one element per list item,
+// one element for other values, none when an optional value is unset
+func GetProjectField(dir, key string) ([]string, error) {
+	cfg, err := LoadProjectConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	return getField(reflect.ValueOf(cfg).Elem(), key)
+}
+
+// GetSubprojectField returns the value of key in the config.yaml of dir, like GetProjectField
+func GetSubprojectField(dir, key string) ([]string, error) {
+	cfg, err := LoadSubprojectConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	return getField(reflect.ValueOf(cfg).Elem(), key)
+}
+
+// SetProjectField sets key in the banago.yaml of dir. Lists take any number of values (none clears them),
+// other fields exactly one. The rest of the file, comments included, is kept, and nothing is written
+// if the result does not validate.
+func SetProjectField(dir, key string, values []string) error {
+	return setField(filepath.Join(dir, projectConfigFile), &ProjectConfig{}, key, values)
+}
+
+// SetSubprojectField sets key in the config.yaml of dir, like SetProjectField
+func SetSubprojectField(dir, key string, values []string) error {
+	return setField(filepath.Join(dir, subprojectConfigFile), &SubprojectConfig{}, key, values)
+}
+
+// lookupField returns the struct field that the dotted yaml key names in t
+func lookupField(t reflect.Type, key string) (reflect.StructField, error) {
+	var field reflect.StructField
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if t.Kind() != reflect.Struct {
+			return reflect.StructField{}, fmt.Errorf("unknown key %q: %s has no nested keys", key, strings.Join(parts[:i], "."))
+		}
+		var names []string
+		found := false
+		for j := range t.NumField() {
+			f := t.Field(j)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if name == part {
+				field, found = f, true
+				break
+			}
+			names = append(names, name)
+		}
+		if !found {
+			msg := fmt.Sprintf("unknown key %q", key)
+			if s := closestKey(part, names); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", strings.Join(append(parts[:i:i], s), "."))
+			}
+			return reflect.StructField{}, errors.New(msg)
+		}
+		t = field.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() == reflect.Struct {
+		return reflect.StructField{}, fmt.Errorf("%s has nested keys; use one of: %s", key, strings.Join(nestedKeys(t, key), ", "))
+	}
+	return field, nil
+}
+
+// nestedKeys lists the dotted keys of struct type t below prefix
+func nestedKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, prefix+"."+name)
+		}
+	}
+	return keys
+}
+
+// getField formats the value of the dotted key of the config struct v
+func getField(v reflect.Value, key string) ([]string, error) {
+	if _, err := lookupField(v.Type(), key); err != nil {
+		return nil, err
+	}
+	for part := range strings.SplitSeq(key, ".") {
+		for i := range v.NumField() {
+			if name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ","); name == part {
+				v = v.Field(i)
+				break
+			}
+		}
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		values := make([]string, v.Len())
+		for i := range v.Len() {
+			values[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return values, nil
+	}
+	return []string{fmt.Sprint(v.Interface())}, nil
+}
+
+// setField rewrites key in the YAML file at path and checks the result by decoding it into cfg
+func setField(path string, cfg interface{ Validate() error }, key string, values []string) error {
+	if managedFields[key] {
+		return fmt.Errorf("%w: %s", ErrManagedField, key)
+	}
+	field, err := lookupField(reflect.TypeOf(cfg).Elem(), key)
+	if err != nil {
+		return err
+	}
+	value, err := valueNode(key, field.Type, values)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	setNode(doc.Content[0], strings.Split(key, "."), value)
+
+	data, err = yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	if err := decodeStrict(data, cfg); err != nil {
+		return fmt.Errorf("refusing to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// valueNode converts CLI values into the YAML node for a field of type t.
+// It returns nil for an empty list, which removes the key.
+func valueNode(key string, t reflect.Type, values []string) (*yaml.Node, error) {
+	if t.Kind() == reflect.Slice {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, v := range values {
+			seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+		}
+		return seq, nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%s takes exactly one value, got %d", key, len(values))
+	}
+	v := values[0]
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be true or false", key, v)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}, nil
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be a number", key, v)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(n)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	}
+}
+
+// setNode sets the value of the dotted key path in mapping, creating nested mappings as needed.
+// A nil value removes the key.
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		switch {
+		case len(path) > 1:
+			if mapping.Content[i+1].Kind != yaml.MappingNode {
+				mapping.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+			}
+			setNode(mapping.Content[i+1], path[1:], value)
+		case value == nil:
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		default:
+			// Keep a comment written after the old value
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+		}
+		return
+	}
+	if value == nil {
+		return
+	}
+	if len(path) > 1 {
+		nested := &yaml.Node{Kind: yaml.MappingNode}
+		setNode(nested, path[1:], value)
+		value = nested
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}, value)
+}
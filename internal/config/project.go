@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,11 +17,75 @@ type ProjectConfig struct {
 	Name      string `yaml:"name"`
 	Model     string `yaml:"model"`
 	CreatedAt string `yaml:"created_at"`
+	ReadOnly  bool   `yaml:"readonly,omitempty"` // refuse all mutating commands
+
+	ConfirmBeforeGenerate bool `yaml:"confirm_before_generate,omitempty"` // preview and confirm before each API call
+
+	KeepFailures bool `yaml:"keep_failures,omitempty"` // keep entries of failed API calls with the error recorded
+
+	VideoModel string `yaml:"video_model,omitempty"` // model for banago video generate (default: gemini.DefaultVideoModel)
+	TextModel  string `yaml:"text_model,omitempty"`  // model drafting subprojects from a brief (default: gemini.DefaultTextModel)
+
+	Hints *bool `yaml:"hints,omitempty"` // print "Next steps" hints (default: true)
+
+	MinFreeDiskMB *int `yaml:"min_free_disk_mb,omitempty"` // warn when less would remain free after a run (default: DefaultMinFreeDiskMB, 0 = off)
+
+	TmpDir   string `yaml:"tmp_dir,omitempty"`    // scratch space of runs, absolute or relative to the project root (default: DefaultTmpDir)
+	TmpMaxMB *int   `yaml:"tmp_max_mb,omitempty"` // size cap of the scratch space (default: DefaultTmpMaxMB, 0 = unlimited)
+
+	HistoryLayout string `yaml:"history_layout,omitempty"` // layout of new history entries: flat (default) or nested
+
+	Timeout string `yaml:"timeout,omitempty"` // how long generate, edit and regenerate may run, as a Go duration such as 5m (default: no limit)
+}
+
+// HintsEnabled reports whether commands should print next-step hints
+func (c *ProjectConfig) HintsEnabled() bool {
+	return c.Hints == nil || *c.Hints
 }
 
+// DefaultMinFreeDiskMB is the free disk space, in MB, below which generation warns by default
+const DefaultMinFreeDiskMB = 500
+
+// MinFreeDisk returns the low disk space threshold in bytes (0 = no warning)
+func (c *ProjectConfig) MinFreeDisk() int64 {
+	mb := DefaultMinFreeDiskMB
+	if c.MinFreeDiskMB != nil {
+		mb = max(*c.MinFreeDiskMB, 0)
+	}
+	return int64(mb) << 20
+}
+
+const (
+	// DefaultTmpDir is the scratch space of runs, relative to the project root
+	DefaultTmpDir = ".banago/tmp"
+	// DefaultTmpMaxMB is the size, in MB, the scratch space may grow to by default
+	DefaultTmpMaxMB = 2048
+)
+
+// TmpMaxBytes returns the size cap of the scratch space in bytes (0 = unlimited)
+func (c *ProjectConfig) TmpMaxBytes() int64 {
+	mb := DefaultTmpMaxMB
+	if c.TmpMaxMB != nil {
+		mb = max(*c.TmpMaxMB, 0)
+	}
+	return int64(mb) << 20
+}
+
+// RunTimeout returns the time limit of generate, edit and regenerate (0 = none).
+// Invalid values are rejected by Validate and count as no limit here.
+func (c *ProjectConfig) RunTimeout() time.Duration {
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// DefaultModel is the image model of new projects
+const DefaultModel = "gemini-3-pro-image-preview"
+
 const (
 	projectConfigFile = "banago.yaml"
-	defaultModel      = "gemini-3-pro-image-preview"
 	configVersion     = "2"
 )
 
@@ -28,7 +94,7 @@ func NewProjectConfig(name string) *ProjectConfig {
 	return &ProjectConfig{
 		Version:   configVersion,
 		Name:      name,
-		Model:     defaultModel,
+		Model:     DefaultModel,
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 }
@@ -52,12 +118,15 @@ func (c *ProjectConfig) Save(dir string) error {
 func LoadProjectConfig(dir string) (*ProjectConfig, error) {
 	path := filepath.Join(dir, projectConfigFile)
 	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read project config: %w", err)
 	}
 
 	var config ProjectConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := decodeStrict(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse project config: %w", err)
 	}
 
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,15 +14,128 @@ import (
 
 // SubprojectConfig represents a subproject configuration (config.yaml)
 type SubprojectConfig struct {
-	Version       string   `yaml:"version"`
-	Name          string   `yaml:"name"`
-	Description   string   `yaml:"description,omitempty"`
-	CreatedAt     string   `yaml:"created_at"`
-	CharacterFile string   `yaml:"character_file,omitempty"`
-	ContextFile   string   `yaml:"context_file"`
-	AspectRatio   string   `yaml:"aspect_ratio,omitempty"`
-	ImageSize     string   `yaml:"image_size,omitempty"`
-	InputImages   []string `yaml:"input_images,omitempty"`
+	Version        string        `yaml:"version"`
+	Name           string        `yaml:"name"`
+	Description    string        `yaml:"description,omitempty"`
+	CreatedAt      string        `yaml:"created_at"`
+	CharacterFile  string        `yaml:"character_file,omitempty"`
+	ContextFile    string        `yaml:"context_file"`
+	AspectRatio    string        `yaml:"aspect_ratio,omitempty"`
+	ImageSize      string        `yaml:"image_size,omitempty"`
+	OutputFormat   string        `yaml:"output_format,omitempty"` // convert outputs to png, jpeg or webp when saving (default: as returned)
+	InputImages    []string      `yaml:"input_images,omitempty"`
+	HistoryDir     string        `yaml:"history_dir,omitempty"` // absolute, or relative to the subproject directory
+	Archive        ArchiveConfig `yaml:"archive,omitempty"`
+	Mirror         MirrorConfig  `yaml:"mirror,omitempty"`
+	TokenBudget    int           `yaml:"token_budget,omitempty"`    // max estimated prompt tokens; trailing input_images are dropped to fit
+	AllowTextOnly  bool          `yaml:"allow_text_only,omitempty"` // generate runs without input images when none are configured
+	PromptTemplate bool          `yaml:"prompt_template,omitempty"` // generate renders prompts as templates (see generation.PromptTemplate)
+	IncludeContext bool          `yaml:"include_context,omitempty"` // prepend context and character files to the prompt sent
+
+	AssembleAnimation bool `yaml:"assemble_animation,omitempty"` // combine multi-frame responses into animation.gif
+
+	Evaluators []string         `yaml:"evaluators,omitempty"` // commands run on each new entry directory, from the subproject directory
+	Evaluation EvaluationConfig `yaml:"evaluation,omitempty"`
+}
+
+// ArchiveConfig controls what is archived into each history entry besides the prompt and outputs
+type ArchiveConfig struct {
+	Inputs      string `yaml:"inputs,omitempty"`       // "copy" (default) or "hash"
+	Context     bool   `yaml:"context,omitempty"`      // copy the context file into the entry
+	Character   bool   `yaml:"character,omitempty"`    // copy the character file into the entry
+	RawResponse bool   `yaml:"raw_response,omitempty"` // save the raw API response into the entry
+}
+
+const (
+	// ArchiveInputsCopy copies input images into each history entry
+	ArchiveInputsCopy = "copy"
+	// ArchiveInputsHash records only the SHA-256 of each input image
+	ArchiveInputsHash = "hash"
+)
+
+// InputsMode returns the effective input archive mode
+func (a ArchiveConfig) InputsMode() string {
+	return cmp.Or(a.Inputs, ArchiveInputsCopy)
+}
+
+// Validate checks that the archive settings are valid
+func (a ArchiveConfig) Validate() error {
+	switch a.InputsMode() {
+	case ArchiveInputsCopy, ArchiveInputsHash:
+		return nil
+	default:
+		return fmt.Errorf("invalid archive.inputs %q: must be %s or %s", a.Inputs, ArchiveInputsCopy, ArchiveInputsHash)
+	}
+}
+
+// MirrorConfig mirrors every new output into a flat <dir>/YYYY-MM-DD/ folder besides the history entry
+type MirrorConfig struct {
+	Mode string `yaml:"mode,omitempty"` // "copy" or "symlink"; empty disables mirroring
+	Dir  string `yaml:"dir,omitempty"`  // absolute, or relative to the subproject directory (default: outputs)
+}
+
+const (
+	// MirrorCopy copies outputs into the mirror folder
+	MirrorCopy = "copy"
+	// MirrorSymlink links outputs from the mirror folder
+	MirrorSymlink = "symlink"
+	// DefaultMirrorDir is the default mirror folder, relative to the subproject directory
+	DefaultMirrorDir = "outputs"
+)
+
+// Enabled reports whether outputs are mirrored
+func (m MirrorConfig) Enabled() bool {
+	return m.Mode != ""
+}
+
+// Validate checks that the mirror settings are valid
+func (m MirrorConfig) Validate() error {
+	switch m.Mode {
+	case "", MirrorCopy, MirrorSymlink:
+		return nil
+	default:
+		return fmt.Errorf("invalid mirror.mode %q: must be %s or %s", m.Mode, MirrorCopy, MirrorSymlink)
+	}
+}
+
+// EvaluationConfig controls what happens when an evaluator rejects a new entry.
+// The zero value only records the results in meta.yaml.
+type EvaluationConfig struct {
+	OnFail     string `yaml:"on_fail,omitempty"`     // "mark" or "retry"; empty only records
+	MaxRetries int    `yaml:"max_retries,omitempty"` // generations retried with on_fail: retry (default: DefaultEvaluationRetries)
+}
+
+const (
+	// EvaluationMark marks rejected entries as failed
+	EvaluationMark = "mark"
+	// EvaluationRetry marks rejected entries as failed and generates again
+	EvaluationRetry = "retry"
+	// DefaultEvaluationRetries is the number of retries with on_fail: retry when max_retries is not set
+	DefaultEvaluationRetries = 1
+)
+
+// Retries returns how many times a rejected generation is retried
+func (e EvaluationConfig) Retries() int {
+	if e.OnFail != EvaluationRetry {
+		return 0
+	}
+	if e.MaxRetries > 0 {
+		return e.MaxRetries
+	}
+	return DefaultEvaluationRetries
+}
+
+// Validate checks that the evaluation settings are valid
+func (e EvaluationConfig) Validate() error {
+	switch e.OnFail {
+	case "", EvaluationMark, EvaluationRetry:
+	default:
+		return fmt.Errorf("invalid evaluation.on_fail %q: must be %s or %s", e.OnFail, EvaluationMark, EvaluationRetry)
+	}
+	if e.MaxRetries < 0 {
+		return fmt.Errorf("invalid evaluation.max_retries %d: must be 0 (default) or more", e.MaxRetries)
+	}
+	return nil
 }
 
 const (
@@ -57,12 +173,15 @@ func (c *SubprojectConfig) Save(dir string) error {
 func LoadSubprojectConfig(dir string) (*SubprojectConfig, error) {
 	path := filepath.Join(dir, subprojectConfigFile)
 	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read subproject config: %w", err)
 	}
 
 	var config SubprojectConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := decodeStrict(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse subproject config: %w", err)
 	}
 
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrConfigNotFound is returned when loading a configuration file that does not exist
+	ErrConfigNotFound = errors.New("config file not found")
+	// ErrInvalidConfig is returned when a configuration file has unknown keys, invalid values or is not YAML
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// invalidConfigError marks the errors found in a configuration file as ErrInvalidConfig.
+// Its message is that of the errors so that they can still be listed one by one.
+type invalidConfigError struct {
+	err error
+}
+
+func (e *invalidConfigError) Error() string        { return e.err.Error() }
+func (e *invalidConfigError) Unwrap() error        { return e.err }
+func (e *invalidConfigError) Is(target error) bool { return target == ErrInvalidConfig }
+
+// aspectRatioRegex matches patterns like "1:1", "16:9", "4:3"
+var aspectRatioRegex = regexp.MustCompile(`^\d+:\d+$`)
+
+// imageSizes are the supported image_size values
+var imageSizes = []string{"1K", "2K", "4K"}
+
+// outputFormats are the supported output_format values
+var outputFormats = []string{"png", "jpeg", "webp"}
+
+var historyLayouts = []string{"flat", "nested"}
+
+// ValidateAspectRatio validates the aspect ratio format (N:N pattern).
+// Empty string is allowed (uses API default).
+func ValidateAspectRatio(aspect string) error {
+	if aspect == "" {
+		return nil
+	}
+	if !aspectRatioRegex.MatchString(aspect) {
+		return fmt.Errorf("invalid aspect ratio %q: must be in N:N format (e.g., 1:1, 16:9)", aspect)
+	}
+	return nil
+}
+
+// ValidateImageSize validates the image size value.
+// Empty string is allowed (uses API default).
+func ValidateImageSize(size string) error {
+	if size == "" || slices.Contains(imageSizes, size) {
+		return nil
+	}
+	return fmt.Errorf("invalid image size %q: must be 1K, 2K, or 4K", size)
+}
+
+// ValidateOutputFormat validates the format outputs are converted to.
+// Empty string is allowed (outputs are saved as returned).
+func ValidateOutputFormat(format string) error {
+	if format == "" || slices.Contains(outputFormats, format) {
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q: must be png, jpeg, or webp", format)
+}
+
+// ValidateHistoryLayout validates the layout of history entry directories.
+// Empty string is allowed (flat).
+func ValidateHistoryLayout(layout string) error {
+	if layout == "" || slices.Contains(historyLayouts, layout) {
+		return nil
+	}
+	return fmt.Errorf("invalid history layout %q: must be flat or nested", layout)
+}
+
+// ParseVersion returns the major number of a config version such as "1.0", "2" or "2.0"
+func ParseVersion(version string) (int, error) {
+	if version == "" {
+		return 0, errors.New("empty version")
+	}
+	major, _, _ := strings.Cut(version, ".")
+	return strconv.Atoi(major)
+}
+
+// validateVersion accepts a missing version (older projects, fixed by 'banago migrate')
+// and rejects malformed ones and versions newer than this banago understands
+func validateVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	major, err := ParseVersion(version)
+	if err != nil || major < 1 {
+		return fmt.Errorf("invalid version %q: must be a number such as %s", version, configVersion)
+	}
+	current, _ := ParseVersion(configVersion)
+	if major > current {
+		return fmt.Errorf("version %s is newer than this banago supports (%s). Upgrade banago", version, configVersion)
+	}
+	return nil
+}
+
+// Validate checks the values of a project configuration
+func (c *ProjectConfig) Validate() error {
+	var errs []error
+	if err := validateVersion(c.Version); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MinFreeDiskMB != nil && *c.MinFreeDiskMB < 0 {
+		errs = append(errs, fmt.Errorf("invalid min_free_disk_mb %d: must be 0 or more", *c.MinFreeDiskMB))
+	}
+	if err := ValidateHistoryLayout(c.HistoryLayout); err != nil {
+		errs = append(errs, fmt.Errorf("history_layout: %w", err))
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("invalid timeout %q: must be a duration such as 90s or 10m", c.Timeout))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the values of a subproject configuration
+func (c *SubprojectConfig) Validate() error {
+	var errs []error
+	if err := validateVersion(c.Version); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ValidateAspectRatio(c.AspectRatio); err != nil {
+		errs = append(errs, fmt.Errorf("aspect_ratio: %w", err))
+	}
+	if err := ValidateImageSize(c.ImageSize); err != nil {
+		errs = append(errs, fmt.Errorf("image_size: %w", err))
+	}
+	if err := ValidateOutputFormat(c.OutputFormat); err != nil {
+		errs = append(errs, fmt.Errorf("output_format: %w", err))
+	}
+	if c.TokenBudget < 0 {
+		errs = append(errs, fmt.Errorf("invalid token_budget %d: must be 0 (unlimited) or more", c.TokenBudget))
+	}
+	if err := c.Archive.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Mirror.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Evaluation.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// decodeStrict decodes YAML into cfg and returns every key that cfg does not define
+// (with its line and the closest known key) and every invalid value, joined into one ErrInvalidConfig
+func decodeStrict(data []byte, cfg interface{ Validate() error }) error {
+	if err := decodeConfig(data, cfg); err != nil {
+		return &invalidConfigError{err: err}
+	}
+	return nil
+}
+
+func decodeConfig(data []byte, cfg interface{ Validate() error }) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	var errs []error
+	if len(doc.Content) > 0 {
+		errs = unknownKeys(doc.Content[0], reflect.TypeOf(cfg).Elem(), "")
+		if err := doc.Decode(cfg); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = append(errs, joined.Unwrap()...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unknownKeys returns an error for every mapping key of node that is not a yaml field of struct type t
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix string) []error {
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := map[string]reflect.Type{}
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		fields[name] = ft
+		names = append(names, name)
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		ft, ok := fields[key.Value]
+		if !ok {
+			msg := fmt.Sprintf("line %d: unknown key %q", key.Line, prefix+key.Value)
+			if s := closestKey(key.Value, names); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", prefix+s)
+			}
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		errs = append(errs, unknownKeys(value, ft, prefix+key.Value+".")...)
+	}
+	return errs
+}
+
+// closestKey returns the known key within a small edit distance of key, or "" if none is close
+func closestKey(key string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
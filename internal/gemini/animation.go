@@ -0,0 +1,64 @@
+package gemini
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg" // register decoder for JPEG frames
+	_ "image/png"  // register decoder for PNG frames
+	"os"
+)
+
+// AnimationFile is the filename of the animation assembled from a multi-frame response
+const AnimationFile = "animation.gif"
+
+// DefaultFrameDelay is the delay between animation frames in 100ths of a second
+const DefaultFrameDelay = 20
+
+// AssembleGIF combines image frames, in order, into a looping animated GIF at dstPath.
+// delay is the time each frame is shown in 100ths of a second.
+func AssembleGIF(framePaths []string, dstPath string, delay int) error {
+	if len(framePaths) < 2 {
+		return fmt.Errorf("an animation needs at least 2 frames, got %d", len(framePaths))
+	}
+
+	anim := &gif.GIF{}
+	for _, path := range framePaths {
+		frame, err := decodeFrame(path)
+		if err != nil {
+			return err
+		}
+		bounds := frame.Bounds()
+		paletted := image.NewPaletted(bounds, palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, bounds, frame, bounds.Min)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create animation (%s): %w", dstPath, err)
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to encode animation: %w", err)
+	}
+	return f.Close()
+}
+
+// decodeFrame reads a single PNG, JPEG or GIF frame
+func decodeFrame(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame (%s): %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame (%s): %w", path, err)
+	}
+	return img, nil
+}
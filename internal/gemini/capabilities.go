@@ -0,0 +1,134 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sources of Capabilities
+const (
+	CapabilitiesBundled = "bundled" // the table in this binary
+	CapabilitiesAPI     = "api"     // probed with ProbeCapabilities
+)
+
+// Capabilities is what a model accepts for image generation. Empty lists and zero limits are unknown.
+type Capabilities struct {
+	Model            string   `json:"model"`
+	DisplayName      string   `json:"display_name,omitempty"`
+	AspectRatios     []string `json:"aspect_ratios,omitempty"`
+	ImageSizes       []string `json:"image_sizes,omitempty"`
+	MaxInputImages   int      `json:"max_input_images,omitempty"`
+	InputTokenLimit  int      `json:"input_token_limit,omitempty"`
+	OutputTokenLimit int      `json:"output_token_limit,omitempty"`
+	Pricing          *Pricing `json:"pricing,omitempty"`
+	Source           string   `json:"source"`
+	FetchedAt        string   `json:"fetched_at,omitempty"` // RFC 3339, set for CapabilitiesAPI
+}
+
+// imageAspectRatios are the aspect ratios the Gemini image models accept
+var imageAspectRatios = []string{"1:1", "2:3", "3:2", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9"}
+
+// capabilityTable lists the capabilities of known image models, as published by Google.
+// The API does not report aspect ratios, sizes or input limits, so probes fill them in from here.
+var capabilityTable = map[string]Capabilities{
+	"gemini-3-pro-image-preview": {
+		AspectRatios:     imageAspectRatios,
+		ImageSizes:       []string{"1K", "2K", "4K"},
+		MaxInputImages:   14,
+		InputTokenLimit:  65536,
+		OutputTokenLimit: 32768,
+	},
+	"gemini-2.5-flash-image": {
+		AspectRatios:     imageAspectRatios,
+		ImageSizes:       []string{"1K"},
+		MaxInputImages:   3,
+		InputTokenLimit:  32768,
+		OutputTokenLimit: 32768,
+	},
+}
+
+// BundledCapabilities returns the capabilities of model from the table in this binary.
+// The second result is false for models the table does not know.
+func BundledCapabilities(model string) (Capabilities, bool) {
+	caps, ok := capabilityTable[model]
+	caps.Model = model
+	caps.Source = CapabilitiesBundled
+	if p, known := LookupPricing(model); known {
+		caps.Pricing = &p
+	}
+	return caps, ok
+}
+
+// ProbeCapabilities asks the API about model and completes the answer with the bundled table.
+// Unknown models fail with the API error.
+func (c *Client) ProbeCapabilities(ctx context.Context, model string) (*Capabilities, error) {
+	start := time.Now()
+	m, err := c.client.Models.Get(ctx, model, nil)
+	logCall(ctx, "get_model", model, start, err)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	caps, _ := BundledCapabilities(model)
+	caps.Source = CapabilitiesAPI
+	caps.FetchedAt = time.Now().UTC().Format(time.RFC3339)
+	caps.DisplayName = m.DisplayName
+	if m.InputTokenLimit > 0 {
+		caps.InputTokenLimit = int(m.InputTokenLimit)
+	}
+	if m.OutputTokenLimit > 0 {
+		caps.OutputTokenLimit = int(m.OutputTokenLimit)
+	}
+	return &caps, nil
+}
+
+// Check returns an error if the model does not accept the aspect ratio, image size or number of
+// input images. Empty values and unknown capabilities pass.
+func (c *Capabilities) Check(aspect, size string, inputImages int) error {
+	if aspect != "" && len(c.AspectRatios) > 0 && !slices.Contains(c.AspectRatios, aspect) {
+		return fmt.Errorf("model %s does not support aspect ratio %q (supported: %s)", c.Model, aspect, strings.Join(c.AspectRatios, ", "))
+	}
+	if size != "" && len(c.ImageSizes) > 0 && !slices.Contains(c.ImageSizes, strings.ToUpper(size)) {
+		return fmt.Errorf("model %s does not support image size %q (supported: %s)", c.Model, size, strings.Join(c.ImageSizes, ", "))
+	}
+	if c.MaxInputImages > 0 && inputImages > c.MaxInputImages {
+		return fmt.Errorf("model %s accepts at most %d input images, got %d", c.Model, c.MaxInputImages, inputImages)
+	}
+	return nil
+}
+
+// LoadCapabilityCache reads the capabilities saved with SaveCapabilityCache, by model.
+// A missing or unreadable cache is empty.
+func LoadCapabilityCache(path string) map[string]*Capabilities {
+	cache := map[string]*Capabilities{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return map[string]*Capabilities{}
+	}
+	return cache
+}
+
+// SaveCapabilityCache adds caps to the cache at path
+func SaveCapabilityCache(path string, caps *Capabilities) error {
+	cache := LoadCapabilityCache(path)
+	cache[caps.Model] = caps
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save model capabilities: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save model capabilities: %w", err)
+	}
+	return nil
+}
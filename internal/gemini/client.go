@@ -2,6 +2,7 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/genai"
@@ -22,6 +24,8 @@ type Params struct {
 	ImagePaths  []string
 	AspectRatio string
 	ImageSize   string
+	Seed        *int32 // nil lets the API pick one
+	Candidates  int    // variants returned by the one request (0 = API default, one)
 }
 
 // Result holds the result of image generation
@@ -51,6 +55,9 @@ func NewClient(ctx context.Context, apiKey string) (*Client, error) {
 
 // Generate calls the Gemini API to generate images
 func (c *Client) Generate(ctx context.Context, params Params) *Result {
+	if ctx.Err() != nil {
+		return &Result{Error: canceledError(ctx)}
+	}
 	parts := []*genai.Part{genai.NewPartFromText(params.Prompt)}
 	for _, imgPath := range params.ImagePaths {
 		part, err := ImagePartFromFile(imgPath)
@@ -60,7 +67,7 @@ func (c *Client) Generate(ctx context.Context, params Params) *Result {
 		parts = append(parts, part)
 	}
 
-	gcfg := &genai.GenerateContentConfig{ResponseModalities: []string{"IMAGE"}}
+	gcfg := &genai.GenerateContentConfig{ResponseModalities: []string{"IMAGE"}, Seed: params.Seed, CandidateCount: int32(params.Candidates)}
 	if params.AspectRatio != "" || params.ImageSize != "" {
 		gcfg.ImageConfig = &genai.ImageConfig{
 			AspectRatio: params.AspectRatio,
@@ -69,11 +76,12 @@ func (c *Client) Generate(ctx context.Context, params Params) *Result {
 	}
 
 	contents := []*genai.Content{{Parts: parts}}
+	start := time.Now()
 	resp, err := c.client.Models.GenerateContent(ctx, params.Model, contents, gcfg)
 
 	result := &Result{
 		Response: resp,
-		Error:    err,
+		Error:    classifyError(ctx, err),
 	}
 
 	if err == nil && resp != nil && resp.UsageMetadata != nil {
@@ -85,10 +93,63 @@ func (c *Client) Generate(ctx context.Context, params Params) *Result {
 			Thoughts:   int(resp.UsageMetadata.ThoughtsTokenCount),
 		}
 	}
+	logCall(ctx, "generate_content", params.Model, start, err, "inputs", len(params.ImagePaths), "tokens", result.TokenUsage.Total)
 
 	return result
 }
 
+// CountTokenBreakdown measures how many prompt tokens the text and each input image contribute.
+// The text is counted alone, then once together with each image; the difference is the image's share.
+func (c *Client) CountTokenBreakdown(ctx context.Context, params Params) (*TokenBreakdown, error) {
+	textPart := genai.NewPartFromText(params.Prompt)
+	text, err := c.countTokens(ctx, params.Model, textPart)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &TokenBreakdown{Text: text}
+	for _, imgPath := range params.ImagePaths {
+		part, err := ImagePartFromFile(imgPath)
+		if err != nil {
+			return nil, err
+		}
+		withImage, err := c.countTokens(ctx, params.Model, textPart, part)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Inputs = append(breakdown.Inputs, InputTokens{Name: filepath.Base(imgPath), Tokens: withImage - text})
+	}
+	return breakdown, nil
+}
+
+func (c *Client) countTokens(ctx context.Context, model string, parts ...*genai.Part) (int, error) {
+	start := time.Now()
+	resp, err := c.client.Models.CountTokens(ctx, model, []*genai.Content{{Parts: parts}}, nil)
+	logCall(ctx, "count_tokens", model, start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", classifyError(ctx, err))
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// PrintTokenBreakdown prints each contribution with its share of the total
+func PrintTokenBreakdown(w io.Writer, b *TokenBreakdown) {
+	total := b.Total()
+	share := func(n int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) * 100 / float64(total)
+	}
+
+	_, _ = fmt.Fprintln(w, "Prompt token breakdown:")
+	_, _ = fmt.Fprintf(w, "  text: %d (%.0f%%)\n", b.Text, share(b.Text))
+	for _, in := range b.Inputs {
+		_, _ = fmt.Fprintf(w, "  %s: %d (%.0f%%)\n", in.Name, in.Tokens, share(in.Tokens))
+	}
+	_, _ = fmt.Fprintf(w, "  total: %d\n", total)
+}
+
 // PrintOutput prints the generation result to the writer
 func PrintOutput(w io.Writer, resp *genai.GenerateContentResponse, model string) {
 	if text := strings.TrimSpace(resp.Text()); text != "" {
@@ -116,6 +177,30 @@ func PrintOutput(w io.Writer, resp *genai.GenerateContentResponse, model string)
 	_, _ = fmt.Fprintf(w, "Model: %s\n", model)
 }
 
+// MarshalRawResponse returns the API response as indented JSON.
+// Inline image data is dropped because the images are saved as separate files.
+func MarshalRawResponse(resp *genai.GenerateContentResponse) ([]byte, error) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	var stripped genai.GenerateContentResponse
+	if err := json.Unmarshal(data, &stripped); err != nil {
+		return nil, fmt.Errorf("failed to copy response: %w", err)
+	}
+	for _, cand := range stripped.Candidates {
+		if cand == nil || cand.Content == nil {
+			continue
+		}
+		for _, part := range cand.Content.Parts {
+			if part != nil && part.InlineData != nil {
+				part.InlineData.Data = nil
+			}
+		}
+	}
+	return json.MarshalIndent(&stripped, "", "  ")
+}
+
 // ImagePartFromFile reads an image file and returns a genai.Part
 func ImagePartFromFile(path string) (*genai.Part, error) {
 	data, err := os.ReadFile(path)
@@ -134,6 +219,13 @@ func ImagePartFromFile(path string) (*genai.Part, error) {
 
 // SaveImages saves generated images from the response to the specified directory
 func SaveImages(resp *genai.GenerateContentResponse, dir string) ([]string, error) {
+	return SaveImagesAs(resp, dir, "", nil)
+}
+
+// SaveImagesAs saves generated images like SaveImages, converted to format (FormatPNG, FormatJPEG or
+// FormatWebP) and with prov embedded (see Provenance). Images are saved as returned when format is
+// empty, and without metadata when prov is nil.
+func SaveImagesAs(resp *genai.GenerateContentResponse, dir, format string, prov *Provenance) ([]string, error) {
 	if resp == nil {
 		return nil, errors.New("response is empty")
 	}
@@ -153,10 +245,18 @@ func SaveImages(resp *genai.GenerateContentResponse, dir string) ([]string, erro
 				continue
 			}
 			ext := NormalizeExt(part.InlineData.MIMEType)
-			fileName := fmt.Sprintf("output-%s-%d%s", runID, imageIndex+1, ext)
-			fullPath := filepath.Join(dir, fileName)
-			if err := os.WriteFile(fullPath, part.InlineData.Data, 0o644); err != nil {
-				return nil, fmt.Errorf("failed to save image (%s): %w", fullPath, err)
+			if ext == ".bin" {
+				// Missing or unrecognized MIME type: sniff the data instead
+				ext = NormalizeExt(http.DetectContentType(part.InlineData.Data))
+			}
+			data, ext, err := convertImage(part.InlineData.Data, ext, format)
+			if err != nil {
+				return nil, err
+			}
+			data = embedProvenance(data, ext, prov)
+			fullPath, err := writeOutput(dir, OutputFileName(runID, imageIndex+1, ext), data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to save image: %w", err)
 			}
 			saved = append(saved, fullPath)
 			imageIndex++
@@ -164,7 +264,10 @@ func SaveImages(resp *genai.GenerateContentResponse, dir string) ([]string, erro
 	}
 
 	if len(saved) == 0 {
-		return nil, errors.New("no image response found")
+		if reason := blockReason(resp); reason != "" {
+			return nil, fmt.Errorf("%w (%s)", ErrSafetyBlocked, reason)
+		}
+		return nil, ErrNoImage
 	}
 
 	return saved, nil
@@ -181,6 +284,8 @@ func NormalizeExt(mimeType string) string {
 		return ".webp"
 	case "image/gif":
 		return ".gif"
+	case "image/apng":
+		return ".apng"
 	case "image/bmp":
 		return ".bmp"
 	case "image/avif":
@@ -195,5 +300,8 @@ func NormalizeExt(mimeType string) string {
 	if strings.Contains(strings.ToLower(mimeType), "jpeg") {
 		return ".jpg"
 	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
 	return ".bin"
 }
@@ -0,0 +1,64 @@
+package gemini
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	_ "golang.org/x/image/webp" // register decoder for WebP outputs
+)
+
+// Output formats images can be converted to when saved (output_format / --format)
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// jpegQuality is the quality outputs are converted to JPEG with
+const jpegQuality = 92
+
+// formatExt returns the file extension of an output format
+func formatExt(format string) string {
+	switch format {
+	case FormatPNG:
+		return ".png"
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	}
+	return ""
+}
+
+// convertImage re-encodes image data whose extension is ext into format and returns the new data
+// and extension. Data already in format, and any data with an empty format, is returned unchanged.
+func convertImage(data []byte, ext, format string) ([]byte, string, error) {
+	target := formatExt(format)
+	switch {
+	case format == "" || ext == target:
+		return data, ext, nil
+	case target == "":
+		return nil, "", fmt.Errorf("unknown output format %q", format)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s output for conversion to %s: %w", ext, format, err)
+	}
+	var buf bytes.Buffer
+	switch format {
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case FormatWebP:
+		err = EncodeWebP(&buf, img)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to convert output to %s: %w", format, err)
+	}
+	return buf.Bytes(), target, nil
+}
@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"google.golang.org/genai"
+)
+
+var (
+	// ErrSafetyBlocked is returned when the API refused the prompt or withheld the output for safety reasons
+	ErrSafetyBlocked = errors.New("blocked by the safety filter")
+	// ErrQuota is returned when the API rejected a request because the rate limit or quota is used up
+	ErrQuota = errors.New("API quota exceeded")
+	// ErrNoImage is returned when a response holds no image without being blocked
+	ErrNoImage = errors.New("no image response found")
+	// ErrCanceled is returned when a call was interrupted or ran out of time. The cause of the
+	// context (context.Canceled, context.DeadlineExceeded or the cause the caller set) stays wrapped.
+	ErrCanceled = errors.New("canceled")
+)
+
+// Failure categories of failed API calls (FailureCategory)
+const (
+	FailureSafety  = "safety"  // blocked by the safety filter: usually the prompt or input images
+	FailureQuota   = "quota"   // rate limit or quota used up
+	FailureNetwork = "network" // the request or its answer got lost on the way
+	FailureServer  = "server"  // the API failed (HTTP 5xx)
+	FailureEmpty   = "empty"   // the response held no image without being blocked
+	FailureOther   = "other"
+)
+
+// FailureCategories lists the failure categories in report order
+var FailureCategories = []string{FailureSafety, FailureQuota, FailureNetwork, FailureServer, FailureEmpty, FailureOther}
+
+// FailureCategory returns the category of the error of a failed API call
+func FailureCategory(err error) string {
+	var apiErr genai.APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrSafetyBlocked):
+		return FailureSafety
+	case errors.Is(err, ErrQuota):
+		return FailureQuota
+	case errors.Is(err, ErrNoImage):
+		return FailureEmpty
+	case errors.As(err, &apiErr):
+		if apiErr.Code >= http.StatusInternalServerError {
+			return FailureServer
+		}
+		return FailureOther
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return FailureNetwork
+	}
+	return FailureOther
+}
+
+// classifyError marks an error as ErrCanceled when ctx ended and an API error as ErrQuota when it is
+// one, keeping the original error wrapped
+func classifyError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return canceledError(ctx)
+	}
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	if apiErr.Code == http.StatusTooManyRequests || apiErr.Status == "RESOURCE_EXHAUSTED" {
+		return fmt.Errorf("%w: %w", ErrQuota, err)
+	}
+	return err
+}
+
+// canceledError is the error of a call whose context ended
+func canceledError(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+}
+
+// blockReason returns why the API withheld the images of resp, or "" if it did not block it
+func blockReason(resp *genai.GenerateContentResponse) string {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return "prompt: " + string(resp.PromptFeedback.BlockReason)
+	}
+	for _, cand := range resp.Candidates {
+		if cand == nil {
+			continue
+		}
+		switch cand.FinishReason {
+		case genai.FinishReasonSafety, genai.FinishReasonProhibitedContent, genai.FinishReasonBlocklist,
+			genai.FinishReasonSPII, genai.FinishReasonImageSafety, genai.FinishReasonImageProhibitedContent:
+			return "output: " + string(cand.FinishReason)
+		}
+	}
+	return ""
+}
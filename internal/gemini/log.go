@@ -0,0 +1,18 @@
+package gemini
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logCall logs one API call and its latency, at warn level when it failed
+func logCall(ctx context.Context, op, model string, start time.Time, err error, attrs ...any) {
+	level := slog.LevelInfo
+	args := append([]any{"op", op, "model", model, "duration", time.Since(start)}, attrs...)
+	if err != nil {
+		level = slog.LevelWarn
+		args = append(args, "error", err)
+	}
+	slog.Log(ctx, level, "gemini api call", args...)
+}
@@ -0,0 +1,274 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrStripUnsupported is returned by StripMetadata and MetadataKinds for data that is not a PNG,
+// JPEG, WebP or GIF image, whose metadata cannot be removed or ruled out
+var ErrStripUnsupported = errors.New("cannot strip metadata: only PNG, JPEG, WebP and GIF images are supported")
+
+// errMalformedImage is returned for image data whose container cannot be parsed
+var errMalformedImage = errors.New("malformed image data")
+
+// metadataSegment is a run of bytes of an image container, with the kind of metadata it holds
+// or an empty kind for image data that is kept
+type metadataSegment struct {
+	data []byte
+	kind string
+}
+
+// StripMetadata returns a copy of image data without embedded metadata: text, time and EXIF chunks
+// of PNG files, EXIF, XMP, IPTC and comments of JPEG files, EXIF and XMP chunks of WebP files, and
+// comment and XMP extensions of GIF files. This includes the banago provenance. Color profiles and
+// animation settings are kept. The copy is checked with MetadataKinds before it is returned.
+func StripMetadata(data []byte) ([]byte, error) {
+	segments, err := metadataSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	for _, s := range segments {
+		if s.kind == "" {
+			out = append(out, s.data...)
+		}
+	}
+	if isWebP(out) {
+		fixWebPHeader(out)
+	}
+
+	kinds, err := MetadataKinds(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify stripped image: %w", err)
+	}
+	if len(kinds) > 0 {
+		return nil, fmt.Errorf("metadata left after stripping: %s", strings.Join(kinds, ", "))
+	}
+	return out, nil
+}
+
+// MetadataKinds returns the kinds of metadata embedded in image data (e.g. "EXIF", "XMP",
+// "PNG text"), each once in order of appearance. Images without metadata return none.
+func MetadataKinds(data []byte) ([]string, error) {
+	segments, err := metadataSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	var kinds []string
+	seen := map[string]bool{}
+	for _, s := range segments {
+		if s.kind != "" && !seen[s.kind] {
+			seen[s.kind] = true
+			kinds = append(kinds, s.kind)
+		}
+	}
+	return kinds, nil
+}
+
+func metadataSegments(data []byte) ([]metadataSegment, error) {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return pngSegments(data)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return jpegSegments(data)
+	case isWebP(data):
+		return webpSegments(data)
+	case bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a")):
+		return gifSegments(data)
+	}
+	return nil, ErrStripUnsupported
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+func pngSegments(data []byte) ([]metadataSegment, error) {
+	segments := []metadataSegment{{data: data[:len(pngSignature)]}}
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, errMalformedImage
+		}
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + n
+		if end > len(data) {
+			return nil, errMalformedImage
+		}
+		s := metadataSegment{data: data[pos:end]}
+		switch string(data[pos+4 : pos+8]) {
+		case "tEXt", "zTXt", "iTXt":
+			s.kind = "PNG text"
+		case "eXIf":
+			s.kind = "EXIF"
+		case "tIME":
+			s.kind = "PNG time"
+		case "IEND":
+			segments = append(segments, s)
+			if end < len(data) {
+				segments = append(segments, metadataSegment{data: data[end:], kind: "trailing data"})
+			}
+			return segments, nil
+		}
+		segments = append(segments, s)
+		pos = end
+	}
+	return nil, errMalformedImage
+}
+
+func jpegSegments(data []byte) ([]metadataSegment, error) {
+	segments := []metadataSegment{{data: data[:2]}}
+	for pos := 2; ; {
+		if pos+4 > len(data) || data[pos] != 0xff {
+			return nil, errMalformedImage
+		}
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image: the image data follows
+			return append(segments, metadataSegment{data: data[pos:]}), nil
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			return nil, errMalformedImage
+		}
+		payload := data[pos+4 : end]
+		s := metadataSegment{data: data[pos:end]}
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, []byte(exifHeader)):
+			s.kind = "EXIF"
+		case marker == 0xe1 && bytes.HasPrefix(payload, []byte("http://ns.adobe.com/xap/1.0/")):
+			s.kind = "XMP"
+		case marker == 0xed:
+			s.kind = "IPTC"
+		case marker == 0xfe:
+			s.kind = "JPEG comment"
+		case marker == 0xe0 && bytes.HasPrefix(payload, []byte("JFIF")),
+			marker == 0xe2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE")),
+			marker == 0xee && bytes.HasPrefix(payload, []byte("Adobe")):
+			// Needed to show the image as intended
+		case marker >= 0xe0 && marker <= 0xef:
+			s.kind = fmt.Sprintf("JPEG APP%d", marker-0xe0)
+		}
+		segments = append(segments, s)
+		pos = end
+	}
+}
+
+func webpSegments(data []byte) ([]metadataSegment, error) {
+	segments := []metadataSegment{{data: data[:12]}}
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		n := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + n + n&1
+		if pos+8+n > len(data) {
+			return nil, errMalformedImage
+		}
+		end = min(end, len(data))
+		s := metadataSegment{data: data[pos:end]}
+		switch string(data[pos : pos+4]) {
+		case "EXIF":
+			s.kind = "EXIF"
+		case "XMP ":
+			s.kind = "XMP"
+		}
+		segments = append(segments, s)
+		pos = end
+	}
+	return segments, nil
+}
+
+// fixWebPHeader updates the RIFF size and the metadata flags of the VP8X chunk after chunks were removed
+func fixWebPHeader(data []byte) {
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	if len(data) >= 12+8+10 && string(data[12:16]) == "VP8X" {
+		const exifFlag, xmpFlag = 0x08, 0x04
+		data[20] &^= exifFlag | xmpFlag
+	}
+}
+
+func gifSegments(data []byte) ([]metadataSegment, error) {
+	const headerSize = 6 + 7 // signature and logical screen descriptor
+	if len(data) < headerSize {
+		return nil, errMalformedImage
+	}
+	pos := headerSize
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1) // global color table
+	}
+	if pos > len(data) {
+		return nil, errMalformedImage
+	}
+	segments := []metadataSegment{{data: data[:pos]}}
+
+	// subBlocks returns the end of the data sub-blocks starting at pos
+	subBlocks := func(pos int) (int, error) {
+		for pos < len(data) {
+			n := int(data[pos])
+			pos += 1 + n
+			if n == 0 {
+				return pos, nil
+			}
+		}
+		return 0, errMalformedImage
+	}
+
+	for pos < len(data) {
+		start := pos
+		s := metadataSegment{}
+		switch data[pos] {
+		case 0x3b: // trailer
+			segments = append(segments, metadataSegment{data: data[pos : pos+1]})
+			if pos+1 < len(data) {
+				segments = append(segments, metadataSegment{data: data[pos+1:], kind: "trailing data"})
+			}
+			return segments, nil
+		case 0x21: // extension
+			if pos+2 > len(data) {
+				return nil, errMalformedImage
+			}
+			switch label := data[pos+1]; {
+			case label == 0xfe:
+				s.kind = "GIF comment"
+			case label == 0xff && pos+14 <= len(data) && data[pos+2] == 11:
+				switch app := string(data[pos+3 : pos+14]); app {
+				case "NETSCAPE2.0", "ANIMEXTS1.0":
+					// Loop count of animations
+				case "XMP DataXMP":
+					s.kind = "XMP"
+				default:
+					s.kind = "GIF application data"
+				}
+			}
+			end, err := subBlocks(pos + 2)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+		case 0x2c: // image descriptor
+			if pos+10 > len(data) {
+				return nil, errMalformedImage
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1) // local color table
+			}
+			end, err := subBlocks(pos + 1) // after the LZW minimum code size
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+		default:
+			return nil, errMalformedImage
+		}
+		s.data = data[start:pos]
+		segments = append(segments, s)
+	}
+	return nil, errMalformedImage
+}
@@ -0,0 +1,54 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+
+	"google.golang.org/genai"
+)
+
+// mockImageSize is the width and height of the images MockClient returns
+const mockImageSize = 64
+
+// MockClient generates placeholder images without calling the API.
+// It lets banago run its full workflow offline, for example in 'banago selftest'.
+type MockClient struct{}
+
+// NewMockClient creates a MockClient
+func NewMockClient() *MockClient {
+	return &MockClient{}
+}
+
+// Generate returns one solid PNG whose color is derived from the prompt, so the same prompt gives the same image
+func (c *MockClient) Generate(_ context.Context, params Params) *Result {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(params.Prompt))
+	sum := h.Sum32()
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 0xff}
+
+	img := image.NewRGBA(image.Rect(0, 0, mockImageSize, mockImageSize))
+	for y := range mockImageSize {
+		for x := range mockImageSize {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return &Result{Error: err}
+	}
+
+	usage := TokenUsage{Prompt: EstimatePromptTokens(len(params.Prompt), len(params.ImagePaths)), Candidates: 1290}
+	usage.Total = usage.Prompt + usage.Candidates
+	return &Result{
+		Response: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{
+				Content: &genai.Content{Parts: []*genai.Part{genai.NewPartFromBytes(buf.Bytes(), "image/png")}},
+			}},
+		},
+		TokenUsage: usage,
+	}
+}
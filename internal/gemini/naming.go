@@ -0,0 +1,60 @@
+package gemini
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// OutputPattern is the glob matching every output file of entries, edits and quick runs
+const OutputPattern = "output-*"
+
+// outputNameRegex matches output-<run UUID>-<index><ext>
+var outputNameRegex = regexp.MustCompile(`^output-([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})-([1-9][0-9]*)(\.[A-Za-z0-9]+)$`)
+
+// OutputFileName returns the name of the index-th (1-based) output of a run: output-<run UUID>-<index><ext>.
+// The run UUID keeps names unique when outputs of several runs share a folder
+// (quick runs, merged entries, mirror folders).
+func OutputFileName(runID uuid.UUID, index int, ext string) string {
+	return fmt.Sprintf("output-%s-%d%s", runID, index, ext)
+}
+
+// IsOutputFileName reports whether name follows the OutputFileName scheme
+func IsOutputFileName(name string) bool {
+	return outputNameRegex.MatchString(name)
+}
+
+// ParseOutputFileName returns the run UUID and index of an output file name
+func ParseOutputFileName(name string) (uuid.UUID, int, bool) {
+	m := outputNameRegex.FindStringSubmatch(name)
+	if m == nil {
+		return uuid.UUID{}, 0, false
+	}
+	runID, err := uuid.Parse(m[1])
+	if err != nil {
+		return uuid.UUID{}, 0, false
+	}
+	index, err := strconv.Atoi(m[2])
+	if err != nil {
+		return uuid.UUID{}, 0, false
+	}
+	return runID, index, true
+}
+
+// writeOutput writes an output file and fails instead of overwriting an existing one
+func writeOutput(dir, name string, data []byte) (string, error) {
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
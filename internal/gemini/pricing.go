@@ -0,0 +1,72 @@
+package gemini
+
+// Pricing holds USD prices per million tokens for a model
+type Pricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// pricingTable lists known model prices (USD per million tokens, as published by Google)
+var pricingTable = map[string]Pricing{
+	"gemini-3-pro-image-preview": {InputPerMillion: 2.00, OutputPerMillion: 120.00},
+}
+
+const (
+	// inputImageTokens is the approximate token cost of one input image
+	inputImageTokens = 560
+	// outputImageTokens is the token cost of one 1K or 2K output image
+	outputImageTokens = 1120
+	// outputImageTokens4K is the token cost of one 4K output image
+	outputImageTokens4K = 2000
+	// charsPerToken is a rough ratio used to estimate prompt tokens from text length
+	charsPerToken = 4
+)
+
+// Estimate is an estimated token usage and cost for a single generation
+type Estimate struct {
+	InputTokens  int
+	OutputTokens int
+	USD          float64
+	Known        bool // false if the model has no pricing entry (USD is zero)
+}
+
+// LookupPricing returns the pricing of a model
+func LookupPricing(model string) (Pricing, bool) {
+	p, ok := pricingTable[model]
+	return p, ok
+}
+
+// EstimateCost returns the cost in USD of recorded token usage.
+// Thought tokens are billed as output. The second result is false if the model has no pricing entry.
+func EstimateCost(model string, usage TokenUsage) (float64, bool) {
+	p, ok := LookupPricing(model)
+	if !ok {
+		return 0, false
+	}
+	output := usage.Candidates + usage.Thoughts
+	return float64(usage.Prompt)*p.InputPerMillion/1e6 + float64(output)*p.OutputPerMillion/1e6, true
+}
+
+// EstimatePromptTokens roughly estimates the prompt tokens of a request with the given text length and image count
+func EstimatePromptTokens(promptChars, inputImages int) int {
+	return promptChars/charsPerToken + inputImages*inputImageTokens
+}
+
+// EstimateGeneration estimates the cost of one generation producing a single image
+func EstimateGeneration(model string, promptChars, inputImages int, size string) Estimate {
+	est := Estimate{
+		InputTokens:  EstimatePromptTokens(promptChars, inputImages),
+		OutputTokens: outputImageTokens,
+	}
+	if size == "4K" {
+		est.OutputTokens = outputImageTokens4K
+	}
+
+	p, ok := LookupPricing(model)
+	if !ok {
+		return est
+	}
+	est.Known = true
+	est.USD = float64(est.InputTokens)*p.InputPerMillion/1e6 + float64(est.OutputTokens)*p.OutputPerMillion/1e6
+	return est
+}
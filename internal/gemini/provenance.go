@@ -0,0 +1,388 @@
+package gemini
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"time"
+)
+
+// Provenance identifies the banago run an output image was saved by. SaveImagesAs embeds it in PNG
+// outputs as tEXt chunks and in JPEG and WebP outputs as EXIF, so copies taken out of history keep it.
+type Provenance struct {
+	EntryID    string
+	EditID     string // empty for generations
+	Model      string
+	PromptHash string // PromptHash of the saved prompt (prompt.txt of the entry or edit)
+	CreatedAt  string // RFC3339
+}
+
+// ErrNoProvenance is returned by ReadProvenance for images banago did not embed provenance in
+var ErrNoProvenance = errors.New("image has no banago metadata")
+
+// provenanceSoftware is written as the Software text of PNG and EXIF metadata
+const provenanceSoftware = "banago"
+
+// pngKeyPrefix prefixes the tEXt keywords of provenance fields in PNG outputs
+const pngKeyPrefix = "banago:"
+
+// PromptHash returns the hex-encoded SHA-256 of a prompt, as recorded in Provenance
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// provenanceField is a named field of Provenance
+type provenanceField struct {
+	key   string
+	value *string
+}
+
+func (p *Provenance) fields() []provenanceField {
+	return []provenanceField{
+		{"entry_id", &p.EntryID},
+		{"edit_id", &p.EditID},
+		{"model", &p.Model},
+		{"prompt_sha256", &p.PromptHash},
+		{"created_at", &p.CreatedAt},
+	}
+}
+
+// set stores value in the field named key and reports whether key is a provenance field
+func (p *Provenance) set(key, value string) bool {
+	for _, f := range p.fields() {
+		if f.key == key {
+			*f.value = value
+			return true
+		}
+	}
+	return false
+}
+
+// description returns the fields as key=value lines, the form stored in the EXIF ImageDescription
+func (p *Provenance) description() string {
+	var b strings.Builder
+	for _, f := range p.fields() {
+		if *f.value != "" {
+			b.WriteString(f.key + "=" + *f.value + "\n")
+		}
+	}
+	return b.String()
+}
+
+// parseDescription reads the key=value lines written by description
+func parseDescription(s string) (*Provenance, bool) {
+	p := &Provenance{}
+	found := false
+	for line := range strings.Lines(s) {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r\n"), "=")
+		if ok && p.set(key, value) {
+			found = true
+		}
+	}
+	return p, found
+}
+
+// embedProvenance returns image data whose extension is ext with p embedded. Formats without
+// metadata support, and data whose container cannot be parsed, are returned unchanged.
+func embedProvenance(data []byte, ext string, p *Provenance) []byte {
+	if p == nil {
+		return data
+	}
+	switch ext {
+	case ".png":
+		return embedPNG(data, p)
+	case ".jpg":
+		return embedJPEG(data, p)
+	case ".webp":
+		return embedWebP(data, p)
+	}
+	return data
+}
+
+// ReadProvenance returns the provenance embedded in PNG, JPEG or WebP image data, or ErrNoProvenance
+func ReadProvenance(data []byte) (*Provenance, error) {
+	var p *Provenance
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		p = readPNG(data)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		p = readJPEG(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		p = readWebP(data)
+	default:
+		return nil, errors.New("unsupported image format: only PNG, JPEG and WebP carry banago metadata")
+	}
+	if p == nil {
+		return nil, ErrNoProvenance
+	}
+	return p, nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func embedPNG(data []byte, p *Provenance) []byte {
+	// IHDR is always the first chunk: the text chunks go right after it
+	if len(data) < 16 || string(data[12:16]) != "IHDR" {
+		return data
+	}
+	ihdrEnd := 8 + 12 + int(binary.BigEndian.Uint32(data[8:]))
+	if ihdrEnd > len(data) {
+		return data
+	}
+	out := append([]byte(nil), data[:ihdrEnd]...)
+	out = append(out, pngChunk("tEXt", []byte("Software\x00"+provenanceSoftware))...)
+	for _, f := range p.fields() {
+		if *f.value != "" {
+			out = append(out, pngChunk("tEXt", []byte(pngKeyPrefix+f.key+"\x00"+*f.value))...)
+		}
+	}
+	return append(out, data[ihdrEnd:]...)
+}
+
+func readPNG(data []byte) *Provenance {
+	p := &Provenance{}
+	found := false
+	for pos := len(pngSignature); pos+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if pos+12+n > len(data) || typ == "IDAT" || typ == "IEND" {
+			break
+		}
+		if typ == "tEXt" {
+			key, value, _ := bytes.Cut(data[pos+8:pos+8+n], []byte{0})
+			if name, ok := strings.CutPrefix(string(key), pngKeyPrefix); ok && p.set(name, string(value)) {
+				found = true
+			}
+		}
+		pos += 12 + n
+	}
+	if !found {
+		return nil
+	}
+	return p
+}
+
+// EXIF tags written in IFD0
+const (
+	exifImageDescription = 0x010e
+	exifSoftware         = 0x0131
+	exifDateTime         = 0x0132
+	exifTypeASCII        = 2
+)
+
+// exifHeader prefixes EXIF data in JPEG APP1 segments
+const exifHeader = "Exif\x00\x00"
+
+// exifTIFF returns little-endian EXIF (TIFF) data with p in ImageDescription, Software and DateTime
+func exifTIFF(p *Provenance) []byte {
+	type tag struct {
+		id    uint16
+		value string
+	}
+	tags := []tag{{exifImageDescription, p.description()}, {exifSoftware, provenanceSoftware}}
+	if t, err := time.Parse(time.RFC3339, p.CreatedAt); err == nil {
+		tags = append(tags, tag{exifDateTime, t.UTC().Format("2006:01:02 15:04:05")})
+	}
+
+	const ifdOffset = 8
+	dataOffset := ifdOffset + 2 + 12*len(tags) + 4
+	out := []byte("II*\x00")
+	out = binary.LittleEndian.AppendUint32(out, ifdOffset)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(tags)))
+	var values []byte
+	for _, t := range tags {
+		value := append([]byte(t.value), 0)
+		out = binary.LittleEndian.AppendUint16(out, t.id)
+		out = binary.LittleEndian.AppendUint16(out, exifTypeASCII)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(value)))
+		if len(value) <= 4 {
+			// Values of up to four bytes are stored in the entry itself
+			out = append(out, append(value, make([]byte, 4-len(value))...)...)
+			continue
+		}
+		out = binary.LittleEndian.AppendUint32(out, uint32(dataOffset+len(values)))
+		values = append(values, value...)
+		if len(values)%2 == 1 {
+			values = append(values, 0) // offsets are word aligned
+		}
+	}
+	out = binary.LittleEndian.AppendUint32(out, 0) // no next IFD
+	return append(out, values...)
+}
+
+// readEXIF returns the provenance in the ImageDescription of EXIF (TIFF) data, or nil
+func readEXIF(data []byte) *Provenance {
+	data = bytes.TrimPrefix(data, []byte(exifHeader))
+	if len(data) < 8 {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd+2 > len(data) {
+		return nil
+	}
+	count := int(order.Uint16(data[ifd:]))
+	for i := range count {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(data) {
+			return nil
+		}
+		if order.Uint16(data[entry:]) != exifImageDescription || order.Uint16(data[entry+2:]) != exifTypeASCII {
+			continue
+		}
+		n := int(order.Uint32(data[entry+4:]))
+		start := entry + 8
+		if n > 4 {
+			start = int(order.Uint32(data[entry+8:]))
+		}
+		if start+n > len(data) {
+			return nil
+		}
+		if p, ok := parseDescription(string(bytes.TrimRight(data[start:start+n], "\x00"))); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func embedJPEG(data []byte, p *Provenance) []byte {
+	pos := 2 // after SOI
+	// JFIF readers expect APP0 right after SOI: the EXIF segment follows it
+	if len(data) >= pos+4 && data[pos] == 0xff && data[pos+1] == 0xe0 {
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+	}
+	payload := append([]byte(exifHeader), exifTIFF(p)...)
+	if pos > len(data) || len(payload)+2 > 0xffff {
+		return data
+	}
+	out := append([]byte(nil), data[:pos]...)
+	out = append(out, 0xff, 0xe1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	out = append(out, payload...)
+	return append(out, data[pos:]...)
+}
+
+func readJPEG(data []byte) *Provenance {
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xff; {
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			break // start of scan or end of image: metadata comes before
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			break
+		}
+		if segment := data[pos+4 : end]; marker == 0xe1 && bytes.HasPrefix(segment, []byte(exifHeader)) {
+			if p := readEXIF(segment); p != nil {
+				return p
+			}
+		}
+		pos = end
+	}
+	return nil
+}
+
+// webpChunk is a chunk of a RIFF WebP file
+type webpChunk struct {
+	fourCC string
+	data   []byte
+}
+
+func webpChunks(data []byte) ([]webpChunk, bool) {
+	var chunks []webpChunk
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, false
+		}
+		n := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+n > len(data) {
+			return nil, false
+		}
+		chunks = append(chunks, webpChunk{string(data[pos : pos+4]), data[pos+8 : pos+8+n]})
+		pos += 8 + n + n&1
+	}
+	return chunks, len(chunks) > 0
+}
+
+func embedWebP(data []byte, p *Provenance) []byte {
+	chunks, ok := webpChunks(data)
+	if !ok {
+		return data
+	}
+	// EXIF needs the extended format: simple files get a VP8X chunk with the canvas size
+	const exifFlag = 0x08
+	first := chunks[0]
+	switch {
+	case first.fourCC == "VP8X" && len(first.data) >= 10:
+		for _, c := range chunks {
+			if c.fourCC == "EXIF" {
+				return data
+			}
+		}
+		flags := append([]byte(nil), first.data...)
+		flags[0] |= exifFlag
+		chunks[0].data = flags
+	case first.fourCC == "VP8L" && len(first.data) >= 5:
+		// The alpha flag stays unset: VP8L carries its own alpha, and x/image/webp rejects VP8L
+		// images whose VP8X chunk announces alpha
+		bits := binary.LittleEndian.Uint32(first.data[1:])
+		width, height := bits&0x3fff+1, bits>>14&0x3fff+1
+		chunks = append([]webpChunk{{"VP8X", vp8xData(exifFlag, width, height)}}, chunks...)
+	case first.fourCC == "VP8 " && len(first.data) >= 10:
+		width := uint32(binary.LittleEndian.Uint16(first.data[6:]) & 0x3fff)
+		height := uint32(binary.LittleEndian.Uint16(first.data[8:]) & 0x3fff)
+		chunks = append([]webpChunk{{"VP8X", vp8xData(exifFlag, width, height)}}, chunks...)
+	default:
+		return data
+	}
+	chunks = append(chunks, webpChunk{"EXIF", exifTIFF(p)})
+
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		out = append(out, c.fourCC...)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(c.data)))
+		out = append(out, c.data...)
+		if len(c.data)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// vp8xData returns the payload of a VP8X chunk
+func vp8xData(flags byte, width, height uint32) []byte {
+	out := []byte{flags, 0, 0, 0}
+	out = append(out, byte(width-1), byte((width-1)>>8), byte((width-1)>>16))
+	return append(out, byte(height-1), byte((height-1)>>8), byte((height-1)>>16))
+}
+
+func readWebP(data []byte) *Provenance {
+	chunks, _ := webpChunks(data)
+	for _, c := range chunks {
+		if c.fourCC == "EXIF" {
+			return readEXIF(c.data)
+		}
+	}
+	return nil
+}
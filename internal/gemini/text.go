@@ -0,0 +1,33 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultTextModel is the text model used when banago.yaml sets no text_model
+const DefaultTextModel = "gemini-2.5-flash"
+
+// GenerateJSON sends a text-only prompt and returns the model's answer, constrained to a JSON document
+func (c *Client) GenerateJSON(ctx context.Context, model, prompt string) (string, error) {
+	start := time.Now()
+	resp, err := c.client.Models.GenerateContent(ctx, model, genai.Text(prompt), &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+	})
+	logCall(ctx, "generate_json", model, start, err)
+	if err != nil {
+		return "", classifyError(ctx, err)
+	}
+	if reason := blockReason(resp); reason != "" {
+		return "", fmt.Errorf("%w: %s", ErrSafetyBlocked, reason)
+	}
+	text := resp.Text()
+	if text == "" {
+		return "", errors.New("no text response found")
+	}
+	return text, nil
+}
@@ -2,9 +2,39 @@ package gemini
 
 // TokenUsage contains token usage information from API response
 type TokenUsage struct {
-	Prompt     int `yaml:"prompt"`
-	Candidates int `yaml:"candidates"`
-	Total      int `yaml:"total"`
-	Cached     int `yaml:"cached,omitempty"`
-	Thoughts   int `yaml:"thoughts,omitempty"`
+	Prompt     int `yaml:"prompt" json:"prompt"`
+	Candidates int `yaml:"candidates" json:"candidates"`
+	Total      int `yaml:"total" json:"total"`
+	Cached     int `yaml:"cached,omitempty" json:"cached,omitempty"`
+	Thoughts   int `yaml:"thoughts,omitempty" json:"thoughts,omitempty"`
+}
+
+// Add accumulates other into u
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.Prompt += other.Prompt
+	u.Candidates += other.Candidates
+	u.Total += other.Total
+	u.Cached += other.Cached
+	u.Thoughts += other.Thoughts
+}
+
+// TokenBreakdown is the approximate prompt token contribution of the text and of each input image
+type TokenBreakdown struct {
+	Text   int           `yaml:"text" json:"text"`
+	Inputs []InputTokens `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+}
+
+// InputTokens is the token contribution of a single input image
+type InputTokens struct {
+	Name   string `yaml:"name" json:"name"`
+	Tokens int    `yaml:"tokens" json:"tokens"`
+}
+
+// Total returns the sum of all contributions
+func (b TokenBreakdown) Total() int {
+	total := b.Text
+	for _, in := range b.Inputs {
+		total += in.Tokens
+	}
+	return total
 }
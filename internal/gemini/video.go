@@ -0,0 +1,151 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/genai"
+)
+
+// DefaultVideoModel is the Veo model used when banago.yaml sets no video_model
+const DefaultVideoModel = "veo-3.0-generate-001"
+
+// videoPollInterval is how often a running video operation is polled
+const videoPollInterval = 10 * time.Second
+
+// VideoParams holds parameters for video generation
+type VideoParams struct {
+	Model           string
+	Prompt          string
+	ImagePath       string // optional first frame
+	AspectRatio     string
+	Resolution      string // e.g. 720p, 1080p
+	DurationSeconds int
+}
+
+// Video is a generated video
+type Video struct {
+	Data     []byte
+	MIMEType string
+}
+
+// VideoResult holds the result of video generation
+type VideoResult struct {
+	Videos []Video
+	Error  error
+}
+
+// GenerateVideo starts a Veo generation, waits for the long-running operation to finish and downloads the videos
+func (c *Client) GenerateVideo(ctx context.Context, params VideoParams) *VideoResult {
+	var image *genai.Image
+	if params.ImagePath != "" {
+		part, err := ImagePartFromFile(params.ImagePath)
+		if err != nil {
+			return &VideoResult{Error: err}
+		}
+		image = &genai.Image{ImageBytes: part.InlineData.Data, MIMEType: part.InlineData.MIMEType}
+	}
+
+	vcfg := &genai.GenerateVideosConfig{
+		AspectRatio: params.AspectRatio,
+		Resolution:  params.Resolution,
+	}
+	if params.DurationSeconds > 0 {
+		d := int32(params.DurationSeconds)
+		vcfg.DurationSeconds = &d
+	}
+
+	start := time.Now()
+	op, err := c.client.Models.GenerateVideos(ctx, params.Model, params.Prompt, image, vcfg)
+	logCall(ctx, "generate_videos", params.Model, start, err)
+	if err != nil {
+		return &VideoResult{Error: classifyError(ctx, err)}
+	}
+	for polls := 1; !op.Done; polls++ {
+		select {
+		case <-ctx.Done():
+			return &VideoResult{Error: canceledError(ctx)}
+		case <-time.After(videoPollInterval):
+		}
+		slog.DebugContext(ctx, "polling video operation", "model", params.Model, "poll", polls, "elapsed", time.Since(start))
+		op, err = c.client.Operations.GetVideosOperation(ctx, op, nil)
+		if err != nil {
+			return &VideoResult{Error: fmt.Errorf("failed to poll video operation: %w", classifyError(ctx, err))}
+		}
+	}
+	if op.Error != nil {
+		return &VideoResult{Error: fmt.Errorf("video generation failed: %v", op.Error["message"])}
+	}
+	if op.Response == nil || len(op.Response.GeneratedVideos) == 0 {
+		if op.Response != nil && op.Response.RAIMediaFilteredCount > 0 {
+			return &VideoResult{Error: fmt.Errorf("%w: %s", ErrSafetyBlocked, strings.Join(op.Response.RAIMediaFilteredReasons, "; "))}
+		}
+		return &VideoResult{Error: errors.New("no video response found")}
+	}
+
+	result := &VideoResult{}
+	for _, gv := range op.Response.GeneratedVideos {
+		if gv == nil || gv.Video == nil {
+			continue
+		}
+		data := gv.Video.VideoBytes
+		if len(data) == 0 {
+			downloadStart := time.Now()
+			data, err = c.client.Files.Download(ctx, genai.NewDownloadURIFromGeneratedVideo(gv), nil)
+			logCall(ctx, "download_video", params.Model, downloadStart, err, "bytes", len(data))
+			if err != nil {
+				return &VideoResult{Error: fmt.Errorf("failed to download video: %w", classifyError(ctx, err))}
+			}
+		}
+		result.Videos = append(result.Videos, Video{Data: data, MIMEType: gv.Video.MIMEType})
+	}
+	return result
+}
+
+// SaveVideos saves generated videos to the specified directory
+func SaveVideos(videos []Video, dir string) ([]string, error) {
+	if len(videos) == 0 {
+		return nil, errors.New("no video response found")
+	}
+	runID := uuid.Must(uuid.NewV7())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var saved []string
+	for i, v := range videos {
+		fullPath, err := writeOutput(dir, OutputFileName(runID, i+1, videoExt(v.MIMEType)), v.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save video: %w", err)
+		}
+		saved = append(saved, fullPath)
+	}
+	return saved, nil
+}
+
+// IsVideoFile reports whether a filename has a video extension
+func IsVideoFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mp4", ".webm", ".mov":
+		return true
+	}
+	return false
+}
+
+// videoExt returns the file extension for a video MIME type (mp4 unless known otherwise)
+func videoExt(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "video/webm":
+		return ".webm"
+	case "video/quicktime":
+		return ".mov"
+	}
+	return ".mp4"
+}
@@ -0,0 +1,277 @@
+package gemini
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// The encoder below writes lossless WebP (VP8L): the subtract-green transform followed by one
+// Huffman code per color channel built from the image's histograms, without LZ77 back references.
+// It is not as small as libwebp's output but decodes everywhere and needs no cgo.
+
+const (
+	vp8lSignature     = 0x2f
+	vp8lMaxDimension  = 1 << 14
+	vp8lSubtractGreen = 2
+	vp8lMaxCodeLength = 15
+	vp8lMaxCLCLength  = 7 // code lengths of the code length code are written in 3 bits
+	vp8lGreenAlphabet = 256 + 24
+	vp8lDistAlphabet  = 40
+)
+
+// vp8lCodeLengthOrder is the order the code length code lengths are written in
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// bitWriter writes bits least significant first, as VP8L reads them
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (b *bitWriter) write(value uint32, n uint) {
+	b.acc |= uint64(value) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nbits = 0, 0
+	}
+	return b.buf
+}
+
+// huffmanCode holds the canonical code of each symbol of an alphabet, bits reversed for writing
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint32
+	single  bool // only one symbol is used: it takes no bits
+}
+
+func (c *huffmanCode) writeSymbol(b *bitWriter, symbol int) {
+	if c.single {
+		return
+	}
+	b.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// newHuffmanCode builds a length-limited canonical Huffman code from symbol counts
+func newHuffmanCode(counts []int, maxLength int) *huffmanCode {
+	c := &huffmanCode{lengths: huffmanLengths(counts, maxLength), codes: make([]uint32, len(counts))}
+	used := 0
+	for _, l := range c.lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	c.single = used <= 1
+
+	// Canonical codes as in DEFLATE: shorter codes first, then by symbol
+	var blCount [vp8lMaxCodeLength + 1]uint32
+	for _, l := range c.lengths {
+		blCount[l]++
+	}
+	blCount[0] = 0
+	var next [vp8lMaxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + blCount[l-1]) << 1
+		next[l] = code
+	}
+	for sym, l := range c.lengths {
+		if l == 0 {
+			continue
+		}
+		c.codes[sym] = reverseBits(next[l], uint(l))
+		next[l]++
+	}
+	return c
+}
+
+func reverseBits(v uint32, n uint) uint32 {
+	r := uint32(0)
+	for range n {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// huffmanLengths returns Huffman code lengths of at most maxLength for counts. A lone used symbol gets
+// length 1. Counts are halved until the tree is shallow enough.
+func huffmanLengths(counts []int, maxLength int) []uint8 {
+	counts = append([]int(nil), counts...)
+	for {
+		lengths, depth := huffmanTree(counts)
+		if depth <= maxLength {
+			return lengths
+		}
+		for i, n := range counts {
+			if n > 0 {
+				counts[i] = max(n/2, 1)
+			}
+		}
+	}
+}
+
+// huffmanTree returns the code lengths of the Huffman tree of counts and its depth
+func huffmanTree(counts []int) ([]uint8, int) {
+	type node struct {
+		count       int
+		symbol      int // leaf symbol, -1 for inner nodes
+		left, right int
+	}
+	var nodes []node
+	var queue []int
+	for sym, n := range counts {
+		if n > 0 {
+			nodes = append(nodes, node{count: n, symbol: sym})
+			queue = append(queue, len(nodes)-1)
+		}
+	}
+	lengths := make([]uint8, len(counts))
+	switch len(queue) {
+	case 0:
+		return lengths, 0
+	case 1:
+		lengths[nodes[0].symbol] = 1
+		return lengths, 1
+	}
+
+	for len(queue) > 1 {
+		// The alphabets are small enough to sort instead of keeping a heap
+		sort.SliceStable(queue, func(i, j int) bool { return nodes[queue[i]].count < nodes[queue[j]].count })
+		a, b := queue[0], queue[1]
+		nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, symbol: -1, left: a, right: b})
+		queue = append(queue[2:], len(nodes)-1)
+	}
+
+	depth := 0
+	var walk func(n, d int)
+	walk = func(n, d int) {
+		if nodes[n].symbol >= 0 {
+			lengths[nodes[n].symbol] = uint8(d)
+			depth = max(depth, d)
+			return
+		}
+		walk(nodes[n].left, d+1)
+		walk(nodes[n].right, d+1)
+	}
+	walk(queue[0], 0)
+	return lengths, depth
+}
+
+// writeHuffmanCode writes the code lengths of c
+func writeHuffmanCode(b *bitWriter, c *huffmanCode) {
+	if c.single {
+		// Simple code with one 8-bit symbol
+		sym := 0
+		for s, l := range c.lengths {
+			if l > 0 {
+				sym = s
+			}
+		}
+		b.write(1, 1) // simple code
+		b.write(0, 1) // one symbol
+		b.write(1, 1) // 8-bit symbol
+		b.write(uint32(sym), 8)
+		return
+	}
+
+	b.write(0, 1) // normal code
+	var clCounts [19]int
+	for _, l := range c.lengths {
+		clCounts[l]++
+	}
+	clCode := newHuffmanCode(clCounts[:], vp8lMaxCLCLength)
+	b.write(uint32(len(vp8lCodeLengthOrder)-4), 4)
+	for _, sym := range vp8lCodeLengthOrder {
+		b.write(uint32(clCode.lengths[sym]), 3)
+	}
+	b.write(0, 1) // max_symbol is the alphabet size
+	for _, l := range c.lengths {
+		clCode.writeSymbol(b, int(l))
+	}
+}
+
+// EncodeWebP writes img as a lossless WebP image
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errors.New("webp: image size out of range")
+	}
+
+	pixels := make([][4]uint8, 0, width*height) // green, red - green, blue - green, alpha
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			hasAlpha = hasAlpha || c.A != 0xff
+			pixels = append(pixels, [4]uint8{c.G, c.R - c.G, c.B - c.G, c.A})
+		}
+	}
+
+	counts := [4][]int{make([]int, vp8lGreenAlphabet), make([]int, 256), make([]int, 256), make([]int, 256)}
+	for _, p := range pixels {
+		for i, v := range p {
+			counts[i][v]++
+		}
+	}
+	var codes [4]*huffmanCode
+	for i := range codes {
+		codes[i] = newHuffmanCode(counts[i], vp8lMaxCodeLength)
+	}
+
+	var b bitWriter
+	b.write(vp8lSignature, 8)
+	b.write(uint32(width-1), 14)
+	b.write(uint32(height-1), 14)
+	alpha := uint32(0)
+	if hasAlpha {
+		alpha = 1
+	}
+	b.write(alpha, 1)
+	b.write(0, 3) // version
+	b.write(1, 1) // a transform follows
+	b.write(vp8lSubtractGreen, 2)
+	b.write(0, 1) // no more transforms
+	b.write(0, 1) // no color cache
+	b.write(0, 1) // a single prefix code group
+	for _, c := range codes {
+		writeHuffmanCode(&b, c)
+	}
+	writeHuffmanCode(&b, newHuffmanCode(make([]int, vp8lDistAlphabet), vp8lMaxCodeLength))
+	for _, p := range pixels {
+		for i, v := range p {
+			codes[i].writeSymbol(&b, int(v))
+		}
+	}
+	data := b.bytes()
+
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if chunkSize&1 == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
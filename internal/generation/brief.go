@@ -0,0 +1,73 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+)
+
+// TextGenerator defines the interface for text models answering with JSON.
+// gemini.Client implements it; tests use mocks.
+type TextGenerator interface {
+	GenerateJSON(ctx context.Context, model, prompt string) (string, error)
+}
+
+// SubprojectDraft is what a text model drafts for a new subproject from a free-form brief
+type SubprojectDraft struct {
+	Description string `json:"description"`
+	Context     string `json:"context"`      // context.md: scene, costume, pose, background
+	Prompt      string `json:"prompt"`       // starting prompt.txt
+	AspectRatio string `json:"aspect_ratio"` // suggested config.yaml field, empty if none
+	ImageSize   string `json:"image_size"`   // suggested config.yaml field, empty if none
+
+	// Suggestions that were not valid config values and were dropped
+	Dropped []string `json:"-"`
+}
+
+// briefInstructions tells the text model how to turn a brief into a SubprojectDraft
+const briefInstructions = `You prepare a subproject of banago, a tool generating images with an image model.
+From the brief below, draft a JSON object with these string fields:
+- "description": one line describing the subproject
+- "context": Markdown for context.md: the scene, costume, pose, expression, background and any specific requirements stated in the brief, under headings
+- "prompt": a starting image generation prompt of a few sentences
+- "aspect_ratio": width:height such as 1:1, 16:9, 9:16, 4:3 or 3:4, or "" if the brief does not suggest one
+- "image_size": 1K, 2K or 4K, or "" if the brief does not suggest one
+Only use information from the brief. Do not invent characters, names or details it does not mention.
+
+Brief:
+%s`
+
+// DraftSubproject asks a text model to draft the context, starting prompt and config fields of a
+// subproject from brief. Suggested config values that are not valid are dropped and listed in Dropped.
+func DraftSubproject(ctx context.Context, generator TextGenerator, model, brief string) (*SubprojectDraft, error) {
+	brief = strings.TrimSpace(brief)
+	if brief == "" {
+		return nil, errors.New("brief is empty")
+	}
+
+	answer, err := generator.GenerateJSON(ctx, model, fmt.Sprintf(briefInstructions, brief))
+	if err != nil {
+		return nil, fmt.Errorf("failed to draft subproject: %w", err)
+	}
+
+	var draft SubprojectDraft
+	if err := json.Unmarshal([]byte(answer), &draft); err != nil {
+		return nil, fmt.Errorf("failed to parse drafted subproject: %w", err)
+	}
+	if strings.TrimSpace(draft.Context) == "" || strings.TrimSpace(draft.Prompt) == "" {
+		return nil, errors.New("drafted subproject has no context or prompt")
+	}
+	if err := config.ValidateAspectRatio(draft.AspectRatio); err != nil {
+		draft.Dropped = append(draft.Dropped, "aspect_ratio "+draft.AspectRatio)
+		draft.AspectRatio = ""
+	}
+	if err := config.ValidateImageSize(draft.ImageSize); err != nil {
+		draft.Dropped = append(draft.Dropped, "image_size "+draft.ImageSize)
+		draft.ImageSize = ""
+	}
+	return &draft, nil
+}
@@ -0,0 +1,74 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTextGenerator is a mock implementation of TextGenerator for testing.
+type mockTextGenerator struct {
+	answer  string
+	err     error
+	prompts []string
+}
+
+// GenerateJSON implements TextGenerator.
+func (m *mockTextGenerator) GenerateJSON(_ context.Context, _, prompt string) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.answer, m.err
+}
+
+func TestDraftSubproject(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parses the draft", func(t *testing.T) {
+		t.Parallel()
+		mock := &mockTextGenerator{answer: `{"description":"Beach poster","context":"# Scene\nA beach at dusk","prompt":"A girl on a beach at dusk","aspect_ratio":"9:16","image_size":"2K"}`}
+		draft, err := DraftSubproject(context.Background(), mock, "text-test", "  summer poster, a girl on a beach at dusk, portrait  ")
+		require.NoError(t, err)
+
+		assert.Equal(t, "Beach poster", draft.Description)
+		assert.Equal(t, "# Scene\nA beach at dusk", draft.Context)
+		assert.Equal(t, "A girl on a beach at dusk", draft.Prompt)
+		assert.Equal(t, "9:16", draft.AspectRatio)
+		assert.Equal(t, "2K", draft.ImageSize)
+		assert.Empty(t, draft.Dropped)
+		require.Len(t, mock.prompts, 1)
+		assert.Contains(t, mock.prompts[0], "Brief:\nsummer poster, a girl on a beach at dusk, portrait")
+	})
+
+	t.Run("drops invalid config suggestions", func(t *testing.T) {
+		t.Parallel()
+		mock := &mockTextGenerator{answer: `{"context":"c","prompt":"p","aspect_ratio":"portrait","image_size":"8K"}`}
+		draft, err := DraftSubproject(context.Background(), mock, "text-test", "brief")
+		require.NoError(t, err)
+
+		assert.Empty(t, draft.AspectRatio)
+		assert.Empty(t, draft.ImageSize)
+		assert.Equal(t, []string{"aspect_ratio portrait", "image_size 8K"}, draft.Dropped)
+	})
+
+	t.Run("rejects drafts without context or prompt", func(t *testing.T) {
+		t.Parallel()
+		_, err := DraftSubproject(context.Background(), &mockTextGenerator{answer: `{"context":"c"}`}, "text-test", "brief")
+		assert.Error(t, err)
+		_, err = DraftSubproject(context.Background(), &mockTextGenerator{answer: "not json"}, "text-test", "brief")
+		assert.Error(t, err)
+	})
+
+	t.Run("fails on an empty brief or an API error", func(t *testing.T) {
+		t.Parallel()
+		mock := &mockTextGenerator{}
+		_, err := DraftSubproject(context.Background(), mock, "text-test", " \n")
+		assert.Error(t, err)
+		assert.Empty(t, mock.prompts)
+
+		apiErr := errors.New("quota")
+		_, err = DraftSubproject(context.Background(), &mockTextGenerator{err: apiErr}, "text-test", "brief")
+		assert.ErrorIs(t, err, apiErr)
+	})
+}
@@ -0,0 +1,51 @@
+package generation
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+)
+
+// applyTokenBudget drops the lowest-priority (last) input images until the estimated prompt tokens
+// fit spec.TokenBudget, with prompt the text sent. At least one input is always kept. It returns the
+// names of the omitted inputs.
+func applyTokenBudget(spec *Spec, prompt string, w io.Writer) []string {
+	if spec.TokenBudget <= 0 {
+		return nil
+	}
+
+	estimate := func() int {
+		return gemini.EstimatePromptTokens(len(prompt), len(spec.ImagePaths))
+	}
+	before := estimate()
+	if before <= spec.TokenBudget {
+		return nil
+	}
+
+	var omitted []string
+	for len(spec.ImagePaths) > 1 && estimate() > spec.TokenBudget {
+		last := spec.ImagePaths[len(spec.ImagePaths)-1]
+		spec.ImagePaths = spec.ImagePaths[:len(spec.ImagePaths)-1]
+
+		name := filepath.Base(last)
+		spec.InputImageNames = slices.DeleteFunc(slices.Clone(spec.InputImageNames), func(n string) bool {
+			return filepath.Base(n) == name
+		})
+		omitted = append(omitted, name)
+	}
+
+	_, _ = fmt.Fprintf(w, "Token budget: estimated %d prompt tokens exceeds budget %d\n", before, spec.TokenBudget)
+	if len(omitted) > 0 {
+		slices.Reverse(omitted)
+		_, _ = fmt.Fprintf(w, "  Omitted inputs: %s (now ~%d tokens)\n", strings.Join(omitted, ", "), estimate())
+	}
+	if estimate() > spec.TokenBudget {
+		_, _ = fmt.Fprintln(w, "  Warning: still over budget with a single input image")
+	}
+	_, _ = fmt.Fprintln(w, "")
+	return omitted
+}
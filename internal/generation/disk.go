@@ -0,0 +1,71 @@
+package generation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientDisk is returned when the outputs of a run would not fit on the disk
+var ErrInsufficientDisk = errors.New("not enough disk space")
+
+// estimatedOutputBytes is a generous size of one saved output image (PNG) per image size
+var estimatedOutputBytes = map[string]int64{
+	"":   2 << 20, // API default (1K)
+	"1K": 2 << 20,
+	"2K": 8 << 20,
+	"4K": 32 << 20,
+}
+
+// estimateOutputBytes estimates the disk space count generations need for their outputs.
+// A raw response holds the images again, base64-encoded.
+func estimateOutputBytes(size string, count int, rawResponse bool) int64 {
+	per, ok := estimatedOutputBytes[size]
+	if !ok {
+		per = estimatedOutputBytes["4K"]
+	}
+	if rawResponse {
+		per += per * 4 / 3
+	}
+	return per * int64(max(count, 1))
+}
+
+// checkDiskSpace refuses to start when the estimated outputs do not fit on the disk holding historyDir,
+// and warns when less than minFree bytes would remain. The check is skipped when free space is unknown.
+func checkDiskSpace(historyDir string, need, minFree int64, w io.Writer) error {
+	dir := existingParent(historyDir)
+	free, ok := diskFree(dir)
+	if !ok {
+		return nil
+	}
+	if free < need {
+		return fmt.Errorf("%w: about %d MB needed for the outputs, %d MB free in %s", ErrInsufficientDisk, need>>20, free>>20, dir)
+	}
+	if minFree > 0 && free-need < minFree {
+		_, _ = fmt.Fprintf(w, "Warning: low disk space: %d MB free in %s, about %d MB after this run (min_free_disk_mb: %d)\n",
+			free>>20, dir, (free-need)>>20, minFree>>20)
+	}
+	return nil
+}
+
+// existingParent returns dir or its nearest existing ancestor, so that the check works before the history directory exists
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// FreeDiskSpace returns the bytes available on the disk holding dir (or its nearest existing parent).
+// ok is false when free space cannot be determined on this platform.
+func FreeDiskSpace(dir string) (free int64, ok bool) {
+	return diskFree(existingParent(dir))
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin)
+
+package generation
+
+// diskFree reports that free space is unknown on platforms without statfs, which skips the disk space check
+func diskFree(string) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package generation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/history"
+)
+
+// maxEvaluationNotes caps the evaluator output recorded in meta.yaml, in bytes
+const maxEvaluationNotes = 4096
+
+// EvaluationPolicy runs external evaluator commands on each new entry, for project-specific checks
+// of the outputs. The zero value evaluates nothing.
+type EvaluationPolicy struct {
+	Commands   []string // command lines, each run with the entry directory appended as the last argument
+	Dir        string   // working directory of the commands
+	MarkFailed bool     // record rejected entries as failed
+	Retries    int      // generate again up to this many times while an evaluator rejects the entry
+	TmpDir     string   // each command gets a fresh directory in it as BANAGO_TMP_DIR and TMPDIR (empty: system default)
+}
+
+// evaluate runs every evaluator on entryDir and records the verdicts in entry. An evaluator passes
+// by exiting with status 0; what it prints on stdout becomes its notes, stderr goes to w.
+// It reports whether an evaluator rejected the entry.
+func (p EvaluationPolicy) evaluate(ctx context.Context, entry *history.Entry, entryDir string, w io.Writer) bool {
+	var rejected []string
+	outputDir := history.GetEntryOutputDir(entryDir, entry.Layout())
+	for _, command := range p.Commands {
+		evaluation := p.runEvaluator(ctx, command, entryDir, outputDir, w)
+		entry.Result.Evaluations = append(entry.Result.Evaluations, evaluation)
+		if evaluation.Passed {
+			_, _ = fmt.Fprintf(w, "Evaluation passed: %s\n", command)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "Evaluation failed: %s\n", command)
+		if evaluation.Notes != "" {
+			_, _ = fmt.Fprintf(w, "  %s\n", strings.ReplaceAll(evaluation.Notes, "\n", "\n  "))
+		}
+		rejected = append(rejected, command)
+	}
+	if len(rejected) == 0 {
+		return false
+	}
+	if p.MarkFailed {
+		entry.Result.Success = false
+		entry.Result.ErrorMessage = "rejected by evaluator: " + strings.Join(rejected, ", ")
+	}
+	return true
+}
+
+// runEvaluator runs one evaluator command line on entryDir, whose outputs are in outputDir
+func (p EvaluationPolicy) runEvaluator(ctx context.Context, command, entryDir, outputDir string, w io.Writer) history.Evaluation {
+	evaluation := history.Evaluation{Evaluator: command}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		evaluation.Notes = "empty evaluator command"
+		return evaluation
+	}
+
+	var stdout bytes.Buffer
+	c := exec.CommandContext(ctx, args[0], append(args[1:], entryDir)...)
+	c.Dir = p.Dir
+	c.Env = append(os.Environ(), "BANAGO_ENTRY_DIR="+entryDir, "BANAGO_OUTPUT_DIR="+outputDir)
+	if p.TmpDir != "" {
+		// Parallel runs share TmpDir, so every command gets its own directory, removed when it exits
+		if tmp, err := os.MkdirTemp(p.TmpDir, "eval-"); err == nil {
+			defer func() { _ = os.RemoveAll(tmp) }()
+			c.Env = append(c.Env, "BANAGO_TMP_DIR="+tmp, "TMPDIR="+tmp)
+		}
+	}
+	c.Stdout = &stdout
+	c.Stderr = w
+	start := time.Now()
+	err := c.Run()
+	slog.InfoContext(ctx, "evaluator finished", "command", command, "entry", filepath.Base(entryDir), "duration", time.Since(start), "error", err)
+
+	evaluation.Notes = strings.TrimSpace(stdout.String())
+	if len(evaluation.Notes) > maxEvaluationNotes {
+		evaluation.Notes = evaluation.Notes[:maxEvaluationNotes] + "..."
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		evaluation.Passed = true
+	case !errors.As(err, &exitErr):
+		// The evaluator could not run at all; a broken check must not pass entries
+		evaluation.Notes = strings.TrimSpace(fmt.Sprintf("failed to run evaluator: %v\n%s", err, evaluation.Notes))
+	}
+	return evaluation
+}
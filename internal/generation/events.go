@@ -0,0 +1,43 @@
+package generation
+
+import "time"
+
+// Event types reported while a generation or edit runs
+const (
+	EventValidation   = "validation"    // the request was checked; Error is set if it was rejected
+	EventRequestSent  = "request_sent"  // the API call started
+	EventImageSaved   = "image_saved"   // an output image was written
+	EventEntryCreated = "entry_created" // the history entry (or edit) was saved
+	EventEvaluated    = "evaluated"     // the evaluators ran on the entry; Error is set if one rejected it
+	EventError        = "error"         // the run failed after validation
+)
+
+// Event is a progress event of a generation or edit run
+type Event struct {
+	Type    string `json:"type"`
+	Time    string `json:"time"`
+	EntryID string `json:"entry_id,omitempty"`
+	EditID  string `json:"edit_id,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Image   string `json:"image,omitempty"` // path of a saved image
+	Error   string `json:"error,omitempty"`
+}
+
+// EventSink receives progress events.
+// It must be safe for concurrent use because RunParallel runs generations in parallel.
+type EventSink func(Event)
+
+// WithEvents makes the service report progress events to sink and returns the service
+func (s *Service) WithEvents(sink EventSink) *Service {
+	s.events = sink
+	return s
+}
+
+// emit reports an event if a sink is set
+func (s *Service) emit(e Event) {
+	if s.events == nil {
+		return
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	s.events(e)
+}
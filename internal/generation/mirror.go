@@ -0,0 +1,53 @@
+package generation
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/platform"
+)
+
+// MirrorPolicy mirrors new outputs into a flat date folder besides the history entry,
+// for tools that handle a simple folder better than the UUID tree.
+// The zero value mirrors nothing.
+type MirrorPolicy struct {
+	Dir     string // root of the YYYY-MM-DD folders (empty to skip)
+	Symlink bool   // link to the history files instead of copying them
+}
+
+// mirror places the saved outputs in today's folder. Failures only produce a warning
+// because the history entry is already complete.
+func (p MirrorPolicy) mirror(saved []string, w io.Writer) {
+	if p.Dir == "" || len(saved) == 0 {
+		return
+	}
+	dir := filepath.Join(p.Dir, time.Now().Format(time.DateOnly))
+	if err := p.mirrorTo(dir, saved); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to mirror outputs: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Mirrored to: %s\n", dir)
+}
+
+func (p MirrorPolicy) mirrorTo(dir string, saved []string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, src := range saved {
+		dst := filepath.Join(dir, filepath.Base(src))
+		if p.Symlink {
+			// Falls back to a copy where the user may not create symlinks (Windows without Developer Mode)
+			if _, err := platform.LinkOrCopy(src, dst); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := platform.CopyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ type mockGenerator struct {
 	responseMIME   string            // MIME type for response images (default: image/png)
 	tokenUsage     gemini.TokenUsage // Token usage to return
 	err            error             // Error to return (if set, overrides success response)
+	onGenerate     func()            // Called during Generate, e.g. to change the file system mid-run
 
 	// Recording fields
 	calls []gemini.Params // Records all Generate calls
@@ -31,6 +32,9 @@ func (m *mockGenerator) Generate(_ context.Context, params gemini.Params) *gemin
 	defer m.mu.Unlock()
 
 	m.calls = append(m.calls, params)
+	if m.onGenerate != nil {
+		m.onGenerate()
+	}
 
 	if m.err != nil {
 		return &gemini.Result{Error: m.err}
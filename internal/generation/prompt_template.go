@@ -0,0 +1,86 @@
+package generation
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/history"
+)
+
+// PromptTemplate is a prompt written as a Go text/template, rendered anew for every generation so it
+// can vary with the date, the entry number or a random pick without being edited by hand.
+//
+// Templates see PromptData as dot and can call:
+//
+//	date "Jan 2, 2006"             today's local date in a Go time layout
+//	choice "ink" "watercolor"      one of the arguments at random
+//	cycle "front" "side" "back"    the arguments in turn, advancing with .Counter
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// PromptData is what a prompt template is rendered with
+type PromptData struct {
+	Counter int    // number of the entry being generated in its history directory, starting at 1
+	Date    string // today's local date, YYYY-MM-DD
+}
+
+// ParsePromptTemplate parses text as a prompt template
+func ParsePromptTemplate(text string) (*PromptTemplate, error) {
+	tmpl, err := template.New("prompt").Funcs(promptFuncs(PromptData{})).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return &PromptTemplate{tmpl: tmpl}, nil
+}
+
+// Render executes the template with data
+func (t *PromptTemplate) Render(data PromptData) (string, error) {
+	// cycle depends on the counter, so each render binds its own functions
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Funcs(promptFuncs(data)).Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	prompt := strings.TrimSpace(b.String())
+	if prompt == "" {
+		return "", errors.New("prompt template rendered an empty prompt")
+	}
+	return prompt, nil
+}
+
+// render renders the template for the entry ahead entries after the next one of historyDir
+func (t *PromptTemplate) render(historyDir string, ahead int) (string, error) {
+	entries, err := history.ListEntries(historyDir)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(PromptData{Counter: len(entries) + 1 + ahead, Date: time.Now().Format(time.DateOnly)})
+}
+
+func promptFuncs(data PromptData) template.FuncMap {
+	return template.FuncMap{
+		"date": func(layout string) string {
+			return time.Now().Format(layout)
+		},
+		"choice": func(items ...string) (string, error) {
+			if len(items) == 0 {
+				return "", errors.New("choice needs at least one argument")
+			}
+			return items[rand.IntN(len(items))], nil
+		},
+		"cycle": func(items ...string) (string, error) {
+			if len(items) == 0 {
+				return "", errors.New("cycle needs at least one argument")
+			}
+			return items[max(data.Counter-1, 0)%len(items)], nil
+		},
+	}
+}
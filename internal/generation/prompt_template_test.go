@@ -0,0 +1,47 @@
+package generation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptTemplate_Render(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		text    string
+		data    PromptData
+		want    string
+		wantErr string
+	}{
+		{name: "plain text", text: "a fox", want: "a fox"},
+		{name: "fields", text: "#{{.Counter}} on {{.Date}}", data: PromptData{Counter: 7, Date: "2026-01-31"}, want: "#7 on 2026-01-31"},
+		{name: "cycle rotates with the counter", text: `{{cycle "a" "b" "c"}}`, data: PromptData{Counter: 5}, want: "b"},
+		{name: "choice of one", text: `{{choice "only"}}`, want: "only"},
+		{name: "date layout", text: `{{date "2006"}}`, want: time.Now().Format("2006")},
+		{name: "choice without items", text: `{{choice}}`, wantErr: "choice needs at least one argument"},
+		{name: "unknown field", text: `{{.Seed}}`, wantErr: "failed to render prompt template"},
+		{name: "empty result", text: `{{if false}}x{{end}}`, wantErr: "empty prompt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tmpl, err := ParsePromptTemplate(tt.text)
+			require.NoError(t, err)
+			got, err := tmpl.Render(tt.data)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParsePromptTemplate("{{.Counter")
+	require.ErrorContains(t, err, "invalid prompt template")
+}
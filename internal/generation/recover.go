@@ -0,0 +1,182 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"google.golang.org/genai"
+)
+
+// writeRetryDelays are the waits between attempts to write into the history directory.
+// Sync clients (OneDrive, Dropbox) and virus scanners briefly lock files they are processing.
+var writeRetryDelays = []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second}
+
+// recoveryDirName is the folder in the temp directory that receives outputs the history directory refused
+const recoveryDirName = "banago-recovered"
+
+// WithRecoveryDir sets where outputs are saved when they cannot be written to the history directory
+// (default: banago-recovered in the temp directory) and returns the service
+func (s *Service) WithRecoveryDir(dir string) *Service {
+	s.recoveryDir = dir
+	return s
+}
+
+// retryWrite runs write until it succeeds, fails with an error that retrying cannot fix, or the retries run out
+func retryWrite(ctx context.Context, write func() error) error {
+	err := write()
+	for attempt, delay := range writeRetryDelays {
+		if err == nil || !isTransientWriteError(err) {
+			return err
+		}
+		slog.WarnContext(ctx, "retrying history write", "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		err = write()
+	}
+	return err
+}
+
+// isTransientWriteError reports whether a write failed because a file was locked or briefly inaccessible
+func isTransientWriteError(err error) bool {
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EAGAIN) {
+		return true
+	}
+	// ERROR_SHARING_VIOLATION and ERROR_LOCK_VIOLATION: another process has the file open
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && (errno == 32 || errno == 33)
+}
+
+// saveImages writes the images of resp into dir converted to format and with prov embedded, retrying
+// while the directory is locked
+func saveImages(ctx context.Context, resp *genai.GenerateContentResponse, dir, format string, prov *gemini.Provenance) ([]string, error) {
+	var saved []string
+	err := retryWrite(ctx, func() error {
+		var err error
+		saved, err = gemini.SaveImagesAs(resp, dir, format, prov)
+		return err
+	})
+	return saved, err
+}
+
+// entryProvenance returns the provenance embedded in the outputs of a generation
+func entryProvenance(entry *history.Entry, spec Spec) *gemini.Provenance {
+	return &gemini.Provenance{
+		EntryID:    entry.ID,
+		Model:      spec.Model,
+		PromptHash: gemini.PromptHash(spec.Prompt),
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// editProvenance returns the provenance embedded in the outputs of an edit
+func editProvenance(editEntry *history.EditEntry, spec EditSpec) *gemini.Provenance {
+	return &gemini.Provenance{
+		EntryID:    spec.EntryID,
+		EditID:     editEntry.ID,
+		Model:      spec.Model,
+		PromptHash: gemini.PromptHash(spec.Prompt),
+		CreatedAt:  editEntry.CreatedAt,
+	}
+}
+
+// recoverOutputs is called when the outputs of a paid API call could not be written to dst.
+// save writes them below a recovery directory and returns the directory it created; the returned error
+// tells where the outputs went. Errors that are not file system errors (e.g. no image in the response)
+// are returned unchanged.
+func (s *Service) recoverOutputs(saveErr error, dst string, save func(recoveryDir string) (string, error), w io.Writer) error {
+	var pathErr *fs.PathError
+	if !errors.As(saveErr, &pathErr) {
+		return saveErr
+	}
+
+	recoveryDir := s.recoveryDir
+	if recoveryDir == "" {
+		recoveryDir = filepath.Join(os.TempDir(), recoveryDirName)
+	}
+	recovered, err := save(recoveryDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to save outputs to %s either: %v\n", recoveryDir, err)
+		return saveErr
+	}
+
+	_, _ = fmt.Fprintf(w, "Warning: could not write to %s: %v\n", dst, saveErr)
+	_, _ = fmt.Fprintf(w, "The outputs were saved to %s instead.\n", recovered)
+	_, _ = fmt.Fprintf(w, "Once %s is writable, move that directory into it to restore them.\n", dst)
+	return fmt.Errorf("%w (outputs recovered to %s)", saveErr, recovered)
+}
+
+// isResponseFailure reports whether saving outputs failed because of the response rather than the disk
+func isResponseFailure(err error) bool {
+	return errors.Is(err, gemini.ErrNoImage) || errors.Is(err, gemini.ErrSafetyBlocked)
+}
+
+// logFailure adds a failed API call to the failure log of historyDir. The log only feeds statistics,
+// so not being able to write it is a warning.
+func logFailure(historyDir string, f history.Failure, err error, w io.Writer) {
+	f.Category = gemini.FailureCategory(err)
+	f.Error = err.Error()
+	if err := history.RecordFailure(historyDir, f); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+	}
+}
+
+// recordResult stores the outputs and usage of a successful API call in entry
+func recordResult(entry *history.Entry, model string, saved []string, result *gemini.Result) {
+	entry.Result.Success = true
+	for _, path := range saved {
+		entry.Result.OutputImages = append(entry.Result.OutputImages, filepath.Base(path))
+	}
+	entry.Result.TokenUsage = result.TokenUsage
+	entry.Result.CostUSD, _ = gemini.EstimateCost(model, result.TokenUsage)
+}
+
+// recoverEntry writes entry with the outputs of result as a complete history entry below recoveryDir
+func recoverEntry(entry *history.Entry, spec Spec, result *gemini.Result, recoveryDir string) (string, error) {
+	entryDir := entry.GetEntryDir(recoveryDir)
+	saved, err := gemini.SaveImagesAs(result.Response, entry.OutputDir(recoveryDir), spec.OutputFormat, entryProvenance(entry, spec))
+	if err != nil {
+		return "", err
+	}
+	if err := entry.SavePrompt(recoveryDir, spec.Prompt); err != nil {
+		return "", err
+	}
+	if !spec.Archive.HashInputsOnly {
+		_ = entry.SaveInputImages(recoveryDir, spec.ImagePaths)
+	}
+	recordResult(entry, spec.Model, saved, result)
+	return entryDir, entry.Save(recoveryDir)
+}
+
+// recoverEdit writes editEntry with the outputs of result below <recoveryDir>/<entry ID>/edits/
+func recoverEdit(editEntry *history.EditEntry, spec EditSpec, result *gemini.Result, recoveryDir string) (string, error) {
+	entryDir := history.GetEntryDirByID(recoveryDir, spec.EntryID)
+	editDir := editEntry.GetEditEntryDir(entryDir)
+	saved, err := gemini.SaveImagesAs(result.Response, editDir, spec.OutputFormat, editProvenance(editEntry, spec))
+	if err != nil {
+		return "", err
+	}
+	if err := editEntry.SavePrompt(entryDir, spec.Prompt); err != nil {
+		return "", err
+	}
+	editEntry.Result.Success = true
+	for _, path := range saved {
+		editEntry.Result.OutputImages = append(editEntry.Result.OutputImages, filepath.Base(path))
+	}
+	editEntry.Result.TokenUsage = result.TokenUsage
+	editEntry.Result.CostUSD, _ = gemini.EstimateCost(spec.Model, result.TokenUsage)
+	return editDir, editEntry.Save(entryDir)
+}
@@ -1,14 +1,23 @@
 package generation
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
+	"math/rand/v2"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/tracing"
+	"google.golang.org/genai"
 )
 
 // Generator defines the interface for image generation.
@@ -17,10 +26,17 @@ type Generator interface {
 	Generate(ctx context.Context, params gemini.Params) *gemini.Result
 }
 
+// TokenCounter is implemented by generators that can measure prompt tokens without generating.
+type TokenCounter interface {
+	CountTokenBreakdown(ctx context.Context, params gemini.Params) (*gemini.TokenBreakdown, error)
+}
+
 // Result contains the output of a generation run.
 type Result struct {
 	EntryID      string
 	OutputImages []string
+	TokenUsage   gemini.TokenUsage
+	Rejected     bool // an evaluator rejected the entry (after any retries)
 }
 
 // EditResult contains the output of an edit operation.
@@ -31,7 +47,9 @@ type EditResult struct {
 
 // Service handles image generation with dependency injection support.
 type Service struct {
-	generator Generator
+	generator   Generator
+	events      EventSink // nil when progress events are not reported
+	recoveryDir string    // where outputs go when the history directory cannot be written (empty: temp directory)
 }
 
 // NewService creates a new Service with the given generator.
@@ -40,11 +58,157 @@ func NewService(generator Generator) *Service {
 }
 
 // Run executes the generation workflow and saves the result to history.
+// While an evaluator rejects the new entry and spec.Evaluation allows retries, it generates again,
+// recording each retry as regenerated from the rejected entry, and returns the last attempt.
 func (s *Service) Run(ctx context.Context, spec Spec, historyDir string, w io.Writer) (*Result, error) {
-	// Validate inputs before any work
-	if err := validateSpec(spec); err != nil {
+	result, err := s.logRun(ctx, spec, historyDir, w)
+	for attempt := 1; err == nil && result.Rejected && attempt <= spec.Evaluation.Retries; attempt++ {
+		_, _ = fmt.Fprintf(w, "\nRetrying rejected entry %s (%d/%d)\n", result.EntryID, attempt, spec.Evaluation.Retries)
+		slog.InfoContext(ctx, "retrying rejected entry", "entry", result.EntryID, "attempt", attempt, "retries", spec.Evaluation.Retries)
+		retry := spec
+		retry.SourceEntryID = result.EntryID
+		retry.Seed = nil // the same seed would reproduce the rejected output
+		result, err = s.logRun(ctx, retry, historyDir, w)
+	}
+	if err == nil && result.Rejected && spec.Evaluation.Retries > 0 {
+		_, _ = fmt.Fprintf(w, "Warning: entry %s is still rejected after %d retries\n", result.EntryID, spec.Evaluation.Retries)
+	}
+	return result, err
+}
+
+// logRun runs one generation attempt in its own span and logs its outcome and duration
+func (s *Service) logRun(ctx context.Context, spec Spec, historyDir string, w io.Writer) (*Result, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "generation.run", "model", spec.Model, "source", spec.SourceEntryID)
+	result, err := s.run(ctx, spec, historyDir, w)
+	if result != nil {
+		span.SetAttributes("entry", result.EntryID, "outputs", len(result.OutputImages), "rejected", result.Rejected)
+	}
+	span.End(err)
+	attrs := []any{"model", spec.Model, "source", spec.SourceEntryID, "duration", time.Since(start)}
+	if err != nil {
+		slog.WarnContext(ctx, "generation failed", append(attrs, "error", err)...)
+		return result, err
+	}
+	slog.InfoContext(ctx, "generation finished", append(attrs, "entry", result.EntryID, "outputs", len(result.OutputImages),
+		"tokens", result.TokenUsage.Total, "rejected", result.Rejected)...)
+	return result, nil
+}
+
+// DryRunResult is the request a run would send
+type DryRunResult struct {
+	Params   gemini.Params   // Seed is nil when the run would pick a random one
+	Omitted  []string        // input images the token budget leaves out
+	Estimate gemini.Estimate // of one generation, all candidates included
+}
+
+// DryRun resolves spec exactly as Run does and returns the request it would send, without calling
+// the API or writing history.
+func (s *Service) DryRun(ctx context.Context, spec Spec, historyDir string, w io.Writer) (*DryRunResult, error) {
+	params, omitted, err := s.prepare(ctx, &spec, historyDir, w)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{Params: params, Omitted: omitted, Estimate: estimateRequest(params)}, nil
+}
+
+// DryRunBatch is DryRun for RunBatch. With a prompt template or a seed every generation sends its
+// own request, each is resolved as its run would be and one result returned per generation;
+// otherwise the single result stands for all count generations.
+func (s *Service) DryRunBatch(ctx context.Context, spec Spec, count int, historyDir string, w io.Writer) ([]*DryRunResult, error) {
+	if count <= 1 || (spec.PromptTemplate == nil && spec.Seed == nil) {
+		result, err := s.DryRun(ctx, spec, historyDir, w)
+		if err != nil {
+			return nil, err
+		}
+		return []*DryRunResult{result}, nil
+	}
+
+	var results []*DryRunResult
+	for i := range count {
+		runSpec := spec
+		if spec.Seed != nil {
+			seed := *spec.Seed + int32(i)
+			runSpec.Seed = &seed
+		}
+		if spec.PromptTemplate != nil {
+			// Each earlier run of the batch would have added an entry
+			prompt, err := spec.PromptTemplate.render(historyDir, i)
+			if err != nil {
+				return nil, err
+			}
+			runSpec.Prompt, runSpec.PromptTemplate = prompt, nil
+		}
+		result, err := s.DryRun(ctx, runSpec, historyDir, w)
+		if err != nil {
+			return nil, fmt.Errorf("generation %d/%d: %w", i+1, count, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// estimateRequest estimates the tokens and cost of one API call with params
+func estimateRequest(params gemini.Params) gemini.Estimate {
+	est := gemini.EstimateGeneration(params.Model, len([]rune(params.Prompt)), len(params.ImagePaths), strings.ToUpper(params.ImageSize))
+	est.OutputTokens *= max(params.Candidates, 1)
+	est.USD, est.Known = gemini.EstimateCost(params.Model, gemini.TokenUsage{Prompt: est.InputTokens, Candidates: est.OutputTokens})
+	return est
+}
+
+// prepare resolves spec into the request a run sends: it renders the prompt template, validates the
+// inputs and disk space, prepends the context and applies the token budget, dropping inputs from
+// spec. It returns the parameters of the API call and the omitted inputs, and writes nothing.
+func (s *Service) prepare(ctx context.Context, spec *Spec, historyDir string, w io.Writer) (gemini.Params, []string, error) {
+	var err error
+	if spec.PromptTemplate != nil {
+		if spec.Prompt, err = spec.PromptTemplate.render(historyDir, 0); err != nil {
+			return gemini.Params{}, nil, err
+		}
+		_, _ = fmt.Fprintf(w, "Prompt: %s\n", spec.Prompt)
+	}
+
+	// Validate inputs and disk space before any work
+	_, span := tracing.Start(ctx, "generation.validate", "inputs", len(spec.ImagePaths))
+	err = validateSpec(*spec)
+	if err == nil {
+		err = checkDiskSpace(historyDir, estimateOutputBytes(spec.ImageSize, max(spec.Candidates, 1), spec.Archive.RawResponse), spec.MinFreeDisk, w)
+	}
+	prompt := spec.Prompt
+	if err == nil && spec.IncludeContext {
+		prompt, err = spec.Archive.withContext(spec.Prompt, w)
+	}
+	span.End(err)
+	if err != nil {
+		return gemini.Params{}, nil, err
+	}
+	omitted := applyTokenBudget(spec, prompt, w)
+	return gemini.Params{
+		Model:       spec.Model,
+		Prompt:      prompt,
+		ImagePaths:  spec.ImagePaths,
+		AspectRatio: spec.AspectRatio,
+		ImageSize:   spec.ImageSize,
+		Seed:        spec.Seed,
+		Candidates:  spec.Candidates,
+	}, omitted, nil
+}
+
+// run executes one generation attempt
+func (s *Service) run(ctx context.Context, spec Spec, historyDir string, w io.Writer) (*Result, error) {
+	params, omitted, err := s.prepare(ctx, &spec, historyDir, w)
+	if err != nil {
+		s.emit(Event{Type: EventValidation, Model: spec.Model, Error: err.Error()})
 		return nil, err
 	}
+	s.emit(Event{Type: EventValidation, Model: spec.Model})
+	seed := spec.Seed
+	if seed == nil {
+		// Pick the seed here rather than leaving it to the API so the entry can record it
+		random := rand.Int32()
+		seed = &random
+	}
+	params.Seed = seed
 
 	// Create history entry
 	var entry *history.Entry
@@ -55,63 +219,136 @@ func (s *Service) Run(ctx context.Context, spec Spec, historyDir string, w io.Wr
 	} else {
 		entry = history.NewEntry()
 	}
+	entry.SetLayout(spec.Layout)
 
+	entry.Generation.Model = spec.Model
+	entry.Generation.ModelOverride = spec.ModelOverride
 	entry.Generation.PromptFile = history.PromptFile
 	entry.Generation.InputImages = spec.InputImageNames
 	entry.Generation.AspectRatio = spec.AspectRatio
 	entry.Generation.ImageSize = spec.ImageSize
+	entry.Generation.OmittedInputs = omitted
+	entry.Generation.TextOnly = len(spec.ImagePaths) == 0
+	entry.Generation.Seed = seed
+	if spec.Candidates > 1 {
+		entry.Generation.Candidates = spec.Candidates
+	}
+	entry.Generation.Config = &history.ConfigSnapshot{
+		TokenBudget:       spec.TokenBudget,
+		AssembleAnimation: spec.AssembleAnimation,
+		IncludeContext:    spec.IncludeContext,
+	}
 
 	entryDir := entry.GetEntryDir(historyDir)
-
-	// Create history directory and save prompt
-	if err := os.MkdirAll(entryDir, 0o755); err != nil {
-		return nil, fmt.Errorf("failed to create history directory: %w", err)
-	}
-	if err := entry.SavePrompt(historyDir, spec.Prompt); err != nil {
-		return nil, fmt.Errorf("failed to save prompt: %w", err)
+	if err := s.saveSnapshot(ctx, entry, spec, historyDir, w); err != nil {
+		return nil, err
 	}
 
-	// Save input images
-	if err := entry.SaveInputImages(historyDir, spec.ImagePaths); err != nil {
-		_, _ = fmt.Fprintf(w, "Warning: failed to save input images: %v\n", err)
+	if spec.TokenBreakdown {
+		entry.Generation.TokenBreakdown = s.countTokenBreakdown(ctx, params, w)
 	}
 
 	// Call Gemini API
-	result := s.generator.Generate(ctx, gemini.Params{
-		Model:       spec.Model,
-		Prompt:      spec.Prompt,
-		ImagePaths:  spec.ImagePaths,
-		AspectRatio: spec.AspectRatio,
-		ImageSize:   spec.ImageSize,
-	})
+	s.emit(Event{Type: EventRequestSent, EntryID: entry.ID, Model: spec.Model})
+	result := s.generate(ctx, params)
+	failure := history.Failure{Kind: history.FailureKindGenerate, EntryID: entry.ID, Model: spec.Model}
 
-	if result.Error != nil {
-		// Clean up history directory on generation failure
+	if errors.Is(result.Error, gemini.ErrCanceled) {
+		// Interrupted or timed out: nothing failed, so leave no trace in history
 		if err := entry.Cleanup(historyDir); err != nil {
 			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
 		}
-		return nil, fmt.Errorf("failed to generate image: %w", result.Error)
+		err := fmt.Errorf("generation stopped: %w", result.Error)
+		s.emit(Event{Type: EventError, EntryID: entry.ID, Error: err.Error()})
+		return nil, err
+	}
+	if result.Error != nil {
+		logFailure(historyDir, failure, result.Error, w)
+		if spec.KeepFailures {
+			// Keep the failed attempt for debugging and 'regenerate --all-failed'
+			entry.Result.ErrorMessage = result.Error.Error()
+			if err := retryWrite(ctx, func() error { return entry.Save(historyDir) }); err != nil {
+				_, _ = fmt.Fprintf(w, "Warning: failed to record the failure in history: %v\n", err)
+			} else {
+				_, _ = fmt.Fprintf(w, "Failure recorded in history: %s\n", entry.ID)
+			}
+		} else if err := entry.Cleanup(historyDir); err != nil {
+			// Clean up history directory on generation failure
+			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
+		}
+		err := fmt.Errorf("failed to generate image: %w", result.Error)
+		s.emit(Event{Type: EventError, EntryID: entry.ID, Error: err.Error()})
+		return nil, err
 	}
 
 	// Save generated images
-	saved, saveErr := gemini.SaveImages(result.Response, entryDir)
+	saved, saveErr := s.saveOutputs(ctx, result.Response, entry.OutputDir(historyDir), spec.OutputFormat, entryProvenance(entry, spec))
 	if saveErr != nil {
+		if isResponseFailure(saveErr) {
+			logFailure(historyDir, failure, saveErr, w)
+		}
 		// Clean up history directory on save failure
 		if err := entry.Cleanup(historyDir); err != nil {
 			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
 		}
+		// The API call is already paid for: keep the images outside the history directory
+		saveErr = s.recoverOutputs(saveErr, historyDir, func(recoveryDir string) (string, error) {
+			return recoverEntry(entry, spec, result, recoveryDir)
+		}, w)
+		s.emit(Event{Type: EventError, EntryID: entry.ID, Error: saveErr.Error()})
 		return nil, saveErr
 	}
+	for _, path := range saved {
+		s.emit(Event{Type: EventImageSaved, EntryID: entry.ID, Image: path})
+	}
 
 	// Update entry with results
-	entry.Result.Success = true
-	for _, s := range saved {
-		entry.Result.OutputImages = append(entry.Result.OutputImages, filepath.Base(s))
+	recordResult(entry, spec.Model, saved, result)
+
+	if spec.AssembleAnimation && len(saved) > 1 {
+		animPath := entry.OutputPath(historyDir, gemini.AnimationFile)
+		if err := gemini.AssembleGIF(saved, animPath, gemini.DefaultFrameDelay); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to assemble animation: %v\n", err)
+		} else {
+			entry.Result.Animation = gemini.AnimationFile
+		}
+	}
+
+	if spec.Archive.RawResponse {
+		data, err := gemini.MarshalRawResponse(result.Response)
+		if err == nil {
+			err = entry.SaveRawResponse(historyDir, data)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to save raw response: %v\n", err)
+		}
 	}
-	entry.Result.TokenUsage = result.TokenUsage
 
-	if err := entry.Save(historyDir); err != nil {
+	// Announce the entry only once it is finalized and readable
+	if err := retryWrite(ctx, func() error { return entry.Save(historyDir) }); err != nil {
 		_, _ = fmt.Fprintf(w, "Warning: failed to save history: %v\n", err)
+	} else {
+		s.emit(Event{Type: EventEntryCreated, EntryID: entry.ID})
+	}
+
+	// Evaluators see the finished entry; their verdicts are saved into it afterwards
+	rejected := false
+	if len(spec.Evaluation.Commands) > 0 {
+		_, span := tracing.Start(ctx, "generation.evaluate", "evaluators", len(spec.Evaluation.Commands))
+		rejected = spec.Evaluation.evaluate(ctx, entry, entryDir, w)
+		span.SetAttributes("rejected", rejected)
+		span.End(nil)
+		if err := retryWrite(ctx, func() error { return entry.Save(historyDir) }); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to save evaluation results: %v\n", err)
+		}
+		event := Event{Type: EventEvaluated, EntryID: entry.ID}
+		if rejected {
+			event.Error = "rejected by evaluator"
+		}
+		s.emit(event)
+	}
+	if entry.Result.Success {
+		spec.Mirror.mirror(saved, w)
 	}
 
 	// Print output
@@ -121,17 +358,279 @@ func (s *Service) Run(ctx context.Context, spec Spec, historyDir string, w io.Wr
 	for _, s := range saved {
 		_, _ = fmt.Fprintf(w, "  %s\n", filepath.Base(s))
 	}
+	if entry.Result.Animation != "" {
+		_, _ = fmt.Fprintf(w, "  %s (%d frames)\n", entry.Result.Animation, len(saved))
+	}
 
 	gemini.PrintOutput(w, result.Response, spec.Model)
 
 	return &Result{
 		EntryID:      entry.ID,
 		OutputImages: entry.Result.OutputImages,
+		TokenUsage:   entry.Result.TokenUsage,
+		Rejected:     rejected,
 	}, nil
 }
 
+// saveSnapshot creates the entry directory, marked pending until the outputs are written, and saves
+// the prompt and the inputs according to the archive policy
+func (s *Service) saveSnapshot(ctx context.Context, entry *history.Entry, spec Spec, historyDir string, w io.Writer) (err error) {
+	ctx, span := tracing.Start(ctx, "generation.save_snapshot", "entry", entry.ID, "inputs", len(spec.ImagePaths))
+	defer func() { span.End(err) }()
+
+	if err := retryWrite(ctx, func() error { return entry.SavePending(historyDir) }); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := retryWrite(ctx, func() error { return entry.SavePrompt(historyDir, spec.Prompt) }); err != nil {
+		return fmt.Errorf("failed to save prompt: %w", err)
+	}
+
+	// Archive inputs according to policy
+	if spec.Archive.HashInputsOnly {
+		if err := entry.SaveInputHashes(spec.ImagePaths); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to hash input images: %v\n", err)
+		}
+	} else if err := entry.SaveInputImages(historyDir, spec.ImagePaths); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to save input images: %v\n", err)
+	}
+	if contextPaths := spec.Archive.contextPaths(); len(contextPaths) > 0 {
+		if err := entry.SaveContextFile(historyDir, contextPaths...); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+		}
+	}
+	if spec.Archive.CharacterPath != "" {
+		if err := entry.SaveCharacterFile(historyDir, spec.Archive.CharacterPath); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// generate calls the generator in a span that records the token usage
+func (s *Service) generate(ctx context.Context, params gemini.Params) *gemini.Result {
+	ctx, span := tracing.Start(ctx, "gemini.generate", "model", params.Model, "inputs", len(params.ImagePaths))
+	result := s.generator.Generate(ctx, params)
+	span.SetAttributes("tokens", result.TokenUsage.Total)
+	span.End(result.Error)
+	return result
+}
+
+// saveOutputs saves the images of response in a span
+func (s *Service) saveOutputs(ctx context.Context, response *genai.GenerateContentResponse, dir, format string, prov *gemini.Provenance) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "generation.save_images", "format", format)
+	saved, err := saveImages(ctx, response, dir, format, prov)
+	span.SetAttributes("images", len(saved))
+	span.End(err)
+	return saved, err
+}
+
+// countTokenBreakdown measures and prints the prompt token breakdown.
+// Failures only produce a warning since the breakdown is informational.
+func (s *Service) countTokenBreakdown(ctx context.Context, params gemini.Params, w io.Writer) *gemini.TokenBreakdown {
+	counter, ok := s.generator.(TokenCounter)
+	if !ok {
+		_, _ = fmt.Fprintln(w, "Warning: token breakdown is not supported by this generator")
+		return nil
+	}
+	breakdown, err := counter.CountTokenBreakdown(ctx, params)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+		return nil
+	}
+	gemini.PrintTokenBreakdown(w, breakdown)
+	_, _ = fmt.Fprintln(w, "")
+	return breakdown
+}
+
+// RunBatch executes count independent generations from the same spec, creating one history entry each.
+// It stops at the first failure and returns the results of the runs that succeeded.
+func (s *Service) RunBatch(ctx context.Context, spec Spec, count int, historyDir string, w io.Writer) ([]*Result, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid count %d: must be at least 1", count)
+	}
+	if count == 1 {
+		result, err := s.Run(ctx, spec, historyDir, w)
+		if err != nil {
+			return nil, err
+		}
+		return []*Result{result}, nil
+	}
+
+	// Check room for the whole batch up front; each run still checks that its own outputs fit
+	need := estimateOutputBytes(spec.ImageSize, count*max(spec.Candidates, 1), spec.Archive.RawResponse)
+	if err := checkDiskSpace(historyDir, need, spec.MinFreeDisk, w); err != nil {
+		s.emit(Event{Type: EventValidation, Model: spec.Model, Error: err.Error()})
+		return nil, err
+	}
+	spec.MinFreeDisk = 0
+
+	var results []*Result
+	for i := range count {
+		_, _ = fmt.Fprintf(w, "=== Generation %d/%d ===\n", i+1, count)
+		runSpec := spec
+		if spec.Seed != nil {
+			// The same seed would give count copies of one image; consecutive seeds keep the batch reproducible
+			seed := *spec.Seed + int32(i)
+			runSpec.Seed = &seed
+		}
+		result, err := s.Run(ctx, runSpec, historyDir, w)
+		if err != nil {
+			printBatchSummary(w, results, count)
+			return results, fmt.Errorf("generation %d/%d failed: %w", i+1, count, err)
+		}
+		results = append(results, result)
+		_, _ = fmt.Fprintln(w, "")
+	}
+
+	printBatchSummary(w, results, count)
+	return results, nil
+}
+
+// RunParallel executes the given specs with at most concurrency generations in flight, creating one
+// history entry each. Unlike RunBatch it does not stop at a failure: the progress output of every run
+// is buffered and printed in spec order, and the returned slice holds nil for runs that failed.
+func (s *Service) RunParallel(ctx context.Context, specs []Spec, concurrency int, historyDir string, w io.Writer) ([]*Result, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("invalid concurrency %d: must be at least 1", concurrency)
+	}
+
+	if len(specs) > 0 {
+		var need int64
+		for _, spec := range specs {
+			need += estimateOutputBytes(spec.ImageSize, max(spec.Candidates, 1), spec.Archive.RawResponse)
+		}
+		if err := checkDiskSpace(historyDir, need, specs[0].MinFreeDisk, w); err != nil {
+			s.emit(Event{Type: EventValidation, Model: specs[0].Model, Error: err.Error()})
+			return nil, err
+		}
+		specs = slices.Clone(specs)
+		for i := range specs {
+			specs[i].MinFreeDisk = 0
+		}
+	}
+
+	results := make([]*Result, len(specs))
+	errs := make([]error, len(specs))
+	outputs := make([]bytes.Buffer, len(specs))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = s.Run(ctx, spec, historyDir, &outputs[i])
+		}()
+	}
+	wg.Wait()
+
+	var succeeded []*Result
+	var failures []error
+	for i, spec := range specs {
+		_, _ = fmt.Fprintf(w, "=== Generation %d/%d (from %s) ===\n", i+1, len(specs), spec.SourceEntryID)
+		_, _ = w.Write(outputs[i].Bytes())
+		if errs[i] != nil {
+			_, _ = fmt.Fprintf(w, "Error: %v\n", errs[i])
+			failures = append(failures, fmt.Errorf("generation %d/%d failed: %w", i+1, len(specs), errs[i]))
+		} else {
+			succeeded = append(succeeded, results[i])
+		}
+		_, _ = fmt.Fprintln(w, "")
+	}
+
+	printBatchSummary(w, succeeded, len(specs))
+	return results, errors.Join(failures...)
+}
+
+// printBatchSummary prints the entries created by a batch and their aggregate token usage
+func printBatchSummary(w io.Writer, results []*Result, count int) {
+	var total gemini.TokenUsage
+	images := 0
+	for _, r := range results {
+		total.Add(r.TokenUsage)
+		images += len(r.OutputImages)
+	}
+
+	_, _ = fmt.Fprintf(w, "Batch: %d/%d generations succeeded, %d images\n", len(results), count, images)
+	for _, r := range results {
+		_, _ = fmt.Fprintf(w, "  %s\n", r.EntryID)
+	}
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Aggregate token usage:")
+	_, _ = fmt.Fprintf(w, "  prompt: %d\n", total.Prompt)
+	_, _ = fmt.Fprintf(w, "  candidates: %d\n", total.Candidates)
+	_, _ = fmt.Fprintf(w, "  total: %d\n", total.Total)
+	if total.Cached > 0 {
+		_, _ = fmt.Fprintf(w, "  cached: %d\n", total.Cached)
+	}
+	if total.Thoughts > 0 {
+		_, _ = fmt.Fprintf(w, "  thoughts: %d\n", total.Thoughts)
+	}
+}
+
+// maskInstruction is appended to the edit prompt when a mask image is sent after the source image
+const maskInstruction = "\n\nThe second image is a mask for the first image. Only change the areas that are white in the mask; keep the black areas exactly as they are."
+
 // Edit executes an edit operation on an existing image.
 func (s *Service) Edit(ctx context.Context, spec EditSpec, historyDir string, w io.Writer) (*EditResult, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "generation.edit", "model", spec.Model, "entry", spec.EntryID)
+	result, err := s.edit(ctx, spec, historyDir, w)
+	if result != nil {
+		span.SetAttributes("edit", result.EditID, "outputs", len(result.OutputImages))
+	}
+	span.End(err)
+	attrs := []any{"model", spec.Model, "entry", spec.EntryID, "duration", time.Since(start)}
+	if err != nil {
+		slog.WarnContext(ctx, "edit failed", append(attrs, "error", err)...)
+		return result, err
+	}
+	slog.InfoContext(ctx, "edit finished", append(attrs, "edit", result.EditID, "outputs", len(result.OutputImages))...)
+	return result, nil
+}
+
+// DryRunEdit resolves spec exactly as Edit does and returns the request it would send, without
+// calling the API or writing history.
+func (s *Service) DryRunEdit(spec EditSpec, historyDir string, w io.Writer) (*DryRunResult, error) {
+	params, err := prepareEdit(spec, historyDir, w)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{Params: params, Estimate: estimateRequest(params)}, nil
+}
+
+// prepareEdit validates the mask and disk space and returns the parameters of the API call of an edit
+func prepareEdit(spec EditSpec, historyDir string, w io.Writer) (gemini.Params, error) {
+	params := gemini.Params{
+		Model:       spec.Model,
+		Prompt:      spec.Prompt,
+		ImagePaths:  []string{spec.SourceImagePath},
+		AspectRatio: spec.AspectRatio,
+		ImageSize:   spec.ImageSize,
+	}
+	if spec.MaskPath != "" {
+		if err := validateInputImages([]string{spec.MaskPath}); err != nil {
+			return gemini.Params{}, fmt.Errorf("mask: %w", err)
+		}
+		params.ImagePaths = append(params.ImagePaths, spec.MaskPath)
+		params.Prompt += maskInstruction
+	}
+	if err := checkDiskSpace(historyDir, estimateOutputBytes(spec.ImageSize, 1, false), spec.MinFreeDisk, w); err != nil {
+		return gemini.Params{}, err
+	}
+	return params, nil
+}
+
+func (s *Service) edit(ctx context.Context, spec EditSpec, historyDir string, w io.Writer) (*EditResult, error) {
+	params, err := prepareEdit(spec, historyDir, w)
+	if err != nil {
+		s.emit(Event{Type: EventValidation, EntryID: spec.EntryID, Model: spec.Model, Error: err.Error()})
+		return nil, err
+	}
+	s.emit(Event{Type: EventValidation, EntryID: spec.EntryID, Model: spec.Model})
+
 	// Create edit entry
 	editEntry := history.NewEditEntry()
 	editEntry.Source = history.EditSource{
@@ -139,45 +638,77 @@ func (s *Service) Edit(ctx context.Context, spec EditSpec, historyDir string, w
 		EditID: spec.SourceEditID,
 		Output: spec.SourceOutput,
 	}
+	editEntry.Generation.Model = spec.Model
+	editEntry.Generation.ModelOverride = spec.ModelOverride
 	editEntry.Generation.AspectRatio = spec.AspectRatio
 	editEntry.Generation.ImageSize = spec.ImageSize
+	editEntry.Generation.Upscale = spec.Upscale
 
-	entryDir := filepath.Join(historyDir, spec.EntryID)
+	entryDir := history.GetEntryDirByID(historyDir, spec.EntryID)
 	editDir := editEntry.GetEditEntryDir(entryDir)
 
-	// Create edit directory and save prompt
-	if err := os.MkdirAll(editDir, 0o755); err != nil {
+	// Create edit directory, marked pending until the outputs are written, and save prompt
+	if err := retryWrite(ctx, func() error { return editEntry.SavePending(entryDir) }); err != nil {
 		return nil, fmt.Errorf("failed to create edit directory: %w", err)
 	}
-	if err := editEntry.SavePrompt(entryDir, spec.Prompt); err != nil {
+	if err := retryWrite(ctx, func() error { return editEntry.SavePrompt(entryDir, spec.Prompt) }); err != nil {
 		return nil, fmt.Errorf("failed to save edit prompt: %w", err)
 	}
+	if spec.MaskPath != "" {
+		if err := editEntry.SaveMask(entryDir, spec.MaskPath); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+		}
+	}
 
 	// Call Gemini API
-	result := s.generator.Generate(ctx, gemini.Params{
-		Model:       spec.Model,
-		Prompt:      spec.Prompt,
-		ImagePaths:  []string{spec.SourceImagePath},
-		AspectRatio: spec.AspectRatio,
-		ImageSize:   spec.ImageSize,
-	})
+	s.emit(Event{Type: EventRequestSent, EntryID: spec.EntryID, EditID: editEntry.ID, Model: spec.Model})
+	result := s.generate(ctx, params)
 
-	if result.Error != nil {
-		// Clean up edit directory on failure
+	failure := history.Failure{Kind: history.FailureKindEdit, EntryID: spec.EntryID, EditID: editEntry.ID, Model: spec.Model}
+	if errors.Is(result.Error, gemini.ErrCanceled) {
 		if err := editEntry.Cleanup(entryDir); err != nil {
 			_, _ = fmt.Fprintf(w, "Warning: failed to clean up edit directory: %v\n", err)
 		}
-		return nil, fmt.Errorf("failed to edit image: %w", result.Error)
+		err := fmt.Errorf("edit stopped: %w", result.Error)
+		s.emit(Event{Type: EventError, EntryID: spec.EntryID, EditID: editEntry.ID, Error: err.Error()})
+		return nil, err
+	}
+	if result.Error != nil {
+		logFailure(historyDir, failure, result.Error, w)
+		if spec.KeepFailures {
+			editEntry.Result.ErrorMessage = result.Error.Error()
+			if err := retryWrite(ctx, func() error { return editEntry.Save(entryDir) }); err != nil {
+				_, _ = fmt.Fprintf(w, "Warning: failed to record the failure in history: %v\n", err)
+			} else {
+				_, _ = fmt.Fprintf(w, "Failure recorded in history: edit %s\n", editEntry.ID)
+			}
+		} else if err := editEntry.Cleanup(entryDir); err != nil {
+			// Clean up edit directory on failure
+			_, _ = fmt.Fprintf(w, "Warning: failed to clean up edit directory: %v\n", err)
+		}
+		err := fmt.Errorf("failed to edit image: %w", result.Error)
+		s.emit(Event{Type: EventError, EntryID: spec.EntryID, EditID: editEntry.ID, Error: err.Error()})
+		return nil, err
 	}
 
 	// Save edited images
-	saved, saveErr := gemini.SaveImages(result.Response, editDir)
+	saved, saveErr := s.saveOutputs(ctx, result.Response, editDir, spec.OutputFormat, editProvenance(editEntry, spec))
 	if saveErr != nil {
+		if isResponseFailure(saveErr) {
+			logFailure(historyDir, failure, saveErr, w)
+		}
 		if err := editEntry.Cleanup(entryDir); err != nil {
 			_, _ = fmt.Fprintf(w, "Warning: failed to clean up edit directory: %v\n", err)
 		}
+		saveErr = s.recoverOutputs(saveErr, history.GetEditsDir(entryDir), func(recoveryDir string) (string, error) {
+			return recoverEdit(editEntry, spec, result, recoveryDir)
+		}, w)
+		s.emit(Event{Type: EventError, EntryID: spec.EntryID, EditID: editEntry.ID, Error: saveErr.Error()})
 		return nil, saveErr
 	}
+	for _, path := range saved {
+		s.emit(Event{Type: EventImageSaved, EntryID: spec.EntryID, EditID: editEntry.ID, Image: path})
+	}
 
 	// Update entry with results
 	editEntry.Result.Success = true
@@ -185,10 +716,14 @@ func (s *Service) Edit(ctx context.Context, spec EditSpec, historyDir string, w
 		editEntry.Result.OutputImages = append(editEntry.Result.OutputImages, filepath.Base(savedPath))
 	}
 	editEntry.Result.TokenUsage = result.TokenUsage
+	editEntry.Result.CostUSD, _ = gemini.EstimateCost(spec.Model, result.TokenUsage)
 
-	if err := editEntry.Save(entryDir); err != nil {
+	if err := retryWrite(ctx, func() error { return editEntry.Save(entryDir) }); err != nil {
 		_, _ = fmt.Fprintf(w, "Warning: failed to save edit metadata: %v\n", err)
+	} else {
+		s.emit(Event{Type: EventEntryCreated, EntryID: spec.EntryID, EditID: editEntry.ID})
 	}
+	spec.Mirror.mirror(saved, w)
 
 	// Print output
 	_, _ = fmt.Fprintf(w, "Edit ID: %s\n", editEntry.ID)
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"image/gif"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 	"github.com/stretchr/testify/assert"
@@ -73,6 +79,171 @@ func TestService_Run_Success(t *testing.T) {
 	lastCall := mock.lastCall()
 	assert.Equal(t, "test-model", lastCall.Model)
 	assert.Equal(t, "test prompt", lastCall.Prompt)
+
+	// The model is recorded; it has no pricing, so no cost is
+	entry, err := history.GetEntryByID(historyDir, result.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", entry.Generation.Model)
+	assert.Zero(t, entry.Result.CostUSD)
+}
+
+func TestService_Run_RecordsCost(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	mock := newSuccessMock([]byte("image"))
+	svc := NewService(mock)
+
+	var buf bytes.Buffer
+	result, err := svc.Run(context.Background(), Spec{
+		Model:    "gemini-3-pro-image-preview",
+		Prompt:   "test prompt",
+		TextOnly: true,
+	}, historyDir, &buf)
+	require.NoError(t, err)
+
+	entry, err := history.GetEntryByID(historyDir, result.EntryID)
+	require.NoError(t, err)
+	want, ok := gemini.EstimateCost("gemini-3-pro-image-preview", mock.tokenUsage)
+	require.True(t, ok)
+	assert.InDelta(t, want, entry.Result.CostUSD, 1e-12)
+}
+
+func TestService_DiskSpaceCheck(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := diskFree(t.TempDir()); !ok {
+		t.Skip("free disk space is unknown on this platform")
+	}
+	spec := Spec{Model: "test-model", Prompt: "test prompt", TextOnly: true}
+
+	t.Run("warns below the threshold", func(t *testing.T) {
+		t.Parallel()
+
+		// The history directory does not exist yet; its parent is checked
+		historyDir := filepath.Join(t.TempDir(), "history")
+		low := spec
+		low.MinFreeDisk = 1 << 62
+		var buf bytes.Buffer
+		_, err := NewService(newSuccessMock([]byte("image"))).Run(context.Background(), low, historyDir, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Warning: low disk space")
+	})
+
+	t.Run("refuses a batch that does not fit", func(t *testing.T) {
+		t.Parallel()
+
+		mock := newSuccessMock([]byte("image"))
+		var buf bytes.Buffer
+		_, err := NewService(mock).RunBatch(context.Background(), spec, 1<<40, t.TempDir(), &buf)
+		require.ErrorIs(t, err, ErrInsufficientDisk)
+		assert.Zero(t, mock.callCount())
+	})
+}
+
+func TestEstimateOutputBytes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, int64(2<<20), estimateOutputBytes("", 1, false))
+	assert.Equal(t, int64(3*32<<20), estimateOutputBytes("4K", 3, false))
+	assert.Greater(t, estimateOutputBytes("2K", 1, true), estimateOutputBytes("2K", 1, false))
+}
+
+func TestService_Run_RecoversOutputs(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	recoveryDir := t.TempDir()
+	mock := newSuccessMock([]byte("image"))
+	mock.onGenerate = func() {
+		// The history directory becomes unwritable while the API call runs
+		dirs, _ := os.ReadDir(historyDir)
+		for _, d := range dirs {
+			path := filepath.Join(historyDir, d.Name())
+			_ = os.RemoveAll(path)
+			_ = os.WriteFile(path, nil, 0o644)
+		}
+	}
+
+	var buf bytes.Buffer
+	_, err := NewService(mock).WithRecoveryDir(recoveryDir).Run(context.Background(), Spec{
+		Model:    "test-model",
+		Prompt:   "test prompt",
+		TextOnly: true,
+	}, historyDir, &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outputs recovered to")
+	assert.Contains(t, buf.String(), "move that directory into it")
+
+	entries, err := history.ListEntries(recoveryDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Result.Success)
+	require.Len(t, entries[0].Result.OutputImages, 1)
+	assert.FileExists(t, filepath.Join(entries[0].GetEntryDir(recoveryDir), entries[0].Result.OutputImages[0]))
+	prompt, err := history.LoadPrompt(entries[0].GetEntryDir(recoveryDir))
+	require.NoError(t, err)
+	assert.Equal(t, "test prompt", prompt)
+}
+
+func TestSaveImages_OutputNames(t *testing.T) {
+	t.Parallel()
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	mock := &mockGenerator{responseImages: [][]byte{pngData, pngData}}
+	dir := t.TempDir()
+
+	// Two runs saving into the same folder, as quick runs and recoveries do
+	var all []string
+	for range 2 {
+		saved, err := saveImages(context.Background(), mock.Generate(context.Background(), gemini.Params{}).Response, dir, "", nil)
+		require.NoError(t, err)
+		require.Len(t, saved, 2)
+		for i, path := range saved {
+			name := filepath.Base(path)
+			assert.True(t, gemini.IsOutputFileName(name), name)
+			_, index, ok := gemini.ParseOutputFileName(name)
+			assert.True(t, ok)
+			assert.Equal(t, i+1, index)
+		}
+		all = append(all, saved...)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, gemini.OutputPattern))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, all, matches, "outputs of both runs must be kept")
+}
+
+func TestRetryWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retries locked files", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := retryWrite(context.Background(), func() error {
+			attempts++
+			if attempts < 2 {
+				return &os.PathError{Op: "open", Path: "meta.yaml", Err: os.ErrPermission}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("gives up on other errors at once", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := retryWrite(context.Background(), func() error {
+			attempts++
+			return errors.New("no image response found")
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
 }
 
 func TestService_Run_APIError(t *testing.T) {
@@ -118,6 +289,175 @@ func TestService_Run_APIError(t *testing.T) {
 	assert.Empty(t, entries, "expected no history entries after error")
 }
 
+func TestService_KeepFailures(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	svc := NewService(newErrorMock(errors.New("503 Service Unavailable")))
+
+	var buf bytes.Buffer
+	_, err = svc.Run(context.Background(), Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+		KeepFailures:    true,
+	}, historyDir, &buf)
+	require.Error(t, err)
+	assert.Contains(t, buf.String(), "Failure recorded in history:")
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.False(t, entry.Result.Success)
+	assert.Equal(t, "503 Service Unavailable", entry.Result.ErrorMessage)
+	entryDir := entry.GetEntryDir(historyDir)
+	prompt, err := history.LoadPrompt(entryDir)
+	require.NoError(t, err)
+	assert.Equal(t, "test prompt", prompt)
+	assert.FileExists(t, history.ResolveEntryFile(entryDir, "test.png"))
+
+	// Edits keep failures the same way
+	entry.Result.Success = true
+	entry.Result.OutputImages = []string{"output.png"}
+	require.NoError(t, entry.Save(historyDir))
+	require.NoError(t, os.WriteFile(history.GetEntryFilePath(entryDir, "output.png"), pngData, 0o644))
+	_, err = svc.Edit(context.Background(), EditSpec{
+		Model:           "test-model",
+		Prompt:          "make it night",
+		SourceImagePath: history.GetEntryFilePath(entryDir, "output.png"),
+		EntryID:         entry.ID,
+		SourceType:      "generate",
+		SourceOutput:    "output.png",
+		KeepFailures:    true,
+	}, historyDir, &buf)
+	require.Error(t, err)
+	edits, err := history.ListEditEntries(entryDir)
+	require.NoError(t, err)
+	require.Len(t, edits, 1)
+	assert.False(t, edits[0].Result.Success)
+	assert.Equal(t, "503 Service Unavailable", edits[0].Result.ErrorMessage)
+}
+
+func TestService_Canceled(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	// An interrupted run is cleaned up even with keep_failures, and is not a failure
+	svc := NewService(newErrorMock(fmt.Errorf("%w: %w", gemini.ErrCanceled, context.Canceled)))
+	_, err = svc.Run(context.Background(), Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+		KeepFailures:    true,
+	}, historyDir, &bytes.Buffer{})
+	require.ErrorIs(t, err, gemini.ErrCanceled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	failures, err := history.ListFailures(historyDir)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	dirs, err := os.ReadDir(historyDir)
+	require.NoError(t, err)
+	for _, d := range dirs {
+		assert.False(t, d.IsDir(), "leftover directory %s", d.Name())
+	}
+}
+
+func TestService_DryRun(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	var paths []string
+	for _, name := range []string{"a.png", "b.png"} {
+		path := filepath.Join(project.GetInputsDir(subprojectDir), name)
+		require.NoError(t, os.WriteFile(path, pngData, 0o644))
+		paths = append(paths, path)
+	}
+	contextPath := filepath.Join(projectRoot, "context.md")
+	require.NoError(t, os.WriteFile(contextPath, []byte("Shared notes"), 0o644))
+
+	// The request has the context prepended and the inputs the token budget leaves
+	mock := newSuccessMock(pngData)
+	svc := NewService(mock)
+	result, err := svc.DryRun(context.Background(), Spec{
+		Model:           "gemini-3-pro-image-preview",
+		Prompt:          "test prompt",
+		ImagePaths:      paths,
+		InputImageNames: []string{"a.png", "b.png"},
+		AspectRatio:     "16:9",
+		ImageSize:       "4K",
+		Candidates:      2,
+		TokenBudget:     1000,
+		IncludeContext:  true,
+		Archive:         ArchivePolicy{ContextPath: contextPath},
+	}, historyDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, "Shared notes\n\ntest prompt", result.Params.Prompt)
+	assert.Equal(t, paths[:1], result.Params.ImagePaths)
+	assert.Equal(t, []string{"b.png"}, result.Omitted)
+	assert.Nil(t, result.Params.Seed)
+	assert.True(t, result.Estimate.Known)
+	single := gemini.EstimateGeneration("gemini-3-pro-image-preview", 0, 0, "4K")
+	assert.Equal(t, 2*single.OutputTokens, result.Estimate.OutputTokens)
+
+	// Nothing was sent or written
+	assert.Equal(t, 0, mock.callCount())
+	entries, err := history.ListEntries(historyDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Invalid specs fail like Run
+	_, err = svc.DryRun(context.Background(), Spec{Model: "test-model", Prompt: "test prompt"}, historyDir, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrInvalidSpec)
+
+	// Edits get the mask and its instruction
+	maskPath := filepath.Join(t.TempDir(), "mask.png")
+	require.NoError(t, os.WriteFile(maskPath, pngData, 0o644))
+	editResult, err := svc.DryRunEdit(EditSpec{
+		Model:           "test-model",
+		Prompt:          "make it night",
+		SourceImagePath: paths[0],
+		MaskPath:        maskPath,
+	}, historyDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{paths[0], maskPath}, editResult.Params.ImagePaths)
+	assert.Equal(t, "make it night"+maskInstruction, editResult.Params.Prompt)
+	assert.False(t, editResult.Estimate.Known)
+}
+
 func TestService_Run_MultipleImages(t *testing.T) {
 	t.Parallel()
 
@@ -320,3 +660,800 @@ func TestService_Run_WithAspectRatioAndSize(t *testing.T) {
 
 // Note: Scenario tests (TestScenario_*) have been moved to cmd/ layer
 // where they can be tested through the handler pattern with DI support.
+
+func TestService_Run_ArchivePolicy(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	// Create input image and character file
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+	characterPath := project.GetCharacterPath(projectRoot, "hero.md")
+	require.NoError(t, os.WriteFile(characterPath, []byte("# Hero"), 0o644))
+	projectContextPath := project.GetProjectContextPath(projectRoot)
+	require.NoError(t, os.WriteFile(projectContextPath, []byte("# Shared style"), 0o644))
+	contextPath := filepath.Join(subprojectDir, config.DefaultContextFile)
+	require.NoError(t, os.WriteFile(contextPath, []byte("# Scene\n"), 0o644))
+
+	svc := NewService(newSuccessMock(pngData))
+
+	var buf bytes.Buffer
+	result, err := svc.Run(context.Background(), Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+		Archive: ArchivePolicy{
+			HashInputsOnly:     true,
+			ProjectContextPath: projectContextPath,
+			ContextPath:        contextPath,
+			CharacterPath:      characterPath,
+			RawResponse:        true,
+		},
+	}, historyDir, &buf)
+	require.NoError(t, err)
+
+	entry, err := history.GetEntryByID(historyDir, result.EntryID)
+	require.NoError(t, err)
+	entryDir := entry.GetEntryDir(historyDir)
+
+	// Inputs are referenced by hash only
+	assert.NoFileExists(t, filepath.Join(entryDir, "test.png"))
+	assert.Len(t, entry.Generation.InputHashes["test.png"], 64)
+	paths, err := entry.ResolveHashedInputs(project.GetInputsDir(subprojectDir))
+	require.NoError(t, err)
+	assert.Equal(t, []string{inputPath}, paths)
+
+	// Context, character and raw response are archived
+	assert.Equal(t, history.ContextFile, entry.Generation.ContextFile)
+	archivedContext, err := os.ReadFile(filepath.Join(entryDir, history.ContextFile))
+	require.NoError(t, err)
+	assert.Equal(t, "# Shared style\n\n# Scene\n", string(archivedContext), "project context comes first")
+	assert.Equal(t, history.CharacterFile, entry.Generation.CharacterFile)
+	assert.FileExists(t, filepath.Join(entryDir, history.CharacterFile))
+	assert.FileExists(t, filepath.Join(entryDir, history.ResponseFile))
+
+	// Changed inputs no longer resolve
+	require.NoError(t, os.WriteFile(inputPath, []byte("changed"), 0o644))
+	_, err = entry.ResolveHashedInputs(project.GetInputsDir(subprojectDir))
+	assert.ErrorContains(t, err, "has changed")
+}
+
+func TestService_RunBatch(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	spec := Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+	}
+
+	t.Run("creates one entry per generation", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := newSuccessMock(pngData)
+		mock.tokenUsage.Total = 100
+
+		var buf bytes.Buffer
+		results, err := NewService(mock).RunBatch(context.Background(), spec, 3, historyDir, &buf)
+		require.NoError(t, err)
+		assert.Len(t, results, 3)
+		assert.Equal(t, 3, mock.callCount())
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 3)
+
+		output := buf.String()
+		assert.Contains(t, output, "=== Generation 3/3 ===")
+		assert.Contains(t, output, "Batch: 3/3 generations succeeded, 3 images")
+		assert.Contains(t, output, "  total: 300")
+	})
+
+	t.Run("records the seed of each generation", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := newSuccessMock(pngData)
+		seeded := spec
+		seed := int32(42)
+		seeded.Seed = &seed
+
+		results, err := NewService(mock).RunBatch(context.Background(), seeded, 3, historyDir, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Len(t, mock.calls, 3)
+		for i, call := range mock.calls {
+			require.NotNil(t, call.Seed)
+			assert.Equal(t, int32(42+i), *call.Seed, "consecutive seeds keep the batch reproducible")
+
+			entry, err := history.GetEntryByID(historyDir, results[i].EntryID)
+			require.NoError(t, err)
+			assert.Equal(t, call.Seed, entry.Generation.Seed)
+		}
+
+		// Without a seed one is picked and recorded
+		mock = newSuccessMock(pngData)
+		result, err := NewService(mock).Run(context.Background(), spec, historyDir, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Len(t, mock.calls, 1)
+		require.NotNil(t, mock.calls[0].Seed)
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		assert.Equal(t, mock.calls[0].Seed, entry.Generation.Seed)
+	})
+
+	t.Run("stops at first failure", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := newErrorMock(errors.New("quota exceeded"))
+
+		var buf bytes.Buffer
+		results, err := NewService(mock).RunBatch(context.Background(), spec, 3, historyDir, &buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "generation 1/3 failed")
+		assert.Empty(t, results)
+		assert.Equal(t, 1, mock.callCount())
+	})
+
+	t.Run("rejects invalid count", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewService(newSuccessMock(pngData)).RunBatch(context.Background(), spec, 0, historyDir, &bytes.Buffer{})
+		require.Error(t, err)
+	})
+}
+
+func TestService_RunParallel(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	var specs []Spec
+	for _, id := range []string{"source-a", "source-b", "source-c"} {
+		specs = append(specs, Spec{
+			Model:           "test-model",
+			Prompt:          "prompt of " + id,
+			ImagePaths:      []string{inputPath},
+			InputImageNames: []string{"test.png"},
+			SourceEntryID:   id,
+		})
+	}
+
+	t.Run("runs every spec", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := newSuccessMock(pngData)
+
+		var buf bytes.Buffer
+		results, err := NewService(mock).RunParallel(context.Background(), specs, 2, historyDir, &buf)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		for _, r := range results {
+			assert.NotNil(t, r)
+		}
+		assert.Equal(t, 3, mock.callCount())
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 3)
+		assert.Contains(t, buf.String(), "=== Generation 2/3 (from source-b) ===")
+		assert.Contains(t, buf.String(), "Batch: 3/3 generations succeeded, 3 images")
+	})
+
+	t.Run("continues after failures", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := newErrorMock(errors.New("quota exceeded"))
+
+		var buf bytes.Buffer
+		results, err := NewService(mock).RunParallel(context.Background(), specs, 2, historyDir, &buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "generation 3/3 failed")
+		assert.Equal(t, []*Result{nil, nil, nil}, results)
+		assert.Equal(t, 3, mock.callCount())
+		assert.Contains(t, buf.String(), "Batch: 0/3 generations succeeded")
+	})
+
+	t.Run("rejects invalid concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewService(newSuccessMock(pngData)).RunParallel(context.Background(), specs, 0, t.TempDir(), &bytes.Buffer{})
+		require.Error(t, err)
+	})
+}
+
+// countingMock adds token counting to mockGenerator.
+type countingMock struct {
+	*mockGenerator
+	breakdownErr error
+}
+
+// CountTokenBreakdown implements TokenCounter with 10 text tokens and 500 tokens per image.
+func (m *countingMock) CountTokenBreakdown(_ context.Context, params gemini.Params) (*gemini.TokenBreakdown, error) {
+	if m.breakdownErr != nil {
+		return nil, m.breakdownErr
+	}
+	b := &gemini.TokenBreakdown{Text: 10}
+	for _, p := range params.ImagePaths {
+		b.Inputs = append(b.Inputs, gemini.InputTokens{Name: filepath.Base(p), Tokens: 500})
+	}
+	return b, nil
+}
+
+func TestService_Run_TokenBreakdown(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	spec := Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+		TokenBreakdown:  true,
+	}
+
+	t.Run("records breakdown", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		var buf bytes.Buffer
+		result, err := NewService(&countingMock{mockGenerator: newSuccessMock(pngData)}).Run(context.Background(), spec, historyDir, &buf)
+		require.NoError(t, err)
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		require.NotNil(t, entry.Generation.TokenBreakdown)
+		assert.Equal(t, 10, entry.Generation.TokenBreakdown.Text)
+		assert.Equal(t, []gemini.InputTokens{{Name: "test.png", Tokens: 500}}, entry.Generation.TokenBreakdown.Inputs)
+
+		assert.Contains(t, buf.String(), "Prompt token breakdown:")
+		assert.Contains(t, buf.String(), "  test.png: 500 (98%)")
+	})
+
+	t.Run("counting failure does not stop generation", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := &countingMock{mockGenerator: newSuccessMock(pngData), breakdownErr: errors.New("failed to count tokens: quota")}
+
+		var buf bytes.Buffer
+		result, err := NewService(mock).Run(context.Background(), spec, historyDir, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Warning: failed to count tokens: quota")
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		assert.Nil(t, entry.Generation.TokenBreakdown)
+	})
+
+	t.Run("unsupported generator", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		_, err := NewService(newSuccessMock(pngData)).Run(context.Background(), spec, t.TempDir(), &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "token breakdown is not supported")
+	})
+}
+
+func TestService_Run_TokenBudget(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	names := []string{"a.png", "b.png", "c.png"}
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(project.GetInputsDir(subprojectDir), name)
+		require.NoError(t, os.WriteFile(path, pngData, 0o644))
+		paths = append(paths, path)
+	}
+
+	tests := []struct {
+		name        string
+		budget      int
+		wantInputs  []string
+		wantOmitted []string
+		wantOutput  string
+	}{
+		{"unlimited", 0, names, nil, ""},
+		{"fits", 5000, names, nil, ""},
+		{"drops lowest priority", 1200, []string{"a.png", "b.png"}, []string{"c.png"}, "Omitted inputs: c.png"},
+		{"keeps one input", 100, []string{"a.png"}, []string{"b.png", "c.png"}, "still over budget"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			historyDir := t.TempDir()
+
+			mock := newSuccessMock(pngData)
+			var buf bytes.Buffer
+			result, err := NewService(mock).Run(context.Background(), Spec{
+				Model:           "test-model",
+				Prompt:          "test prompt",
+				ImagePaths:      paths,
+				InputImageNames: names,
+				TokenBudget:     tt.budget,
+			}, historyDir, &buf)
+			require.NoError(t, err)
+
+			assert.Len(t, mock.lastCall().ImagePaths, len(tt.wantInputs))
+
+			entry, err := history.GetEntryByID(historyDir, result.EntryID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantInputs, entry.Generation.InputImages)
+			assert.Equal(t, tt.wantOmitted, entry.Generation.OmittedInputs)
+			if tt.wantOutput != "" {
+				assert.Contains(t, buf.String(), tt.wantOutput)
+			} else {
+				assert.NotContains(t, buf.String(), "Token budget")
+			}
+		})
+	}
+}
+
+func TestService_Run_AssembleAnimation(t *testing.T) {
+	t.Parallel()
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	run := func(t *testing.T, frames int, assemble bool) (*history.Entry, string) {
+		t.Helper()
+		historyDir := t.TempDir()
+		inputPath := filepath.Join(t.TempDir(), "test.png")
+		require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+		svc := NewService(newMultiImageMock(pngData, frames))
+		var buf bytes.Buffer
+		result, err := svc.Run(context.Background(), Spec{
+			Model:             "test-model",
+			Prompt:            "a walk cycle",
+			ImagePaths:        []string{inputPath},
+			InputImageNames:   []string{"test.png"},
+			AssembleAnimation: assemble,
+		}, historyDir, &buf)
+		require.NoError(t, err)
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		return entry, entry.GetEntryDir(historyDir)
+	}
+
+	t.Run("multiple frames are assembled", func(t *testing.T) {
+		t.Parallel()
+		entry, entryDir := run(t, 3, true)
+		assert.Equal(t, gemini.AnimationFile, entry.Result.Animation)
+		assert.Len(t, entry.Result.OutputImages, 3, "frames are kept as outputs")
+
+		f, err := os.Open(filepath.Join(entryDir, gemini.AnimationFile))
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		anim, err := gif.DecodeAll(f)
+		require.NoError(t, err)
+		assert.Len(t, anim.Image, 3)
+	})
+
+	t.Run("single frame is not assembled", func(t *testing.T) {
+		t.Parallel()
+		entry, entryDir := run(t, 1, true)
+		assert.Empty(t, entry.Result.Animation)
+		assert.NoFileExists(t, filepath.Join(entryDir, gemini.AnimationFile))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		entry, _ := run(t, 3, false)
+		assert.Empty(t, entry.Result.Animation)
+	})
+}
+
+func TestService_Run_Mirror(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	for _, symlink := range []bool{false, true} {
+		mirrorDir := filepath.Join(t.TempDir(), "outputs")
+		svc := NewService(newSuccessMock(pngData))
+
+		var buf bytes.Buffer
+		result, err := svc.Run(context.Background(), Spec{
+			Model:           "test-model",
+			Prompt:          "test prompt",
+			ImagePaths:      []string{inputPath},
+			InputImageNames: []string{"test.png"},
+			Mirror:          MirrorPolicy{Dir: mirrorDir, Symlink: symlink},
+		}, historyDir, &buf)
+		require.NoError(t, err)
+		require.Len(t, result.OutputImages, 1)
+
+		// Outputs land in today's folder, under their history file names
+		mirrored := filepath.Join(mirrorDir, time.Now().Format(time.DateOnly), result.OutputImages[0])
+		data, err := os.ReadFile(mirrored)
+		require.NoError(t, err, "symlink=%v", symlink)
+		saved, err := os.ReadFile(history.GetEntryFilePath(history.GetEntryDirByID(historyDir, result.EntryID), result.OutputImages[0]))
+		require.NoError(t, err)
+		assert.Equal(t, saved, data)
+		info, err := os.Lstat(mirrored)
+		require.NoError(t, err)
+		if runtime.GOOS != "windows" {
+			// Windows may fall back to copies when symlinks need privileges
+			assert.Equal(t, symlink, info.Mode()&os.ModeSymlink != 0, "symlink=%v", symlink)
+		}
+		assert.Contains(t, buf.String(), "Mirrored to:")
+	}
+}
+
+func TestService_Run_Evaluation(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("evaluator test scripts need a POSIX shell")
+	}
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+
+	// check.sh rejects the first entry it sees and passes later ones
+	scriptDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"test -d \"$1\" || exit 2\n" +
+		"if [ -e seen ]; then echo \"logo area clear\"; exit 0; fi\n" +
+		"touch seen; echo \"logo area covered\"; exit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(scriptDir, "check.sh"), []byte(script), 0o755))
+
+	run := func(t *testing.T, policy EvaluationPolicy) (*mockGenerator, string, *Result, string) {
+		t.Helper()
+		policy.Dir = t.TempDir()
+		policy.Commands = []string{filepath.Join(scriptDir, "check.sh")}
+		mock := newSuccessMock(pngData)
+		historyDir := filepath.Join(t.TempDir(), "history")
+		var buf bytes.Buffer
+		result, err := NewService(mock).Run(context.Background(), Spec{
+			Model:      "test-model",
+			Prompt:     "a logo on a shirt",
+			TextOnly:   true,
+			Evaluation: policy,
+		}, historyDir, &buf)
+		require.NoError(t, err)
+		return mock, historyDir, result, buf.String()
+	}
+
+	t.Run("records verdicts", func(t *testing.T) {
+		t.Parallel()
+		_, historyDir, result, out := run(t, EvaluationPolicy{})
+		assert.True(t, result.Rejected)
+		assert.Contains(t, out, "Evaluation failed:")
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		assert.True(t, entry.Result.Success, "recording only must not mark the entry")
+		require.Len(t, entry.Result.Evaluations, 1)
+		assert.False(t, entry.Result.Evaluations[0].Passed)
+		assert.Equal(t, "logo area covered", entry.Result.Evaluations[0].Notes)
+	})
+
+	t.Run("marks rejected entries failed", func(t *testing.T) {
+		t.Parallel()
+		mock, historyDir, result, _ := run(t, EvaluationPolicy{MarkFailed: true})
+		assert.Equal(t, 1, mock.callCount())
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		assert.False(t, entry.Result.Success)
+		assert.Contains(t, entry.Result.ErrorMessage, "rejected by evaluator")
+		assert.NotEmpty(t, entry.Result.OutputImages, "outputs of rejected entries are kept")
+	})
+
+	t.Run("retries until accepted", func(t *testing.T) {
+		t.Parallel()
+		mock, historyDir, result, out := run(t, EvaluationPolicy{MarkFailed: true, Retries: 2})
+		assert.Equal(t, 2, mock.callCount())
+		assert.False(t, result.Rejected)
+		assert.Contains(t, out, "Retrying rejected entry")
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.False(t, entries[0].Result.Success)
+		assert.True(t, entries[1].Result.Success)
+		require.NotNil(t, entries[1].Lineage)
+		assert.Equal(t, entries[0].ID, entries[1].Lineage.RegeneratedFrom)
+		assert.Equal(t, "logo area clear", entries[1].Result.Evaluations[0].Notes)
+	})
+
+	t.Run("gets its own scratch directory", func(t *testing.T) {
+		t.Parallel()
+		tmpScript := filepath.Join(t.TempDir(), "tmp.sh")
+		require.NoError(t, os.WriteFile(tmpScript, []byte("#!/bin/sh\n"+
+			"test -d \"$BANAGO_TMP_DIR\" && test \"$TMPDIR\" = \"$BANAGO_TMP_DIR\" || exit 1\n"+
+			"echo \"$BANAGO_TMP_DIR\"\n"), 0o755))
+		tmpDir := t.TempDir()
+		historyDir := filepath.Join(t.TempDir(), "history")
+		var buf bytes.Buffer
+		result, err := NewService(newSuccessMock(pngData)).Run(context.Background(), Spec{
+			Model:      "test-model",
+			Prompt:     "a logo on a shirt",
+			TextOnly:   true,
+			Evaluation: EvaluationPolicy{Commands: []string{tmpScript}, Dir: t.TempDir(), TmpDir: tmpDir},
+		}, historyDir, &buf)
+		require.NoError(t, err)
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		require.Len(t, entry.Result.Evaluations, 1)
+		assert.True(t, entry.Result.Evaluations[0].Passed)
+		assert.Equal(t, tmpDir, filepath.Dir(entry.Result.Evaluations[0].Notes))
+		assert.NoDirExists(t, entry.Result.Evaluations[0].Notes, "the scratch directory is removed after the evaluator")
+	})
+}
+
+func TestService_Run_Events(t *testing.T) {
+	t.Parallel()
+
+	// Setup project
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputsDir := project.GetInputsDir(subprojectDir)
+	require.NoError(t, os.WriteFile(filepath.Join(inputsDir, "test.png"), pngData, 0o644))
+	spec := Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{filepath.Join(inputsDir, "test.png")},
+		InputImageNames: []string{"test.png"},
+	}
+
+	collect := func(events *[]Event) EventSink {
+		return func(e Event) { *events = append(*events, e) }
+	}
+	types := func(events []Event) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.Type)
+		}
+		return out
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var events []Event
+		svc := NewService(newMultiImageMock(pngData, 2)).WithEvents(collect(&events))
+		result, err := svc.Run(context.Background(), spec, historyDir, io.Discard)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{EventValidation, EventRequestSent, EventImageSaved, EventImageSaved, EventEntryCreated}, types(events))
+		assert.Equal(t, result.EntryID, events[len(events)-1].EntryID)
+		assert.FileExists(t, events[2].Image)
+		for _, e := range events {
+			assert.NotEmpty(t, e.Time)
+		}
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		var events []Event
+		svc := NewService(newSuccessMock(pngData)).WithEvents(collect(&events))
+		invalid := spec
+		invalid.ImageSize = "8K"
+		_, err := svc.Run(context.Background(), invalid, historyDir, io.Discard)
+		require.Error(t, err)
+
+		require.Equal(t, []string{EventValidation}, types(events))
+		assert.Equal(t, err.Error(), events[0].Error)
+	})
+
+	t.Run("API error", func(t *testing.T) {
+		var events []Event
+		svc := NewService(newErrorMock(errors.New("API error"))).WithEvents(collect(&events))
+		_, err := svc.Run(context.Background(), spec, historyDir, io.Discard)
+		require.Error(t, err)
+
+		assert.Equal(t, []string{EventValidation, EventRequestSent, EventError}, types(events))
+		assert.Contains(t, events[2].Error, "API error")
+	})
+}
+
+func TestService_Run_Candidates(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	spec := Spec{
+		Model:      "test-model",
+		Prompt:     "test prompt",
+		TextOnly:   true,
+		Candidates: 3,
+	}
+
+	mock := newMultiImageMock(pngData, 3)
+	result, err := NewService(mock).Run(context.Background(), spec, historyDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Len(t, mock.calls, 1, "all candidates come from one call")
+	assert.Equal(t, 3, mock.calls[0].Candidates)
+
+	entry, err := history.GetEntryByID(historyDir, result.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, entry.Generation.Candidates)
+	assert.Len(t, entry.Result.OutputImages, 3)
+
+	spec.Candidates = MaxCandidates + 1
+	_, err = NewService(newSuccessMock(pngData)).Run(context.Background(), spec, historyDir, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrInvalidSpec)
+	assert.ErrorContains(t, err, "invalid candidates")
+}
+
+func TestService_FailureLog(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+	spec := Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+	}
+
+	// A quota error, a response without images and a success
+	for _, generator := range []Generator{
+		newErrorMock(fmt.Errorf("%w: slow down", gemini.ErrQuota)),
+		&mockGenerator{},
+		newSuccessMock(pngData),
+	} {
+		_, _ = NewService(generator).Run(context.Background(), spec, historyDir, &bytes.Buffer{})
+	}
+
+	failures, err := history.ListFailures(historyDir)
+	require.NoError(t, err)
+	require.Len(t, failures, 2)
+	assert.Equal(t, gemini.FailureQuota, failures[0].Category)
+	assert.Equal(t, history.FailureKindGenerate, failures[0].Kind)
+	assert.Equal(t, "test-model", failures[0].Model)
+	assert.NotEmpty(t, failures[0].EntryID)
+	assert.Equal(t, gemini.FailureEmpty, failures[1].Category)
+
+	days, err := history.FailuresByDay(historyDir, time.Now().Add(-time.Hour), time.Time{})
+	require.NoError(t, err)
+	require.Len(t, days, 1)
+	for _, day := range days {
+		assert.Equal(t, 3, day.Runs)
+		assert.Equal(t, 2, day.Failed())
+		assert.Equal(t, map[string]int{gemini.FailureQuota: 1, gemini.FailureEmpty: 1}, day.Categories)
+	}
+}
+
+func TestService_Provenance(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	require.NoError(t, project.InitProject(projectRoot, "test-project", false))
+	require.NoError(t, project.CreateSubproject(projectRoot, "test-sub", ""))
+	subprojectDir := project.GetSubprojectDir(projectRoot, "test-sub")
+	historyDir := filepath.Join(subprojectDir, "history")
+
+	pngData, err := os.ReadFile("testdata/sample.png")
+	require.NoError(t, err)
+	inputPath := filepath.Join(project.GetInputsDir(subprojectDir), "test.png")
+	require.NoError(t, os.WriteFile(inputPath, pngData, 0o644))
+
+	svc := NewService(newSuccessMock(pngData))
+	result, err := svc.Run(context.Background(), Spec{
+		Model:           "test-model",
+		Prompt:          "test prompt",
+		ImagePaths:      []string{inputPath},
+		InputImageNames: []string{"test.png"},
+	}, historyDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	entry, err := history.GetEntryByID(historyDir, result.EntryID)
+	require.NoError(t, err)
+	entryDir := entry.GetEntryDir(historyDir)
+	outputPath := history.GetEntryFilePath(entryDir, result.OutputImages[0])
+	data, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	prov, err := gemini.ReadProvenance(data)
+	require.NoError(t, err)
+	assert.Equal(t, &gemini.Provenance{
+		EntryID:    entry.ID,
+		Model:      "test-model",
+		PromptHash: gemini.PromptHash("test prompt"),
+		CreatedAt:  entry.CreatedAt,
+	}, prov)
+
+	// Edits converted to JPEG carry the edit ID in EXIF
+	editResult, err := svc.Edit(context.Background(), EditSpec{
+		Model:           "test-model",
+		Prompt:          "make it night",
+		SourceImagePath: outputPath,
+		EntryID:         entry.ID,
+		SourceType:      "generate",
+		SourceOutput:    result.OutputImages[0],
+		OutputFormat:    gemini.FormatJPEG,
+	}, historyDir, &bytes.Buffer{})
+	require.NoError(t, err)
+	edit, err := history.GetEditEntryByID(entryDir, editResult.EditID)
+	require.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join(edit.GetEditEntryDir(entryDir), edit.Result.OutputImages[0]))
+	require.NoError(t, err)
+	prov, err = gemini.ReadProvenance(data)
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, prov.EntryID)
+	assert.Equal(t, edit.ID, prov.EditID)
+	assert.Equal(t, gemini.PromptHash("make it night"), prov.PromptHash)
+
+	_, err = gemini.ReadProvenance(pngData)
+	assert.ErrorIs(t, err, gemini.ErrNoProvenance)
+}
@@ -1,5 +1,14 @@
 package generation
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+)
+
 // Spec holds all information needed for generation and to be saved to history.
 type Spec struct {
 	// Generation parameters
@@ -9,11 +18,110 @@ type Spec struct {
 	AspectRatio string
 	ImageSize   string
 
+	// Seed sent to the API so a generation can be reproduced (nil = a random one, recorded like a given one)
+	Seed *int32
+
+	// Variants one API call returns, all saved as outputs of the same entry (0 = one; at most MaxCandidates)
+	Candidates int
+
+	// Format outputs are converted to when saved (gemini.FormatPNG, FormatJPEG, FormatWebP; "" = as returned)
+	OutputFormat string
+
+	// What Model accepts, checked before the API call (nil = the bundled table, see gemini.BundledCapabilities)
+	Capabilities *gemini.Capabilities
+
 	// For history metadata - the filenames of input images
 	InputImageNames []string
 
+	// Model was chosen for this run (--model) instead of by banago.yaml; regenerate then reuses it
+	ModelOverride bool
+
 	// Source entry ID for regeneration tracking (empty for new generation)
 	SourceEntryID string
+
+	// Layout of the new entry directory, the project's history_layout (history.LayoutFlat or LayoutNested)
+	Layout string
+
+	// What to archive into the history entry besides prompt and outputs
+	Archive ArchivePolicy
+
+	// Measure and record the prompt token contribution of the text and each input image
+	TokenBreakdown bool
+
+	// Allow generating from the prompt alone, without input images
+	TextOnly bool
+
+	// Maximum estimated prompt tokens (0 = unlimited). Inputs are in priority order:
+	// the last ones are dropped until the estimate fits.
+	TokenBudget int
+
+	// Combine responses with multiple frames into an animated GIF in the entry
+	AssembleAnimation bool
+
+	// Where to mirror the outputs besides the history entry
+	Mirror MirrorPolicy
+
+	// Warn when less than this many bytes would remain free after saving the outputs (0 = no warning).
+	// A run whose estimated outputs do not fit is always refused.
+	MinFreeDisk int64
+
+	// Keep the entry with the API error recorded when generation fails, instead of removing it
+	KeepFailures bool
+
+	// External checks run on the new entry, and what a rejection does
+	Evaluation EvaluationPolicy
+
+	// When set, Prompt is replaced by this template rendered for each generation (nil to send Prompt as is)
+	PromptTemplate *PromptTemplate
+
+	// Prepend the context and character files of Archive to the prompt sent to the API.
+	// The entry keeps the prompt as given; the files are archived next to it.
+	IncludeContext bool
+}
+
+// ArchivePolicy controls what is archived into a history entry.
+// The zero value copies input images and archives nothing else.
+type ArchivePolicy struct {
+	HashInputsOnly     bool   // record input image hashes instead of copying the images
+	ProjectContextPath string // project-level context file archived before ContextPath (empty to skip)
+	ContextPath        string // context file to copy into the entry (empty to skip)
+	CharacterPath      string // character file to copy into the entry (empty to skip)
+	RawResponse        bool   // save the raw API response
+}
+
+// contextPaths returns the context files to archive, project-level first
+func (p ArchivePolicy) contextPaths() []string {
+	var paths []string
+	for _, path := range []string{p.ProjectContextPath, p.ContextPath} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// withContext returns prompt preceded by the context files and the character file, each separated
+// by a blank line. Empty files are skipped; without any file the prompt is returned unchanged.
+func (p ArchivePolicy) withContext(prompt string, w io.Writer) (string, error) {
+	paths := p.contextPaths()
+	if p.CharacterPath != "" {
+		paths = append(paths, p.CharacterPath)
+	}
+	var parts []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read context for the prompt: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 {
+		_, _ = fmt.Fprintln(w, "Warning: no context or character file to include in the prompt")
+		return prompt, nil
+	}
+	return strings.Join(append(parts, prompt), "\n\n"), nil
 }
 
 // EditSpec holds all information needed for editing an existing image.
@@ -24,9 +132,18 @@ type EditSpec struct {
 	AspectRatio string
 	ImageSize   string
 
+	// Format outputs are converted to when saved ("" = as returned)
+	OutputFormat string
+
+	// Model was chosen for this run (--model) instead of by banago.yaml
+	ModelOverride bool
+
 	// Source image information
 	SourceImagePath string
 
+	// Optional mask image: white areas may change, black areas are preserved
+	MaskPath string
+
 	// History context
 	EntryID string // The generate entry ID
 
@@ -34,4 +151,16 @@ type EditSpec struct {
 	SourceType   string // "generate" or "edit"
 	SourceEditID string // If editing from an edit, the source edit ID
 	SourceOutput string // The output filename being edited
+
+	// The edit re-renders the source at a higher ImageSize (banago upscale)
+	Upscale bool
+
+	// Where to mirror the outputs besides the history entry
+	Mirror MirrorPolicy
+
+	// Warn when less than this many bytes would remain free after saving the outputs (0 = no warning)
+	MinFreeDisk int64
+
+	// Keep the edit with the API error recorded when editing fails, instead of removing it
+	KeepFailures bool
 }
@@ -4,41 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"regexp"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
 )
 
-// Valid image sizes
-var validSizes = map[string]bool{
-	"1K": true,
-	"2K": true,
-	"4K": true,
-}
+// ErrInvalidSpec is returned when a spec is rejected before any API call: bad aspect ratio or image size,
+// or missing input images
+var ErrInvalidSpec = errors.New("invalid generation parameters")
 
-// aspectRatioRegex matches patterns like "1:1", "16:9", "4:3"
-var aspectRatioRegex = regexp.MustCompile(`^\d+:\d+$`)
+// MaxCandidates is the most variants the API returns for one request
+const MaxCandidates = 8
 
 // validateAspectRatio validates the aspect ratio format (N:N pattern).
 // Empty string is allowed (uses API default).
 func validateAspectRatio(aspect string) error {
-	if aspect == "" {
-		return nil
-	}
-	if !aspectRatioRegex.MatchString(aspect) {
-		return fmt.Errorf("invalid aspect ratio %q: must be in N:N format (e.g., 1:1, 16:9)", aspect)
-	}
-	return nil
+	return config.ValidateAspectRatio(aspect)
 }
 
 // validateImageSize validates the image size value.
 // Empty string is allowed (uses API default).
 func validateImageSize(size string) error {
-	if size == "" {
-		return nil
-	}
-	if !validSizes[size] {
-		return fmt.Errorf("invalid image size %q: must be 1K, 2K, or 4K", size)
-	}
-	return nil
+	return config.ValidateImageSize(size)
 }
 
 // validateInputImages checks that all input image files exist.
@@ -58,15 +45,42 @@ func validateInputImages(paths []string) error {
 	return nil
 }
 
+// ValidateSpec validates a spec for callers that invoke a Generator directly instead of through Service.
+func ValidateSpec(spec Spec) error {
+	return validateSpec(spec)
+}
+
 // validateSpec validates the generation spec before making API calls.
 func validateSpec(spec Spec) error {
+	if err := checkSpec(spec); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+	return nil
+}
+
+func checkSpec(spec Spec) error {
 	if err := validateAspectRatio(spec.AspectRatio); err != nil {
 		return err
 	}
 	if err := validateImageSize(spec.ImageSize); err != nil {
 		return err
 	}
-	if len(spec.ImagePaths) == 0 {
+	if err := config.ValidateOutputFormat(spec.OutputFormat); err != nil {
+		return err
+	}
+	caps := spec.Capabilities
+	if bundled, ok := gemini.BundledCapabilities(spec.Model); caps == nil && ok {
+		caps = &bundled
+	}
+	if caps != nil {
+		if err := caps.Check(spec.AspectRatio, spec.ImageSize, len(spec.ImagePaths)); err != nil {
+			return err
+		}
+	}
+	if spec.Candidates < 0 || spec.Candidates > MaxCandidates {
+		return fmt.Errorf("invalid candidates %d: must be 1 to %d", spec.Candidates, MaxCandidates)
+	}
+	if len(spec.ImagePaths) == 0 && !spec.TextOnly {
 		return errors.New("no input images specified")
 	}
 	if err := validateInputImages(spec.ImagePaths); err != nil {
@@ -1,9 +1,12 @@
 package generation
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
 )
 
 func Test_validateAspectRatio(t *testing.T) {
@@ -147,8 +150,8 @@ func Test_validateSpec(t *testing.T) {
 			ImageSize:   "2K",
 		}
 		err := validateSpec(spec)
-		if err == nil {
-			t.Error("validateSpec() expected error for invalid aspect ratio")
+		if !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("validateSpec() error = %v, want ErrInvalidSpec", err)
 		}
 	})
 
@@ -167,6 +170,30 @@ func Test_validateSpec(t *testing.T) {
 		}
 	})
 
+	t.Run("unsupported by the model", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			name string
+			spec Spec
+		}{
+			{"bundled aspect ratio", Spec{Model: "gemini-3-pro-image-preview", AspectRatio: "2:1"}},
+			{"bundled image size", Spec{Model: "gemini-2.5-flash-image", ImageSize: "4K"}},
+			{"bundled input count", Spec{Model: "gemini-2.5-flash-image", ImagePaths: []string{testFile, testFile, testFile, testFile}}},
+			{"probed aspect ratio", Spec{Model: "gemini-next-image", AspectRatio: "21:9", Capabilities: &gemini.Capabilities{
+				Model: "gemini-next-image", AspectRatios: []string{"1:1", "16:9"},
+			}}},
+		}
+		for _, tt := range tests {
+			tt.spec.Prompt = "test prompt"
+			if len(tt.spec.ImagePaths) == 0 {
+				tt.spec.ImagePaths = []string{testFile}
+			}
+			if err := validateSpec(tt.spec); !errors.Is(err, ErrInvalidSpec) {
+				t.Errorf("%s: validateSpec() error = %v, want ErrInvalidSpec", tt.name, err)
+			}
+		}
+	})
+
 	t.Run("no input images", func(t *testing.T) {
 		t.Parallel()
 		spec := Spec{
@@ -182,6 +209,18 @@ func Test_validateSpec(t *testing.T) {
 		}
 	})
 
+	t.Run("no input images in text-only mode", func(t *testing.T) {
+		t.Parallel()
+		spec := Spec{
+			Model:    "gemini-2.0-flash-exp-image-generation",
+			Prompt:   "test prompt",
+			TextOnly: true,
+		}
+		if err := validateSpec(spec); err != nil {
+			t.Errorf("validateSpec() error = %v, want nil", err)
+		}
+	})
+
 	t.Run("missing input image", func(t *testing.T) {
 		t.Parallel()
 		spec := Spec{
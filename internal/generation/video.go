@@ -0,0 +1,143 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+)
+
+// VideoGenerator defines the interface for video generation providers.
+// gemini.Client implements it with Veo; tests use mocks.
+type VideoGenerator interface {
+	GenerateVideo(ctx context.Context, params gemini.VideoParams) *gemini.VideoResult
+}
+
+// VideoSpec holds all information needed for video generation and to be saved to history.
+type VideoSpec struct {
+	Model           string
+	Prompt          string
+	ImagePath       string // optional first frame
+	AspectRatio     string
+	Resolution      string
+	DurationSeconds int
+	Layout          string // layout of the new entry directory, the project's history_layout
+	KeepFailures    bool   // keep the entry with the error when the API call fails, as Spec.KeepFailures
+}
+
+// VideoService handles video generation with dependency injection support.
+type VideoService struct {
+	generator VideoGenerator
+}
+
+// NewVideoService creates a new VideoService with the given generator.
+func NewVideoService(generator VideoGenerator) *VideoService {
+	return &VideoService{generator: generator}
+}
+
+// Run executes the video generation workflow and saves the result to history.
+// Videos are recorded as the entry's outputs and the entry is marked with media: video.
+func (s *VideoService) Run(ctx context.Context, spec VideoSpec, historyDir string, w io.Writer) (*Result, error) {
+	if err := validateAspectRatio(spec.AspectRatio); err != nil {
+		return nil, err
+	}
+	if spec.DurationSeconds < 0 {
+		return nil, fmt.Errorf("invalid duration %d: must not be negative", spec.DurationSeconds)
+	}
+	if spec.Prompt == "" {
+		return nil, errors.New("prompt is empty")
+	}
+	var imagePaths []string
+	if spec.ImagePath != "" {
+		imagePaths = []string{spec.ImagePath}
+		if err := validateInputImages(imagePaths); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := history.NewEntry()
+	entry.SetLayout(spec.Layout)
+	entry.Generation.Media = history.MediaVideo
+	entry.Generation.Model = spec.Model
+	entry.Generation.PromptFile = history.PromptFile
+	entry.Generation.AspectRatio = spec.AspectRatio
+	entry.Generation.Resolution = spec.Resolution
+	entry.Generation.DurationSeconds = spec.DurationSeconds
+	entry.Generation.TextOnly = len(imagePaths) == 0
+	for _, p := range imagePaths {
+		entry.Generation.InputImages = append(entry.Generation.InputImages, filepath.Base(p))
+	}
+
+	if err := entry.SavePending(historyDir); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := entry.SavePrompt(historyDir, spec.Prompt); err != nil {
+		return nil, fmt.Errorf("failed to save prompt: %w", err)
+	}
+	if err := entry.SaveInputImages(historyDir, imagePaths); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to save input images: %v\n", err)
+	}
+
+	_, _ = fmt.Fprintln(w, "Generating video (this can take a few minutes)...")
+	result := s.generator.GenerateVideo(ctx, gemini.VideoParams{
+		Model:           spec.Model,
+		Prompt:          spec.Prompt,
+		ImagePath:       spec.ImagePath,
+		AspectRatio:     spec.AspectRatio,
+		Resolution:      spec.Resolution,
+		DurationSeconds: spec.DurationSeconds,
+	})
+	if errors.Is(result.Error, gemini.ErrCanceled) {
+		// Interrupted or timed out: nothing failed, so leave no trace in history
+		if err := entry.Cleanup(historyDir); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
+		}
+		return nil, fmt.Errorf("video generation stopped: %w", result.Error)
+	}
+	if result.Error != nil {
+		logFailure(historyDir, history.Failure{Kind: history.FailureKindVideo, EntryID: entry.ID, Model: spec.Model}, result.Error, w)
+		if spec.KeepFailures {
+			entry.Result.ErrorMessage = result.Error.Error()
+			if err := retryWrite(ctx, func() error { return entry.Save(historyDir) }); err != nil {
+				_, _ = fmt.Fprintf(w, "Warning: failed to record the failure in history: %v\n", err)
+			} else {
+				_, _ = fmt.Fprintf(w, "Failure recorded in history: %s\n", entry.ID)
+			}
+		} else if err := entry.Cleanup(historyDir); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
+		}
+		return nil, fmt.Errorf("failed to generate video: %w", result.Error)
+	}
+
+	saved, err := gemini.SaveVideos(result.Videos, entry.OutputDir(historyDir))
+	if err != nil {
+		if err := entry.Cleanup(historyDir); err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to clean up history directory: %v\n", err)
+		}
+		return nil, err
+	}
+
+	entry.Result.Success = true
+	for _, p := range saved {
+		entry.Result.OutputImages = append(entry.Result.OutputImages, filepath.Base(p))
+	}
+	if err := retryWrite(ctx, func() error { return entry.Save(historyDir) }); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to save history: %v\n", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "History ID: %s\n", entry.ID)
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintln(w, "Generated files:")
+	for _, p := range saved {
+		_, _ = fmt.Fprintf(w, "  %s\n", filepath.Base(p))
+	}
+
+	return &Result{
+		EntryID:      entry.ID,
+		OutputImages: entry.Result.OutputImages,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package generation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockVideoGenerator is a mock implementation of VideoGenerator for testing.
+type mockVideoGenerator struct {
+	videos []gemini.Video
+	err    error
+	calls  []gemini.VideoParams
+}
+
+// GenerateVideo implements VideoGenerator.
+func (m *mockVideoGenerator) GenerateVideo(_ context.Context, params gemini.VideoParams) *gemini.VideoResult {
+	m.calls = append(m.calls, params)
+	if m.err != nil {
+		return &gemini.VideoResult{Error: m.err}
+	}
+	return &gemini.VideoResult{Videos: m.videos}
+}
+
+func TestVideoService_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("saves video entry", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		pngData, err := os.ReadFile("testdata/sample.png")
+		require.NoError(t, err)
+		framePath := filepath.Join(t.TempDir(), "first.png")
+		require.NoError(t, os.WriteFile(framePath, pngData, 0o644))
+
+		mock := &mockVideoGenerator{videos: []gemini.Video{{Data: []byte("fake mp4"), MIMEType: "video/mp4"}}}
+		var buf bytes.Buffer
+		result, err := NewVideoService(mock).Run(context.Background(), VideoSpec{
+			Model:           "veo-test",
+			Prompt:          "a slow pan",
+			ImagePath:       framePath,
+			AspectRatio:     "16:9",
+			DurationSeconds: 8,
+		}, historyDir, &buf)
+		require.NoError(t, err)
+		require.Len(t, mock.calls, 1)
+		assert.Equal(t, 8, mock.calls[0].DurationSeconds)
+
+		entry, err := history.GetEntryByID(historyDir, result.EntryID)
+		require.NoError(t, err)
+		assert.Equal(t, history.MediaVideo, entry.Generation.Media)
+		assert.Equal(t, "veo-test", entry.Generation.Model)
+		assert.Equal(t, 8, entry.Generation.DurationSeconds)
+		assert.Equal(t, []string{"first.png"}, entry.Generation.InputImages)
+		require.Len(t, entry.Result.OutputImages, 1)
+		assert.Equal(t, ".mp4", filepath.Ext(entry.Result.OutputImages[0]))
+
+		data, err := os.ReadFile(filepath.Join(entry.GetEntryDir(historyDir), entry.Result.OutputImages[0]))
+		require.NoError(t, err)
+		assert.Equal(t, "fake mp4", string(data))
+	})
+
+	t.Run("cleans up on failure", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := &mockVideoGenerator{err: errors.New("quota exceeded")}
+		_, err := NewVideoService(mock).Run(context.Background(), VideoSpec{Model: "veo-test", Prompt: "a slow pan"}, historyDir, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "quota exceeded")
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		// The failure log still counts it
+		failures, err := history.ListFailures(historyDir)
+		require.NoError(t, err)
+		require.Len(t, failures, 1)
+		assert.Equal(t, history.FailureKindVideo, failures[0].Kind)
+		assert.Equal(t, "veo-test", failures[0].Model)
+		assert.Contains(t, failures[0].Error, "quota exceeded")
+	})
+
+	t.Run("keeps failure", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := &mockVideoGenerator{err: errors.New("quota exceeded")}
+		var buf bytes.Buffer
+		_, err := NewVideoService(mock).Run(context.Background(), VideoSpec{Model: "veo-test", Prompt: "a slow pan", KeepFailures: true}, historyDir, &buf)
+		require.Error(t, err)
+		assert.Contains(t, buf.String(), "Failure recorded in history")
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.False(t, entries[0].Result.Success)
+		assert.Contains(t, entries[0].Result.ErrorMessage, "quota exceeded")
+		assert.Equal(t, "veo-test", entries[0].Generation.Model)
+	})
+
+	t.Run("leaves no trace when canceled", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		mock := &mockVideoGenerator{err: fmt.Errorf("%w: %w", gemini.ErrCanceled, context.Canceled)}
+		_, err := NewVideoService(mock).Run(context.Background(), VideoSpec{Prompt: "a slow pan", KeepFailures: true}, historyDir, &bytes.Buffer{})
+		require.ErrorIs(t, err, gemini.ErrCanceled)
+
+		entries, err := history.ListEntries(historyDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		failures, err := history.ListFailures(historyDir)
+		require.NoError(t, err)
+		assert.Empty(t, failures)
+	})
+
+	t.Run("rejects invalid aspect ratio", func(t *testing.T) {
+		t.Parallel()
+		mock := &mockVideoGenerator{}
+		_, err := NewVideoService(mock).Run(context.Background(), VideoSpec{Prompt: "a", AspectRatio: "wide"}, t.TempDir(), &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Empty(t, mock.calls)
+	})
+}
@@ -0,0 +1,52 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxRating is the highest rating of an entry; ratings go from 1 to MaxRating and 0 means unrated
+const MaxRating = 5
+
+// ValidateTag checks that tag can be stored and passed on the command line: not empty, without
+// whitespace or commas
+func ValidateTag(tag string) error {
+	if tag == "" || strings.ContainsFunc(tag, func(r rune) bool { return r == ',' || r <= ' ' }) {
+		return fmt.Errorf("invalid tag %q: must be non-empty without whitespace or commas", tag)
+	}
+	return nil
+}
+
+// AddTags adds the tags the entry does not have yet, keeping Tags sorted, and reports whether any was added
+func (e *Entry) AddTags(tags ...string) bool {
+	changed := false
+	for _, tag := range tags {
+		if !slices.Contains(e.Tags, tag) {
+			e.Tags = append(e.Tags, tag)
+			changed = true
+		}
+	}
+	slices.Sort(e.Tags)
+	return changed
+}
+
+// RemoveTags removes the tags from the entry and reports whether it had any of them
+func (e *Entry) RemoveTags(tags ...string) bool {
+	n := len(e.Tags)
+	e.Tags = slices.DeleteFunc(e.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	if len(e.Tags) == 0 {
+		e.Tags = nil
+	}
+	return len(e.Tags) != n
+}
+
+// SetRating sets the rating of the entry (0 clears it) and reports whether it changed
+func (e *Entry) SetRating(rating int) (bool, error) {
+	if rating < 0 || rating > MaxRating {
+		return false, fmt.Errorf("invalid rating %d: must be 0 to %d", rating, MaxRating)
+	}
+	changed := e.Rating != rating
+	e.Rating = rating
+	return changed, nil
+}
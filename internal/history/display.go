@@ -0,0 +1,92 @@
+package history
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Timestamps are stored in RFC3339 UTC; these helpers render them for people, in the local time zone.
+
+// LocalTime parses a stored timestamp (RFC3339) into the local time zone
+func LocalTime(value string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.Local(), true
+}
+
+// LocalDay returns the local calendar day of a stored timestamp as YYYY-MM-DD, or "" if it does not parse
+func LocalDay(value string) string {
+	t, ok := LocalTime(value)
+	if !ok {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+// FormatLocal renders a stored timestamp in local time in the layout of DisplayLayout, followed by
+// how long before now it was, e.g. "2026-01-31 14:05 (2 hours ago)". Values that do not parse are
+// returned as is.
+func FormatLocal(value string, now time.Time) string {
+	t, ok := LocalTime(value)
+	if !ok {
+		return value
+	}
+	return fmt.Sprintf("%s (%s)", t.Format(DisplayLayout()), RelativeTime(t, now))
+}
+
+// RelativeTime describes how long before now t was: "just now", "5 minutes ago", "2 hours ago",
+// then "yesterday", "3 days ago", "2 months ago" and "1 year ago" by local calendar day
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	}
+
+	y, m, dd := t.In(now.Location()).Date()
+	days := int(now.Sub(time.Date(y, m, dd, 0, 0, 0, 0, now.Location())).Hours() / 24)
+	switch {
+	case days <= 1:
+		return "yesterday"
+	case days < 30:
+		return plural(days, "day") + " ago"
+	case days < 365:
+		return plural(days/30, "month") + " ago"
+	}
+	return plural(days/365, "year") + " ago"
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// DisplayLayout returns the date and time layout for the user's locale, read from LC_ALL, LC_TIME
+// or LANG like the C library does. Locales without a known convention get YYYY-MM-DD HH:MM.
+func DisplayLayout() string {
+	locale := cmp.Or(os.Getenv("LC_ALL"), os.Getenv("LC_TIME"), os.Getenv("LANG"))
+	locale, _, _ = strings.Cut(locale, ".") // en_US.UTF-8
+	lang, _, _ := strings.Cut(locale, "_")
+	switch {
+	case locale == "en_US":
+		return "Jan 2, 2006 3:04 PM"
+	case lang == "en":
+		return "2 Jan 2006 15:04"
+	case lang == "de" || lang == "ru" || lang == "pl":
+		return "02.01.2006 15:04"
+	case lang == "fr" || lang == "es" || lang == "it" || lang == "pt":
+		return "02/01/2006 15:04"
+	}
+	return "2006-01-02 15:04"
+}
@@ -1,30 +1,41 @@
 package history
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
 
+	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
 )
 
 // EditEntry represents an edit entry (edit-meta.yaml)
 type EditEntry struct {
-	ID         string         `yaml:"id"`
-	CreatedAt  string         `yaml:"created_at"`
-	Source     EditSource     `yaml:"source"`
-	Generation EditGeneration `yaml:"generation"`
-	Result     Result         `yaml:"result"`
+	SchemaVersion string         `yaml:"schema_version,omitempty"` // see SchemaVersion; empty for edits written before it existed
+	ID            string         `yaml:"id"`
+	CreatedAt     string         `yaml:"created_at"`
+	Finalized     bool           `yaml:"finalized,omitempty"` // set once the outputs are written; see SavePending
+	Source        EditSource     `yaml:"source"`
+	Generation    EditGeneration `yaml:"generation"`
+	Result        Result         `yaml:"result"`
+	Extra         Extra          `yaml:",inline"`
 }
 
 // EditGeneration contains edit generation parameters
 type EditGeneration struct {
-	PromptFile  string `yaml:"prompt_file"`
-	AspectRatio string `yaml:"aspect_ratio,omitempty"`
-	ImageSize   string `yaml:"image_size,omitempty"`
+	Model         string `yaml:"model,omitempty"`
+	ModelOverride bool   `yaml:"model_override,omitempty"` // Model came from --model rather than banago.yaml
+	PromptFile    string `yaml:"prompt_file"`
+	AspectRatio   string `yaml:"aspect_ratio,omitempty"`
+	ImageSize     string `yaml:"image_size,omitempty"`
+	MaskFile      string `yaml:"mask_file,omitempty"` // mask image copied into the edit directory
+	Upscale       bool   `yaml:"upscale,omitempty"`   // made by banago upscale: the source at a higher image size
+	Extra         Extra  `yaml:",inline"`
 }
 
 // EditSource contains information about the source of the edit
@@ -32,12 +43,12 @@ type EditSource struct {
 	Type   string `yaml:"type"`              // "generate" or "edit"
 	EditID string `yaml:"edit_id,omitempty"` // edit ID if type is "edit"
 	Output string `yaml:"output"`            // source output image filename
+	Extra  Extra  `yaml:",inline"`
 }
 
 const (
 	editMetaFile   = "edit-meta.yaml"
 	EditPromptFile = "edit-prompt.txt"
-	editsDir       = "edits"
 )
 
 // NewEditEntry creates a new edit entry with a UUID v7 ID
@@ -52,36 +63,47 @@ func NewEditEntry() *EditEntry {
 	}
 }
 
-// GetEditsDir returns the path to the edits directory within an entry
-func GetEditsDir(entryDir string) string {
-	return filepath.Join(entryDir, editsDir)
-}
-
 // GetEditEntryDir returns the path to a specific edit entry directory
 func (e *EditEntry) GetEditEntryDir(entryDir string) string {
-	return filepath.Join(GetEditsDir(entryDir), e.ID)
+	return GetEditDirByID(entryDir, e.ID)
 }
 
-// Save writes the edit entry to the edits directory
+// Save writes the edit entry to the edits directory and marks it finalized
 func (e *EditEntry) Save(entryDir string) error {
+	return e.save(entryDir, true)
+}
+
+func (e *EditEntry) save(entryDir string, finalized bool) error {
 	editDir := e.GetEditEntryDir(entryDir)
 	if err := os.MkdirAll(editDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create edit directory: %w", err)
 	}
 
+	e.SchemaVersion = saveSchemaVersion(e.SchemaVersion)
+	e.Finalized = finalized
 	data, err := yaml.Marshal(e)
 	if err != nil {
 		return fmt.Errorf("failed to marshal edit entry: %w", err)
 	}
 
 	metaPath := filepath.Join(editDir, editMetaFile)
-	if err := os.WriteFile(metaPath, data, 0o644); err != nil {
+	if err := writeMetaFile(metaPath, data); err != nil {
 		return fmt.Errorf("failed to write edit-meta.yaml: %w", err)
 	}
 
 	return nil
 }
 
+// SaveMask copies the mask image into the edit entry directory as mask<ext>
+func (e *EditEntry) SaveMask(entryDir, srcPath string) error {
+	name := "mask" + filepath.Ext(srcPath)
+	if err := copyFile(srcPath, filepath.Join(e.GetEditEntryDir(entryDir), name)); err != nil {
+		return fmt.Errorf("failed to save mask image: %w", err)
+	}
+	e.Generation.MaskFile = name
+	return nil
+}
+
 // SavePrompt saves the edit prompt text to the edit entry directory
 func (e *EditEntry) SavePrompt(entryDir, prompt string) error {
 	editDir := e.GetEditEntryDir(entryDir)
@@ -102,6 +124,9 @@ func (e *EditEntry) Cleanup(entryDir string) error {
 func loadEditEntry(editDir string) (*EditEntry, error) {
 	metaPath := filepath.Join(editDir, editMetaFile)
 	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, filepath.Base(editDir))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read edit-meta.yaml: %w", err)
 	}
@@ -110,6 +135,12 @@ func loadEditEntry(editDir string) (*EditEntry, error) {
 	if err := yaml.Unmarshal(data, &entry); err != nil {
 		return nil, fmt.Errorf("failed to parse edit-meta.yaml: %w", err)
 	}
+	if err := checkSchemaVersion(editMetaFile, entry.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if !isFinalized(entry.SchemaVersion, entry.Finalized) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFinalized, entry.ID)
+	}
 
 	return &entry, nil
 }
@@ -145,7 +176,7 @@ func ListEditEntries(entryDir string) ([]*EditEntry, error) {
 			continue
 		}
 
-		editDir := filepath.Join(editsPath, e.Name())
+		editDir := GetEditDirByID(entryDir, e.Name())
 		entry, err := loadEditEntry(editDir)
 		if err != nil {
 			continue // Skip invalid entries
@@ -170,6 +201,30 @@ func CountEditEntries(entryDir string) int {
 	return len(entries)
 }
 
+// EditStats aggregates the edits of an entry
+type EditStats struct {
+	Count      int
+	TokenUsage gemini.TokenUsage
+	LastEditAt string
+}
+
+// ComputeEditStats returns aggregate statistics over the edits of an entry
+func ComputeEditStats(entryDir string) EditStats {
+	var stats EditStats
+	entries, err := ListEditEntries(entryDir)
+	if err != nil {
+		return stats
+	}
+	for _, e := range entries {
+		stats.Count++
+		stats.TokenUsage.Add(e.Result.TokenUsage)
+		if e.CreatedAt > stats.LastEditAt {
+			stats.LastEditAt = e.CreatedAt
+		}
+	}
+	return stats
+}
+
 // GetLatestEditEntry returns the most recent edit entry
 func GetLatestEditEntry(entryDir string) (*EditEntry, error) {
 	entries, err := ListEditEntries(entryDir)
@@ -184,11 +239,5 @@ func GetLatestEditEntry(entryDir string) (*EditEntry, error) {
 
 // GetEditEntryByID returns an edit entry by its ID
 func GetEditEntryByID(entryDir, id string) (*EditEntry, error) {
-	editDir := filepath.Join(GetEditsDir(entryDir), id)
-	return loadEditEntry(editDir)
-}
-
-// GetEditOutputPath returns the path to an output image in an edit entry
-func GetEditOutputPath(entryDir, editID, outputFilename string) string {
-	return filepath.Join(GetEditsDir(entryDir), editID, outputFilename)
+	return loadEditEntry(GetEditDirByID(entryDir, id))
 }
@@ -1,7 +1,12 @@
 package history
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,33 +19,87 @@ import (
 
 // Entry represents a history entry (meta.yaml)
 type Entry struct {
-	ID         string     `yaml:"id"`
-	CreatedAt  string     `yaml:"created_at"`
-	Generation Generation `yaml:"generation"`
-	Result     Result     `yaml:"result"`
+	SchemaVersion string     `yaml:"schema_version,omitempty"` // see SchemaVersion; empty for entries written before it existed
+	ID            string     `yaml:"id"`
+	CreatedAt     string     `yaml:"created_at"`
+	Finalized     bool       `yaml:"finalized,omitempty"` // set once the outputs are written; see SavePending
+	Generation    Generation `yaml:"generation"`
+	Result        Result     `yaml:"result"`
+	Lineage       *Lineage   `yaml:"lineage,omitempty"`
+	Tags          []string   `yaml:"tags,omitempty"`   // labels for curation, sorted; see AddTags
+	Rating        int        `yaml:"rating,omitempty"` // 1 to MaxRating; 0 = unrated
+	Extra         Extra      `yaml:",inline"`
 }
 
 // Generation contains generation parameters
 type Generation struct {
-	PromptFile    string   `yaml:"prompt_file"`
-	InputImages   []string `yaml:"input_images"`
-	ContextFile   string   `yaml:"context_file,omitempty"`
-	CharacterFile string   `yaml:"character_file,omitempty"`
-	AspectRatio   string   `yaml:"aspect_ratio,omitempty"`
-	ImageSize     string   `yaml:"image_size,omitempty"`
+	Model         string            `yaml:"model,omitempty"`
+	ModelOverride bool              `yaml:"model_override,omitempty"` // Model came from --model rather than banago.yaml
+	PromptFile    string            `yaml:"prompt_file"`
+	InputImages   []string          `yaml:"input_images"`
+	ContextFile   string            `yaml:"context_file,omitempty"`
+	CharacterFile string            `yaml:"character_file,omitempty"`
+	AspectRatio   string            `yaml:"aspect_ratio,omitempty"`
+	ImageSize     string            `yaml:"image_size,omitempty"`
+	InputHashes   map[string]string `yaml:"input_hashes,omitempty"`  // filename -> SHA-256, when inputs are not copied
+	InputObjects  map[string]string `yaml:"input_objects,omitempty"` // filename -> SHA-256 of the snapshot in the project's ObjectStore
+
+	TokenBreakdown *gemini.TokenBreakdown `yaml:"token_breakdown,omitempty"`
+	OmittedInputs  []string               `yaml:"omitted_inputs,omitempty"` // inputs dropped to fit the token budget
+	TextOnly       bool                   `yaml:"text_only,omitempty"`      // generated from the prompt alone
+	Seed           *int32                 `yaml:"seed,omitempty"`           // seed sent to the API; regenerate reuses it
+	Candidates     int                    `yaml:"candidates,omitempty"`     // variants requested in one call (unset = one)
+
+	// Config records the settings resolved from banago.yaml and config.yaml for this run. When set,
+	// Model, AspectRatio and ImageSize are the exact values sent (empty meaning the API default) and
+	// regenerate reuses them instead of the current config. Entries written before it existed lack it.
+	Config *ConfigSnapshot `yaml:"config,omitempty"`
+
+	// Video entries (banago video generate); outputs are then video files
+	Media           string `yaml:"media,omitempty"` // MediaVideo, or empty for images
+	Resolution      string `yaml:"resolution,omitempty"`
+	DurationSeconds int    `yaml:"duration_seconds,omitempty"`
+
+	Extra Extra `yaml:",inline"`
 }
 
+// ConfigSnapshot holds the config settings that shaped a generation besides its recorded parameters
+type ConfigSnapshot struct {
+	TokenBudget       int   `yaml:"token_budget,omitempty"`
+	AssembleAnimation bool  `yaml:"assemble_animation,omitempty"`
+	IncludeContext    bool  `yaml:"include_context,omitempty"` // context_file and character_file were prepended to the prompt
+	Extra             Extra `yaml:",inline"`
+}
+
+// MediaVideo marks an entry whose outputs are videos
+const MediaVideo = "video"
+
 // Result contains generation results
 type Result struct {
 	Success      bool              `yaml:"success"`
 	OutputImages []string          `yaml:"output_images,omitempty"`
+	Animation    string            `yaml:"animation,omitempty"` // GIF assembled from multi-frame outputs
 	TokenUsage   gemini.TokenUsage `yaml:"token_usage,omitempty"`
+	CostUSD      float64           `yaml:"estimated_cost_usd,omitempty"` // from token_usage and the model's pricing at generation time
 	ErrorMessage string            `yaml:"error_message,omitempty"`
+	Evaluations  []Evaluation      `yaml:"evaluations,omitempty"` // results of the configured evaluator commands
+	Extra        Extra             `yaml:",inline"`
+}
+
+// Evaluation is the verdict of one evaluator command run on the finished entry
+type Evaluation struct {
+	Evaluator string `yaml:"evaluator"` // the command as configured
+	Passed    bool   `yaml:"passed"`
+	Notes     string `yaml:"notes,omitempty"` // what the evaluator printed on stdout
+	Extra     Extra  `yaml:",inline"`
 }
 
 const (
-	metaFile   = "meta.yaml"
-	PromptFile = "prompt.txt"
+	metaFile      = "meta.yaml"
+	PromptFile    = "prompt.txt"
+	ContextFile   = "context.md"
+	CharacterFile = "character.md"
+	ResponseFile  = "response.json"
 )
 
 // NewEntry creates a new history entry with a UUID v7 ID
@@ -53,64 +112,151 @@ func NewEntry() *Entry {
 }
 
 // NewEntryFromSource creates a new entry copying Generation metadata from source
+// and recording source as the entry it was regenerated from
 func NewEntryFromSource(source *Entry) *Entry {
 	entry := NewEntry()
 	entry.Generation.PromptFile = source.Generation.PromptFile
 	entry.Generation.InputImages = append([]string{}, source.Generation.InputImages...)
+	entry.Lineage = &Lineage{RegeneratedFrom: source.ID}
 	return entry
 }
 
-// Save writes the entry to the history directory
+// Save writes the entry to the history directory and marks it finalized
 func (e *Entry) Save(historyDir string) error {
-	entryDir := filepath.Join(historyDir, e.ID)
+	return e.save(historyDir, true)
+}
+
+func (e *Entry) save(historyDir string, finalized bool) error {
+	entryDir := e.GetEntryDir(historyDir)
 	if err := os.MkdirAll(entryDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create entry directory: %w", err)
 	}
 
+	e.SchemaVersion = saveSchemaVersion(e.SchemaVersion)
+	e.Finalized = finalized
 	data, err := yaml.Marshal(e)
 	if err != nil {
 		return fmt.Errorf("failed to marshal entry: %w", err)
 	}
 
 	metaPath := filepath.Join(entryDir, metaFile)
-	if err := os.WriteFile(metaPath, data, 0o644); err != nil {
+	if err := writeMetaFile(metaPath, data); err != nil {
 		return fmt.Errorf("failed to write meta.yaml: %w", err)
 	}
+	indexEntry(historyDir, e.ID, data, finalized)
 
 	return nil
 }
 
 // SavePrompt saves the prompt text to the entry directory
 func (e *Entry) SavePrompt(historyDir, prompt string) error {
-	entryDir := filepath.Join(historyDir, e.ID)
-	promptPath := filepath.Join(entryDir, PromptFile)
+	promptPath := GetEntryFilePath(e.GetEntryDir(historyDir), PromptFile)
 	if err := os.WriteFile(promptPath, []byte(prompt), 0o644); err != nil {
 		return fmt.Errorf("failed to write prompt.txt: %w", err)
 	}
 	return nil
 }
 
-// SaveInputImages copies input images to the entry directory
+// SaveInputImages snapshots input images for the entry. Inside a project they go to the shared
+// ObjectStore, recorded in Generation.InputObjects, so identical inputs are stored once; otherwise
+// they are copied into the entry directory. An existing meta.yaml is rewritten to record the snapshots.
 func (e *Entry) SaveInputImages(historyDir string, srcPaths []string) error {
-	entryDir := filepath.Join(historyDir, e.ID)
+	store := FindObjectStore(historyDir)
 	for _, srcPath := range srcPaths {
+		if err := e.saveInputImage(historyDir, store, srcPath); err != nil {
+			return err
+		}
+	}
+	if store != nil && len(srcPaths) > 0 && fileExists(filepath.Join(e.GetEntryDir(historyDir), metaFile)) {
+		return e.save(historyDir, e.Finalized)
+	}
+	return nil
+}
+
+// SaveInputHashes records the SHA-256 of each input image instead of copying it
+func (e *Entry) SaveInputHashes(srcPaths []string) error {
+	hashes := make(map[string]string, len(srcPaths))
+	for _, srcPath := range srcPaths {
+		sum, err := hashFile(srcPath)
+		if err != nil {
+			return fmt.Errorf("failed to hash input image (%s): %w", srcPath, err)
+		}
+		hashes[filepath.Base(srcPath)] = sum
+	}
+	e.Generation.InputHashes = hashes
+	return nil
+}
+
+// ResolveHashedInputs returns paths to the input images in inputsDir,
+// failing if any image is missing or no longer matches its recorded hash
+func (e *Entry) ResolveHashedInputs(inputsDir string) ([]string, error) {
+	var paths []string
+	for _, filename := range e.Generation.InputImages {
+		want, ok := e.Generation.InputHashes[filename]
+		if !ok {
+			return nil, fmt.Errorf("no hash recorded for input image %s", filename)
+		}
+		path := filepath.Join(inputsDir, filename)
+		got, err := hashFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash input image (%s): %w", path, err)
+		}
+		if got != want {
+			return nil, fmt.Errorf("input image %s has changed since entry %s was created", filename, e.ID)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// SaveContextFile copies the context files into the entry directory as one context.md,
+// in order and separated by a blank line
+func (e *Entry) SaveContextFile(historyDir string, srcPaths ...string) error {
+	var buf bytes.Buffer
+	for i, srcPath := range srcPaths {
 		data, err := os.ReadFile(srcPath)
 		if err != nil {
-			return fmt.Errorf("failed to read input image (%s): %w", srcPath, err)
+			return fmt.Errorf("failed to archive context file: %w", err)
 		}
-		dstPath := filepath.Join(entryDir, filepath.Base(srcPath))
-		if err := os.WriteFile(dstPath, data, 0o644); err != nil {
-			return fmt.Errorf("failed to save input image (%s): %w", dstPath, err)
+		if i > 0 {
+			if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			buf.WriteByte('\n')
 		}
+		buf.Write(data)
+	}
+	if err := os.WriteFile(GetEntryFilePath(e.GetEntryDir(historyDir), ContextFile), buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to archive context file: %w", err)
+	}
+	e.Generation.ContextFile = ContextFile
+	return nil
+}
+
+// SaveCharacterFile copies the character file into the entry directory
+func (e *Entry) SaveCharacterFile(historyDir, srcPath string) error {
+	if err := copyFile(srcPath, GetEntryFilePath(e.GetEntryDir(historyDir), CharacterFile)); err != nil {
+		return fmt.Errorf("failed to archive character file: %w", err)
+	}
+	e.Generation.CharacterFile = CharacterFile
+	return nil
+}
+
+// SaveRawResponse writes the raw API response to the entry directory
+func (e *Entry) SaveRawResponse(historyDir string, data []byte) error {
+	path := GetEntryFilePath(e.GetEntryDir(historyDir), ResponseFile)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ResponseFile, err)
 	}
 	return nil
 }
 
-// GetInputImagePaths returns paths to input images in the entry directory
+// GetInputImagePaths returns paths to the input images of the entry in entryDir, copied into the
+// directory or kept in the object store (see ResolveEntryFile). Missing images are skipped.
 func GetInputImagePaths(entryDir string, filenames []string) []string {
 	var paths []string
 	for _, filename := range filenames {
-		path := filepath.Join(entryDir, filename)
+		path := ResolveEntryFile(entryDir, filename)
 		if _, err := os.Stat(path); err == nil {
 			paths = append(paths, path)
 		}
@@ -120,34 +266,113 @@ func GetInputImagePaths(entryDir string, filenames []string) []string {
 
 // GetEntryDir returns the path to the entry directory
 func (e *Entry) GetEntryDir(historyDir string) string {
-	return filepath.Join(historyDir, e.ID)
+	return GetEntryDirByID(historyDir, e.ID)
 }
 
 // Cleanup removes the entry directory (use on generation failure)
 func (e *Entry) Cleanup(historyDir string) error {
 	entryDir := e.GetEntryDir(historyDir)
-	return os.RemoveAll(entryDir)
+	if err := os.RemoveAll(entryDir); err != nil {
+		return err
+	}
+	unindexEntry(historyDir, e.ID)
+	return nil
+}
+
+// RemoveOutputs deletes output images matching any of the glob patterns and updates meta.yaml.
+// Patterns are matched against output filenames (e.g., "output-*-3.png").
+// Outputs used as the source of an edit are kept to preserve edit lineage.
+// Returns the removed filenames.
+func (e *Entry) RemoveOutputs(historyDir string, patterns []string) ([]string, error) {
+	entryDir := e.GetEntryDir(historyDir)
+
+	edits, err := ListEditEntries(entryDir)
+	if err != nil {
+		return nil, err
+	}
+	editSources := make(map[string]string)
+	for _, edit := range edits {
+		if edit.Source.Type == "generate" {
+			editSources[edit.Source.Output] = edit.ID
+		}
+	}
+
+	var removed, kept []string
+	for _, img := range e.Result.OutputImages {
+		matched := false
+		for _, pattern := range patterns {
+			ok, err := filepath.Match(pattern, img)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			kept = append(kept, img)
+			continue
+		}
+		if editID, ok := editSources[img]; ok {
+			return nil, fmt.Errorf("output %s is the source of edit %s", img, editID)
+		}
+		removed = append(removed, img)
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("patterns match all outputs of entry %s", e.ID)
+	}
+
+	for _, img := range removed {
+		if err := os.Remove(e.OutputPath(historyDir, img)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove output (%s): %w", img, err)
+		}
+	}
+
+	e.Result.OutputImages = kept
+	if err := e.Save(historyDir); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
 }
 
 // loadEntry reads an entry from the specified directory
 func loadEntry(entryDir string) (*Entry, error) {
 	metaPath := filepath.Join(entryDir, metaFile)
 	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, filepath.Base(entryDir))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read meta.yaml: %w", err)
 	}
+	return parseEntry(data)
+}
 
+// parseEntry parses meta.yaml content, rejecting incompatible and unfinalized entries
+func parseEntry(data []byte) (*Entry, error) {
 	var entry Entry
 	if err := yaml.Unmarshal(data, &entry); err != nil {
 		return nil, fmt.Errorf("failed to parse meta.yaml: %w", err)
 	}
+	if err := checkSchemaVersion(metaFile, entry.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if !isFinalized(entry.SchemaVersion, entry.Finalized) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFinalized, entry.ID)
+	}
 
 	return &entry, nil
 }
 
 // LoadPrompt reads the prompt from the entry directory
 func LoadPrompt(entryDir string) (string, error) {
-	promptPath := filepath.Join(entryDir, PromptFile)
+	promptPath := GetEntryFilePath(entryDir, PromptFile)
 	data, err := os.ReadFile(promptPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read prompt.txt: %w", err)
@@ -157,15 +382,33 @@ func LoadPrompt(entryDir string) (string, error) {
 
 // ListEntries returns all entries in the history directory, sorted by UUID v7 (chronological)
 func ListEntries(historyDir string) ([]*Entry, error) {
+	ids, err := entryIDs(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unchanged entries come from the index; invalid entries are skipped
+	result := listIndexed(historyDir, ids)
+
+	// Sort by UUID v7 (which is chronologically sortable)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// entryIDs returns the names of the entry directories in historyDir (none if it does not exist)
+func entryIDs(historyDir string) ([]string, error) {
 	entries, err := os.ReadDir(historyDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []*Entry{}, nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to read history directory: %w", err)
 	}
 
-	var result []*Entry
+	var ids []string
 	for _, e := range entries {
 		if !e.IsDir() {
 			continue
@@ -174,21 +417,9 @@ func ListEntries(historyDir string) ([]*Entry, error) {
 		if _, err := uuid.Parse(e.Name()); err != nil {
 			continue
 		}
-
-		entryDir := filepath.Join(historyDir, e.Name())
-		entry, err := loadEntry(entryDir)
-		if err != nil {
-			continue // Skip invalid entries
-		}
-		result = append(result, entry)
+		ids = append(ids, e.Name())
 	}
-
-	// Sort by UUID v7 (which is chronologically sortable)
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].ID < result[j].ID
-	})
-
-	return result, nil
+	return ids, nil
 }
 
 // GetLatestEntry returns the most recent entry
@@ -205,6 +436,24 @@ func GetLatestEntry(historyDir string) (*Entry, error) {
 
 // GetEntryByID returns an entry by its ID
 func GetEntryByID(historyDir, id string) (*Entry, error) {
-	entryDir := filepath.Join(historyDir, id)
-	return loadEntry(entryDir)
+	return loadEntry(GetEntryDirByID(historyDir, id))
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// copyFile copies a single file
+func copyFile(srcPath, dstPath string) error {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, data, 0o644)
 }
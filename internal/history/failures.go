@@ -0,0 +1,159 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FailureLogFile records every failed API call of a history directory, one JSON object per line.
+// Entries of failed calls are removed unless keep_failures is set, so failure statistics read the log.
+const FailureLogFile = "failures.jsonl"
+
+// Kinds of API calls in the failure log
+const (
+	FailureKindGenerate = "generate"
+	FailureKindEdit     = "edit"
+	FailureKindVideo    = "video"
+)
+
+// Failure is a failed API call
+type Failure struct {
+	Time     string `json:"time"` // RFC3339, UTC
+	Kind     string `json:"kind"` // FailureKindGenerate, FailureKindEdit or FailureKindVideo
+	EntryID  string `json:"entry_id"`
+	EditID   string `json:"edit_id,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Category string `json:"category"` // gemini.FailureCategory of the error
+	Error    string `json:"error"`
+}
+
+// RecordFailure appends f to the failure log of historyDir, stamping its time if unset
+func RecordFailure(historyDir string, f Failure) error {
+	if f.Time == "" {
+		f.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	line, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(historyDir, FailureLogFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open failure log: %w", err)
+	}
+	// One write per record keeps lines whole when parallel runs append at the same time
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write failure log: %w", err)
+	}
+	return file.Close()
+}
+
+// ListFailures returns the failures logged in historyDir, oldest first. Lines that are not valid
+// records (e.g. cut short by a crash) are skipped.
+func ListFailures(historyDir string) ([]Failure, error) {
+	file, err := os.Open(filepath.Join(historyDir, FailureLogFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failure log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var failures []Failure
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var f Failure
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil || f.Time == "" {
+			continue
+		}
+		failures = append(failures, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read failure log: %w", err)
+	}
+	return failures, nil
+}
+
+// FailureStats counts the API calls of a period and their failures by category
+type FailureStats struct {
+	Runs       int            // successful generations and edits plus failed calls
+	Categories map[string]int // failed calls by gemini.FailureCategory
+}
+
+// Failed returns the number of failed calls
+func (s *FailureStats) Failed() int {
+	n := 0
+	for _, c := range s.Categories {
+		n += c
+	}
+	return n
+}
+
+// Add adds the counts of other to s
+func (s *FailureStats) Add(other FailureStats) {
+	s.Runs += other.Runs
+	if s.Categories == nil {
+		s.Categories = map[string]int{}
+	}
+	for category, n := range other.Categories {
+		s.Categories[category] += n
+	}
+}
+
+// FailuresByDay returns the API calls of historyDir made at or after since and before until (the zero
+// time for no upper bound), keyed by their day (YYYY-MM-DD, UTC). Successful generations and edits
+// come from the entries, failures from the failure log.
+func FailuresByDay(historyDir string, since, until time.Time) (map[string]*FailureStats, error) {
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := ListFailures(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	days := map[string]*FailureStats{}
+	day := func(createdAt string) *FailureStats {
+		t, err := time.Parse(time.RFC3339, createdAt)
+		if err != nil || t.Before(since) || (!until.IsZero() && !t.Before(until)) {
+			return nil
+		}
+		key := t.UTC().Format(time.DateOnly)
+		if days[key] == nil {
+			days[key] = &FailureStats{Categories: map[string]int{}}
+		}
+		return days[key]
+	}
+
+	for _, e := range entries {
+		// Failed entries kept with keep_failures are already in the log
+		if s := day(e.CreatedAt); s != nil && e.Result.Success {
+			s.Runs++
+		}
+		edits, err := ListEditEntries(e.GetEntryDir(historyDir))
+		if err != nil {
+			continue
+		}
+		for _, edit := range edits {
+			if s := day(edit.CreatedAt); s != nil && edit.Result.Success {
+				s.Runs++
+			}
+		}
+	}
+	for _, f := range failures {
+		if s := day(f.Time); s != nil {
+			s.Runs++
+			s.Categories[f.Category]++
+		}
+	}
+	return days, nil
+}
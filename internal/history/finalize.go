@@ -0,0 +1,65 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFinalized is returned when loading an entry or edit whose generation has not finished writing it
+var ErrNotFinalized = errors.New("entry is still being written")
+
+// ErrEntryNotFound is returned when loading an entry or edit that does not exist
+var ErrEntryNotFound = errors.New("history entry not found")
+
+// finalizedSinceMinor is the schema minor version (of major 1) that introduced the finalized marker.
+// Metadata written before it has no marker and was only written once complete.
+const finalizedSinceMinor = 3
+
+// isFinalized reports whether metadata with the given schema version and finalized flag is complete
+func isFinalized(version string, finalized bool) bool {
+	if finalized {
+		return true
+	}
+	major, minor, err := parseSchemaVersion(version)
+	return err == nil && major == 1 && minor < finalizedSinceMinor
+}
+
+// SavePending writes the entry without the finalized marker, reserving its directory while a generation
+// writes the outputs. ListEntries and GetEntryByID ignore the entry until Save finalizes it.
+func (e *Entry) SavePending(historyDir string) error {
+	return e.save(historyDir, false)
+}
+
+// SavePending writes the edit entry without the finalized marker, like Entry.SavePending
+func (e *EditEntry) SavePending(entryDir string) error {
+	return e.save(entryDir, false)
+}
+
+// writeMetaFile replaces path with data through a temporary file and a rename,
+// so readers see either the old or the new content, never a partial write
+func writeMetaFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// gcGracePeriod is how long FindGarbage keeps unfinished entries, edits and temporary files after
+// their last change. Commands write history under the subproject lock, but banago versions before
+// it, or a process on another machine sharing the folder, may still be writing them.
+const gcGracePeriod = time.Hour
+
+// Garbage is a file or directory left behind by an interrupted run that nothing references
+type Garbage struct {
+	Path   string
+	Reason string
+	Size   int64
+	root   string // directory up to which directories emptied by removing Path are removed too
+
+	historyDir, entryID string // set for entry directories, which are dropped from the index
+}
+
+// FindGarbage returns the garbage in historyDir: entry directories without meta.yaml, edit directories
+// without edit-meta.yaml, entries and edits whose generation never finalized them, and temporary files
+// of interrupted metadata writes. Callers should hold the subproject lock; anything changed within
+// gcGracePeriod is kept regardless. Directories not named like entries are left alone (see FindOrphans).
+func FindGarbage(historyDir string) ([]Garbage, error) {
+	dirEntries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var garbage []Garbage
+	now := time.Now()
+	add := func(path, reason string) error {
+		if changedSince(path, now.Add(-gcGracePeriod)) {
+			return nil
+		}
+		size, err := DirSize(path)
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		garbage = append(garbage, Garbage{Path: path, Reason: reason, Size: size})
+		return nil
+	}
+	addTempFiles := func(dir string) error {
+		names, err := tempFiles(dir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := add(filepath.Join(dir, name), "temporary file of an interrupted write"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := addTempFiles(historyDir); err != nil {
+		return nil, err
+	}
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			continue
+		}
+		if _, err := uuid.Parse(d.Name()); err != nil {
+			continue
+		}
+		entryDir := GetEntryDirByID(historyDir, d.Name())
+		if reason := unfinishedReason(filepath.Join(entryDir, metaFile), "entry"); reason != "" {
+			n := len(garbage)
+			if err := add(entryDir, reason); err != nil {
+				return nil, err
+			}
+			if len(garbage) > n {
+				garbage[n].historyDir, garbage[n].entryID = historyDir, d.Name()
+			}
+			continue
+		}
+		for _, dir := range []string{entryDir, GetEntryOutputDir(entryDir, LayoutNested), GetEntryInputDir(entryDir, LayoutNested)} {
+			if err := addTempFiles(dir); err != nil {
+				return nil, err
+			}
+		}
+
+		edits, err := os.ReadDir(GetEditsDir(entryDir))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read edits of %s: %w", d.Name(), err)
+		}
+		for _, edit := range edits {
+			if !edit.IsDir() {
+				continue
+			}
+			editDir := GetEditDirByID(entryDir, edit.Name())
+			if reason := unfinishedReason(filepath.Join(editDir, editMetaFile), "edit"); reason != "" {
+				if err := add(editDir, reason); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := addTempFiles(editDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return garbage, nil
+}
+
+// unfinishedReason returns why the directory with the metadata file metaPath of an entry or edit
+// (kind) is garbage, or "" if it is complete. Metadata that cannot be parsed is not garbage: it may
+// be from a newer banago, and doctor reports it.
+func unfinishedReason(metaPath, kind string) string {
+	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("%s directory without %s", kind, filepath.Base(metaPath))
+	}
+	if err != nil {
+		return ""
+	}
+	var meta struct {
+		SchemaVersion string `yaml:"schema_version"`
+		Finalized     bool   `yaml:"finalized"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil || isFinalized(meta.SchemaVersion, meta.Finalized) {
+		return ""
+	}
+	return fmt.Sprintf("%s never finalized (interrupted run)", kind)
+}
+
+// changedSince reports whether path or anything below it was modified after t. Paths that cannot
+// be read count as changed, so they are kept.
+func changedSince(path string, t time.Time) bool {
+	changed := false
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(t) {
+			changed = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return changed || err != nil
+}
+
+// tempFiles returns the names of the temporary files writeMetaFile leaves in dir when interrupted
+func tempFiles(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if f.Type().IsRegular() && strings.HasPrefix(f.Name(), ".") && strings.HasSuffix(f.Name(), ".tmp") {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
+
+// AddReferences adds the paths of the snapshots that the entries in historyDir reference to refs.
+// Entries FindGarbage reports do not count; entries this binary cannot load, and unfinished entries
+// still in their grace period, do.
+func (s *ObjectStore) AddReferences(historyDir string, refs map[string]bool) error {
+	dirEntries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, d := range dirEntries {
+		entryDir := GetEntryDirByID(historyDir, d.Name())
+		if !d.IsDir() {
+			continue
+		}
+		if unfinishedReason(filepath.Join(entryDir, metaFile), "entry") != "" && !changedSince(entryDir, time.Now().Add(-gcGracePeriod)) {
+			continue
+		}
+		for name, sum := range readInputObjects(entryDir) {
+			refs[s.Path(sum, name)] = true
+		}
+	}
+	return nil
+}
+
+// FindGarbage returns the snapshots in the store that are not in refs (see AddReferences) and the
+// temporary files of interrupted writes
+func (s *ObjectStore) FindGarbage(refs map[string]bool) ([]Garbage, error) {
+	var garbage []Garbage
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || refs[path] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		reason := "snapshot no entry references"
+		if strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".tmp") {
+			reason = "temporary file of an interrupted write"
+		}
+		garbage = append(garbage, Garbage{Path: path, Reason: reason, Size: info.Size(), root: s.dir})
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return garbage, nil
+}
+
+// RemoveGarbage deletes the garbage. Directories of the object store left empty are removed too.
+func RemoveGarbage(garbage []Garbage) error {
+	for _, g := range garbage {
+		if err := os.RemoveAll(g.Path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", g.Path, err)
+		}
+		if g.entryID != "" {
+			unindexEntry(g.historyDir, g.entryID)
+		}
+		if g.root == "" {
+			continue
+		}
+		for dir := filepath.Dir(g.Path); dir != g.root && strings.HasPrefix(dir, g.root); dir = filepath.Dir(dir) {
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -1,9 +1,16 @@
 package history
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/google/uuid"
 )
 
 func TestNewEntry(t *testing.T) {
@@ -41,6 +48,9 @@ func TestNewEntryFromSource(t *testing.T) {
 	if len(entry.Generation.InputImages) != len(source.Generation.InputImages) {
 		t.Errorf("InputImages length = %d, want %d", len(entry.Generation.InputImages), len(source.Generation.InputImages))
 	}
+	if entry.Lineage == nil || entry.Lineage.RegeneratedFrom != source.ID {
+		t.Errorf("Lineage.RegeneratedFrom = %v, want %q", entry.Lineage, source.ID)
+	}
 
 	// Verify InputImages is a copy, not the same slice
 	source.Generation.InputImages[0] = "modified.png"
@@ -142,6 +152,135 @@ func TestEntry_SaveInputImages(t *testing.T) {
 	}
 }
 
+func TestEntry_SaveInputImages_ObjectStore(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectRoot, "banago.yaml"), []byte("version: \"2\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project config: %v", err)
+	}
+	historyDir := filepath.Join(projectRoot, "subprojects", "fox", "history")
+	srcPath := filepath.Join(t.TempDir(), "ref.png")
+	if err := os.WriteFile(srcPath, []byte("reference"), 0o644); err != nil {
+		t.Fatalf("failed to write source file: %v", err)
+	}
+
+	// Two entries with the same input share one snapshot
+	var entries []*Entry
+	for range 2 {
+		entry := NewEntry()
+		entry.Generation.InputImages = []string{"ref.png"}
+		if err := entry.SaveInputImages(historyDir, []string{srcPath}); err != nil {
+			t.Fatalf("SaveInputImages() error = %v", err)
+		}
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	sum := entries[0].Generation.InputObjects["ref.png"]
+	if sum == "" || entries[1].Generation.InputObjects["ref.png"] != sum {
+		t.Fatalf("InputObjects = %v, %v, want the same sum", entries[0].Generation.InputObjects, entries[1].Generation.InputObjects)
+	}
+	blob := NewObjectStore(filepath.Join(projectRoot, ObjectsDir)).Path(sum, "ref.png")
+	for _, entry := range entries {
+		entryDir := entry.GetEntryDir(historyDir)
+		if _, err := os.Stat(filepath.Join(entryDir, "ref.png")); !os.IsNotExist(err) {
+			t.Errorf("input copied into entry directory: %v", err)
+		}
+		paths := GetInputImagePaths(entryDir, entry.Generation.InputImages)
+		if len(paths) != 1 || paths[0] != blob {
+			t.Errorf("GetInputImagePaths() = %v, want [%s]", paths, blob)
+		}
+	}
+
+	// Copies of older entries move into the store
+	legacy := NewEntry()
+	legacy.Generation.InputImages = []string{"ref.png"}
+	if err := legacy.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	legacyCopy := filepath.Join(legacy.GetEntryDir(historyDir), "ref.png")
+	if err := os.WriteFile(legacyCopy, []byte("reference"), 0o644); err != nil {
+		t.Fatalf("failed to write input copy: %v", err)
+	}
+	n, err := legacy.StoreInputImages(historyDir)
+	if err != nil || n != 1 {
+		t.Fatalf("StoreInputImages() = %d, %v, want 1", n, err)
+	}
+	if _, err := os.Stat(legacyCopy); !os.IsNotExist(err) {
+		t.Errorf("input copy not removed: %v", err)
+	}
+	if got := ResolveEntryFile(legacy.GetEntryDir(historyDir), "ref.png"); got != blob {
+		t.Errorf("ResolveEntryFile() = %s, want %s", got, blob)
+	}
+	if n, err := legacy.StoreInputImages(historyDir); err != nil || n != 0 {
+		t.Errorf("second StoreInputImages() = %d, %v, want 0", n, err)
+	}
+}
+
+func TestEntry_NestedLayout(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+
+	entry := NewEntry()
+	entry.SetLayout(LayoutNested)
+	entry.Result.OutputImages = []string{"output_1.png"}
+	entryDir := entry.GetEntryDir(historyDir)
+	if err := os.MkdirAll(entry.OutputDir(historyDir), 0o755); err != nil {
+		t.Fatalf("failed to create output directory: %v", err)
+	}
+	if err := os.WriteFile(entry.OutputPath(historyDir, "output_1.png"), []byte("output"), 0o644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if want := filepath.Join(entryDir, "outputs", "output_1.png"); entry.OutputPath(historyDir, "output_1.png") != want {
+		t.Errorf("OutputPath() = %s, want %s", entry.OutputPath(historyDir, "output_1.png"), want)
+	}
+	if want := filepath.Join(entryDir, "inputs", "ref.png"); entry.InputPath(historyDir, "ref.png") != want {
+		t.Errorf("InputPath() = %s, want %s", entry.InputPath(historyDir, "ref.png"), want)
+	}
+
+	loaded, err := GetEntryByID(historyDir, entry.ID)
+	if err != nil {
+		t.Fatalf("GetEntryByID() error = %v", err)
+	}
+	if loaded.SchemaVersion != NestedSchemaVersion || loaded.Layout() != LayoutNested {
+		t.Errorf("loaded entry = schema %s, layout %s, want %s, %s", loaded.SchemaVersion, loaded.Layout(), NestedSchemaVersion, LayoutNested)
+	}
+	if got := ResolveEntryFile(entryDir, "output_1.png"); got != entry.OutputPath(historyDir, "output_1.png") {
+		t.Errorf("ResolveEntryFile() = %s, want the nested output", got)
+	}
+	names, err := ListEntryFiles(entryDir)
+	if err != nil || !slices.Equal(names, []string{"meta.yaml", "output_1.png"}) {
+		t.Errorf("ListEntryFiles() = %v, %v, want [meta.yaml output_1.png]", names, err)
+	}
+
+	// Converting to flat and back moves the output and records the layout
+	for _, layout := range []string{LayoutFlat, LayoutNested} {
+		changed, err := loaded.ConvertLayout(historyDir, layout)
+		if err != nil || !changed {
+			t.Fatalf("ConvertLayout(%s) = %v, %v, want true", layout, changed, err)
+		}
+		if _, err := os.Stat(loaded.OutputPath(historyDir, "output_1.png")); err != nil {
+			t.Errorf("output not moved to %s layout: %v", layout, err)
+		}
+		reloaded, err := GetEntryByID(historyDir, entry.ID)
+		if err != nil || reloaded.Layout() != layout {
+			t.Errorf("layout after ConvertLayout(%s) = %v, %v", layout, reloaded, err)
+		}
+		if changed, err := loaded.ConvertLayout(historyDir, layout); err != nil || changed {
+			t.Errorf("second ConvertLayout(%s) = %v, %v, want false", layout, changed, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(entryDir, "output_1.png")); !os.IsNotExist(err) {
+		t.Errorf("flat output left behind: %v", err)
+	}
+}
+
 func TestGetInputImagePaths(t *testing.T) {
 	t.Parallel()
 
@@ -211,6 +350,105 @@ func TestEntry_Cleanup(t *testing.T) {
 	}
 }
 
+func TestEntry_RemoveOutputs(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (string, *Entry) {
+		t.Helper()
+		historyDir := t.TempDir()
+		entry := NewEntry()
+		entry.Result.Success = true
+		entry.Result.OutputImages = []string{"output-a-1.png", "output-a-2.png", "output-a-3.png"}
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		for _, img := range entry.Result.OutputImages {
+			if err := os.WriteFile(filepath.Join(entry.GetEntryDir(historyDir), img), []byte("data"), 0o644); err != nil {
+				t.Fatalf("failed to write output: %v", err)
+			}
+		}
+		return historyDir, entry
+	}
+
+	t.Run("removes matching outputs and updates meta", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entry := setup(t)
+
+		removed, err := entry.RemoveOutputs(historyDir, []string{"output-*-3.png", "output-*-1.png"})
+		if err != nil {
+			t.Fatalf("RemoveOutputs() error = %v", err)
+		}
+		if len(removed) != 2 {
+			t.Fatalf("RemoveOutputs() removed %d outputs, want 2", len(removed))
+		}
+
+		entryDir := entry.GetEntryDir(historyDir)
+		if _, err := os.Stat(filepath.Join(entryDir, "output-a-3.png")); !os.IsNotExist(err) {
+			t.Error("output-a-3.png should be removed")
+		}
+		if _, err := os.Stat(filepath.Join(entryDir, "output-a-2.png")); err != nil {
+			t.Error("output-a-2.png should be kept")
+		}
+
+		loaded, err := GetEntryByID(historyDir, entry.ID)
+		if err != nil {
+			t.Fatalf("GetEntryByID() error = %v", err)
+		}
+		if len(loaded.Result.OutputImages) != 1 || loaded.Result.OutputImages[0] != "output-a-2.png" {
+			t.Errorf("OutputImages = %v, want [output-a-2.png]", loaded.Result.OutputImages)
+		}
+	})
+
+	t.Run("no match is a no-op", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entry := setup(t)
+
+		removed, err := entry.RemoveOutputs(historyDir, []string{"nothing-*.png"})
+		if err != nil {
+			t.Fatalf("RemoveOutputs() error = %v", err)
+		}
+		if len(removed) != 0 {
+			t.Errorf("RemoveOutputs() removed %d outputs, want 0", len(removed))
+		}
+	})
+
+	t.Run("refuses to remove all outputs", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entry := setup(t)
+
+		if _, err := entry.RemoveOutputs(historyDir, []string{"output-*"}); err == nil {
+			t.Error("RemoveOutputs() expected error when all outputs match")
+		}
+		if _, err := os.Stat(filepath.Join(entry.GetEntryDir(historyDir), "output-a-1.png")); err != nil {
+			t.Error("outputs should be kept on error")
+		}
+	})
+
+	t.Run("keeps outputs used as edit source", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entry := setup(t)
+
+		edit := NewEditEntry()
+		edit.Source = EditSource{Type: "generate", Output: "output-a-1.png"}
+		if err := edit.Save(entry.GetEntryDir(historyDir)); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		if _, err := entry.RemoveOutputs(historyDir, []string{"output-a-1.png"}); err == nil {
+			t.Error("RemoveOutputs() expected error for edit source output")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entry := setup(t)
+
+		if _, err := entry.RemoveOutputs(historyDir, []string{"["}); err == nil {
+			t.Error("RemoveOutputs() expected error for invalid pattern")
+		}
+	})
+}
+
 func TestListEntries(t *testing.T) {
 	t.Parallel()
 
@@ -284,6 +522,153 @@ func TestListEntries(t *testing.T) {
 	})
 }
 
+func TestListEntries_Index(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unchanged entries come from the index", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		entry.Generation.Model = "model-a"
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		idx := readIndex(historyDir)
+		rec, ok := idx.Entries[entry.ID]
+		if !ok {
+			t.Fatal("Save() did not add the entry to the index")
+		}
+
+		// A record still matching meta.yaml is trusted without reading the file
+		rec.Meta = strings.Replace(rec.Meta, "model-a", "model-b", 1)
+		idx.Entries[entry.ID] = rec
+		writeIndex(historyDir, idx)
+		entries, err := ListEntries(historyDir)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ListEntries() = %d entries, %v, want 1", len(entries), err)
+		}
+		if entries[0].Generation.Model != "model-b" {
+			t.Errorf("Model = %q, want the cached %q", entries[0].Generation.Model, "model-b")
+		}
+	})
+
+	t.Run("rewritten meta.yaml is read again", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if _, err := ListEntries(historyDir); err != nil {
+			t.Fatalf("ListEntries() error = %v", err)
+		}
+
+		metaPath := filepath.Join(entry.GetEntryDir(historyDir), "meta.yaml")
+		data, err := os.ReadFile(metaPath)
+		if err != nil {
+			t.Fatalf("failed to read meta.yaml: %v", err)
+		}
+		data = append(data, []byte("note: edited by hand\n")...)
+		if err := os.WriteFile(metaPath, data, 0o644); err != nil {
+			t.Fatalf("failed to write meta.yaml: %v", err)
+		}
+
+		entries, err := ListEntries(historyDir)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ListEntries() = %d entries, %v, want 1", len(entries), err)
+		}
+		if entries[0].Extra["note"] != "edited by hand" {
+			t.Errorf("Extra = %v, want the hand edit", entries[0].Extra)
+		}
+		if rec := readIndex(historyDir).Entries[entry.ID]; strings.Contains(rec.Meta, "edited by hand") {
+			t.Error("ListEntries() wrote the index")
+		}
+		RefreshIndex(historyDir)
+		if rec := readIndex(historyDir).Entries[entry.ID]; !strings.Contains(rec.Meta, "edited by hand") {
+			t.Error("RefreshIndex() did not refresh the index")
+		}
+	})
+
+	t.Run("removed entries leave the index", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		cleaned := NewEntry()
+		deleted := NewEntry()
+		kept := NewEntry()
+		for _, e := range []*Entry{cleaned, deleted, kept} {
+			if err := e.Save(historyDir); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+		}
+		if err := cleaned.Cleanup(historyDir); err != nil {
+			t.Fatalf("Cleanup() error = %v", err)
+		}
+		if _, ok := readIndex(historyDir).Entries[cleaned.ID]; ok {
+			t.Error("Cleanup() kept the entry in the index")
+		}
+
+		if err := os.RemoveAll(deleted.GetEntryDir(historyDir)); err != nil {
+			t.Fatalf("failed to remove entry: %v", err)
+		}
+		entries, err := ListEntries(historyDir)
+		if err != nil || len(entries) != 1 || entries[0].ID != kept.ID {
+			t.Fatalf("ListEntries() = %v, %v, want only %s", entries, err, kept.ID)
+		}
+		RefreshIndex(historyDir)
+		if idx := readIndex(historyDir); len(idx.Entries) != 1 {
+			t.Errorf("index has %d entries, want 1", len(idx.Entries))
+		}
+	})
+
+	t.Run("corrupt index is rebuilt", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(historyDir, IndexFile), []byte("{not json"), 0o644); err != nil {
+			t.Fatalf("failed to write index: %v", err)
+		}
+
+		entries, err := ListEntries(historyDir)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ListEntries() = %d entries, %v, want 1", len(entries), err)
+		}
+		RefreshIndex(historyDir)
+		if _, ok := readIndex(historyDir).Entries[entry.ID]; !ok {
+			t.Error("RefreshIndex() did not rebuild the index")
+		}
+	})
+
+	t.Run("listing never writes the index", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		indexPath := filepath.Join(historyDir, IndexFile)
+		if err := os.Remove(indexPath); err != nil {
+			t.Fatalf("failed to remove index: %v", err)
+		}
+
+		// Read-only commands list history too, so a stale index is only rebuilt in memory
+		entries, err := ListEntries(historyDir)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ListEntries() = %d entries, %v, want 1", len(entries), err)
+		}
+		if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+			t.Errorf("ListEntries() wrote %s (stat error = %v)", IndexFile, err)
+		}
+	})
+}
+
 func TestGetLatestEntry(t *testing.T) {
 	t.Parallel()
 
@@ -348,8 +733,8 @@ func TestGetEntryByID(t *testing.T) {
 		historyDir := t.TempDir()
 
 		_, err := GetEntryByID(historyDir, "nonexistent-id")
-		if err == nil {
-			t.Error("GetEntryByID() expected error for nonexistent ID")
+		if !errors.Is(err, ErrEntryNotFound) {
+			t.Errorf("GetEntryByID() error = %v, want ErrEntryNotFound", err)
 		}
 	})
 }
@@ -716,6 +1101,30 @@ func TestGetEditsDir(t *testing.T) {
 	}
 }
 
+func TestGetEntryDirByID(t *testing.T) {
+	t.Parallel()
+
+	historyDir := "/path/to/history"
+	expected := "/path/to/history/entry-id"
+	got := GetEntryDirByID(historyDir, "entry-id")
+
+	if got != expected {
+		t.Errorf("GetEntryDirByID() = %q, want %q", got, expected)
+	}
+}
+
+func TestGetEditDirByID(t *testing.T) {
+	t.Parallel()
+
+	entryDir := "/path/to/history/entry-id"
+	expected := "/path/to/history/entry-id/edits/edit-uuid"
+	got := GetEditDirByID(entryDir, "edit-uuid")
+
+	if got != expected {
+		t.Errorf("GetEditDirByID() = %q, want %q", got, expected)
+	}
+}
+
 func TestGetHistoryDir(t *testing.T) {
 	t.Parallel()
 
@@ -737,3 +1146,1115 @@ func TestLoadEditPrompt_NotFound(t *testing.T) {
 		t.Error("LoadEditPrompt() expected error for missing file")
 	}
 }
+
+func TestSplitEntry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("creates one entry per output", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		source := NewEntry()
+		source.Generation.PromptFile = PromptFile
+		source.Generation.InputImages = []string{"input.png"}
+		source.Result.Success = true
+		source.Result.OutputImages = []string{"output-a-1.png", "output-a-2.png"}
+		source.Result.TokenUsage.Total = 100
+		if err := source.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := source.SavePrompt(historyDir, "test prompt"); err != nil {
+			t.Fatalf("SavePrompt() error = %v", err)
+		}
+		sourceDir := source.GetEntryDir(historyDir)
+		for _, name := range []string{"input.png", "output-a-1.png", "output-a-2.png"} {
+			if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		created, err := SplitEntry(historyDir, source.ID, LayoutFlat)
+		if err != nil {
+			t.Fatalf("SplitEntry() error = %v", err)
+		}
+		if len(created) != 2 {
+			t.Fatalf("SplitEntry() created %d entries, want 2", len(created))
+		}
+
+		if _, err := os.Stat(sourceDir); !os.IsNotExist(err) {
+			t.Error("source entry should be removed after split")
+		}
+
+		for i, entry := range created {
+			entryDir := entry.GetEntryDir(historyDir)
+			loaded, err := GetEntryByID(historyDir, entry.ID)
+			if err != nil {
+				t.Fatalf("GetEntryByID() error = %v", err)
+			}
+			if loaded.Lineage == nil || loaded.Lineage.SplitFrom != source.ID {
+				t.Errorf("Lineage.SplitFrom = %v, want %q", loaded.Lineage, source.ID)
+			}
+			if len(loaded.Result.OutputImages) != 1 || loaded.Result.OutputImages[0] != source.Result.OutputImages[i] {
+				t.Errorf("OutputImages = %v, want [%s]", loaded.Result.OutputImages, source.Result.OutputImages[i])
+			}
+			if prompt, err := LoadPrompt(entryDir); err != nil || prompt != "test prompt" {
+				t.Errorf("LoadPrompt() = %q, %v", prompt, err)
+			}
+			if _, err := os.Stat(filepath.Join(entryDir, "input.png")); err != nil {
+				t.Error("input image should be copied")
+			}
+			other := source.Result.OutputImages[1-i]
+			if _, err := os.Stat(filepath.Join(entryDir, other)); !os.IsNotExist(err) {
+				t.Errorf("%s should not be copied", other)
+			}
+		}
+
+		if created[0].Result.TokenUsage.Total != 100 || created[1].Result.TokenUsage.Total != 0 {
+			t.Error("token usage should be kept on the first entry only")
+		}
+	})
+
+	t.Run("keeps cost, curation and the animation", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		source := NewEntry()
+		source.Generation.PromptFile = PromptFile
+		source.Result.Success = true
+		source.Result.OutputImages = []string{"output-a-1.png", "output-a-2.png"}
+		source.Result.Animation = "animation.gif"
+		source.Result.CostUSD = 0.5
+		source.Result.Evaluations = []Evaluation{{Evaluator: "check", Passed: true}}
+		source.Tags = []string{"keeper"}
+		source.Rating = 4
+		if err := source.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		sourceDir := source.GetEntryDir(historyDir)
+		for _, name := range []string{"output-a-1.png", "output-a-2.png", "animation.gif"} {
+			if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		created, err := SplitEntry(historyDir, source.ID, LayoutFlat)
+		if err != nil {
+			t.Fatalf("SplitEntry() error = %v", err)
+		}
+		for i, entry := range created {
+			loaded, err := GetEntryByID(historyDir, entry.ID)
+			if err != nil {
+				t.Fatalf("GetEntryByID() error = %v", err)
+			}
+			if !slices.Equal(loaded.Tags, source.Tags) || loaded.Rating != source.Rating {
+				t.Errorf("entry %d: Tags = %v, Rating = %d, want %v, %d", i, loaded.Tags, loaded.Rating, source.Tags, source.Rating)
+			}
+			if len(loaded.Result.Evaluations) != 1 {
+				t.Errorf("entry %d: Evaluations = %v, want the source's", i, loaded.Result.Evaluations)
+			}
+		}
+
+		if created[0].Result.CostUSD != 0.5 || created[1].Result.CostUSD != 0 {
+			t.Error("cost should be kept on the first entry only")
+		}
+		if created[0].Result.Animation != "animation.gif" || created[1].Result.Animation != "" {
+			t.Error("animation should be kept on the first entry only")
+		}
+		if _, err := os.Stat(filepath.Join(created[0].GetEntryDir(historyDir), "animation.gif")); err != nil {
+			t.Error("animation should be copied into the first entry")
+		}
+		if _, err := os.Stat(filepath.Join(created[1].GetEntryDir(historyDir), "animation.gif")); !os.IsNotExist(err) {
+			t.Error("animation should not be copied into the other entries")
+		}
+	})
+
+	t.Run("fails for single output", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		entry.Result.Success = true
+		entry.Result.OutputImages = []string{"output.png"}
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		if _, err := SplitEntry(historyDir, entry.ID, LayoutFlat); err == nil {
+			t.Error("SplitEntry() expected error for single output")
+		}
+	})
+}
+
+func TestMergeEntries(t *testing.T) {
+	t.Parallel()
+
+	createEntry := func(t *testing.T, historyDir, prompt, output string) *Entry {
+		t.Helper()
+		entry := NewEntry()
+		entry.Generation.PromptFile = PromptFile
+		entry.Result.Success = true
+		entry.Result.OutputImages = []string{output}
+		entry.Result.TokenUsage.Total = 10
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := entry.SavePrompt(historyDir, prompt); err != nil {
+			t.Fatalf("SavePrompt() error = %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(entry.GetEntryDir(historyDir), output), []byte(output), 0o644); err != nil {
+			t.Fatalf("failed to write output: %v", err)
+		}
+		return entry
+	}
+
+	t.Run("merges outputs into one entry", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		entry1 := createEntry(t, historyDir, "same prompt", "output-a-1.png")
+		entry2 := createEntry(t, historyDir, "same prompt", "output-b-1.png")
+
+		merged, err := MergeEntries(historyDir, []string{entry1.ID, entry2.ID}, LayoutFlat)
+		if err != nil {
+			t.Fatalf("MergeEntries() error = %v", err)
+		}
+
+		entries, err := ListEntries(historyDir)
+		if err != nil {
+			t.Fatalf("ListEntries() error = %v", err)
+		}
+		if len(entries) != 1 || entries[0].ID != merged.ID {
+			t.Fatalf("ListEntries() = %d entries, want only the merged entry", len(entries))
+		}
+
+		mergedDir := merged.GetEntryDir(historyDir)
+		for _, output := range []string{"output-a-1.png", "output-b-1.png"} {
+			if _, err := os.Stat(filepath.Join(mergedDir, output)); err != nil {
+				t.Errorf("%s should exist in merged entry", output)
+			}
+		}
+		if len(entries[0].Result.OutputImages) != 2 {
+			t.Errorf("OutputImages length = %d, want 2", len(entries[0].Result.OutputImages))
+		}
+		if entries[0].Result.TokenUsage.Total != 20 {
+			t.Errorf("TokenUsage.Total = %d, want 20", entries[0].Result.TokenUsage.Total)
+		}
+		if entries[0].Lineage == nil || len(entries[0].Lineage.MergedFrom) != 2 {
+			t.Errorf("Lineage.MergedFrom = %v, want 2 IDs", entries[0].Lineage)
+		}
+	})
+
+	t.Run("combines cost and curation", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		entry1 := createEntry(t, historyDir, "same prompt", "output-a-1.png")
+		entry1.Result.CostUSD = 0.25
+		entry1.Result.Animation = "animation.gif"
+		entry1.Result.Evaluations = []Evaluation{{Evaluator: "check", Passed: true}}
+		entry1.Tags = []string{"keeper"}
+		entry1.Rating = 2
+		entry2 := createEntry(t, historyDir, "same prompt", "output-b-1.png")
+		entry2.Result.CostUSD = 0.5
+		entry2.Result.Animation = "animation.gif"
+		entry2.Result.Evaluations = []Evaluation{{Evaluator: "check", Passed: false}}
+		entry2.Tags = []string{"blue", "keeper"}
+		entry2.Rating = 5
+		for _, entry := range []*Entry{entry1, entry2} {
+			if err := entry.Save(historyDir); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(entry.GetEntryDir(historyDir), "animation.gif"), []byte(entry.ID), 0o644); err != nil {
+				t.Fatalf("failed to write animation: %v", err)
+			}
+		}
+
+		merged, err := MergeEntries(historyDir, []string{entry1.ID, entry2.ID}, LayoutFlat)
+		if err != nil {
+			t.Fatalf("MergeEntries() error = %v", err)
+		}
+		loaded, err := GetEntryByID(historyDir, merged.ID)
+		if err != nil {
+			t.Fatalf("GetEntryByID() error = %v", err)
+		}
+		if loaded.Result.CostUSD != 0.75 {
+			t.Errorf("CostUSD = %v, want 0.75", loaded.Result.CostUSD)
+		}
+		if len(loaded.Result.Evaluations) != 2 {
+			t.Errorf("Evaluations = %v, want both", loaded.Result.Evaluations)
+		}
+		if want := []string{"blue", "keeper"}; !slices.Equal(loaded.Tags, want) {
+			t.Errorf("Tags = %v, want %v", loaded.Tags, want)
+		}
+		if loaded.Rating != 5 {
+			t.Errorf("Rating = %d, want 5", loaded.Rating)
+		}
+		if loaded.Result.Animation != "animation.gif" {
+			t.Errorf("Animation = %q, want animation.gif", loaded.Result.Animation)
+		}
+		data, err := os.ReadFile(filepath.Join(merged.GetEntryDir(historyDir), "animation.gif"))
+		if err != nil || string(data) != entry1.ID {
+			t.Errorf("animation = %q, %v, want the first entry's", data, err)
+		}
+	})
+
+	t.Run("fails for different prompts", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		entry1 := createEntry(t, historyDir, "prompt one", "output-a-1.png")
+		entry2 := createEntry(t, historyDir, "prompt two", "output-b-1.png")
+
+		if _, err := MergeEntries(historyDir, []string{entry1.ID, entry2.ID}, LayoutFlat); err == nil {
+			t.Error("MergeEntries() expected error for different prompts")
+		}
+
+		entries, err := ListEntries(historyDir)
+		if err != nil {
+			t.Fatalf("ListEntries() error = %v", err)
+		}
+		if len(entries) != 2 {
+			t.Errorf("ListEntries() returned %d entries, want 2 (originals kept)", len(entries))
+		}
+	})
+
+	t.Run("fails for a single entry", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		entry := createEntry(t, historyDir, "prompt", "output-a-1.png")
+
+		if _, err := MergeEntries(historyDir, []string{entry.ID}, LayoutFlat); err == nil {
+			t.Error("MergeEntries() expected error for a single entry")
+		}
+	})
+}
+
+func TestImportEntry(t *testing.T) {
+	t.Parallel()
+
+	srcHistoryDir := t.TempDir()
+	historyDir := t.TempDir()
+
+	source := NewEntry()
+	source.Generation.PromptFile = PromptFile
+	source.Generation.InputImages = []string{"input.png"}
+	source.Result.Success = true
+	source.Result.OutputImages = []string{"output-a-1.png"}
+	if err := source.Save(srcHistoryDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := source.SavePrompt(srcHistoryDir, "test prompt"); err != nil {
+		t.Fatalf("SavePrompt() error = %v", err)
+	}
+	sourceDir := source.GetEntryDir(srcHistoryDir)
+	for _, name := range []string{"input.png", "output-a-1.png"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	from := ImportSource{Project: "shared", Subproject: "forest", EntryID: source.ID}
+	entry, err := ImportEntry(srcHistoryDir, source.ID, historyDir, LayoutFlat, from)
+	if err != nil {
+		t.Fatalf("ImportEntry() error = %v", err)
+	}
+
+	if entry.ID == source.ID {
+		t.Error("imported entry should get a new ID")
+	}
+	loaded, err := GetEntryByID(historyDir, entry.ID)
+	if err != nil {
+		t.Fatalf("GetEntryByID() error = %v", err)
+	}
+	if loaded.Lineage == nil || loaded.Lineage.ImportedFrom == nil || *loaded.Lineage.ImportedFrom != from {
+		t.Errorf("Lineage.ImportedFrom = %v, want %v", loaded.Lineage, from)
+	}
+	entryDir := loaded.GetEntryDir(historyDir)
+	if prompt, err := LoadPrompt(entryDir); err != nil || prompt != "test prompt" {
+		t.Errorf("LoadPrompt() = %q, %v", prompt, err)
+	}
+	for _, name := range []string{"input.png", "output-a-1.png"} {
+		if _, err := os.Stat(filepath.Join(entryDir, name)); err != nil {
+			t.Errorf("%s should be copied", name)
+		}
+	}
+
+	if _, err := os.Stat(sourceDir); err != nil {
+		t.Error("source entry should be kept")
+	}
+}
+
+func TestComputeEditStats(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	genEntry := NewEntry()
+	if err := genEntry.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	entryDir := genEntry.GetEntryDir(historyDir)
+
+	if stats := ComputeEditStats(entryDir); stats.Count != 0 || stats.LastEditAt != "" {
+		t.Errorf("ComputeEditStats() without edits = %+v, want zero", stats)
+	}
+
+	for i, createdAt := range []string{"2026-01-02T00:00:00Z", "2026-01-03T00:00:00Z"} {
+		edit := NewEditEntry()
+		edit.CreatedAt = createdAt
+		edit.Result.TokenUsage.Prompt = 100 * (i + 1)
+		edit.Result.TokenUsage.Total = 1000 * (i + 1)
+		if err := edit.Save(entryDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	stats := ComputeEditStats(entryDir)
+	if stats.Count != 2 {
+		t.Errorf("Count = %d, want 2", stats.Count)
+	}
+	if stats.TokenUsage.Total != 3000 || stats.TokenUsage.Prompt != 300 {
+		t.Errorf("TokenUsage = %+v, want prompt 300 total 3000", stats.TokenUsage)
+	}
+	if stats.LastEditAt != "2026-01-03T00:00:00Z" {
+		t.Errorf("LastEditAt = %q, want 2026-01-03T00:00:00Z", stats.LastEditAt)
+	}
+}
+
+func TestSpendByMonth(t *testing.T) {
+	t.Parallel()
+
+	const model = "gemini-3-pro-image-preview"
+	historyDir := t.TempDir()
+
+	// Recorded cost is used as is
+	recorded := NewEntry()
+	recorded.CreatedAt = "2026-01-31T23:00:00Z"
+	recorded.Result.TokenUsage = gemini.TokenUsage{Prompt: 1000, Candidates: 1000, Total: 2000}
+	recorded.Result.CostUSD = 0.5
+	if err := recorded.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// Older entry without a cost is estimated with the default model
+	older := NewEntry()
+	older.CreatedAt = "2026-02-01T00:00:00Z"
+	older.Result.TokenUsage = gemini.TokenUsage{Prompt: 1000, Candidates: 1000, Total: 2000}
+	if err := older.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// Edits count in the month they were made, and unknown models are not priced
+	edit := NewEditEntry()
+	edit.CreatedAt = "2026-02-03T00:00:00Z"
+	edit.Generation.Model = "unknown-model"
+	edit.Result.TokenUsage = gemini.TokenUsage{Prompt: 10, Candidates: 10, Total: 20}
+	if err := edit.Save(recorded.GetEntryDir(historyDir)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	months, err := SpendByMonth(historyDir, model)
+	if err != nil {
+		t.Fatalf("SpendByMonth() error = %v", err)
+	}
+	if len(months) != 2 {
+		t.Fatalf("SpendByMonth() months = %v, want 2026-01 and 2026-02", months)
+	}
+
+	jan := months["2026-01"]
+	if jan.Generations != 1 || jan.Edits != 0 || jan.USD != 0.5 {
+		t.Errorf("2026-01 = %+v, want 1 generation costing 0.5", *jan)
+	}
+	feb := months["2026-02"]
+	wantFeb, _ := gemini.EstimateCost(model, older.Result.TokenUsage)
+	if feb.Generations != 1 || feb.Edits != 1 || feb.USD != wantFeb || feb.Unpriced != 1 {
+		t.Errorf("2026-02 = %+v, want 1 generation costing %v and 1 unpriced edit", *feb, wantFeb)
+	}
+	if feb.TokenUsage.Total != 2020 {
+		t.Errorf("2026-02 tokens = %d, want 2020", feb.TokenUsage.Total)
+	}
+}
+
+func TestActivityBetween(t *testing.T) {
+	t.Parallel()
+
+	const model = "gemini-3-pro-image-preview"
+	historyDir := t.TempDir()
+	since := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Only the edit of an older entry falls in the period
+	older := NewEntry()
+	older.CreatedAt = "2026-01-31T23:59:59Z"
+	older.Result.Success = true
+	if err := older.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	edit := NewEditEntry()
+	edit.CreatedAt = "2026-02-02T00:00:00Z"
+	edit.Result.CostUSD = 0.25
+	edit.Result.TokenUsage = gemini.TokenUsage{Total: 100}
+	if err := edit.Save(older.GetEntryDir(historyDir)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	failed := NewEntry()
+	failed.CreatedAt = "2026-02-01T00:00:00Z"
+	if err := failed.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	retried := NewEntryFromSource(failed)
+	retried.CreatedAt = "2026-02-10T00:00:00Z"
+	retried.Result.Success = true
+	retried.Result.CostUSD = 0.5
+	if err := retried.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	later := NewEntry()
+	later.CreatedAt = "2026-03-01T00:00:00Z"
+	if err := later.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	activity, err := ActivityBetween(historyDir, since, until, model)
+	if err != nil {
+		t.Fatalf("ActivityBetween() error = %v", err)
+	}
+	if activity.Generations != 2 || activity.Failed != 1 || activity.Regenerated != 1 || activity.Edits != 1 {
+		t.Errorf("ActivityBetween() = %d generations (%d failed, %d regenerated), %d edits, want 2 (1, 1), 1",
+			activity.Generations, activity.Failed, activity.Regenerated, activity.Edits)
+	}
+	if activity.USD != 0.75 || activity.TokenUsage.Total != 100 {
+		t.Errorf("ActivityBetween() spend = %v USD, %d tokens, want 0.75 USD, 100 tokens", activity.USD, activity.TokenUsage.Total)
+	}
+	if len(activity.Entries) != 2 || activity.Entries[0].ID != failed.ID || activity.Entries[1].ID != retried.ID {
+		t.Errorf("ActivityBetween() entries = %v, want the failed and retried entries", activity.Entries)
+	}
+
+	// The zero until has no upper bound
+	activity, err = ActivityBetween(historyDir, since, time.Time{}, model)
+	if err != nil || activity.Generations != 3 {
+		t.Errorf("ActivityBetween() without until = %v, %v, want 3 generations", activity, err)
+	}
+}
+
+func TestSearchEntries(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	fixtures := []struct {
+		prompt    string
+		createdAt string
+		success   bool
+		tags      []string
+		rating    int
+	}{
+		{"A knight in the rain", "2026-01-01T10:00:00Z", true, []string{"hero"}, 4},
+		{"a dragon over the castle", "2026-01-05T10:00:00Z", false, nil, 0},
+		{"The knight and the dragon", "2026-01-10T10:00:00Z", true, []string{"draft", "hero"}, 2},
+	}
+	var ids []string
+	for _, f := range fixtures {
+		entry := NewEntry()
+		entry.CreatedAt = f.createdAt
+		entry.Result.Success = f.success
+		entry.Tags = f.tags
+		entry.Rating = f.rating
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := entry.SavePrompt(historyDir, f.prompt); err != nil {
+			t.Fatalf("SavePrompt() error = %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	day := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatalf("time.Parse() error = %v", err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name       string
+		predicates []Predicate
+		want       []string
+	}{
+		{"no predicates", nil, ids},
+		{"prompt ignores case", []Predicate{PromptContains("KNIGHT")}, []string{ids[0], ids[2]}},
+		{"since", []Predicate{CreatedSince(day("2026-01-05"))}, []string{ids[1], ids[2]}},
+		{"before", []Predicate{CreatedBefore(day("2026-01-05"))}, []string{ids[0]}},
+		{"failed", []Predicate{Failed()}, []string{ids[1]}},
+		{"combined", []Predicate{PromptContains("dragon"), CreatedSince(day("2026-01-06"))}, []string{ids[2]}},
+		{"tag", []Predicate{HasTag("draft")}, []string{ids[2]}},
+		{"rating", []Predicate{RatedAtLeast(2)}, []string{ids[0], ids[2]}},
+		{"no match", []Predicate{PromptContains("spaceship")}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := SearchEntries(historyDir, tt.predicates...)
+			if err != nil {
+				t.Fatalf("SearchEntries() error = %v", err)
+			}
+			got := []string{}
+			for _, e := range entries {
+				got = append(got, e.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_Curation(t *testing.T) {
+	t.Parallel()
+
+	e := NewEntry()
+	if !e.AddTags("hero", "draft") || e.AddTags("draft") {
+		t.Error("AddTags() should report a change only for new tags")
+	}
+	if !slices.Equal(e.Tags, []string{"draft", "hero"}) {
+		t.Errorf("Tags = %v, want sorted [draft hero]", e.Tags)
+	}
+	if !e.RemoveTags("draft", "missing") || e.RemoveTags("missing") {
+		t.Error("RemoveTags() should report a change only for tags the entry had")
+	}
+	if e.RemoveTags("hero"); e.Tags != nil {
+		t.Errorf("Tags = %v after removing the last tag, want nil", e.Tags)
+	}
+
+	if changed, err := e.SetRating(3); !changed || err != nil {
+		t.Errorf("SetRating(3) = %v, %v, want true, nil", changed, err)
+	}
+	if changed, _ := e.SetRating(3); changed {
+		t.Error("SetRating() with the same rating should report no change")
+	}
+	if _, err := e.SetRating(MaxRating + 1); err == nil {
+		t.Error("SetRating() expected error above MaxRating")
+	}
+
+	for _, tag := range []string{"", "two words", "a,b"} {
+		if err := ValidateTag(tag); err == nil {
+			t.Errorf("ValidateTag(%q) expected error", tag)
+		}
+	}
+}
+
+func TestSelectPrune(t *testing.T) {
+	t.Parallel()
+
+	newHistory := func(t *testing.T) (string, []*Entry) {
+		t.Helper()
+		historyDir := t.TempDir()
+		var entries []*Entry
+		for i, success := range []bool{false, true, false, true} {
+			entry := NewEntry()
+			entry.CreatedAt = time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+			entry.Result.Success = success
+			if err := entry.Save(historyDir); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			entries = append(entries, entry)
+		}
+		return historyDir, entries
+	}
+
+	ids := func(candidates []PruneCandidate) []string {
+		got := []string{}
+		for _, c := range candidates {
+			got = append(got, c.Entry.ID)
+		}
+		return got
+	}
+
+	t.Run("combines criteria", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entries := newHistory(t)
+
+		candidates, err := SelectPrune(historyDir, PruneCriteria{
+			Predicates: []Predicate{Failed()},
+			KeepNewest: 2,
+		})
+		if err != nil {
+			t.Fatalf("SelectPrune() error = %v", err)
+		}
+		if want := []string{entries[0].ID}; !slices.Equal(ids(candidates), want) {
+			t.Errorf("SelectPrune() = %v, want %v", ids(candidates), want)
+		}
+		if candidates[0].Size <= 0 {
+			t.Errorf("Size = %d, want > 0", candidates[0].Size)
+		}
+	})
+
+	t.Run("keep newest alone", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entries := newHistory(t)
+
+		candidates, err := SelectPrune(historyDir, PruneCriteria{KeepNewest: 3})
+		if err != nil {
+			t.Fatalf("SelectPrune() error = %v", err)
+		}
+		if want := []string{entries[0].ID}; !slices.Equal(ids(candidates), want) {
+			t.Errorf("SelectPrune() = %v, want %v", ids(candidates), want)
+		}
+	})
+
+	t.Run("rejects empty criteria", func(t *testing.T) {
+		t.Parallel()
+		historyDir, _ := newHistory(t)
+
+		if _, err := SelectPrune(historyDir, PruneCriteria{}); err == nil {
+			t.Error("SelectPrune() should fail without criteria")
+		}
+	})
+
+	t.Run("prune deletes entries", func(t *testing.T) {
+		t.Parallel()
+		historyDir, entries := newHistory(t)
+
+		candidates, err := SelectPrune(historyDir, PruneCriteria{Predicates: []Predicate{CreatedBefore(time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC))}})
+		if err != nil {
+			t.Fatalf("SelectPrune() error = %v", err)
+		}
+		if err := Prune(historyDir, candidates); err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+
+		remaining, err := ListEntries(historyDir)
+		if err != nil {
+			t.Fatalf("ListEntries() error = %v", err)
+		}
+		got := []string{}
+		for _, e := range remaining {
+			got = append(got, e.ID)
+		}
+		if want := []string{entries[2].ID, entries[3].ID}; !slices.Equal(got, want) {
+			t.Errorf("remaining = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestFindOrphans(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	entry := NewEntry()
+	if err := os.MkdirAll(entry.GetEntryDir(historyDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatal(err)
+	}
+	noMeta := NewEntry()
+	broken := NewEntry()
+	for _, dir := range []string{noMeta.GetEntryDir(historyDir), broken.GetEntryDir(historyDir), filepath.Join(historyDir, "tmp")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(broken.GetEntryDir(historyDir), "meta.yaml"), []byte("id: [unclosed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := FindOrphans(historyDir)
+	if err != nil {
+		t.Fatalf("FindOrphans() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, o := range orphans {
+		got[o.Name] = o.Reason
+	}
+	if len(got) != 3 {
+		t.Fatalf("FindOrphans() = %v, want 3 orphans", orphans)
+	}
+	if _, ok := got[entry.ID]; ok {
+		t.Errorf("valid entry %s reported as orphan", entry.ID)
+	}
+	if got[noMeta.ID] != "meta.yaml is missing" {
+		t.Errorf("reason for %s = %q, want meta.yaml is missing", noMeta.ID, got[noMeta.ID])
+	}
+	if got["tmp"] != "not an entry ID" {
+		t.Errorf("reason for tmp = %q, want not an entry ID", got["tmp"])
+	}
+	if got[broken.ID] == "" {
+		t.Errorf("broken entry %s not reported", broken.ID)
+	}
+
+	missing, err := FindOrphans(filepath.Join(historyDir, "none"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("FindOrphans(missing dir) = %v, %v; want none", missing, err)
+	}
+}
+
+func TestNormalizeOutputNames(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	canonical := gemini.OutputFileName(uuid.Must(uuid.NewV7()), 2, ".png")
+	entry := NewEntry()
+	entry.Result.Success = true
+	entry.Result.OutputImages = []string{"output_1.png", canonical}
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	entryDir := entry.GetEntryDir(historyDir)
+	for _, name := range entry.Result.OutputImages {
+		if err := os.WriteFile(filepath.Join(entryDir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edit := NewEditEntry()
+	edit.Source = EditSource{Type: "generate", Output: "output_1.png"}
+	edit.Result.OutputImages = []string{"edited.jpg"}
+	if err := edit.Save(entryDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(edit.GetEditEntryDir(entryDir), "edited.jpg"), []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chained := NewEditEntry()
+	chained.Source = EditSource{Type: "edit", EditID: edit.ID, Output: "edited.jpg"}
+	chained.Result.OutputImages = []string{gemini.OutputFileName(uuid.Must(uuid.NewV7()), 1, ".png")}
+	if err := chained.Save(entryDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	renames, err := NormalizeOutputNames(historyDir, true)
+	if err != nil {
+		t.Fatalf("NormalizeOutputNames(dry run) error = %v", err)
+	}
+	if len(renames) != 2 {
+		t.Fatalf("NormalizeOutputNames(dry run) = %d renames, want 2", len(renames))
+	}
+	if _, err := os.Stat(filepath.Join(entryDir, "output_1.png")); err != nil {
+		t.Error("dry run should not rename files")
+	}
+
+	renames, err = NormalizeOutputNames(historyDir, false)
+	if err != nil {
+		t.Fatalf("NormalizeOutputNames() error = %v", err)
+	}
+	if len(renames) != 2 || renames[0].From != "output_1.png" || renames[1].EditID != edit.ID {
+		t.Fatalf("NormalizeOutputNames() = %+v", renames)
+	}
+
+	loaded, err := GetEntryByID(historyDir, entry.ID)
+	if err != nil {
+		t.Fatalf("GetEntryByID() error = %v", err)
+	}
+	if loaded.Result.OutputImages[0] != renames[0].To || loaded.Result.OutputImages[1] != canonical {
+		t.Errorf("OutputImages = %v, want [%s %s]", loaded.Result.OutputImages, renames[0].To, canonical)
+	}
+	for _, name := range loaded.Result.OutputImages {
+		if !gemini.IsOutputFileName(name) {
+			t.Errorf("%s does not follow the naming scheme", name)
+		}
+		if _, err := os.Stat(filepath.Join(entryDir, name)); err != nil {
+			t.Errorf("output %s missing: %v", name, err)
+		}
+	}
+
+	loadedEdit, err := GetEditEntryByID(entryDir, edit.ID)
+	if err != nil {
+		t.Fatalf("GetEditEntryByID() error = %v", err)
+	}
+	if loadedEdit.Source.Output != renames[0].To || loadedEdit.Result.OutputImages[0] != renames[1].To {
+		t.Errorf("edit = %+v, want source %s and output %s", loadedEdit, renames[0].To, renames[1].To)
+	}
+	if filepath.Ext(renames[1].To) != ".jpg" {
+		t.Errorf("renamed edit output %s should keep its extension", renames[1].To)
+	}
+	loadedChained, err := GetEditEntryByID(entryDir, chained.ID)
+	if err != nil {
+		t.Fatalf("GetEditEntryByID() error = %v", err)
+	}
+	if loadedChained.Source.Output != renames[1].To {
+		t.Errorf("chained edit source = %s, want %s", loadedChained.Source.Output, renames[1].To)
+	}
+
+	renames, err = NormalizeOutputNames(historyDir, false)
+	if err != nil || len(renames) != 0 {
+		t.Errorf("second NormalizeOutputNames() = %v, %v, want no renames", renames, err)
+	}
+}
+
+func TestEntry_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	writeMeta := func(t *testing.T, historyDir, meta string) string {
+		t.Helper()
+		id := uuid.Must(uuid.NewV7()).String()
+		entryDir := GetEntryDirByID(historyDir, id)
+		if err := os.MkdirAll(entryDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := "id: " + id + "\n" + meta
+		if err := os.WriteFile(filepath.Join(entryDir, "meta.yaml"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	t.Run("save writes the current version", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		loaded, err := GetEntryByID(historyDir, entry.ID)
+		if err != nil {
+			t.Fatalf("GetEntryByID() error = %v", err)
+		}
+		if loaded.SchemaVersion != SchemaVersion {
+			t.Errorf("SchemaVersion = %q, want %q", loaded.SchemaVersion, SchemaVersion)
+		}
+	})
+
+	t.Run("newer minor fields survive a rewrite", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		id := writeMeta(t, historyDir, "schema_version: \"1.99\"\nfinalized: true\nrating: 5\ngeneration:\n  prompt_file: prompt.txt\n  seed: 42\nresult:\n  success: true\n")
+
+		entry, err := GetEntryByID(historyDir, id)
+		if err != nil {
+			t.Fatalf("GetEntryByID() error = %v", err)
+		}
+		entry.Result.OutputImages = []string{"output-a-1.png"}
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(GetEntryDirByID(historyDir, id), "meta.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`schema_version: "1.99"`, "rating: 5", "seed: 42", "output-a-1.png"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("meta.yaml lacks %q:\n%s", want, data)
+			}
+		}
+	})
+
+	t.Run("newer major is rejected", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		id := writeMeta(t, historyDir, "schema_version: \"3.0\"\n")
+
+		_, err := GetEntryByID(historyDir, id)
+		if !errors.Is(err, ErrIncompatibleSchema) || !strings.Contains(err.Error(), "Upgrade banago") {
+			t.Errorf("GetEntryByID() error = %v, want ErrIncompatibleSchema with upgrade hint", err)
+		}
+		orphans, err := FindOrphans(historyDir)
+		if err != nil || len(orphans) != 1 || !strings.Contains(orphans[0].Reason, "schema_version 3.0") {
+			t.Errorf("FindOrphans() = %v, %v, want the entry reported", orphans, err)
+		}
+	})
+
+	t.Run("edits are checked too", func(t *testing.T) {
+		t.Parallel()
+		entryDir := t.TempDir()
+		edit := NewEditEntry()
+		editDir := edit.GetEditEntryDir(entryDir)
+		if err := os.MkdirAll(editDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := "schema_version: \"3.1\"\nid: " + edit.ID + "\n"
+		if err := os.WriteFile(filepath.Join(editDir, "edit-meta.yaml"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := GetEditEntryByID(entryDir, edit.ID); !errors.Is(err, ErrIncompatibleSchema) {
+			t.Errorf("GetEditEntryByID() error = %v, want ErrIncompatibleSchema", err)
+		}
+	})
+}
+
+func TestEntry_Finalized(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pending entries are hidden until saved", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+
+		entry := NewEntry()
+		if err := entry.SavePending(historyDir); err != nil {
+			t.Fatalf("SavePending() error = %v", err)
+		}
+		if _, err := GetEntryByID(historyDir, entry.ID); !errors.Is(err, ErrNotFinalized) {
+			t.Errorf("GetEntryByID() error = %v, want ErrNotFinalized", err)
+		}
+		if entries, err := ListEntries(historyDir); err != nil || len(entries) != 0 {
+			t.Errorf("ListEntries() = %d entries, %v, want none", len(entries), err)
+		}
+		orphans, err := FindOrphans(historyDir)
+		if err != nil || len(orphans) != 1 || !strings.Contains(orphans[0].Reason, "not finalized") {
+			t.Errorf("FindOrphans() = %v, %v, want the pending entry reported", orphans, err)
+		}
+
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		loaded, err := GetEntryByID(historyDir, entry.ID)
+		if err != nil {
+			t.Fatalf("GetEntryByID() error = %v", err)
+		}
+		if !loaded.Finalized {
+			t.Error("Finalized = false after Save")
+		}
+	})
+
+	t.Run("entries from before the marker are complete", func(t *testing.T) {
+		t.Parallel()
+		historyDir := t.TempDir()
+		for _, version := range []string{"", "schema_version: \"1.2\"\n"} {
+			id := uuid.Must(uuid.NewV7()).String()
+			entryDir := GetEntryDirByID(historyDir, id)
+			if err := os.MkdirAll(entryDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(entryDir, "meta.yaml"), []byte(version+"id: "+id+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if entries, err := ListEntries(historyDir); err != nil || len(entries) != 2 {
+			t.Errorf("ListEntries() = %d entries, %v, want 2", len(entries), err)
+		}
+	})
+
+	t.Run("pending edits are hidden", func(t *testing.T) {
+		t.Parallel()
+		entryDir := t.TempDir()
+
+		pending := NewEditEntry()
+		if err := pending.SavePending(entryDir); err != nil {
+			t.Fatalf("SavePending() error = %v", err)
+		}
+		done := NewEditEntry()
+		if err := done.Save(entryDir); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		edits, err := ListEditEntries(entryDir)
+		if err != nil || len(edits) != 1 || edits[0].ID != done.ID {
+			t.Errorf("ListEditEntries() = %v, %v, want only the finalized edit", edits, err)
+		}
+	})
+}
+
+func TestRelativeTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 31, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-20 * time.Second), "just now"},
+		{now.Add(time.Minute), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-45 * time.Minute), "45 minutes ago"},
+		{now.Add(-2 * time.Hour), "2 hours ago"},
+		{time.Date(2026, 1, 30, 9, 0, 0, 0, time.UTC), "yesterday"},
+		{time.Date(2026, 1, 29, 23, 0, 0, 0, time.UTC), "2 days ago"},
+		{time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC), "2 months ago"},
+		{time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), "1 year ago"},
+	}
+	for _, tt := range tests {
+		if got := RelativeTime(tt.t, now); got != tt.want {
+			t.Errorf("RelativeTime(%s) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayLayout(t *testing.T) {
+	tests := []struct {
+		lcAll, lang string
+		want        string
+	}{
+		{"", "en_US.UTF-8", "Jan 2, 2006 3:04 PM"},
+		{"", "en_GB.UTF-8", "2 Jan 2006 15:04"},
+		{"", "de_DE.UTF-8", "02.01.2006 15:04"},
+		{"", "ja_JP.UTF-8", "2006-01-02 15:04"},
+		{"", "", "2006-01-02 15:04"},
+		{"fr_FR.UTF-8", "en_US.UTF-8", "02/01/2006 15:04"},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_TIME", "")
+		t.Setenv("LANG", tt.lang)
+		if got := DisplayLayout(); got != tt.want {
+			t.Errorf("DisplayLayout() with LC_ALL=%q LANG=%q = %q, want %q", tt.lcAll, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLocal(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	now := time.Now()
+	created := now.Add(-3 * time.Hour)
+	want := created.Local().Format("2006-01-02 15:04") + " (3 hours ago)"
+	if got := FormatLocal(created.UTC().Format(time.RFC3339), now); got != want {
+		t.Errorf("FormatLocal() = %q, want %q", got, want)
+	}
+	if got := FormatLocal("not a time", now); got != "not a time" {
+		t.Errorf("FormatLocal(invalid) = %q, want the value unchanged", got)
+	}
+}
+
+func TestFailuresByDay(t *testing.T) {
+	t.Parallel()
+
+	historyDir := t.TempDir()
+	ok := NewEntry()
+	ok.CreatedAt = "2025-03-01T10:00:00Z"
+	ok.Result.Success = true
+	if err := ok.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+	// Kept failures are counted from the log only
+	kept := NewEntry()
+	kept.CreatedAt = "2025-03-01T11:00:00Z"
+	if err := kept.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+	for _, f := range []Failure{
+		{Time: "2025-03-01T11:00:00Z", Kind: FailureKindGenerate, EntryID: kept.ID, Category: gemini.FailureSafety},
+		{Time: "2025-03-02T09:00:00Z", Kind: FailureKindEdit, EntryID: ok.ID, Category: gemini.FailureQuota},
+		{Time: "2025-02-01T09:00:00Z", Kind: FailureKindGenerate, Category: gemini.FailureQuota},
+	} {
+		if err := RecordFailure(historyDir, f); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+	// A torn line is skipped
+	file, err := os.OpenFile(filepath.Join(historyDir, FailureLogFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open failure log: %v", err)
+	}
+	_, _ = file.WriteString(`{"time":"2025-03-0`)
+	_ = file.Close()
+
+	since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	days, err := FailuresByDay(historyDir, since, time.Time{})
+	if err != nil {
+		t.Fatalf("FailuresByDay() error = %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("FailuresByDay() returned %d days, want 2", len(days))
+	}
+	if d := days["2025-03-01"]; d.Runs != 2 || d.Failed() != 1 || d.Categories[gemini.FailureSafety] != 1 {
+		t.Errorf("2025-03-01 = %+v, want 2 runs and 1 safety failure", d)
+	}
+	if d := days["2025-03-02"]; d.Runs != 1 || d.Categories[gemini.FailureQuota] != 1 {
+		t.Errorf("2025-03-02 = %+v, want 1 run and 1 quota failure", d)
+	}
+
+	days, err = FailuresByDay(historyDir, since, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("FailuresByDay() error = %v", err)
+	}
+	if _, ok := days["2025-03-02"]; ok {
+		t.Errorf("FailuresByDay() includes a day at or after until")
+	}
+}
@@ -0,0 +1,162 @@
+package history
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// IndexFile is the cache of parsed entries kept in the history directory
+const IndexFile = "index.json"
+
+// indexMu serializes read-modify-write cycles of index files within the process.
+// Other processes may race; the index is only a cache, so a lost update costs a re-read.
+var indexMu sync.Mutex
+
+// historyIndex caches the meta.yaml of each entry with the size and modification time it had,
+// so ListEntries only needs to stat entries that did not change instead of reading them
+type historyIndex struct {
+	SchemaVersion string                 `json:"schema_version"` // SchemaVersion of the writer; another version discards the cache
+	Entries       map[string]indexRecord `json:"entries"`
+}
+
+type indexRecord struct {
+	ModTime int64  `json:"mod_time"` // meta.yaml modification time, nanoseconds since the epoch
+	Size    int64  `json:"size"`
+	Meta    string `json:"meta"` // meta.yaml content, kept verbatim so cached entries parse exactly like files
+}
+
+// matches reports whether the record was taken from meta.yaml as described by info
+func (r indexRecord) matches(info fs.FileInfo) bool {
+	return r.ModTime == info.ModTime().UnixNano() && r.Size == info.Size()
+}
+
+// readIndex loads the index of historyDir. A missing, unreadable or outdated index is empty.
+func readIndex(historyDir string) *historyIndex {
+	idx := &historyIndex{SchemaVersion: SchemaVersion, Entries: map[string]indexRecord{}}
+	data, err := os.ReadFile(filepath.Join(historyDir, IndexFile))
+	if err != nil {
+		return idx
+	}
+	var cached historyIndex
+	if err := json.Unmarshal(data, &cached); err != nil || cached.SchemaVersion != SchemaVersion || cached.Entries == nil {
+		return idx
+	}
+	return &cached
+}
+
+// writeIndex saves the index of historyDir. Errors are ignored: without an index, listing reads every entry.
+func writeIndex(historyDir string, idx *historyIndex) {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+	_ = writeMetaFile(filepath.Join(historyDir, IndexFile), data)
+}
+
+// newIndexRecord describes meta.yaml content data whose file has info
+func newIndexRecord(info fs.FileInfo, data []byte) indexRecord {
+	return indexRecord{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Meta: string(data)}
+}
+
+// indexEntry records the meta.yaml content data just written for entry id in the index of historyDir.
+// Pending entries are dropped from the index since they are not listed.
+func indexEntry(historyDir, id string, data []byte, finalized bool) {
+	if !finalized {
+		unindexEntry(historyDir, id)
+		return
+	}
+	info, err := os.Stat(filepath.Join(GetEntryDirByID(historyDir, id), metaFile))
+	if err != nil {
+		return
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx := readIndex(historyDir)
+	idx.Entries[id] = newIndexRecord(info, data)
+	writeIndex(historyDir, idx)
+}
+
+// unindexEntry removes the entry with id from the index of historyDir
+func unindexEntry(historyDir, id string) {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx := readIndex(historyDir)
+	if _, ok := idx.Entries[id]; !ok {
+		return
+	}
+	delete(idx.Entries, id)
+	writeIndex(historyDir, idx)
+}
+
+// listIndexed loads the entries named ids from historyDir, taking unchanged ones from the index.
+// Entries that fail to load are skipped. The index is only read: listings also run in read-only
+// projects, and RefreshIndex writes it back for writers holding the subproject lock.
+func listIndexed(historyDir string, ids []string) []*Entry {
+	result, _ := scanIndex(historyDir, readIndex(historyDir), ids)
+	return result
+}
+
+// RefreshIndex brings the index of historyDir up to date with its entries: entries read from disk
+// are added and vanished ones removed. Call it only while holding the subproject lock. Errors are
+// ignored like in writeIndex.
+func RefreshIndex(historyDir string) {
+	ids, err := entryIDs(historyDir)
+	if err != nil {
+		return
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx := readIndex(historyDir)
+	if _, changed := scanIndex(historyDir, idx, ids); changed {
+		writeIndex(historyDir, idx)
+	}
+}
+
+// scanIndex loads the entries named ids like listIndexed, updating idx to match them. It reports
+// whether idx changed.
+func scanIndex(historyDir string, idx *historyIndex, ids []string) ([]*Entry, bool) {
+	changed := false
+
+	result := make([]*Entry, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+		metaPath := filepath.Join(GetEntryDirByID(historyDir, id), metaFile)
+		info, err := os.Stat(metaPath)
+		if err != nil {
+			continue
+		}
+		if rec, ok := idx.Entries[id]; ok && rec.matches(info) {
+			if entry, err := parseEntry([]byte(rec.Meta)); err == nil {
+				result = append(result, entry)
+				continue
+			}
+		}
+
+		data, err := os.ReadFile(metaPath)
+		if err != nil {
+			continue
+		}
+		entry, err := parseEntry(data)
+		if err != nil {
+			if _, ok := idx.Entries[id]; ok {
+				delete(idx.Entries, id)
+				changed = true
+			}
+			continue
+		}
+		result = append(result, entry)
+		idx.Entries[id] = newIndexRecord(info, data)
+		changed = true
+	}
+	for id := range idx.Entries {
+		if !seen[id] {
+			delete(idx.Entries, id)
+			changed = true
+		}
+	}
+	return result, changed
+}
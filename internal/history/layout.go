@@ -0,0 +1,140 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Layouts of the files in an entry directory. New entries get the history_layout of banago.yaml,
+// which callers resolve from the loaded project config and pass in.
+const (
+	LayoutFlat   = "flat"   // inputs and outputs next to meta.yaml (schema 1.x)
+	LayoutNested = "nested" // outputs in outputs/, inputs in inputs/ (schema 2.x)
+)
+
+// Layout returns the layout of the entry directory, which its schema version records
+func (e *Entry) Layout() string {
+	if major, _, err := parseSchemaVersion(e.SchemaVersion); err == nil && major == nestedSchemaMajor {
+		return LayoutNested
+	}
+	return LayoutFlat
+}
+
+// SetLayout sets the layout of an entry whose files are not written yet; anything but LayoutNested
+// (including "", an unset history_layout) is flat. Existing entries change layout with ConvertLayout.
+func (e *Entry) SetLayout(layout string) {
+	if layout == LayoutNested {
+		e.SchemaVersion = NestedSchemaVersion
+	} else {
+		e.SchemaVersion = SchemaVersion
+	}
+}
+
+// OutputDir returns the directory the outputs of the entry are saved in
+func (e *Entry) OutputDir(historyDir string) string {
+	return GetEntryOutputDir(e.GetEntryDir(historyDir), e.Layout())
+}
+
+// OutputPath returns the path of the output (or animation) name of the entry
+func (e *Entry) OutputPath(historyDir, name string) string {
+	return GetEntryFilePath(e.OutputDir(historyDir), name)
+}
+
+// InputPath returns the path an input image copied into the entry is saved at.
+// Readers use GetInputImagePaths, which also finds inputs kept in the object store.
+func (e *Entry) InputPath(historyDir, name string) string {
+	return GetEntryFilePath(GetEntryInputDir(e.GetEntryDir(historyDir), e.Layout()), name)
+}
+
+// FilePath returns where the file name of the entry belongs in its layout: outputs and inputs
+// in their directories, everything else (prompt, archived context, response) in the entry directory
+func (e *Entry) FilePath(historyDir, name string) string {
+	switch {
+	case slices.Contains(e.Result.OutputImages, name) || (name == e.Result.Animation && name != ""):
+		return e.OutputPath(historyDir, name)
+	case slices.Contains(e.Generation.InputImages, name):
+		return e.InputPath(historyDir, name)
+	}
+	return GetEntryFilePath(e.GetEntryDir(historyDir), name)
+}
+
+// findEntryFile returns the path of the file name in entryDir in either layout
+func findEntryFile(entryDir, name string) (string, bool) {
+	for _, dir := range []string{entryDir, GetEntryOutputDir(entryDir, LayoutNested), GetEntryInputDir(entryDir, LayoutNested)} {
+		if path := GetEntryFilePath(dir, name); fileExists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ListEntryFiles returns the names of the files in entryDir, meta.yaml included, in either layout.
+// Edits are not listed. Read them with ResolveEntryFile.
+func ListEntryFiles(entryDir string) ([]string, error) {
+	files, err := os.ReadDir(entryDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry directory: %w", err)
+	}
+	for _, dir := range []string{GetEntryOutputDir(entryDir, LayoutNested), GetEntryInputDir(entryDir, LayoutNested)} {
+		nested, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read entry directory: %w", err)
+		}
+		files = append(files, nested...)
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.Type().IsRegular() && !slices.Contains(names, f.Name()) {
+			names = append(names, f.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// ConvertLayout moves the outputs and inputs of the entry into layout and records it in meta.yaml.
+// It reports whether the entry changed. Files already moved by an interrupted conversion are found
+// in either place, so converting again completes it.
+func (e *Entry) ConvertLayout(historyDir, layout string) (bool, error) {
+	if e.Layout() == layout {
+		return false, nil
+	}
+	entryDir := e.GetEntryDir(historyDir)
+	names, err := ListEntryFiles(entryDir)
+	if err != nil {
+		return false, err
+	}
+
+	converted := *e
+	converted.SetLayout(layout)
+	for _, name := range names {
+		if name == metaFile {
+			continue
+		}
+		from, _ := findEntryFile(entryDir, name)
+		to := converted.FilePath(historyDir, name)
+		if from == to {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+			return false, fmt.Errorf("failed to create %s: %w", filepath.Dir(to), err)
+		}
+		if err := os.Rename(from, to); err != nil {
+			return false, fmt.Errorf("failed to move %s: %w", name, err)
+		}
+	}
+
+	e.SetLayout(layout)
+	if err := e.Save(historyDir); err != nil {
+		return false, err
+	}
+	// Emptied directories of the nested layout are left behind otherwise
+	if layout == LayoutFlat {
+		_ = os.Remove(GetEntryOutputDir(entryDir, LayoutNested))
+		_ = os.Remove(GetEntryInputDir(entryDir, LayoutNested))
+	}
+	return true, nil
+}
@@ -0,0 +1,265 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Lineage records how an entry was derived from other entries
+type Lineage struct {
+	SplitFrom    string        `yaml:"split_from,omitempty"`    // entry ID this entry was split from
+	MergedFrom   []string      `yaml:"merged_from,omitempty"`   // entry IDs this entry was merged from
+	ImportedFrom *ImportSource `yaml:"imported_from,omitempty"` // entry this entry was imported from
+	AdoptedFrom  string        `yaml:"adopted_from,omitempty"`  // quick run ID this entry was adopted from
+
+	RegeneratedFrom string `yaml:"regenerated_from,omitempty"` // entry ID this entry was regenerated from
+
+	Extra Extra `yaml:",inline"`
+}
+
+// ImportSource identifies an entry in another banago project
+type ImportSource struct {
+	Project    string `yaml:"project"`
+	Subproject string `yaml:"subproject"`
+	EntryID    string `yaml:"entry_id"`
+}
+
+// SplitEntry breaks a multi-output entry into one entry per output.
+// The prompt and input images are copied into each new entry and the original entry is removed.
+// Token usage, cost and the animation are kept on the first new entry so totals stay unchanged;
+// tags, rating and evaluations are copied into each. New entries get layout.
+func SplitEntry(historyDir, id, layout string) ([]*Entry, error) {
+	source, err := GetEntryByID(historyDir, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(source.Result.OutputImages) < 2 {
+		return nil, fmt.Errorf("entry %s has fewer than two outputs", id)
+	}
+	sourceDir := source.GetEntryDir(historyDir)
+	if CountEditEntries(sourceDir) > 0 {
+		return nil, fmt.Errorf("entry %s has edits and cannot be split", id)
+	}
+
+	var created []*Entry
+	for i, output := range source.Result.OutputImages {
+		entry := NewEntry()
+		entry.CreatedAt = source.CreatedAt
+		entry.Generation = source.Generation
+		entry.Generation.InputImages = slices.Clone(source.Generation.InputImages)
+		entry.Generation.InputObjects = maps.Clone(source.Generation.InputObjects)
+		entry.Result.Success = source.Result.Success
+		entry.Result.OutputImages = []string{output}
+		entry.Result.Evaluations = slices.Clone(source.Result.Evaluations)
+		entry.Tags = slices.Clone(source.Tags)
+		entry.Rating = source.Rating
+		if i == 0 {
+			entry.Result.TokenUsage = source.Result.TokenUsage
+			entry.Result.CostUSD = source.Result.CostUSD
+			entry.Result.Animation = source.Result.Animation
+		}
+		entry.Lineage = &Lineage{SplitFrom: source.ID}
+		entry.SetLayout(layout)
+
+		exclude := slices.DeleteFunc(slices.Clone(source.Result.OutputImages), func(img string) bool {
+			return img == output
+		})
+		if i > 0 && source.Result.Animation != "" {
+			exclude = append(exclude, source.Result.Animation)
+		}
+		if err := copyEntryFiles(sourceDir, entry, historyDir, exclude); err != nil {
+			cleanupEntries(historyDir, append(created, entry))
+			return nil, err
+		}
+		if err := entry.Save(historyDir); err != nil {
+			cleanupEntries(historyDir, append(created, entry))
+			return nil, err
+		}
+		created = append(created, entry)
+	}
+
+	if err := source.Cleanup(historyDir); err != nil {
+		return nil, fmt.Errorf("failed to remove split entry: %w", err)
+	}
+
+	return created, nil
+}
+
+// MergeEntries groups entries sharing the same prompt and input images into a single entry.
+// Outputs of all entries are copied into the new entry, in layout, and the original entries are removed.
+// Token usage and cost are summed, tags and evaluations combined and the highest rating kept.
+// Only the first animation is kept, as they all share one filename.
+func MergeEntries(historyDir string, ids []string, layout string) (*Entry, error) {
+	if len(ids) < 2 {
+		return nil, errors.New("at least two entries are required to merge")
+	}
+
+	var sources []*Entry
+	var basePrompt string
+	for i, id := range ids {
+		entry, err := GetEntryByID(historyDir, id)
+		if err != nil {
+			return nil, err
+		}
+		entryDir := entry.GetEntryDir(historyDir)
+		if !entry.Result.Success {
+			return nil, fmt.Errorf("entry %s is not successful and cannot be merged", id)
+		}
+		if CountEditEntries(entryDir) > 0 {
+			return nil, fmt.Errorf("entry %s has edits and cannot be merged", id)
+		}
+		prompt, err := LoadPrompt(entryDir)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			basePrompt = prompt
+		} else {
+			if prompt != basePrompt {
+				return nil, fmt.Errorf("entry %s has a different prompt", id)
+			}
+			if !slices.Equal(entry.Generation.InputImages, sources[0].Generation.InputImages) {
+				return nil, fmt.Errorf("entry %s has different input images", id)
+			}
+		}
+		sources = append(sources, entry)
+	}
+
+	merged := NewEntry()
+	merged.CreatedAt = sources[0].CreatedAt
+	merged.Generation = sources[0].Generation
+	merged.Generation.InputImages = slices.Clone(sources[0].Generation.InputImages)
+	merged.Generation.InputObjects = maps.Clone(sources[0].Generation.InputObjects)
+	merged.Result.Success = true
+	merged.Lineage = &Lineage{}
+	merged.SetLayout(layout)
+
+	for _, source := range sources {
+		for _, output := range source.Result.OutputImages {
+			if slices.Contains(merged.Result.OutputImages, output) {
+				cleanupEntries(historyDir, []*Entry{merged})
+				return nil, fmt.Errorf("duplicate output filename %s", output)
+			}
+		}
+		merged.Result.OutputImages = append(merged.Result.OutputImages, source.Result.OutputImages...)
+		var exclude []string
+		if animation := source.Result.Animation; animation != "" {
+			if merged.Result.Animation == "" {
+				merged.Result.Animation = animation
+			} else {
+				exclude = append(exclude, animation)
+			}
+		}
+		if err := copyEntryFiles(source.GetEntryDir(historyDir), merged, historyDir, exclude); err != nil {
+			cleanupEntries(historyDir, []*Entry{merged})
+			return nil, err
+		}
+		if source.CreatedAt < merged.CreatedAt {
+			merged.CreatedAt = source.CreatedAt
+		}
+		merged.Result.TokenUsage.Add(source.Result.TokenUsage)
+		merged.Result.CostUSD += source.Result.CostUSD
+		merged.Result.Evaluations = append(merged.Result.Evaluations, source.Result.Evaluations...)
+		merged.AddTags(source.Tags...)
+		merged.Rating = max(merged.Rating, source.Rating)
+		merged.Lineage.MergedFrom = append(merged.Lineage.MergedFrom, source.ID)
+	}
+
+	if err := merged.Save(historyDir); err != nil {
+		cleanupEntries(historyDir, []*Entry{merged})
+		return nil, err
+	}
+
+	for _, source := range sources {
+		if err := source.Cleanup(historyDir); err != nil {
+			return nil, fmt.Errorf("failed to remove merged entry: %w", err)
+		}
+	}
+
+	return merged, nil
+}
+
+// ImportEntry copies an entry from another history directory into historyDir as a new entry.
+// The prompt, input images and outputs are copied, in layout; edits are not.
+func ImportEntry(srcHistoryDir, id, historyDir, layout string, from ImportSource) (*Entry, error) {
+	source, err := GetEntryByID(srcHistoryDir, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := NewEntry()
+	entry.CreatedAt = source.CreatedAt
+	entry.Generation = source.Generation
+	entry.Generation.InputImages = slices.Clone(source.Generation.InputImages)
+	// Input snapshots are taken again below, in the object store of this project if it has one
+	entry.Generation.InputObjects = nil
+	entry.Result = source.Result
+	entry.Result.OutputImages = slices.Clone(source.Result.OutputImages)
+	entry.Lineage = &Lineage{ImportedFrom: &from}
+	entry.SetLayout(layout)
+
+	srcEntryDir := source.GetEntryDir(srcHistoryDir)
+	if err := copyEntryFiles(srcEntryDir, entry, historyDir, nil); err != nil {
+		cleanupEntries(historyDir, []*Entry{entry})
+		return nil, err
+	}
+	var stored []string
+	for _, name := range source.Generation.InputImages {
+		if _, copied := findEntryFile(srcEntryDir, name); !copied && source.Generation.InputObjects[name] != "" {
+			stored = append(stored, ResolveEntryFile(srcEntryDir, name))
+		}
+	}
+	if err := entry.SaveInputImages(historyDir, stored); err != nil {
+		cleanupEntries(historyDir, []*Entry{entry})
+		return nil, err
+	}
+	if err := entry.Save(historyDir); err != nil {
+		cleanupEntries(historyDir, []*Entry{entry})
+		return nil, err
+	}
+	// Copied inputs move into the object store; if that fails they stay valid copies
+	_, _ = entry.StoreInputImages(historyDir)
+
+	return entry, nil
+}
+
+// copyEntryFiles copies the files of the entry directory srcDir, in either layout, into dst in its
+// layout, skipping meta.yaml and excluded names. The outputs and inputs of dst must be set.
+func copyEntryFiles(srcDir string, dst *Entry, historyDir string, exclude []string) error {
+	names, err := ListEntryFiles(srcDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst.GetEntryDir(historyDir), 0o755); err != nil {
+		return fmt.Errorf("failed to create entry directory: %w", err)
+	}
+
+	for _, name := range names {
+		if name == metaFile || slices.Contains(exclude, name) {
+			continue
+		}
+		data, err := os.ReadFile(ResolveEntryFile(srcDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		dstPath := dst.FilePath(historyDir, name)
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create entry directory: %w", err)
+		}
+		if err := os.WriteFile(dstPath, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// cleanupEntries removes partially created entries after a failed split or merge
+func cleanupEntries(historyDir string, entries []*Entry) {
+	for _, e := range entries {
+		_ = e.Cleanup(historyDir)
+	}
+}
@@ -0,0 +1,192 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ObjectsDir holds the input snapshots of all history entries of a project, relative to the project root
+const ObjectsDir = ".banago/objects"
+
+// ObjectStore is a content-addressed store of input snapshots. A snapshot is kept once per content and
+// file name as <sha256[:2]>/<sha256>/<name>, so the file name tools and the API see stays the same.
+type ObjectStore struct {
+	dir string
+}
+
+// NewObjectStore returns the object store in dir
+func NewObjectStore(dir string) *ObjectStore {
+	return &ObjectStore{dir: dir}
+}
+
+// FindObjectStore returns the object store of the project containing dir, or nil when dir is not
+// inside a project (e.g. a history_dir outside it), in which case inputs are copied into entries
+func FindObjectStore(dir string) *ObjectStore {
+	root := findProjectRoot(dir)
+	if root == "" {
+		return nil
+	}
+	return NewObjectStore(filepath.Join(root, ObjectsDir))
+}
+
+// findProjectRoot returns the nearest directory at or above dir with a banago.yaml, or "" if there is none
+func findProjectRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if config.ProjectConfigExists(abs) {
+			return abs
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return ""
+		}
+		abs = parent
+	}
+}
+
+// Dir returns the directory of the store
+func (s *ObjectStore) Dir() string {
+	return s.dir
+}
+
+// Path returns the path of the snapshot named name with the hex SHA-256 sum
+func (s *ObjectStore) Path(sum, name string) string {
+	name = filepath.Base(name)
+	if len(sum) < 2 {
+		return filepath.Join(s.dir, sum, name)
+	}
+	return filepath.Join(s.dir, sum[:2], sum, name)
+}
+
+// Put stores the file at srcPath under its base name and returns its hex SHA-256.
+// Snapshots already in the store are not written again.
+func (s *ObjectStore) Put(srcPath string) (string, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	path := s.Path(sum, filepath.Base(srcPath))
+	if _, err := os.Stat(path); err == nil {
+		return sum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// Written through a temp file so parallel runs and crashes never leave a partial snapshot
+	if err := writeMetaFile(path, data); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// ResolveEntryFile returns the path of the file name of the entry in entryDir: the file in the
+// directory in either layout, or for input images in the object store their snapshot. Files found
+// in neither place resolve to their path in entryDir.
+func ResolveEntryFile(entryDir, name string) string {
+	if path, ok := findEntryFile(entryDir, name); ok {
+		return path
+	}
+	path := GetEntryFilePath(entryDir, name)
+	sum := readInputObjects(entryDir)[name]
+	if sum == "" {
+		return path
+	}
+	store := FindObjectStore(entryDir)
+	if store == nil {
+		return path
+	}
+	if snapshot := store.Path(sum, name); fileExists(snapshot) {
+		return snapshot
+	}
+	return path
+}
+
+// readInputObjects returns the input objects recorded in the meta.yaml of entryDir
+func readInputObjects(entryDir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(entryDir, metaFile))
+	if err != nil {
+		return nil
+	}
+	var meta struct {
+		Generation struct {
+			InputObjects map[string]string `yaml:"input_objects"`
+		} `yaml:"generation"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil
+	}
+	return meta.Generation.InputObjects
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// saveInputImage snapshots one input image: into store, recorded in Generation.InputObjects, or as a
+// copy in the entry directory when store is nil
+func (e *Entry) saveInputImage(historyDir string, store *ObjectStore, srcPath string) error {
+	if store == nil {
+		dstPath := e.InputPath(historyDir, filepath.Base(srcPath))
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+			return fmt.Errorf("failed to save input image (%s): %w", srcPath, err)
+		}
+		if err := copyFile(srcPath, dstPath); err != nil {
+			return fmt.Errorf("failed to save input image (%s): %w", srcPath, err)
+		}
+		return nil
+	}
+	sum, err := store.Put(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to save input image (%s): %w", srcPath, err)
+	}
+	if e.Generation.InputObjects == nil {
+		e.Generation.InputObjects = map[string]string{}
+	}
+	e.Generation.InputObjects[filepath.Base(srcPath)] = sum
+	return nil
+}
+
+// StoreInputImages moves the input images copied into the entry directory into the project's object
+// store and returns how many it moved. The copies are removed once meta.yaml records the snapshots.
+// Entries outside a project are left unchanged.
+func (e *Entry) StoreInputImages(historyDir string) (int, error) {
+	store := FindObjectStore(historyDir)
+	if store == nil {
+		return 0, nil
+	}
+	var copies []string
+	for _, name := range e.Generation.InputImages {
+		path := e.InputPath(historyDir, name)
+		if !fileExists(path) {
+			continue
+		}
+		if err := e.saveInputImage(historyDir, store, path); err != nil {
+			return 0, err
+		}
+		copies = append(copies, path)
+	}
+	if len(copies) == 0 {
+		return 0, nil
+	}
+	if err := e.Save(historyDir); err != nil {
+		return 0, err
+	}
+	for i, path := range copies {
+		if err := os.Remove(path); err != nil {
+			return i, fmt.Errorf("failed to remove input copy: %w", err)
+		}
+	}
+	return len(copies), nil
+}
@@ -0,0 +1,54 @@
+package history
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Orphan is a directory in the history directory that is not a loadable entry.
+// ListEntries skips these silently.
+type Orphan struct {
+	Name        string // directory name inside the history directory
+	Reason      string
+	Collectable bool // left by an interrupted run; FindGarbage reports it
+}
+
+// FindOrphans returns the directories in historyDir that are not valid entries, sorted by name
+func FindOrphans(historyDir string) ([]Orphan, error) {
+	dirEntries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var orphans []Orphan
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			continue
+		}
+		name := d.Name()
+		if _, err := uuid.Parse(name); err != nil {
+			orphans = append(orphans, Orphan{Name: name, Reason: "not an entry ID"})
+			continue
+		}
+		entryDir := GetEntryDirByID(historyDir, name)
+		if _, err := os.Stat(filepath.Join(entryDir, metaFile)); errors.Is(err, fs.ErrNotExist) {
+			orphans = append(orphans, Orphan{Name: name, Reason: "meta.yaml is missing", Collectable: true})
+			continue
+		}
+		if _, err := loadEntry(entryDir); errors.Is(err, ErrNotFinalized) {
+			orphans = append(orphans, Orphan{Name: name, Reason: "not finalized (generation still running or interrupted)", Collectable: true})
+		} else if err != nil {
+			orphans = append(orphans, Orphan{Name: name, Reason: err.Error()})
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	return orphans, nil
+}
@@ -2,9 +2,69 @@ package history
 
 import "path/filepath"
 
-const historyDirName = "history"
+// Layout of the history directory:
+//
+//	history/
+//	└── <entry-id>/
+//	    ├── meta.yaml, prompt.txt, input and output images
+//	    └── edits/
+//	        └── <edit-id>/
+//	            └── edit-meta.yaml, edit-prompt.txt, output images
+//
+// Entries in the nested layout (LayoutNested) keep their output images in outputs/ and their input
+// images in inputs/ instead. All paths inside the history directory are built by the functions in
+// this file and the accessors of Entry in layout.go.
+
+const (
+	historyDirName = "history"
+	editsDir       = "edits"
+	outputsDir     = "outputs"
+	inputsDir      = "inputs"
+)
 
 // GetHistoryDir returns the path to the history directory of a subproject
 func GetHistoryDir(subprojectDir string) string {
 	return filepath.Join(subprojectDir, historyDirName)
 }
+
+// GetEntryDirByID returns the path to a history entry directory
+func GetEntryDirByID(historyDir, id string) string {
+	return filepath.Join(historyDir, id)
+}
+
+// GetEntryFilePath returns the path to a file in a directory of an entry. For input and output images
+// use the accessors of Entry, which follow its layout, or ResolveEntryFile.
+func GetEntryFilePath(entryDir, filename string) string {
+	return filepath.Join(entryDir, filename)
+}
+
+// GetEntryOutputDir returns the directory with the output images of an entry directory in layout
+func GetEntryOutputDir(entryDir, layout string) string {
+	if layout == LayoutNested {
+		return filepath.Join(entryDir, outputsDir)
+	}
+	return entryDir
+}
+
+// GetEntryInputDir returns the directory with the input images copied into an entry directory in layout
+func GetEntryInputDir(entryDir, layout string) string {
+	if layout == LayoutNested {
+		return filepath.Join(entryDir, inputsDir)
+	}
+	return entryDir
+}
+
+// GetEditsDir returns the path to the edits directory within an entry
+func GetEditsDir(entryDir string) string {
+	return filepath.Join(entryDir, editsDir)
+}
+
+// GetEditDirByID returns the path to an edit entry directory within an entry
+func GetEditDirByID(entryDir, editID string) string {
+	return filepath.Join(GetEditsDir(entryDir), editID)
+}
+
+// GetEditOutputPath returns the path to an output image in an edit entry
+func GetEditOutputPath(entryDir, editID, outputFilename string) string {
+	return filepath.Join(GetEditDirByID(entryDir, editID), outputFilename)
+}
@@ -0,0 +1,84 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PruneCriteria selects history entries for deletion.
+// An entry is selected only if it matches every set criterion.
+type PruneCriteria struct {
+	Predicates []Predicate // e.g. CreatedBefore, Failed
+	KeepNewest int         // the newest N entries are never selected (0 = no limit)
+}
+
+// PruneCandidate is an entry selected for deletion and the disk space it uses
+type PruneCandidate struct {
+	Entry *Entry
+	Size  int64
+}
+
+// SelectPrune returns the entries matching the criteria, oldest first.
+// Criteria without any predicate or KeepNewest are rejected so that a mistake cannot select everything.
+func SelectPrune(historyDir string, criteria PruneCriteria) ([]PruneCandidate, error) {
+	if len(criteria.Predicates) == 0 && criteria.KeepNewest <= 0 {
+		return nil, errors.New("no prune criteria given")
+	}
+
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	if criteria.KeepNewest > 0 {
+		entries = entries[:max(len(entries)-criteria.KeepNewest, 0)]
+	}
+
+	var candidates []PruneCandidate
+	for _, e := range entries {
+		if !matchAll(historyDir, e, criteria.Predicates) {
+			continue
+		}
+		size, err := DirSize(e.GetEntryDir(historyDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure entry %s: %w", e.ID, err)
+		}
+		candidates = append(candidates, PruneCandidate{Entry: e, Size: size})
+	}
+	return candidates, nil
+}
+
+// Prune deletes the entry directories of the candidates, including their edits
+func Prune(historyDir string, candidates []PruneCandidate) error {
+	for _, c := range candidates {
+		if err := c.Entry.Cleanup(historyDir); err != nil {
+			return fmt.Errorf("failed to delete entry %s: %w", c.Entry.ID, err)
+		}
+	}
+	return nil
+}
+
+// DirSize returns the total size of regular files under dir (0 if it does not exist)
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Predicate reports whether an entry stored in historyDir should be selected
+type Predicate func(historyDir string, e *Entry) bool
+
+// SearchEntries returns the entries that match all predicates, sorted chronologically like ListEntries
+func SearchEntries(historyDir string, predicates ...Predicate) ([]*Entry, error) {
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*Entry{}
+	for _, e := range entries {
+		if matchAll(historyDir, e, predicates) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func matchAll(historyDir string, e *Entry, predicates []Predicate) bool {
+	for _, p := range predicates {
+		if !p(historyDir, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// PromptContains matches entries whose prompt contains text, ignoring case
+func PromptContains(text string) Predicate {
+	text = strings.ToLower(text)
+	return func(historyDir string, e *Entry) bool {
+		prompt, err := LoadPrompt(e.GetEntryDir(historyDir))
+		if err != nil {
+			return false
+		}
+		return strings.Contains(strings.ToLower(prompt), text)
+	}
+}
+
+// CreatedSince matches entries created at or after t
+func CreatedSince(t time.Time) Predicate {
+	return func(_ string, e *Entry) bool {
+		created, ok := e.createdTime()
+		return ok && !created.Before(t)
+	}
+}
+
+// CreatedBefore matches entries created strictly before t
+func CreatedBefore(t time.Time) Predicate {
+	return func(_ string, e *Entry) bool {
+		created, ok := e.createdTime()
+		return ok && created.Before(t)
+	}
+}
+
+// Failed matches entries whose generation did not succeed
+func Failed() Predicate {
+	return func(_ string, e *Entry) bool {
+		return !e.Result.Success
+	}
+}
+
+// HasTag matches entries tagged with tag
+func HasTag(tag string) Predicate {
+	return func(_ string, e *Entry) bool {
+		return slices.Contains(e.Tags, tag)
+	}
+}
+
+// RatedAtLeast matches entries rated rating or higher; unrated entries never match
+func RatedAtLeast(rating int) Predicate {
+	return func(_ string, e *Entry) bool {
+		return e.Rating > 0 && e.Rating >= rating
+	}
+}
+
+// createdTime parses CreatedAt, which is stored in RFC3339
+func (e *Entry) createdTime() (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, e.CreatedAt)
+	return t, err == nil
+}
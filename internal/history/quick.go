@@ -0,0 +1,133 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// QuickRun records a generation made outside a project (banago quick).
+// It is saved next to the outputs as quick-<id>.yaml so the run can be adopted into a subproject later.
+type QuickRun struct {
+	ID          string            `yaml:"id"`
+	CreatedAt   string            `yaml:"created_at"`
+	Model       string            `yaml:"model"`
+	Prompt      string            `yaml:"prompt"`
+	InputImages []string          `yaml:"input_images,omitempty"` // absolute paths
+	AspectRatio string            `yaml:"aspect_ratio,omitempty"`
+	ImageSize   string            `yaml:"image_size,omitempty"`
+	Outputs     []string          `yaml:"outputs"` // filenames in the same directory
+	TokenUsage  gemini.TokenUsage `yaml:"token_usage,omitempty"`
+	AdoptedAs   string            `yaml:"adopted_as,omitempty"` // history entry ID once adopted
+}
+
+// NewQuickRun creates a new quick run with a UUID v7 ID
+func NewQuickRun() *QuickRun {
+	return &QuickRun{
+		ID:        uuid.Must(uuid.NewV7()).String(),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// quickRunFile returns the manifest filename of a quick run
+func quickRunFile(id string) string {
+	return "quick-" + id + ".yaml"
+}
+
+// Save writes the quick run manifest to dir
+func (r *QuickRun) Save(dir string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quick run: %w", err)
+	}
+	path := filepath.Join(dir, quickRunFile(r.ID))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", quickRunFile(r.ID), err)
+	}
+	return nil
+}
+
+// ListQuickRuns returns the quick runs recorded in dir, oldest first
+func ListQuickRuns(dir string) ([]*QuickRun, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "quick-*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*QuickRun
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		var run QuickRun
+		if err := yaml.Unmarshal(data, &run); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+		}
+		runs = append(runs, &run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, nil
+}
+
+// AdoptQuickRun imports a quick run from dir into historyDir as a new entry.
+// The prompt, outputs and still existing input images are copied into an entry of layout, and the
+// run is marked as adopted.
+func AdoptQuickRun(dir string, run *QuickRun, historyDir, layout string) (*Entry, error) {
+	entry := NewEntry()
+	entry.CreatedAt = run.CreatedAt
+	entry.Generation.PromptFile = PromptFile
+	entry.Generation.Model = run.Model
+	entry.Generation.AspectRatio = run.AspectRatio
+	entry.Generation.ImageSize = run.ImageSize
+	entry.Generation.TextOnly = len(run.InputImages) == 0
+	entry.Result.Success = true
+	entry.Result.OutputImages = append([]string{}, run.Outputs...)
+	entry.Result.TokenUsage = run.TokenUsage
+	entry.Lineage = &Lineage{AdoptedFrom: run.ID}
+	entry.SetLayout(layout)
+
+	if err := os.MkdirAll(entry.OutputDir(historyDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create entry directory: %w", err)
+	}
+	fail := func(err error) (*Entry, error) {
+		cleanupEntries(historyDir, []*Entry{entry})
+		return nil, err
+	}
+
+	if err := entry.SavePrompt(historyDir, run.Prompt); err != nil {
+		return fail(err)
+	}
+	for _, output := range run.Outputs {
+		if err := copyFile(filepath.Join(dir, output), entry.OutputPath(historyDir, output)); err != nil {
+			return fail(fmt.Errorf("failed to copy output %s: %w", output, err))
+		}
+	}
+	store := FindObjectStore(historyDir)
+	for _, input := range run.InputImages {
+		if _, err := os.Stat(input); err != nil {
+			continue // inputs of throwaway runs may be gone; the outputs are what matters
+		}
+		if err := entry.saveInputImage(historyDir, store, input); err != nil {
+			return fail(err)
+		}
+		entry.Generation.InputImages = append(entry.Generation.InputImages, filepath.Base(input))
+	}
+	if err := entry.Save(historyDir); err != nil {
+		return fail(err)
+	}
+
+	run.AdoptedAs = entry.ID
+	if err := run.Save(dir); err != nil {
+		return entry, fmt.Errorf("adopted as %s but failed to mark the quick run: %w", entry.ID, err)
+	}
+	return entry, nil
+}
@@ -0,0 +1,124 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+	"github.com/google/uuid"
+)
+
+// OutputRename is an output file renamed to the gemini.OutputFileName scheme
+type OutputRename struct {
+	EntryID string
+	EditID  string // empty for outputs of the entry itself
+	From    string
+	To      string
+}
+
+// NormalizeOutputNames renames the outputs of every entry and edit in historyDir whose names do not
+// follow gemini.OutputFileName (for example output_1.png written by hand or by other tools), so that
+// globbing output-* finds them. meta.yaml, edit-meta.yaml and edits that use a renamed output as their
+// source are updated. With dryRun the renames are only returned.
+func NormalizeOutputNames(historyDir string, dryRun bool) ([]OutputRename, error) {
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var renames []OutputRename
+	for _, entry := range entries {
+		entryDir := entry.GetEntryDir(historyDir)
+		edits, err := ListEditEntries(entryDir)
+		if err != nil {
+			return renames, err
+		}
+
+		entryNames := planRenames(entry.Result.OutputImages)
+		editNames := map[string]map[string]string{}
+		for _, edit := range edits {
+			editNames[edit.ID] = planRenames(edit.Result.OutputImages)
+		}
+
+		for _, from := range entry.Result.OutputImages {
+			if to, ok := entryNames[from]; ok {
+				renames = append(renames, OutputRename{EntryID: entry.ID, From: from, To: to})
+			}
+		}
+		for _, edit := range edits {
+			for _, from := range edit.Result.OutputImages {
+				if to, ok := editNames[edit.ID][from]; ok {
+					renames = append(renames, OutputRename{EntryID: entry.ID, EditID: edit.ID, From: from, To: to})
+				}
+			}
+		}
+		if dryRun {
+			continue
+		}
+
+		if len(entryNames) > 0 {
+			if err := renameOutputs(entry.OutputDir(historyDir), entry.Result.OutputImages, entryNames); err != nil {
+				return renames, err
+			}
+			if err := entry.Save(historyDir); err != nil {
+				return renames, err
+			}
+		}
+		for _, edit := range edits {
+			changed := len(editNames[edit.ID]) > 0
+			if changed {
+				if err := renameOutputs(edit.GetEditEntryDir(entryDir), edit.Result.OutputImages, editNames[edit.ID]); err != nil {
+					return renames, err
+				}
+			}
+			// Follow the renamed source image
+			sourceNames := entryNames
+			if edit.Source.Type == "edit" {
+				sourceNames = editNames[edit.Source.EditID]
+			}
+			if to, ok := sourceNames[edit.Source.Output]; ok {
+				edit.Source.Output = to
+				changed = true
+			}
+			if changed {
+				if err := edit.Save(entryDir); err != nil {
+					return renames, err
+				}
+			}
+		}
+	}
+	return renames, nil
+}
+
+// planRenames maps each output that does not follow the naming scheme to its new name.
+// The outputs of one entry or edit share a fresh run ID and keep their position as index.
+func planRenames(outputs []string) map[string]string {
+	names := map[string]string{}
+	var runID uuid.UUID
+	for i, name := range outputs {
+		if gemini.IsOutputFileName(name) {
+			continue
+		}
+		if runID == uuid.Nil {
+			runID = uuid.Must(uuid.NewV7())
+		}
+		names[name] = gemini.OutputFileName(runID, i+1, filepath.Ext(name))
+	}
+	return names
+}
+
+// renameOutputs renames the files of outputs in dir and updates outputs in place
+func renameOutputs(dir string, outputs []string, names map[string]string) error {
+	for i, from := range outputs {
+		to, ok := names[from]
+		if !ok {
+			continue
+		}
+		if err := os.Rename(filepath.Join(dir, from), filepath.Join(dir, to)); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", from, err)
+		}
+		outputs[i] = to
+	}
+	return nil
+}
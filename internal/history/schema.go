@@ -0,0 +1,84 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the meta.yaml and edit-meta.yaml format written by this banago.
+// Bump the minor version for added fields, which older binaries keep but ignore, and the major
+// version for changes older binaries cannot read correctly.
+const SchemaVersion = "1.10"
+
+// NestedSchemaVersion is the schema version of entries in the nested layout (LayoutNested). Its major
+// version is one higher, so banago versions that only know the flat layout refuse these entries
+// instead of missing their files. Its minor version follows SchemaVersion.
+const NestedSchemaVersion = "2.10"
+
+// nestedSchemaMajor is the major version of NestedSchemaVersion
+const nestedSchemaMajor = 2
+
+// ErrIncompatibleSchema is returned when loading metadata whose major schema version is neither that of
+// SchemaVersion nor that of NestedSchemaVersion
+var ErrIncompatibleSchema = errors.New("incompatible metadata schema")
+
+// Extra holds the keys of a metadata file that this banago does not know, written by a newer minor version.
+// They are kept when the file is rewritten so mixed-version teams do not lose fields.
+type Extra = map[string]any
+
+// parseSchemaVersion returns the major and minor number of a schema version. Metadata written before
+// schema versions existed has none and is version 1.0.
+func parseSchemaVersion(version string) (int, int, error) {
+	if version == "" {
+		return 1, 0, nil
+	}
+	majorStr, minorStr, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid schema_version %q", version)
+	}
+	minor := 0
+	if minorStr != "" {
+		if minor, err = strconv.Atoi(minorStr); err != nil {
+			return 0, 0, fmt.Errorf("invalid schema_version %q", version)
+		}
+	}
+	return major, minor, nil
+}
+
+// checkSchemaVersion fails with ErrIncompatibleSchema if file was written with a major schema version
+// this banago does not know
+func checkSchemaVersion(file, version string) error {
+	major, _, err := parseSchemaVersion(version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	current, _, _ := parseSchemaVersion(SchemaVersion)
+	switch {
+	case major > nestedSchemaMajor:
+		return fmt.Errorf("%w: %s has schema_version %s, newer than this banago supports (%s). Upgrade banago", ErrIncompatibleSchema, file, version, NestedSchemaVersion)
+	case major < current:
+		return fmt.Errorf("%w: %s has schema_version %s, older than this banago supports (%s). Run 'banago migrate'", ErrIncompatibleSchema, file, version, SchemaVersion)
+	}
+	return nil
+}
+
+// saveSchemaVersion returns the schema version to write for metadata loaded with version, keeping
+// its layout. A newer minor version is kept because its unknown fields are written back unchanged.
+func saveSchemaVersion(version string) string {
+	major, minor, err := parseSchemaVersion(version)
+	if err != nil || version == "" {
+		return SchemaVersion
+	}
+	current := SchemaVersion
+	if major == nestedSchemaMajor {
+		current = NestedSchemaVersion
+	}
+	currentMajor, currentMinor, _ := parseSchemaVersion(current)
+	if major == currentMajor && minor > currentMinor {
+		return version
+	}
+	return current
+}
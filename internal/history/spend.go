@@ -0,0 +1,131 @@
+package history
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/gemini"
+)
+
+// Spend is the estimated spend of generations and edits
+type Spend struct {
+	Generations int
+	Edits       int
+	TokenUsage  gemini.TokenUsage
+	USD         float64
+	Unpriced    int // runs with token usage but no pricing for their model
+}
+
+// Add adds the spend of other to s
+func (s *Spend) Add(other Spend) {
+	s.Generations += other.Generations
+	s.Edits += other.Edits
+	s.TokenUsage.Add(other.TokenUsage)
+	s.USD += other.USD
+	s.Unpriced += other.Unpriced
+}
+
+func (s *Spend) addRun(r Result, model string) {
+	s.TokenUsage.Add(r.TokenUsage)
+	if cost, ok := r.EstimatedCost(model); ok {
+		s.USD += cost
+	} else if r.TokenUsage.Total > 0 {
+		s.Unpriced++
+	}
+}
+
+// EstimatedCost returns the cost recorded at generation time. Results recorded before costs were
+// stored are estimated from their token usage with model. The second result is false if the model has no pricing.
+func (r Result) EstimatedCost(model string) (float64, bool) {
+	if r.CostUSD > 0 {
+		return r.CostUSD, true
+	}
+	return gemini.EstimateCost(model, r.TokenUsage)
+}
+
+// SpendByMonth returns the spend of the generations and edits in historyDir keyed by
+// the month they were created in (YYYY-MM, UTC). defaultModel prices runs that do not record their model.
+func SpendByMonth(historyDir, defaultModel string) (map[string]*Spend, error) {
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	months := map[string]*Spend{}
+	month := func(createdAt string) *Spend {
+		key := createdAt
+		if len(key) >= len("2006-01") {
+			key = key[:len("2006-01")]
+		}
+		if months[key] == nil {
+			months[key] = &Spend{}
+		}
+		return months[key]
+	}
+
+	for _, e := range entries {
+		s := month(e.CreatedAt)
+		s.Generations++
+		s.addRun(e.Result, cmp.Or(e.Generation.Model, defaultModel))
+
+		edits, err := ListEditEntries(e.GetEntryDir(historyDir))
+		if err != nil {
+			continue
+		}
+		for _, edit := range edits {
+			s := month(edit.CreatedAt)
+			s.Edits++
+			s.addRun(edit.Result, cmp.Or(edit.Generation.Model, defaultModel))
+		}
+	}
+	return months, nil
+}
+
+// Activity is what happened in a history directory during a period
+type Activity struct {
+	Spend                // generations and edits created in the period
+	Failed      int      // new entries whose generation did not succeed
+	Regenerated int      // new entries regenerated from another entry
+	Entries     []*Entry // the new entries, chronologically
+}
+
+// ActivityBetween returns the generations and edits in historyDir created at or after since and before
+// until (the zero time for no upper bound). Edits count when they were made, also on older entries.
+// defaultModel prices runs that do not record their model.
+func ActivityBetween(historyDir string, since, until time.Time, defaultModel string) (*Activity, error) {
+	entries, err := ListEntries(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	inPeriod := func(createdAt string) bool {
+		t, err := time.Parse(time.RFC3339, createdAt)
+		return err == nil && !t.Before(since) && (until.IsZero() || t.Before(until))
+	}
+
+	activity := &Activity{Entries: []*Entry{}}
+	for _, e := range entries {
+		if inPeriod(e.CreatedAt) {
+			activity.Generations++
+			activity.addRun(e.Result, cmp.Or(e.Generation.Model, defaultModel))
+			activity.Entries = append(activity.Entries, e)
+			if !e.Result.Success {
+				activity.Failed++
+			}
+			if e.Lineage != nil && e.Lineage.RegeneratedFrom != "" {
+				activity.Regenerated++
+			}
+		}
+
+		edits, err := ListEditEntries(e.GetEntryDir(historyDir))
+		if err != nil {
+			continue
+		}
+		for _, edit := range edits {
+			if inPeriod(edit.CreatedAt) {
+				activity.Edits++
+				activity.addRun(edit.Result, cmp.Or(edit.Generation.Model, defaultModel))
+			}
+		}
+	}
+	return activity, nil
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServer_Serve(t *testing.T) {
+	t.Parallel()
+
+	echo := Tool{
+		Name:        "echo",
+		Description: "Echo the text argument",
+		InputSchema: map[string]any{"type": "object"},
+		Call: func(_ context.Context, args json.RawMessage) (string, error) {
+			var a struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(args, &a); err != nil {
+				return "", err
+			}
+			if a.Text == "" {
+				return "", errors.New("text is required")
+			}
+			return a.Text, nil
+		},
+	}
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := NewServer("test", "1.0", echo).Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	var responses []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	// The notification gets no response
+	if len(responses) != 7 {
+		t.Fatalf("got %d responses, want 7", len(responses))
+	}
+
+	result := func(i int) map[string]any {
+		t.Helper()
+		r, ok := responses[i]["result"].(map[string]any)
+		if !ok {
+			t.Fatalf("response %d has no result: %v", i, responses[i])
+		}
+		return r
+	}
+	errorCode := func(i int) float64 {
+		t.Helper()
+		e, ok := responses[i]["error"].(map[string]any)
+		if !ok {
+			t.Fatalf("response %d has no error: %v", i, responses[i])
+		}
+		return e["code"].(float64)
+	}
+
+	if got := result(0)["protocolVersion"]; got != ProtocolVersion {
+		t.Errorf("protocolVersion = %v, want %s", got, ProtocolVersion)
+	}
+	if tools := result(1)["tools"].([]any); len(tools) != 1 || tools[0].(map[string]any)["name"] != "echo" {
+		t.Errorf("tools/list = %v", tools)
+	}
+	content := result(2)["content"].([]any)[0].(map[string]any)
+	if content["text"] != "hi" {
+		t.Errorf("tools/call text = %v, want hi", content["text"])
+	}
+	if result(3)["isError"] != true {
+		t.Errorf("failing tool call should set isError: %v", result(3))
+	}
+	if got := errorCode(4); got != codeInvalidParams {
+		t.Errorf("unknown tool error code = %v", got)
+	}
+	if got := errorCode(5); got != codeMethodNotFound {
+		t.Errorf("unknown method error code = %v", got)
+	}
+	if got := errorCode(6); got != codeParseError {
+		t.Errorf("parse error code = %v", got)
+	}
+}
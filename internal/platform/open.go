@@ -0,0 +1,56 @@
+package platform
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// OpenCommand returns the command that shows path in the desktop's file manager or default application
+func OpenCommand(path string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open", path}
+	case "windows":
+		return []string{"explorer", path}
+	default:
+		return []string{"xdg-open", path}
+	}
+}
+
+// Open shows path in the file manager (directories) or the default application (files) without waiting
+func Open(path string) error {
+	args := OpenCommand(path)
+	c := exec.Command(args[0], args[1:]...)
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	return c.Process.Release()
+}
+
+// EditorCommand returns the command that edits path: $VISUAL, else $EDITOR (either may include
+// arguments, such as "code --wait"), else notepad on Windows and vi elsewhere
+func EditorCommand(path string) []string {
+	editor := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR")))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+	return append(editor, path)
+}
+
+// Edit opens path in the user's editor and waits until it exits
+func Edit(path string) error {
+	args := EditorCommand(path)
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package platform hides the operating system differences that banago's file handling runs into:
+// names Windows cannot store, symlinks that need extra privileges on Windows, process checks, and
+// opening files in the user's editor or file manager.
+// Paths need no special handling: the os package already handles Windows paths longer than MAX_PATH.
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// reservedNames are device names Windows refuses as file names, with or without an extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// invalidNameChars cannot appear in Windows file names
+const invalidNameChars = `<>:"/\|?*`
+
+// ValidateName checks that name can be used as a single file or directory name on every platform,
+// so that projects created on one system can be opened on another
+func ValidateName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("invalid name %q", name)
+	case strings.ContainsAny(name, invalidNameChars):
+		return fmt.Errorf("invalid name %q: must not contain any of %s", name, invalidNameChars)
+	case strings.ContainsFunc(name, func(r rune) bool { return r < 0x20 }):
+		return fmt.Errorf("invalid name %q: must not contain control characters", name)
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		return fmt.Errorf("invalid name %q: must not end with a dot or space", name)
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return fmt.Errorf("invalid name %q: %s is reserved on Windows", name, base)
+	}
+	return nil
+}
+
+// LinkOrCopy creates dst as a symlink to src, or as a copy of src where symlinks cannot be created
+// (Windows without Developer Mode, file systems such as FAT). It reports whether it copied.
+func LinkOrCopy(src, dst string) (copied bool, err error) {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return false, err
+	}
+	err = os.Symlink(abs, dst)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return false, err
+	}
+	return true, CopyFile(src, dst)
+}
+
+// CopyFile copies the contents of src to dst
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ProcessRunning reports whether a process with pid exists
+func ProcessRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess already fails for missing processes on Windows
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,94 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"my-sub", false},
+		{"scene.v2", false},
+		{"console", false},
+		{"日本語", false},
+		{"", true},
+		{".", true},
+		{"..", true},
+		{"a/b", true},
+		{`a\b`, true},
+		{"a:b", true},
+		{"what?", true},
+		{"tab\tname", true},
+		{"trailing.", true},
+		{"trailing ", true},
+		{"CON", true},
+		{"nul", true},
+		{"com1", true},
+		{"LPT9.txt", true},
+		{"aux.tar.gz", true},
+	}
+	for _, tt := range tests {
+		err := ValidateName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLinkOrCopy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	if err := os.WriteFile(src, []byte("image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst.png")
+
+	copied, err := LinkOrCopy(src, dst)
+	if err != nil {
+		t.Fatalf("LinkOrCopy() error = %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "image" {
+		t.Errorf("dst content = %q, %v, want %q", data, err, "image")
+	}
+	info, err := os.Lstat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isLink := info.Mode()&os.ModeSymlink != 0; isLink == copied {
+		t.Errorf("copied = %v but symlink = %v", copied, isLink)
+	}
+
+	if _, err := LinkOrCopy(src, dst); err == nil {
+		t.Error("LinkOrCopy() should fail when dst exists")
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	got := EditorCommand("prompt.txt")
+	if want := []string{"code", "--wait", "prompt.txt"}; !slices.Equal(got, want) {
+		t.Errorf("EditorCommand() = %q, want %q", got, want)
+	}
+
+	t.Setenv("VISUAL", "nano")
+	if got := EditorCommand("prompt.txt"); got[0] != "nano" {
+		t.Errorf("EditorCommand() = %q, want $VISUAL to win", got)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	if got := EditorCommand("prompt.txt"); len(got) != 2 || got[1] != "prompt.txt" {
+		t.Errorf("EditorCommand() = %q, want a default editor", got)
+	}
+}
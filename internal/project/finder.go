@@ -2,6 +2,7 @@ package project
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +14,7 @@ var (
 	ErrProjectNotFound    = errors.New("banago project not found (no banago.yaml in current or parent directories)")
 	ErrNotInSubproject    = errors.New("not inside a subproject directory")
 	ErrAlreadyInitialized = errors.New("banago project already initialized in this directory")
+	ErrInsideArchiveDir   = errors.New("refusing to run inside a read-only subproject directory")
 )
 
 // FindProjectRoot searches for the project root directory by looking for banago.yaml
@@ -54,6 +56,24 @@ func FindCurrentSubproject(projectRoot, workDir string) (string, error) {
 	return "", ErrNotInSubproject
 }
 
+// CheckWorkDir fails with ErrInsideArchiveDir if workDir is inside the history or inputs
+// directory of the subproject. Both are read-only archives, and commands that write to the
+// subproject would otherwise run against them from an odd working directory.
+func CheckWorkDir(subprojectDir string, cfg *config.SubprojectConfig, workDir string) error {
+	dirs := []struct{ name, path string }{
+		{"history", ResolveHistoryDir(subprojectDir, cfg)},
+		{inputsDirName, GetInputsDir(subprojectDir)},
+	}
+	for _, d := range dirs {
+		rel, err := filepath.Rel(d.path, workDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return fmt.Errorf("%w (%s/). Run the command from the subproject directory: cd %s", ErrInsideArchiveDir, d.name, subprojectDir)
+	}
+	return nil
+}
+
 // listSubprojects returns a list of all subproject names in the project
 func listSubprojects(projectRoot string) ([]string, error) {
 	spDir := filepath.Join(projectRoot, subprojectsDir)
@@ -78,3 +98,9 @@ func listSubprojects(projectRoot string) ([]string, error) {
 
 	return names, nil
 }
+
+// ListSubprojectNames returns the directory names of all subprojects,
+// including those whose config.yaml cannot be loaded
+func ListSubprojectNames(projectRoot string) ([]string, error) {
+	return listSubprojects(projectRoot)
+}
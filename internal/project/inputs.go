@@ -0,0 +1,67 @@
+package project
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/config"
+)
+
+// InputsCheck reports mismatches between input_images and the images in inputs/
+type InputsCheck struct {
+	Missing      []string // listed in input_images but not found in inputs/
+	Unreferenced []string // images in inputs/ that input_images does not list
+}
+
+// OK reports whether input_images and inputs/ agree
+func (c InputsCheck) OK() bool {
+	return len(c.Missing) == 0 && len(c.Unreferenced) == 0
+}
+
+// CheckInputs compares the input_images of a subproject with the image files in its inputs/ directory.
+// Names are relative to inputs/ with forward slashes, as written in config.yaml.
+func CheckInputs(subprojectDir string, cfg *config.SubprojectConfig) (InputsCheck, error) {
+	inputsDir := GetInputsDir(subprojectDir)
+
+	var check InputsCheck
+	referenced := map[string]bool{}
+	for _, img := range cfg.InputImages {
+		name := filepath.ToSlash(filepath.Clean(img))
+		referenced[name] = true
+		if _, err := os.Stat(filepath.Join(inputsDir, img)); err != nil {
+			check.Missing = append(check.Missing, img)
+		}
+	}
+
+	err := filepath.WalkDir(inputsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isImageFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(inputsDir, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); !referenced[name] {
+			check.Unreferenced = append(check.Unreferenced, name)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return check, err
+	}
+	sort.Strings(check.Unreferenced)
+	return check, nil
+}
+
+// isImageFile reports whether path has an image file extension
+func isImageFile(path string) bool {
+	return strings.HasPrefix(mime.TypeByExtension(strings.ToLower(filepath.Ext(path))), "image/")
+}
@@ -0,0 +1,175 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/platform"
+)
+
+// ErrSubprojectLocked is returned by LockSubproject when another process holds the lock
+var ErrSubprojectLocked = errors.New("another banago process is writing to this subproject")
+
+const (
+	lockFileName     = ".banago.lock"
+	lockPollInterval = 200 * time.Millisecond
+	// lockWriteTimeout is how long a lock file may stay unwritten before its owner is taken to have
+	// died between creating and writing it
+	lockWriteTimeout = 5 * time.Second
+)
+
+// LockSubproject takes the lock a subproject's history is written under, so concurrent
+// generate, regenerate and edit runs do not interleave their writes.
+// The lock is a file holding the owner's PID and a random token; a lock left by a process that no
+// longer runs is taken over (see acquireLock).
+// With wait set it retries until the lock is free or ctx is done; otherwise it fails with ErrSubprojectLocked.
+// The returned function releases the lock.
+func LockSubproject(ctx context.Context, subprojectDir string, wait bool) (func(), error) {
+	path := filepath.Join(subprojectDir, lockFileName)
+	token := fmt.Sprintf("%d-%x", os.Getpid(), rand.Uint64())
+	for {
+		acquired, err := acquireLock(path, token)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return func() { releaseLock(path, token) }, nil
+		}
+		if !wait {
+			if pid, _, ok := lockOwner(path); ok {
+				return nil, fmt.Errorf("%w (pid %d)", ErrSubprojectLocked, pid)
+			}
+			return nil, ErrSubprojectLocked
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// acquireLock takes the lock file path for token. It reports false while a running process holds
+// it or another process is taking over a stale one.
+// A lock that stays unreadable for lockWriteTimeout, such as an empty one left by a process that
+// died right after creating it, is stale as well.
+// A stale lock is never removed: contenders claim it by creating a claim file named after its token,
+// which only one of them can, and the winner replaces it. A claim left by a contender that crashed
+// is stale in turn and taken over the same way.
+func acquireLock(path, token string) (bool, error) {
+	for {
+		created, err := createLockFile(path, token)
+		if created || err != nil {
+			return created, err
+		}
+		pid, owner, ok := lockOwner(path)
+		switch {
+		case !ok:
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				continue // released since the create failed
+			}
+			if owner, ok = unwrittenOwner(path); !ok {
+				return false, nil // the owner has not written it yet
+			}
+		case platform.ProcessRunning(pid):
+			return false, nil
+		}
+
+		claim := filepath.Join(filepath.Dir(path), lockFileName+"."+owner+".claim")
+		claimed, err := acquireLock(claim, token)
+		if !claimed || err != nil {
+			return false, err
+		}
+		replaced, err := replaceStaleLock(path, owner, token)
+		_ = os.Remove(claim)
+		if replaced || err != nil {
+			return replaced, err
+		}
+		// Released or taken over while we claimed it; try again
+	}
+}
+
+// createLockFile creates the lock file path holding token. It reports false if it exists.
+func createLockFile(path, token string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create lock file: %w", err)
+	}
+	_, err = fmt.Fprintln(f, token)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return false, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return true, nil
+}
+
+// replaceStaleLock atomically replaces the lock file path by one holding token, if it still holds
+// stale, and re-reads it to verify the lock is ours. Callers must hold the claim of stale.
+func replaceStaleLock(path, stale, token string) (bool, error) {
+	_, owner, ok := lockOwner(path)
+	if !ok {
+		owner, ok = unwrittenOwner(path)
+	}
+	if !ok || owner != stale {
+		return false, nil
+	}
+	tmp := filepath.Join(filepath.Dir(path), lockFileName+"."+token+".tmp")
+	if err := os.WriteFile(tmp, []byte(token+"\n"), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return false, fmt.Errorf("failed to take over stale lock file: %w", err)
+	}
+	_, owner, ok = lockOwner(path)
+	return ok && owner == token, nil
+}
+
+// releaseLock removes the lock file path if token still holds it
+func releaseLock(path, token string) {
+	if _, owner, ok := lockOwner(path); ok && owner == token {
+		_ = os.Remove(path)
+	}
+}
+
+// unwrittenOwner names the owner of a lock file that lockOwner cannot read, once it is older than
+// lockWriteTimeout. The name is taken from its modification time, so contenders claim the same file.
+func unwrittenOwner(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) < lockWriteTimeout {
+		return "", false
+	}
+	return fmt.Sprintf("unwritten-%d", info.ModTime().UnixNano()), true
+}
+
+// lockOwner reads the owner's PID and token from a lock file; locks of older versions hold only the
+// PID, which is then the token. It reports false while the owner has not written it yet; both end
+// in a newline, so a partly written lock is not mistaken for one of another PID.
+func lockOwner(path string) (int, string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasSuffix(string(data), "\n") {
+		return 0, "", false
+	}
+	token := strings.TrimSpace(string(data))
+	pidText, _, _ := strings.Cut(token, "-")
+	pid, err := strconv.Atoi(pidText)
+	if err != nil || pid <= 0 {
+		return 0, "", false
+	}
+	return pid, token, true
+}
@@ -0,0 +1,186 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockSubproject(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	unlock, err := LockSubproject(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("LockSubproject() error = %v", err)
+	}
+
+	if _, err := LockSubproject(ctx, dir, false); !errors.Is(err, ErrSubprojectLocked) {
+		t.Errorf("second LockSubproject() error = %v, want ErrSubprojectLocked", err)
+	}
+
+	// A waiting caller gets the lock once it is released
+	acquired := make(chan error, 1)
+	go func() {
+		unlock, err := LockSubproject(ctx, dir, true)
+		if err == nil {
+			unlock()
+		}
+		acquired <- err
+	}()
+	time.Sleep(2 * lockPollInterval)
+	unlock()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("waiting LockSubproject() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting LockSubproject() did not get the released lock")
+	}
+
+	// A canceled wait gives up
+	unlock, err = LockSubproject(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("LockSubproject() error = %v", err)
+	}
+	defer unlock()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := LockSubproject(canceled, dir, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("LockSubproject() with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLockSubproject_StaleLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	// PIDs are far below this on every platform, so no process owns the lock
+	if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte("2147483646\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := LockSubproject(context.Background(), dir, false)
+	if err != nil {
+		t.Fatalf("LockSubproject() over a stale lock error = %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(filepath.Join(dir, lockFileName)); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed on unlock, stat error = %v", err)
+	}
+}
+
+func TestLockSubproject_UnwrittenLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, lockFileName)
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Its owner may still be writing it
+	if _, err := LockSubproject(context.Background(), dir, false); !errors.Is(err, ErrSubprojectLocked) {
+		t.Fatalf("LockSubproject() over a fresh empty lock error = %v, want ErrSubprojectLocked", err)
+	}
+
+	old := time.Now().Add(-2 * lockWriteTimeout)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := LockSubproject(context.Background(), dir, false)
+	if err != nil {
+		t.Fatalf("LockSubproject() over an old empty lock error = %v", err)
+	}
+	if _, _, ok := lockOwner(path); !ok {
+		t.Error("lock file should hold the new owner")
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed on unlock, stat error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("claim files should be removed, got %d files", len(entries))
+	}
+}
+
+func TestLockSubproject_StaleLockContenders(t *testing.T) {
+	t.Parallel()
+
+	// Every contender sees the stale lock; exactly one may take it over
+	for range 200 {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte("2147483646\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var mu sync.Mutex
+		var unlocks []func()
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				unlock, err := LockSubproject(context.Background(), dir, false)
+				if errors.Is(err, ErrSubprojectLocked) {
+					return
+				}
+				if err != nil {
+					t.Errorf("LockSubproject() error = %v", err)
+					return
+				}
+				mu.Lock()
+				unlocks = append(unlocks, unlock)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+		if len(unlocks) != 1 {
+			t.Fatalf("%d contenders took over the stale lock, want 1", len(unlocks))
+		}
+		unlocks[0]()
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatalf("files left after unlock: %v", entries)
+		}
+	}
+}
+
+func TestLockSubproject_StaleClaim(t *testing.T) {
+	t.Parallel()
+
+	// A contender crashed while taking over the stale lock
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte("2147483646\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	claim := filepath.Join(dir, lockFileName+".2147483646.claim")
+	if err := os.WriteFile(claim, []byte("2147483645-1f\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := LockSubproject(context.Background(), dir, false)
+	if err != nil {
+		t.Fatalf("LockSubproject() over a stale claim error = %v", err)
+	}
+	if _, err := os.Stat(claim); !os.IsNotExist(err) {
+		t.Errorf("claim should be removed after the takeover, stat error = %v", err)
+	}
+	if _, err := LockSubproject(context.Background(), dir, false); !errors.Is(err, ErrSubprojectLocked) {
+		t.Errorf("second LockSubproject() error = %v, want ErrSubprojectLocked", err)
+	}
+	unlock()
+}
@@ -1,11 +1,19 @@
 package project
 
-import "path/filepath"
+import (
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+)
 
 const (
 	subprojectsDir = "subprojects"
 	charactersDir  = "characters"
 	inputsDirName  = "inputs"
+	promptsDirName = "prompts"
+
+	projectContextFile = "context.md"
 )
 
 // GetSubprojectsDir returns the path to the subprojects directory
@@ -28,7 +36,48 @@ func GetCharacterPath(projectRoot, characterFile string) string {
 	return filepath.Join(projectRoot, charactersDir, characterFile)
 }
 
+// GetProjectContextPath returns the path to the project-level context file.
+// Its content is shared by every subproject and comes before the subproject's own context.
+func GetProjectContextPath(projectRoot string) string {
+	return filepath.Join(projectRoot, projectContextFile)
+}
+
 // GetInputsDir returns the path to the inputs directory of a subproject
 func GetInputsDir(subprojectDir string) string {
 	return filepath.Join(subprojectDir, inputsDirName)
 }
+
+// GetPromptsDir returns the path to the prompt library of a subproject
+func GetPromptsDir(subprojectDir string) string {
+	return filepath.Join(subprojectDir, promptsDirName)
+}
+
+// ResolveHistoryDir returns the history directory of a subproject.
+// history_dir in the subproject config takes precedence (relative paths are resolved
+// against the subproject directory); otherwise the default history/ directory is used.
+// All code reading or writing history should resolve the directory through this function.
+func ResolveHistoryDir(subprojectDir string, cfg *config.SubprojectConfig) string {
+	if cfg == nil || cfg.HistoryDir == "" {
+		return history.GetHistoryDir(subprojectDir)
+	}
+	if filepath.IsAbs(cfg.HistoryDir) {
+		return filepath.Clean(cfg.HistoryDir)
+	}
+	return filepath.Join(subprojectDir, cfg.HistoryDir)
+}
+
+// ResolveMirrorDir returns the folder outputs are mirrored into, resolved like ResolveHistoryDir.
+// It returns "" when mirroring is disabled.
+func ResolveMirrorDir(subprojectDir string, cfg *config.SubprojectConfig) string {
+	if cfg == nil || !cfg.Mirror.Enabled() {
+		return ""
+	}
+	dir := cfg.Mirror.Dir
+	if dir == "" {
+		dir = config.DefaultMirrorDir
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(subprojectDir, dir)
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
 )
 
 func setupTestProject(t *testing.T) string {
@@ -165,6 +166,42 @@ func TestFindCurrentSubproject(t *testing.T) {
 	})
 }
 
+func TestCheckWorkDir(t *testing.T) {
+	t.Parallel()
+
+	subprojectDir := filepath.Join(t.TempDir(), "subprojects", "my-subproject")
+	externalHistory := filepath.Join(t.TempDir(), "nas-history")
+
+	tests := []struct {
+		name    string
+		cfg     *config.SubprojectConfig
+		workDir string
+		wantErr bool
+	}{
+		{name: "subproject dir", cfg: &config.SubprojectConfig{}, workDir: subprojectDir},
+		{name: "nested non-archive dir", cfg: &config.SubprojectConfig{}, workDir: filepath.Join(subprojectDir, "drafts")},
+		{name: "dir named like history", cfg: &config.SubprojectConfig{}, workDir: filepath.Join(subprojectDir, "history-notes")},
+		{name: "history dir", cfg: &config.SubprojectConfig{}, workDir: filepath.Join(subprojectDir, "history"), wantErr: true},
+		{name: "history entry", cfg: &config.SubprojectConfig{}, workDir: filepath.Join(subprojectDir, "history", "0190a000-0000-7000-8000-000000000000"), wantErr: true},
+		{name: "inputs dir", cfg: &config.SubprojectConfig{}, workDir: filepath.Join(subprojectDir, "inputs"), wantErr: true},
+		{name: "custom history_dir", cfg: &config.SubprojectConfig{HistoryDir: externalHistory}, workDir: filepath.Join(externalHistory, "entry"), wantErr: true},
+		{name: "default history dir with custom history_dir", cfg: &config.SubprojectConfig{HistoryDir: externalHistory}, workDir: filepath.Join(subprojectDir, "history")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := CheckWorkDir(subprojectDir, tt.cfg, tt.workDir)
+			if tt.wantErr && !errors.Is(err, ErrInsideArchiveDir) {
+				t.Errorf("CheckWorkDir(%q) error = %v, want ErrInsideArchiveDir", tt.workDir, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckWorkDir(%q) error = %v, want nil", tt.workDir, err)
+			}
+		})
+	}
+}
+
 func TestListSubprojects(t *testing.T) {
 	t.Parallel()
 
@@ -353,6 +390,17 @@ func TestCreateSubproject(t *testing.T) {
 			t.Error("CreateSubproject() should fail for existing subproject")
 		}
 	})
+
+	t.Run("fails for names Windows cannot store", func(t *testing.T) {
+		t.Parallel()
+		projectRoot := setupTestProject(t)
+
+		for _, name := range []string{"con", "a:b", "../escape", "trailing."} {
+			if err := CreateSubproject(projectRoot, name, ""); err == nil {
+				t.Errorf("CreateSubproject(%q) should fail", name)
+			}
+		}
+	})
 }
 
 func TestListSubprojectInfos(t *testing.T) {
@@ -467,3 +515,170 @@ func TestGetInputsDir(t *testing.T) {
 		t.Errorf("GetInputsDir() = %q, want %q", got, expected)
 	}
 }
+
+func TestResolveHistoryDir(t *testing.T) {
+	t.Parallel()
+
+	subprojectDir := "/path/to/project/subprojects/my-sub"
+
+	tests := []struct {
+		name       string
+		historyDir string
+		expected   string
+	}{
+		{"default", "", "/path/to/project/subprojects/my-sub/history"},
+		{"relative", "../../archive/my-sub", "/path/to/project/archive/my-sub"},
+		{"absolute", "/mnt/nas/banago/my-sub/", "/mnt/nas/banago/my-sub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &config.SubprojectConfig{HistoryDir: tt.historyDir}
+			got := ResolveHistoryDir(subprojectDir, cfg)
+			if got != tt.expected {
+				t.Errorf("ResolveHistoryDir() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("nil config", func(t *testing.T) {
+		t.Parallel()
+		got := ResolveHistoryDir(subprojectDir, nil)
+		if got != "/path/to/project/subprojects/my-sub/history" {
+			t.Errorf("ResolveHistoryDir() = %q", got)
+		}
+	})
+}
+
+func TestPackAndUnpackTemplate(t *testing.T) {
+	t.Parallel()
+
+	srcRoot := setupTestProject(t)
+	if err := CreateSubproject(srcRoot, "watercolor", "Soft watercolor style"); err != nil {
+		t.Fatalf("CreateSubproject() error = %v", err)
+	}
+	srcDir := GetSubprojectDir(srcRoot, "watercolor")
+
+	cfg, err := config.LoadSubprojectConfig(srcDir)
+	if err != nil {
+		t.Fatalf("LoadSubprojectConfig() error = %v", err)
+	}
+	cfg.AspectRatio = "3:4"
+	cfg.InputImages = []string{"private-reference.png"}
+	cfg.CharacterFile = "alice.md"
+	if err := cfg.Save(srcDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "context.md"), []byte("Use pastel colors."), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(GetInputsDir(srcDir), "private-reference.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	historyDir := ResolveHistoryDir(srcDir, cfg)
+	entry := history.NewEntry()
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := entry.SavePrompt(historyDir, "a lighthouse at dusk"); err != nil {
+		t.Fatalf("SavePrompt() error = %v", err)
+	}
+
+	tmpl, err := PackSubproject(srcRoot, "watercolor", []string{entry.ID})
+	if err != nil {
+		t.Fatalf("PackSubproject() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "watercolor.yaml")
+	if err := tmpl.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+
+	dstRoot := setupTestProject(t)
+	if err := UnpackTemplate(dstRoot, "my-watercolor", loaded); err != nil {
+		t.Fatalf("UnpackTemplate() error = %v", err)
+	}
+	dstDir := GetSubprojectDir(dstRoot, "my-watercolor")
+
+	dstCfg, err := config.LoadSubprojectConfig(dstDir)
+	if err != nil {
+		t.Fatalf("LoadSubprojectConfig() error = %v", err)
+	}
+	if dstCfg.Name != "my-watercolor" || dstCfg.Description != "Soft watercolor style" || dstCfg.AspectRatio != "3:4" {
+		t.Errorf("unpacked config = %+v", dstCfg)
+	}
+	if dstCfg.CharacterFile != "" {
+		t.Errorf("CharacterFile = %q, want empty", dstCfg.CharacterFile)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dstDir, "context.md")); string(data) != "Use pastel colors." {
+		t.Errorf("context.md = %q, want %q", data, "Use pastel colors.")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dstDir, TemplatePromptsDir, "prompt-1.txt")); string(data) != "a lighthouse at dusk" {
+		t.Errorf("prompt-1.txt = %q, want %q", data, "a lighthouse at dusk")
+	}
+
+	// Reference images are never shipped
+	if _, err := os.Stat(filepath.Join(GetInputsDir(dstDir), "private-reference.png")); !os.IsNotExist(err) {
+		t.Error("reference image should not be unpacked")
+	}
+}
+
+func TestLoadTemplate_InvalidFormat(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "template.yaml")
+	if err := os.WriteFile(path, []byte("format: something-else\nname: x\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := LoadTemplate(path); err == nil {
+		t.Error("LoadTemplate() should fail for an unknown format")
+	}
+}
+
+func TestCheckInputs(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	subprojectDir := setupTestSubproject(t, projectRoot, "test-sub")
+	inputsDir := GetInputsDir(subprojectDir)
+	if err := os.MkdirAll(filepath.Join(inputsDir, "poses"), 0o755); err != nil {
+		t.Fatalf("failed to create inputs dir: %v", err)
+	}
+	for _, name := range []string{"hero.png", "old.jpg", "poses/run.webp", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(inputsDir, name), []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.SubprojectConfig{InputImages: []string{"hero.png", "poses/run.webp", "gone.png"}}
+	check, err := CheckInputs(subprojectDir, cfg)
+	if err != nil {
+		t.Fatalf("CheckInputs() error = %v", err)
+	}
+	if check.OK() {
+		t.Error("CheckInputs().OK() = true, want false")
+	}
+	if len(check.Missing) != 1 || check.Missing[0] != "gone.png" {
+		t.Errorf("Missing = %v, want [gone.png]", check.Missing)
+	}
+	// Non-image files are ignored
+	if len(check.Unreferenced) != 1 || check.Unreferenced[0] != "old.jpg" {
+		t.Errorf("Unreferenced = %v, want [old.jpg]", check.Unreferenced)
+	}
+
+	cfg.InputImages = []string{"hero.png", "old.jpg", "poses/run.webp"}
+	check, err = CheckInputs(subprojectDir, cfg)
+	if err != nil {
+		t.Fatalf("CheckInputs() error = %v", err)
+	}
+	if !check.OK() {
+		t.Errorf("CheckInputs() = %+v, want no mismatches", check)
+	}
+}
@@ -0,0 +1,96 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blck-snwmn/banago/internal/platform"
+)
+
+var (
+	// ErrPromptNotFound is returned when the prompt library of a subproject has no prompt with the name
+	ErrPromptNotFound = errors.New("prompt not found")
+	// ErrPromptExists is returned by SavePrompt when a prompt with the name exists and overwrite is not set
+	ErrPromptExists = errors.New("prompt already exists")
+)
+
+// promptExt is the extension of prompt files in prompts/; the name of a prompt is its file name without it
+const promptExt = ".txt"
+
+// GetPromptPath returns the file of a named prompt in the prompt library of a subproject
+func GetPromptPath(subprojectDir, name string) (string, error) {
+	if err := platform.ValidateName(name); err != nil {
+		return "", fmt.Errorf("invalid prompt name: %w", err)
+	}
+	return filepath.Join(GetPromptsDir(subprojectDir), name+promptExt), nil
+}
+
+// ListPrompts returns the names of the prompts in the prompt library of a subproject, sorted.
+// A subproject without prompts/ has none.
+func ListPrompts(subprojectDir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(GetPromptsDir(subprojectDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+	var names []string
+	for _, d := range dirEntries {
+		name, ok := strings.CutSuffix(d.Name(), promptExt)
+		if d.IsDir() || !ok || name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ReadPrompt returns a named prompt of the prompt library, without surrounding whitespace
+func ReadPrompt(subprojectDir, name string) (string, error) {
+	path, err := GetPromptPath(subprojectDir, name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt %s: %w", name, err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("prompt %s is empty", name)
+	}
+	return text, nil
+}
+
+// SavePrompt writes text to the prompt library under name, creating prompts/ if needed.
+// An existing prompt is only replaced when overwrite is set.
+func SavePrompt(subprojectDir, name, text string, overwrite bool) error {
+	path, err := GetPromptPath(subprojectDir, name)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("prompt is empty")
+	}
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return fmt.Errorf("%w: %s", ErrPromptExists, name)
+	}
+	if err := os.MkdirAll(GetPromptsDir(subprojectDir), 0o755); err != nil {
+		return fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(text+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write prompt %s: %w", name, err)
+	}
+	return nil
+}
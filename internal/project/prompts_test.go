@@ -0,0 +1,58 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPromptLibrary(t *testing.T) {
+	t.Parallel()
+
+	subprojectDir := t.TempDir()
+	names, err := ListPrompts(subprojectDir)
+	if err != nil || len(names) != 0 {
+		t.Errorf("ListPrompts() without prompts/ = %v, %v, want none", names, err)
+	}
+
+	if err := SavePrompt(subprojectDir, "sunset", "  the castle at sunset\n", false); err != nil {
+		t.Fatalf("SavePrompt() error = %v", err)
+	}
+	if err := SavePrompt(subprojectDir, "dawn", "the castle at dawn", false); err != nil {
+		t.Fatalf("SavePrompt() error = %v", err)
+	}
+	if err := SavePrompt(subprojectDir, "sunset", "replaced", false); !errors.Is(err, ErrPromptExists) {
+		t.Errorf("SavePrompt() over an existing prompt error = %v, want ErrPromptExists", err)
+	}
+	if err := SavePrompt(subprojectDir, "../escape", "text", false); err == nil {
+		t.Error("SavePrompt() expected error for a name with a path separator")
+	}
+	// Files that are not prompts are not listed
+	if err := os.WriteFile(filepath.Join(GetPromptsDir(subprojectDir), "notes.md"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err = ListPrompts(subprojectDir)
+	if err != nil {
+		t.Fatalf("ListPrompts() error = %v", err)
+	}
+	if want := []string{"dawn", "sunset"}; !slices.Equal(names, want) {
+		t.Errorf("ListPrompts() = %v, want %v", names, want)
+	}
+
+	text, err := ReadPrompt(subprojectDir, "sunset")
+	if err != nil || text != "the castle at sunset" {
+		t.Errorf("ReadPrompt() = %q, %v, want %q", text, err, "the castle at sunset")
+	}
+	if err := SavePrompt(subprojectDir, "sunset", "replaced", true); err != nil {
+		t.Fatalf("SavePrompt() with overwrite error = %v", err)
+	}
+	if text, _ := ReadPrompt(subprojectDir, "sunset"); text != "replaced" {
+		t.Errorf("ReadPrompt() after overwrite = %q, want %q", text, "replaced")
+	}
+	if _, err := ReadPrompt(subprojectDir, "missing"); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("ReadPrompt() error = %v, want ErrPromptNotFound", err)
+	}
+}
@@ -7,11 +7,15 @@ import (
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/platform"
 	"github.com/blck-snwmn/banago/internal/templates"
 )
 
 // CreateSubproject creates a new subproject in the specified project
 func CreateSubproject(projectRoot, name, description string) error {
+	if err := platform.ValidateName(name); err != nil {
+		return fmt.Errorf("invalid subproject name: %w", err)
+	}
 	subprojectDir := GetSubprojectDir(projectRoot, name)
 
 	// Check if subproject already exists
@@ -0,0 +1,160 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/platform"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateFormat identifies banago subproject template files and their format version
+const TemplateFormat = "banago-subproject-template/v1"
+
+// TemplatePromptsDir is where unpacked prompt templates are written inside a subproject
+const TemplatePromptsDir = "prompts"
+
+// SubprojectTemplate is a portable subproject setup that can be shared between projects.
+// It carries text and parameters only: reference images, the character file and history are never included.
+type SubprojectTemplate struct {
+	Format      string               `yaml:"format"`
+	Name        string               `yaml:"name"`
+	Description string               `yaml:"description,omitempty"`
+	Context     string               `yaml:"context,omitempty"`
+	AspectRatio string               `yaml:"aspect_ratio,omitempty"`
+	ImageSize   string               `yaml:"image_size,omitempty"`
+	InputImages []string             `yaml:"input_images,omitempty"` // expected reference image names, to be supplied by the user
+	Archive     config.ArchiveConfig `yaml:"archive,omitempty"`
+	Prompts     []TemplatePrompt     `yaml:"prompts,omitempty"`
+}
+
+// TemplatePrompt is a named prompt shipped with a template
+type TemplatePrompt struct {
+	Name string `yaml:"name"`
+	Text string `yaml:"text"`
+}
+
+// PackSubproject builds a template from a subproject.
+// The prompts of the given history entries are included as prompt templates.
+func PackSubproject(projectRoot, name string, promptEntryIDs []string) (*SubprojectTemplate, error) {
+	subprojectDir := GetSubprojectDir(projectRoot, name)
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &SubprojectTemplate{
+		Format:      TemplateFormat,
+		Name:        cfg.Name,
+		Description: cfg.Description,
+		AspectRatio: cfg.AspectRatio,
+		ImageSize:   cfg.ImageSize,
+		InputImages: cfg.InputImages,
+		Archive:     cfg.Archive,
+	}
+
+	if cfg.ContextFile != "" {
+		data, err := os.ReadFile(filepath.Join(subprojectDir, cfg.ContextFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read context file: %w", err)
+		}
+		tmpl.Context = string(data)
+	}
+
+	historyDir := ResolveHistoryDir(subprojectDir, cfg)
+	for i, id := range promptEntryIDs {
+		entry, err := history.GetEntryByID(historyDir, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history entry %s: %w", id, err)
+		}
+		prompt, err := history.LoadPrompt(entry.GetEntryDir(historyDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt of %s: %w", id, err)
+		}
+		tmpl.Prompts = append(tmpl.Prompts, TemplatePrompt{Name: fmt.Sprintf("prompt-%d", i+1), Text: prompt})
+	}
+
+	return tmpl, nil
+}
+
+// Save writes the template as YAML to path
+func (t *SubprojectTemplate) Save(path string) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
+
+// LoadTemplate reads a template file and checks its format
+func LoadTemplate(path string) (*SubprojectTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	var tmpl SubprojectTemplate
+	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if tmpl.Format != TemplateFormat {
+		return nil, fmt.Errorf("unsupported template format %q: expected %s", tmpl.Format, TemplateFormat)
+	}
+	if err := tmpl.Archive.Validate(); err != nil {
+		return nil, err
+	}
+	for _, p := range tmpl.Prompts {
+		if err := platform.ValidateName(p.Name); err != nil {
+			return nil, fmt.Errorf("invalid prompt name in template: %w", err)
+		}
+	}
+
+	return &tmpl, nil
+}
+
+// UnpackTemplate creates a new subproject named name from a template.
+// Prompt templates are written to prompts/<name>.txt inside the subproject.
+func UnpackTemplate(projectRoot, name string, tmpl *SubprojectTemplate) error {
+	if err := CreateSubproject(projectRoot, name, tmpl.Description); err != nil {
+		return err
+	}
+
+	subprojectDir := GetSubprojectDir(projectRoot, name)
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return err
+	}
+	cfg.AspectRatio = tmpl.AspectRatio
+	cfg.ImageSize = tmpl.ImageSize
+	cfg.InputImages = tmpl.InputImages
+	cfg.Archive = tmpl.Archive
+	if err := cfg.Save(subprojectDir); err != nil {
+		return fmt.Errorf("failed to save subproject config: %w", err)
+	}
+
+	if tmpl.Context != "" {
+		if err := os.WriteFile(filepath.Join(subprojectDir, cfg.ContextFile), []byte(tmpl.Context), 0o644); err != nil {
+			return fmt.Errorf("failed to write context file: %w", err)
+		}
+	}
+
+	if len(tmpl.Prompts) > 0 {
+		promptsDir := filepath.Join(subprojectDir, TemplatePromptsDir)
+		if err := os.MkdirAll(promptsDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", promptsDir, err)
+		}
+		for _, p := range tmpl.Prompts {
+			if err := os.WriteFile(filepath.Join(promptsDir, p.Name+".txt"), []byte(p.Text), 0o644); err != nil {
+				return fmt.Errorf("failed to write prompt %s: %w", p.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
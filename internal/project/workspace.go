@@ -0,0 +1,150 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/history"
+	"github.com/blck-snwmn/banago/internal/platform"
+)
+
+// ErrWorkspaceFull is returned by OpenWorkspace when runs in progress already fill the size cap
+var ErrWorkspaceFull = errors.New("temp workspace is full")
+
+const (
+	workspaceRunPrefix = "run-"
+	// Run directories older than this are stale even if a process with their PID exists, since PIDs are reused
+	workspaceMaxAge = 24 * time.Hour
+)
+
+// ResolveWorkspaceDir returns the scratch space of a project: tmp_dir in banago.yaml (relative paths
+// are resolved against the project root) or config.DefaultTmpDir.
+func ResolveWorkspaceDir(projectRoot string, cfg *config.ProjectConfig) string {
+	dir := config.DefaultTmpDir
+	if cfg != nil && cfg.TmpDir != "" {
+		dir = cfg.TmpDir
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(projectRoot, dir)
+}
+
+// Workspace is a scratch directory owned by one run, for intermediate files that must not outlive it.
+// Each run gets its own directory, named after its PID, so concurrent runs never share files and the
+// directories of crashed runs can be recognized and removed.
+type Workspace struct {
+	Dir string
+}
+
+// OpenWorkspace creates a new run directory in the scratch space root. It first removes the directories
+// of runs that ended without cleaning up, then fails with ErrWorkspaceFull when the remaining runs use
+// maxBytes or more (0 = no cap). Close removes the directory again.
+func OpenWorkspace(root string, maxBytes int64) (*Workspace, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create temp workspace: %w", err)
+	}
+	used, err := SweepWorkspace(root)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && used >= maxBytes {
+		return nil, fmt.Errorf("%w: running processes use %d MB of %d MB (tmp_max_mb in banago.yaml)", ErrWorkspaceFull, used>>20, maxBytes>>20)
+	}
+	dir, err := os.MkdirTemp(root, fmt.Sprintf("%s%d-", workspaceRunPrefix, os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp workspace: %w", err)
+	}
+	return &Workspace{Dir: dir}, nil
+}
+
+// Close removes the run directory and everything in it
+func (w *Workspace) Close() error {
+	return os.RemoveAll(w.Dir)
+}
+
+// WorkspaceUsage returns the bytes used in the scratch space root by runs in progress and by stale runs.
+// A missing root uses nothing. Nothing is removed.
+func WorkspaceUsage(root string) (live, stale int64, err error) {
+	runs, err := workspaceRuns(root)
+	for _, run := range runs {
+		if run.stale {
+			stale += run.size
+		} else {
+			live += run.size
+		}
+	}
+	return live, stale, err
+}
+
+// SweepWorkspace removes the run directories in root whose process no longer runs or that are older
+// than a day, and returns the bytes still used by runs in progress
+func SweepWorkspace(root string) (int64, error) {
+	runs, err := workspaceRuns(root)
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, run := range runs {
+		if !run.stale {
+			used += run.size
+			continue
+		}
+		if err := os.RemoveAll(run.path); err != nil {
+			return 0, fmt.Errorf("failed to remove stale temp directory: %w", err)
+		}
+	}
+	return used, nil
+}
+
+// workspaceRun is a run directory in the scratch space
+type workspaceRun struct {
+	path  string
+	size  int64
+	stale bool
+}
+
+// workspaceRuns lists the run directories in root. Other files are left alone.
+func workspaceRuns(root string) ([]workspaceRun, error) {
+	dirEntries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read temp workspace: %w", err)
+	}
+	var runs []workspaceRun
+	for _, d := range dirEntries {
+		pid, ok := workspaceRunPID(d.Name())
+		if !d.IsDir() || !ok {
+			continue
+		}
+		run := workspaceRun{path: filepath.Join(root, d.Name())}
+		run.size, _ = history.DirSize(run.path)
+		info, err := d.Info()
+		run.stale = err != nil || time.Since(info.ModTime()) > workspaceMaxAge ||
+			(pid != os.Getpid() && !platform.ProcessRunning(pid))
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// workspaceRunPID returns the PID in a run directory name (run-<pid>-<random>)
+func workspaceRunPID(name string) (int, bool) {
+	rest, ok := strings.CutPrefix(name, workspaceRunPrefix)
+	if !ok {
+		return 0, false
+	}
+	pidStr, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(pidStr)
+	return pid, err == nil && pid > 0
+}
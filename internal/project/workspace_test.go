@@ -0,0 +1,96 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenWorkspace(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(t.TempDir(), ".banago", "tmp")
+	a, err := OpenWorkspace(root, 0)
+	if err != nil {
+		t.Fatalf("OpenWorkspace() error = %v", err)
+	}
+	b, err := OpenWorkspace(root, 0)
+	if err != nil {
+		t.Fatalf("second OpenWorkspace() error = %v", err)
+	}
+	if a.Dir == b.Dir || filepath.Dir(a.Dir) != root {
+		t.Errorf("OpenWorkspace() dirs = %s, %s, want distinct directories in %s", a.Dir, b.Dir, root)
+	}
+
+	// Opening again keeps the directories of running processes, this one included
+	if err := os.WriteFile(filepath.Join(a.Dir, "scratch.png"), make([]byte, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenWorkspace(root, 0); err != nil {
+		t.Fatalf("OpenWorkspace() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a.Dir, "scratch.png")); err != nil {
+		t.Errorf("file of a running workspace was removed: %v", err)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(a.Dir); !os.IsNotExist(err) {
+		t.Errorf("Close() left %s, stat error = %v", a.Dir, err)
+	}
+}
+
+func TestOpenWorkspace_Sweep(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	// PIDs are far below this on every platform, so the run has ended
+	stale := filepath.Join(root, "run-2147483646-123")
+	if err := os.MkdirAll(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, "big.bin"), make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(root, "notes.txt")
+	if err := os.WriteFile(other, []byte("not a run"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	live, staleBytes, err := WorkspaceUsage(root)
+	if err != nil || live != 0 || staleBytes != 4096 {
+		t.Errorf("WorkspaceUsage() = %d, %d, %v, want 0, 4096", live, staleBytes, err)
+	}
+
+	w, err := OpenWorkspace(root, 1)
+	if err != nil {
+		t.Fatalf("OpenWorkspace() with only stale runs over the cap error = %v", err)
+	}
+	defer func() { _ = w.Close() }()
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale run directory should be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("files that are not run directories should be kept: %v", err)
+	}
+}
+
+func TestOpenWorkspace_Full(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	w, err := OpenWorkspace(root, 0)
+	if err != nil {
+		t.Fatalf("OpenWorkspace() error = %v", err)
+	}
+	defer func() { _ = w.Close() }()
+	if err := os.WriteFile(filepath.Join(w.Dir, "big.bin"), make([]byte, 2<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenWorkspace(root, 1<<20); !errors.Is(err, ErrWorkspaceFull) {
+		t.Errorf("OpenWorkspace() over the cap error = %v, want ErrWorkspaceFull", err)
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireToken makes every request authenticate with token, either as a bearer token
+// (Authorization: Bearer <token>) or as the password of HTTP basic auth with any user name,
+// which lets browsers log in through their built-in prompt.
+func (s *Server) RequireToken(token string) {
+	s.authToken = token
+}
+
+// requireToken rejects requests that do not carry the server's token
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="banago"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	var got string
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		got = bearer
+	} else if _, password, ok := r.BasicAuth(); ok {
+		got = password
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.authToken)) == 1
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/project"
+	"github.com/google/uuid"
+)
+
+// GenerateRequest is a generation submitted from the subproject page
+type GenerateRequest struct {
+	Prompt string
+	Aspect string // empty to use the subproject default
+	Size   string // empty to use the subproject default
+}
+
+// GenerateFunc runs a generation in subprojectDir, writing progress text to w.
+// It is supplied by the serve command so the server reuses the CLI's generate logic.
+type GenerateFunc func(ctx context.Context, subprojectDir string, req GenerateRequest, w io.Writer) error
+
+// jobPollInterval is how often the event stream checks a job for new progress
+const jobPollInterval = 500 * time.Millisecond
+
+// maxRunningJobs is how many generations may run at once; more submissions are refused
+const maxRunningJobs = 4
+
+// jobRetention is how long a finished job's page and progress stay available
+const jobRetention = time.Hour
+
+// job is a generation running in the background. Progress is written to it as an io.Writer.
+type job struct {
+	id         string
+	subproject string
+	prompt     string
+
+	mu         sync.Mutex
+	log        bytes.Buffer
+	done       bool
+	err        error
+	finishedAt time.Time
+}
+
+func (j *job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.Write(p)
+}
+
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done = true
+	j.err = err
+	j.finishedAt = time.Now()
+}
+
+// expired reports whether the job finished longer than jobRetention before now
+func (j *job) expired(now time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done && now.Sub(j.finishedAt) > jobRetention
+}
+
+// snapshot returns the progress written so far and whether the job has finished
+func (j *job) snapshot() (string, bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.String(), j.done, j.err
+}
+
+// EnableGeneration lets the subproject page submit generations, run by fn in the background.
+// Without it the server is browse-only.
+func (s *Server) EnableGeneration(fn GenerateFunc) {
+	s.generate = fn
+}
+
+// errTooManyJobs is returned by startJob when maxRunningJobs generations are already running
+var errTooManyJobs = errors.New("too many generations running. Wait for one to finish")
+
+// startJob runs fn for a subproject in the background and registers the job. Finished jobs older
+// than jobRetention are forgotten.
+func (s *Server) startJob(subprojectName, subprojectDir string, req GenerateRequest) (*job, error) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	now := time.Now()
+	running := 0
+	for id, j := range s.jobs {
+		if j.expired(now) {
+			delete(s.jobs, id)
+			continue
+		}
+		if _, done, _ := j.snapshot(); !done {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		return nil, errTooManyJobs
+	}
+
+	j := &job{
+		id:         uuid.Must(uuid.NewV7()).String(),
+		subproject: subprojectName,
+		prompt:     req.Prompt,
+	}
+	s.jobs[j.id] = j
+	s.jobsWG.Add(1)
+	go func() {
+		defer s.jobsWG.Done()
+		// Generation outlives the form request that started it, but not the server
+		j.finish(s.generate(s.jobsCtx, subprojectDir, req, j))
+	}()
+	return j, nil
+}
+
+func (s *Server) getJob(id string) *job {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	return s.jobs[id]
+}
+
+// handleGenerate starts a generation from the subproject page form and redirects to its progress page
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.generate == nil {
+		http.Error(w, "generation is disabled. Restart 'banago serve' with --allow-generate", http.StatusServiceUnavailable)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin generation requests are not allowed", http.StatusForbidden)
+		return
+	}
+	// banago.yaml may have turned readonly on since the server started
+	if projectCfg, err := config.LoadProjectConfig(s.projectRoot); err != nil || projectCfg.ReadOnly {
+		http.Error(w, "project is read-only (readonly: true in banago.yaml)", http.StatusForbidden)
+		return
+	}
+
+	// Extract subproject name from /generate/{name}
+	name := r.URL.Path[len("/generate/"):]
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	subprojectDir := project.GetSubprojectDir(s.projectRoot, name)
+	if _, err := config.LoadSubprojectConfig(subprojectDir); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	req := GenerateRequest{
+		Prompt: strings.TrimSpace(r.FormValue("prompt")),
+		Aspect: strings.TrimSpace(r.FormValue("aspect")),
+		Size:   strings.TrimSpace(r.FormValue("size")),
+	}
+	if req.Prompt == "" {
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	j, err := s.startJob(name, subprojectDir, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	http.Redirect(w, r, "/jobs/"+j.id, http.StatusSeeOther)
+}
+
+// sameOrigin reports whether a form submission comes from a page of this server. Browsers send
+// Sec-Fetch-Site or Origin with cross-site POSTs; requests without either are not from a web page
+// (curl, scripts) and pass.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// handleJob shows a generation job page (/jobs/{id}) or streams its progress (/jobs/{id}/events)
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, events := strings.CutSuffix(r.URL.Path[len("/jobs/"):], "/events")
+	j := s.getJob(id)
+	if j == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if events {
+		s.streamJob(w, r, j)
+		return
+	}
+
+	data := struct {
+		ID         string
+		Subproject string
+		Prompt     string
+	}{
+		ID:         j.id,
+		Subproject: j.subproject,
+		Prompt:     j.prompt,
+	}
+	if err := s.templates.ExecuteTemplate(w, "job.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// streamJob sends a job's progress as server-sent events until it finishes.
+// "log" events carry new progress text; a final "done" event carries the error, if any.
+func (s *Server) streamJob(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// The stream stays open for the whole generation, longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	sent := 0
+	for {
+		out, done, jobErr := j.snapshot()
+		if len(out) > sent {
+			writeEvent(w, "log", out[sent:])
+			sent = len(out)
+		}
+		if done {
+			errText := ""
+			if jobErr != nil {
+				errText = jobErr.Error()
+			}
+			writeEvent(w, "done", map[string]string{"error": errText})
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with JSON-encoded data, which keeps multi-line text on one data line
+func writeEvent(w io.Writer, event string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+}
@@ -1,15 +1,22 @@
 package server
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
+	"github.com/blck-snwmn/banago/internal/gemini"
 	"github.com/blck-snwmn/banago/internal/history"
 	"github.com/blck-snwmn/banago/internal/project"
 )
@@ -22,34 +29,128 @@ type Server struct {
 	projectRoot string
 	port        int
 	templates   *template.Template
+
+	listener  net.Listener
+	authToken string // empty when no authentication is required
+
+	pageSize int // entries per subproject page
+
+	generate GenerateFunc // nil when generation from the browser is disabled
+	jobsMu   sync.Mutex
+	jobs     map[string]*job
+	jobsCtx  context.Context // canceled when the server shuts down, aborting running jobs
+	stopJobs context.CancelFunc
+	jobsWG   sync.WaitGroup
 }
 
+// HTTP server timeouts. Job event streams clear the write deadline since they stay open while generating.
+const (
+	readTimeout     = 15 * time.Second
+	writeTimeout    = 60 * time.Second
+	idleTimeout     = 120 * time.Second
+	shutdownTimeout = 5 * time.Second
+)
+
+// DefaultPageSize is the number of entries per subproject page unless SetPageSize changes it
+const DefaultPageSize = 48
+
+// pendingRetrySeconds is how long clients wait before asking again for an entry that is still being written
+const pendingRetrySeconds = 1
+
 // New creates a new Server instance
 func New(projectRoot string, port int) *Server {
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
 	return &Server{
 		projectRoot: projectRoot,
 		port:        port,
+		pageSize:    DefaultPageSize,
+		jobs:        map[string]*job{},
+		jobsCtx:     jobsCtx,
+		stopJobs:    stopJobs,
 	}
 }
 
-// Start starts the web server
-func (s *Server) Start() error {
+// SetPageSize sets how many entries a subproject page shows; 0 or less shows all of them on one page
+func (s *Server) SetPageSize(n int) {
+	s.pageSize = n
+}
+
+// Start listens on the configured port and serves until ctx is canceled
+func (s *Server) Start(ctx context.Context) error {
+	if _, err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve(ctx)
+}
+
+// Listen parses the templates and binds the port, returning the bound address.
+// With port 0 a free port is chosen.
+func (s *Server) Listen() (net.Addr, error) {
 	var err error
 	s.templates, err = template.ParseFS(templateFS, "templates/*.html")
 	if err != nil {
-		return fmt.Errorf("failed to parse templates: %w", err)
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
-	mux := http.NewServeMux()
+	s.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %d: %w", s.port, err)
+	}
+	return s.listener.Addr(), nil
+}
+
+// Serve serves requests on the listener bound by Listen until ctx is canceled,
+// then shuts down gracefully, letting in-flight requests finish. Running generation jobs are
+// canceled and waited for, so their unfinished entries are cleaned up before Serve returns.
+func (s *Server) Serve(ctx context.Context) error {
+	if s.listener == nil {
+		return errors.New("server is not listening. Call Listen first")
+	}
 
-	// Routes
+	srv := &http.Server{
+		Handler:      s.routes(),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(s.listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.stopJobs()
+	defer s.jobsWG.Wait()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// routes returns the handler of all pages
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/subprojects/", s.handleSubproject)
 	mux.HandleFunc("/entry/", s.handleEntry)
 	mux.HandleFunc("/images/", s.handleImage)
-
-	addr := fmt.Sprintf(":%d", s.port)
-	return http.ListenAndServe(addr, mux)
+	mux.HandleFunc("/generate/", s.handleGenerate)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	if s.authToken != "" {
+		return logRequests(s.requireToken(mux))
+	}
+	return logRequests(mux)
 }
 
 // SubprojectView contains subproject information for templates
@@ -91,14 +192,160 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Timestamp is a stored RFC3339 time for templates: Value goes in <time datetime>, Local is the
+// server-side rendering (see history.FormatLocal) that the page script replaces with the browser's
+// locale format.
+type Timestamp struct {
+	Value string
+	Local string
+}
+
+func newTimestamp(value string, now time.Time) Timestamp {
+	if value == "" {
+		return Timestamp{}
+	}
+	return Timestamp{Value: value, Local: history.FormatLocal(value, now)}
+}
+
+// DayGroup holds the entries of a subproject page created on the same local calendar day
+type DayGroup struct {
+	Day     string // YYYY-MM-DD, empty for entries without a valid date
+	Label   string // e.g. "Saturday, January 31, 2026"
+	Entries []EntryInfo
+}
+
+// groupByDay splits entries, already in display order, into runs of the same local calendar day
+func groupByDay(entries []EntryInfo) []DayGroup {
+	var groups []DayGroup
+	for _, e := range entries {
+		day := history.LocalDay(e.CreatedAt.Value)
+		if len(groups) == 0 || groups[len(groups)-1].Day != day {
+			label := "Unknown date"
+			if t, err := time.Parse(time.DateOnly, day); err == nil {
+				label = t.Format("Monday, January 2, 2006")
+			}
+			groups = append(groups, DayGroup{Day: day, Label: label})
+		}
+		last := &groups[len(groups)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	return groups
+}
+
 // EntryInfo contains entry information for templates
 type EntryInfo struct {
 	ID           string
-	CreatedAt    string
+	CreatedAt    Timestamp
 	Success      bool
 	OutputImages []string
 	ImageCount   int
+	IsVideo      bool // outputs are videos (banago video generate)
 	EditCount    int
+	EditTokens   int
+	EditCost     string // estimated cost of all edits, empty if the model has no pricing
+	LastEditAt   Timestamp
+}
+
+// Page describes one page of a paginated list for templates
+type Page struct {
+	Number int // 1-based
+	Count  int // number of pages, at least 1
+	First  int // 1-based position of the first item shown, 0 when the list is empty
+	Last   int // 1-based position of the last item shown
+	Prev   int // number of the previous page, 0 on the first
+	Next   int // number of the next page, 0 on the last
+
+	start, end int // slice bounds of the page
+}
+
+// paginate returns the page named by the page query value of total items, size per page.
+// Missing or invalid values give the first page and values past the end the last one.
+func paginate(total, size int, value string) Page {
+	if size <= 0 {
+		size = max(total, 1)
+	}
+	count := max((total+size-1)/size, 1)
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		number = 1
+	}
+	number = min(number, count)
+
+	p := Page{Number: number, Count: count}
+	if number > 1 {
+		p.Prev = number - 1
+	}
+	if number < count {
+		p.Next = number + 1
+	}
+	p.start = min((number-1)*size, total)
+	p.end = min(p.start+size, total)
+	if p.end > p.start {
+		p.First, p.Last = p.start+1, p.end
+	}
+	return p
+}
+
+// failurePanelDays is the period, in days, the failure panel of a subproject page covers
+const failurePanelDays = 30
+
+// FailurePanel summarizes the failed API calls of a subproject for its page
+type FailurePanel struct {
+	Days       int // length of the period
+	Calls      int
+	Failed     int
+	Rate       int              // percentage of the calls that failed
+	Categories []FailureCount   // categories with failures, in gemini.FailureCategories order
+	ByDay      []FailureDayView // days with failures, newest first
+}
+
+// FailureCount is the number of failures of one category
+type FailureCount struct {
+	Category string
+	Count    int
+}
+
+// FailureDayView holds the API calls of one day (UTC)
+type FailureDayView struct {
+	Day        string
+	Calls      int
+	Failed     int
+	Categories []FailureCount
+}
+
+func failureCounts(s *history.FailureStats) []FailureCount {
+	var counts []FailureCount
+	for _, category := range gemini.FailureCategories {
+		if n := s.Categories[category]; n > 0 {
+			counts = append(counts, FailureCount{Category: category, Count: n})
+		}
+	}
+	return counts
+}
+
+// newFailurePanel returns the failure panel of historyDir for the days before now, or nil when
+// nothing failed (or the history cannot be read)
+func newFailurePanel(historyDir string, now time.Time) *FailurePanel {
+	days, err := history.FailuresByDay(historyDir, now.AddDate(0, 0, -failurePanelDays), time.Time{})
+	if err != nil {
+		return nil
+	}
+	var total history.FailureStats
+	panel := &FailurePanel{Days: failurePanelDays}
+	for day, s := range days {
+		total.Add(*s)
+		if s.Failed() > 0 {
+			panel.ByDay = append(panel.ByDay, FailureDayView{Day: day, Calls: s.Runs, Failed: s.Failed(), Categories: failureCounts(s)})
+		}
+	}
+	if total.Failed() == 0 {
+		return nil
+	}
+	sort.Slice(panel.ByDay, func(i, j int) bool { return panel.ByDay[i].Day > panel.ByDay[j].Day })
+	panel.Calls, panel.Failed = total.Runs, total.Failed()
+	panel.Rate = panel.Failed * 100 / panel.Calls
+	panel.Categories = failureCounts(&total)
+	return panel
 }
 
 // handleSubproject shows the history entries of a subproject
@@ -111,12 +358,13 @@ func (s *Server) handleSubproject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	subprojectDir := project.GetSubprojectDir(s.projectRoot, name)
-	if !config.SubprojectConfigExists(subprojectDir) {
+	subprojectConfig, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	historyDir := history.GetHistoryDir(subprojectDir)
+	historyDir := project.ResolveHistoryDir(subprojectDir, subprojectConfig)
 	entries, err := history.ListEntries(historyDir)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -128,33 +376,67 @@ func (s *Server) handleSubproject(w http.ResponseWriter, r *http.Request) {
 		return entries[i].ID > entries[j].ID
 	})
 
-	var entryInfos []EntryInfo
+	model := ""
+	readOnly := false
+	if projectConfig, err := config.LoadProjectConfig(s.projectRoot); err == nil {
+		model = projectConfig.Model
+		readOnly = projectConfig.ReadOnly
+	}
+
+	// Only the entries of the requested page are read further
+	pg := paginate(len(entries), s.pageSize, r.URL.Query().Get("page"))
+	failed := 0
 	for _, e := range entries {
-		entryDir := filepath.Join(historyDir, e.ID)
-		entryInfos = append(entryInfos, EntryInfo{
+		if !e.Result.Success {
+			failed++
+		}
+	}
+
+	now := time.Now()
+	var entryInfos []EntryInfo
+	for _, e := range entries[pg.start:pg.end] {
+		stats := history.ComputeEditStats(e.GetEntryDir(historyDir))
+		info := EntryInfo{
 			ID:           e.ID,
-			CreatedAt:    e.CreatedAt,
+			CreatedAt:    newTimestamp(e.CreatedAt, now),
 			Success:      e.Result.Success,
 			OutputImages: e.Result.OutputImages,
 			ImageCount:   len(e.Result.OutputImages),
-			EditCount:    history.CountEditEntries(entryDir),
-		})
-	}
-
-	subprojectConfig, _ := config.LoadSubprojectConfig(subprojectDir)
-	description := ""
-	if subprojectConfig != nil {
-		description = subprojectConfig.Description
+			IsVideo:      e.Generation.Media == history.MediaVideo,
+			EditCount:    stats.Count,
+			EditTokens:   stats.TokenUsage.Total,
+			LastEditAt:   newTimestamp(stats.LastEditAt, now),
+		}
+		if cost, ok := gemini.EstimateCost(model, stats.TokenUsage); ok && stats.Count > 0 {
+			info.EditCost = fmt.Sprintf("$%.2f", cost)
+		}
+		entryInfos = append(entryInfos, info)
 	}
 
 	data := struct {
-		Name        string
-		Description string
-		Entries     []EntryInfo
+		Name          string
+		Description   string
+		Entries       []EntryInfo
+		Days          []DayGroup // Entries grouped by local calendar day
+		Page          Page
+		Total         int // entries in the subproject, on all pages
+		Failed        int
+		Failures      *FailurePanel // failed API calls of the last failurePanelDays, nil if none
+		CanGenerate   bool          // show the generate form
+		DefaultAspect string
+		DefaultSize   string
 	}{
-		Name:        name,
-		Description: description,
-		Entries:     entryInfos,
+		Name:          name,
+		Description:   subprojectConfig.Description,
+		Entries:       entryInfos,
+		Days:          groupByDay(entryInfos),
+		Page:          pg,
+		Total:         len(entries),
+		Failed:        failed,
+		Failures:      newFailurePanel(historyDir, now),
+		CanGenerate:   s.generate != nil && !readOnly,
+		DefaultAspect: subprojectConfig.AspectRatio,
+		DefaultSize:   subprojectConfig.ImageSize,
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "subproject.html", data); err != nil {
@@ -180,60 +462,172 @@ func (s *Server) handleEntry(w http.ResponseWriter, r *http.Request) {
 // EditInfo contains edit entry information for templates
 type EditInfo struct {
 	ID           string
-	CreatedAt    string
+	CreatedAt    Timestamp
 	Prompt       string
 	SourceType   string
 	SourceOutput string
 	OutputImages []string
 	ImageURLs    []string
+	ParentID     string // source edit of a chained edit (empty when editing the generated image)
+	SourceURL    string // image the edit started from
+	Depth        int    // position in the lineage tree: 1 for edits of the generated image
+	Upscale      bool   // made by banago upscale
+	Compare      *Comparison
+}
+
+// Candidate is one of the variants a generation requested in a single API call
+type Candidate struct {
+	Number int // 1-based, in output order
+	URL    string
+}
+
+// Comparison is a before/after image pair shown with a slider
+type Comparison struct {
+	BeforeURL   string
+	BeforeLabel string
+	AfterURL    string
+	AfterLabel  string
+}
+
+// editLineage orders edits as a depth-first walk of their source tree and sets each Depth.
+// Edits whose source edit no longer exists are shown as edits of the generated image.
+func editLineage(edits []EditInfo) []EditInfo {
+	known := map[string]bool{}
+	for _, e := range edits {
+		known[e.ID] = true
+	}
+	children := map[string][]EditInfo{}
+	for _, e := range edits {
+		parent := e.ParentID
+		if !known[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], e)
+	}
+
+	var lineage []EditInfo
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		for _, e := range children[parent] {
+			e.Depth = depth
+			lineage = append(lineage, e)
+			walk(e.ID, depth+1)
+		}
+	}
+	walk("", 1)
+	return lineage
 }
 
 func (s *Server) renderEntry(w http.ResponseWriter, subprojectName, entryID string) {
-	subprojectDir := project.GetSubprojectDir(s.projectRoot, subprojectName)
-	historyDir := history.GetHistoryDir(subprojectDir)
+	historyDir, err := s.historyDir(subprojectName)
+	if err != nil {
+		http.NotFound(w, nil)
+		return
+	}
 
 	entry, err := history.GetEntryByID(historyDir, entryID)
-	if err != nil {
+	if errors.Is(err, history.ErrNotFinalized) {
+		writePending(w)
+		return
+	}
+	if errors.Is(err, history.ErrEntryNotFound) {
 		http.NotFound(w, nil)
 		return
 	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	entryDir := filepath.Join(historyDir, entryID)
+	entryDir := entry.GetEntryDir(historyDir)
 	prompt, _ := history.LoadPrompt(entryDir)
 
-	// Build output image URLs
-	var imageURLs []string
+	// Build output image and video URLs
+	var imageURLs, videoURLs []string
 	for _, img := range entry.Result.OutputImages {
-		imageURLs = append(imageURLs, fmt.Sprintf("/images/%s/%s/%s", subprojectName, entryID, img))
+		url := imageURL(subprojectName, historyDir, history.GetEntryFilePath(entryDir, img))
+		if gemini.IsVideoFile(img) {
+			videoURLs = append(videoURLs, url)
+			continue
+		}
+		imageURLs = append(imageURLs, url)
+	}
+
+	// Entries that asked for several candidates show their outputs as a grid of variants
+	var candidates []Candidate
+	if entry.Generation.Candidates > 1 {
+		for i, url := range imageURLs {
+			candidates = append(candidates, Candidate{Number: i + 1, URL: url})
+		}
+	}
+
+	var animationURL string
+	if entry.Result.Animation != "" {
+		animationURL = imageURL(subprojectName, historyDir, history.GetEntryFilePath(entryDir, entry.Result.Animation))
 	}
 
 	// Build input image URLs
 	var inputImageURLs []string
-	for _, img := range entry.Generation.InputImages {
-		inputImageURLs = append(inputImageURLs, fmt.Sprintf("/images/%s/%s/%s", subprojectName, entryID, img))
+	for _, path := range history.GetInputImagePaths(entryDir, entry.Generation.InputImages) {
+		// Snapshots in the object store are served through the entry (see handleImage)
+		path = history.GetEntryFilePath(entryDir, filepath.Base(path))
+		inputImageURLs = append(inputImageURLs, imageURL(subprojectName, historyDir, path))
 	}
 
 	// Get edits
+	now := time.Now()
 	var edits []EditInfo
 	editEntries, _ := history.ListEditEntries(entryDir)
 	for _, e := range editEntries {
-		editDir := filepath.Join(history.GetEditsDir(entryDir), e.ID)
+		editDir := e.GetEditEntryDir(entryDir)
 		editPrompt, _ := history.LoadEditPrompt(editDir)
 
 		var editImageURLs []string
 		for _, img := range e.Result.OutputImages {
-			editImageURLs = append(editImageURLs, fmt.Sprintf("/images/%s/%s/edits/%s/%s", subprojectName, entryID, e.ID, img))
+			editImageURLs = append(editImageURLs, imageURL(subprojectName, historyDir, history.GetEditOutputPath(entryDir, e.ID, img)))
+		}
+		sourcePath := history.GetEntryFilePath(entryDir, e.Source.Output)
+		if e.Source.EditID != "" {
+			sourcePath = history.GetEditOutputPath(entryDir, e.Source.EditID, e.Source.Output)
 		}
 
-		edits = append(edits, EditInfo{
+		edit := EditInfo{
 			ID:           e.ID,
-			CreatedAt:    e.CreatedAt,
+			CreatedAt:    newTimestamp(e.CreatedAt, now),
 			Prompt:       editPrompt,
 			SourceType:   e.Source.Type,
 			SourceOutput: e.Source.Output,
 			OutputImages: e.Result.OutputImages,
 			ImageURLs:    editImageURLs,
-		})
+			ParentID:     e.Source.EditID,
+			SourceURL:    imageURL(subprojectName, historyDir, sourcePath),
+			Upscale:      e.Generation.Upscale,
+		}
+		if len(editImageURLs) > 0 {
+			edit.Compare = &Comparison{
+				BeforeURL:   edit.SourceURL,
+				BeforeLabel: "Source",
+				AfterURL:    editImageURLs[0],
+				AfterLabel:  "Edited",
+			}
+		}
+		edits = append(edits, edit)
+	}
+
+	// Compare a regenerated entry with the entry it was regenerated from
+	var sourceEntryID string
+	var sourceCompare *Comparison
+	if entry.Lineage != nil && entry.Lineage.RegeneratedFrom != "" && len(imageURLs) > 0 {
+		sourceEntryID = entry.Lineage.RegeneratedFrom
+		if source, err := history.GetEntryByID(historyDir, sourceEntryID); err == nil && len(source.Result.OutputImages) > 0 {
+			sourceDir := source.GetEntryDir(historyDir)
+			sourceCompare = &Comparison{
+				BeforeURL:   imageURL(subprojectName, historyDir, history.GetEntryFilePath(sourceDir, source.Result.OutputImages[0])),
+				BeforeLabel: "Source entry",
+				AfterURL:    imageURLs[0],
+				AfterLabel:  "Regenerated",
+			}
+		}
 	}
 
 	// Get prev/next entry IDs for navigation
@@ -242,19 +636,33 @@ func (s *Server) renderEntry(w http.ResponseWriter, subprojectName, entryID stri
 	data := struct {
 		SubprojectName string
 		Entry          *history.Entry
+		Created        Timestamp
 		Prompt         string
 		ImageURLs      []string
+		Candidates     []Candidate // grid of variants, set when the entry requested several
+		AnimationURL   string
+		VideoURLs      []string
 		InputImageURLs []string
 		Edits          []EditInfo
+		Lineage        []EditInfo // edits in tree order
+		SourceEntryID  string     // entry this one was regenerated from
+		SourceCompare  *Comparison
 		PrevEntryID    string
 		NextEntryID    string
 	}{
 		SubprojectName: subprojectName,
 		Entry:          entry,
+		Created:        newTimestamp(entry.CreatedAt, now),
 		Prompt:         prompt,
 		ImageURLs:      imageURLs,
+		Candidates:     candidates,
+		AnimationURL:   animationURL,
+		VideoURLs:      videoURLs,
 		InputImageURLs: inputImageURLs,
 		Edits:          edits,
+		Lineage:        editLineage(edits),
+		SourceEntryID:  sourceEntryID,
+		SourceCompare:  sourceCompare,
 		PrevEntryID:    prevID,
 		NextEntryID:    nextID,
 	}
@@ -292,6 +700,16 @@ func (s *Server) getAdjacentEntryIDs(historyDir, currentID string) (prevID, next
 	return prevID, nextID
 }
 
+// imageURL returns the URL under /images/ that serves a file inside the history directory.
+// The URL mirrors the path relative to the history directory, which handleImage resolves back.
+func imageURL(subprojectName, historyDir, path string) string {
+	rel, err := filepath.Rel(historyDir, path)
+	if err != nil {
+		return ""
+	}
+	return "/images/" + subprojectName + "/" + filepath.ToSlash(rel)
+}
+
 // handleImage serves image files from history
 func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
 	// /images/{subproject}/{...path}
@@ -303,8 +721,11 @@ func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	subprojectDir := project.GetSubprojectDir(s.projectRoot, parts[0])
-	historyDir := history.GetHistoryDir(subprojectDir)
+	historyDir, err := s.historyDir(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
 	imagePath := filepath.Join(historyDir, filepath.Join(parts[1:]...))
 
 	// Security: ensure the path is within the history directory
@@ -322,10 +743,40 @@ func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if isPending(historyDir, parts[1:]) {
+		writePending(w)
+		return
+	}
+	if len(parts) == 3 {
+		// Input images of an entry may be kept in the project's object store
+		imagePath = history.ResolveEntryFile(filepath.Dir(imagePath), parts[2])
+	}
 
 	http.ServeFile(w, r, imagePath)
 }
 
+// isPending reports whether the file at path (entry ID, then edits/{edit ID} for edit files) belongs to
+// an entry or edit that a generation is still writing, so its outputs may be incomplete
+func isPending(historyDir string, path []string) bool {
+	_, err := history.GetEntryByID(historyDir, path[0])
+	if errors.Is(err, history.ErrNotFinalized) {
+		return true
+	}
+	if len(path) >= 4 && path[1] == "edits" {
+		_, err := history.GetEditEntryByID(history.GetEntryDirByID(historyDir, path[0]), path[2])
+		return errors.Is(err, history.ErrNotFinalized)
+	}
+	return false
+}
+
+// writePending answers a request for an entry that is still being written. Browsers reload the page
+// after pendingRetrySeconds, by which time the generation has usually finalized it.
+func writePending(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(pendingRetrySeconds))
+	w.Header().Set("Refresh", strconv.Itoa(pendingRetrySeconds))
+	http.Error(w, "This entry is still being written. The page reloads when it is ready.", http.StatusServiceUnavailable)
+}
+
 func (s *Server) listSubprojects() ([]SubprojectView, error) {
 	infos, err := project.ListSubprojectInfos(s.projectRoot)
 	if err != nil {
@@ -334,8 +785,10 @@ func (s *Server) listSubprojects() ([]SubprojectView, error) {
 
 	var result []SubprojectView
 	for _, info := range infos {
-		subprojectDir := project.GetSubprojectDir(s.projectRoot, info.Name)
-		hDir := history.GetHistoryDir(subprojectDir)
+		hDir, err := s.historyDir(info.Name)
+		if err != nil {
+			continue
+		}
 		historyEntries, _ := history.ListEntries(hDir)
 
 		result = append(result, SubprojectView{
@@ -347,3 +800,13 @@ func (s *Server) listSubprojects() ([]SubprojectView, error) {
 
 	return result, nil
 }
+
+// historyDir resolves the history directory of a subproject from its config
+func (s *Server) historyDir(subprojectName string) (string, error) {
+	subprojectDir := project.GetSubprojectDir(s.projectRoot, subprojectName)
+	cfg, err := config.LoadSubprojectConfig(subprojectDir)
+	if err != nil {
+		return "", err
+	}
+	return project.ResolveHistoryDir(subprojectDir, cfg), nil
+}
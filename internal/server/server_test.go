@@ -1,11 +1,20 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/blck-snwmn/banago/internal/config"
 	"github.com/blck-snwmn/banago/internal/history"
@@ -157,3 +166,615 @@ func TestHandleImageContent(t *testing.T) {
 		t.Errorf("handleImage() body = %q, want %q", body, "fake-png-data")
 	}
 }
+
+func TestPendingEntry(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	entry := &history.Entry{ID: "pending-entry-id", Result: history.Result{OutputImages: []string{"output.png"}}}
+	if err := entry.SavePending(historyDir); err != nil {
+		t.Fatalf("failed to save pending entry: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(entry.GetEntryDir(historyDir), "output.png"), []byte("partial"), 0o644); err != nil {
+		t.Fatalf("failed to write test image: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.renderEntry(rec, "test-subproject", entry.ID)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("renderEntry() status = %d, Retry-After = %q, want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rec = httptest.NewRecorder()
+	srv.handleImage(rec, httptest.NewRequest(http.MethodGet, "/images/test-subproject/pending-entry-id/output.png", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("handleImage() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Once finalized the entry is served normally
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	srv.renderEntry(rec, "test-subproject", entry.ID)
+	if rec.Code != http.StatusOK {
+		t.Errorf("renderEntry() status = %d after finalizing, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestGroupByDay(t *testing.T) {
+	t.Parallel()
+
+	// Newest first, as on the subproject page. Noon UTC keeps each date on one local day in any zone.
+	entries := []EntryInfo{
+		{ID: "c", CreatedAt: Timestamp{Value: "2024-01-03T12:00:00Z"}},
+		{ID: "b", CreatedAt: Timestamp{Value: "2024-01-01T12:30:00Z"}},
+		{ID: "a", CreatedAt: Timestamp{Value: "2024-01-01T12:00:00Z"}},
+		{ID: "legacy", CreatedAt: Timestamp{Value: "unknown"}},
+	}
+	groups := groupByDay(entries)
+	if len(groups) != 3 {
+		t.Fatalf("groupByDay() = %d groups, want 3", len(groups))
+	}
+	if len(groups[0].Entries) != 1 || len(groups[1].Entries) != 2 || groups[1].Entries[1].ID != "a" {
+		t.Errorf("groupByDay() = %+v, want c | b, a | legacy", groups)
+	}
+	if groups[2].Day != "" || groups[2].Label != "Unknown date" {
+		t.Errorf("group of an invalid date = %q %q, want an unknown date", groups[2].Day, groups[2].Label)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		total, size         int
+		value               string
+		wantNumber          int
+		wantCount           int
+		wantFirst, wantLast int
+	}{
+		{name: "first page by default", total: 5, size: 2, value: "", wantNumber: 1, wantCount: 3, wantFirst: 1, wantLast: 2},
+		{name: "middle page", total: 5, size: 2, value: "2", wantNumber: 2, wantCount: 3, wantFirst: 3, wantLast: 4},
+		{name: "short last page", total: 5, size: 2, value: "3", wantNumber: 3, wantCount: 3, wantFirst: 5, wantLast: 5},
+		{name: "past the end clamps", total: 5, size: 2, value: "9", wantNumber: 3, wantCount: 3, wantFirst: 5, wantLast: 5},
+		{name: "invalid value", total: 5, size: 2, value: "-1", wantNumber: 1, wantCount: 3, wantFirst: 1, wantLast: 2},
+		{name: "empty list", total: 0, size: 2, value: "2", wantNumber: 1, wantCount: 1, wantFirst: 0, wantLast: 0},
+		{name: "unlimited size", total: 5, size: 0, value: "2", wantNumber: 1, wantCount: 1, wantFirst: 1, wantLast: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := paginate(tt.total, tt.size, tt.value)
+			if p.Number != tt.wantNumber || p.Count != tt.wantCount || p.First != tt.wantFirst || p.Last != tt.wantLast {
+				t.Errorf("paginate(%d, %d, %q) = page %d/%d showing %d-%d, want page %d/%d showing %d-%d",
+					tt.total, tt.size, tt.value, p.Number, p.Count, p.First, p.Last, tt.wantNumber, tt.wantCount, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestHandleSubproject_Pagination(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.SetPageSize(2)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	var ids []string
+	for range 5 {
+		entry := history.NewEntry()
+		entry.Result.Success = true
+		if err := entry.Save(historyDir); err != nil {
+			t.Fatalf("failed to save entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.handleSubproject(rec, httptest.NewRequest(http.MethodGet, "/subprojects/test-subproject?page=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleSubproject() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Showing 3–4 of 5 entries") {
+		t.Errorf("body does not show the page position and total")
+	}
+	// Newest first: page 2 holds the third and fourth newest entries
+	for i, id := range ids {
+		want := i == 1 || i == 2
+		if strings.Contains(body, id) != want {
+			t.Errorf("entry %d on page 2 = %v, want %v", i, !want, want)
+		}
+	}
+	if !strings.Contains(body, `href="?page=1"`) || !strings.Contains(body, `href="?page=3"`) {
+		t.Errorf("body lacks links to the previous and next page")
+	}
+}
+
+func TestHandleSubproject_FailurePanel(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	rec := httptest.NewRecorder()
+	srv.handleSubproject(rec, httptest.NewRequest(http.MethodGet, "/subprojects/test-subproject", nil))
+	if strings.Contains(rec.Body.String(), `class="failures"`) {
+		t.Errorf("body shows a failure panel without failures")
+	}
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	entry := history.NewEntry()
+	entry.Result.Success = true
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+	for _, category := range []string{"quota", "quota", "safety"} {
+		if err := history.RecordFailure(historyDir, history.Failure{Kind: history.FailureKindGenerate, Category: category}); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	srv.handleSubproject(rec, httptest.NewRequest(http.MethodGet, "/subprojects/test-subproject", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "3 of 4 API calls failed in the last 30 days (75%): safety 1, quota 2") {
+		t.Errorf("body lacks the failure summary")
+	}
+	if !strings.Contains(body, "<td>"+time.Now().UTC().Format(time.DateOnly)+"</td>") {
+		t.Errorf("body lacks today's failures")
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+
+	// Create an edit output inside the entry
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	entryDir := history.GetEntryDirByID(historyDir, "test-entry-id")
+	editOutput := history.GetEditOutputPath(entryDir, "edit-id", "edited.png")
+	if err := os.MkdirAll(filepath.Dir(editOutput), 0o755); err != nil {
+		t.Fatalf("failed to create edit dir: %v", err)
+	}
+	if err := os.WriteFile(editOutput, []byte("edited-data"), 0o644); err != nil {
+		t.Fatalf("failed to write edit output: %v", err)
+	}
+
+	url := imageURL("test-subproject", historyDir, editOutput)
+	if url != "/images/test-subproject/test-entry-id/edits/edit-id/edited.png" {
+		t.Errorf("imageURL() = %q", url)
+	}
+
+	// The URL must resolve back to the same file
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rec := httptest.NewRecorder()
+	srv.handleImage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleImage() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != "edited-data" {
+		t.Errorf("handleImage() body = %q, want %q", rec.Body.String(), "edited-data")
+	}
+}
+
+func TestServer_ListenAndShutdown(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 0)
+
+	addr, err := srv.Listen()
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	port := addr.(*net.TCPAddr).Port
+	if port == 0 {
+		t.Fatal("Listen() with port 0 did not return the bound port")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx)
+	}()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/", port))
+	if err != nil {
+		t.Fatalf("GET / error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET / status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() after cancel error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after cancel")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+	srv.RequireToken("s3cret")
+	handler := srv.routes()
+
+	tests := []struct {
+		name       string
+		setAuth    func(r *http.Request)
+		wantStatus int
+	}{
+		{name: "no credentials", setAuth: func(*http.Request) {}, wantStatus: http.StatusUnauthorized},
+		{name: "bearer token", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }, wantStatus: http.StatusOK},
+		{name: "wrong bearer token", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, wantStatus: http.StatusUnauthorized},
+		{name: "basic auth password", setAuth: func(r *http.Request) { r.SetBasicAuth("anyone", "s3cret") }, wantStatus: http.StatusOK},
+		{name: "wrong basic auth password", setAuth: func(r *http.Request) { r.SetBasicAuth("s3cret", "nope") }, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.setAuth(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET / status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response should ask for basic auth")
+			}
+		})
+	}
+}
+
+func TestHandleGenerate(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+
+	// Disabled until a generator is set
+	form := url.Values{"prompt": {"a red fox"}, "size": {"2K"}}
+	req := httptest.NewRequest(http.MethodPost, "/generate/test-subproject", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.handleGenerate(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("handleGenerate() without generator status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var got GenerateRequest
+	var gotDir string
+	srv.EnableGeneration(func(_ context.Context, subprojectDir string, r GenerateRequest, w io.Writer) error {
+		gotDir, got = subprojectDir, r
+		_, _ = fmt.Fprintln(w, "Generated files:")
+		return errors.New("quota exceeded")
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		form       url.Values
+		wantStatus int
+	}{
+		{"missing prompt", "/generate/test-subproject", url.Values{}, http.StatusBadRequest},
+		{"nonexistent subproject", "/generate/nonexistent", form, http.StatusNotFound},
+		{"nested path", "/generate/test-subproject/x", form, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleGenerate(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: handleGenerate() status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/generate/test-subproject", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	srv.handleGenerate(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("handleGenerate() status = %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/jobs/") {
+		t.Fatalf("handleGenerate() Location = %q", location)
+	}
+
+	// The event stream ends once the job has finished
+	req = httptest.NewRequest(http.MethodGet, location+"/events", nil)
+	rec = httptest.NewRecorder()
+	srv.handleJob(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: log\ndata: \"Generated files:\\n\"") {
+		t.Errorf("event stream missing progress: %q", body)
+	}
+	if !strings.Contains(body, "event: done\ndata: {\"error\":\"quota exceeded\"}") {
+		t.Errorf("event stream missing done event: %q", body)
+	}
+
+	if got.Prompt != "a red fox" || got.Size != "2K" || got.Aspect != "" {
+		t.Errorf("generate request = %+v", got)
+	}
+	if gotDir != project.GetSubprojectDir(projectRoot, "test-subproject") {
+		t.Errorf("generate dir = %q", gotDir)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs/unknown/events", nil)
+	rec = httptest.NewRecorder()
+	srv.handleJob(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("handleJob() unknown status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleGenerate_Guards(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	release := make(chan struct{})
+	srv.EnableGeneration(func(ctx context.Context, _ string, _ GenerateRequest, _ io.Writer) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	post := func(setHeaders func(r *http.Request)) int {
+		form := url.Values{"prompt": {"a red fox"}}
+		req := httptest.NewRequest(http.MethodPost, "/generate/test-subproject", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setHeaders(req)
+		rec := httptest.NewRecorder()
+		srv.handleGenerate(rec, req)
+		return rec.Code
+	}
+
+	// Posts from other sites are refused
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"cross-site fetch", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"foreign origin", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same origin", map[string]string{"Origin": "http://example.com", "Sec-Fetch-Site": "same-origin"}, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		got := post(func(r *http.Request) {
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+		})
+		if got != tt.wantStatus {
+			t.Errorf("%s: handleGenerate() status = %d, want %d", tt.name, got, tt.wantStatus)
+		}
+	}
+
+	// maxRunningJobs jobs run at once; the first is already running
+	for i := 1; i < maxRunningJobs; i++ {
+		if got := post(func(*http.Request) {}); got != http.StatusSeeOther {
+			t.Fatalf("job %d: handleGenerate() status = %d, want %d", i+1, got, http.StatusSeeOther)
+		}
+	}
+	if got := post(func(*http.Request) {}); got != http.StatusTooManyRequests {
+		t.Errorf("handleGenerate() over the limit status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	// Stopping the server cancels running jobs
+	srv.stopJobs()
+	srv.jobsWG.Wait()
+	srv.jobsMu.Lock()
+	for _, j := range srv.jobs {
+		if _, done, err := j.snapshot(); !done || !errors.Is(err, context.Canceled) {
+			t.Errorf("job after stop: done = %v, err = %v, want canceled", done, err)
+		}
+		// Finished jobs are forgotten after jobRetention
+		j.finishedAt = time.Now().Add(-2 * jobRetention)
+	}
+	srv.jobsMu.Unlock()
+	close(release)
+	if got := post(func(*http.Request) {}); got != http.StatusSeeOther {
+		t.Errorf("handleGenerate() after stop status = %d, want %d", got, http.StatusSeeOther)
+	}
+	srv.jobsMu.Lock()
+	kept := len(srv.jobs)
+	srv.jobsMu.Unlock()
+	if kept != 1 {
+		t.Errorf("jobs kept = %d, want only the new one", kept)
+	}
+
+	// readonly in banago.yaml is checked on every submission
+	cfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ReadOnly = true
+	if err := cfg.Save(projectRoot); err != nil {
+		t.Fatal(err)
+	}
+	if got := post(func(*http.Request) {}); got != http.StatusForbidden {
+		t.Errorf("handleGenerate() on read-only project status = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestEditLineage(t *testing.T) {
+	t.Parallel()
+
+	// a and d edit the generated image, b and c chain on a, e's source edit was removed
+	edits := []EditInfo{
+		{ID: "a"},
+		{ID: "b", ParentID: "a"},
+		{ID: "c", ParentID: "b"},
+		{ID: "d"},
+		{ID: "e", ParentID: "gone"},
+		{ID: "f", ParentID: "a"},
+	}
+
+	got := editLineage(edits)
+	want := []struct {
+		id    string
+		depth int
+	}{{"a", 1}, {"b", 2}, {"c", 3}, {"f", 2}, {"d", 1}, {"e", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("editLineage() returned %d edits, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].ID != w.id || got[i].Depth != w.depth {
+			t.Errorf("editLineage()[%d] = %s (depth %d), want %s (depth %d)", i, got[i].ID, got[i].Depth, w.id, w.depth)
+		}
+	}
+}
+
+func TestRenderEntry_EditLineage(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	entryDir := history.GetEntryDirByID(historyDir, "test-entry-id")
+	first := &history.EditEntry{
+		ID:     "0190b5a0-0000-7000-8000-000000000001",
+		Source: history.EditSource{Type: "generate", Output: "output.png"},
+		Result: history.Result{Success: true, OutputImages: []string{"edited.png"}},
+	}
+	second := &history.EditEntry{
+		ID:     "0190b5a0-0000-7000-8000-000000000002",
+		Source: history.EditSource{Type: "edit", EditID: first.ID, Output: "edited.png"},
+		Result: history.Result{Success: true, OutputImages: []string{"edited.png"}},
+	}
+	for _, e := range []*history.EditEntry{first, second} {
+		if err := os.MkdirAll(e.GetEditEntryDir(entryDir), 0o755); err != nil {
+			t.Fatalf("failed to create edit dir: %v", err)
+		}
+		if err := e.Save(entryDir); err != nil {
+			t.Fatalf("failed to save edit: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	srv.renderEntry(rec, "test-subproject", "test-entry-id")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("renderEntry() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Edit Lineage",
+		`style="--depth: 2"`,
+		`src="/images/test-subproject/test-entry-id/edits/` + first.ID + `/edited.png"`, // source of the second edit
+		`href="#edit-` + first.ID + `">parent edit</a>`,
+		`id="edit-` + second.ID + `"`,
+		"Compare with source",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("entry page missing %q", want)
+		}
+	}
+}
+
+func TestRenderEntry_CompareWithSourceEntry(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	regenerated := &history.Entry{
+		ID:        "regenerated-id",
+		CreatedAt: "2024-01-02T00:00:00Z",
+		Result:    history.Result{Success: true, OutputImages: []string{"output.png"}},
+		Lineage:   &history.Lineage{RegeneratedFrom: "test-entry-id"},
+	}
+	if err := regenerated.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.renderEntry(rec, "test-subproject", "regenerated-id")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("renderEntry() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`regenerated from <a href="/entry/test-subproject/test-entry-id">`,
+		"Compare with Source Entry",
+		`src="/images/test-subproject/test-entry-id/output.png" alt="Source entry"`,
+		`src="/images/test-subproject/regenerated-id/output.png" alt="Regenerated"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("entry page missing %q", want)
+		}
+	}
+}
+
+func TestRenderEntry_Candidates(t *testing.T) {
+	t.Parallel()
+
+	projectRoot := setupTestProject(t)
+	srv := New(projectRoot, 8080)
+	srv.templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+	historyDir := history.GetHistoryDir(project.GetSubprojectDir(projectRoot, "test-subproject"))
+	entry := &history.Entry{
+		ID:         "candidates-id",
+		CreatedAt:  "2024-01-02T00:00:00Z",
+		Generation: history.Generation{Candidates: 3},
+		Result:     history.Result{Success: true, OutputImages: []string{"output-1.png", "output-2.png"}},
+	}
+	if err := entry.Save(historyDir); err != nil {
+		t.Fatalf("failed to save entry: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.renderEntry(rec, "test-subproject", "candidates-id")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("renderEntry() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Candidates (2 of 3)",
+		`<div class="images candidates">`,
+		`src="/images/test-subproject/candidates-id/output-2.png" alt="Candidate 2"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("entry page missing %q", want)
+		}
+	}
+	if strings.Contains(body, "Generated Images") {
+		t.Error("entry page with candidates should not show the plain image list")
+	}
+}
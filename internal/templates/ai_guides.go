@@ -10,8 +10,8 @@ const baseGuide = `**Model**: ` + "`gemini-3-pro-image-preview`" + ` - Prompts m
 - Reading a file (whether text or image) is just file I/O - the CLI handles it the same way
 - You SHOULD proactively read image files to:
   - Understand reference images in ` + "`inputs/`" + `
-  - Review generated images in ` + "`history/<uuid>/output_*.png`" + `
-  - Check edited images in ` + "`history/<uuid>/edits/<edit-uuid>/output_*.png`" + `
+  - Review generated images in ` + "`history/<uuid>/output-*.png`" + `
+  - Check edited images in ` + "`history/<uuid>/edits/<edit-uuid>/output-*.png`" + `
   - Analyze character appearance from existing images
 - **Do NOT assume you cannot read images** - you absolutely can and should
 
@@ -31,6 +31,7 @@ This shows whether you are in a subproject and its configuration.
 
 1. **Run** ` + "`banago subproject list`" + ` to check existing subprojects
 2. **If no suitable subproject exists, run** ` + "`banago subproject create <name>`" + ` to create one
+   - If the user gave a written brief, ` + "`banago subproject create <name> --brief <file>`" + ` drafts context.md, prompt.txt and config fields from it; **review the drafted files with the user** in Step 3
 3. **Run** ` + "`cd subprojects/<name>`" + ` to navigate into the subproject
 
 **Do NOT skip this step.** The subproject must exist and you must be inside it before proceeding.
@@ -85,7 +86,8 @@ input_images:
 
 **Always save prompts to a file.** This prevents context loss during long conversations.
 
-1. Read ` + "`context.md`" + ` and ` + "`characters/<name>.md`" + `
+1. Read the project-level ` + "`context.md`" + ` (shared guidelines, if present), then the subproject's ` + "`context.md`" + ` and ` + "`characters/<name>.md`" + `
+   - The project-level context applies to every subproject and comes first; follow it unless the subproject's context overrides it
 2. **Read the reference images** in ` + "`inputs/`" + ` directly
    - You CAN read image files - do not skip this step
    - Understand the character's appearance, style, and details from the images
@@ -116,7 +118,7 @@ After generation, you MUST review the results and decide on the appropriate acti
 ` + "```bash" + `
 banago history
 ` + "```" + `
-1. **Read the generated images** in ` + "`history/<uuid>/output_*.png`" + `
+1. **Read the generated images** in ` + "`history/<uuid>/output-*.png`" + `
    - You CAN and SHOULD view images directly - do not skip this step
 2. **Read the prompt** used for generation in ` + "`history/<uuid>/prompt.txt`" + `
 3. **Compare**: Does the generated image match the prompt's intent?
@@ -169,7 +171,7 @@ banago edit --id <uuid> -p "Fix the background lighting"
 # Chain edits (edit a previously edited image)
 banago edit --latest --edit-latest -p "Further adjust the shadows"
 ` + "```" + `
-4. **Read the edited images** in ` + "`history/<uuid>/edits/<edit-uuid>/output_*.png`" + `
+4. **Read the edited images** in ` + "`history/<uuid>/edits/<edit-uuid>/output-*.png`" + `
 5. If still not right, either:
    - Chain another edit (for minor adjustments)
    - Go back to regeneration (if edits aren't working)
@@ -197,6 +199,7 @@ banago edit --latest --edit-latest -p "Further adjust the shadows"
 ` + "```" + `
 <project-root>/
 ├── banago.yaml           # Project config
+├── context.md            # Shared world/style guidelines (optional)
 ├── characters/           # Shared character definitions
 │   └── <name>.md
 └── subprojects/
@@ -208,15 +211,15 @@ banago edit --latest --edit-latest -p "Further adjust the shadows"
         └── history/      # Generation history (UUID v7)
             └── <uuid>/
                 ├── prompt.txt    # Prompt snapshot (read-only)
-                ├── context.md    # Context at generation time
+                ├── context.md    # Context at generation time (project + subproject)
                 ├── character.md  # Character info (if configured)
-                ├── output_*.png  # Generated images
+                ├── output-*.png  # Generated images
                 ├── meta.yaml     # Metadata
                 └── edits/        # Edit history
                     └── <edit-uuid>/
                         ├── edit-prompt.txt  # Edit prompt
                         ├── edit-meta.yaml   # Edit metadata
-                        └── output_*.png     # Edited images
+                        └── output-*.png     # Edited images
 ` + "```" + `
 
 ## Important Rules
@@ -233,6 +236,7 @@ banago edit --latest --edit-latest -p "Further adjust the shadows"
 ### Other Rules
 
 - Do NOT modify ` + "`inputs/`" + ` images (for consistency)
+- Run banago commands from the subproject directory. Commands that write refuse to run inside ` + "`history/`" + ` or ` + "`inputs/`" + `
 - History sorted by UUID v7 (chronological)
 `
 
@@ -0,0 +1,242 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// exportInterval is how often long-running commands such as serve export finished spans
+const exportInterval = 5 * time.Second
+
+// maxBuffered is how many finished spans are kept before older ones are dropped when the
+// collector cannot be reached
+const maxBuffered = 2048
+
+// tracer buffers finished spans and exports them in the background
+type tracer struct {
+	cfg    config
+	client *http.Client
+
+	mu      sync.Mutex
+	spans   []finishedSpan
+	dropped int
+
+	exportMu sync.Mutex // one export at a time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type finishedSpan struct {
+	span *Span
+	end  time.Time
+}
+
+func newTracer(cfg config) *tracer {
+	t := &tracer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+func (t *tracer) record(s *Span, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.spans) >= maxBuffered {
+		t.spans = t.spans[1:]
+		t.dropped++
+	}
+	t.spans = append(t.spans, finishedSpan{span: s, end: end})
+}
+
+func (t *tracer) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.export(context.Background()); err != nil {
+				slog.Warn("trace export failed", "endpoint", t.cfg.endpoint, "error", err)
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// shutdown stops the background exports and exports what is left
+func (t *tracer) shutdown(ctx context.Context) error {
+	close(t.stop)
+	<-t.done
+	return t.export(ctx)
+}
+
+// export sends the buffered spans to the collector. Spans are dropped even if sending fails so a
+// missing collector does not grow the buffer; the next batch is tried again.
+func (t *tracer) export(ctx context.Context) error {
+	t.exportMu.Lock()
+	defer t.exportMu.Unlock()
+
+	t.mu.Lock()
+	spans, dropped := t.spans, t.dropped
+	t.spans, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		slog.WarnContext(ctx, "trace spans dropped", "count", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(encodeSpans(t.cfg.serviceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON request (ExportTraceServiceRequest); IDs are hex and 64-bit integers strings
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// OTLP enum values
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+func encodeSpans(serviceName string, spans []finishedSpan) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, f := range spans {
+		s := f.span
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(f.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(s.attrs),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+		}
+		s.mu.Unlock()
+		encoded = append(encoded, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]any{"service.name", serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/blck-snwmn/banago"}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes converts key-value pairs; a trailing key without a value is dropped
+func encodeAttributes(kv []any) []otlpAttribute {
+	var attrs []otlpAttribute
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		attrs = append(attrs, otlpAttribute{Key: key, Value: encodeValue(kv[i+1])})
+	}
+	return attrs
+}
+
+func encodeValue(v any) otlpValue {
+	intValue := func(n int64) otlpValue {
+		s := strconv.FormatInt(n, 10)
+		return otlpValue{IntValue: &s}
+	}
+	switch v := v.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,178 @@
+// Package tracing records spans of banago runs and exports them to an OpenTelemetry collector
+// with OTLP over HTTP in its JSON encoding. It has no dependencies, so builds without tracing stay
+// as small as before; nothing is recorded unless Setup found an endpoint in the environment.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// active is the tracer installed by Setup, nil while tracing is off
+var active atomic.Pointer[tracer]
+
+// Span is one timed operation. A nil Span, returned while tracing is off, ignores every call.
+type Span struct {
+	tracer   *tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte // zero for root spans
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []any // key-value pairs as in log/slog
+	err   error
+	ended bool
+}
+
+type spanKey struct{}
+
+// Start starts a span named name as a child of the span in ctx and returns a context carrying it.
+// attrs are key-value pairs like the arguments of slog.Info.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	t := active.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes adds key-value pairs to the span
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End finishes the span, marking it failed when err is not nil. Only the first call counts.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.err = err
+	s.mu.Unlock()
+	s.tracer.record(s, end)
+}
+
+// Setup starts exporting spans when the standard OpenTelemetry environment variables name an
+// endpoint: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (used as is) or OTEL_EXPORTER_OTLP_ENDPOINT (with
+// /v1/traces appended). OTEL_EXPORTER_OTLP_HEADERS, OTEL_SERVICE_NAME and OTEL_SDK_DISABLED are
+// honored too. The returned function exports the spans still buffered and stops tracing; it does
+// nothing when tracing is off.
+func Setup() (shutdown func(context.Context) error, err error) {
+	cfg, ok, err := configFromEnv(os.Getenv)
+	if err != nil || !ok {
+		return func(context.Context) error { return nil }, err
+	}
+	t := newTracer(cfg)
+	active.Store(t)
+	return func(ctx context.Context) error {
+		active.CompareAndSwap(t, nil)
+		return t.shutdown(ctx)
+	}, nil
+}
+
+// config is where and how spans are exported
+type config struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+}
+
+// configFromEnv reads the exporter configuration; the second result is false when tracing is off
+func configFromEnv(getenv func(string) string) (config, bool, error) {
+	if strings.EqualFold(getenv("OTEL_SDK_DISABLED"), "true") {
+		return config{}, false, nil
+	}
+	switch exporter := getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "otlp":
+	case "none":
+		return config{}, false, nil
+	default:
+		return config{}, false, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q: only otlp is supported", exporter)
+	}
+
+	cfg := config{endpoint: getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), serviceName: getenv("OTEL_SERVICE_NAME")}
+	if cfg.endpoint == "" {
+		base := getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return config{}, false, nil
+		}
+		cfg.endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if u, err := url.Parse(cfg.endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return config{}, false, fmt.Errorf("invalid OTLP endpoint %q: must be an http or https URL", cfg.endpoint)
+	}
+	protocol := getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol != "" && protocol != "http/json" {
+		return config{}, false, fmt.Errorf("unsupported OTLP protocol %q: only http/json is supported", protocol)
+	}
+	if cfg.serviceName == "" {
+		cfg.serviceName = "banago"
+	}
+
+	headers, err := parseHeaders(getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return config{}, false, err
+	}
+	tracesHeaders, err := parseHeaders(getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"))
+	if err != nil {
+		return config{}, false, err
+	}
+	for k, v := range tracesHeaders {
+		headers[k] = v
+	}
+	cfg.headers = headers
+	return cfg, true, nil
+}
+
+// parseHeaders parses the key=value,key=value list of OTEL_EXPORTER_OTLP_HEADERS (values URL-encoded)
+func parseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, errors.New("invalid OTEL_EXPORTER_OTLP_HEADERS: want key=value pairs separated by commas")
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS value for %s: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
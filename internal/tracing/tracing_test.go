@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		enabled  bool
+		endpoint string
+		wantErr  bool
+	}{
+		{name: "no endpoint", env: map[string]string{}},
+		{name: "base endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318/"}, enabled: true, endpoint: "http://localhost:4318/v1/traces"},
+		{name: "traces endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://b/traces"}, enabled: true, endpoint: "https://b/traces"},
+		{name: "disabled", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_SDK_DISABLED": "true"}},
+		{name: "exporter none", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_TRACES_EXPORTER": "none"}},
+		{name: "unsupported exporter", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_TRACES_EXPORTER": "zipkin"}, wantErr: true},
+		{name: "grpc", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4317", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, wantErr: true},
+		{name: "not a URL", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4318"}, wantErr: true},
+		{name: "bad headers", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_HEADERS": "novalue"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, enabled, err := configFromEnv(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if enabled != tt.enabled {
+				t.Errorf("enabled = %v, want %v", enabled, tt.enabled)
+			}
+			if cfg.endpoint != tt.endpoint {
+				t.Errorf("endpoint = %q, want %q", cfg.endpoint, tt.endpoint)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv_Headers(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":       "http://a:4318",
+		"OTEL_EXPORTER_OTLP_HEADERS":        "x-team=images, authorization=Bearer%20abc",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS": "x-team=traces",
+	}
+	cfg, _, err := configFromEnv(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("configFromEnv() error = %v", err)
+	}
+	if got := cfg.headers["authorization"]; got != "Bearer abc" {
+		t.Errorf("authorization = %q, want %q", got, "Bearer abc")
+	}
+	if got := cfg.headers["x-team"]; got != "traces" {
+		t.Errorf("x-team = %q, want the traces-specific value", got)
+	}
+	if cfg.serviceName != "banago" {
+		t.Errorf("serviceName = %q, want banago", cfg.serviceName)
+	}
+}
+
+func TestStart_Off(t *testing.T) {
+	ctx, span := Start(context.Background(), "op")
+	if span != nil {
+		t.Fatal("Start() returned a span while tracing is off")
+	}
+	// A nil span ignores every call
+	span.SetAttributes("key", "value")
+	span.End(errors.New("boom"))
+	if ctx != context.Background() {
+		t.Error("Start() changed the context while tracing is off")
+	}
+}
+
+func TestExport(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	tr := newTracer(config{endpoint: srv.URL + "/v1/traces", headers: map[string]string{"Authorization": "Bearer abc"}, serviceName: "banago"})
+	active.Store(tr)
+	defer active.Store(nil)
+
+	ctx, root := Start(context.Background(), "banago generate")
+	_, child := Start(ctx, "gemini.generate", "model", "gemini-test", "inputs", 2)
+	child.SetAttributes("tokens", 42)
+	child.End(errors.New("quota exceeded"))
+	child.End(nil) // ignored
+	root.End(nil)
+
+	if err := tr.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Bearer abc" {
+		t.Errorf("Authorization = %q, want the configured header", auth)
+	}
+	if len(requests) != 1 || len(requests[0].ResourceSpans) != 1 || len(requests[0].ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("requests = %+v, want one batch", requests)
+	}
+	spans := requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	gotChild, gotRoot := spans[0], spans[1]
+	if gotChild.Name != "gemini.generate" || gotRoot.Name != "banago generate" {
+		t.Fatalf("span names = %q, %q", gotChild.Name, gotRoot.Name)
+	}
+	if gotChild.TraceID != gotRoot.TraceID || len(gotRoot.TraceID) != 32 {
+		t.Errorf("trace IDs = %q, %q, want the same 32 hex digits", gotChild.TraceID, gotRoot.TraceID)
+	}
+	if gotChild.ParentSpanID != gotRoot.SpanID || gotRoot.ParentSpanID != "" {
+		t.Errorf("parent IDs = %q, %q, want the child under the root", gotChild.ParentSpanID, gotRoot.ParentSpanID)
+	}
+	if gotChild.Status.Code != statusCodeError || gotChild.Status.Message != "quota exceeded" {
+		t.Errorf("child status = %+v, want the error", gotChild.Status)
+	}
+	if gotRoot.Status.Code != 0 {
+		t.Errorf("root status = %+v, want unset", gotRoot.Status)
+	}
+
+	attrs := map[string]otlpValue{}
+	for _, a := range gotChild.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if v := attrs["model"].StringValue; v == nil || *v != "gemini-test" {
+		t.Errorf("model attribute = %+v", attrs["model"])
+	}
+	if v := attrs["tokens"].IntValue; v == nil || *v != "42" {
+		t.Errorf("tokens attribute = %+v", attrs["tokens"])
+	}
+}